@@ -16,6 +16,15 @@ export function parseYaml(content: string): unknown {
 	return yaml.load(content);
 }
 
+// =============================================================================
+// Icons
+// =============================================================================
+
+/** Records the icon name instead of drawing the Lucide SVG. */
+export function setIcon(element: HTMLElement, iconName: string): void {
+	element.dataset.icon = iconName;
+}
+
 // =============================================================================
 // Platform
 // =============================================================================
@@ -49,6 +58,17 @@ export class TFile {
 	}
 }
 
+// =============================================================================
+// Metadata
+// =============================================================================
+
+/** Collects a metadata cache's tags, frontmatter ones first, each with #. */
+export function getAllTags(cache: { tags?: { tag: string }[]; frontmatter?: { tags?: string[] } }): string[] | null {
+	const frontmatterTags = (cache.frontmatter?.tags ?? []).map(tag => tag.startsWith('#') ? tag : `#${tag}`);
+	const tags = [...frontmatterTags, ...(cache.tags ?? []).map(entry => entry.tag)];
+	return tags.length > 0 ? tags : null;
+}
+
 // =============================================================================
 // App
 // =============================================================================
@@ -91,6 +111,24 @@ export class MarkdownView {
 	file: TFile | null = null;
 }
 
+// =============================================================================
+// ItemView
+// =============================================================================
+
+export class WorkspaceLeaf {}
+
+export class ItemView {
+	leaf: WorkspaceLeaf;
+	contentEl: HTMLElement;
+
+	constructor(leaf: WorkspaceLeaf) {
+		this.leaf = leaf;
+		this.contentEl = typeof document !== 'undefined'
+			? document.createElement('div')
+			: ({} as HTMLElement);
+	}
+}
+
 // =============================================================================
 // Modal
 // =============================================================================
@@ -111,6 +149,50 @@ export class Modal {
 	close(): void { /* no-op */ }
 }
 
+export class SuggestModal<T> extends Modal {
+	placeholder = '';
+
+	setPlaceholder(placeholder: string): void { this.placeholder = placeholder; }
+	getSuggestions(_query: string): T[] | Promise<T[]> { return []; }
+	renderSuggestion(_value: T, _el: HTMLElement): void { /* no-op */ }
+	onChooseSuggestion(_item: T, _evt: MouseEvent | KeyboardEvent): void { /* no-op */ }
+}
+
+// =============================================================================
+// Menu
+// =============================================================================
+
+export class MenuItem {
+	title = '';
+	icon = '';
+	callback: (() => void) | null = null;
+
+	setTitle(title: string): this { this.title = title; return this; }
+	setIcon(icon: string): this { this.icon = icon; return this; }
+	onClick(callback: () => void): this { this.callback = callback; return this; }
+}
+
+export class Menu {
+	/** Most recently shown menu, so tests can inspect and click its items */
+	static lastShown: Menu | null = null;
+
+	items: MenuItem[] = [];
+
+	addItem(cb: (item: MenuItem) => void): this {
+		const item = new MenuItem();
+		cb(item);
+		this.items.push(item);
+		return this;
+	}
+
+	addSeparator(): this { return this; }
+
+	showAtMouseEvent(_event: MouseEvent): this {
+		Menu.lastShown = this;
+		return this;
+	}
+}
+
 // =============================================================================
 // PluginSettingTab
 // =============================================================================
@@ -34,6 +34,8 @@ const context = await esbuild.context({
 		...builtins],
 	format: "cjs",
 	target: "es2018",
+	// Keep import() for modules loaded from the vault at runtime (Shiki)
+	supported: { "dynamic-import": true },
 	logLevel: "info",
 	sourcemap: prod ? false : "inline",
 	treeShaking: true,
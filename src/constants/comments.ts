@@ -0,0 +1,120 @@
+/**
+ * Ultra Code Fence - Comment Syntax
+ *
+ * Comment markers per language, used to strip comment lines from copied
+ * code (COPY.STRIP_COMMENTS). Only whole-line comments are recognised;
+ * trailing comments after code are left alone.
+ */
+
+import type { CommentSyntax } from '../types';
+
+// =============================================================================
+// Syntax Families
+// =============================================================================
+
+const HASH: CommentSyntax = { line: ['#'] };
+const SLASH: CommentSyntax = { line: ['//'], block: ['/*', '*/'] };
+const DASH: CommentSyntax = { line: ['--'] };
+const SEMICOLON: CommentSyntax = { line: [';'] };
+const PERCENT: CommentSyntax = { line: ['%'] };
+const MARKUP: CommentSyntax = { line: [], block: ['<!--', '-->'] };
+
+// =============================================================================
+// Language Mappings
+// =============================================================================
+
+/**
+ * Comment syntax keyed by language or extension.
+ */
+export const COMMENT_SYNTAX: Record<string, CommentSyntax | undefined> = {
+	// Shell and scripting
+	bash: HASH,
+	sh: HASH,
+	shell: HASH,
+	zsh: HASH,
+	fish: HASH,
+	python: HASH,
+	py: HASH,
+	ruby: HASH,
+	rb: HASH,
+	perl: HASH,
+	pl: HASH,
+	r: HASH,
+	elixir: HASH,
+	powershell: { line: ['#'], block: ['<#', '#>'] },
+	ps1: { line: ['#'], block: ['<#', '#>'] },
+	bat: { line: ['::', 'REM ', 'rem '] },
+	cmd: { line: ['::', 'REM ', 'rem '] },
+
+	// Config and build files
+	yaml: HASH,
+	yml: HASH,
+	toml: HASH,
+	dockerfile: HASH,
+	makefile: HASH,
+	nginx: HASH,
+	conf: HASH,
+	ini: { line: [';', '#'] },
+	hcl: { line: ['#', '//'], block: ['/*', '*/'] },
+	terraform: { line: ['#', '//'], block: ['/*', '*/'] },
+
+	// C family and friends
+	javascript: SLASH,
+	js: SLASH,
+	jsx: SLASH,
+	typescript: SLASH,
+	ts: SLASH,
+	tsx: SLASH,
+	java: SLASH,
+	kotlin: SLASH,
+	kt: SLASH,
+	scala: SLASH,
+	groovy: SLASH,
+	c: SLASH,
+	cpp: SLASH,
+	csharp: SLASH,
+	cs: SLASH,
+	go: SLASH,
+	rust: SLASH,
+	rs: SLASH,
+	swift: SLASH,
+	dart: SLASH,
+	php: { line: ['//', '#'], block: ['/*', '*/'] },
+	css: { line: [], block: ['/*', '*/'] },
+	scss: SLASH,
+	less: SLASH,
+
+	// Query languages
+	sql: { line: ['--'], block: ['/*', '*/'] },
+	lua: DASH,
+	haskell: DASH,
+	hs: DASH,
+
+	// Other
+	lisp: SEMICOLON,
+	clojure: SEMICOLON,
+	asm: SEMICOLON,
+	latex: PERCENT,
+	tex: PERCENT,
+	matlab: PERCENT,
+	erlang: PERCENT,
+	vim: { line: ['"'] },
+	html: MARKUP,
+	xml: MARKUP,
+	svg: MARKUP,
+};
+
+// =============================================================================
+// Lookup Functions
+// =============================================================================
+
+/**
+ * Retrieves the comment syntax for a given language or extension.
+ *
+ * @param key - Language name or file extension (case-insensitive)
+ * @returns Comment syntax, or undefined if the language is not known
+ */
+export function getCommentSyntax(key: string | undefined): CommentSyntax | undefined {
+	if (!key) return undefined;
+	return COMMENT_SYNTAX[key.toLowerCase()];
+}
@@ -16,7 +16,31 @@ import type { PluginSettings } from '../types';
  */
 export const DEFAULT_SETTINGS: PluginSettings = {
 	// Language support - common programming languages
-	supportedLanguages: 'c,cpp,cs,java,kotlin,swift,python,go,ruby,rust,php,r,javascript,js,typescript,ts,shell,sh,bash,powershell,sql,lua,dart,scala,perl,haskell,zig,elixir,yaml,json,xml,html,css,toml',
+	supportedLanguages: 'c,cpp,cs,java,kotlin,swift,python,go,ruby,rust,php,r,javascript,js,typescript,ts,shell,sh,bash,powershell,sql,lua,dart,scala,perl,haskell,zig,elixir,yaml,json,xml,html,css,toml,diff,patch',
+
+	// Custom grammars: none until a folder is chosen
+	customGrammarFolder: '',
+
+	// Highlighting engine: Obsidian's Prism
+	highlighterEngine: 'prism',
+	shikiModulePath: '',
+	prismLanguages: '',
+
+	// Language aliases: none
+	languageAliases: {},
+
+	// Embedded languages: SQL in the strings of common languages
+	languageInjections: [
+		{
+			hosts: 'go, python, javascript, typescript, java, cs, php, ruby, rust, kotlin',
+			token: 'string',
+			language: 'sql',
+			match: '^\\s*(?:SELECT|INSERT|UPDATE|DELETE|WITH|CREATE|ALTER|DROP|MERGE)\\b',
+		},
+	],
+
+	// Highlight themes: none until one is imported
+	highlightThemes: {},
 
 	// Title bar colours (used when useThemeColours is false)
 	titleBarBackgroundColour: '#282c34',
@@ -31,11 +55,25 @@ export const DEFAULT_SETTINGS: PluginSettings = {
 
 	// Code block features
 	showCopyButton: true,
+	showLineCopyButtons: false,
+	copyPlaceholders: false,
+	clipboardHistorySize: 10,
+	showCopyCount: false,
+	copyCounts: {},
+	showWrapButton: true,
+	wrapStates: {},
+
+	// Copy confirmation
+	copyFeedbackCheckmark: true,
+	copyFeedbackToast: '',
+	copyFeedbackStatusBar: false,
+	copyFeedbackDuration: 2000,
 
 	// Fold/scroll: 0 = disabled, 1+ = enabled with N lines visible
 	// FOLD takes precedence over SCROLL if both are non-zero
 	foldLines: 0,
 	scrollLines: 0,
+	minimapLines: 0,
 
 	// Theme integration
 	useThemeColours: true,
@@ -46,9 +84,29 @@ export const DEFAULT_SETTINGS: PluginSettings = {
 	// Line formatting
 	showLineNumbers: false,
 	showZebraStripes: false,
+	showLineHover: false,
+	bracketPairColours: false,
+	semanticHighlighting: false,
+	renderVariables: false,
+	codeVariables: '',
+	changedSince: 'off',
+	commentKeywordMode: 'on',
+	commentKeywords: [
+		{ keyword: 'TODO', type: 'todo', colour: '' },
+		{ keyword: 'FIXME', type: 'bug', colour: '' },
+		{ keyword: 'BUG', type: 'bug', colour: '' },
+		{ keyword: 'HACK', type: 'warning', colour: '' },
+		{ keyword: 'XXX', type: 'danger', colour: '' },
+		{ keyword: 'NOTE', type: 'note', colour: 'rgb(0, 191, 188)' },
+	],
+	tabSize: 4,
+	languageTabSizes: '',
 
 	// Path handling
 	defaultPathPrefix: 'vault://',
+	remoteCacheMinutes: 60,
+	progressiveLoadLines: 2000,
+	gitRepositories: [],
 
 	// Version tracking
 	lastSeenVersion: '',
@@ -60,7 +118,7 @@ export const DEFAULT_SETTINGS: PluginSettings = {
 
 	// Embedded code support
 	enableGenericProcessor: true,
-	defaultLanguage: 'text',
+	defaultLanguage: 'auto',
 
 	// Command output styling
 	commandPromptColour: '#6b7280',
@@ -78,11 +136,44 @@ export const DEFAULT_SETTINGS: PluginSettings = {
 
 	// Download button
 	showDownloadButton: true,
+	downloadFilenameTemplate: '',
+	downloadAddShebang: false,
+	downloadExecutable: false,
 	downloadPathHistory: {},
 
+	// Running code blocks (the user's consent is kept in local storage)
+	runTimeoutSeconds: 60,
+
+	// Edit button on blocks embedding a vault file
+	showEditButton: true,
+
 	// Print behaviour: 'expand' = show full code, 'asis' = keep folded/scrolled state
 	printBehaviour: 'expand',
 
+	// Print profile: light colours, line numbers only if shown on screen, blocks kept on one page
+	printTheme: 'light',
+	printLineNumbers: false,
+	printAvoidBreaks: true,
+
+	// Configuration problems: render with defaults and flag them
+	configMode: 'lenient',
+
 	// Presets: named YAML presets (empty by default)
 	presets: {},
+
+	// Folder presets: no folder defaults
+	folderPresets: [],
+
+	// Language presets: no language defaults
+	languagePresets: {},
+
+	// Settings cascade: folder preset ← frontmatter ← page config ← preset ← block ← shorthand
+	configCascade: [
+		{ layer: 'folder', enabled: true },
+		{ layer: 'frontmatter', enabled: true },
+		{ layer: 'page', enabled: true },
+		{ layer: 'preset', enabled: true },
+		{ layer: 'block', enabled: true },
+		{ layer: 'shorthand', enabled: true },
+	],
 };
@@ -23,15 +23,65 @@ export {
 	VAULT_PREFIX,
 	HTTPS_PREFIX,
 	HTTP_PREFIX,
+	GIT_SOURCE_PATTERN,
 	INLINE_CODE_SEPARATOR,
 	INLINE_CODE_SEPARATOR_END,
+	PLACEHOLDER_PATTERN,
+	CODE_VARIABLE_PATTERN,
+	SECRET_REFERENCE_PATTERN,
+	SECRET_NAME_PATTERN,
+	ENV_VARIABLE_NAME_PATTERN,
+	PRESET_PARAM_PATTERN,
+	REGION_START_PATTERN,
+	REGION_END_PATTERN,
+	SOURCE_REGION_MARKER_PATTERN,
+	FOLD_REGION_START_PATTERN,
+	FOLD_REGION_END_PATTERN,
+	BLOCK_ID_LINE_PATTERN,
+	LINE_LINK_PATTERN,
+	FONT_FEATURE_PATTERN,
 	CSS_PREFIX,
 	CSS_CLASSES,
 	styleClass,
 	LINE_HEIGHT_MULTIPLIER,
+	INDENT_GUIDE_TAB_WIDTH,
+	WORD_DIFF_MAX_TOKENS,
+	BLAME_MAX_WIDTH,
+	BRACKET_COLOUR_COUNT,
+	TERM_COLOUR_COUNT,
+	DIFF_LANGUAGES,
+	AUTO_LANGUAGE,
 	SCROLL_BOTTOM_TOLERANCE,
 	WHATS_NEW_DELAY_MS,
+	COPY_COUNT_SAVE_DELAY_MS,
+	BLOCK_REFRESH_DELAY_MS,
+	GRAMMAR_RELOAD_DELAY_MS,
+	CONTRIBUTION_REFRESH_DELAY_MS,
+	QUERY_REFRESH_DELAY_MS,
+	PROGRESSIVE_LOAD_CHUNK_LINES,
+	MAX_LISTED_LINE,
+	REMOTE_CACHE_FOLDER,
+	SNIPPET_LIBRARY_VIEW_TYPE,
+	SNIPPET_LIBRARY_REFRESH_DELAY_MS,
+	MIN_SOURCE_REFRESH_INTERVAL_MS,
+	MAX_TIMER_DELAY_MS,
+	RUN_OUTPUT_MAX_LENGTH,
+	RUN_CONSENT_STORE_KEY,
+	RUN_KILL_GRACE_MS,
+	SECRET_STORE_KEY,
+	OUTPUT_BLOCK_LANGUAGE,
+	CONFIG_SUGGEST_LOOKBACK_LINES,
+	LINT_REPORT_PATH,
+	PRESET_PACK_FORMAT,
+	PRESET_PACK_VERSION,
+	PRESET_PACK_FILENAME,
+	PRESET_PREVIEW_DELAY_MS,
+	PRESET_PREVIEW_LANGUAGE,
+	PRESET_PREVIEW_CODE,
 	COPY_SUCCESS_DURATION_MS,
+	LINE_LINK_WAIT_MS,
+	LINE_FLASH_DURATION_MS,
+	DEFAULT_COPY_MESSAGE,
 	YAML_SECTIONS,
 	YAML_META,
 	YAML_RENDER_DISPLAY,
@@ -42,7 +92,29 @@ export {
 	YAML_TEXT_STYLE,
 	YAML_CALLOUT,
 	YAML_CALLOUT_ENTRY,
+	YAML_COPY,
+	YAML_COPY_AS_ENTRY,
+	YAML_HIGHLIGHT,
+	YAML_DOWNLOAD,
+	YAML_PRINT,
+	YAML_RUN,
+	YAML_HEADER,
+	YAML_FOOTER,
+	YAML_ANNOTATION,
+	YAML_BLAME,
+	YAML_DIFF,
 	YAML_PROMPT,
+	YAML_PRESET_EXTENDS,
+	YAML_PRESET_PARAMS,
+	YAML_PRESET_WHEN,
+	YAML_PRESET_APPLIES_TO_TAGS,
+	PRESET_CONDITIONS,
+	CONFIG_RENAMES,
+	FRONTMATTER_CONFIG_KEY,
+	YAML_MERGE_KEY,
+	YAML_SCRATCH_KEY_PREFIX,
+	CONFIG_FORMATS,
+	CONFIG_MODES,
 	ICON_IMAGE_EXTENSIONS,
 } from './patterns';
 
@@ -54,3 +126,13 @@ export {
 	getCalloutColor,
 	getCalloutIcon,
 } from './callout-types';
+
+export {
+	COMMENT_SYNTAX,
+	getCommentSyntax,
+} from './comments';
+
+export {
+	REDACTION_MARK,
+	BUILT_IN_REDACTIONS,
+} from './redaction';
@@ -6,6 +6,8 @@
  * reduces the risk of typos.
  */
 
+import type { ConfigFormat, ConfigMode } from '../types';
+
 // =============================================================================
 // Path Prefixes
 // =============================================================================
@@ -25,6 +27,12 @@ export const HTTPS_PREFIX = 'https://';
  */
 export const HTTP_PREFIX = 'http://';
 
+/**
+ * A file in a configured git checkout, at a ref (repository:path@ref).
+ * Groups are the repository name, the path and the ref.
+ */
+export const GIT_SOURCE_PATTERN = /^([A-Za-z][\w.-]*):(?!\/\/)(.+)@([^@]+)$/;
+
 // =============================================================================
 // Block Separators
 // =============================================================================
@@ -39,6 +47,91 @@ export const INLINE_CODE_SEPARATOR = '\n~~~\n';
  */
 export const INLINE_CODE_SEPARATOR_END = '\n~~~';
 
+// =============================================================================
+// Placeholders
+// =============================================================================
+
+/**
+ * Copy-time placeholder, e.g. {{HOSTNAME}}. Group 1 is the name.
+ */
+export const PLACEHOLDER_PATTERN = /\{\{\s*([A-Za-z_][\w-]*)\s*\}\}/g;
+
+/**
+ * Code variable, e.g. {{fm.cluster}}, {{var.registry}} or {{date:YYYY-MM}}.
+ * Group 1 is the name, group 2 the date or time format.
+ */
+export const CODE_VARIABLE_PATTERN = /\{\{\s*(date|time|fm\.[\w-]+(?:\.[\w-]+)*|var\.[\w.-]+)\s*(?::([^}]*))?\}\}/g;
+
+/**
+ * Secret reference in a RUN.ENV value, e.g. {{secret.prod-token}}.
+ * Group 1 is the secret's name.
+ */
+export const SECRET_REFERENCE_PATTERN = /\{\{\s*secret\.([\w.-]+)\s*\}\}/g;
+
+/** Names a secret may have (those a reference can name). */
+export const SECRET_NAME_PATTERN = /^[\w.-]+$/;
+
+/** Names an environment variable may have in RUN.ENV. */
+export const ENV_VARIABLE_NAME_PATTERN = /^[A-Za-z_][A-Za-z0-9_]*$/;
+
+/**
+ * Preset parameter reference, e.g. ${title}. Group 1 is the name.
+ */
+export const PRESET_PARAM_PATTERN = /\$\{\s*([A-Za-z_][\w-]*)\s*\}/g;
+
+// =============================================================================
+// Regions
+// =============================================================================
+
+/**
+ * Start of a named copy region, e.g. `# region: setup`. Group 1 is the name.
+ * Accepts the common comment markers (#, //, --, ;, %, <!-- and /*).
+ */
+export const REGION_START_PATTERN = /^\s*(?:#|\/\/|--|;|%|<!--|\/\*)\s*region:\s*(.*?)\s*(?:-->|\*\/)?\s*$/i;
+
+/**
+ * End of the innermost open region, e.g. `# endregion`.
+ */
+export const REGION_END_PATTERN = /^\s*(?:#|\/\/|--|;|%|<!--|\/\*)\s*endregion\b/i;
+
+/**
+ * Marker of a named region in a source file for FILTER.BY_REGION, e.g.
+ * `// ufence:begin setup` … `// ufence:end setup`, in any comment syntax.
+ * Group 1 is "begin" or "end", group 2 the name.
+ */
+export const SOURCE_REGION_MARKER_PATTERN = /\bufence:(begin|end)\s+([\w.-]+)/i;
+
+/**
+ * Start of a fold region, e.g. `#region setup`, `// #region`, `#pragma region`
+ * or `<!-- #region -->`. The `#` is required, so the `# region: name`
+ * markers of region copy buttons don't fold. Group 1 is the (optional) name.
+ */
+export const FOLD_REGION_START_PATTERN = /^\s*(?:(?:#|\/\/|--|;|%|'|<!--|\/\*)\s*)?#(?:pragma\s+)?region\b:?\s*(.*?)\s*(?:-->|\*\/)?\s*$/i;
+
+/**
+ * End of the innermost open fold region, e.g. `#endregion`, `#End Region`
+ * or `#pragma endregion`.
+ */
+export const FOLD_REGION_END_PATTERN = /^\s*(?:(?:#|\/\/|--|;|%|'|<!--|\/\*)\s*)?#(?:pragma\s+)?end\s?region\b/i;
+
+/**
+ * An Obsidian block ID on its own line after a fence, e.g. `^setup-script`.
+ * Group 1 is the ID.
+ */
+export const BLOCK_ID_LINE_PATTERN = /^\s*\^([A-Za-z0-9-]+)\s*$/;
+
+/**
+ * Link subpath addressing lines of a block, e.g. `^setup:L42` or
+ * `^setup:L40-L45`. Groups: block ID, first line, last line.
+ */
+export const LINE_LINK_PATTERN = /^\^([A-Za-z0-9-]+):L(\d+)(?:-L?(\d+))?$/i;
+
+/**
+ * One OpenType feature in RENDER.FONT_FEATURES, e.g. `ss01`, `cv02=3` or
+ * `-calt` (off). Groups: minus sign, tag, value.
+ */
+export const FONT_FEATURE_PATTERN = /^(-)?([A-Za-z0-9]{4})(?:\s*=\s*(\d+))?$/;
+
 // =============================================================================
 // CSS Classes
 // =============================================================================
@@ -63,6 +156,9 @@ export const CSS_CLASSES = {
 	titleLeft: 'ucf-left',
 	titleMeta: 'ucf-meta',
 	titleText: 'ucf-text',
+	languageBadge: 'ucf-language-badge',
+	languageBadgeDetected: 'ucf-language-badge-detected',
+	headerButtons: 'ucf-header-buttons',
 
 	// Icon classes
 	icon: 'ucf-icon',
@@ -71,15 +167,20 @@ export const CSS_CLASSES = {
 	iconText: 'ucf-icon-text',
 	iconCustom: 'ucf-icon-custom',
 	iconImg: 'ucf-icon-img',
+	iconLucide: 'ucf-icon-lucide',
 
 	// Feature classes
 	linkIndicator: 'ucf-link-indicator',
 	copyButton: 'ucf-copy-button',
 	copied: 'ucf-copied',
 	downloadButton: 'ucf-download-button',
+	editButton: 'ucf-edit-button',
 	foldBar: 'ucf-fold-bar',
 	foldButton: 'ucf-fold-button',
 	folded: 'ucf-folded',
+	collapseBar: 'ucf-collapse-bar',
+	collapseButton: 'ucf-collapse-button',
+	collapsed: 'ucf-collapsed',
 
 	// Line formatting
 	line: 'ucf-line',
@@ -87,7 +188,66 @@ export const CSS_CLASSES = {
 	lineNum: 'ucf-line-num',
 	lineContent: 'ucf-line-content',
 	lineNumbers: 'ucf-line-numbers',
+	lineWrapped: 'ucf-line-wrapped',
+	lineCopyButton: 'ucf-line-copy-button',
+	lineCopySpacer: 'ucf-line-copy-spacer',
+	copyAsButton: 'ucf-copy-as-button',
+	prompt: 'ucf-prompt',
+	lineHighlight: 'ucf-line-highlight',
+	lineChanged: 'ucf-line-changed',
+	term: 'ucf-term',
+	termColour: 'ucf-term-',
+	focus: 'ucf-focus',
+	lineFocused: 'ucf-line-focused',
+	focusRevealed: 'ucf-focus-revealed',
+	lineAnchor: 'ucf-line-anchor',
+	placeholder: 'ucf-placeholder',
+	redacted: 'ucf-redacted',
+	copyCount: 'ucf-copy-count',
+	wrapButton: 'ucf-wrap-button',
+	focusButton: 'ucf-focus-button',
+	softWrapped: 'ucf-soft-wrapped',
 	zebra: 'ucf-zebra',
+	lineHover: 'ucf-line-hover',
+	fontFeatures: 'ucf-font-features',
+	bidi: 'ucf-bidi',
+	printLight: 'ucf-print-light',
+	printAvoidBreaks: 'ucf-print-avoid-breaks',
+	printLineNum: 'ucf-print-line-num',
+	fontFace: 'ucf-font-face',
+	highlightTheme: 'ucf-highlight-theme',
+	patternStyle: 'ucf-pattern-style',
+	embeddedHost: 'ucf-embedded-host',
+	embeddedCode: 'ucf-embedded-code',
+
+	// Region copy buttons
+	regionBar: 'ucf-region-bar',
+	regionButton: 'ucf-region-button',
+
+	// Fold regions
+	foldRegionStart: 'ucf-fold-region-start',
+	foldRegionToggle: 'ucf-fold-region-toggle',
+	foldRegionCollapsed: 'ucf-fold-region-collapsed',
+	foldRegionHidden: 'ucf-fold-region-hidden',
+
+	// Indentation guides
+	indentGuides: 'ucf-indent-guides',
+	indentGuide: 'ucf-indent-guide',
+	indentGuideActive: 'ucf-indent-guide-active',
+	rulers: 'ucf-rulers',
+	ruler: 'ucf-ruler',
+
+	// Whitespace visualisation
+	whitespaceSpace: 'ucf-ws-space',
+	whitespaceTab: 'ucf-ws-tab',
+	whitespaceTrailing: 'ucf-ws-trailing',
+
+	// Minimap
+	minimapFrame: 'ucf-minimap-frame',
+	minimap: 'ucf-minimap',
+	minimapLine: 'ucf-minimap-line',
+	minimapHighlight: 'ucf-minimap-highlight',
+	minimapViewport: 'ucf-minimap-viewport',
 
 	// Scrolling
 	scrollable: 'ucf-scrollable',
@@ -101,6 +261,73 @@ export const CSS_CLASSES = {
 	tooltipContent: 'ucf-tooltip-content',
 	hasTooltip: 'has-tooltip',
 
+	// Attribution footer
+	footer: 'ucf-footer',
+	footerLink: 'ucf-footer-link',
+
+	// Status line of a block loaded from a URL
+	remoteStatus: 'ucf-remote-status',
+	remoteStatusStale: 'ucf-remote-status-stale',
+	remoteRefresh: 'ucf-remote-refresh',
+
+	// Line under a partly loaded long file
+	loadMore: 'ucf-load-more',
+	loadMoreButton: 'ucf-load-more-button',
+
+	// Running a shell block
+	runButton: 'ucf-run-button',
+	runOutput: 'ucf-run-output',
+	runOutputStatus: 'ucf-run-output-status',
+	runOutputFailed: 'ucf-run-output-failed',
+	runOutputText: 'ucf-run-output-text',
+	runOutputStderr: 'ucf-run-output-stderr',
+	runOutputClear: 'ucf-run-output-clear',
+	runOutputStop: 'ucf-run-output-stop',
+	runOutputSaved: 'ucf-run-output-saved',
+	lineRunButton: 'ucf-line-run-button',
+	lineRunSpacer: 'ucf-line-run-spacer',
+	lineRunCurrent: 'ucf-line-run-current',
+	lineRunStatus: 'ucf-line-run-status',
+	lineRunFailed: 'ucf-line-run-failed',
+	runConsentModal: 'ucf-run-consent-modal',
+
+	// Diff rendering
+	diff: 'ucf-diff',
+	diffGutter: 'ucf-diff-gutter',
+	diffMarker: 'ucf-diff-marker',
+	diffAdded: 'ucf-diff-added',
+	diffRemoved: 'ucf-diff-removed',
+	diffHunk: 'ucf-diff-hunk',
+	diffHeader: 'ucf-diff-header',
+	diffWordAdded: 'ucf-diff-word-added',
+	diffWordRemoved: 'ucf-diff-word-removed',
+	splitDiff: 'ucf-split-diff',
+	splitDiffActive: 'ucf-split-diff-active',
+	splitDiffSide: 'ucf-split-diff-side',
+	splitDiffFiller: 'ucf-split-diff-filler',
+
+	// Gutter annotation icons
+	annotationGutter: 'ucf-annotation-gutter',
+	annotation: 'ucf-annotation',
+	commentKeyword: 'ucf-comment-keyword',
+	commentKeywordIcon: 'ucf-comment-keyword-icon',
+	bracket: 'ucf-bracket',
+	bracketDepth: 'ucf-bracket-depth-',
+	bracketUnmatched: 'ucf-bracket-unmatched',
+	bracketMatch: 'ucf-bracket-match',
+	semantic: 'ucf-semantic',
+
+	// Line links
+	lineLinks: 'ucf-line-links',
+	lineFlash: 'ucf-line-flash',
+
+	// Blame column
+	blame: 'ucf-blame',
+	blameStart: 'ucf-blame-start',
+	blameAuthor: 'ucf-blame-author',
+	blameDate: 'ucf-blame-date',
+	blameTicket: 'ucf-blame-ticket',
+
 	// Callout classes
 	calloutInline: 'ucf-callout-inline',
 	calloutSection: 'ucf-callout-section',
@@ -120,6 +347,7 @@ export const CSS_CLASSES = {
 	cmdoutPrompt: 'ucf-cmdout-prompt',
 	cmdoutCommand: 'ucf-cmdout-command',
 	cmdoutOutput: 'ucf-cmdout-output',
+	cmdoutAnsi: 'ucf-cmdout-ansi',
 
 	// Settings
 	settings: 'ucf-settings',
@@ -143,6 +371,62 @@ export const CSS_CLASSES = {
 	highlight: 'ucf-highlight',
 	credits: 'ucf-credits',
 	modalButtons: 'ucf-modal-buttons',
+
+	// Editing a block's embedded file
+	sourceEditModal: 'ucf-source-edit-modal',
+	sourceEditInput: 'ucf-source-edit-input',
+	sourceEditSummary: 'ucf-source-edit-summary',
+
+	// Placeholder prompt
+	placeholderModal: 'ucf-placeholder-modal',
+	placeholderField: 'ucf-placeholder-field',
+
+	// Clipboard history
+	historyItem: 'ucf-history-item',
+	historyText: 'ucf-history-text',
+	historyDetails: 'ucf-history-details',
+
+	// Snippet library view and picker
+	snippetLibrary: 'ucf-snippet-library',
+	snippetSearch: 'ucf-snippet-search',
+	snippetList: 'ucf-snippet-list',
+	snippetItem: 'ucf-snippet-item',
+	snippetTitle: 'ucf-snippet-title',
+	snippetDetails: 'ucf-snippet-details',
+	snippetActions: 'ucf-snippet-actions',
+	snippetEmpty: 'ucf-snippet-empty',
+
+	// Block configuration warnings
+	configWarnings: 'ucf-config-warnings',
+	configDiagnostics: 'ucf-config-diagnostics',
+	configDiagnosticsHeading: 'ucf-config-diagnostics-heading',
+
+	// Settings autocomplete
+	suggestDetail: 'ucf-suggest-detail',
+
+	// Renamed settings notice
+	configRenames: 'ucf-config-renames',
+
+	// Settings migration preview
+	migrationModal: 'ucf-migration-modal',
+	migrationNote: 'ucf-migration-note',
+	migrationLine: 'ucf-migration-line',
+
+	// Settings inspector
+	inspectModal: 'ucf-inspect-modal',
+	inspectTable: 'ucf-inspect-table',
+	inspectLayer: 'ucf-inspect-layer',
+
+	// Preset import and export
+	presetTransferModal: 'ucf-preset-transfer-modal',
+	presetTransferList: 'ucf-preset-transfer-list',
+	presetTransferConflict: 'ucf-preset-transfer-conflict',
+
+	// Preset gallery
+	presetGalleryModal: 'ucf-preset-gallery-modal',
+	presetGalleryEntry: 'ucf-preset-gallery-entry',
+	presetGalleryDescription: 'ucf-preset-gallery-description',
+	presetGalleryPreview: 'ucf-preset-gallery-preview',
 } as const;
 
 // =============================================================================
@@ -168,6 +452,42 @@ export function styleClass(style: string): string {
  */
 export const LINE_HEIGHT_MULTIPLIER = 1.4;
 
+/**
+ * Block languages rendered as unified diffs.
+ */
+export const DIFF_LANGUAGES = ['diff', 'patch'];
+
+/**
+ * Block language that asks for the language to be guessed from the code.
+ */
+export const AUTO_LANGUAGE = 'auto';
+
+/**
+ * Columns per tab stop when measuring indentation for indent guides,
+ * when the block's tab width isn't given.
+ */
+export const INDENT_GUIDE_TAB_WIDTH = 4;
+
+/**
+ * Most words a changed line can have and still be compared word by word.
+ */
+export const WORD_DIFF_MAX_TOKENS = 200;
+
+/**
+ * Widest the blame column grows, in characters; longer entries are cut off.
+ */
+export const BLAME_MAX_WIDTH = 36;
+
+/**
+ * Colours bracket pairs cycle through by depth (ucf-bracket-depth-1 to -3).
+ */
+export const BRACKET_COLOUR_COUNT = 3;
+
+/**
+ * Colours HIGHLIGHT.TERMS cycle through, one per term (ucf-term-1 to -4).
+ */
+export const TERM_COLOUR_COUNT = 4;
+
 /**
  * Tolerance in pixels for "at bottom" scroll detection.
  */
@@ -178,11 +498,194 @@ export const SCROLL_BOTTOM_TOLERANCE = 5;
  */
 export const WHATS_NEW_DELAY_MS = 1000;
 
+/**
+ * Delay in milliseconds before saving copy counts, so bursts of copies
+ * are written to disk once.
+ */
+export const COPY_COUNT_SAVE_DELAY_MS = 2000;
+
+/**
+ * Delay in milliseconds after the last keystroke before blocks affected
+ * by an edit are re-rendered.
+ */
+export const BLOCK_REFRESH_DELAY_MS = 300;
+
+/**
+ * Delay in milliseconds after the last change to the grammar folder (or
+ * its setting) before custom grammars are reloaded.
+ */
+export const GRAMMAR_RELOAD_DELAY_MS = 1000;
+
+/**
+ * Delay in milliseconds after the last contribution from another plugin
+ * before blocks are re-rendered, so a plugin registering several things
+ * at load causes one refresh.
+ */
+export const CONTRIBUTION_REFRESH_DELAY_MS = 200;
+
+/**
+ * Delay in milliseconds after the last change to a note before blocks
+ * built from a query (META.QUERY) are re-rendered, so typing in a note
+ * doesn't rerun every query on each keystroke.
+ */
+export const QUERY_REFRESH_DELAY_MS = 2000;
+
+/**
+ * Shortest interval in milliseconds a block re-reads its embedded file
+ * at (META.REFRESH), so a typo like "1" doesn't hammer a server.
+ */
+export const MIN_SOURCE_REFRESH_INTERVAL_MS = 5000;
+
+/**
+ * Longest delay a timer takes (2^31 - 1 ms, about 24.8 days). setTimeout
+ * treats anything longer as 0 and fires at once.
+ */
+export const MAX_TIMER_DELAY_MS = 2147483647;
+
+/**
+ * Lines each click of "load next lines" adds to a long embedded file
+ * shown in part.
+ */
+export const PROGRESSIVE_LOAD_CHUNK_LINES = 500;
+
+/**
+ * Highest line number a line list (HIGHLIGHT.LINES, DIFF.ADDED, …) takes,
+ * so a range like "1-999999999" can't stall rendering.
+ */
+export const MAX_LISTED_LINE = 100000;
+
+/**
+ * Most characters of output a run keeps under its block. Past this the
+ * rest is dropped, so a runaway command can't fill the note.
+ */
+export const RUN_OUTPUT_MAX_LENGTH = 200000;
+
+/**
+ * Local storage key of the user's consent to running code in the vault.
+ * Kept out of the plugin settings so that it isn't synced to, or
+ * committed with, other copies of the vault.
+ */
+export const RUN_CONSENT_STORE_KEY = 'ultra-code-fence-run-consent';
+
+/**
+ * How long a killed run's processes are given to stop before they are
+ * forced to.
+ */
+export const RUN_KILL_GRACE_MS = 2000;
+
+/**
+ * Local storage key of the vault's secrets. Local storage is per vault
+ * and per device, and is kept outside the vault folder.
+ */
+export const SECRET_STORE_KEY = 'ultra-code-fence-secrets';
+
+/**
+ * Language of the block a run saves its output in (RUN.SAVE), just
+ * after the block that was run.
+ */
+export const OUTPUT_BLOCK_LANGUAGE = 'ufence-output';
+
+/**
+ * Folder in the plugin's own directory where copies of remote files are
+ * cached.
+ */
+export const REMOTE_CACHE_FOLDER = 'remote-cache';
+
+/**
+ * View type of the snippet library sidebar.
+ */
+export const SNIPPET_LIBRARY_VIEW_TYPE = 'ufence-snippet-library';
+
+/**
+ * Delay in milliseconds after the last change to a note before an open
+ * snippet library is updated.
+ */
+export const SNIPPET_LIBRARY_REFRESH_DELAY_MS = 1000;
+
+/**
+ * How long in milliseconds a followed line link waits for its block to
+ * render in the opened note.
+ */
+export const LINE_LINK_WAIT_MS = 5000;
+
+/**
+ * How many lines above the cursor settings autocomplete searches for the
+ * opening fence of a ufence block.
+ */
+export const CONFIG_SUGGEST_LOOKBACK_LINES = 200;
+
+/**
+ * Note the vault-wide code block check writes its report to.
+ */
+export const LINT_REPORT_PATH = 'Code block check.md';
+
+/**
+ * Identifies a preset pack file (exported presets).
+ */
+export const PRESET_PACK_FORMAT = 'ultra-code-fence-presets';
+
+/**
+ * Version of the preset pack format written by this release.
+ */
+export const PRESET_PACK_VERSION = 1;
+
+/**
+ * Suggested filename for exported presets.
+ */
+export const PRESET_PACK_FILENAME = 'ufence-presets.json';
+
+/**
+ * Delay in milliseconds after the last keystroke in a preset editor
+ * before its preview is rendered again.
+ */
+export const PRESET_PREVIEW_DELAY_MS = 300;
+
+/**
+ * Language of the preset preview's sample code, unless the preset sets
+ * RENDER.LANG.
+ */
+export const PRESET_PREVIEW_LANGUAGE = 'bash';
+
+/**
+ * Sample code shown in the preset preview.
+ */
+export const PRESET_PREVIEW_CODE = [
+	'#!/usr/bin/env bash',
+	'# Build and deploy the site',
+	'set -euo pipefail',
+	'',
+	'npm ci',
+	'npm run build',
+	'',
+	'rsync -az dist/ deploy@example.com:/var/www/site',
+	'echo "Deployed {{VERSION}}"',
+].join('\n');
+
 /**
  * Duration in milliseconds for copy button success state.
  */
 export const COPY_SUCCESS_DURATION_MS = 2000;
 
+/**
+ * Duration in milliseconds of the flash on a line reached by a link.
+ */
+export const LINE_FLASH_DURATION_MS = 1500;
+
+/**
+ * Default confirmation text for copy toasts and the status bar.
+ */
+export const DEFAULT_COPY_MESSAGE = 'Copied to clipboard';
+
+/**
+ * Settings formats that can be named in a ufence fence info string.
+ */
+export const CONFIG_FORMATS: readonly ConfigFormat[] = ['yaml', 'toml', 'json'];
+
+/**
+ * Values accepted by META.MODE.
+ */
+export const CONFIG_MODES: readonly ConfigMode[] = ['strict', 'lenient'];
+
 // =============================================================================
 // YAML Section Names (Nested Structure)
 // =============================================================================
@@ -195,6 +698,18 @@ export const YAML_SECTIONS = {
 	render: 'RENDER',
 	filter: 'FILTER',
 	callout: 'CALLOUT',
+	copy: 'COPY',
+	highlight: 'HIGHLIGHT',
+	download: 'DOWNLOAD',
+	header: 'HEADER',
+	footer: 'FOOTER',
+	annotations: 'ANNOTATIONS',
+	blame: 'BLAME',
+	diff: 'DIFF',
+	print: 'PRINT',
+	tokens: 'TOKENS',
+	patterns: 'PATTERNS',
+	run: 'RUN',
 } as const;
 
 /**
@@ -202,9 +717,16 @@ export const YAML_SECTIONS = {
  */
 export const YAML_META = {
 	path: 'PATH',
+	src: 'SRC',
+	query: 'QUERY',
+	refresh: 'REFRESH',
+	ref: 'REF',
 	title: 'TITLE',
 	desc: 'DESC',
 	preset: 'PRESET',
+	mode: 'MODE',
+	summary: 'SUMMARY',
+	id: 'ID',
 } as const;
 
 /**
@@ -223,6 +745,37 @@ export const YAML_RENDER_DISPLAY = {
 	cmdCopyJoin: 'CMD_COPY_JOIN',
 	joinIgnoreRegex: 'JOIN_IGNORE_REGEX',
 	print: 'PRINT',
+	lineCopy: 'LINE_COPY',
+	lineStart: 'LINE_START',
+	lineAnchor: 'LINE_ANCHOR',
+	foldRegions: 'FOLD_REGIONS',
+	foldStart: 'FOLD_START',
+	foldEnd: 'FOLD_END',
+	collapsed: 'COLLAPSED',
+	maxHeight: 'MAX_HEIGHT',
+	indentGuides: 'INDENT_GUIDES',
+	indentGuideColour: 'INDENT_GUIDE_COLOUR',
+	indentGuideStyle: 'INDENT_GUIDE_STYLE',
+	whitespace: 'WHITESPACE',
+	commentKeywords: 'COMMENT_KEYWORDS',
+	brackets: 'BRACKETS',
+	semantic: 'SEMANTIC',
+	changedSince: 'CHANGED_SINCE',
+	minimap: 'MINIMAP',
+	zebraColour: 'ZEBRA_COLOUR',
+	hover: 'HOVER',
+	ligatures: 'LIGATURES',
+	fontFeatures: 'FONT_FEATURES',
+	fontFamily: 'FONT_FAMILY',
+	fontSize: 'FONT_SIZE',
+	tabSize: 'TAB_SIZE',
+	ruler: 'RULER',
+	rulerColour: 'RULER_COLOUR',
+	ansi: 'ANSI',
+	ansiCopy: 'ANSI_COPY',
+	direction: 'DIRECTION',
+	theme: 'THEME',
+	variables: 'VARIABLES',
 } as const;
 
 /**
@@ -231,6 +784,7 @@ export const YAML_RENDER_DISPLAY = {
 export const YAML_FILTER = {
 	byLines: 'BY_LINES',
 	byMarks: 'BY_MARKS',
+	byRegion: 'BY_REGION',
 } as const;
 
 /**
@@ -291,11 +845,183 @@ export const YAML_CALLOUT_ENTRY = {
 	type: 'TYPE',
 } as const;
 
+/**
+ * COPY section property names.
+ */
+export const YAML_COPY = {
+	as: 'AS',
+	placeholders: 'PLACEHOLDERS',
+	variables: 'VARIABLES',
+	stripComments: 'STRIP_COMMENTS',
+	redact: 'REDACT',
+	feedback: 'FEEDBACK',
+	toast: 'TOAST',
+	feedbackDuration: 'FEEDBACK_DURATION',
+} as const;
+
+/**
+ * COPY.AS subsection property names.
+ */
+export const YAML_COPY_AS_ENTRY = {
+	label: 'LABEL',
+	format: 'FORMAT',
+	target: 'TARGET',
+	schedule: 'SCHEDULE',
+} as const;
+
+/**
+ * HIGHLIGHT section property names.
+ */
+export const YAML_HIGHLIGHT = {
+	lines: 'LINES',
+	match: 'MATCH',
+	focus: 'FOCUS',
+	focusToggle: 'FOCUS_TOGGLE',
+	terms: 'TERMS',
+} as const;
+
+/**
+ * DOWNLOAD section property names.
+ */
+export const YAML_DOWNLOAD = {
+	filename: 'FILENAME',
+	shebang: 'SHEBANG',
+	executable: 'EXECUTABLE',
+} as const;
+
+/**
+ * PRINT section property names (how a block prints or exports to PDF).
+ */
+export const YAML_PRINT = {
+	expand: 'EXPAND',
+	theme: 'THEME',
+	lines: 'LINES',
+	avoidBreaks: 'AVOID_BREAKS',
+} as const;
+
+/**
+ * RUN section property names (running a block from the note).
+ */
+export const YAML_RUN = {
+	enabled: 'ENABLED',
+	save: 'SAVE',
+	lines: 'LINES',
+	timeout: 'TIMEOUT',
+	env: 'ENV',
+} as const;
+
+/**
+ * HEADER section property names.
+ */
+export const YAML_HEADER = {
+	icon: 'ICON',
+	title: 'TITLE',
+	language: 'LANGUAGE',
+	buttons: 'BUTTONS',
+} as const;
+
+/**
+ * FOOTER section property names.
+ */
+export const YAML_FOOTER = {
+	source: 'SOURCE',
+	url: 'URL',
+	license: 'LICENSE',
+} as const;
+
+/**
+ * DIFF section property names.
+ */
+export const YAML_DIFF = {
+	added: 'ADDED',
+	removed: 'REMOVED',
+	copy: 'COPY',
+	words: 'WORDS',
+	view: 'VIEW',
+	old: 'OLD',
+	new: 'NEW',
+} as const;
+
+/**
+ * Property names of an ANNOTATIONS entry written as a mapping.
+ */
+export const YAML_ANNOTATION = {
+	type: 'TYPE',
+	text: 'TEXT',
+} as const;
+
+/**
+ * Property names of a BLAME entry written as a mapping.
+ */
+export const YAML_BLAME = {
+	author: 'AUTHOR',
+	date: 'DATE',
+	ticket: 'TICKET',
+} as const;
+
 /**
  * Top-level PROMPT property (for cmdout blocks).
  */
 export const YAML_PROMPT = 'PROMPT';
 
+/**
+ * Top-level EXTENDS property (presets only): the preset or presets whose
+ * settings this preset builds on.
+ */
+export const YAML_PRESET_EXTENDS = 'EXTENDS';
+
+/**
+ * Top-level PARAMS property (presets only): parameters blocks can set when
+ * naming the preset, with their default values.
+ */
+export const YAML_PRESET_PARAMS = 'PARAMS';
+
+/**
+ * Top-level WHEN property (presets only): settings that apply only in a
+ * theme or on a platform, keyed by condition (e.g. "dark", "dark mobile").
+ */
+export const YAML_PRESET_WHEN = 'WHEN';
+
+/**
+ * Top-level APPLIES_TO_TAGS property (presets only): note tags whose
+ * notes use the preset by default.
+ */
+export const YAML_PRESET_APPLIES_TO_TAGS = 'APPLIES_TO_TAGS';
+
+/**
+ * Conditions a WHEN entry can name. A key may combine several, separated
+ * by spaces, and applies when all of them hold.
+ */
+export const PRESET_CONDITIONS = ['dark', 'light', 'mobile', 'desktop'] as const;
+
+/**
+ * Settings keys renamed between releases: old dotted path → new key name.
+ *
+ * Paths name mapping keys (not list entries), and a rename only changes
+ * the last key of the path. Old keys keep working, but blocks that use
+ * them show a notice with a one-click update.
+ */
+export const CONFIG_RENAMES: Record<string, string> = {
+	DISPLAY: 'RENDER',
+	STYLE: 'RENDER',
+};
+
+/**
+ * Frontmatter key whose mapping sets defaults for every block in a note.
+ */
+export const FRONTMATTER_CONFIG_KEY = 'ufence';
+
+/**
+ * YAML merge key: `<<: *anchor` copies the anchored mapping's keys.
+ */
+export const YAML_MERGE_KEY = '<<';
+
+/**
+ * Top-level keys starting with this prefix are ignored, so they can hold
+ * anchored values for reuse elsewhere in the block (e.g. `_colours: &c`).
+ */
+export const YAML_SCRATCH_KEY_PREFIX = '_';
+
 // =============================================================================
 // Supported Image Extensions
 // =============================================================================
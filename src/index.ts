@@ -19,7 +19,28 @@ export type {
 	ReleaseNotesData,
 	ChangelogItem,
 	CommandOutputStyles,
+	HighlightTheme,
+	LineAnnotation,
+	ResolvedCopyAsEntry,
 } from './types';
 
+// Re-export the API other plugins contribute through
+export type {
+	UltraCodeFenceApi,
+	AnnotationContext,
+	AnnotationProvider,
+	ContributedAnnotation,
+	Disposer,
+	CodeRunner,
+	RunContext,
+	RunRequest,
+	ContributedRunResult,
+	OutputStream,
+	PrismGrammar,
+	PrismTokenRule,
+} from './services';
+
+export type { CopyTransform } from './utils';
+
 // Re-export constants for extension
 export { DEFAULT_SETTINGS } from './constants';
@@ -8,13 +8,41 @@
  * All heavy lifting is delegated to specialised modules in the src folder.
  */
 
-import { Component, Plugin, MarkdownRenderer, MarkdownPostProcessorContext, MarkdownView, TFile, parseYaml } from 'obsidian';
+import { Component, Editor, Menu, Notice, Platform, Plugin, MarkdownRenderer, MarkdownPostProcessorContext, MarkdownView, TFile, debounce, getAllTags, loadPrism, normalizePath } from 'obsidian';
+import type { CachedMetadata, TAbstractFile, WorkspaceLeaf } from 'obsidian';
 
 // Types
-import type { PluginSettings, TitleBarStyle, SourceFileMetadata, ParsedYamlConfig } from './types';
+import type { PluginSettings, GitRepository, TitleBarStyle, SourceFileMetadata, ParsedYamlConfig, ResolvedBlockConfig, CopyFeedbackConfig, HighlightTheme, ConfigFormat } from './types';
+import type { CodeButtonOptions, PlaceholderFiller } from './renderers';
+import type { LineLink, CodeVariableContext } from './utils';
+import type { FenceLintNoteResult, ConfigMigrationPlan, UfenceBlock, PresetConflictAction, PrismLanguages, PrismTokenizer, HighlighterBackend, UltraCodeFenceApi, RemoteSourceStatus, CachedRemoteLoadResult, Snippet, OutputStream, RunResult, RunControlOptions, RunEnvironment } from './services';
 
 // Constants
-import { DEFAULT_SETTINGS, WHATS_NEW_DELAY_MS } from './constants';
+import {
+	DEFAULT_SETTINGS,
+	DEFAULT_COPY_MESSAGE,
+	WHATS_NEW_DELAY_MS,
+	COPY_COUNT_SAVE_DELAY_MS,
+	BLOCK_REFRESH_DELAY_MS,
+	GRAMMAR_RELOAD_DELAY_MS,
+	CONTRIBUTION_REFRESH_DELAY_MS,
+	QUERY_REFRESH_DELAY_MS,
+	REMOTE_CACHE_FOLDER,
+	LINE_LINK_WAIT_MS,
+	PRESET_PREVIEW_LANGUAGE,
+	PRESET_PREVIEW_CODE,
+	FRONTMATTER_CONFIG_KEY,
+	LINT_REPORT_PATH,
+	PRESET_PACK_FILENAME,
+	CSS_CLASSES,
+	AUTO_LANGUAGE,
+	PROGRESSIVE_LOAD_CHUNK_LINES,
+	SNIPPET_LIBRARY_VIEW_TYPE,
+	SNIPPET_LIBRARY_REFRESH_DELAY_MS,
+	RUN_OUTPUT_MAX_LENGTH,
+	OUTPUT_BLOCK_LANGUAGE,
+	getCommentSyntax,
+} from './constants';
 
 // Parsers
 import {
@@ -22,8 +50,16 @@ import {
 	parseNestedYamlConfig,
 	resolveBlockConfig,
 	resolveCmdoutConfig,
+	resolveCopyFeedback,
+	detectConfigFormat,
+	parseConfigFormatFromInfoString,
+	parseYamlSettings,
+	parseInfoStringOptions,
+	parseFrontmatterConfig,
 	applyFilterChain,
+	replaceLineRange,
 	resolveCalloutConfig,
+	parseSourceReference,
 } from './parsers';
 
 // Services
@@ -31,8 +67,78 @@ import {
 	loadSource,
 	createEmbeddedCodeMetadata,
 	isRemotePath,
+	isVaultPath,
+	detectSourceLocationType,
+	normalizeGitRepositories,
+	gitRepositoryPaths,
+	RemoteSourceCache,
+	loadCachedRemoteFile,
 	buildSuggestedFilename,
 	downloadCodeToFile,
+	injectShebang,
+	saveExecutableFile,
+	ClipboardHistory,
+	buildCopyUsageKey,
+	findCopyUsageKeys,
+	copyUsageNotePath,
+	pruneCopyUsage,
+	moveCopyUsage,
+	getCopyCount,
+	incrementCopyCount,
+	lintNoteContent,
+	buildLintReport,
+	planNoteMigration,
+	getUndoContent,
+	findUfenceBlocks,
+	diffUfenceBlocks,
+	readBlockSettings,
+	readReferenceId,
+	mergeReferencedSettings,
+	BlockReferenceIndex,
+	SnippetLibrary,
+	buildSnippetReference,
+	buildPresetPack,
+	parsePresetPack,
+	importPresets,
+	convertVSCodeTheme,
+	highlightThemeKey,
+	findInstalledGalleryPreset,
+	installGalleryPreset,
+	loadCustomGrammars,
+	registerCustomGrammars,
+	registerLanguageAliases,
+	isInGrammarFolder,
+	findLanguageInjections,
+	isBundledGrammar,
+	registerBundledGrammars,
+	pickHighlighterEngine,
+	readShikiModule,
+	createShikiBackend,
+	ContributionRegistry,
+	parseSnapshotAge,
+	pickSnapshot,
+	matchSnapshotBlock,
+	findChangedLines,
+	readFileRecoverySnapshots,
+	isDataviewQuery,
+	parseCodeQuery,
+	runCodeQuery,
+	runDataviewQuery,
+	formatCodeQueryMatches,
+	formatComment,
+	isShellLanguage,
+	isScriptLanguage,
+	prepareScript,
+	runScript,
+	loadRunConsent,
+	saveRunConsent,
+	loadSecrets,
+	saveSecrets,
+	resolveRunEnvironment,
+	maskSecrets,
+	resolveShellInvocation,
+	runShellInvocation,
+	loadDesktopShell,
 } from './services';
 
 // Renderers
@@ -41,20 +147,57 @@ import {
 	countSourceLines,
 	wrapPreElement,
 	addCodeBlockButtons,
+	addCollapseToggle,
+	moveButtonsToHeader,
 	buildTitleContainer,
 	renderCommandOutput,
 	injectCallouts,
+	createFooterElement,
+	addMinimap,
+	addSplitDiffView,
+	addLineLinks,
+	revealLines,
+	appendPrismTokens,
+	highlightEmbeddedLanguages,
+	addSemanticTokens,
+	createRemoteStatusElement,
+	createLoadMoreElement,
+	createRunOutputPanel,
+	describeRunResult,
+	renderSavedRunOutput,
 } from './renderers';
 
 // UI
-import { UltraCodeFenceSettingTab, WhatsNewModal } from './ui';
+import {
+	UltraCodeFenceSettingTab,
+	WhatsNewModal,
+	ClipboardHistoryModal,
+	SourceEditModal,
+	SnippetModal,
+	SnippetLibraryView,
+	ConfigMigrationModal,
+	ConfigInspectModal,
+	PresetExportModal,
+	PresetGalleryModal,
+	PresetImportModal,
+	ConfigSuggest,
+	promptForPlaceholders,
+	CODE_BLOCK_SCHEMA,
+	CMDOUT_BLOCK_SCHEMA,
+	validateYamlSchema,
+	formatWarning,
+} from './ui';
+import type { YamlWarning } from './ui';
 
 // Utils
-import { replaceTemplateVariables, containsTemplateVariables, findPreElement, findCodeElement, resolvePreset } from './utils';
+import { replaceTemplateVariables, containsTemplateVariables, findPreElement, findCodeElement, applyPrintProfile, resolvePreset, findDefaultPreset, findFolderPreset, normalizeConfigCascade, getActiveCascadeLayers, resolvePresetConfig, deepMergeYamlConfigs, renameDeprecatedKeys, setBlockPreset, inspectBlockConfig, findFoldRegions, parseLineLink, formatLineLinkSubpath, findFenceBlockId, resolveLanguageAlias, setBlockLanguage, detectLanguage, DETECTABLE_LANGUAGES, parseCodeVariables, interpolateCodeVariables, languageFromPath, stripPrompts, findPlaceholders, parseOutputBlock, writeOutputBlock } from './utils';
 
 // What's New data
 import releaseNotesData from './data/whatsnew.json';
 
+// Preset gallery data
+import presetGalleryData from './data/preset-gallery.json';
+
 // =============================================================================
 // Title Bar Attachment Config
 // =============================================================================
@@ -73,14 +216,96 @@ interface AttachTitleBarConfig {
 	language?: string;
 	descriptionText?: string;
 	containingNotePath: string;
-	showCopyButton: boolean;
-	totalLineCount: number;
-	foldLines: number;
-	shiftCopyJoin?: string;
-	altCopyJoin?: string;
-	joinIgnoreRegex?: string;
-	showDownloadButton?: boolean;
-	onDownload?: (codeText: string) => void;
+	buttons: CodeButtonOptions;
+	headerIcon?: string;
+	showTitleText?: boolean;
+	showLanguageBadge?: boolean;
+	onLanguageBadgeClick?: (event: MouseEvent) => void;
+	buttonsInHeader?: boolean;
+}
+
+/**
+ * A rendered ufence block, kept so it can be re-rendered on demand.
+ */
+interface TrackedBlock {
+	container: HTMLElement;
+	rawContent: string;
+	context: MarkdownPostProcessorContext;
+	defaultLanguage: string;
+	/** Whether an embedded file's name sets the language (ufence-code blocks) */
+	inferLanguage?: boolean;
+
+	/** Vault file the block's code comes from (META.PATH or META.SRC) */
+	sourceFile?: string;
+
+	/** URL the block's code comes from (META.PATH or META.SRC) */
+	sourceUrl?: string;
+
+	/** Whether the block's code comes from a query over the vault (META.QUERY) */
+	fromQuery?: boolean;
+
+	/** ID of the block this one shows (META.REF) */
+	reference?: string;
+
+	/** Note the referenced block was found in */
+	referencedNote?: string;
+}
+
+/**
+ * A block's resolved settings and code, ready to render.
+ */
+interface RenderedCodeContent {
+	/** Resolved block settings */
+	config: ResolvedBlockConfig;
+	/** Merged settings before resolution (for callouts) */
+	mergedConfig: ParsedYamlConfig;
+	/** Code to show, already filtered */
+	sourceCode: string;
+	/** Metadata of the code's source, for the title template */
+	fileMetadata: SourceFileMetadata;
+	/** Path of the note containing the block ("" for the preset preview) */
+	notePath: string;
+	/** Text between the block's fences, that its copy count and wrap choice are kept under (omitted = the code) */
+	blockText?: string;
+	/** Path the title links to, if any */
+	clickablePath?: string;
+	/** Block ID that line links address (empty or omitted = none) */
+	blockId?: string;
+	/** Opens the menu for correcting a detected language (omitted = badge not clickable) */
+	onLanguageBadgeClick?: (event: MouseEvent) => void;
+	/** Lines changed since a snapshot of the note (omitted = none) */
+	changedLines?: number[];
+
+	/** Fetch time and offline state, for code loaded from a URL */
+	remoteStatus?: RemoteSourceStatus;
+
+	/** Opens the code for editing, for a block embedding a vault file (omitted = not editable) */
+	onEdit?: () => void;
+
+	/** The whole code of a long file shown in part, with a callback showing more (omitted = all shown) */
+	partialLoad?: { fullCode: string; totalLines: number; onLoadMore: () => void };
+
+	/** Runs the block's code, once running code is allowed (omitted = no run button) */
+	onRun?: () => void;
+
+	/** Runs one line of the block's code (omitted = no per-line run buttons) */
+	onRunLine?: (line: string) => Promise<RunResult | undefined>;
+}
+
+/**
+ * How a block's code is run (its RUN section).
+ */
+interface BlockRunOptions {
+	/** Kill the run after this long (0 = no limit) */
+	timeoutMs: number;
+	/** Environment variables from RUN.ENV, references not yet filled in */
+	env: Record<string, string>;
+	/** Note the block is in, for {{fm.name}} in the variables */
+	notePath: string;
+	/** The block's PROMPT, stripped from the code before it runs */
+	promptPattern?: RegExp;
+	/** Processor context of the block, when its output is saved in the note */
+	saveContext?: MarkdownPostProcessorContext;
 }
 
 // =============================================================================
@@ -94,6 +319,16 @@ interface AttachTitleBarConfig {
  * markdown processors for the various ufence block types.
  */
 
+/**
+ * Summarises the parts of a note's metadata that block rendering depends
+ * on (the frontmatter, for `ufence:` and code variables, and the tags),
+ * so changes can be spotted.
+ */
+function buildMetadataSnapshot(cache: CachedMetadata | null): string {
+	const tags = cache ? getAllTags(cache) ?? [] : [];
+	return JSON.stringify([cache?.frontmatter ?? null, [...new Set(tags)].sort()]);
+}
+
 /**
  * Normalises a raw YAML record from a ufence-ufence config block.
  *
@@ -130,12 +365,167 @@ export default class UltraCodeFence extends Plugin {
 	 * page, stale DOM entries are filtered by `isConnected` at refresh
 	 * time so detached blocks never accumulate.
 	 */
-	private renderedBlocks = new Map<string, {
-		container: HTMLElement;
-		rawContent: string;
-		context: MarkdownPostProcessorContext;
-		defaultLanguage: string;
-	}[]>();
+	private renderedBlocks = new Map<string, TrackedBlock[]>();
+
+	/**
+	 * Recent copies from code blocks, for the clipboard history command.
+	 * Kept in memory only, so it resets when Obsidian restarts.
+	 */
+	private clipboardHistory = new ClipboardHistory(DEFAULT_SETTINGS.clipboardHistorySize);
+
+	/**
+	 * Notes changed by the last settings migration, for the undo command.
+	 * Kept in memory only, so the undo is available until Obsidian restarts.
+	 */
+	private lastMigration: ConfigMigrationPlan[] = [];
+
+	/** Status bar item for copy confirmations, created on first use */
+	private copyStatusBarItem: HTMLElement | null = null;
+
+	/** Timer that clears the status bar confirmation */
+	private copyStatusBarTimer: number | undefined;
+
+	/**
+	 * A followed line link whose note is still opening; the block reveals
+	 * the lines when it renders, unless LINE_LINK_WAIT_MS passes first.
+	 */
+	private pendingLineLink: (LineLink & { requestedAt: number }) | null = null;
+
+	/**
+	 * Saves copy counts and wrap choices without re-rendering blocks (unlike
+	 * saveSettings), batching bursts of changes into one write.
+	 */
+	private requestCopyCountSave = debounce(() => {
+		void this.saveData(this.settings);
+	}, COPY_COUNT_SAVE_DELAY_MS, true);
+
+	/**
+	 * Each open note's ufence blocks as last seen in the editor, keyed by
+	 * note path — compared after an edit to find blocks Obsidian won't
+	 * re-render itself. Pruned with the other per-note maps.
+	 */
+	private editorBlocks = new Map<string, UfenceBlock[]>();
+
+	/**
+	 * Each note's frontmatter and tags as last rendered (JSON), keyed by
+	 * note path — compared when the metadata cache changes.
+	 */
+	private frontmatterSnapshots = new Map<string, string>();
+
+	/**
+	 * Lines loaded so far of each long embedded file shown in part, keyed
+	 * by block container so re-renders keep them.
+	 */
+	private loadedLineCounts = new WeakMap<HTMLElement, number>();
+
+	/**
+	 * Pending re-reads of blocks with META.REFRESH, keyed by block
+	 * container. Each render replaces its block's timer.
+	 */
+	private sourceRefreshTimers = new Map<HTMLElement, number>();
+
+	/**
+	 * Runs under way, by block container, so a second click waits for the
+	 * first run and every run can be stopped when the plugin unloads.
+	 */
+	private runningBlocks = new Map<HTMLElement, AbortController>();
+
+	/**
+	 * Languages registered with Prism from the grammar folder, so a reload
+	 * can replace them without touching Prism's own languages.
+	 */
+	private customGrammarLanguages = new Set<string>();
+
+	/** Language aliases registered with Prism */
+	private languageAliasNames = new Set<string>();
+
+	/** Grammar folder last loaded (null until the vault is ready) */
+	private loadedGrammarFolder: string | null = null;
+
+	/** Language aliases last registered (JSON), to spot changes */
+	private loadedLanguageAliases = '';
+
+	/** Whether the bundled template grammars (Helm) are registered */
+	private bundledGrammarsRegistered = false;
+
+	/** The Shiki backend, loading or loaded (null = it could not be loaded) */
+	private shikiBackend: Promise<HighlighterBackend | null> | null = null;
+
+	/** Vault path the Shiki backend was loaded from */
+	private loadedShikiModulePath = '';
+
+	/** Grammars, themes, copy formats, annotations and runners from other plugins */
+	private contributions = new ContributionRegistry(() => {
+		this.requestContributionRefresh();
+	});
+
+	/**
+	 * Blocks with IDs across the vault, for META.REF; read in full the
+	 * first time a block refers to one, then kept up to date note by note.
+	 */
+	private blockReferences: BlockReferenceIndex | null = null;
+
+	/**
+	 * Titled blocks across the vault, for the snippet library; read in
+	 * full the first time it is opened, then kept up to date note by note.
+	 */
+	private snippetLibrary: SnippetLibrary | null = null;
+
+	/** Cached copies of the files blocks load from URLs */
+	private remoteCache = new RemoteSourceCache(
+		this.app.vault.adapter,
+		`${this.manifest.dir ?? `${this.app.vault.configDir}/plugins/${this.manifest.id}`}/${REMOTE_CACHE_FOLDER}`
+	);
+
+	/** Languages registered with Prism from other plugins' grammars */
+	private contributedGrammarLanguages = new Set<string>();
+
+	/**
+	 * The API other plugins use to contribute to ufence blocks, reached as
+	 * `app.plugins.getPlugin('ultra-code-fence').api`.
+	 */
+	api: UltraCodeFenceApi = this.contributions.createApi();
+
+	/**
+	 * Reloads custom grammars and language aliases once changes to the
+	 * grammar folder or their settings pause.
+	 */
+	private requestGrammarReload = debounce(() => {
+		void this.reloadCustomGrammars();
+	}, GRAMMAR_RELOAD_DELAY_MS, true);
+
+	/**
+	 * Applies other plugins' contributions once a burst of them pauses.
+	 */
+	private requestContributionRefresh = debounce(() => {
+		void this.applyContributions();
+	}, CONTRIBUTION_REFRESH_DELAY_MS, true);
+
+	/**
+	 * Re-renders the blocks affected by an edit once typing pauses.
+	 */
+	private requestEditedBlockRefresh = debounce((editor: Editor, notePath: string) => {
+		void this.refreshEditedBlocks(editor.getValue(), notePath);
+	}, BLOCK_REFRESH_DELAY_MS, true);
+
+	/**
+	 * Re-renders the blocks built from queries once changes to notes
+	 * pause, since any note may change what a query finds.
+	 */
+	private requestQueryBlockRefresh = debounce(() => {
+		void this.refreshQueryBlocks();
+	}, QUERY_REFRESH_DELAY_MS, true);
+
+	/**
+	 * Reloads open snippet library views once changes to notes pause.
+	 */
+	private requestSnippetLibraryRefresh = debounce(() => {
+		for (const leaf of this.app.workspace.getLeavesOfType(SNIPPET_LIBRARY_VIEW_TYPE)) {
+			if (leaf.view instanceof SnippetLibraryView) {
+				void leaf.view.refresh();
+			}
+		}
+	}, SNIPPET_LIBRARY_REFRESH_DELAY_MS, true);
 
 	/**
 	 * Called when the plugin is loaded.
@@ -152,6 +542,28 @@ export default class UltraCodeFence extends Plugin {
 		// Show What's New modal on version update
 		await this.checkVersionUpdate();
 
+		// Follow links to lines of a block ([[Note#^id:L42]]) ourselves;
+		// Obsidian only knows the block ID
+		this.registerDomEvent(document, 'click', (event) => {
+			this.handleLineLinkClick(event);
+		}, { capture: true });
+
+		// Stop re-reading embedded files once the plugin is unloaded
+		this.register(() => {
+			for (const timer of this.sourceRefreshTimers.values()) {
+				window.clearTimeout(timer);
+			}
+			this.sourceRefreshTimers.clear();
+		});
+
+		// Stop running blocks, and the processes they started, once the plugin is unloaded
+		this.register(() => {
+			for (const controller of this.runningBlocks.values()) {
+				controller.abort();
+			}
+			this.runningBlocks.clear();
+		});
+
 		// Register markdown post-processor for reading mode
 		this.registerMarkdownPostProcessor((element, context) => {
 		void this.processReadingModeBlock(element, context);
@@ -168,9 +580,15 @@ export default class UltraCodeFence extends Plugin {
 		// Register command output processor
 		this.registerCommandOutputProcessor();
 
+		// Register ufence-output processor (output a run saved in the note)
+		this.registerOutputProcessor();
+
 		// Register ufence-ufence config processor (invisible page-level defaults)
 		this.registerConfigProcessor();
 
+		// Autocomplete keys and values while typing block settings
+		this.registerEditorSuggest(new ConfigSuggest(this.app, () => Object.keys(this.settings.presets)));
+
 		// Command: Force refresh all ufence blocks on the current page
 		this.addCommand({
 			id: 'force-refresh',
@@ -183,9 +601,229 @@ export default class UltraCodeFence extends Plugin {
 			},
 		});
 
+		// Command: copy a recent code block copy again
+		this.addCommand({
+			id: 'copy-from-history',
+			name: 'Copy again from clipboard history',
+			callback: () => {
+				this.openClipboardHistory();
+			},
+		});
+
+		// Command: check the settings of every ufence block in the vault
+		this.addCommand({
+			id: 'check-vault-blocks',
+			name: 'Check code block settings in all notes',
+			callback: () => {
+				void this.checkVaultBlocks();
+			},
+		});
+
+		// Command: show where the settings of the block at the cursor come from
+		this.addCommand({
+			id: 'inspect-block-settings',
+			name: 'Inspect settings of the code block at the cursor',
+			editorCheckCallback: (checking, editor, view) => {
+				const cursorLine = editor.getCursor().line + 1;
+				const block = findUfenceBlocks(editor.getValue()).find(candidate =>
+					candidate.blockType !== 'ufence'
+					&& cursorLine >= candidate.line
+					&& cursorLine <= candidate.line + candidate.content.split('\n').length + 1
+				);
+				if (!block || !view.file) return false;
+				if (!checking) {
+					void this.inspectBlockSettings(block, view.file.path);
+				}
+				return true;
+			},
+		});
+
+		// Command: preview and apply the rewrite of old settings keys
+		this.addCommand({
+			id: 'migrate-vault-blocks',
+			name: 'Update old code block settings in all notes',
+			callback: () => {
+				void this.openConfigMigration(true);
+			},
+		});
+
+		// Command: put back the notes changed by the last settings update
+		this.addCommand({
+			id: 'undo-vault-migration',
+			name: 'Undo last code block settings update',
+			checkCallback: (checking) => {
+				if (this.lastMigration.length === 0) return false;
+				if (!checking) {
+					void this.undoConfigMigration();
+				}
+				return true;
+			},
+		});
+
+		// Command: save chosen presets to a preset pack file
+		this.addCommand({
+			id: 'export-presets',
+			name: 'Export presets to a file',
+			callback: () => {
+				this.openPresetExport();
+			},
+		});
+
+		// Command: add presets from a preset pack file
+		this.addCommand({
+			id: 'import-presets',
+			name: 'Import presets from a file',
+			callback: () => {
+				this.pickPresetPack();
+			},
+		});
+
+		// Command: add a highlight theme from a VS Code theme file
+		this.addCommand({
+			id: 'import-vscode-theme',
+			name: 'Import a VS Code colour theme',
+			callback: () => {
+				this.pickVSCodeTheme();
+			},
+		});
+
+		// Command: browse the bundled preset gallery
+		this.addCommand({
+			id: 'browse-preset-gallery',
+			name: 'Browse the preset gallery',
+			callback: () => {
+				this.openPresetGallery();
+			},
+		});
+
+		// Snippet library: every titled block in the vault, in a sidebar view
+		this.registerView(SNIPPET_LIBRARY_VIEW_TYPE, leaf => new SnippetLibraryView(leaf, {
+			loadSnippets: async () => (await this.getSnippetLibrary()).all(),
+			openSnippet: (snippet) => { this.openSnippet(snippet); },
+			insertSnippet: (snippet, asReference) => { this.insertSnippet(snippet, asReference); },
+		}));
+
+		// Command: show the snippet library
+		this.addCommand({
+			id: 'open-snippet-library',
+			name: 'Open snippet library',
+			callback: () => {
+				void this.openSnippetLibrary();
+			},
+		});
+
+		// Command: put a copy of a snippet into the note
+		this.addCommand({
+			id: 'insert-snippet',
+			name: 'Insert snippet',
+			editorCallback: (editor) => {
+				void this.pickSnippet(editor, false);
+			},
+		});
+
+		// Command: put a block showing a snippet by ID into the note
+		this.addCommand({
+			id: 'insert-snippet-reference',
+			name: 'Insert snippet reference',
+			editorCallback: (editor) => {
+				void this.pickSnippet(editor, true);
+			},
+		});
+
+		// Re-render blocks whose fence line or page defaults were just edited
+		this.registerEvent(
+			this.app.workspace.on('editor-change', (editor, info) => {
+				if (info.file) {
+					this.requestEditedBlockRefresh(editor, info.file.path);
+				}
+			})
+		);
+		this.app.workspace.onLayoutReady(() => {
+			this.snapshotEditorBlocks();
+			void this.reloadCustomGrammars();
+			this.pruneAllCopyUsage();
+		});
+
+		// Reload custom grammars when a file in the grammar folder changes
+		const onGrammarFileChange = (path: string): void => {
+			const folder = this.loadedGrammarFolder?.trim().replace(/^\/+|\/+$/g, '');
+			if (folder && isInGrammarFolder(path, folder)) {
+				this.requestGrammarReload();
+			}
+		};
+		this.registerEvent(this.app.vault.on('create', file => { onGrammarFileChange(file.path); }));
+		this.registerEvent(this.app.vault.on('modify', file => { onGrammarFileChange(file.path); }));
+		this.registerEvent(this.app.vault.on('delete', file => { onGrammarFileChange(file.path); }));
+		this.registerEvent(this.app.vault.on('rename', (file, oldPath) => {
+			onGrammarFileChange(file.path);
+			onGrammarFileChange(oldPath);
+		}));
+
+		// Re-render blocks built from queries when notes change
+		this.registerEvent(this.app.metadataCache.on('changed', () => { this.requestQueryBlockRefresh(); }));
+		this.registerEvent(this.app.vault.on('delete', () => { this.requestQueryBlockRefresh(); }));
+		this.registerEvent(this.app.vault.on('rename', () => { this.requestQueryBlockRefresh(); }));
+
+		// Keep the snippet library up to date
+		this.registerEvent(this.app.vault.on('modify', file => { void this.updateSnippetLibrary(file); }));
+		this.registerEvent(this.app.vault.on('create', file => { void this.updateSnippetLibrary(file); }));
+		this.registerEvent(this.app.vault.on('delete', file => { void this.updateSnippetLibrary(file, true); }));
+		this.registerEvent(this.app.vault.on('rename', (file, oldPath) => { void this.updateSnippetLibrary(file, false, oldPath); }));
+
+		// Re-render blocks showing another by ID when the note it is in changes
+		this.registerEvent(this.app.vault.on('modify', file => { void this.refreshReferencesToNote(file); }));
+		this.registerEvent(this.app.vault.on('create', file => { void this.refreshReferencesToNote(file); }));
+		this.registerEvent(this.app.vault.on('delete', file => { void this.refreshReferencesToNote(file, true); }));
+		this.registerEvent(this.app.vault.on('rename', (file, oldPath) => { void this.refreshReferencesToNote(file, false, oldPath); }));
+
+		// Drop copy counts and wrap choices of blocks that changed or went, and follow renamed notes
+		this.registerEvent(this.app.vault.on('modify', file => { void this.pruneCopyUsageForNote(file.path); }));
+		this.registerEvent(this.app.vault.on('delete', file => { void this.pruneCopyUsageForNote(file.path); }));
+		this.registerEvent(this.app.vault.on('rename', (file, oldPath) => {
+			const countsMoved = moveCopyUsage(this.settings.copyCounts, oldPath, file.path);
+			const wrapsMoved = moveCopyUsage(this.settings.wrapStates, oldPath, file.path);
+			if (countsMoved || wrapsMoved) this.requestCopyCountSave();
+		}));
+
+		// Re-render blocks whose code is loaded from a vault file when it changes
+		this.registerEvent(this.app.vault.on('modify', file => { void this.refreshBlocksForSourceFile(file.path); }));
+		this.registerEvent(this.app.vault.on('create', file => { void this.refreshBlocksForSourceFile(file.path); }));
+		this.registerEvent(this.app.vault.on('delete', file => { void this.refreshBlocksForSourceFile(file.path); }));
+		this.registerEvent(this.app.vault.on('rename', (file, oldPath) => {
+			void this.refreshBlocksForSourceFile(file.path);
+			void this.refreshBlocksForSourceFile(oldPath);
+		}));
+
+		// Re-render blocks when the theme switches between light and dark,
+		// so presets with WHEN: dark / light entries follow it
+		let darkMode = document.body.classList.contains('theme-dark');
+		this.registerEvent(
+			this.app.workspace.on('css-change', () => {
+				const isDark = document.body.classList.contains('theme-dark');
+				if (isDark !== darkMode) {
+					darkMode = isDark;
+					void this.refreshAllBlocks();
+				}
+			})
+		);
+
+		// Re-render a note's blocks when its frontmatter or tags change
+		this.registerEvent(
+			this.app.metadataCache.on('changed', (file, _data, cache) => {
+				const snapshot = buildMetadataSnapshot(cache);
+				const previous = this.frontmatterSnapshots.get(file.path);
+				this.frontmatterSnapshots.set(file.path, snapshot);
+				if (previous !== undefined && previous !== snapshot) {
+					void this.refreshBlocksForPath(file.path);
+				}
+			})
+		);
+
 		// Prune stale page config entries when navigating between notes
 		this.registerEvent(
 			this.app.workspace.on('active-leaf-change', () => {
+				this.snapshotEditorBlocks();
+
 				const openPaths = new Set<string>();
 				this.app.workspace.iterateAllLeaves((leaf) => {
 					if (leaf.view instanceof MarkdownView && leaf.view.file) {
@@ -203,6 +841,11 @@ export default class UltraCodeFence extends Plugin {
 						this.renderedBlocks.delete(path);
 					}
 				}
+				for (const path of this.editorBlocks.keys()) {
+					if (!openPaths.has(path)) {
+						this.editorBlocks.delete(path);
+					}
+				}
 			})
 		);
 	}
@@ -220,6 +863,18 @@ export default class UltraCodeFence extends Plugin {
 	async loadSettings(): Promise<void> {
 		const stored = (await this.loadData()) as Partial<PluginSettings> | null;
 		this.settings = Object.assign({}, DEFAULT_SETTINGS, stored ?? {});
+		// Copy counts are updated in place, so never share the default object
+		this.settings.copyCounts = { ...this.settings.copyCounts };
+		this.settings.wrapStates = { ...this.settings.wrapStates };
+		this.settings.folderPresets = [...this.settings.folderPresets];
+		this.settings.gitRepositories = normalizeGitRepositories(this.settings.gitRepositories as GitRepository[] | string);
+		this.settings.languagePresets = { ...this.settings.languagePresets };
+		this.settings.languageAliases = { ...this.settings.languageAliases };
+		this.settings.languageInjections = this.settings.languageInjections.map(rule => ({ ...rule }));
+		this.settings.commentKeywords = this.settings.commentKeywords.map(rule => ({ ...rule }));
+		this.settings.highlightThemes = { ...this.settings.highlightThemes };
+		this.settings.configCascade = normalizeConfigCascade(this.settings.configCascade);
+		this.clipboardHistory.setMaxEntries(this.settings.clipboardHistorySize);
 	}
 
 	/**
@@ -229,84 +884,289 @@ export default class UltraCodeFence extends Plugin {
 	 */
 	async saveSettings(): Promise<void> {
 		await this.saveData(this.settings);
+		this.clipboardHistory.setMaxEntries(this.settings.clipboardHistorySize);
+		if (this.loadedGrammarFolder !== null && (this.settings.customGrammarFolder !== this.loadedGrammarFolder
+			|| JSON.stringify(this.settings.languageAliases) !== this.loadedLanguageAliases)) {
+			this.requestGrammarReload();
+		}
 		await this.refreshAllBlocks();
 	}
 
 	/**
-	 * Compares the running plugin version against the last-seen version
-	 * stored in settings. If they differ, shows the What's New modal
-	 * after a short delay ({@link WHATS_NEW_DELAY_MS}) and persists the
-	 * new version so the modal isn't shown again.
+	 * Whether the user has agreed to running code blocks in this vault,
+	 * on this device.
+	 *
+	 * @returns True once the user has agreed
 	 */
-	private async checkVersionUpdate(): Promise<void> {
-		const currentVersion = this.manifest.version;
-
-		if (this.settings.lastSeenVersion !== currentVersion) {
-			setTimeout(() => {
-				new WhatsNewModal(this.app, currentVersion, releaseNotesData).open();
-			}, WHATS_NEW_DELAY_MS);
+	isRunningCodeAllowed(): boolean {
+		return loadRunConsent(this.app);
+	}
 
-			this.settings.lastSeenVersion = currentVersion;
-			await this.saveSettings();
-		}
+	/**
+	 * Records the user's consent to running code blocks, and re-renders
+	 * the blocks to add or remove their run buttons.
+	 *
+	 * @param allowed - Whether running code is allowed
+	 */
+	async setRunningCodeAllowed(allowed: boolean): Promise<void> {
+		saveRunConsent(this.app, allowed);
+		await this.refreshAllBlocks();
 	}
 
-	// ===========================================================================
-	// Processor Registration
-	// ===========================================================================
+	/**
+	 * Names of the vault's secrets, for the settings tab (values stay
+	 * out of it).
+	 *
+	 * @returns Secret names, sorted
+	 */
+	getSecretNames(): string[] {
+		return Object.keys(loadSecrets(this.app)).sort();
+	}
 
 	/**
-	 * Registers a code block processor for every language in the
-	 * comma-separated {@link PluginSettings.supportedLanguages} list.
+	 * Adds or replaces a secret, or removes it.
 	 *
-	 * Each processor responds to <code>```ufence-{lang}</code> blocks
-	 * and delegates to {@link processUfenceBlock} with the language id
-	 * as the default highlighting language.
+	 * @param name - Secret name, as {{secret.name}} refers to it
+	 * @param value - New value, or undefined to remove the secret
 	 */
-	private registerLanguageProcessors(): void {
-		const languages = this.settings.supportedLanguages
-			.split(',')
-			.map(lang => lang.trim())
-			.filter(lang => lang);
+	setSecret(name: string, value: string | undefined): void {
+		const secrets = loadSecrets(this.app);
+		if (value === undefined) {
+			Reflect.deleteProperty(secrets, name);
+		} else {
+			secrets[name] = value;
+		}
+		saveSecrets(this.app, secrets);
+	}
 
-		for (const languageId of languages) {
-			this.registerMarkdownCodeBlockProcessor(
-				`ufence-${languageId}`,
-				(content, element, context) => this.processUfenceBlock(content, element, context, languageId)
-			);
+	/**
+	 * Loads the grammar files in {@link PluginSettings.customGrammarFolder}
+	 * and registers them with Obsidian's Prism highlighter, along with the
+	 * {@link PluginSettings.languageAliases}, then re-renders every block
+	 * so the new highlighting shows. Files that can't be used are reported
+	 * in a notice.
+	 */
+	private async reloadCustomGrammars(): Promise<void> {
+		const folder = this.settings.customGrammarFolder;
+		this.loadedGrammarFolder = folder;
+		this.loadedLanguageAliases = JSON.stringify(this.settings.languageAliases);
+
+		const { grammars, errors } = await loadCustomGrammars(this.app, folder);
+		if (grammars.length === 0 && this.customGrammarLanguages.size === 0 && errors.length === 0
+			&& Object.keys(this.settings.languageAliases).length === 0 && this.languageAliasNames.size === 0) {
+			return;
+		}
+
+		const prism = (await loadPrism()) as PrismLanguages;
+		// Other plugins' grammars yield to the folder's, so they go in after it
+		this.contributedGrammarLanguages = registerCustomGrammars(prism, [], this.contributedGrammarLanguages);
+		this.customGrammarLanguages = registerCustomGrammars(prism, grammars, this.customGrammarLanguages);
+		this.registerContributedGrammars(prism);
+		// Aliases may name a custom grammar, so they go in after the grammars
+		this.languageAliasNames = registerLanguageAliases(prism, this.settings.languageAliases, this.languageAliasNames);
+
+		if (errors.length > 0) {
+			new Notice(`Could not load ${errors.length === 1 ? 'a grammar' : `${String(errors.length)} grammars`} from ${folder}:\n`
+				+ errors.map(error => `${error.path}: ${error.message}`).join('\n'));
 		}
+		await this.refreshAllBlocks();
 	}
 
 	/**
-	 * Registers the generic <code>```ufence-code</code> processor.
+	 * Registers other plugins' grammars with Prism, except for languages
+	 * the grammar folder defines.
 	 *
-	 * Unlike the language-specific processors, this one uses the
-	 * {@link PluginSettings.defaultLanguage} setting for highlighting.
-	 * Gated behind {@link PluginSettings.enableGenericProcessor}.
+	 * @param prism - Prism global (from Obsidian's loadPrism)
 	 */
-	private registerGenericProcessor(): void {
-		this.registerMarkdownCodeBlockProcessor(
-			'ufence-code',
-			(content, element, context) => this.processUfenceBlock(content, element, context, this.settings.defaultLanguage)
-		);
+	private registerContributedGrammars(prism: PrismLanguages): void {
+		const grammars = this.contributions.grammars().filter(grammar => !this.customGrammarLanguages.has(grammar.language));
+		this.contributedGrammarLanguages = registerCustomGrammars(prism, grammars, this.contributedGrammarLanguages);
 	}
 
 	/**
-	 * Registers the <code>```ufence-cmdout</code> processor for
-	 * terminal / command-output blocks. Delegates to
-	 * {@link processCommandOutputBlock}.
+	 * Applies what other plugins have contributed: registers their
+	 * grammars (and the aliases that may name them), then re-renders every
+	 * block so new themes, copy formats, annotations and run buttons show.
 	 */
-	private registerCommandOutputProcessor(): void {
-		this.registerMarkdownCodeBlockProcessor(
-			'ufence-cmdout',
-			(content, element, context) => this.processCommandOutputBlock(content, element, context)
-		);
+	private async applyContributions(): Promise<void> {
+		const prism = (await loadPrism()) as PrismLanguages;
+		this.registerContributedGrammars(prism);
+		this.languageAliasNames = registerLanguageAliases(prism, this.settings.languageAliases, this.languageAliasNames);
+		await this.refreshAllBlocks();
 	}
 
 	/**
-	 * Registers the ufence-ufence config processor.
+	 * Loads Obsidian's Prism highlighter, registering the bundled template
+	 * grammars on first use.
 	 *
-	 * This invisible block allows page-level defaults to be set for all
+	 * @returns Prism's global object
+	 */
+	private async loadHighlighter(): Promise<PrismTokenizer> {
+		const prism = (await loadPrism()) as PrismTokenizer;
+		if (!this.bundledGrammarsRegistered) {
+			registerBundledGrammars(prism);
+			this.bundledGrammarsRegistered = true;
+		}
+		return prism;
+	}
+
+	/**
+	 * Loads Shiki from {@link PluginSettings.shikiModulePath} on first use,
+	 * and again when the path changes. A file that isn't a Shiki build is
+	 * reported once in a notice; until there is one, Prism highlights.
+	 *
+	 * @returns The Shiki backend, or null if there is none
+	 */
+	private loadShikiBackend(): Promise<HighlighterBackend | null> {
+		const path = this.settings.shikiModulePath.trim() ? normalizePath(this.settings.shikiModulePath.trim()) : '';
+		if (this.shikiBackend && this.loadedShikiModulePath === path) {
+			return this.shikiBackend;
+		}
+
+		this.loadedShikiModulePath = path;
+		this.shikiBackend = (async () => {
+			if (!path) return null;
+			if (!(await this.app.vault.adapter.exists(path))) {
+				// Not there yet (or the path is still being typed): look again next time
+				this.shikiBackend = null;
+				return null;
+			}
+			try {
+				// Left as a real import() by the build, so the file's own chunks resolve next to it
+				const shiki = readShikiModule(await import(this.app.vault.adapter.getResourcePath(path)));
+				if (!shiki) throw new Error('the file is not a Shiki build');
+				return createShikiBackend(shiki);
+			} catch (error) {
+				new Notice(`Could not load Shiki from ${path}: ${error instanceof Error ? error.message : String(error)}`);
+				return null;
+			}
+		})();
+		return this.shikiBackend;
+	}
+
+	/**
+	 * Re-highlights a block's code with the engine chosen in the settings,
+	 * when that isn't Prism. If the engine doesn't know the language or
+	 * fails, Prism's highlighting stays.
+	 *
+	 * @param codeElement - Code element highlighted by Prism
+	 * @param sourceCode - The block's code
+	 * @param language - The block's language (after aliases)
+	 */
+	private async highlightWithBackend(codeElement: HTMLElement, sourceCode: string, language: string): Promise<void> {
+		if (pickHighlighterEngine(this.settings.highlighterEngine, this.settings.prismLanguages, language) !== 'shiki') return;
+
+		const backend = await this.loadShikiBackend();
+		if (!backend) return;
+
+		try {
+			const tokens = await backend.tokenize(sourceCode, language);
+			if (!tokens) return;
+
+			const trailingNewline = codeElement.textContent?.endsWith('\n') ? '\n' : '';
+			codeElement.textContent = '';
+			appendPrismTokens(codeElement, tokens);
+			if (trailingNewline) codeElement.appendChild(document.createTextNode(trailingNewline));
+		} catch {
+			// Keep Prism's highlighting
+		}
+	}
+
+	/**
+	 * Compares the running plugin version against the last-seen version
+	 * stored in settings. If they differ, shows the What's New modal
+	 * after a short delay ({@link WHATS_NEW_DELAY_MS}) and persists the
+	 * new version so the modal isn't shown again. On an upgrade (not a
+	 * fresh install) the vault is also checked for old settings keys.
+	 */
+	private async checkVersionUpdate(): Promise<void> {
+		const currentVersion = this.manifest.version;
+
+		if (this.settings.lastSeenVersion !== currentVersion) {
+			if (this.settings.lastSeenVersion !== '') {
+				this.app.workspace.onLayoutReady(() => {
+					void this.openConfigMigration(false);
+				});
+			}
+
+			setTimeout(() => {
+				new WhatsNewModal(this.app, currentVersion, releaseNotesData).open();
+			}, WHATS_NEW_DELAY_MS);
+
+			this.settings.lastSeenVersion = currentVersion;
+			await this.saveSettings();
+		}
+	}
+
+	// ===========================================================================
+	// Processor Registration
+	// ===========================================================================
+
+	/**
+	 * Registers a code block processor for every language in the
+	 * comma-separated {@link PluginSettings.supportedLanguages} list.
+	 *
+	 * Each processor responds to <code>```ufence-{lang}</code> blocks
+	 * and delegates to {@link processUfenceBlock} with the language id
+	 * as the default highlighting language.
+	 */
+	private registerLanguageProcessors(): void {
+		const languages = this.settings.supportedLanguages
+			.split(',')
+			.map(lang => lang.trim())
+			.filter(lang => lang);
+
+		for (const languageId of languages) {
+			this.registerMarkdownCodeBlockProcessor(
+				`ufence-${languageId}`,
+				(content, element, context) => this.processUfenceBlock(content, element, context, languageId)
+			);
+		}
+	}
+
+	/**
+	 * Registers the generic <code>```ufence-code</code> processor.
+	 *
+	 * Unlike the language-specific processors, this one uses the
+	 * {@link PluginSettings.defaultLanguage} setting for highlighting.
+	 * Gated behind {@link PluginSettings.enableGenericProcessor}.
+	 */
+	private registerGenericProcessor(): void {
+		this.registerMarkdownCodeBlockProcessor(
+			'ufence-code',
+			(content, element, context) => this.processUfenceBlock(content, element, context, this.settings.defaultLanguage, true)
+		);
+	}
+
+	/**
+	 * Registers the <code>```ufence-cmdout</code> processor for
+	 * terminal / command-output blocks. Delegates to
+	 * {@link processCommandOutputBlock}.
+	 */
+	private registerCommandOutputProcessor(): void {
+		this.registerMarkdownCodeBlockProcessor(
+			'ufence-cmdout',
+			(content, element, context) => this.processCommandOutputBlock(content, element, context)
+		);
+	}
+
+	/**
+	 * Registers the processor for ufence-output blocks, which hold the
+	 * output a run saved in the note (RUN.SAVE).
+	 */
+	private registerOutputProcessor(): void {
+		this.registerMarkdownCodeBlockProcessor(
+			OUTPUT_BLOCK_LANGUAGE,
+			(content, element) => {
+				element.appendChild(renderSavedRunOutput(parseOutputBlock(content)));
+			}
+		);
+	}
+
+	/**
+	 * Registers the ufence-ufence config processor.
+	 *
+	 * This invisible block allows page-level defaults to be set for all
 	 * ufence blocks on the page. Supports both named preset references
 	 * (PRESET: name) and inline YAML config (e.g. RENDER:\n  ZEBRA: true).
 	 */
@@ -315,7 +1175,7 @@ export default class UltraCodeFence extends Plugin {
 			'ufence-ufence',
 			(content, element, _context) => {
 				try {
-					const raw: unknown = parseYaml(content);
+					const raw: unknown = parseYamlSettings(content);
 					if (raw && typeof raw === 'object') {
 						const record = raw as Record<string, unknown>;
 						normaliseConfigRecord(record);
@@ -388,6 +1248,53 @@ export default class UltraCodeFence extends Plugin {
 		}
 	}
 
+	/**
+	 * Gets the block defaults from a note's `ufence:` frontmatter.
+	 *
+	 * Read from the metadata cache each time, so edits to the frontmatter
+	 * apply on the next render without any cache of our own. The value
+	 * is recorded so a later frontmatter edit can trigger a re-render.
+	 *
+	 * @param notePath - Vault-relative path of the note.
+	 * @returns The parsed config, or `undefined` if the note has none.
+	 */
+	private getFrontmatterConfig(notePath: string): ParsedYamlConfig | undefined {
+		const file = this.app.vault.getAbstractFileByPath(notePath);
+		if (!(file instanceof TFile)) return undefined;
+
+		const cache = this.app.metadataCache.getFileCache(file);
+		this.frontmatterSnapshots.set(notePath, buildMetadataSnapshot(cache));
+		return parseFrontmatterConfig(cache?.frontmatter);
+	}
+
+	/**
+	 * Gets the values code variables are filled in from for a note.
+	 *
+	 * @param notePath - Vault-relative path of the note.
+	 * @returns The note's frontmatter, the vault variables and the current time.
+	 */
+	private getCodeVariableContext(notePath: string): CodeVariableContext {
+		return {
+			frontmatter: this.app.metadataCache.getCache(notePath)?.frontmatter ?? {},
+			variables: parseCodeVariables(this.settings.codeVariables),
+			now: new Date(),
+		};
+	}
+
+	/**
+	 * Gets a note's tags (frontmatter and inline) from the metadata cache.
+	 *
+	 * @param notePath - Vault-relative path of the note.
+	 * @returns The tags, each starting with #.
+	 */
+	private getNoteTags(notePath: string): string[] {
+		const file = this.app.vault.getAbstractFileByPath(notePath);
+		if (!(file instanceof TFile)) return [];
+
+		const cache = this.app.metadataCache.getFileCache(file);
+		return cache ? getAllTags(cache) ?? [] : [];
+	}
+
 	/**
 	 * Parses page-level config from raw file content by finding
 	 * ufence-ufence code blocks and extracting their YAML.
@@ -404,7 +1311,7 @@ export default class UltraCodeFence extends Plugin {
 		if (!match) return undefined;
 
 		try {
-			const raw: unknown = parseYaml(match[1]);
+			const raw: unknown = parseYamlSettings(match[1]);
 			if (raw && typeof raw === 'object') {
 				const record = raw as Record<string, unknown>;
 				normaliseConfigRecord(record);
@@ -428,145 +1335,950 @@ export default class UltraCodeFence extends Plugin {
 	 *
 	 * @param notePath - Vault-relative path of the note whose blocks
 	 *                   should be refreshed.
+	 * @param contents - Only refresh blocks with this content (all when omitted)
 	 */
-	private async refreshBlocksForPath(notePath: string): Promise<void> {
+	private async refreshBlocksForPath(notePath: string, contents?: Set<string>): Promise<void> {
 		const blocks = this.renderedBlocks.get(notePath);
 		if (!blocks) return;
 
-		// Snapshot only DOM-connected blocks and clear the list.
-		// Re-rendering calls processUfenceBlock which re-registers each
-		// block fresh, so no duplicates accumulate. The delete-before-
-		// re-render pattern is safe because Obsidian's markdown post-
-		// processors run on the main thread (no concurrent re-entry).
+		// Snapshot only DOM-connected blocks and take the ones being
+		// refreshed off the list. Re-rendering calls processUfenceBlock
+		// which re-registers each block fresh, so no duplicates accumulate.
+		// The remove-before-re-render pattern is safe because Obsidian's
+		// markdown post-processors run on the main thread (no concurrent
+		// re-entry).
 		const active = blocks.filter(b => b.container.isConnected);
-		this.renderedBlocks.delete(notePath);
+		const targets = contents ? active.filter(b => contents.has(b.rawContent)) : active;
+		this.renderedBlocks.set(notePath, active.filter(b => !targets.includes(b)));
 
-		for (const block of active) {
+		for (const block of targets) {
 			block.container.empty();
 			await this.processUfenceBlock(
 				block.rawContent,
 				block.container,
 				block.context,
-				block.defaultLanguage
+				block.defaultLanguage,
+				block.inferLanguage
 			);
 		}
 	}
 
 	/**
-	 * Re-renders ufence blocks across ALL tracked pages.
+	 * Re-renders the blocks, in any open note, whose code is loaded from
+	 * a vault file (META.PATH or META.SRC), so they keep up with it.
 	 *
-	 * Called after settings changes so that every visible ufence block
-	 * reflects the updated defaults immediately.
+	 * @param filePath - Vault-relative path of the file that changed
 	 */
-	private async refreshAllBlocks(): Promise<void> {
-		for (const path of [...this.renderedBlocks.keys()]) {
-			await this.refreshBlocksForPath(path);
+	private async refreshBlocksForSourceFile(filePath: string): Promise<void> {
+		for (const [notePath, blocks] of [...this.renderedBlocks]) {
+			const contents = new Set(blocks.filter(block => block.sourceFile === filePath).map(block => block.rawContent));
+			if (contents.size > 0) {
+				await this.refreshBlocksForPath(notePath, contents);
+			}
 		}
 	}
 
-	// ===========================================================================
-	// Block Processing
-	// ===========================================================================
-
 	/**
-	 * Processes a ufence code block.
-	 *
-	 * Handles both file references (META.PATH) and inline code
-	 * (~~~ separator). Uses the nested YAML configuration structure.
+	 * Gets the index of blocks with IDs, reading every note the first time.
 	 *
-	 * @param rawContent - Raw block content
-	 * @param containerElement - Container element to render into
-	 * @param processorContext - Markdown processor context
-	 * @param defaultLanguage - Default language for syntax highlighting
+	 * @returns The index
 	 */
-	private async processUfenceBlock(
-		rawContent: string,
-		containerElement: HTMLElement,
-		processorContext: MarkdownPostProcessorContext,
-		defaultLanguage: string
-	): Promise<void> {
-		// Track this block so we can re-render it on demand (Force Refresh / settings change)
-		const path = processorContext.sourcePath;
-		if (!this.renderedBlocks.has(path)) {
-			this.renderedBlocks.set(path, []);
+	private async getBlockReferenceIndex(): Promise<BlockReferenceIndex> {
+		if (!this.blockReferences) {
+			const index = new BlockReferenceIndex();
+			for (const file of this.app.vault.getMarkdownFiles()) {
+				index.update(file.path, await this.app.vault.cachedRead(file));
+			}
+			this.blockReferences = index;
 		}
-		this.renderedBlocks.get(path)?.push({
-			container: containerElement,
-			rawContent,
-			context: processorContext,
-			defaultLanguage,
-		});
+		return this.blockReferences;
+	}
 
-		// Parse block content
-		let parsedBlock;
-		try {
-			parsedBlock = parseBlockContent(rawContent);
-		} catch {
-			await this.renderErrorMessage(containerElement, 'invalid embedding (invalid YAML)');
-			return;
-		}
+	/**
+	 * Updates the block ID index for a changed note and re-renders the
+	 * blocks that showed a block from it, or whose ID it now has.
+	 *
+	 * @param file - The changed file
+	 * @param deleted - Whether it was deleted
+	 * @param oldPath - Its previous path, when renamed
+	 */
+	private async refreshReferencesToNote(file: TAbstractFile, deleted = false, oldPath?: string): Promise<void> {
+		const index = this.blockReferences;
+		if (!index) return;
 
-		// Parse nested YAML configuration and resolve with defaults
-		const yamlConfig = parseNestedYamlConfig(parsedBlock.yamlProperties);
+		if (oldPath !== undefined) index.remove(oldPath);
+		if (!deleted && file instanceof TFile && file.extension === 'md') {
+			index.update(file.path, await this.app.vault.cachedRead(file));
+		} else {
+			index.remove(file.path);
+		}
 
-		// Apply preset and/or page-level defaults (if any)
-		const pageConfig = await this.getPageConfig(processorContext.sourcePath);
-		const mergedConfig = resolvePreset(
-			yamlConfig,
-			this.settings.presets,
-			pageConfig
-		);
+		const changedPaths = new Set([file.path, oldPath]);
+		for (const [notePath, blocks] of [...this.renderedBlocks]) {
+			const contents = new Set(blocks
+				.filter(block => block.reference !== undefined
+					&& (changedPaths.has(block.referencedNote) || changedPaths.has(index.find(block.reference)?.path)))
+				.map(block => block.rawContent));
+			if (contents.size > 0) {
+				await this.refreshBlocksForPath(notePath, contents);
+			}
+		}
+	}
 
-		const config = resolveBlockConfig(mergedConfig, this.settings, defaultLanguage);
+	/**
+	 * Gets the snippet library, reading every note the first time.
+	 *
+	 * @returns The library
+	 */
+	private async getSnippetLibrary(): Promise<SnippetLibrary> {
+		if (!this.snippetLibrary) {
+			const library = new SnippetLibrary();
+			for (const file of this.app.vault.getMarkdownFiles()) {
+				library.update(file.path, await this.app.vault.cachedRead(file));
+			}
+			this.snippetLibrary = library;
+		}
+		return this.snippetLibrary;
+	}
 
-		let sourceCode = '';
-		let fileMetadata: SourceFileMetadata;
+	/**
+	 * Updates the snippet library for a changed note, and open library
+	 * views once changes pause.
+	 *
+	 * @param file - The changed file
+	 * @param deleted - Whether it was deleted
+	 * @param oldPath - Its previous path, when renamed
+	 */
+	private async updateSnippetLibrary(file: TAbstractFile, deleted = false, oldPath?: string): Promise<void> {
+		const library = this.snippetLibrary;
+		if (!library) return;
 
-		// Determine source
-		if (parsedBlock.hasEmbeddedCode) {
-			sourceCode = parsedBlock.embeddedCode ?? '';
-			fileMetadata = createEmbeddedCodeMetadata(config.titleTemplate, config.language);
+		if (oldPath !== undefined) library.remove(oldPath);
+		if (!deleted && file instanceof TFile && file.extension === 'md') {
+			library.update(file.path, await this.app.vault.cachedRead(file));
 		} else {
-			if (!config.sourcePath) {
-				await this.renderErrorMessage(containerElement, 'invalid source - use META.PATH or ~~~ separator for inline code');
-				return;
-			}
-
-			const loadResult = await loadSource(this.app, config.sourcePath);
+			library.remove(file.path);
+		}
+		this.requestSnippetLibraryRefresh();
+	}
 
-			if (!loadResult.succeeded) {
-				await this.renderErrorMessage(containerElement, loadResult.errorMessage ?? 'failed to load source');
-				return;
-			}
+	/**
+	 * Shows the snippet library in the right sidebar, opening it if needed.
+	 */
+	private async openSnippetLibrary(): Promise<void> {
+		const { workspace } = this.app;
+		const openLeaves = workspace.getLeavesOfType(SNIPPET_LIBRARY_VIEW_TYPE);
+		let leaf: WorkspaceLeaf | null = openLeaves.length > 0 ? openLeaves[0] : null;
+		if (!leaf) {
+			leaf = workspace.getRightLeaf(false);
+			await leaf?.setViewState({ type: SNIPPET_LIBRARY_VIEW_TYPE, active: true });
+		}
+		if (leaf) {
+			await workspace.revealLeaf(leaf);
+		}
+	}
 
-			sourceCode = loadResult.sourceCode;
-			fileMetadata = loadResult.fileMetadata ?? createEmbeddedCodeMetadata('', config.language);
+	/**
+	 * Lets a snippet be picked and puts it into a note.
+	 *
+	 * @param editor - Editor of the note
+	 * @param asReference - Insert a block showing it by ID rather than a copy
+	 */
+	private async pickSnippet(editor: Editor, asReference: boolean): Promise<void> {
+		const snippets = (await this.getSnippetLibrary()).all().filter(snippet => !asReference || snippet.id);
+		if (snippets.length === 0) {
+			new Notice(asReference
+				? 'No snippets with an ID yet: give a titled block META.ID to show it by reference'
+				: 'No snippets yet: give a ufence block META.TITLE to add it to the library');
+			return;
 		}
 
-		// Apply filter chain (BY_LINES first, then BY_MARKS)
-		const filterResult = applyFilterChain(sourceCode, config);
+		new SnippetModal(
+			this.app,
+			snippets,
+			asReference ? 'Search snippets to insert by reference' : 'Search snippets to insert a copy of',
+			(snippet) => { this.insertSnippet(snippet, asReference, editor); }
+		).open();
+	}
 
-		if (filterResult.error) {
-			await this.renderErrorMessage(containerElement, filterResult.error);
+	/**
+	 * Puts a snippet into a note at the cursor, on a line of its own.
+	 *
+	 * @param snippet - The snippet
+	 * @param asReference - Insert a block showing it by ID rather than a copy
+	 * @param editor - Editor of the note (the most recently used note when omitted)
+	 */
+	private insertSnippet(snippet: Snippet, asReference: boolean, editor?: Editor): void {
+		const recentView = this.app.workspace.getMostRecentLeaf()?.view;
+		const targetEditor = editor ?? (recentView instanceof MarkdownView ? recentView.editor : null);
+		if (!targetEditor) {
+			new Notice('Open a note to insert the snippet into');
 			return;
 		}
 
-		sourceCode = filterResult.content;
+		const text = asReference ? buildSnippetReference(snippet) : snippet.copyText;
+		const cursor = targetEditor.getCursor();
+		const lineText = targetEditor.getLine(cursor.line);
+		if (lineText.trim() === '') {
+			targetEditor.replaceRange(text, { line: cursor.line, ch: 0 }, { line: cursor.line, ch: lineText.length });
+		} else {
+			targetEditor.replaceRange(`\n${text}`, { line: cursor.line, ch: lineText.length });
+		}
+	}
 
-		// Resolve display options
-		const shouldHideTitle = config.titleTemplate === '' || config.titleTemplate.toLowerCase() === 'none' || config.titleBarStyle === 'none';
+	/**
+	 * Opens the note a snippet is in, at the snippet.
+	 *
+	 * @param snippet - The snippet
+	 */
+	private openSnippet(snippet: Snippet): void {
+		const file = this.app.vault.getAbstractFileByPath(snippet.path);
+		if (file instanceof TFile) {
+			void this.app.workspace.getLeaf(false).openFile(file, { eState: { line: snippet.line - 1 } });
+		}
+	}
 
-		let displayTitle = config.titleTemplate;
+	/**
+	 * Fetches a remote file again, whatever the age of its cached copy,
+	 * and re-renders the blocks showing it. When the fetch fails they
+	 * keep showing the cached copy.
+	 *
+	 * @param url - URL of the file
+	 */
+	private async refreshRemoteSource(url: string): Promise<void> {
+		const result = await loadCachedRemoteFile(url, this.remoteCache, { maxAge: 0, force: true, now: Date.now() });
+		if (!result.succeeded || result.remoteStatus?.offline) {
+			new Notice(`Couldn't fetch ${url}`);
+			return;
+		}
 
-		if (displayTitle && containsTemplateVariables(displayTitle)) {
-			displayTitle = replaceTemplateVariables(displayTitle, fileMetadata);
+		for (const [notePath, blocks] of [...this.renderedBlocks]) {
+			const contents = new Set(blocks.filter(block => block.sourceUrl === url).map(block => block.rawContent));
+			if (contents.size > 0) {
+				await this.refreshBlocksForPath(notePath, contents);
+			}
 		}
+	}
 
-		// FOLD takes precedence over SCROLL
-		const enableCodeFolding = config.foldLines > 0;
-		const enableScrolling = !enableCodeFolding && config.scrollLines > 0;
+	/**
+	 * Arranges for a block with META.REFRESH to re-read its embedded file
+	 * after the interval, replacing any re-read already pending for it.
+	 * A file from a URL is fetched again whatever the age of its cached
+	 * copy; if the fetch fails the block is left as it is until the next
+	 * try. Nothing happens once the block has left the page.
+	 *
+	 * @param containerElement - The block's container
+	 * @param notePath - Path of the note containing the block
+	 * @param rawContent - The block's content, to find it again
+	 * @param config - The block's resolved settings
+	 */
+	private scheduleSourceRefresh(containerElement: HTMLElement, notePath: string, rawContent: string, config: ResolvedBlockConfig): void {
+		const pending = this.sourceRefreshTimers.get(containerElement);
+		if (pending !== undefined) {
+			window.clearTimeout(pending);
+			this.sourceRefreshTimers.delete(containerElement);
+		}
+		if (config.sourceRefreshInterval <= 0 || !config.sourcePath) return;
+
+		const sourcePath = config.sourcePath;
+		this.sourceRefreshTimers.set(containerElement, window.setTimeout(() => {
+			this.sourceRefreshTimers.delete(containerElement);
+			if (!containerElement.isConnected) return;
+
+			void (async () => {
+				if (isRemotePath(sourcePath)) {
+					const result = await loadCachedRemoteFile(sourcePath, this.remoteCache, { maxAge: 0, force: true, now: Date.now() });
+					if (!result.succeeded || result.remoteStatus?.offline) {
+						this.scheduleSourceRefresh(containerElement, notePath, rawContent, config);
+						return;
+					}
+				}
+				await this.refreshBlocksForPath(notePath, new Set([rawContent]));
+			})();
+		}, config.sourceRefreshInterval));
+	}
+
+	/**
+	 * Runs a block's META.QUERY: a Dataview query gives a line per result,
+	 * a search the code blocks it matches, each headed by a comment naming
+	 * its note when the block's language has comments.
+	 *
+	 * @param config - The block's resolved settings
+	 * @param notePath - Path of the note containing the block
+	 * @returns The code, or an error message
+	 */
+	private async runBlockQuery(config: ResolvedBlockConfig, notePath: string): Promise<{ code: string } | { error: string }> {
+		const commentSyntax = getCommentSyntax(resolveLanguageAlias(config.language, this.settings.languageAliases));
+
+		if (isDataviewQuery(config.sourceQuery)) {
+			const result = await runDataviewQuery(this.app, config.sourceQuery, notePath);
+			return 'error' in result ? result : { code: result.lines.join('\n') };
+		}
+
+		const matches = await runCodeQuery(this.app, parseCodeQuery(config.sourceQuery));
+		if (matches.length === 0) {
+			return { code: formatComment(`Nothing matches ${config.sourceQuery}`, commentSyntax) ?? '' };
+		}
+		return { code: formatCodeQueryMatches(matches, commentSyntax) };
+	}
+
+	/**
+	 * Re-renders every block built from a query (META.QUERY).
+	 */
+	private async refreshQueryBlocks(): Promise<void> {
+		for (const [notePath, blocks] of [...this.renderedBlocks]) {
+			const contents = new Set(blocks.filter(block => block.fromQuery).map(block => block.rawContent));
+			if (contents.size > 0) {
+				await this.refreshBlocksForPath(notePath, contents);
+			}
+		}
+	}
+
+	/**
+	 * Runs a block's code, with its output streamed into a panel under
+	 * the block. Each run replaces the last one's panel. When the output
+	 * is saved, the panel gives way to the note's ufence-output block once
+	 * the run ends. Prompts are stripped first, as on copy; code with
+	 * {{PLACEHOLDER}}s left in isn't run.
+	 *
+	 * @param containerElement - The block's container
+	 * @param code - The code to run: the block's, or one of its lines
+	 * @param language - The block's language, aliases resolved
+	 * @param options - Time limit, environment, and where the output is saved
+	 * @returns How the run ended, or undefined if it didn't start
+	 */
+	private async runCodeBlock(containerElement: HTMLElement, code: string, language: string, options: BlockRunOptions): Promise<RunResult | undefined> {
+		if (this.runningBlocks.has(containerElement)) {
+			new Notice('This block is still running');
+			return undefined;
+		}
+
+		const runnableCode = options.promptPattern ? stripPrompts(code, options.promptPattern) : code;
+		const placeholders = findPlaceholders(runnableCode);
+		if (placeholders.length > 0) {
+			new Notice(`Not run: fill in ${placeholders.map(name => `{{${name}}}`).join(', ')} first`);
+			return undefined;
+		}
+
+		const environment = this.resolveBlockEnvironment(options);
+		if (environment.missingSecrets.length > 0) {
+			new Notice(`Not run: no secret named ${environment.missingSecrets.join(', ')}. Add it under Secrets in the settings`);
+			return undefined;
+		}
+
+		const run = this.prepareRun(runnableCode, language, environment.env, options.notePath);
+		if (typeof run === 'string') {
+			new Notice(run);
+			return undefined;
+		}
+
+		const { saveContext } = options;
+
+		const controller = new AbortController();
+		containerElement.querySelector(`.${CSS_CLASSES.runOutput}`)?.remove();
+		const panel = createRunOutputPanel(undefined, () => { controller.abort(); });
+		containerElement.appendChild(panel.element);
+
+		const output: string[] = [];
+		const onOutput = (text: string, stream: OutputStream): void => {
+			const shown = maskSecrets(text, environment.secretValues);
+			panel.append(shown, stream);
+			if (saveContext) output.push(shown);
+		};
+
+		this.runningBlocks.set(containerElement, controller);
+		try {
+			const result = await run({ onOutput, timeoutMs: options.timeoutMs, signal: controller.signal });
+			panel.finish(result.exitCode, result.errorMessage, result.stopped);
+			if (saveContext) {
+				const saved = await this.saveRunOutput(containerElement, saveContext, describeRunResult(result.exitCode, result.errorMessage, result.stopped), output.join(''));
+				if (saved) panel.element.remove();
+			}
+			return result;
+		} finally {
+			this.runningBlocks.delete(containerElement);
+		}
+	}
+
+	/**
+	 * Saves a run's output in the note, in the ufence-output block after
+	 * the block that was run (added if it has none).
+	 *
+	 * @param containerElement - The block's container
+	 * @param processorContext - Processor context (locates the block in the note)
+	 * @param status - How the run ended
+	 * @param output - What the run printed
+	 * @returns Whether the output was saved
+	 */
+	private async saveRunOutput(containerElement: HTMLElement, processorContext: MarkdownPostProcessorContext, status: string, output: string): Promise<boolean> {
+		const sectionInfo = processorContext.getSectionInfo(containerElement);
+		const file = this.app.vault.getAbstractFileByPath(processorContext.sourcePath);
+		if (!sectionInfo || !(file instanceof TFile)) {
+			new Notice('Could not find this block in the note to save its output');
+			return false;
+		}
+
+		const kept = output.length > RUN_OUTPUT_MAX_LENGTH ? `${output.slice(0, RUN_OUTPUT_MAX_LENGTH)}\n… output truncated` : output;
+		await this.app.vault.process(file, data => writeOutputBlock(data.split('\n'), sectionInfo.lineEnd, status, kept).join('\n'));
+		return true;
+	}
+
+	/**
+	 * Fills in a block's environment variables: vault variables,
+	 * frontmatter fields and dates first, then secrets.
+	 *
+	 * @param options - The block's run options
+	 * @returns The variables, with the secrets they used
+	 */
+	private resolveBlockEnvironment(options: BlockRunOptions): RunEnvironment {
+		const variableContext = this.getCodeVariableContext(options.notePath);
+		const env: Record<string, string> = {};
+		for (const [name, value] of Object.entries(options.env)) {
+			env[name] = interpolateCodeVariables(value, variableContext);
+		}
+		return resolveRunEnvironment(env, loadSecrets(this.app));
+	}
+
+	/**
+	 * Works out how a block's code runs: with a runner another plugin
+	 * registered for its language if there is one, otherwise JavaScript
+	 * and TypeScript in a worker, shell languages in the user's shell
+	 * from the vault folder.
+	 *
+	 * @param code - The code the block shows
+	 * @param language - The block's language, aliases resolved
+	 * @param env - Environment variables for a shell block (scripts have no environment)
+	 * @param notePath - Vault path of the note holding the block
+	 * @returns Starts the run, or why the code can't be run
+	 */
+	private prepareRun(code: string, language: string, env: Record<string, string>, notePath: string): ((options: RunControlOptions) => Promise<RunResult>) | string {
+		if (this.contributions.hasRunner(language)) {
+			return (options) => this.contributions.run({ code, language, notePath, env }, options);
+		}
+
+		if (isScriptLanguage(language)) {
+			let script: string;
+			try {
+				script = prepareScript(code, language);
+			} catch (error) {
+				return error instanceof Error ? error.message : String(error);
+			}
+			return (options) => runScript(script, options);
+		}
+
+		const shell = loadDesktopShell();
+		const invocation = shell ? resolveShellInvocation(language, code, shell.environment) : null;
+		if (!shell || !invocation) return 'Running shell blocks needs the desktop app';
+
+		const vaultFolder = (this.app.vault.adapter as { getBasePath?: () => string }).getBasePath?.();
+		return (options) => runShellInvocation(invocation, { ...options, cwd: vaultFolder, env }, shell.spawn);
+	}
+
+	/**
+	 * Opens a block's code from a vault file for editing and, once
+	 * confirmed, writes it back over the lines the block shows. If the file
+	 * changed since the block was rendered the write is refused, so edits
+	 * made elsewhere aren't overwritten; the modify event re-renders blocks.
+	 *
+	 * @param filePath - Vault path of the file
+	 * @param shownCode - The code as the block shows it
+	 * @param config - The block's resolved settings
+	 */
+	private editSourceFile(filePath: string, shownCode: string, config: ResolvedBlockConfig): void {
+		const { filterByLines } = config;
+		new SourceEditModal(this.app, filePath, shownCode, async (editedCode) => {
+			const file = this.app.vault.getAbstractFileByPath(filePath);
+			if (!(file instanceof TFile)) {
+				new Notice(`Couldn't find ${filePath}`);
+				return false;
+			}
+
+			let writes = 0;
+			await this.app.vault.process(file, (data) => {
+				if (applyFilterChain(data, config).content !== shownCode) return data;
+				writes++;
+				return filterByLines.enabled
+					? replaceLineRange(data, filterByLines.start, filterByLines.end, editedCode, { inclusive: filterByLines.inclusive })
+					: editedCode;
+			});
+
+			if (writes === 0) {
+				new Notice(`${filePath} changed since the block was shown; nothing was written`);
+			}
+			return writes > 0;
+		}).open();
+	}
+
+	/**
+	 * Renders a sample code block styled by a preset, for the preview in
+	 * the preset editor. The preset's YAML may be unsaved; other presets it
+	 * extends are taken from the settings.
+	 *
+	 * @param containerElement - Element to render into (emptied first)
+	 * @param presetYaml       - The preset's YAML as currently edited
+	 * @param presetName       - The preset's saved name, if it has one
+	 */
+	async renderPresetPreview(containerElement: HTMLElement, presetYaml: string, presetName = ''): Promise<void> {
+		const presets = { ...this.settings.presets, [presetName]: presetYaml };
+		const presetConfig = resolvePresetConfig(presetName, presets, this.getPresetConditions());
+		const mergedConfig = deepMergeYamlConfigs({ META: { TITLE: 'deploy.sh' } }, presetConfig);
+		const config = resolveBlockConfig(mergedConfig, this.settings, PRESET_PREVIEW_LANGUAGE, this.contributions.themes());
+
+		const filterResult = applyFilterChain(PRESET_PREVIEW_CODE, config);
+		const sourceCode = filterResult.error ? PRESET_PREVIEW_CODE : filterResult.content;
+
+		containerElement.empty();
+		const blockElement = containerElement.createEl('div');
+		await this.renderCodeBlockContent(blockElement, {
+			config,
+			mergedConfig,
+			sourceCode,
+			fileMetadata: createEmbeddedCodeMetadata(config.titleTemplate, config.language),
+			notePath: '',
+		});
+	}
+
+	/**
+	 * Lists the conditions presets can depend on that currently hold: the
+	 * theme (dark or light) and the platform (mobile or desktop).
+	 */
+	private getPresetConditions(): Set<string> {
+		return new Set([
+			document.body.classList.contains('theme-dark') ? 'dark' : 'light',
+			Platform.isMobile ? 'mobile' : 'desktop',
+		]);
+	}
+
+	/**
+	 * Records the ufence blocks of every open note, as the baseline the
+	 * next edit is compared against.
+	 */
+	private snapshotEditorBlocks(): void {
+		this.app.workspace.iterateAllLeaves((leaf) => {
+			if (leaf.view instanceof MarkdownView && leaf.view.file && !this.editorBlocks.has(leaf.view.file.path)) {
+				this.editorBlocks.set(leaf.view.file.path, findUfenceBlocks(leaf.view.editor.getValue()));
+			}
+		});
+	}
+
+	/**
+	 * Re-renders the blocks an edit affected that Obsidian won't re-render
+	 * itself: blocks whose fence line changed (shorthand options, settings
+	 * format), and every block on the page when its ufence-ufence block
+	 * changed. Blocks whose content changed are re-rendered by Obsidian.
+	 *
+	 * @param content - Note content after the edit
+	 * @param notePath - Vault-relative path of the note
+	 */
+	private async refreshEditedBlocks(content: string, notePath: string): Promise<void> {
+		const current = findUfenceBlocks(content);
+		const previous = this.editorBlocks.get(notePath);
+		this.editorBlocks.set(notePath, current);
+		if (!previous) return;
+
+		const changes = diffUfenceBlocks(previous, current);
+		if (changes.pageConfigChanged) {
+			// The file on disk may lag behind the editor, so parse the edited text
+			this.setPageConfig(notePath, this.parsePageConfigFromContent(content) ?? {});
+			await this.refreshBlocksForPath(notePath);
+		} else if (changes.fenceLineChanged.length > 0) {
+			await this.refreshBlocksForPath(notePath, new Set(changes.fenceLineChanged.map(block => block.content)));
+		}
+	}
+
+	/**
+	 * Re-renders ufence blocks across ALL tracked pages.
+	 *
+	 * Called after settings changes so that every visible ufence block
+	 * reflects the updated defaults immediately.
+	 */
+	private async refreshAllBlocks(): Promise<void> {
+		for (const path of [...this.renderedBlocks.keys()]) {
+			await this.refreshBlocksForPath(path);
+		}
+	}
+
+	// ===========================================================================
+	// Block Processing
+	// ===========================================================================
+
+	/**
+	 * Processes a ufence code block.
+	 *
+	 * Handles both file references (META.PATH) and inline code
+	 * (~~~ separator). Uses the nested YAML configuration structure.
+	 *
+	 * @param rawContent - Raw block content
+	 * @param containerElement - Container element to render into
+	 * @param processorContext - Markdown processor context
+	 * @param defaultLanguage - Default language for syntax highlighting
+	 * @param inferLanguage - Use the language of an embedded file, by its name, over the default
+	 */
+	private async processUfenceBlock(
+		rawContent: string,
+		containerElement: HTMLElement,
+		processorContext: MarkdownPostProcessorContext,
+		defaultLanguage: string,
+		inferLanguage = false
+	): Promise<void> {
+		// Track this block so we can re-render it on demand (Force Refresh / settings change)
+		const path = processorContext.sourcePath;
+		if (!this.renderedBlocks.has(path)) {
+			this.renderedBlocks.set(path, []);
+		}
+		const renderedBlock: TrackedBlock = {
+			container: containerElement,
+			rawContent,
+			context: processorContext,
+			defaultLanguage,
+			inferLanguage,
+		};
+		this.renderedBlocks.get(path)?.push(renderedBlock);
+
+		// Parse block content
+		const fenceLine = this.getFenceLine(containerElement, processorContext) ?? '';
+		const configFormat = parseConfigFormatFromInfoString(fenceLine);
+		let parsedBlock;
+		try {
+			parsedBlock = parseBlockContent(rawContent, configFormat);
+		} catch (error) {
+			const formatName = (configFormat ?? detectConfigFormat(rawContent)).toUpperCase();
+			if (this.settings.configMode === 'strict') {
+				this.renderConfigDiagnostics(containerElement, [`Invalid ${formatName}: ${error instanceof Error ? error.message : String(error)}`]);
+			} else {
+				await this.renderErrorMessage(containerElement, `invalid embedding (invalid ${formatName})`);
+			}
+			return;
+		}
+
+		// Fence line shorthand ({ln title="…"}) is a cascade layer of its own, over the settings section
+		const shorthand = parseInfoStringOptions(fenceLine);
+		const shorthandConfig = parseNestedYamlConfig(shorthand.settings);
+		let blockSettings = parsedBlock.yamlProperties;
+		const schemaWarnings = validateYamlSchema(blockSettings, CODE_BLOCK_SCHEMA);
+
+		// A block naming another's ID (META.REF) shows that block, with its own settings on top
+		const referenceId = readReferenceId(shorthand.settings) || readReferenceId(blockSettings);
+		let showsReferencedCode = false;
+		if (referenceId) {
+			renderedBlock.reference = referenceId;
+			const reference = (await this.getBlockReferenceIndex()).find(referenceId);
+			const referencedSettings = reference ? readBlockSettings(reference.block) : null;
+			if (!reference || !referencedSettings) {
+				await this.renderErrorMessage(containerElement, `no block with ID '${referenceId}' - give one META.ID or a ^${referenceId} line after it`);
+				return;
+			}
+
+			renderedBlock.referencedNote = reference.path;
+			blockSettings = mergeReferencedSettings(referencedSettings, blockSettings);
+			if (!parsedBlock.embeddedCode?.trim()) {
+				parsedBlock = parseBlockContent(reference.block.content, parseConfigFormatFromInfoString(reference.block.fenceLine));
+				showsReferencedCode = true;
+			}
+			// The code is in the referenced block's language unless this block says otherwise
+			defaultLanguage = reference.block.blockType;
+		}
+
+		// Parse nested YAML configuration and resolve with defaults; the block's
+		// own settings (shorthand over the settings section) name its language and file
+		const yamlConfig = parseNestedYamlConfig(blockSettings);
+		const ownConfig = deepMergeYamlConfigs(yamlConfig, shorthandConfig);
+
+		// A block that doesn't name a language shows an embedded file in the file's language
+		if (inferLanguage) {
+			defaultLanguage = this.inferSourceLanguage(ownConfig) ?? defaultLanguage;
+		}
+		const renameWarnings = schemaWarnings.filter(warning => warning.renamedTo !== undefined);
+		const configWarnings = [
+			...shorthand.problems,
+			...schemaWarnings.filter(warning => warning.renamedTo === undefined).map(formatWarning),
+		];
+
+		// Apply preset and/or page-level defaults (if any)
+		const pageConfig = await this.getPageConfig(processorContext.sourcePath);
+		let mergedConfig = resolvePreset(
+			yamlConfig,
+			this.settings.presets,
+			pageConfig,
+			this.getFrontmatterConfig(processorContext.sourcePath),
+			findDefaultPreset(ownConfig.RENDER?.LANG ?? defaultLanguage, this.settings, this.getNoteTags(processorContext.sourcePath)),
+			this.getPresetConditions(),
+			this.settings.configCascade,
+			findFolderPreset(processorContext.sourcePath, this.settings.folderPresets),
+			shorthandConfig
+		);
+
+		let config = resolveBlockConfig(mergedConfig, this.settings, defaultLanguage, this.contributions.themes());
+
+		if (config.configMode === 'strict' && configWarnings.length > 0) {
+			this.renderConfigDiagnostics(containerElement, configWarnings);
+			return;
+		}
+
+		let sourceCode = '';
+		let fileMetadata: SourceFileMetadata;
+		let remoteStatus: RemoteSourceStatus | undefined;
+
+		// Determine source; an empty block whose fence line names a file ({src=…}) or query loads it
+		const embedsCode = parsedBlock.hasEmbeddedCode
+			&& !((config.sourcePath || config.sourceQuery) && !parsedBlock.embeddedCode?.trim());
+		if (embedsCode) {
+			sourceCode = parsedBlock.embeddedCode ?? '';
+			if (config.language.toLowerCase() === AUTO_LANGUAGE) {
+				({ config, mergedConfig } = this.resolveDetectedLanguage(mergedConfig, sourceCode));
+			}
+			fileMetadata = createEmbeddedCodeMetadata(config.titleTemplate, config.language);
+		} else if (config.sourceQuery) {
+			// Followed even if it fails, so the block updates once the query can run
+			renderedBlock.fromQuery = true;
+
+			const queryResult = await this.runBlockQuery(config, processorContext.sourcePath);
+			if ('error' in queryResult) {
+				await this.renderErrorMessage(containerElement, queryResult.error);
+				return;
+			}
+
+			sourceCode = queryResult.code;
+			fileMetadata = createEmbeddedCodeMetadata(config.titleTemplate, config.language);
+		} else {
+			if (!config.sourcePath) {
+				await this.renderErrorMessage(containerElement, 'invalid source - use META.PATH, META.SRC or ~~~ separator for inline code');
+				return;
+			}
+
+			// Followed even if it can't be read yet, so the block updates once it can
+			if (isVaultPath(config.sourcePath)) {
+				renderedBlock.sourceFile = config.sourcePath.replace(/^vault:\/\//, '');
+			} else if (isRemotePath(config.sourcePath)) {
+				renderedBlock.sourceUrl = config.sourcePath;
+			}
+			this.scheduleSourceRefresh(containerElement, processorContext.sourcePath, rawContent, config);
+
+			const loadResult: CachedRemoteLoadResult = isRemotePath(config.sourcePath)
+				? await loadCachedRemoteFile(config.sourcePath, this.remoteCache, {
+					maxAge: this.settings.remoteCacheMinutes * 60 * 1000,
+					now: Date.now(),
+				})
+				: await loadSource(this.app, config.sourcePath, gitRepositoryPaths(this.settings.gitRepositories));
+
+			if (!loadResult.succeeded) {
+				await this.renderErrorMessage(containerElement, loadResult.errorMessage ?? 'failed to load source');
+				return;
+			}
+
+			sourceCode = loadResult.sourceCode;
+			remoteStatus = loadResult.remoteStatus;
+			if (config.language.toLowerCase() === AUTO_LANGUAGE) {
+				({ config, mergedConfig } = this.resolveDetectedLanguage(mergedConfig, sourceCode));
+			}
+			fileMetadata = loadResult.fileMetadata ?? createEmbeddedCodeMetadata('', config.language);
+		}
+
+		// Apply filter chain (BY_LINES first, then BY_MARKS)
+		const filterResult = applyFilterChain(sourceCode, config);
+
+		if (filterResult.error) {
+			await this.renderErrorMessage(containerElement, filterResult.error);
+			return;
+		}
+
+		sourceCode = config.renderVariables
+			? interpolateCodeVariables(filterResult.content, this.getCodeVariableContext(processorContext.sourcePath))
+			: filterResult.content;
+
+		// A file from git is at a ref, not on disk or the web, so there is nothing to open
+		const clickablePath = embedsCode || config.sourceQuery || !config.sourcePath || detectSourceLocationType(config.sourcePath) === 'git'
+			? undefined
+			: (isRemotePath(config.sourcePath)
+				? config.sourcePath
+				: config.sourcePath.replace(/^vault:\/\//, ''));
+
+		// Marked sections lose their markers when shown, so only line ranges and whole files are editable;
+		// filled-in variables would be written back as their values
+		const editableFile = this.settings.showEditButton && !embedsCode && !config.filterByMarks.enabled && !config.filterByRegion && !config.renderVariables
+			? renderedBlock.sourceFile
+			: undefined;
+
+		// A long embedded file shows its first lines, with more loaded on request
+		const sourceLines = sourceCode.split('\n');
+		const loadedLineCount = this.loadedLineCounts.get(containerElement) ?? this.settings.progressiveLoadLines;
+		const partialLoad = !embedsCode && this.settings.progressiveLoadLines > 0 && sourceLines.length > loadedLineCount
+			? {
+				fullCode: sourceCode,
+				totalLines: sourceLines.length,
+				onLoadMore: () => {
+					this.loadedLineCounts.set(containerElement, loadedLineCount + PROGRESSIVE_LOAD_CHUNK_LINES);
+					void this.refreshBlocksForPath(processorContext.sourcePath, new Set([rawContent]));
+				},
+			}
+			: undefined;
+
+		const isYaml = (configFormat ?? detectConfigFormat(rawContent)) === 'yaml';
+		const runLanguage = resolveLanguageAlias(config.language, this.settings.languageAliases);
+		// Only code written in the block runs; a file, URL, git ref, query or referenced block can change without it showing
+		const canRun = embedsCode && !showsReferencedCode && this.isRunningCodeAllowed() && config.runEnabled
+			&& (this.contributions.hasRunner(runLanguage) || isScriptLanguage(runLanguage) || (Platform.isDesktopApp && isShellLanguage(runLanguage)));
+		const runOptions: BlockRunOptions = { timeoutMs: config.runTimeoutMs, env: config.runEnv, notePath: processorContext.sourcePath, promptPattern: config.promptPattern };
+		// Plain code comes back whole as the embedded code
+		const hasSettings = !parsedBlock.hasEmbeddedCode || parsedBlock.embeddedCode !== rawContent;
+
+		await this.renderCodeBlockContent(containerElement, {
+			config,
+			mergedConfig,
+			sourceCode: partialLoad ? sourceLines.slice(0, loadedLineCount).join('\n') : sourceCode,
+			fileMetadata,
+			notePath: processorContext.sourcePath,
+			blockText: rawContent,
+			clickablePath,
+			changedLines: embedsCode
+				? await this.findLinesChangedSinceSnapshot(containerElement, processorContext, sourceCode, config, configFormat)
+				: [],
+			remoteStatus,
+			// The editor needs every line the block shows
+			onEdit: editableFile && !partialLoad
+				? () => { this.editSourceFile(editableFile, sourceCode, config); }
+				: undefined,
+			partialLoad,
+			// Runs every line, even of a long file shown in part
+			onRun: canRun
+				? () => { void this.runCodeBlock(containerElement, sourceCode, runLanguage, { ...runOptions, saveContext: config.runSaveOutput ? processorContext : undefined }); }
+				: undefined,
+			// A single line's output is only shown, never saved
+			onRunLine: canRun && config.runLines
+				? (line) => this.runCodeBlock(containerElement, line, runLanguage, runOptions)
+				: undefined,
+			blockId: config.blockId || this.getFenceBlockId(containerElement, processorContext),
+			// Only YAML settings can be rewritten in place
+			onLanguageBadgeClick: config.languageDetected && isYaml
+				? (event) => { this.showLanguageMenu(event, containerElement, processorContext, config.language, hasSettings); }
+				: undefined,
+		});
+
+		this.renderConfigWarnings(containerElement, configWarnings);
+		this.renderRenameNotice(containerElement, processorContext, renameWarnings, isYaml);
+
+		if (isYaml) {
+			this.attachPresetSwitcher(containerElement, processorContext, ownConfig.META?.PRESET, hasSettings);
+		}
+	}
+
+	/**
+	 * Finds the lines of a block's embedded code that changed since the
+	 * snapshot of its note picked by RENDER.CHANGED_SINCE. The block's
+	 * earlier version is filtered with its current settings, so the line
+	 * numbers match what is rendered.
+	 *
+	 * @param containerElement - Block container
+	 * @param processorContext - Processor context
+	 * @param sourceCode - The block's code, filtered
+	 * @param config - The block's resolved settings
+	 * @param configFormat - Settings format from the fence line, if given
+	 * @returns Changed line numbers (1-based); none when off or without a snapshot
+	 */
+	private async findLinesChangedSinceSnapshot(
+		containerElement: HTMLElement,
+		processorContext: MarkdownPostProcessorContext,
+		sourceCode: string,
+		config: ResolvedBlockConfig,
+		configFormat: ConfigFormat | undefined
+	): Promise<number[]> {
+		const age = parseSnapshotAge(config.changedSince);
+		const sectionInfo = age === null ? null : processorContext.getSectionInfo(containerElement);
+		if (age === null || !sectionInfo) return [];
+
+		const block = findUfenceBlocks(sectionInfo.text).find(candidate => candidate.line === sectionInfo.lineStart + 1);
+		if (!block) return [];
+
+		const snapshots = await readFileRecoverySnapshots(this.app, processorContext.sourcePath);
+		const snapshot = pickSnapshot(snapshots, sectionInfo.text, age, Date.now());
+		if (!snapshot) return [];
+
+		// A block that isn't in the snapshot is new, every line of it
+		const previous = matchSnapshotBlock(block, findUfenceBlocks(snapshot.content));
+		if (!previous) return sourceCode.split('\n').map((_line, index) => index + 1);
+
+		let previousCode: string;
+		try {
+			previousCode = applyFilterChain(parseBlockContent(previous.content, configFormat).embeddedCode ?? '', config).content;
+		} catch {
+			// Its settings back then no longer parse; compare against nothing
+			previousCode = '';
+		}
+		return findChangedLines(previousCode, sourceCode);
+	}
+
+	/**
+	 * Finds the language of the file a block embeds from the file's name,
+	 * for a block that doesn't name a language.
+	 *
+	 * @param yamlConfig - The block's own settings
+	 * @returns The file's language, or undefined if the block names one, embeds no file or the name doesn't say
+	 */
+	private inferSourceLanguage(yamlConfig: ParsedYamlConfig): string | undefined {
+		if (yamlConfig.RENDER?.LANG) return undefined;
+
+		const sourcePath = yamlConfig.META?.PATH ?? (yamlConfig.META?.SRC ? parseSourceReference(yamlConfig.META.SRC)?.path : undefined);
+		return sourcePath ? languageFromPath(sourcePath, this.settings.languageAliases) : undefined;
+	}
+
+	/**
+	 * Resolves a block's settings again with the language guessed from its
+	 * code, for a block whose language is "auto". Settings that depend on
+	 * the language (tab width, diff view, shebang) follow the guess; the
+	 * header shows it in a badge so it can be corrected.
+	 *
+	 * @param mergedConfig - The block's merged settings
+	 * @param sourceCode   - The block's code
+	 * @returns Settings resolved for the guessed language ("text" if none stands out)
+	 */
+	private resolveDetectedLanguage(
+		mergedConfig: ParsedYamlConfig,
+		sourceCode: string
+	): { config: ResolvedBlockConfig; mergedConfig: ParsedYamlConfig } {
+		const language = detectLanguage(sourceCode) ?? 'text';
+		const detectedConfig: ParsedYamlConfig = { ...mergedConfig, RENDER: { ...mergedConfig.RENDER, LANG: language } };
+		return {
+			config: {
+				...resolveBlockConfig(detectedConfig, this.settings, language, this.contributions.themes()),
+				languageDetected: true,
+				showLanguageBadge: true,
+			},
+			mergedConfig: detectedConfig,
+		};
+	}
+
+	/**
+	 * Renders a block's code with its title bar, buttons and callouts,
+	 * once its settings are resolved and its source is loaded and filtered.
+	 *
+	 * @param containerElement - The block container
+	 * @param content          - Resolved settings, code and where it comes from
+	 */
+	private async renderCodeBlockContent(containerElement: HTMLElement, content: RenderedCodeContent): Promise<void> {
+		const { config, mergedConfig, sourceCode, fileMetadata, notePath, blockText, clickablePath, blockId = '', onLanguageBadgeClick, changedLines, remoteStatus, onEdit, partialLoad, onRun, onRunLine } = content;
+
+		// Resolve display options
+		const shouldHideTitle = config.titleTemplate === '' || config.titleTemplate.toLowerCase() === 'none' || config.titleBarStyle === 'none';
+
+		let displayTitle = config.titleTemplate;
+
+		if (displayTitle && containsTemplateVariables(displayTitle)) {
+			displayTitle = replaceTemplateVariables(displayTitle, fileMetadata);
+		}
+
+		// FOLD takes precedence over MAX_HEIGHT, which takes precedence over SCROLL
+		const enableCodeFolding = config.foldLines > 0;
+		const maxHeight = enableCodeFolding ? undefined : config.maxHeight;
+		const enableScrolling = !enableCodeFolding && !maxHeight && config.scrollLines > 0;
 
 		const totalLineCount = countSourceLines(sourceCode);
+		const showMinimap = config.minimapLines > 0 && totalLineCount > config.minimapLines;
+		const calloutConfig = resolveCalloutConfig(mergedConfig.CALLOUT, sourceCode, totalLineCount);
+
+		// Embedded languages and bundled grammars need Prism before rendering
+		const highlightLanguage = resolveLanguageAlias(config.language, this.settings.languageAliases);
+		const injections = findLanguageInjections(this.settings.languageInjections, highlightLanguage);
+		const prism = injections.length > 0 || isBundledGrammar(highlightLanguage) ? await this.loadHighlighter() : null;
 
 		// Render the code block with a short-lived component — unloaded immediately
 		// after rendering since the output is static HTML with no ongoing lifecycle.
@@ -581,17 +2293,94 @@ export default class UltraCodeFence extends Plugin {
 		);
 		renderComponent.unload();
 
+		const highlightedCode = findCodeElement(containerElement);
+		if (highlightedCode) {
+			await this.highlightWithBackend(highlightedCode, sourceCode, highlightLanguage);
+		}
+		if (prism && highlightedCode && injections.length > 0) {
+			highlightEmbeddedLanguages(highlightedCode, injections, (code, language) => {
+				const grammar = prism.languages[resolveLanguageAlias(language, this.settings.languageAliases)];
+				return grammar ? prism.tokenize(code, grammar) : null;
+			});
+		}
+		if (config.semanticHighlighting && highlightedCode) {
+			addSemanticTokens(highlightedCode, highlightLanguage);
+		}
+
 		// Process code block (line numbers, zebra, scrolling)
 		processCodeBlock(containerElement, {
 			showLineNumbers: config.showLineNumbers,
 			showZebraStripes: config.showZebraStripes,
-			startingLineNumber: 1,
+			zebraColour: config.zebraColour,
+			lineHover: config.showLineHover,
+			startingLineNumber: config.startingLineNumber,
+			anchorLine: config.anchorLine,
 			scrollLines: enableScrolling ? config.scrollLines : 0,
+			forceLineWrapping: config.showLineCopyButtons || onRunLine !== undefined || showMinimap || calloutConfig.enabled || blockId !== '',
+			promptPattern: config.promptPattern,
+			highlightLines: config.highlightLines,
+			highlightPattern: config.highlightPattern,
+			highlightTerms: config.highlightTerms,
+			changedLines,
+			focusLines: config.focusLines,
+			markPlaceholders: config.copyPlaceholders,
+			redactPatterns: config.redactPatterns,
+			foldRegions: config.foldRegionsMode === 'none'
+				? []
+				: findFoldRegions(sourceCode, config.foldStartPattern, config.foldEndPattern),
+			collapseFoldRegions: config.foldRegionsMode === 'collapsed',
+			indentGuides: config.showIndentGuides
+				? { colour: config.indentGuideColour, style: config.indentGuideStyle }
+				: undefined,
+			whitespaceMode: config.whitespaceMode,
+			commentKeywords: config.commentKeywordMode === 'off' ? [] : config.commentKeywords,
+			commentKeywordIcons: config.commentKeywordMode === 'icons',
+			bracketPairs: config.bracketPairColours,
+			annotations: this.contributions.annotate(config.annotations, { code: sourceCode, language: config.language, notePath }),
+			blame: config.blame,
+			ligatures: config.fontLigatures,
+			fontFeatures: config.fontFeatures,
+			fontFamily: config.fontFamily,
+			fontSize: config.fontSize,
+			tabSize: config.tabSize,
+			rulers: config.rulerColumns.length > 0
+				? { columns: config.rulerColumns, colour: config.rulerColour }
+				: undefined,
+			textDirection: config.textDirection || undefined,
+			printLineNumbers: config.printLineNumbers,
+			wrapIndents: this.settings.showWrapButton,
+			tokenStyles: config.tokenStyles,
+			patternStyles: config.patternStyles,
+			highlightTheme: config.highlightTheme,
+			diff: config.unifiedDiff || config.splitDiff || config.diffAddedLines.length > 0 || config.diffRemovedLines.length > 0
+				? {
+					unified: config.unifiedDiff,
+					addedLines: config.diffAddedLines,
+					removedLines: config.diffRemovedLines,
+					words: config.diffWordHighlight,
+				}
+				: undefined,
 		});
 
-		// Resolve and inject callouts (must happen after processCodeBlock
-		// creates the ucf-line DOM structure that callouts attach to)
-		const calloutConfig = resolveCalloutConfig(mergedConfig.CALLOUT, sourceCode, totalLineCount);
+		// Split diffs copy the marked lines, so build them before callouts
+		// add their own elements to those lines
+		if (config.splitDiff) {
+			const codeEl = findCodeElement(containerElement);
+			const preEl = findPreElement(containerElement);
+			if (codeEl && preEl) {
+				addSplitDiffView(preEl, codeEl, config.diffOldRegion
+					? { oldRegion: config.diffOldRegion, newRegion: config.diffNewRegion }
+					: undefined, config.diffWordHighlight);
+			}
+		}
+
+		// Prompt colour follows the cmdout prompt setting
+		if (config.promptPattern && this.settings.commandPromptColour) {
+			findPreElement(containerElement)?.style.setProperty('--ucf-prompt-colour', this.settings.commandPromptColour);
+		}
+
+		// Inject callouts (must happen after processCodeBlock creates the
+		// ucf-line DOM structure that callouts attach to)
 		if (calloutConfig.enabled) {
 			const codeEl = findCodeElement(containerElement);
 			const preEl = findPreElement(containerElement);
@@ -605,24 +2394,68 @@ export default class UltraCodeFence extends Plugin {
 		if (preElementForPrint) {
 			preElementForPrint.dataset.ucfPrint = config.printBehaviour;
 		}
+		applyPrintProfile(containerElement, config.printTheme, config.printAvoidBreaks);
 
-		// Build download callback — prefer source filename over display title
-		const downloadName = fileMetadata.filename || displayTitle || '';
+		// Build download callback — a filename template wins, then source filename, then display title
+		const downloadName = config.downloadFilenameTemplate
+			? replaceTemplateVariables(config.downloadFilenameTemplate, fileMetadata)
+			: fileMetadata.filename || displayTitle || '';
 		const suggestedFilename = buildSuggestedFilename(downloadName, config.language);
 		const onDownload = this.settings.showDownloadButton
 			? (codeText: string) => {
-				downloadCodeToFile(codeText, suggestedFilename);
+				const fileText = injectShebang(codeText, config.downloadShebang);
+				if (config.downloadExecutable) {
+					void this.saveExecutableScript(fileText, suggestedFilename, notePath);
+				} else {
+					downloadCodeToFile(fileText, suggestedFilename);
+				}
 			}
 			: undefined;
 
-		// Add title or just buttons
-		if (!shouldHideTitle && displayTitle) {
-			const clickablePath = parsedBlock.hasEmbeddedCode || !config.sourcePath
-				? undefined
-				: (isRemotePath(config.sourcePath)
-					? config.sourcePath
-					: config.sourcePath.replace(/^vault:\/\//, ''));
+		const copyUsageKey = buildCopyUsageKey(notePath, blockText ?? partialLoad?.fullCode ?? sourceCode);
+		const buttonOptions: CodeButtonOptions = {
+			showCopyButton: config.showCopyButton,
+			showLineCopyButtons: config.showLineCopyButtons,
+			copyAsEntries: config.copyAsEntries,
+			showDownloadButton: this.settings.showDownloadButton,
+			totalLineCount,
+			foldLines: config.foldLines,
+			maxHeight,
+			shiftCopyJoin: config.shiftCopyJoin,
+			altCopyJoin: config.altCopyJoin,
+			joinIgnoreRegex: config.joinIgnoreRegex,
+			promptPattern: config.promptPattern,
+			commentSyntax: config.stripComments ? getCommentSyntax(resolveLanguageAlias(config.language, this.settings.languageAliases)) : undefined,
+			redactPatterns: config.redactPatterns,
+			copyDiffAfter: config.copyDiffAfter,
+			fullText: partialLoad?.fullCode,
+			fillPlaceholders: this.createPlaceholderFiller(config, notePath),
+			onCopied: this.createCopyHandler(displayTitle, notePath, config.copyFeedback, copyUsageKey),
+			feedback: config.copyFeedback,
+			copyCount: this.getCopyCountBadgeValue(copyUsageKey),
+			onDownload,
+			onEdit,
+			onRun,
+			onRunLine,
+			softWrapped: this.settings.showWrapButton ? this.settings.wrapStates[copyUsageKey] === true : undefined,
+			onWrapToggled: (wrapped: boolean) => {
+				if (wrapped) {
+					this.settings.wrapStates[copyUsageKey] = true;
+				} else {
+					delete this.settings.wrapStates[copyUsageKey];
+				}
+				this.requestCopyCountSave();
+			},
+			showFocusToggle: config.showFocusToggle,
+		};
+
+		// Add the header or just buttons. A language badge or header buttons keep
+		// the header even when the title is hidden
+		const showTitleText = !shouldHideTitle && Boolean(displayTitle) && config.showHeaderTitle;
+		const showHeader = config.titleBarStyle !== 'none'
+			&& (showTitleText || config.showLanguageBadge || config.buttonsInHeader);
 
+		if (showHeader) {
 			await this.attachTitleBarToCodeBlock(containerElement, {
 				titleText: displayTitle,
 				clickablePath,
@@ -630,30 +2463,181 @@ export default class UltraCodeFence extends Plugin {
 				fileMetadata,
 				language: config.language,
 				descriptionText: config.descriptionText,
-				containingNotePath: processorContext.sourcePath,
-				showCopyButton: config.showCopyButton,
-				totalLineCount,
-				foldLines: config.foldLines,
-				shiftCopyJoin: config.shiftCopyJoin,
-				altCopyJoin: config.altCopyJoin,
-				joinIgnoreRegex: config.joinIgnoreRegex,
-				showDownloadButton: this.settings.showDownloadButton,
-				onDownload,
+				containingNotePath: notePath,
+				buttons: buttonOptions,
+				headerIcon: config.headerIcon,
+				showTitleText,
+				showLanguageBadge: config.showLanguageBadge,
+				onLanguageBadgeClick,
+				buttonsInHeader: config.buttonsInHeader,
 			});
 		} else {
 			const preElement = findPreElement(containerElement);
 			if (preElement) {
-				addCodeBlockButtons(preElement, {
-					showCopyButton: config.showCopyButton,
-					showDownloadButton: this.settings.showDownloadButton,
-					totalLineCount,
-					foldLines: config.foldLines,
-					shiftCopyJoin: config.shiftCopyJoin,
-					altCopyJoin: config.altCopyJoin,
-					joinIgnoreRegex: config.joinIgnoreRegex,
-					onDownload,
-				});
+				addCodeBlockButtons(preElement, buttonOptions);
+			}
+		}
+
+		// Attribution footer under the code
+		const footerElement = createFooterElement({
+			source: config.footerSource,
+			url: config.footerUrl,
+			license: config.footerLicense,
+		});
+		const preElementForFooter = findPreElement(containerElement);
+		if (footerElement && preElementForFooter) {
+			preElementForFooter.parentElement?.insertBefore(footerElement, preElementForFooter.nextSibling);
+		}
+
+		// How old code from a URL is, with a button to fetch it again
+		if (remoteStatus && preElementForFooter) {
+			const statusElement = createRemoteStatusElement(remoteStatus, Date.now(), () => this.refreshRemoteSource(remoteStatus.url));
+			preElementForFooter.parentElement?.insertBefore(statusElement, (footerElement ?? preElementForFooter).nextSibling);
+		}
+
+		// The rest of a long file, a chunk at a time, right under the lines shown
+		if (partialLoad && preElementForFooter) {
+			const loadMoreElement = createLoadMoreElement(totalLineCount, partialLoad.totalLines, PROGRESSIVE_LOAD_CHUNK_LINES, partialLoad.onLoadMore);
+			preElementForFooter.parentElement?.insertBefore(loadMoreElement, preElementForFooter.nextSibling);
+		}
+
+		// Collapse last, below the title and with the buttons in place
+		if (config.startCollapsed) {
+			const preElement = findPreElement(containerElement);
+			if (preElement) {
+				const summaryText = containsTemplateVariables(config.summaryText)
+					? replaceTemplateVariables(config.summaryText, fileMetadata)
+					: config.summaryText;
+				addCollapseToggle(containerElement, preElement, summaryText, totalLineCount);
+			}
+		}
+
+		// Minimap beside the finished block
+		if (showMinimap) {
+			const preElement = findPreElement(containerElement);
+			if (preElement) {
+				addMinimap(preElement);
+			}
+		}
+
+		// Line links, once the block is finished so a followed link lands on its final layout
+		if (blockId) {
+			const codeEl = findCodeElement(containerElement);
+			const preEl = findPreElement(containerElement);
+			if (codeEl && preEl) {
+				addLineLinks(preEl, codeEl, blockId, config.startingLineNumber, notePath
+					? (startLine, endLine) => { void this.copyLineLink(notePath, blockId, startLine, endLine); }
+					: undefined);
+				this.revealPendingLineLink(notePath, preEl);
+			}
+		}
+	}
+
+	/**
+	 * Copies a link to lines of a block, in the vault's link format.
+	 *
+	 * @param notePath - Path of the note containing the block
+	 * @param blockId - Block ID
+	 * @param startLine - First line
+	 * @param endLine - Last line
+	 */
+	private async copyLineLink(notePath: string, blockId: string, startLine: number, endLine: number): Promise<void> {
+		const subpath = formatLineLinkSubpath(blockId, startLine, endLine);
+		const file = this.app.vault.getAbstractFileByPath(notePath);
+		const link = file instanceof TFile
+			? this.app.fileManager.generateMarkdownLink(file, notePath, subpath)
+			: `[[${subpath}]]`;
+
+		await navigator.clipboard.writeText(link);
+		new Notice(startLine === endLine
+			? `Copied link to line ${String(startLine)}`
+			: `Copied link to lines ${String(startLine)}-${String(endLine)}`);
+	}
+
+	/**
+	 * Follows a clicked internal link that addresses lines of a block,
+	 * leaving every other click to Obsidian.
+	 *
+	 * @param event - Click anywhere in the app
+	 */
+	private handleLineLinkClick(event: MouseEvent): void {
+		const linkElement = (event.target as HTMLElement | null)?.closest<HTMLElement>('a.internal-link');
+		if (!linkElement) return;
+
+		const lineLink = parseLineLink(linkElement.dataset.href ?? linkElement.getAttribute('href') ?? '');
+		if (!lineLink) return;
+
+		event.preventDefault();
+		event.stopPropagation();
+		void this.openLineLink(lineLink, this.app.workspace.getActiveFile()?.path ?? '');
+	}
+
+	/**
+	 * Opens the note a line link points to and reveals its lines. When the
+	 * note has to render first, its block reveals them as it renders.
+	 *
+	 * @param lineLink - The followed link
+	 * @param sourcePath - Path of the note the link is in
+	 */
+	private async openLineLink(lineLink: LineLink, sourcePath: string): Promise<void> {
+		const file = lineLink.path
+			? this.app.metadataCache.getFirstLinkpathDest(lineLink.path, sourcePath)
+			: this.app.vault.getAbstractFileByPath(sourcePath);
+		if (!(file instanceof TFile)) return;
+
+		this.pendingLineLink = { ...lineLink, path: file.path, requestedAt: Date.now() };
+
+		if (file.path !== sourcePath) {
+			await this.app.workspace.openLinkText(lineLink.path, sourcePath);
+		}
+
+		// The note may already be rendered
+		const view = this.app.workspace.getActiveViewOfType(MarkdownView);
+		const preElement = view?.containerEl.querySelector<HTMLElement>(`pre[data-ucf-block-id="${lineLink.blockId}"]`);
+		if (preElement) {
+			this.revealPendingLineLink(file.path, preElement);
+		}
+	}
+
+	/**
+	 * Reveals the lines of the pending line link if it points at this block.
+	 *
+	 * @param notePath - Path of the note containing the block
+	 * @param preElement - The block's pre element, tagged by addLineLinks
+	 */
+	private revealPendingLineLink(notePath: string, preElement: HTMLElement): void {
+		const pending = this.pendingLineLink;
+		if (!pending) return;
+
+		if (Date.now() - pending.requestedAt > LINE_LINK_WAIT_MS) {
+			this.pendingLineLink = null;
+			return;
+		}
+
+		if (pending.path === notePath
+			&& preElement.dataset.ucfBlockId === pending.blockId
+			&& revealLines(preElement, pending.startLine, pending.endLine)) {
+			this.pendingLineLink = null;
+		}
+	}
+
+	/**
+	 * Saves a script with the executable bit set, starting the save dialog
+	 * in the directory last used for the same note.
+	 *
+	 * @param fileText - Script content (shebang already applied)
+	 * @param suggestedFilename - Default filename for the dialog
+	 * @param notePath - Path of the note containing the block
+	 */
+	private async saveExecutableScript(fileText: string, suggestedFilename: string, notePath: string): Promise<void> {
+		try {
+			const savedPath = await saveExecutableFile(fileText, suggestedFilename, this.settings.downloadPathHistory[notePath] ?? '');
+			if (savedPath) {
+				this.settings.downloadPathHistory[notePath] = savedPath.replace(/[\\/][^\\/]*$/, '');
+				await this.saveSettings();
 			}
+		} catch (error) {
+			new Notice(`Could not save ${suggestedFilename}: ${error instanceof Error ? error.message : String(error)}`);
 		}
 	}
 
@@ -673,22 +2657,49 @@ export default class UltraCodeFence extends Plugin {
 	): Promise<void> {
 		let outputCode = '';
 		let config;
+		let configWarnings: string[] = [];
+		let renameWarnings: YamlWarning[] = [];
+		const fenceLine = this.getFenceLine(containerElement, processorContext) ?? '';
+		const configFormat = parseConfigFormatFromInfoString(fenceLine);
+		const shorthand = parseInfoStringOptions(fenceLine);
 
 		// Parse content
 		try {
-			const parsedBlock = parseBlockContent(rawContent);
+			const parsedBlock = parseBlockContent(rawContent, configFormat);
 			outputCode = parsedBlock.hasEmbeddedCode ? (parsedBlock.embeddedCode ?? '') : rawContent;
 
-			// Parse nested YAML configuration and resolve with defaults
-			const yamlConfig = parseNestedYamlConfig(parsedBlock.yamlProperties);
+			// Parse nested YAML configuration and resolve with defaults. Of the cascade, only
+			// the settings section and the fence line shorthand apply, in the cascade's order
+			const blockConfig = parseNestedYamlConfig(parsedBlock.yamlProperties);
+			const shorthandConfig = parseNestedYamlConfig(shorthand.settings);
+			const yamlConfig = getActiveCascadeLayers(this.settings.configCascade).reduce<ParsedYamlConfig>((merged, layer) => {
+				if (layer === 'block') return deepMergeYamlConfigs(merged, blockConfig);
+				return layer === 'shorthand' ? deepMergeYamlConfigs(merged, shorthandConfig) : merged;
+			}, {});
 			config = resolveCmdoutConfig(yamlConfig, this.settings);
-		} catch {
+			const schemaWarnings = validateYamlSchema(parsedBlock.yamlProperties, CMDOUT_BLOCK_SCHEMA);
+			renameWarnings = schemaWarnings.filter(warning => warning.renamedTo !== undefined);
+			configWarnings = [
+				...shorthand.problems,
+				...schemaWarnings.filter(warning => warning.renamedTo === undefined).map(formatWarning),
+			];
+		} catch (error) {
+			if (this.settings.configMode === 'strict') {
+				this.renderConfigDiagnostics(containerElement, [`Invalid settings: ${error instanceof Error ? error.message : String(error)}`]);
+				return;
+			}
 			// Fallback: treat entire content as output with default config
 			outputCode = rawContent;
 			config = resolveCmdoutConfig({}, this.settings);
 		}
 
+		if (config.configMode === 'strict' && configWarnings.length > 0) {
+			this.renderConfigDiagnostics(containerElement, configWarnings);
+			return;
+		}
+
 		// Render
+		const copyUsageKey = buildCopyUsageKey(processorContext.sourcePath, rawContent);
 		const renderedContainer = await renderCommandOutput(this.app, outputCode, {
 			titleText: config.titleText,
 			descriptionText: config.descriptionText,
@@ -697,6 +2708,11 @@ export default class UltraCodeFence extends Plugin {
 			showCopyButton: config.showCopyButton,
 			scrollLines: config.scrollLines,
 			containingNotePath: processorContext.sourcePath,
+			onCopied: this.createCopyHandler(config.titleText, processorContext.sourcePath, config.copyFeedback, copyUsageKey),
+			copyFeedback: config.copyFeedback,
+			copyCount: this.getCopyCountBadgeValue(copyUsageKey),
+			renderAnsi: config.renderAnsi,
+			copyRawAnsi: config.copyRawAnsi,
 		}, this);
 
 		containerElement.appendChild(renderedContainer);
@@ -706,6 +2722,10 @@ export default class UltraCodeFence extends Plugin {
 		if (cmdoutPre) {
 			cmdoutPre.dataset.ucfPrint = config.printBehaviour;
 		}
+		applyPrintProfile(renderedContainer, config.printTheme, config.printAvoidBreaks);
+
+		this.renderConfigWarnings(containerElement, configWarnings);
+		this.renderRenameNotice(containerElement, processorContext, renameWarnings, (configFormat ?? detectConfigFormat(rawContent)) === 'yaml');
 	}
 
 	/**
@@ -753,14 +2773,24 @@ export default class UltraCodeFence extends Plugin {
 			descriptionText = descMatch[1];
 		}
 
+		const copyFeedback = resolveCopyFeedback(undefined, this.settings);
+		const blockText = sectionInfo.text.split('\n').slice(sectionInfo.lineStart + 1, sectionInfo.lineEnd).join('\n');
+		const copyUsageKey = buildCopyUsageKey(processorContext.sourcePath, blockText);
+
 		await this.attachTitleBarToCodeBlock(element, {
 			titleText: extractedTitle,
 			titleBarStyle,
 			descriptionText,
 			containingNotePath: processorContext.sourcePath,
-			showCopyButton: this.settings.showCopyButton,
-			totalLineCount: 0,  // not tracked for reading mode
-			foldLines: this.settings.foldLines,
+			buttons: {
+				showCopyButton: this.settings.showCopyButton,
+				showDownloadButton: false,
+				totalLineCount: 0,  // not tracked for reading mode
+				foldLines: this.settings.foldLines,
+				onCopied: this.createCopyHandler(extractedTitle, processorContext.sourcePath, copyFeedback, copyUsageKey),
+				feedback: copyFeedback,
+				copyCount: this.getCopyCountBadgeValue(copyUsageKey),
+			},
 		});
 	}
 
@@ -795,6 +2825,10 @@ export default class UltraCodeFence extends Plugin {
 			language: config.language,
 			descriptionText: config.descriptionText,
 			containingNotePath: config.containingNotePath,
+			headerIcon: config.headerIcon,
+			showTitleText: config.showTitleText,
+			showLanguageBadge: config.showLanguageBadge,
+			onLanguageBadgeClick: config.onLanguageBadgeClick,
 			hideTitle: false,
 			useThemeColours: this.settings.useThemeColours,
 			backgroundColour: this.settings.titleBarBackgroundColour,
@@ -806,16 +2840,476 @@ export default class UltraCodeFence extends Plugin {
 
 		wrapPreElement(preElement, titleContainer);
 
-		addCodeBlockButtons(preElement, {
-			showCopyButton: config.showCopyButton,
-			showDownloadButton: config.showDownloadButton ?? false,
-			totalLineCount: config.totalLineCount,
-			foldLines: config.foldLines,
-			shiftCopyJoin: config.shiftCopyJoin,
-			altCopyJoin: config.altCopyJoin,
-			joinIgnoreRegex: config.joinIgnoreRegex,
-			onDownload: config.onDownload,
+		addCodeBlockButtons(preElement, config.buttons);
+
+		const titleElement = titleContainer.querySelector<HTMLElement>(`:scope > .${CSS_CLASSES.title}`);
+		if (config.buttonsInHeader && titleElement) {
+			moveButtonsToHeader(preElement, titleElement);
+		}
+	}
+
+	/**
+	 * Reads a block's opening fence line from the note source.
+	 *
+	 * @param containerElement - Block container
+	 * @param processorContext - Processor context
+	 * @returns The fence line, or undefined when the block can't be located
+	 */
+	private getFenceLine(containerElement: HTMLElement, processorContext: MarkdownPostProcessorContext): string | undefined {
+		const sectionInfo = processorContext.getSectionInfo(containerElement);
+		if (!sectionInfo) return undefined;
+
+		const fenceLine: string | undefined = sectionInfo.text.split('\n')[sectionInfo.lineStart];
+		return fenceLine;
+	}
+
+	/**
+	 * Reads the Obsidian block ID (`^id`) on the line after a block's
+	 * closing fence.
+	 *
+	 * @param containerElement - Block container
+	 * @param processorContext - Processor context
+	 * @returns The block ID, or an empty string
+	 */
+	private getFenceBlockId(containerElement: HTMLElement, processorContext: MarkdownPostProcessorContext): string {
+		const sectionInfo = processorContext.getSectionInfo(containerElement);
+		if (!sectionInfo) return '';
+
+		return findFenceBlockId(sectionInfo.text.split('\n'), sectionInfo.lineEnd);
+	}
+
+	/**
+	 * Creates the step that fills in a copy or download: code variables
+	 * (COPY.VARIABLES), then a prompt for placeholders (COPY.PLACEHOLDERS).
+	 *
+	 * @param config   - Resolved block configuration
+	 * @param notePath - Path of the note containing the block
+	 * @returns Filler for the copy buttons, or undefined if neither is on
+	 */
+	private createPlaceholderFiller(config: ResolvedBlockConfig, notePath: string): PlaceholderFiller | undefined {
+		if (!config.copyVariables && !config.copyPlaceholders) return undefined;
+
+		return (codeText: string) => {
+			const filledText = config.copyVariables
+				? interpolateCodeVariables(codeText, this.getCodeVariableContext(notePath))
+				: codeText;
+			return config.copyPlaceholders ? promptForPlaceholders(this.app, filledText) : Promise.resolve(filledText);
+		};
+	}
+
+	/**
+	 * Creates an onCopied callback that records copies in the clipboard
+	 * history (labelled with the block title or, failing that, the note
+	 * name), counts them for the block, and shows the block's toast and
+	 * status bar confirmations.
+	 *
+	 * @param titleText - Block title (may be empty)
+	 * @param notePath  - Path of the note containing the block
+	 * @param feedback  - Copy feedback configuration for the block
+	 * @param usageKey  - Key the block's copy count is stored under
+	 * @returns Callback for the copy buttons
+	 */
+	private createCopyHandler(
+		titleText: string | undefined,
+		notePath: string,
+		feedback: CopyFeedbackConfig,
+		usageKey: string
+	): (text: string) => void {
+		const source = titleText || notePath.replace(/^.*\//, '').replace(/\.md$/, '');
+
+		return (text: string) => {
+			this.clipboardHistory.record(text, source);
+			incrementCopyCount(this.settings.copyCounts, usageKey);
+			this.requestCopyCountSave();
+			this.showCopyConfirmation(feedback);
+		};
+	}
+
+	/**
+	 * Drops the copy counts and wrap choices of a note's blocks that have
+	 * changed or gone since they were recorded.
+	 *
+	 * @param notePath - Path of the note
+	 */
+	private async pruneCopyUsageForNote(notePath: string): Promise<void> {
+		const hasRecords = (records: Record<string, unknown>): boolean =>
+			Object.keys(records).some(key => copyUsageNotePath(key) === notePath);
+		if (!hasRecords(this.settings.copyCounts) && !hasRecords(this.settings.wrapStates)) return;
+
+		const file = this.app.vault.getAbstractFileByPath(notePath);
+		const liveKeys = file instanceof TFile ? findCopyUsageKeys(notePath, await this.app.vault.cachedRead(file)) : null;
+		const countsPruned = pruneCopyUsage(this.settings.copyCounts, notePath, liveKeys);
+		const wrapsPruned = pruneCopyUsage(this.settings.wrapStates, notePath, liveKeys);
+		if (countsPruned || wrapsPruned) this.requestCopyCountSave();
+	}
+
+	/**
+	 * Prunes the copy counts and wrap choices of every note that has any,
+	 * for blocks changed while the plugin wasn't running.
+	 */
+	private pruneAllCopyUsage(): void {
+		const keys = [...Object.keys(this.settings.copyCounts), ...Object.keys(this.settings.wrapStates)];
+		for (const notePath of new Set(keys.map(copyUsageNotePath))) {
+			void this.pruneCopyUsageForNote(notePath);
+		}
+	}
+
+	/**
+	 * Gets the copy count to show in a block's badge.
+	 *
+	 * @param usageKey - Key the block's copy count is stored under
+	 * @returns Copy count, or undefined when badges are turned off
+	 */
+	private getCopyCountBadgeValue(usageKey: string): number | undefined {
+		return this.settings.showCopyCount ? getCopyCount(this.settings.copyCounts, usageKey) : undefined;
+	}
+
+	/**
+	 * Shows the toast and/or status bar message configured for a copy.
+	 *
+	 * The status bar uses the toast text when there is one, otherwise
+	 * {@link DEFAULT_COPY_MESSAGE}.
+	 *
+	 * @param feedback - Copy feedback configuration
+	 */
+	private showCopyConfirmation(feedback: CopyFeedbackConfig): void {
+		if (feedback.toastText) {
+			new Notice(feedback.toastText, feedback.durationMs);
+		}
+
+		if (feedback.statusBar) {
+			this.copyStatusBarItem ??= this.addStatusBarItem();
+			const statusBarItem = this.copyStatusBarItem;
+			statusBarItem.setText(feedback.toastText || DEFAULT_COPY_MESSAGE);
+
+			window.clearTimeout(this.copyStatusBarTimer);
+			this.copyStatusBarTimer = window.setTimeout(() => {
+				statusBarItem.setText('');
+			}, feedback.durationMs);
+		}
+	}
+
+	/**
+	 * Opens the clipboard history picker; choosing an entry copies it again.
+	 */
+	private openClipboardHistory(): void {
+		const entries = this.clipboardHistory.getEntries();
+
+		if (entries.length === 0) {
+			new Notice(this.settings.clipboardHistorySize > 0
+				? 'No code blocks copied yet'
+				: 'Clipboard history is turned off in settings');
+			return;
+		}
+
+		new ClipboardHistoryModal(this.app, entries, (entry) => {
+			void navigator.clipboard.writeText(entry.text).then(() => {
+				this.clipboardHistory.record(entry.text, entry.source);
+				new Notice(DEFAULT_COPY_MESSAGE);
+			});
+		}).open();
+	}
+
+	/**
+	 * Checks the settings of every ufence block in the vault and writes
+	 * the problems found to a report note, which is then opened.
+	 */
+	private async checkVaultBlocks(): Promise<void> {
+		const notes = this.app.vault.getMarkdownFiles().filter(file => file.path !== LINT_REPORT_PATH);
+		const presetNames = Object.keys(this.settings.presets);
+		const results: FenceLintNoteResult[] = [];
+
+		for (const note of notes) {
+			const issues = lintNoteContent(await this.app.vault.cachedRead(note), presetNames);
+			if (issues.length > 0) {
+				results.push({ path: note.path, issues });
+			}
+		}
+
+		const report = buildLintReport(results, notes.length);
+		const existing = this.app.vault.getAbstractFileByPath(LINT_REPORT_PATH);
+		let reportFile: TFile;
+		if (existing instanceof TFile) {
+			await this.app.vault.modify(existing, report);
+			reportFile = existing;
+		} else {
+			reportFile = await this.app.vault.create(LINT_REPORT_PATH, report);
+		}
+
+		await this.app.workspace.getLeaf(true).openFile(reportFile);
+		new Notice(results.length > 0 ? 'Some code blocks have settings problems' : 'No code block problems found');
+	}
+
+	/**
+	 * Shows a block's effective settings and the layer each comes from.
+	 *
+	 * @param block - Block to inspect
+	 * @param notePath - Path of the note containing the block
+	 */
+	private async inspectBlockSettings(block: UfenceBlock, notePath: string): Promise<void> {
+		let yamlProperties: Record<string, unknown>;
+		try {
+			yamlProperties = parseBlockContent(block.content, parseConfigFormatFromInfoString(block.fenceLine)).yamlProperties;
+		} catch (error) {
+			new Notice(`Could not read the block settings: ${error instanceof Error ? error.message : String(error)}`);
+			return;
+		}
+
+		const isCmdout = block.blockType === 'cmdout';
+		const blockConfig = parseNestedYamlConfig(yamlProperties);
+		const shorthandConfig = parseNestedYamlConfig(parseInfoStringOptions(block.fenceLine).settings);
+		const ownConfig = deepMergeYamlConfigs(blockConfig, shorthandConfig);
+		const language = block.blockType === 'code'
+			? this.inferSourceLanguage(ownConfig) ?? this.settings.defaultLanguage
+			: block.blockType;
+		const inspection = inspectBlockConfig(blockConfig, this.settings, DEFAULT_SETTINGS, {
+			isCmdout,
+			defaultLanguage: language,
+			shorthandConfig,
+			pageConfig: isCmdout ? undefined : await this.getPageConfig(notePath),
+			frontmatterConfig: isCmdout ? undefined : this.getFrontmatterConfig(notePath),
+			defaultPreset: isCmdout ? undefined : findDefaultPreset(ownConfig.RENDER?.LANG ?? language, this.settings, this.getNoteTags(notePath)),
+			folderPreset: isCmdout ? undefined : findFolderPreset(notePath, this.settings.folderPresets),
+			conditions: this.getPresetConditions(),
+		});
+
+		new ConfigInspectModal(this.app, block.blockType, inspection).open();
+	}
+
+	/**
+	 * Finds notes whose ufence blocks use old settings keys.
+	 *
+	 * @returns Migration plans, one per note that would change
+	 */
+	private async planConfigMigration(): Promise<ConfigMigrationPlan[]> {
+		const plans: ConfigMigrationPlan[] = [];
+
+		for (const note of this.app.vault.getMarkdownFiles()) {
+			const plan = planNoteMigration(note.path, await this.app.vault.cachedRead(note));
+			if (plan) {
+				plans.push(plan);
+			}
+		}
+
+		return plans;
+	}
+
+	/**
+	 * Previews the settings migration and applies the notes the user picks.
+	 *
+	 * @param reportNothingFound - Show a notice when no note needs updating
+	 *                             (off for the check made after an upgrade)
+	 */
+	private async openConfigMigration(reportNothingFound: boolean): Promise<void> {
+		const plans = await this.planConfigMigration();
+		if (plans.length === 0) {
+			if (reportNothingFound) {
+				new Notice('No code blocks use old settings');
+			}
+			return;
+		}
+
+		new ConfigMigrationModal(this.app, plans, (chosen) => {
+			void this.applyConfigMigration(chosen);
+		}).open();
+	}
+
+	/**
+	 * Writes migrated notes, skipping any that changed since the preview.
+	 *
+	 * @param plans - Notes to update
+	 */
+	private async applyConfigMigration(plans: ConfigMigrationPlan[]): Promise<void> {
+		const applied: ConfigMigrationPlan[] = [];
+
+		for (const plan of plans) {
+			const file = this.app.vault.getAbstractFileByPath(plan.path);
+			if (!(file instanceof TFile)) continue;
+
+			await this.app.vault.process(file, (data) => {
+				if (data !== plan.original) return data;
+				applied.push(plan);
+				return plan.migrated;
+			});
+		}
+
+		this.lastMigration = applied;
+		const skipped = plans.length - applied.length;
+		new Notice(
+			`Updated ${applied.length === 1 ? '1 note' : `${String(applied.length)} notes`}`
+			+ (skipped > 0 ? ` (${String(skipped)} changed since the preview and were skipped)` : '')
+			+ '. Run "Undo last code block settings update" to revert.'
+		);
+	}
+
+	/**
+	 * Restores the notes changed by the last settings migration. Notes
+	 * edited since are left alone.
+	 */
+	private async undoConfigMigration(): Promise<void> {
+		let restored = 0;
+
+		for (const plan of this.lastMigration) {
+			const file = this.app.vault.getAbstractFileByPath(plan.path);
+			if (!(file instanceof TFile)) continue;
+
+			await this.app.vault.process(file, (data) => {
+				const undoContent = getUndoContent(plan, data);
+				if (undoContent === null) return data;
+				restored++;
+				return undoContent;
+			});
+		}
+
+		const skipped = this.lastMigration.length - restored;
+		this.lastMigration = [];
+		new Notice(
+			`Restored ${restored === 1 ? '1 note' : `${String(restored)} notes`}`
+			+ (skipped > 0 ? ` (${String(skipped)} edited since the update and left as they are)` : '')
+		);
+	}
+
+	/**
+	 * Lets the user choose presets to export, then downloads them as a
+	 * preset pack.
+	 */
+	private openPresetExport(): void {
+		const names = Object.keys(this.settings.presets).sort();
+		if (names.length === 0) {
+			new Notice('There are no presets to export');
+			return;
+		}
+
+		new PresetExportModal(this.app, names, (chosen) => {
+			downloadCodeToFile(buildPresetPack(this.settings.presets, chosen), PRESET_PACK_FILENAME);
+		}).open();
+	}
+
+	/**
+	 * Opens the preset gallery, where curated presets can be previewed and
+	 * installed.
+	 */
+	openPresetGallery(): void {
+		new PresetGalleryModal(
+			this.app,
+			presetGalleryData.presets,
+			entry => findInstalledGalleryPreset(this.settings.presets, entry),
+			(containerElement, presetYaml) => this.renderPresetPreview(containerElement, presetYaml),
+			async (entry) => {
+				const result = installGalleryPreset(this.settings.presets, entry);
+				if (result.installed) {
+					this.settings.presets = result.presets;
+					await this.saveSettings();
+					new Notice(`Installed preset "${result.name}"`);
+				}
+				return result.name;
+			}
+		).open();
+	}
+
+	/**
+	 * Asks for a preset pack file and previews its import.
+	 */
+	private pickPresetPack(): void {
+		const input = document.createElement('input');
+		input.type = 'file';
+		input.accept = '.json,application/json';
+		input.addEventListener('change', () => {
+			const file = input.files?.[0];
+			if (!file) return;
+			void file.text().then((text) => { this.openPresetImport(text); });
+		});
+		input.click();
+	}
+
+	/**
+	 * Reads a preset pack and lets the user choose what to import.
+	 *
+	 * @param packText - Contents of the preset pack file
+	 */
+	private openPresetImport(packText: string): void {
+		let incoming: Record<string, string>;
+		try {
+			incoming = parsePresetPack(packText);
+		} catch (error) {
+			new Notice(`Could not import presets: ${error instanceof Error ? error.message : String(error)}`);
+			return;
+		}
+
+		if (Object.keys(incoming).length === 0) {
+			new Notice('The preset pack is empty');
+			return;
+		}
+
+		new PresetImportModal(this.app, incoming, this.settings.presets, (chosen, action) => {
+			void this.applyPresetImport(chosen, action);
+		}).open();
+	}
+
+	/**
+	 * Adds imported presets to the settings and reports what happened.
+	 *
+	 * @param incoming - Presets chosen for import
+	 * @param action - What to do with names that are already taken
+	 */
+	private async applyPresetImport(incoming: Record<string, string>, action: PresetConflictAction): Promise<void> {
+		const result = importPresets(this.settings.presets, incoming, action);
+		this.settings.presets = result.presets;
+		await this.saveSettings();
+
+		const count = (n: number): string => (n === 1 ? '1 preset' : `${String(n)} presets`);
+		const details = [
+			result.overwritten.length > 0 ? `${String(result.overwritten.length)} replaced` : '',
+			result.renamed.length > 0 ? `${String(result.renamed.length)} renamed: ${result.renamed.map(r => `${r.from} → ${r.to}`).join(', ')}` : '',
+			result.skipped.length > 0 ? `${String(result.skipped.length)} skipped` : '',
+		].filter(detail => detail !== '');
+		const imported = result.added.length + result.overwritten.length + result.renamed.length;
+		new Notice(`Imported ${count(imported)}${details.length > 0 ? ` (${details.join('; ')})` : ''}`);
+	}
+
+	/**
+	 * Asks for a VS Code theme file and adds it as a highlight theme.
+	 *
+	 * @param onImported - Called once the theme is saved
+	 */
+	pickVSCodeTheme(onImported?: () => void): void {
+		const input = document.createElement('input');
+		input.type = 'file';
+		input.accept = '.json,.jsonc,application/json';
+		input.addEventListener('change', () => {
+			const file = input.files?.[0];
+			if (!file) return;
+			void file.text().then(async (text) => {
+				if (await this.importVSCodeTheme(text, file.name.replace(/(?:-color-theme)?\.jsonc?$/i, ''))) {
+					onImported?.();
+				}
+			});
 		});
+		input.click();
+	}
+
+	/**
+	 * Converts a VS Code theme and saves it, replacing a theme of the
+	 * same name.
+	 *
+	 * @param themeText - Contents of the theme file
+	 * @param fallbackName - Name to use if the theme has none
+	 * @returns Whether the theme was imported
+	 */
+	private async importVSCodeTheme(themeText: string, fallbackName: string): Promise<boolean> {
+		let theme: HighlightTheme;
+		try {
+			theme = convertVSCodeTheme(themeText, fallbackName);
+		} catch (error) {
+			new Notice(`Could not import the theme: ${error instanceof Error ? error.message : String(error)}`);
+			return false;
+		}
+
+		const key = highlightThemeKey(theme.name);
+		this.settings.highlightThemes[key] = theme;
+		await this.saveSettings();
+		new Notice(`Imported theme "${theme.name}" — use it with RENDER.THEME: ${key}`);
+		return true;
 	}
 
 	/**
@@ -840,4 +3334,265 @@ export default class UltraCodeFence extends Plugin {
 		);
 		errorComponent.unload();
 	}
+
+	/**
+	 * Flags configuration warnings (unknown keys, mistyped values) on a
+	 * block rendered in lenient mode: a small badge below the block that
+	 * expands to list them.
+	 *
+	 * @param containerElement - The block container
+	 * @param warningTexts     - Formatted warnings (nothing is added when empty)
+	 */
+	private renderConfigWarnings(containerElement: HTMLElement, warningTexts: string[]): void {
+		if (warningTexts.length === 0) return;
+
+		const warningsElement = document.createElement('details');
+		warningsElement.className = CSS_CLASSES.configWarnings;
+
+		const badgeElement = document.createElement('summary');
+		badgeElement.textContent = warningTexts.length === 1
+			? '1 configuration warning'
+			: `${String(warningTexts.length)} configuration warnings`;
+		warningsElement.appendChild(badgeElement);
+
+		for (const warningText of warningTexts) {
+			const itemElement = document.createElement('div');
+			itemElement.textContent = warningText;
+			warningsElement.appendChild(itemElement);
+		}
+
+		containerElement.appendChild(warningsElement);
+	}
+
+	/**
+	 * Tells the reader a block uses renamed settings and, for YAML
+	 * settings, offers a button that rewrites the old keys in the note.
+	 *
+	 * @param containerElement - The block container
+	 * @param processorContext - Processor context (locates the block in the note)
+	 * @param renameWarnings   - Warnings for renamed keys (nothing is added when empty)
+	 * @param canUpdate        - Whether the settings are YAML and can be rewritten
+	 */
+	private renderRenameNotice(
+		containerElement: HTMLElement,
+		processorContext: MarkdownPostProcessorContext,
+		renameWarnings: YamlWarning[],
+		canUpdate: boolean
+	): void {
+		if (renameWarnings.length === 0) return;
+
+		const noticeElement = document.createElement('div');
+		noticeElement.className = CSS_CLASSES.configRenames;
+
+		const textElement = document.createElement('span');
+		textElement.textContent = `This block uses renamed settings: ${renameWarnings.map(warning => `${warning.path} → ${warning.renamedTo ?? ''}`).join(', ')}`;
+		noticeElement.appendChild(textElement);
+
+		if (canUpdate) {
+			const buttonElement = document.createElement('button');
+			buttonElement.textContent = 'Update this block';
+			buttonElement.addEventListener('click', () => {
+				void this.updateRenamedSettings(containerElement, processorContext);
+			});
+			noticeElement.appendChild(buttonElement);
+		}
+
+		containerElement.appendChild(noticeElement);
+	}
+
+	/**
+	 * Rewrites renamed settings keys in the note source of a block.
+	 *
+	 * @param containerElement - The block container
+	 * @param processorContext - Processor context (locates the block in the note)
+	 */
+	private async updateRenamedSettings(containerElement: HTMLElement, processorContext: MarkdownPostProcessorContext): Promise<void> {
+		const sectionInfo = processorContext.getSectionInfo(containerElement);
+		const file = this.app.vault.getAbstractFileByPath(processorContext.sourcePath);
+		if (!sectionInfo || !(file instanceof TFile)) {
+			new Notice('Could not find this block in the note');
+			return;
+		}
+
+		await this.app.vault.process(file, data => {
+			const lines = data.split('\n');
+			const bodyStart = sectionInfo.lineStart + 1;
+			const body = lines.slice(bodyStart, sectionInfo.lineEnd).join('\n');
+			lines.splice(bodyStart, sectionInfo.lineEnd - bodyStart, ...renameDeprecatedKeys(body).split('\n'));
+			return lines.join('\n');
+		});
+	}
+
+	/**
+	 * Adds a context menu to a rendered block for switching its preset,
+	 * with one item per saved preset and one for removing the block's own.
+	 *
+	 * @param containerElement - The block container
+	 * @param processorContext - Processor context (locates the block in the note)
+	 * @param currentPreset    - Preset the block names itself, if any
+	 * @param hasSettings      - Whether the block has a settings section (false for plain code)
+	 */
+	private attachPresetSwitcher(
+		containerElement: HTMLElement,
+		processorContext: MarkdownPostProcessorContext,
+		currentPreset: string | undefined,
+		hasSettings: boolean
+	): void {
+		containerElement.addEventListener('contextmenu', (event) => {
+			const presetNames = Object.keys(this.settings.presets).sort();
+			if (presetNames.length === 0 && !currentPreset) return;
+
+			event.preventDefault();
+			event.stopPropagation();
+
+			const menu = new Menu();
+			for (const name of presetNames) {
+				menu.addItem(item => item
+					.setTitle(`Use preset: ${name}`)
+					.setIcon(name === currentPreset ? 'check' : 'layers')
+					.onClick(() => {
+						if (name === currentPreset) return;
+						void this.applyBlockPreset(containerElement, processorContext, name, hasSettings);
+					}));
+			}
+			if (currentPreset) {
+				menu.addSeparator();
+				menu.addItem(item => item
+					.setTitle('Remove preset')
+					.setIcon('x')
+					.onClick(() => {
+						void this.applyBlockPreset(containerElement, processorContext, undefined, hasSettings);
+					}));
+			}
+			menu.showAtMouseEvent(event);
+		});
+	}
+
+	/**
+	 * Writes a block's new preset (META.PRESET) into the note source.
+	 *
+	 * @param containerElement - The block container
+	 * @param processorContext - Processor context (locates the block in the note)
+	 * @param presetName       - Preset to use, or undefined to remove the block's preset
+	 * @param hasSettings      - Whether the block has a settings section (false for plain code)
+	 */
+	private async applyBlockPreset(
+		containerElement: HTMLElement,
+		processorContext: MarkdownPostProcessorContext,
+		presetName: string | undefined,
+		hasSettings: boolean
+	): Promise<void> {
+		await this.rewriteBlockBody(
+			containerElement,
+			processorContext,
+			body => setBlockPreset(body, presetName, hasSettings),
+			'This block writes META on one line; change its preset by hand'
+		);
+	}
+
+	/**
+	 * Shows the menu for correcting a block's detected language, opened
+	 * from its language badge. Picking a language writes it as the
+	 * block's RENDER.LANG.
+	 *
+	 * @param event            - Click on the badge
+	 * @param containerElement - The block container
+	 * @param processorContext - Processor context (locates the block in the note)
+	 * @param detectedLanguage - Language the block was guessed to be
+	 * @param hasSettings      - Whether the block has a settings section (false for plain code)
+	 */
+	private showLanguageMenu(
+		event: MouseEvent,
+		containerElement: HTMLElement,
+		processorContext: MarkdownPostProcessorContext,
+		detectedLanguage: string,
+		hasSettings: boolean
+	): void {
+		const languages = DETECTABLE_LANGUAGES.includes(detectedLanguage)
+			? DETECTABLE_LANGUAGES
+			: [detectedLanguage, ...DETECTABLE_LANGUAGES];
+
+		const menu = new Menu();
+		for (const language of languages) {
+			menu.addItem(item => item
+				.setTitle(language)
+				.setIcon(language === detectedLanguage ? 'check' : 'code')
+				.onClick(() => {
+					void this.rewriteBlockBody(
+						containerElement,
+						processorContext,
+						body => setBlockLanguage(body, language, hasSettings),
+						'This block writes RENDER on one line; set its language by hand'
+					);
+				}));
+		}
+		menu.showAtMouseEvent(event);
+	}
+
+	/**
+	 * Rewrites the text between a block's fences in the note source.
+	 *
+	 * @param containerElement - The block container
+	 * @param processorContext - Processor context (locates the block in the note)
+	 * @param rewrite          - Returns the new block text, or undefined if it can't be rewritten
+	 * @param failureMessage   - Notice shown when rewrite returns undefined
+	 */
+	private async rewriteBlockBody(
+		containerElement: HTMLElement,
+		processorContext: MarkdownPostProcessorContext,
+		rewrite: (body: string) => string | undefined,
+		failureMessage: string
+	): Promise<void> {
+		const sectionInfo = processorContext.getSectionInfo(containerElement);
+		const file = this.app.vault.getAbstractFileByPath(processorContext.sourcePath);
+		if (!sectionInfo || !(file instanceof TFile)) {
+			new Notice('Could not find this block in the note');
+			return;
+		}
+
+		let updated = true;
+		await this.app.vault.process(file, data => {
+			const lines = data.split('\n');
+			const bodyStart = sectionInfo.lineStart + 1;
+			const body = lines.slice(bodyStart, sectionInfo.lineEnd).join('\n');
+			const newBody = rewrite(body);
+			if (newBody === undefined) {
+				updated = false;
+				return data;
+			}
+			lines.splice(bodyStart, sectionInfo.lineEnd - bodyStart, ...newBody.split('\n'));
+			return lines.join('\n');
+		});
+
+		if (!updated) {
+			new Notice(failureMessage);
+		}
+	}
+
+	/**
+	 * Shows the diagnostic panel a strict-mode block renders instead of
+	 * its code.
+	 *
+	 * @param containerElement - The block container
+	 * @param problemTexts     - Configuration problems to list
+	 */
+	private renderConfigDiagnostics(containerElement: HTMLElement, problemTexts: string[]): void {
+		const panelElement = document.createElement('div');
+		panelElement.className = CSS_CLASSES.configDiagnostics;
+
+		const headingElement = document.createElement('div');
+		headingElement.className = CSS_CLASSES.configDiagnosticsHeading;
+		headingElement.textContent = 'Not rendered because of configuration problems (strict mode)';
+		panelElement.appendChild(headingElement);
+
+		const listElement = document.createElement('ul');
+		for (const problemText of problemTexts) {
+			const itemElement = document.createElement('li');
+			itemElement.textContent = problemText;
+			listElement.appendChild(itemElement);
+		}
+		panelElement.appendChild(listElement);
+
+		containerElement.appendChild(panelElement);
+	}
 }
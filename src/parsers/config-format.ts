@@ -0,0 +1,170 @@
+/**
+ * Ultra Code Fence - Config Formats
+ *
+ * Block settings can be written in YAML (the default), TOML or JSON.
+ * All three parse into the same nested object, so everything after this
+ * point works with one settings model whatever the block was written in.
+ */
+
+import { parseYaml } from 'obsidian';
+import type { ConfigFormat } from '../types';
+import { CONFIG_FORMATS, YAML_MERGE_KEY } from '../constants';
+import { parseToml } from './toml-parser';
+import { stripInfoStringOptions } from './info-string';
+
+// =============================================================================
+// Detection
+// =============================================================================
+
+/** A TOML table header: [RENDER], [[COPY.AS]] */
+const TOML_TABLE_PATTERN = /^\[\[?\s*[\w"'.\- ]+\]\]?\s*(?:#.*)?$/;
+
+/** A TOML assignment: KEY = value */
+const TOML_ASSIGNMENT_PATTERN = /^[A-Za-z_][\w.-]*\s*=/;
+
+/**
+ * Guesses the format of a settings section from its first line.
+ *
+ * `{` starts JSON; a `[TABLE]` header or `KEY = value` line starts TOML;
+ * anything else is treated as YAML.
+ *
+ * @param configText - Settings text
+ * @returns Detected format
+ */
+export function detectConfigFormat(configText: string): ConfigFormat {
+	const firstLine = configText
+		.split('\n')
+		.map(line => line.trim())
+		.find(line => line !== '' && !line.startsWith('#'));
+
+	if (!firstLine) return 'yaml';
+	if (firstLine.startsWith('{')) return 'json';
+	if (TOML_TABLE_PATTERN.test(firstLine) || TOML_ASSIGNMENT_PATTERN.test(firstLine)) return 'toml';
+
+	return 'yaml';
+}
+
+/**
+ * Reads a settings format named in a code fence info string, e.g.
+ * <code>```ufence-bash toml</code>.
+ *
+ * @param fenceLine - Opening fence line of the block
+ * @returns Named format, or undefined to auto-detect
+ */
+export function parseConfigFormatFromInfoString(fenceLine: string): ConfigFormat | undefined {
+	const match = /^\s*(?:`{3,}|~{3,})\s*\S+\s+(.*)$/.exec(stripInfoStringOptions(fenceLine));
+	if (!match) return undefined;
+
+	const words = match[1].toLowerCase().split(/\s+/);
+	return CONFIG_FORMATS.find(format => words.includes(format));
+}
+
+// =============================================================================
+// Parsing
+// =============================================================================
+
+/**
+ * Expands YAML merge keys (<<: *anchor) and copies aliased values, so
+ * every part of the result is a separate object that can be changed
+ * without affecting the others.
+ *
+ * Keys written next to a merge key win over merged ones; with a list of
+ * anchors (<<: [*a, *b]) the earlier anchor wins, as in the YAML spec.
+ *
+ * @param value - Parsed YAML value
+ * @returns Value with merge keys expanded
+ */
+export function expandYamlMergeKeys(value: unknown): unknown {
+	if (Array.isArray(value)) {
+		return value.map(expandYamlMergeKeys);
+	}
+	// Leave scalars (and non-plain objects such as YAML timestamps) as they are
+	if (!value || typeof value !== 'object' || Object.getPrototypeOf(value) !== Object.prototype) {
+		return value;
+	}
+
+	const record = value as Record<string, unknown>;
+	const result: Record<string, unknown> = {};
+
+	const merged: unknown = record[YAML_MERGE_KEY];
+	const sources = Array.isArray(merged) ? merged : [merged];
+	for (const source of [...sources].reverse()) {
+		const expanded = expandYamlMergeKeys(source);
+		if (expanded && typeof expanded === 'object' && !Array.isArray(expanded)) {
+			Object.assign(result, expanded);
+		}
+	}
+
+	for (const [key, entry] of Object.entries(record)) {
+		if (key !== YAML_MERGE_KEY) {
+			result[key] = expandYamlMergeKeys(entry);
+		}
+	}
+
+	return result;
+}
+
+/**
+ * Parses YAML settings, expanding merge keys (see expandYamlMergeKeys).
+ * Anchors and aliases are handled by the YAML parser itself.
+ *
+ * @param yamlText - YAML text
+ * @returns Parsed value
+ * @throws Error if the text is not valid YAML
+ */
+export function parseYamlSettings(yamlText: string): unknown {
+	return expandYamlMergeKeys(parseYaml(yamlText));
+}
+
+/**
+ * Parses JSON settings. When the format was only detected, text that
+ * isn't JSON is read as YAML instead, since a YAML flow mapping
+ * (`{META: {TITLE: x}}`) starts with `{` too.
+ *
+ * @param configText - Settings text
+ * @param detected - True if no format was given
+ * @returns Parsed value, and the format it was read as
+ * @throws Error if the text is not valid JSON (nor, when detected, YAML)
+ */
+function parseJsonSettings(configText: string, detected: boolean): { parsed: unknown; format: ConfigFormat } {
+	try {
+		return { parsed: JSON.parse(configText), format: 'json' };
+	} catch (jsonError) {
+		if (!detected) throw jsonError;
+		try {
+			return { parsed: parseYamlSettings(configText), format: 'yaml' };
+		} catch {
+			throw jsonError;
+		}
+	}
+}
+
+/**
+ * Parses a settings section in the given (or detected) format.
+ *
+ * @param configText - Settings text
+ * @param format - Format to use (undefined = detect)
+ * @returns Parsed settings object
+ * @throws Error if the text is not valid in that format, or is not an object
+ */
+export function parseConfigText(configText: string, format?: ConfigFormat): Record<string, unknown> {
+	let resolvedFormat = format ?? detectConfigFormat(configText);
+
+	let parsed: unknown;
+	switch (resolvedFormat) {
+		case 'toml':
+			return parseToml(configText);
+		case 'json':
+			({ parsed, format: resolvedFormat } = parseJsonSettings(configText, format === undefined));
+			break;
+		default:
+			parsed = parseYamlSettings(configText);
+	}
+
+	if (parsed === null || parsed === undefined) return {};
+	if (typeof parsed !== 'object' || Array.isArray(parsed)) {
+		throw new Error(`Block settings must be a ${resolvedFormat.toUpperCase()} object`);
+	}
+
+	return parsed as Record<string, unknown>;
+}
@@ -13,13 +13,68 @@ export {
 	createSafeRegex,
 	parseNestedYamlConfig,
 	parseLineRange,
+	parseSourceReference,
+	parseRefreshInterval,
+	parseRunTimeout,
+	parseMaxHeight,
+	parseFontSize,
+	parseRulerColumns,
+	parseLineList,
 	resolveBlockConfig,
 	resolveCmdoutConfig,
+	resolveCopyFeedback,
+	resolveConfigMode,
+	applyConfigRenames,
 	parseCalloutSection,
+	parseCopySection,
+	parseHighlightSection,
+	parseDownloadSection,
+	parsePrintSection,
+	parseRunSection,
+	parseHeaderSection,
+	parseFooterSection,
+	parseAnnotationsSection,
+	parseTokensSection,
+	parseBlameSection,
+	parseDiffSection,
+	resolveAnnotations,
+	resolveBlame,
 	resolveCalloutConfig,
 	parsePresetYaml,
+	parsePresetExtends,
+	parsePresetAppliesToTags,
+	parsePresetParamDefaults,
+	parsePresetConditions,
+	parseFrontmatterConfig,
 } from './yaml-parser';
 
+export {
+	detectConfigFormat,
+	parseConfigFormatFromInfoString,
+	parseConfigText,
+	parseYamlSettings,
+	expandYamlMergeKeys,
+} from './config-format';
+
+export { parseToml } from './toml-parser';
+
+export type { PresetParamValue, PresetReference } from './preset-params';
+
+export {
+	parsePresetReference,
+	substitutePresetParams,
+	getPresetParamDefaults,
+	mergePresetParams,
+} from './preset-params';
+
+export type { InfoStringOptions } from './info-string';
+
+export {
+	parseInfoStringOptions,
+	mergeInfoStringOptions,
+	stripInfoStringOptions,
+} from './info-string';
+
 export type {
 	MarkerExtractionResult,
 	MarkerExtractionOptions,
@@ -31,6 +86,8 @@ export {
 	extractBetweenMarkersWithOptions,
 	extractLines,
 	extractLineRange,
+	replaceLineRange,
+	extractNamedRegion,
 	applyFilterChain,
 	countLines,
 	trimTrailingEmptyLines,
@@ -0,0 +1,240 @@
+/**
+ * Ultra Code Fence - Info String Shorthand
+ *
+ * Simple settings can be written on the fence line instead of in a
+ * settings section:
+ *
+ *     ```ufence-bash {ln zebra title="Install" fold=20}
+ *
+ * Each option maps to one settings key. The options are turned into the
+ * same nested object a settings section parses into, and the settings
+ * section (if any) is merged on top, so it wins where both set a key.
+ */
+
+import {
+	YAML_SECTIONS,
+	YAML_META,
+	YAML_RENDER_DISPLAY,
+	YAML_COPY,
+	YAML_COPY_AS_ENTRY,
+	YAML_HIGHLIGHT,
+	YAML_DIFF,
+	YAML_DOWNLOAD,
+	YAML_RUN,
+	YAML_PROMPT,
+} from '../constants';
+
+// =============================================================================
+// Options
+// =============================================================================
+
+/** How an option's value is read. */
+type ShorthandValueType = 'boolean' | 'number' | 'text';
+
+/** A shorthand option and the settings key it sets. */
+interface ShorthandOption {
+	/** Settings path: section and key, or a top-level key */
+	path: readonly string[];
+	/** Value type; boolean options can be written bare (ln) or negated (!ln) */
+	type: ShorthandValueType;
+}
+
+/** Shorthand options by name (lower case). */
+const SHORTHAND_OPTIONS: Record<string, ShorthandOption | undefined> = {
+	title: { path: [YAML_SECTIONS.meta, YAML_META.title], type: 'text' },
+	desc: { path: [YAML_SECTIONS.meta, YAML_META.desc], type: 'text' },
+	preset: { path: [YAML_SECTIONS.meta, YAML_META.preset], type: 'text' },
+	mode: { path: [YAML_SECTIONS.meta, YAML_META.mode], type: 'text' },
+	src: { path: [YAML_SECTIONS.meta, YAML_META.src], type: 'text' },
+	query: { path: [YAML_SECTIONS.meta, YAML_META.query], type: 'text' },
+	refresh: { path: [YAML_SECTIONS.meta, YAML_META.refresh], type: 'text' },
+	id: { path: [YAML_SECTIONS.meta, YAML_META.id], type: 'text' },
+	ref: { path: [YAML_SECTIONS.meta, YAML_META.ref], type: 'text' },
+	ln: { path: [YAML_SECTIONS.render, YAML_RENDER_DISPLAY.lines], type: 'boolean' },
+	lines: { path: [YAML_SECTIONS.render, YAML_RENDER_DISPLAY.lines], type: 'boolean' },
+	zebra: { path: [YAML_SECTIONS.render, YAML_RENDER_DISPLAY.zebra], type: 'boolean' },
+	hover: { path: [YAML_SECTIONS.render, YAML_RENDER_DISPLAY.hover], type: 'boolean' },
+	ligatures: { path: [YAML_SECTIONS.render, YAML_RENDER_DISPLAY.ligatures], type: 'boolean' },
+	features: { path: [YAML_SECTIONS.render, YAML_RENDER_DISPLAY.fontFeatures], type: 'text' },
+	font: { path: [YAML_SECTIONS.render, YAML_RENDER_DISPLAY.fontFamily], type: 'text' },
+	fontsize: { path: [YAML_SECTIONS.render, YAML_RENDER_DISPLAY.fontSize], type: 'text' },
+	tabsize: { path: [YAML_SECTIONS.render, YAML_RENDER_DISPLAY.tabSize], type: 'number' },
+	ruler: { path: [YAML_SECTIONS.render, YAML_RENDER_DISPLAY.ruler], type: 'text' },
+	dir: { path: [YAML_SECTIONS.render, YAML_RENDER_DISPLAY.direction], type: 'text' },
+	copy: { path: [YAML_SECTIONS.render, YAML_RENDER_DISPLAY.copy], type: 'boolean' },
+	linecopy: { path: [YAML_SECTIONS.render, YAML_RENDER_DISPLAY.lineCopy], type: 'boolean' },
+	start: { path: [YAML_SECTIONS.render, YAML_RENDER_DISPLAY.lineStart], type: 'number' },
+	anchor: { path: [YAML_SECTIONS.render, YAML_RENDER_DISPLAY.lineAnchor], type: 'number' },
+	collapsed: { path: [YAML_SECTIONS.render, YAML_RENDER_DISPLAY.collapsed], type: 'boolean' },
+	guides: { path: [YAML_SECTIONS.render, YAML_RENDER_DISPLAY.indentGuides], type: 'boolean' },
+	ws: { path: [YAML_SECTIONS.render, YAML_RENDER_DISPLAY.whitespace], type: 'text' },
+	summary: { path: [YAML_SECTIONS.meta, YAML_META.summary], type: 'text' },
+	fold: { path: [YAML_SECTIONS.render, YAML_RENDER_DISPLAY.fold], type: 'number' },
+	scroll: { path: [YAML_SECTIONS.render, YAML_RENDER_DISPLAY.scroll], type: 'number' },
+	maxheight: { path: [YAML_SECTIONS.render, YAML_RENDER_DISPLAY.maxHeight], type: 'text' },
+	minimap: { path: [YAML_SECTIONS.render, YAML_RENDER_DISPLAY.minimap], type: 'number' },
+	style: { path: [YAML_SECTIONS.render, YAML_RENDER_DISPLAY.style], type: 'text' },
+	lang: { path: [YAML_SECTIONS.render, YAML_RENDER_DISPLAY.lang], type: 'text' },
+	print: { path: [YAML_SECTIONS.render, YAML_RENDER_DISPLAY.print], type: 'text' },
+	join: { path: [YAML_SECTIONS.render, YAML_RENDER_DISPLAY.shiftCopyJoin], type: 'text' },
+	hl: { path: [YAML_SECTIONS.highlight, YAML_HIGHLIGHT.lines], type: 'text' },
+	focus: { path: [YAML_SECTIONS.highlight, YAML_HIGHLIGHT.focus], type: 'text' },
+	added: { path: [YAML_SECTIONS.diff, YAML_DIFF.added], type: 'text' },
+	removed: { path: [YAML_SECTIONS.diff, YAML_DIFF.removed], type: 'text' },
+	diffview: { path: [YAML_SECTIONS.diff, YAML_DIFF.view], type: 'text' },
+	nocomments: { path: [YAML_SECTIONS.copy, YAML_COPY.stripComments], type: 'boolean' },
+	placeholders: { path: [YAML_SECTIONS.copy, YAML_COPY.placeholders], type: 'boolean' },
+	variables: { path: [YAML_SECTIONS.render, YAML_RENDER_DISPLAY.variables], type: 'boolean' },
+	filename: { path: [YAML_SECTIONS.download, YAML_DOWNLOAD.filename], type: 'text' },
+	run: { path: [YAML_SECTIONS.run, YAML_RUN.enabled], type: 'boolean' },
+	saveoutput: { path: [YAML_SECTIONS.run, YAML_RUN.save], type: 'boolean' },
+	runlines: { path: [YAML_SECTIONS.run, YAML_RUN.lines], type: 'boolean' },
+	timeout: { path: [YAML_SECTIONS.run, YAML_RUN.timeout], type: 'text' },
+	prompt: { path: [YAML_PROMPT], type: 'text' },
+};
+
+/** The option brace group on a fence line: {ln title="Install"} */
+const SHORTHAND_GROUP_PATTERN = /\{([^}]*)\}/;
+
+/** One option: !name, name, name=value, name="value" or name='value' */
+const SHORTHAND_TOKEN_PATTERN = /(!)?([A-Za-z][\w-]*)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s,"']+)))?/;
+
+// =============================================================================
+// Types
+// =============================================================================
+
+/** Settings read from a fence line. */
+export interface InfoStringOptions {
+	/** Settings in the nested form of a parsed settings section */
+	settings: Record<string, unknown>;
+	/** Options that could not be used, as messages */
+	problems: string[];
+}
+
+// =============================================================================
+// Parsing
+// =============================================================================
+
+/**
+ * Removes the shorthand option group from a fence line, so the rest can
+ * be read without matching words inside option values.
+ *
+ * @param fenceLine - Opening fence line
+ * @returns Fence line without the {…} group
+ */
+export function stripInfoStringOptions(fenceLine: string): string {
+	return fenceLine.replace(SHORTHAND_GROUP_PATTERN, ' ');
+}
+
+/**
+ * Sets a value at a path in a nested object, creating sections as needed.
+ *
+ * @param target - Object to change
+ * @param path - Section and key names
+ * @param value - Value to set
+ */
+function setPath(target: Record<string, unknown>, path: readonly string[], value: unknown): void {
+	let level = target;
+	for (const key of path.slice(0, -1)) {
+		const next = level[key];
+		if (!next || typeof next !== 'object') {
+			level[key] = {};
+		}
+		level = level[key] as Record<string, unknown>;
+	}
+	level[path[path.length - 1]] = value;
+}
+
+/**
+ * Reads the shorthand options from a fence line.
+ *
+ * `copy=<format>` adds a Copy as entry (e.g. copy=dockerfile); `copy`,
+ * `!copy`, `copy=true` and `copy=false` show or hide the copy button.
+ *
+ * @param fenceLine - Opening fence line of the block
+ * @returns Settings and problems (both empty when there is no {…} group)
+ */
+export function parseInfoStringOptions(fenceLine: string): InfoStringOptions {
+	const settings: Record<string, unknown> = {};
+	const problems: string[] = [];
+
+	const groupMatch = SHORTHAND_GROUP_PATTERN.exec(fenceLine);
+	if (!groupMatch) return { settings, problems };
+
+	const tokenPattern = new RegExp(SHORTHAND_TOKEN_PATTERN.source, 'g');
+	let match: RegExpExecArray | null;
+	while ((match = tokenPattern.exec(groupMatch[1])) !== null) {
+		// Unmatched groups are undefined
+		const [, negated, , doubleQuoted, singleQuoted, bare]: (string | undefined)[] = match;
+		const rawName = match[2];
+		const name = rawName.toLowerCase();
+		const text = doubleQuoted ?? singleQuoted ?? bare;
+		const option = SHORTHAND_OPTIONS[name];
+
+		if (!option) {
+			problems.push(`Unknown shorthand option: ${rawName}`);
+			continue;
+		}
+
+		if (option.type === 'boolean') {
+			if (text !== undefined && text !== 'true' && text !== 'false') {
+				// copy=<format> is a Copy as entry rather than the button toggle
+				if (name === 'copy' && !negated) {
+					setPath(settings, [YAML_SECTIONS.copy, YAML_COPY.as], [{ [YAML_COPY_AS_ENTRY.format]: text }]);
+				} else {
+					problems.push(`Shorthand option ${rawName} should be true or false`);
+				}
+				continue;
+			}
+			const enabled = text === undefined ? true : text === 'true';
+			setPath(settings, option.path, negated ? !enabled : enabled);
+			continue;
+		}
+
+		if (negated || text === undefined) {
+			problems.push(`Shorthand option ${rawName} needs a value, e.g. ${rawName}=…`);
+			continue;
+		}
+
+		if (option.type === 'number') {
+			const value = Number(text);
+			if (!Number.isFinite(value)) {
+				problems.push(`Shorthand option ${rawName} should be a number`);
+				continue;
+			}
+			setPath(settings, option.path, value);
+			continue;
+		}
+
+		setPath(settings, option.path, text);
+	}
+
+	return { settings, problems };
+}
+
+/**
+ * Merges block settings on top of the fence line shorthand, so a key set
+ * in both places takes its value from the settings section.
+ *
+ * @param shorthand - Settings from parseInfoStringOptions
+ * @param yamlProps - Parsed settings section
+ * @returns Combined settings (neither input is modified)
+ */
+export function mergeInfoStringOptions(
+	shorthand: Record<string, unknown>,
+	yamlProps: Record<string, unknown>
+): Record<string, unknown> {
+	const result: Record<string, unknown> = { ...shorthand };
+
+	for (const [key, value] of Object.entries(yamlProps)) {
+		const base = result[key];
+		const bothSections = base && typeof base === 'object' && !Array.isArray(base)
+			&& value && typeof value === 'object' && !Array.isArray(value);
+
+		result[key] = bothSections
+			? mergeInfoStringOptions(base as Record<string, unknown>, value as Record<string, unknown>)
+			: value;
+	}
+
+	return result;
+}
@@ -1,12 +1,13 @@
 /**
  * Ultra Code Fence - Line Extractor
  *
- * Extracts specific portions of source code based on line numbers
- * or marker strings. Used to embed only relevant sections of larger
- * source files.
+ * Extracts specific portions of source code based on line numbers,
+ * marker strings or named regions. Used to embed only relevant sections
+ * of larger source files.
  */
 
 import type { ResolvedBlockConfig } from '../types';
+import { SOURCE_REGION_MARKER_PATTERN } from '../constants';
 
 // =============================================================================
 // Marker-Based Extraction
@@ -108,6 +109,48 @@ export function extractBetweenMarkersWithOptions(
 	return { content: extractedLines.join('\n'), error: null };
 }
 
+// =============================================================================
+// Named Region Extraction (for FILTER.BY_REGION)
+// =============================================================================
+
+/**
+ * Extracts a named region of a source file, between its
+ * `ufence:begin name` and `ufence:end name` marker lines. Unlike line
+ * numbers, the markers move with the code they surround.
+ *
+ * The marker lines aren't included, nor are the markers of other
+ * regions nested inside, so a file can mark overlapping sections.
+ *
+ * @param sourceCode - Complete source code to extract from
+ * @param name - Region name (case-sensitive)
+ * @returns Object containing extracted content or error message
+ *
+ * @example
+ * // Source: "// ufence:begin setup\ninit();\n// ufence:end setup"
+ * // extractNamedRegion(source, 'setup') returns "init();"
+ */
+export function extractNamedRegion(sourceCode: string, name: string): MarkerExtractionResult {
+	const lines = sourceCode.split('\n');
+	const markers = lines.map(line => SOURCE_REGION_MARKER_PATTERN.exec(line));
+
+	const startIndex = markers.findIndex(marker => marker?.[1].toLowerCase() === 'begin' && marker[2] === name);
+	if (startIndex === -1) {
+		return { content: null, error: `Region "${name}" not found in file (mark it with ufence:begin ${name})` };
+	}
+
+	const endOffset = markers.slice(startIndex + 1).findIndex(marker => marker?.[1].toLowerCase() === 'end' && marker[2] === name);
+	if (endOffset === -1) {
+		return { content: null, error: `Region "${name}" has no ufence:end ${name} after its start` };
+	}
+
+	const endIndex = startIndex + 1 + endOffset;
+	const regionLines = lines
+		.slice(startIndex + 1, endIndex)
+		.filter((_line, index) => markers[startIndex + 1 + index] === null);
+
+	return { content: regionLines.join('\n'), error: null };
+}
+
 // =============================================================================
 // Line Number Extraction
 // =============================================================================
@@ -277,8 +320,62 @@ export function extractLineRange(
 	endLine: number,
 	options: LineRangeExtractionOptions = {}
 ): string {
-	const { inclusive = true } = options;
 	const lines = sourceCode.split('\n');
+	const indices = resolveLineRangeIndices(lines.length, startLine, endLine, options);
+
+	if (!indices) {
+		return ''; // Invalid range after adjustment
+	}
+
+	return lines.slice(indices[0], indices[1] + 1).join('\n');
+}
+
+/**
+ * Replaces the lines {@link extractLineRange} would extract, for writing
+ * an edited excerpt back to its file. When the range holds no lines the
+ * source is returned as it is.
+ *
+ * @param sourceCode - Complete source code
+ * @param startLine - Start line number (1-based)
+ * @param endLine - End line number (1-based)
+ * @param replacement - New text for the range
+ * @param options - Extraction options the excerpt was made with
+ * @returns Source code with the range replaced
+ */
+export function replaceLineRange(
+	sourceCode: string,
+	startLine: number,
+	endLine: number,
+	replacement: string,
+	options: LineRangeExtractionOptions = {}
+): string {
+	const lines = sourceCode.split('\n');
+	const indices = resolveLineRangeIndices(lines.length, startLine, endLine, options);
+
+	if (!indices) {
+		return sourceCode;
+	}
+
+	lines.splice(indices[0], indices[1] - indices[0] + 1, ...replacement.split('\n'));
+	return lines.join('\n');
+}
+
+/**
+ * Converts a line range to 0-based indices clamped to the source.
+ *
+ * @param lineCount - Number of lines in the source
+ * @param startLine - Start line number (1-based)
+ * @param endLine - End line number (1-based)
+ * @param options - Extraction options
+ * @returns [start, end] indices (inclusive), or null if no lines are left
+ */
+function resolveLineRangeIndices(
+	lineCount: number,
+	startLine: number,
+	endLine: number,
+	options: LineRangeExtractionOptions
+): [number, number] | null {
+	const { inclusive = true } = options;
 
 	// Convert to 0-based indices
 	let startIndex = startLine - 1;
@@ -292,12 +389,9 @@ export function extractLineRange(
 
 	// Validate range
 	if (startIndex < 0) startIndex = 0;
-	if (endIndex >= lines.length) endIndex = lines.length - 1;
-	if (startIndex > endIndex) {
-		return ''; // Invalid range after adjustment
-	}
+	if (endIndex >= lineCount) endIndex = lineCount - 1;
 
-	return lines.slice(startIndex, endIndex + 1).join('\n');
+	return startIndex > endIndex ? null : [startIndex, endIndex];
 }
 
 // =============================================================================
@@ -316,11 +410,13 @@ export interface FilterChainResult {
 }
 
 /**
- * Applies the filter chain: BY_LINES first, then BY_MARKS on the result.
+ * Applies the filter chain: BY_REGION first, then BY_LINES, then BY_MARKS
+ * on the result.
  *
  * This implements the nested YAML FILTER section processing where:
- * 1. FILTER.BY_LINES extracts a line range (if enabled)
- * 2. FILTER.BY_MARKS extracts between markers (if enabled) from the result of step 1
+ * 1. FILTER.BY_REGION extracts a named region of the whole file (if set)
+ * 2. FILTER.BY_LINES extracts a line range (if enabled) from the result of step 1
+ * 3. FILTER.BY_MARKS extracts between markers (if enabled) from the result of step 2
  *
  * @param sourceCode - Original source code
  * @param config - Resolved block configuration with filter settings
@@ -343,7 +439,18 @@ export function applyFilterChain(
 ): FilterChainResult {
 	let content = sourceCode;
 
-	// Step 1: Apply BY_LINES filter first
+	// Step 1: Apply BY_REGION to the whole file
+	if (config.filterByRegion) {
+		const result = extractNamedRegion(content, config.filterByRegion);
+
+		if (result.error) {
+			return { content: '', error: result.error };
+		}
+
+		content = result.content ?? '';
+	}
+
+	// Step 2: Apply BY_LINES filter
 	if (config.filterByLines.enabled) {
 		content = extractLineRange(
 			content,
@@ -353,7 +460,7 @@ export function applyFilterChain(
 		);
 	}
 
-	// Step 2: Apply BY_MARKS filter on the result
+	// Step 3: Apply BY_MARKS filter on the result
 	if (config.filterByMarks.enabled) {
 		const result = extractBetweenMarkersWithOptions(
 			content,
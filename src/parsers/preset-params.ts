@@ -0,0 +1,159 @@
+/**
+ * Ultra Code Fence - Preset Parameters
+ *
+ * A preset can declare parameters under PARAMS (with default values) and
+ * use them as ${name} anywhere in its settings. A block supplies values
+ * when it names the preset:
+ *
+ *     META:
+ *       PRESET: api-example(title="Create user", startLine=5)
+ */
+
+import { PRESET_PARAM_PATTERN, YAML_PRESET_PARAMS } from '../constants';
+
+// =============================================================================
+// Types
+// =============================================================================
+
+/** A parameter value given in a preset reference. */
+export type PresetParamValue = string | number | boolean;
+
+/** A preset name with the parameter values given for it. */
+export interface PresetReference {
+	/** Preset name */
+	name: string;
+	/** Parameter values by name */
+	args: Record<string, PresetParamValue>;
+}
+
+// =============================================================================
+// Patterns
+// =============================================================================
+
+/** A preset reference: name, then optional (arguments) */
+const PRESET_REFERENCE_PATTERN = /^([^(]*?)\s*\((.*)\)\s*$/;
+
+/** One argument: name=value, name="value" or name='value' */
+const PRESET_ARG_PATTERN = /([A-Za-z_][\w-]*)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s,"')]+))/;
+
+// =============================================================================
+// Parsing
+// =============================================================================
+
+/**
+ * Reads an unquoted argument value: true/false and numbers keep their type.
+ *
+ * @param text - Argument text
+ * @returns Typed value
+ */
+function parseBareValue(text: string): PresetParamValue {
+	if (text === 'true') return true;
+	if (text === 'false') return false;
+	const number = Number(text);
+	return Number.isFinite(number) ? number : text;
+}
+
+/**
+ * Splits a preset reference into the preset name and its arguments.
+ *
+ * @param reference - e.g. "api-example" or `api-example(title="Create user")`
+ * @returns Preset name and arguments (empty when none are given)
+ */
+export function parsePresetReference(reference: string): PresetReference {
+	const trimmed = reference.trim();
+	const referenceMatch = PRESET_REFERENCE_PATTERN.exec(trimmed);
+	if (!referenceMatch) return { name: trimmed, args: {} };
+
+	const args: Record<string, PresetParamValue> = {};
+	const argPattern = new RegExp(PRESET_ARG_PATTERN.source, 'g');
+	let match: RegExpExecArray | null;
+	while ((match = argPattern.exec(referenceMatch[2])) !== null) {
+		// Unmatched groups are undefined
+		const [, , doubleQuoted, singleQuoted, bare]: (string | undefined)[] = match;
+		args[match[1]] = doubleQuoted ?? singleQuoted ?? parseBareValue(bare ?? '');
+	}
+
+	return { name: referenceMatch[1], args };
+}
+
+/**
+ * Replaces ${name} references with parameter values throughout parsed
+ * settings. A value that is only a reference takes the parameter's type
+ * (so `FOLD: ${lines}` stays a number); references inside longer text are
+ * substituted as text. References to unknown or unset parameters are left
+ * as they are.
+ *
+ * @param value - Parsed settings (or any value within them)
+ * @param params - Parameter values by name
+ * @returns Settings with references replaced (the input is not modified)
+ */
+export function substitutePresetParams(value: unknown, params: Record<string, PresetParamValue | undefined>): unknown {
+	if (typeof value === 'string') {
+		const wholeMatch = new RegExp(`^${PRESET_PARAM_PATTERN.source}$`).exec(value.trim());
+		const wholeValue = wholeMatch ? params[wholeMatch[1]] : undefined;
+		if (wholeValue !== undefined) {
+			return wholeValue;
+		}
+
+		return value.replace(new RegExp(PRESET_PARAM_PATTERN.source, 'g'), (reference, name: string) => {
+			const paramValue = params[name];
+			return paramValue !== undefined ? String(paramValue) : reference;
+		});
+	}
+
+	if (Array.isArray(value)) {
+		return value.map((entry: unknown) => substitutePresetParams(entry, params));
+	}
+
+	if (value && typeof value === 'object') {
+		const result: Record<string, unknown> = {};
+		for (const [key, entry] of Object.entries(value)) {
+			result[key] = substitutePresetParams(entry, params);
+		}
+		return result;
+	}
+
+	return value;
+}
+
+/**
+ * Reads the parameters a preset declares under PARAMS, with their
+ * defaults. Parameters without a default (or with a non-scalar one) are
+ * listed as undefined.
+ *
+ * @param presetProps - Parsed preset settings
+ * @returns Default values by parameter name
+ */
+export function getPresetParamDefaults(presetProps: Record<string, unknown>): Record<string, PresetParamValue | undefined> {
+	const declared: unknown = presetProps[YAML_PRESET_PARAMS];
+	const defaults: Record<string, PresetParamValue | undefined> = {};
+	if (!declared || typeof declared !== 'object' || Array.isArray(declared)) return defaults;
+
+	for (const [name, value] of Object.entries(declared as Record<string, unknown>)) {
+		defaults[name] = typeof value === 'string' || typeof value === 'number' || typeof value === 'boolean'
+			? value
+			: undefined;
+	}
+	return defaults;
+}
+
+/**
+ * Combines parameter values, skipping unset ones so they don't hide a
+ * value from a lower layer.
+ *
+ * @param base - Lower-priority values (e.g. defaults)
+ * @param override - Higher-priority values (e.g. block arguments)
+ * @returns Combined values (neither input is modified)
+ */
+export function mergePresetParams(
+	base: Record<string, PresetParamValue | undefined>,
+	override: Record<string, PresetParamValue | undefined>
+): Record<string, PresetParamValue | undefined> {
+	const result = { ...base };
+	for (const [name, value] of Object.entries(override)) {
+		if (value !== undefined) {
+			result[name] = value;
+		}
+	}
+	return result;
+}
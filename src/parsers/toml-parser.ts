@@ -0,0 +1,491 @@
+/**
+ * Ultra Code Fence - TOML Parser
+ *
+ * Minimal TOML reader for ufence block settings, for people who prefer
+ * `KEY = value` to YAML indentation. Covers what block settings need:
+ * tables, arrays of tables, dotted and quoted keys, strings (basic,
+ * literal and multi-line), numbers, booleans, arrays and inline tables.
+ * Dates are kept as strings.
+ */
+
+// =============================================================================
+// Types
+// =============================================================================
+
+/** A TOML table (also used for inline tables) */
+type TomlTable = Record<string, unknown>;
+
+// =============================================================================
+// Constants
+// =============================================================================
+
+/** Characters allowed in bare keys */
+const BARE_KEY_PATTERN = /[A-Za-z0-9_-]/;
+
+/** Integer literals, including hex, octal and binary */
+const INTEGER_PATTERN = /^[+-]?(?:0|[1-9](?:_?\d)*)$|^0x[\da-fA-F](?:_?[\da-fA-F])*$|^0o[0-7](?:_?[0-7])*$|^0b[01](?:_?[01])*$/;
+
+/** Float literals */
+const FLOAT_PATTERN = /^[+-]?(?:0|[1-9](?:_?\d)*)(?:\.\d(?:_?\d)*)?(?:[eE][+-]?\d(?:_?\d)*)?$/;
+
+/** Offset, local date-time, date and time literals (kept as strings) */
+const DATE_TIME_PATTERN = /^\d{4}-\d{2}-\d{2}(?:[T ]\d{2}:\d{2}(?::\d{2}(?:\.\d+)?)?(?:Z|[+-]\d{2}:\d{2})?)?$|^\d{2}:\d{2}:\d{2}(?:\.\d+)?$/;
+
+/** Single-character escapes in basic strings */
+const ESCAPES: Record<string, string | undefined> = {
+	b: '\b',
+	t: '\t',
+	n: '\n',
+	f: '\f',
+	r: '\r',
+	'"': '"',
+	'\\': '\\',
+};
+
+// =============================================================================
+// Reader
+// =============================================================================
+
+/**
+ * Cursor over TOML source text.
+ */
+class TomlReader {
+	private text: string;
+	private position = 0;
+
+	constructor(text: string) {
+		this.text = text;
+	}
+
+	/** True once all input has been consumed */
+	get done(): boolean {
+		return this.position >= this.text.length;
+	}
+
+	/** Current character ('' at the end) */
+	peek(offset = 0): string {
+		return this.text.charAt(this.position + offset);
+	}
+
+	/** Checks whether the input continues with the given text */
+	startsWith(prefix: string): boolean {
+		return this.text.startsWith(prefix, this.position);
+	}
+
+	/** Moves past the given number of characters */
+	advance(count = 1): void {
+		this.position += count;
+	}
+
+	/** Consumes the given text or fails */
+	expect(token: string): void {
+		if (!this.startsWith(token)) {
+			this.fail(`expected "${token}"`);
+		}
+		this.advance(token.length);
+	}
+
+	/** Skips spaces and tabs */
+	skipSpaces(): void {
+		while (this.peek() === ' ' || this.peek() === '\t') this.advance();
+	}
+
+	/** Skips a comment up to (not including) the end of the line */
+	skipComment(): void {
+		if (this.peek() !== '#') return;
+		while (!this.done && this.peek() !== '\n') this.advance();
+	}
+
+	/** Skips whitespace, newlines and comments (between statements or array items) */
+	skipBlank(): void {
+		for (;;) {
+			this.skipSpaces();
+			this.skipComment();
+			if (this.peek() === '\n' || this.peek() === '\r') {
+				this.advance();
+				continue;
+			}
+			return;
+		}
+	}
+
+	/** Requires the rest of the line to be blank or a comment */
+	endOfLine(): void {
+		this.skipSpaces();
+		this.skipComment();
+		if (this.peek() === '\r') this.advance();
+		if (this.done) return;
+		if (this.peek() !== '\n') {
+			this.fail('expected end of line');
+		}
+		this.advance();
+	}
+
+	/** Reads characters while they match a test */
+	readWhile(test: (char: string) => boolean): string {
+		const start = this.position;
+		while (!this.done && test(this.peek())) this.advance();
+		return this.text.substring(start, this.position);
+	}
+
+	/** Throws an error that names the current line */
+	fail(message: string): never {
+		const line = this.text.substring(0, this.position).split('\n').length;
+		throw new Error(`TOML line ${String(line)}: ${message}`);
+	}
+}
+
+// =============================================================================
+// Keys
+// =============================================================================
+
+/**
+ * Reads a possibly dotted key (`a.b."c d"`).
+ */
+function readKey(reader: TomlReader): string[] {
+	const parts: string[] = [];
+
+	for (;;) {
+		reader.skipSpaces();
+
+		if (reader.peek() === '"') {
+			parts.push(readBasicString(reader));
+		} else if (reader.peek() === '\'') {
+			parts.push(readLiteralString(reader));
+		} else {
+			const bare = reader.readWhile(char => BARE_KEY_PATTERN.test(char));
+			if (!bare) reader.fail('expected a key');
+			parts.push(bare);
+		}
+
+		reader.skipSpaces();
+		if (reader.peek() !== '.') return parts;
+		reader.advance();
+	}
+}
+
+// =============================================================================
+// Strings
+// =============================================================================
+
+/**
+ * Reads a \u or \U escape of the given length.
+ */
+function readUnicodeEscape(reader: TomlReader, length: number): string {
+	let digits = '';
+	for (let i = 0; i < length; i++) {
+		digits += reader.peek();
+		reader.advance();
+	}
+
+	if (!/^[\da-fA-F]+$/.test(digits)) reader.fail('invalid unicode escape');
+	return String.fromCodePoint(parseInt(digits, 16));
+}
+
+/**
+ * Reads one escape sequence after a backslash in a basic string.
+ */
+function readEscape(reader: TomlReader): string {
+	const char = reader.peek();
+	reader.advance();
+
+	if (char === 'u') return readUnicodeEscape(reader, 4);
+	if (char === 'U') return readUnicodeEscape(reader, 8);
+
+	const escaped = ESCAPES[char];
+	if (escaped === undefined) reader.fail(`invalid escape "\\${char}"`);
+	return escaped;
+}
+
+/**
+ * Reads a "basic" string with escapes.
+ */
+function readBasicString(reader: TomlReader): string {
+	reader.expect('"');
+	let value = '';
+
+	while (reader.peek() !== '"') {
+		if (reader.done || reader.peek() === '\n') reader.fail('unterminated string');
+
+		if (reader.peek() === '\\') {
+			reader.advance();
+			value += readEscape(reader);
+		} else {
+			value += reader.peek();
+			reader.advance();
+		}
+	}
+
+	reader.advance();
+	return value;
+}
+
+/**
+ * Reads a 'literal' string (no escapes).
+ */
+function readLiteralString(reader: TomlReader): string {
+	reader.expect('\'');
+	const value = reader.readWhile(char => char !== '\'' && char !== '\n');
+	if (reader.peek() !== '\'') reader.fail('unterminated string');
+	reader.advance();
+	return value;
+}
+
+/**
+ * Reads a """multi-line basic""" or '''multi-line literal''' string.
+ *
+ * A newline right after the opening quotes is dropped. In basic strings
+ * a backslash at the end of a line joins it to the next non-blank text.
+ */
+function readMultilineString(reader: TomlReader, quote: string): string {
+	const delimiter = quote.repeat(3);
+	const isBasic = quote === '"';
+	reader.expect(delimiter);
+
+	if (reader.startsWith('\r\n')) reader.advance(2);
+	else if (reader.peek() === '\n') reader.advance();
+
+	let value = '';
+
+	for (;;) {
+		if (reader.done) reader.fail('unterminated multi-line string');
+
+		// Up to two quotes may sit right before the closing delimiter
+		if (reader.startsWith(delimiter)) {
+			const quotes = reader.readWhile(char => char === quote);
+			value += quote.repeat(Math.min(quotes.length - 3, 2));
+			return value;
+		}
+
+		if (isBasic && reader.peek() === '\\') {
+			reader.advance();
+			const rest = reader.readWhile(char => char === ' ' || char === '\t');
+			if (reader.peek() === '\n' || reader.startsWith('\r\n')) {
+				reader.readWhile(char => /\s/.test(char));
+				continue;
+			}
+			if (rest) reader.fail('invalid escape "\\ "');
+			value += readEscape(reader);
+			continue;
+		}
+
+		value += reader.peek();
+		reader.advance();
+	}
+}
+
+// =============================================================================
+// Values
+// =============================================================================
+
+/**
+ * Reads an array, which may span several lines and hold comments.
+ */
+function readArray(reader: TomlReader): unknown[] {
+	reader.expect('[');
+	const items: unknown[] = [];
+
+	for (;;) {
+		reader.skipBlank();
+		if (reader.peek() === ']') break;
+
+		items.push(readValue(reader));
+		reader.skipBlank();
+
+		if (reader.peek() === ',') {
+			reader.advance();
+			continue;
+		}
+		if (reader.peek() !== ']') reader.fail('expected "," or "]" in array');
+	}
+
+	reader.advance();
+	return items;
+}
+
+/**
+ * Reads an inline table (`{ KEY = value, ... }`) on one line.
+ */
+function readInlineTable(reader: TomlReader): TomlTable {
+	reader.expect('{');
+	const table: TomlTable = {};
+
+	reader.skipSpaces();
+	if (reader.peek() === '}') {
+		reader.advance();
+		return table;
+	}
+
+	for (;;) {
+		const key = readKey(reader);
+		reader.expect('=');
+		reader.skipSpaces();
+		assignValue(reader, table, key, readValue(reader));
+		reader.skipSpaces();
+
+		if (reader.peek() === ',') {
+			reader.advance();
+			continue;
+		}
+		if (reader.peek() === '}') {
+			reader.advance();
+			return table;
+		}
+		reader.fail('expected "," or "}" in inline table');
+	}
+}
+
+/**
+ * Converts a bare token into a boolean, number or date string.
+ */
+function readScalar(reader: TomlReader): unknown {
+	const token = reader.readWhile(char => !/[\s,\]}#]/.test(char));
+
+	if (token === 'true') return true;
+	if (token === 'false') return false;
+
+	const digits = token.replace(/_/g, '');
+	if (INTEGER_PATTERN.test(token)) {
+		if (/^0[xob]/.test(digits)) {
+			const radix = { x: 16, o: 8, b: 2 }[digits.charAt(1) as 'x' | 'o' | 'b'];
+			return parseInt(digits.substring(2), radix);
+		}
+		return parseInt(digits, 10);
+	}
+	if (FLOAT_PATTERN.test(token)) return parseFloat(digits);
+	if (/^[+-]?inf$/.test(token)) return token.startsWith('-') ? -Infinity : Infinity;
+	if (/^[+-]?nan$/.test(token)) return NaN;
+	if (DATE_TIME_PATTERN.test(token)) return token;
+
+	return reader.fail(token ? `invalid value "${token}"` : 'expected a value');
+}
+
+/**
+ * Reads any value.
+ */
+function readValue(reader: TomlReader): unknown {
+	if (reader.startsWith('"""')) return readMultilineString(reader, '"');
+	if (reader.startsWith('\'\'\'')) return readMultilineString(reader, '\'');
+
+	switch (reader.peek()) {
+		case '"': return readBasicString(reader);
+		case '\'': return readLiteralString(reader);
+		case '[': return readArray(reader);
+		case '{': return readInlineTable(reader);
+		default: return readScalar(reader);
+	}
+}
+
+// =============================================================================
+// Tables
+// =============================================================================
+
+/**
+ * Checks whether a value is a (non-array) table.
+ */
+function isTable(value: unknown): value is TomlTable {
+	return typeof value === 'object' && value !== null && !Array.isArray(value);
+}
+
+/**
+ * Walks (creating as needed) the tables along a key path.
+ *
+ * For arrays of tables, the most recently added table is used.
+ */
+function resolveTable(reader: TomlReader, root: TomlTable, path: string[]): TomlTable {
+	let table = root;
+
+	for (const key of path) {
+		const existing = table[key];
+
+		if (existing === undefined) {
+			const created: TomlTable = {};
+			table[key] = created;
+			table = created;
+		} else if (Array.isArray(existing) && existing.length > 0 && isTable(existing[existing.length - 1])) {
+			table = existing[existing.length - 1] as TomlTable;
+		} else if (isTable(existing)) {
+			table = existing;
+		} else {
+			reader.fail(`"${key}" is already a value, not a table`);
+		}
+	}
+
+	return table;
+}
+
+/**
+ * Assigns a value at a (possibly dotted) key, refusing duplicates.
+ */
+function assignValue(reader: TomlReader, table: TomlTable, key: string[], value: unknown): void {
+	const parent = resolveTable(reader, table, key.slice(0, -1));
+	const name = key[key.length - 1];
+
+	if (name in parent) reader.fail(`duplicate key "${key.join('.')}"`);
+	parent[name] = value;
+}
+
+/**
+ * Appends a new table to the array of tables at a key path.
+ */
+function appendTable(reader: TomlReader, root: TomlTable, path: string[]): TomlTable {
+	const parent = resolveTable(reader, root, path.slice(0, -1));
+	const name = path[path.length - 1];
+	const created: TomlTable = {};
+
+	const existing = parent[name];
+	if (existing === undefined) {
+		parent[name] = [created];
+	} else if (Array.isArray(existing)) {
+		existing.push(created);
+	} else {
+		reader.fail(`"${path.join('.')}" is not an array of tables`);
+	}
+
+	return created;
+}
+
+// =============================================================================
+// Public API
+// =============================================================================
+
+/**
+ * Parses TOML text into a plain object.
+ *
+ * @param text - TOML source
+ * @returns Parsed tables and values
+ * @throws Error naming the line for invalid TOML
+ *
+ * @example
+ * parseToml('[RENDER]\nLINES = true\n[[COPY.AS]]\nFORMAT = "json"')
+ * // { RENDER: { LINES: true }, COPY: { AS: [{ FORMAT: 'json' }] } }
+ */
+export function parseToml(text: string): Record<string, unknown> {
+	const reader = new TomlReader(text);
+	const root: TomlTable = {};
+	let current = root;
+
+	for (;;) {
+		reader.skipBlank();
+		if (reader.done) return root;
+
+		if (reader.startsWith('[[')) {
+			reader.advance(2);
+			const path = readKey(reader);
+			reader.expect(']]');
+			current = appendTable(reader, root, path);
+		} else if (reader.peek() === '[') {
+			reader.advance();
+			const path = readKey(reader);
+			reader.expect(']');
+			current = resolveTable(reader, root, path);
+		} else {
+			const key = readKey(reader);
+			reader.expect('=');
+			reader.skipSpaces();
+			assignValue(reader, current, key, readValue(reader));
+		}
+
+		reader.endOfLine();
+	}
+}
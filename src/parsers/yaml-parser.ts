@@ -6,8 +6,8 @@
  * structured data from raw block content.
  */
 
-import { parseYaml } from 'obsidian';
 import type {
+	ConfigFormat,
 	ParsedBlockContent,
 	ParsedYamlConfig,
 	YamlMetaConfig,
@@ -15,6 +15,21 @@ import type {
 	YamlFilterConfig,
 	YamlCalloutConfig,
 	YamlCalloutEntry,
+	YamlCopyConfig,
+	YamlCopyAsEntry,
+	ResolvedCopyAsEntry,
+	CopyFeedbackConfig,
+	YamlHighlightConfig,
+	YamlDownloadConfig,
+	YamlPrintConfig,
+	YamlRunConfig,
+	YamlHeaderConfig,
+	YamlFooterConfig,
+	YamlAnnotationEntry,
+	YamlBlameEntry,
+	YamlDiffConfig,
+	LineAnnotation,
+	LineBlame,
 	YamlRenderCmdoutConfig,
 	YamlTextStyleConfig,
 	ResolvedBlockConfig,
@@ -24,9 +39,16 @@ import type {
 	PluginSettings,
 	TitleBarStyle,
 	CommandOutputStyles,
+	MaxHeightLimit,
+	ConfigMode,
+	YamlPatternsConfig,
+	YamlTokensConfig,
+	HighlightTheme,
 } from '../types';
 import {
 	INLINE_CODE_SEPARATOR_END,
+	VAULT_PREFIX,
+	GIT_SOURCE_PATTERN,
 	YAML_SECTIONS,
 	YAML_META,
 	YAML_RENDER_DISPLAY,
@@ -37,9 +59,42 @@ import {
 	YAML_TEXT_STYLE,
 	YAML_CALLOUT,
 	YAML_CALLOUT_ENTRY,
+	YAML_COPY,
+	YAML_COPY_AS_ENTRY,
+	YAML_HIGHLIGHT,
+	YAML_DOWNLOAD,
+	YAML_PRINT,
+	YAML_RUN,
+	ENV_VARIABLE_NAME_PATTERN,
+	YAML_HEADER,
+	YAML_FOOTER,
+	YAML_ANNOTATION,
+	YAML_BLAME,
+	YAML_DIFF,
+	DIFF_LANGUAGES,
+	INDENT_GUIDE_TAB_WIDTH,
 	YAML_PROMPT,
+	CONFIG_RENAMES,
+	FRONTMATTER_CONFIG_KEY,
+	YAML_PRESET_EXTENDS,
+	YAML_PRESET_APPLIES_TO_TAGS,
+	YAML_PRESET_WHEN,
+	BUILT_IN_REDACTIONS,
+	DEFAULT_COPY_MESSAGE,
+	CONFIG_MODES,
+	MIN_SOURCE_REFRESH_INTERVAL_MS,
+	MAX_TIMER_DELAY_MS,
+	MAX_LISTED_LINE,
 	normalizeCalloutType,
 } from '../constants';
+import { COPY_AS_DEFAULT_LABELS, isKnownCopyAsFormat } from '../utils/copy-transforms';
+import { getDefaultTabSize } from '../utils/tab-size';
+import { resolvePatternStyles, resolveTokenStyles } from '../utils/token-styles';
+import { getDefaultShebang } from '../services/download-service';
+import { highlightThemeKey } from '../services/vscode-theme';
+import type { PresetParamValue } from './preset-params';
+import { getPresetParamDefaults, mergePresetParams, substitutePresetParams } from './preset-params';
+import { parseConfigText, parseYamlSettings, expandYamlMergeKeys } from './config-format';
 
 // =============================================================================
 // Block Parsing
@@ -52,9 +107,13 @@ import {
  * 1. YAML-only: The entire block is YAML configuration with a PATH property
  * 2. Embedded code: YAML followed by ~~~ separator and code content
  *
+ * The settings may also be written in TOML or JSON; the format is taken
+ * from `configFormat` or detected from the settings text.
+ *
  * @param rawContent - The raw content between the code fence markers
+ * @param configFormat - Settings format (undefined = detect)
  * @returns Parsed configuration object
- * @throws Error if YAML parsing fails
+ * @throws Error if the settings before a ~~~ separator fail to parse
  *
  * @example
  * // File reference format:
@@ -67,18 +126,18 @@ import {
  * // ~~~
  * // console.log("Hello");
  */
-export function parseBlockContent(rawContent: string): ParsedBlockContent {
+export function parseBlockContent(rawContent: string, configFormat?: ConfigFormat): ParsedBlockContent {
 	const hasEmbeddedCode = rawContent.includes(INLINE_CODE_SEPARATOR_END) || rawContent.startsWith('~~~');
 
 	if (hasEmbeddedCode) {
-		return parseEmbeddedCodeBlock(rawContent);
+		return parseEmbeddedCodeBlock(rawContent, configFormat);
 	}
 
-	// Try to parse as YAML — if valid and contains known sections, use it
+	// Try to parse as settings — if valid and contains known sections, use it
 	try {
-		const yamlProperties = parseYaml(rawContent) as Record<string, unknown>;
+		const yamlProperties = parseConfigText(rawContent, configFormat);
 
-		if (typeof yamlProperties === 'object' && hasKnownYamlSections(yamlProperties)) {
+		if (hasKnownYamlSections(yamlProperties)) {
 			return {
 				yamlProperties,
 				embeddedCode: null,
@@ -86,7 +145,7 @@ export function parseBlockContent(rawContent: string): ParsedBlockContent {
 			};
 		}
 	} catch {
-		// YAML parsing failed — not YAML
+		// Parsing failed — not settings
 	}
 
 	// No valid YAML structure found — treat entire content as plain code
@@ -101,9 +160,10 @@ export function parseBlockContent(rawContent: string): ParsedBlockContent {
  * Parses a block containing embedded code (YAML + ~~~ + code).
  *
  * @param rawContent - Raw block content with ~~~ separator
+ * @param configFormat - Settings format (undefined = detect)
  * @returns Parsed configuration with embedded code
  */
-function parseEmbeddedCodeBlock(rawContent: string): ParsedBlockContent {
+function parseEmbeddedCodeBlock(rawContent: string, configFormat?: ConfigFormat): ParsedBlockContent {
 	const separatorIndex = rawContent.indexOf(INLINE_CODE_SEPARATOR_END);
 	const yamlPart = rawContent.substring(0, separatorIndex);
 
@@ -117,7 +177,7 @@ function parseEmbeddedCodeBlock(rawContent: string): ParsedBlockContent {
 	}
 
 	const yamlProperties = yamlPart.trim()
-		? parseYaml(yamlPart) as Record<string, unknown>
+		? parseConfigText(yamlPart, configFormat)
 		: {};
 
 	return {
@@ -142,11 +202,50 @@ function hasKnownYamlSections(yamlProps: Record<string, unknown>): boolean {
 		YAML_SECTIONS.render,
 		YAML_SECTIONS.filter,
 		YAML_SECTIONS.callout,
+		YAML_SECTIONS.copy,
+		YAML_SECTIONS.highlight,
+		YAML_SECTIONS.download,
+		YAML_SECTIONS.header,
+		YAML_SECTIONS.footer,
+		YAML_SECTIONS.annotations,
+		YAML_SECTIONS.blame,
+		YAML_SECTIONS.tokens,
+		YAML_SECTIONS.patterns,
+		YAML_SECTIONS.diff,
+		YAML_SECTIONS.print,
+		YAML_SECTIONS.run,
 		YAML_PROMPT,
+		// Old names of renamed sections
+		...Object.keys(CONFIG_RENAMES).filter(path => !path.includes('.')),
 	];
 	return knownKeys.some(key => key in yamlProps);
 }
 
+/**
+ * Moves settings under renamed keys to their new names, so blocks written
+ * for an older release keep working. A key is left alone when its new
+ * name is also present (or another old name already moved there).
+ *
+ * @param yamlProps - Parsed YAML properties
+ * @param pathPrefix - Path of this level ("" at the top)
+ * @returns Properties with renamed keys moved (the input is not modified)
+ */
+export function applyConfigRenames(yamlProps: Record<string, unknown>, pathPrefix = ''): Record<string, unknown> {
+	const result: Record<string, unknown> = {};
+
+	for (const [key, value] of Object.entries(yamlProps)) {
+		const path = pathPrefix ? `${pathPrefix}.${key}` : key;
+		const renamedTo: string | undefined = CONFIG_RENAMES[path];
+		const targetKey = renamedTo !== undefined && !(renamedTo in yamlProps) && !(renamedTo in result) ? renamedTo : key;
+
+		result[targetKey] = value && typeof value === 'object' && !Array.isArray(value)
+			? applyConfigRenames(value as Record<string, unknown>, path)
+			: value;
+	}
+
+	return result;
+}
+
 // =============================================================================
 // Value Resolution
 // =============================================================================
@@ -301,9 +400,16 @@ export function parseMetaSection(yamlProps: Record<string, unknown>): YamlMetaCo
 
 	return {
 		PATH: safeString(meta[YAML_META.path]),
+		SRC: safeString(meta[YAML_META.src]),
+		QUERY: safeString(meta[YAML_META.query]),
+		REFRESH: safeString(meta[YAML_META.refresh]),
+		REF: safeString(meta[YAML_META.ref]),
 		TITLE: safeString(meta[YAML_META.title]),
 		DESC: safeString(meta[YAML_META.desc]),
 		PRESET: safeString(meta[YAML_META.preset]),
+		MODE: safeString(meta[YAML_META.mode]),
+		SUMMARY: safeString(meta[YAML_META.summary]),
+		ID: safeString(meta[YAML_META.id])?.trim().replace(/^\^/, ''),
 	};
 }
 
@@ -339,9 +445,170 @@ export function parseRenderDisplaySection(yamlProps: Record<string, unknown>): Y
 		ALT_COPY_JOIN: safeString(render[YAML_RENDER_DISPLAY.altCopyJoin] ?? render[YAML_RENDER_DISPLAY.cmdCopyJoin]),
 		JOIN_IGNORE_REGEX: safeString(render[YAML_RENDER_DISPLAY.joinIgnoreRegex]),
 		PRINT: safeString(render[YAML_RENDER_DISPLAY.print])?.toLowerCase(),
+		LINE_COPY: render[YAML_RENDER_DISPLAY.lineCopy] !== undefined
+			? resolveBoolean(render[YAML_RENDER_DISPLAY.lineCopy], false)
+			: undefined,
+		LINE_START: render[YAML_RENDER_DISPLAY.lineStart] !== undefined
+			? Math.floor(resolveNumber(render[YAML_RENDER_DISPLAY.lineStart], 1))
+			: undefined,
+		LINE_ANCHOR: render[YAML_RENDER_DISPLAY.lineAnchor] !== undefined
+			? Math.max(0, Math.floor(resolveNumber(render[YAML_RENDER_DISPLAY.lineAnchor], 0)))
+			: undefined,
+		FOLD_REGIONS: safeString(render[YAML_RENDER_DISPLAY.foldRegions])?.toLowerCase(),
+		FOLD_START: safeString(render[YAML_RENDER_DISPLAY.foldStart]),
+		FOLD_END: safeString(render[YAML_RENDER_DISPLAY.foldEnd]),
+		COLLAPSED: render[YAML_RENDER_DISPLAY.collapsed] !== undefined
+			? resolveBoolean(render[YAML_RENDER_DISPLAY.collapsed], false)
+			: undefined,
+		MAX_HEIGHT: safeString(render[YAML_RENDER_DISPLAY.maxHeight]),
+		INDENT_GUIDES: render[YAML_RENDER_DISPLAY.indentGuides] !== undefined
+			? resolveBoolean(render[YAML_RENDER_DISPLAY.indentGuides], false)
+			: undefined,
+		INDENT_GUIDE_COLOUR: safeString(render[YAML_RENDER_DISPLAY.indentGuideColour]),
+		INDENT_GUIDE_STYLE: safeString(render[YAML_RENDER_DISPLAY.indentGuideStyle])?.toLowerCase(),
+		WHITESPACE: parseWhitespaceMode(render[YAML_RENDER_DISPLAY.whitespace]),
+		COMMENT_KEYWORDS: parseCommentKeywordMode(render[YAML_RENDER_DISPLAY.commentKeywords]),
+		BRACKETS: render[YAML_RENDER_DISPLAY.brackets] !== undefined
+			? resolveBoolean(render[YAML_RENDER_DISPLAY.brackets], false)
+			: undefined,
+		SEMANTIC: render[YAML_RENDER_DISPLAY.semantic] !== undefined
+			? resolveBoolean(render[YAML_RENDER_DISPLAY.semantic], false)
+			: undefined,
+		CHANGED_SINCE: parseChangedSince(render[YAML_RENDER_DISPLAY.changedSince]),
+		MINIMAP: render[YAML_RENDER_DISPLAY.minimap] !== undefined
+			? Math.max(0, Math.floor(resolveNumber(render[YAML_RENDER_DISPLAY.minimap], 0)))
+			: undefined,
+		ZEBRA_COLOUR: safeString(render[YAML_RENDER_DISPLAY.zebraColour]),
+		HOVER: render[YAML_RENDER_DISPLAY.hover] !== undefined
+			? resolveBoolean(render[YAML_RENDER_DISPLAY.hover], false)
+			: undefined,
+		LIGATURES: render[YAML_RENDER_DISPLAY.ligatures] !== undefined
+			? resolveBoolean(render[YAML_RENDER_DISPLAY.ligatures], true)
+			: undefined,
+		FONT_FEATURES: joinLineListValue(render[YAML_RENDER_DISPLAY.fontFeatures]),
+		FONT_FAMILY: safeString(render[YAML_RENDER_DISPLAY.fontFamily])?.trim(),
+		FONT_SIZE: safeString(render[YAML_RENDER_DISPLAY.fontSize]),
+		TAB_SIZE: render[YAML_RENDER_DISPLAY.tabSize] !== undefined
+			? Math.max(1, Math.floor(resolveNumber(render[YAML_RENDER_DISPLAY.tabSize], INDENT_GUIDE_TAB_WIDTH)))
+			: undefined,
+		RULER: joinLineListValue(render[YAML_RENDER_DISPLAY.ruler]),
+		RULER_COLOUR: safeString(render[YAML_RENDER_DISPLAY.rulerColour]),
+		ANSI: render[YAML_RENDER_DISPLAY.ansi] !== undefined
+			? resolveBoolean(render[YAML_RENDER_DISPLAY.ansi], true)
+			: undefined,
+		ANSI_COPY: safeString(render[YAML_RENDER_DISPLAY.ansiCopy])?.toLowerCase(),
+		DIRECTION: safeString(render[YAML_RENDER_DISPLAY.direction])?.toLowerCase(),
+		THEME: safeString(render[YAML_RENDER_DISPLAY.theme]),
+		VARIABLES: render[YAML_RENDER_DISPLAY.variables] !== undefined
+			? resolveBoolean(render[YAML_RENDER_DISPLAY.variables], false)
+			: undefined,
 	};
 }
 
+/**
+ * Parses RENDER.WHITESPACE, which takes a mode or a boolean
+ * (true = 'all', false = 'none').
+ *
+ * @param whitespaceValue - WHITESPACE value from YAML
+ * @returns Lower-case mode, or undefined if not set
+ */
+function parseWhitespaceMode(whitespaceValue: unknown): string | undefined {
+	const mode = safeString(whitespaceValue)?.toLowerCase();
+	if (mode === undefined) return undefined;
+
+	if (mode === 'true' || mode === 'yes' || mode === 'on') return 'all';
+	if (mode === 'false' || mode === 'no' || mode === 'off') return 'none';
+	return mode;
+}
+
+/**
+ * Parses RENDER.CHANGED_SINCE, which takes 'last', an age or a boolean
+ * (true = 'last', false = 'off').
+ *
+ * @param changedValue - CHANGED_SINCE value from YAML
+ * @returns Lower-case value, or undefined if not set
+ */
+function parseChangedSince(changedValue: unknown): string | undefined {
+	const value = safeString(changedValue)?.trim().toLowerCase();
+	if (value === undefined) return undefined;
+
+	if (value === 'true' || value === 'yes' || value === 'on') return 'last';
+	if (value === 'false' || value === 'no' || value === 'none') return 'off';
+	return value;
+}
+
+/**
+ * Parses RENDER.COMMENT_KEYWORDS, which takes a mode or a boolean
+ * (true = 'on', false = 'off').
+ *
+ * @param modeValue - COMMENT_KEYWORDS value from YAML
+ * @returns Lower-case mode, or undefined if not set
+ */
+function parseCommentKeywordMode(modeValue: unknown): string | undefined {
+	const mode = safeString(modeValue)?.toLowerCase();
+	if (mode === undefined) return undefined;
+
+	if (mode === 'true' || mode === 'yes') return 'on';
+	if (mode === 'false' || mode === 'no') return 'off';
+	return mode;
+}
+
+/**
+ * Parses a height limit (RENDER.MAX_HEIGHT).
+ *
+ * Accepts a line count ("20", "20 lines", 20) or a pixel height ("400px").
+ *
+ * @param heightValue - Height value from YAML
+ * @returns Parsed limit, or undefined if missing, zero or invalid
+ */
+export function parseMaxHeight(heightValue: string | undefined): MaxHeightLimit | undefined {
+	if (heightValue === undefined) return undefined;
+
+	const match = /^(\d+(?:\.\d+)?)\s*(px|lines?)?$/i.exec(heightValue.trim());
+	if (!match) return undefined;
+
+	const value = parseFloat(match[1]);
+	if (value <= 0) return undefined;
+
+	return (match[2] || '').toLowerCase() === 'px'
+		? { value, unit: 'px' }
+		: { value: Math.floor(value), unit: 'lines' };
+}
+
+/**
+ * Parses a font size (RENDER.FONT_SIZE).
+ *
+ * Accepts a CSS length in px, pt, em or rem, or a percentage. A bare
+ * number is taken as pixels.
+ *
+ * @param sizeValue - Size value from YAML
+ * @returns CSS font size, or an empty string if missing, zero or invalid
+ */
+export function parseFontSize(sizeValue: string | undefined): string {
+	if (sizeValue === undefined) return '';
+
+	const match = /^(\d+(?:\.\d+)?)\s*(px|pt|em|rem|%)?$/i.exec(sizeValue.trim());
+	if (!match || parseFloat(match[1]) <= 0) return '';
+
+	return `${match[1]}${(match[2] || 'px').toLowerCase()}`;
+}
+
+/**
+ * Parses the columns of RENDER.RULER.
+ *
+ * @param rulerValue - Comma-separated columns, e.g. "80, 120"
+ * @returns Positive columns in order, without repeats
+ */
+export function parseRulerColumns(rulerValue: string | undefined): number[] {
+	if (rulerValue === undefined) return [];
+
+	const columns = rulerValue.split(',')
+		.map(part => parseInt(part.trim(), 10))
+		.filter(column => column > 0);
+
+	return Array.from(new Set(columns)).sort((a, b) => a - b);
+}
+
 /**
  * Parses a line range specification.
  *
@@ -373,6 +640,139 @@ export function parseLineRange(rangeValue: unknown): [number, number] | null {
 	return null;
 }
 
+/** A META.SRC value that is a URL rather than a vault path. */
+const REMOTE_SOURCE_PATTERN = /^https?:\/\//;
+
+/** A META.SRC fragment naming a region (ufence:begin name) rather than lines. */
+const SOURCE_REGION_NAME_PATTERN = /^[A-Za-z_][\w.-]*$/;
+
+/**
+ * Parses a META.SRC reference: a vault path, URL or file in a git
+ * checkout, with an optional line range in the fragment as in a GitHub
+ * link, or the name of a region marked in the file.
+ *
+ * Accepts "scripts/deploy.sh", "scripts/deploy.sh#L10-42",
+ * "scripts/deploy.sh#L10-L42", "scripts/deploy.sh#L10",
+ * "scripts/deploy.sh#setup" (the region between `ufence:begin setup` and
+ * `ufence:end setup`), URLs, and "tools:bin/install.sh@v1.4.2" for
+ * bin/install.sh in the "tools" checkout at v1.4.2. A leading vault:// or
+ * / is ignored. A URL keeps a fragment that isn't a line range, since it
+ * may be part of the address.
+ *
+ * @param value - META.SRC value
+ * @returns Source path (vault://, http[s]:// or repository:path@ref), [start, end] range (null for the whole file) and region name if given, or null if empty or the fragment is invalid
+ */
+export function parseSourceReference(value: string): { path: string; range: [number, number] | null; region?: string } | null {
+	const text = value.trim();
+	const isUrl = REMOTE_SOURCE_PATTERN.test(text);
+	const [rawPath, fragment] = text.split(/#(?=[^#]*$)/);
+	const trimmedPath = rawPath.trim();
+	const gitMatch = isUrl ? null : GIT_SOURCE_PATTERN.exec(trimmedPath);
+	const vaultPath = trimmedPath.replace(/^vault:\/\//, '').replace(/^\/+/, '');
+
+	let path: string;
+	if (isUrl) {
+		path = rawPath;
+	} else if (gitMatch) {
+		path = `${gitMatch[1]}:${gitMatch[2].replace(/^\/+/, '')}@${gitMatch[3].trim()}`;
+	} else {
+		path = vaultPath ? VAULT_PREFIX + vaultPath : '';
+	}
+	if (!path) return null;
+	if (fragment === undefined) return { path, range: null };
+
+	const match = /^L?(\d+)(?:\s*-\s*L?(\d+))?$/i.exec(fragment.trim());
+	const range = match ? parseLineRange([match[1], match[2] ?? match[1]]) : null;
+	if (isUrl && !match) return { path: text, range: null };
+	if (!match && SOURCE_REGION_NAME_PATTERN.test(fragment.trim())) return { path, range: null, region: fragment.trim() };
+	return range ? { path, range } : null;
+}
+
+/** Length of each duration unit (META.REFRESH, RUN.TIMEOUT) in milliseconds. */
+const DURATION_UNITS: Record<string, number | undefined> = {
+	s: 1000,
+	m: 60 * 1000,
+	h: 60 * 60 * 1000,
+};
+
+/**
+ * Parses a duration: "30s", "5m", "1h" or a plain number of seconds.
+ *
+ * @param value - Duration value from YAML
+ * @returns Duration in milliseconds, or undefined if invalid
+ */
+function parseDuration(value: string): number | undefined {
+	const match = /^(\d+(?:\.\d+)?)\s*([smh]?)$/.exec(value.trim().toLowerCase());
+	const unit = match ? DURATION_UNITS[match[2] || 's'] : undefined;
+	return match && unit !== undefined ? parseFloat(match[1]) * unit : undefined;
+}
+
+/**
+ * Parses how often a block re-reads its embedded file (META.REFRESH).
+ *
+ * Accepts "30s", "5m", "1h" or a plain number of seconds. Intervals
+ * shorter than MIN_SOURCE_REFRESH_INTERVAL_MS are raised to it, and
+ * ones longer than a timer can wait (MAX_TIMER_DELAY_MS) lowered to it.
+ *
+ * @param value - Interval value from YAML
+ * @returns Interval in milliseconds, or 0 if missing, "off", zero or invalid
+ */
+export function parseRefreshInterval(value: string | undefined): number {
+	const interval = value === undefined ? undefined : parseDuration(value);
+	return interval ? Math.min(Math.max(interval, MIN_SOURCE_REFRESH_INTERVAL_MS), MAX_TIMER_DELAY_MS) : 0;
+}
+
+/**
+ * Parses how long a run may take before it is killed (RUN.TIMEOUT).
+ *
+ * Accepts "30s", "5m", "1h" or a plain number of seconds; "off", "none"
+ * or 0 mean no limit. Timeouts longer than a timer can wait
+ * (MAX_TIMER_DELAY_MS) are lowered to it.
+ *
+ * @param value - Timeout value from YAML
+ * @param defaultTimeoutMs - Used when the value is missing or invalid
+ * @returns Timeout in milliseconds (0 = no limit)
+ */
+export function parseRunTimeout(value: string | undefined, defaultTimeoutMs: number): number {
+	if (/^(?:off|none)$/i.test(value?.trim() ?? '')) return 0;
+	const timeout = (value === undefined ? undefined : parseDuration(value)) ?? defaultTimeoutMs;
+	return Math.min(timeout, MAX_TIMER_DELAY_MS);
+}
+
+/**
+ * Parses a list of line numbers and ranges.
+ *
+ * Accepts:
+ * - "3-5, 8" string format (comma-separated numbers and dash ranges)
+ * - 8 single number
+ * - [3, "5-7"] array of numbers and ranges
+ *
+ * Invalid items are skipped, and ranges stop at {@link MAX_LISTED_LINE}.
+ *
+ * @param listValue - Line list value from YAML
+ * @returns Sorted, de-duplicated line numbers (1-based)
+ */
+export function parseLineList(listValue: unknown): number[] {
+	const items = Array.isArray(listValue)
+		? listValue.map(item => safeString(item) ?? '')
+		: (safeString(listValue) ?? '').split(',');
+
+	const lines = new Set<number>();
+
+	for (const item of items) {
+		const match = /^\s*(\d+)\s*(?:-\s*(\d+)\s*)?$/.exec(item);
+		if (!match) continue;
+
+		const start = parseInt(match[1], 10);
+		const end = Math.min(match[2] !== undefined ? parseInt(match[2], 10) : start, MAX_LISTED_LINE);
+		for (let line = Math.max(start, 1); line <= end; line++) {
+			lines.add(line);
+		}
+	}
+
+	return Array.from(lines).sort((a, b) => a - b);
+}
+
 /**
  * Parses the FILTER section from YAML configuration.
  *
@@ -408,6 +808,12 @@ export function parseFilterSection(yamlProps: Record<string, unknown>): YamlFilt
 		};
 	}
 
+	// Parse BY_REGION
+	const byRegion = safeString(filter[YAML_FILTER.byRegion])?.trim();
+	if (byRegion) {
+		result.BY_REGION = byRegion;
+	}
+
 	return result;
 }
 
@@ -486,6 +892,452 @@ export function parseCalloutSection(yamlProps: Record<string, unknown>): YamlCal
 	return result;
 }
 
+/**
+ * Parses the COPY section from YAML configuration.
+ *
+ * @param yamlProps - Parsed YAML properties
+ * @returns COPY section configuration
+ */
+export function parseCopySection(yamlProps: Record<string, unknown>): YamlCopyConfig {
+	const copy = getSection(yamlProps, YAML_SECTIONS.copy);
+	const result: YamlCopyConfig = {};
+
+	// Parse AS array of copy transformations
+	const asEntries = copy[YAML_COPY.as];
+	if (Array.isArray(asEntries)) {
+		result.AS = asEntries
+			.filter(entry => entry && typeof entry === 'object' && !Array.isArray(entry))
+			.map(entryObj => {
+				const entry = entryObj as Record<string, unknown>;
+				const parsed: YamlCopyAsEntry = {};
+
+				if (entry[YAML_COPY_AS_ENTRY.label] !== undefined) {
+					parsed.LABEL = safeString(entry[YAML_COPY_AS_ENTRY.label]);
+				}
+
+				if (entry[YAML_COPY_AS_ENTRY.format] !== undefined) {
+					parsed.FORMAT = safeString(entry[YAML_COPY_AS_ENTRY.format])?.toLowerCase();
+				}
+
+				if (entry[YAML_COPY_AS_ENTRY.target] !== undefined) {
+					parsed.TARGET = safeString(entry[YAML_COPY_AS_ENTRY.target]);
+				}
+
+				if (entry[YAML_COPY_AS_ENTRY.schedule] !== undefined) {
+					parsed.SCHEDULE = safeString(entry[YAML_COPY_AS_ENTRY.schedule]);
+				}
+
+				return parsed;
+			});
+	}
+
+	if (copy[YAML_COPY.placeholders] !== undefined) {
+		result.PLACEHOLDERS = resolveBoolean(copy[YAML_COPY.placeholders], false);
+	}
+
+	if (copy[YAML_COPY.variables] !== undefined) {
+		result.VARIABLES = resolveBoolean(copy[YAML_COPY.variables], false);
+	}
+
+	if (copy[YAML_COPY.stripComments] !== undefined) {
+		result.STRIP_COMMENTS = resolveBoolean(copy[YAML_COPY.stripComments], false);
+	}
+
+	if (copy[YAML_COPY.feedback] !== undefined) {
+		result.FEEDBACK = resolveBoolean(copy[YAML_COPY.feedback], true);
+	}
+
+	// TOAST stays a string so it can hold a boolean or the toast text
+	if (copy[YAML_COPY.toast] !== undefined) {
+		result.TOAST = safeString(copy[YAML_COPY.toast]);
+	}
+
+	if (copy[YAML_COPY.feedbackDuration] !== undefined) {
+		result.FEEDBACK_DURATION = resolveNumber(copy[YAML_COPY.feedbackDuration], 0);
+	}
+
+	// REDACT accepts a single pattern or a list
+	const redact = copy[YAML_COPY.redact];
+	if (Array.isArray(redact)) {
+		result.REDACT = redact
+			.map(item => safeString(item))
+			.filter((item): item is string => item !== undefined && item !== '');
+	} else if (redact !== undefined) {
+		const pattern = safeString(redact);
+		result.REDACT = pattern ? [pattern] : [];
+	}
+
+	return result;
+}
+
+/**
+ * Reads a list written as a string or a YAML list into the string form.
+ *
+ * @param listValue - List value from YAML (line numbers, font features)
+ * @returns Comma-separated list
+ */
+function joinLineListValue(listValue: unknown): string | undefined {
+	return Array.isArray(listValue)
+		? listValue.map(item => safeString(item) ?? '').join(', ')
+		: safeString(listValue);
+}
+
+/**
+ * Parses the HIGHLIGHT section from YAML configuration.
+ *
+ * Array values for LINES are normalised to the "3-5, 8" string form
+ * so presets and block configs merge as plain strings.
+ *
+ * @param yamlProps - Parsed YAML properties
+ * @returns HIGHLIGHT section configuration
+ */
+export function parseHighlightSection(yamlProps: Record<string, unknown>): YamlHighlightConfig {
+	const highlight = getSection(yamlProps, YAML_SECTIONS.highlight);
+	const result: YamlHighlightConfig = {};
+
+	if (highlight[YAML_HIGHLIGHT.lines] !== undefined) {
+		result.LINES = joinLineListValue(highlight[YAML_HIGHLIGHT.lines]);
+	}
+
+	const match = safeString(highlight[YAML_HIGHLIGHT.match]);
+	if (match) {
+		result.MATCH = match;
+	}
+
+	if (highlight[YAML_HIGHLIGHT.focus] !== undefined) {
+		result.FOCUS = joinLineListValue(highlight[YAML_HIGHLIGHT.focus]);
+	}
+
+	if (highlight[YAML_HIGHLIGHT.focusToggle] !== undefined) {
+		result.FOCUS_TOGGLE = resolveBoolean(highlight[YAML_HIGHLIGHT.focusToggle], false);
+	}
+
+	// TERMS accepts a list or a comma-separated string
+	const terms = highlight[YAML_HIGHLIGHT.terms];
+	if (terms !== undefined) {
+		const values: Array<string | undefined> = Array.isArray(terms) ? terms.map(term => safeString(term)) : (safeString(terms) ?? '').split(',');
+		result.TERMS = values
+			.map(term => term?.trim() ?? '')
+			.filter(term => term !== '');
+	}
+
+	return result;
+}
+
+/**
+ * Parses the DOWNLOAD section from YAML configuration.
+ *
+ * SHEBANG is kept as a string so it can hold either a boolean
+ * ("true" / "false") or a custom shebang line.
+ *
+ * @param yamlProps - Parsed YAML properties
+ * @returns DOWNLOAD section configuration
+ */
+export function parseDownloadSection(yamlProps: Record<string, unknown>): YamlDownloadConfig {
+	const download = getSection(yamlProps, YAML_SECTIONS.download);
+	const result: YamlDownloadConfig = {};
+
+	if (download[YAML_DOWNLOAD.filename] !== undefined) {
+		result.FILENAME = safeString(download[YAML_DOWNLOAD.filename]);
+	}
+
+	if (download[YAML_DOWNLOAD.shebang] !== undefined) {
+		result.SHEBANG = safeString(download[YAML_DOWNLOAD.shebang]);
+	}
+
+	if (download[YAML_DOWNLOAD.executable] !== undefined) {
+		result.EXECUTABLE = resolveBoolean(download[YAML_DOWNLOAD.executable], false);
+	}
+
+	return result;
+}
+
+/**
+ * Parses the PRINT section from YAML configuration.
+ *
+ * @param yamlProps - Parsed YAML properties
+ * @returns PRINT section configuration
+ */
+export function parsePrintSection(yamlProps: Record<string, unknown>): YamlPrintConfig {
+	const print = getSection(yamlProps, YAML_SECTIONS.print);
+	const result: YamlPrintConfig = {};
+
+	if (print[YAML_PRINT.expand] !== undefined) {
+		result.EXPAND = resolveBoolean(print[YAML_PRINT.expand], true);
+	}
+
+	if (print[YAML_PRINT.theme] !== undefined) {
+		result.THEME = safeString(print[YAML_PRINT.theme])?.toLowerCase();
+	}
+
+	if (print[YAML_PRINT.lines] !== undefined) {
+		result.LINES = resolveBoolean(print[YAML_PRINT.lines], false);
+	}
+
+	if (print[YAML_PRINT.avoidBreaks] !== undefined) {
+		result.AVOID_BREAKS = resolveBoolean(print[YAML_PRINT.avoidBreaks], true);
+	}
+
+	return result;
+}
+
+/**
+ * Parses the RUN section from YAML configuration.
+ *
+ * @param yamlProps - Parsed YAML properties
+ * @returns RUN section configuration
+ */
+export function parseRunSection(yamlProps: Record<string, unknown>): YamlRunConfig {
+	const run = getSection(yamlProps, YAML_SECTIONS.run);
+	const result: YamlRunConfig = {};
+
+	if (run[YAML_RUN.enabled] !== undefined) {
+		result.ENABLED = resolveBoolean(run[YAML_RUN.enabled], true);
+	}
+
+	if (run[YAML_RUN.save] !== undefined) {
+		result.SAVE = resolveBoolean(run[YAML_RUN.save], false);
+	}
+
+	if (run[YAML_RUN.lines] !== undefined) {
+		result.LINES = resolveBoolean(run[YAML_RUN.lines], false);
+	}
+
+	if (run[YAML_RUN.timeout] !== undefined) {
+		result.TIMEOUT = safeString(run[YAML_RUN.timeout]);
+	}
+
+	// Variables with names a process can't have, or values that aren't scalars, are skipped
+	const env = run[YAML_RUN.env];
+	if (env && typeof env === 'object' && !Array.isArray(env)) {
+		result.ENV = {};
+		for (const [name, value] of Object.entries(env as Record<string, unknown>)) {
+			const text = safeString(value);
+			if (ENV_VARIABLE_NAME_PATTERN.test(name) && text !== undefined) {
+				result.ENV[name] = text;
+			}
+		}
+	}
+
+	return result;
+}
+
+/**
+ * Parses the HEADER section from YAML configuration.
+ *
+ * @param yamlProps - Parsed YAML properties
+ * @returns HEADER section configuration
+ */
+export function parseHeaderSection(yamlProps: Record<string, unknown>): YamlHeaderConfig {
+	const header = getSection(yamlProps, YAML_SECTIONS.header);
+	const result: YamlHeaderConfig = {};
+
+	if (header[YAML_HEADER.icon] !== undefined) {
+		result.ICON = safeString(header[YAML_HEADER.icon]);
+	}
+
+	if (header[YAML_HEADER.title] !== undefined) {
+		result.TITLE = resolveBoolean(header[YAML_HEADER.title], true);
+	}
+
+	if (header[YAML_HEADER.language] !== undefined) {
+		result.LANGUAGE = resolveBoolean(header[YAML_HEADER.language], false);
+	}
+
+	if (header[YAML_HEADER.buttons] !== undefined) {
+		result.BUTTONS = resolveBoolean(header[YAML_HEADER.buttons], false);
+	}
+
+	return result;
+}
+
+/**
+ * Parses the FOOTER section from YAML configuration.
+ *
+ * @param yamlProps - Parsed YAML properties
+ * @returns FOOTER section configuration
+ */
+export function parseFooterSection(yamlProps: Record<string, unknown>): YamlFooterConfig {
+	const footer = getSection(yamlProps, YAML_SECTIONS.footer);
+	const result: YamlFooterConfig = {};
+
+	if (footer[YAML_FOOTER.source] !== undefined) {
+		result.SOURCE = safeString(footer[YAML_FOOTER.source]);
+	}
+
+	if (footer[YAML_FOOTER.url] !== undefined) {
+		result.URL = safeString(footer[YAML_FOOTER.url]);
+	}
+
+	if (footer[YAML_FOOTER.license] !== undefined) {
+		result.LICENSE = safeString(footer[YAML_FOOTER.license]);
+	}
+
+	return result;
+}
+
+/**
+ * Parses the DIFF section from YAML configuration.
+ *
+ * @param yamlProps - Parsed YAML properties
+ * @returns DIFF section configuration
+ */
+export function parseDiffSection(yamlProps: Record<string, unknown>): YamlDiffConfig {
+	const diff = getSection(yamlProps, YAML_SECTIONS.diff);
+	const result: YamlDiffConfig = {};
+
+	if (diff[YAML_DIFF.added] !== undefined) {
+		result.ADDED = joinLineListValue(diff[YAML_DIFF.added]);
+	}
+
+	if (diff[YAML_DIFF.removed] !== undefined) {
+		result.REMOVED = joinLineListValue(diff[YAML_DIFF.removed]);
+	}
+
+	const copy = safeString(diff[YAML_DIFF.copy]);
+	if (copy) {
+		result.COPY = copy.trim().toLowerCase();
+	}
+
+	if (diff[YAML_DIFF.words] !== undefined) {
+		result.WORDS = resolveBoolean(diff[YAML_DIFF.words], true);
+	}
+
+	const view = safeString(diff[YAML_DIFF.view]);
+	if (view) {
+		result.VIEW = view.trim().toLowerCase();
+	}
+
+	const oldRegion = safeString(diff[YAML_DIFF.old]);
+	if (oldRegion) {
+		result.OLD = oldRegion.trim();
+	}
+
+	const newRegion = safeString(diff[YAML_DIFF.new]);
+	if (newRegion) {
+		result.NEW = newRegion.trim();
+	}
+
+	return result;
+}
+
+/**
+ * Parses the ANNOTATIONS section from YAML configuration.
+ *
+ * Each key is a line number or line list; its value is either the icon
+ * type ("warning") or a mapping with TYPE and TEXT.
+ *
+ * @param yamlProps - Parsed YAML properties
+ * @returns Annotation entries by line key
+ */
+export function parseAnnotationsSection(yamlProps: Record<string, unknown>): Record<string, YamlAnnotationEntry> {
+	const annotations = getSection(yamlProps, YAML_SECTIONS.annotations);
+	const result: Record<string, YamlAnnotationEntry> = {};
+
+	for (const [lineKey, value] of Object.entries(annotations)) {
+		if (value && typeof value === 'object' && !Array.isArray(value)) {
+			const entry = value as Record<string, unknown>;
+			result[lineKey] = {
+				TYPE: safeString(entry[YAML_ANNOTATION.type]),
+				TEXT: safeString(entry[YAML_ANNOTATION.text]),
+			};
+		} else if (value !== null && value !== undefined) {
+			result[lineKey] = { TYPE: safeString(value) };
+		}
+	}
+
+	return result;
+}
+
+/**
+ * Parses the TOKENS section from YAML configuration.
+ *
+ * Each key is a token name with a style ("keyword: bold purple"), or a
+ * language with a mapping of token styles for it. Keys are read in
+ * lower case, like Prism's token names and language IDs.
+ *
+ * @param yamlProps - Parsed YAML properties
+ * @returns Token styles by token name or language
+ */
+export function parseTokensSection(yamlProps: Record<string, unknown>): YamlTokensConfig {
+	const tokens = getSection(yamlProps, YAML_SECTIONS.tokens);
+	const result: YamlTokensConfig = {};
+
+	for (const [key, value] of Object.entries(tokens)) {
+		if (value && typeof value === 'object' && !Array.isArray(value)) {
+			const languageTokens: Record<string, string> = {};
+			for (const [token, style] of Object.entries(value as Record<string, unknown>)) {
+				const styleText = safeString(style);
+				if (styleText !== undefined) {
+					languageTokens[token.toLowerCase()] = styleText;
+				}
+			}
+			result[key.toLowerCase()] = languageTokens;
+		} else {
+			const styleText = safeString(value);
+			if (styleText !== undefined) {
+				result[key.toLowerCase()] = styleText;
+			}
+		}
+	}
+
+	return result;
+}
+
+/**
+ * Parses the PATTERNS section from YAML configuration.
+ *
+ * Each key is a regex and its value a style ("PROJ-\d+: bold purple").
+ * The regexes are kept as written, since a regex is case-sensitive.
+ *
+ * @param yamlProps - Parsed YAML properties
+ * @returns Styles by regex
+ */
+export function parsePatternsSection(yamlProps: Record<string, unknown>): YamlPatternsConfig {
+	const patterns = getSection(yamlProps, YAML_SECTIONS.patterns);
+	const result: YamlPatternsConfig = {};
+
+	for (const [pattern, value] of Object.entries(patterns)) {
+		const styleText = safeString(value);
+		if (styleText !== undefined) {
+			result[pattern] = styleText;
+		}
+	}
+
+	return result;
+}
+
+/**
+ * Parses the BLAME section from YAML configuration.
+ *
+ * Each key is a line number or line list; its value is either the
+ * author ("alice") or a mapping with AUTHOR, DATE and TICKET.
+ *
+ * @param yamlProps - Parsed YAML properties
+ * @returns Blame entries by line key
+ */
+export function parseBlameSection(yamlProps: Record<string, unknown>): Record<string, YamlBlameEntry> {
+	const blame = getSection(yamlProps, YAML_SECTIONS.blame);
+	const result: Record<string, YamlBlameEntry> = {};
+
+	for (const [lineKey, value] of Object.entries(blame)) {
+		if (value && typeof value === 'object' && !Array.isArray(value)) {
+			const entry = value as Record<string, unknown>;
+			const date = entry[YAML_BLAME.date];
+			result[lineKey] = {
+				AUTHOR: safeString(entry[YAML_BLAME.author]),
+				// Unquoted YAML dates can arrive as Date objects
+				DATE: date instanceof Date ? date.toISOString().slice(0, 10) : safeString(date),
+				TICKET: safeString(entry[YAML_BLAME.ticket]),
+			};
+		} else if (value !== null && value !== undefined) {
+			result[lineKey] = { AUTHOR: safeString(value) };
+		}
+	}
+
+	return result;
+}
+
 /**
  * Parses a text style subsection (COLOUR, BOLD, ITALIC).
  *
@@ -527,45 +1379,194 @@ export function parseRenderCmdoutSection(yamlProps: Record<string, unknown>): Ya
 /**
  * Parses complete nested YAML configuration from a ufence block.
  *
- * @param yamlProps - Parsed YAML properties from parseBlockContent
+ * Settings under renamed keys are read from their old names too.
+ *
+ * @param rawYamlProps - Parsed YAML properties from parseBlockContent
  * @returns Complete parsed YAML configuration
  */
-export function parseNestedYamlConfig(yamlProps: Record<string, unknown>): ParsedYamlConfig {
+export function parseNestedYamlConfig(rawYamlProps: Record<string, unknown>): ParsedYamlConfig {
+	const yamlProps = applyConfigRenames(rawYamlProps);
+
 	return {
 		META: parseMetaSection(yamlProps),
 		RENDER: parseRenderDisplaySection(yamlProps),
 		FILTER: parseFilterSection(yamlProps),
 		CALLOUT: parseCalloutSection(yamlProps),
-		// Top-level PROMPT for cmdout blocks
+		COPY: parseCopySection(yamlProps),
+		HIGHLIGHT: parseHighlightSection(yamlProps),
+		DOWNLOAD: parseDownloadSection(yamlProps),
+		HEADER: parseHeaderSection(yamlProps),
+		FOOTER: parseFooterSection(yamlProps),
+		ANNOTATIONS: parseAnnotationsSection(yamlProps),
+		BLAME: parseBlameSection(yamlProps),
+		TOKENS: parseTokensSection(yamlProps),
+		PATTERNS: parsePatternsSection(yamlProps),
+		DIFF: parseDiffSection(yamlProps),
+		PRINT: parsePrintSection(yamlProps),
+		RUN: parseRunSection(yamlProps),
+		// Top-level PROMPT (cmdout command detection; prompt stripping for code blocks)
 		PROMPT: safeString(yamlProps[YAML_PROMPT]),
 		// RENDER section for cmdout styling (stored separately as RENDER_CMDOUT)
 		RENDER_CMDOUT: parseRenderCmdoutSection(yamlProps),
 	};
 }
 
+/**
+ * Parses a preset's raw YAML into a mapping.
+ *
+ * @param yamlString - Raw YAML string from a preset
+ * @returns Parsed mapping, or undefined if empty or unparseable
+ */
+function parsePresetProps(yamlString: string): Record<string, unknown> | undefined {
+	if (!yamlString.trim()) {
+		return undefined;
+	}
+
+	try {
+		const yamlProps: unknown = parseYamlSettings(yamlString);
+		return yamlProps && typeof yamlProps === 'object' && !Array.isArray(yamlProps)
+			? yamlProps as Record<string, unknown>
+			: undefined;
+	} catch {
+		return undefined;
+	}
+}
+
 /**
  * Parses a raw YAML string (from a saved preset) into a ParsedYamlConfig.
  *
  * This is used to convert the free-form YAML stored in plugin settings into
- * the same structured format used by code block configs.
+ * the same structured format used by code block configs. ${name}
+ * references are replaced with the given parameter values, falling back
+ * to the defaults declared under PARAMS.
  *
  * @param yamlString - Raw YAML string from a preset
+ * @param params - Parameter values given for the preset
  * @returns Parsed YAML configuration, or empty config on failure
  */
-export function parsePresetYaml(yamlString: string): ParsedYamlConfig {
-	if (!yamlString.trim()) {
+export function parsePresetYaml(yamlString: string, params: Record<string, PresetParamValue | undefined> = {}): ParsedYamlConfig {
+	const yamlProps = parsePresetProps(yamlString);
+	if (!yamlProps) {
 		return {};
 	}
 
-	try {
-		const yamlProps = parseYaml(yamlString) as Record<string, unknown>;
-		if (typeof yamlProps !== 'object') {
-			return {};
-		}
-		return parseNestedYamlConfig(yamlProps);
-	} catch {
-		return {};
+	const values = mergePresetParams(getPresetParamDefaults(yamlProps), params);
+	return parseNestedYamlConfig(substitutePresetParams(yamlProps, values) as Record<string, unknown>);
+}
+
+/**
+ * Parses the conditional settings of a preset, from its WHEN mapping.
+ *
+ * Each key names one or more conditions separated by spaces (e.g.
+ * "dark", "dark mobile"); its settings apply when all of them hold.
+ * Parameters are substituted as in {@link parsePresetYaml}.
+ *
+ * @param yamlString - Raw YAML string from a preset
+ * @param params - Parameter values given for the preset
+ * @returns Conditional settings in the order written
+ */
+export function parsePresetConditions(
+	yamlString: string,
+	params: Record<string, PresetParamValue | undefined> = {}
+): { conditions: string[]; config: ParsedYamlConfig }[] {
+	const yamlProps = parsePresetProps(yamlString);
+	const when: unknown = yamlProps?.[YAML_PRESET_WHEN];
+	if (!yamlProps || !when || typeof when !== 'object' || Array.isArray(when)) {
+		return [];
 	}
+
+	const values = mergePresetParams(getPresetParamDefaults(yamlProps), params);
+	const entries: { conditions: string[]; config: ParsedYamlConfig }[] = [];
+	for (const [key, settings] of Object.entries(when as Record<string, unknown>)) {
+		if (!settings || typeof settings !== 'object' || Array.isArray(settings)) continue;
+		entries.push({
+			conditions: key.toLowerCase().split(/\s+/).filter(condition => condition !== ''),
+			config: parseNestedYamlConfig(substitutePresetParams(settings, values) as Record<string, unknown>),
+		});
+	}
+	return entries;
+}
+
+/**
+ * Reads the parameters a preset declares under PARAMS, with their defaults.
+ *
+ * @param yamlString - Raw YAML string from a preset
+ * @returns Default values by parameter name (undefined when none is set)
+ */
+export function parsePresetParamDefaults(yamlString: string): Record<string, PresetParamValue | undefined> {
+	const yamlProps = parsePresetProps(yamlString);
+	return yamlProps ? getPresetParamDefaults(yamlProps) : {};
+}
+
+/**
+ * Reads the names of the presets a preset extends, from its EXTENDS key
+ * (a single name or a list of names).
+ *
+ * @param yamlString - Raw YAML string from a preset
+ * @returns Preset names in the order given, or empty if none/unparseable
+ */
+export function parsePresetExtends(yamlString: string): string[] {
+	const yamlProps = parsePresetProps(yamlString);
+	if (!yamlProps) {
+		return [];
+	}
+
+	const extendsValue: unknown = yamlProps[YAML_PRESET_EXTENDS];
+	const names: unknown[] = Array.isArray(extendsValue) ? extendsValue : [extendsValue];
+	return names
+		.filter((name): name is string | number => typeof name === 'string' || typeof name === 'number')
+		.map(name => String(name).trim())
+		.filter(name => name !== '');
+}
+
+/**
+ * Reads the note tags a preset applies to, from its APPLIES_TO_TAGS key
+ * (a single tag or a list of tags).
+ *
+ * Tags are returned without the leading # and in lower case, as Obsidian
+ * matches tags regardless of case.
+ *
+ * @param yamlString - Raw YAML string from a preset
+ * @returns Tags in the order given, or empty if none/unparseable
+ */
+export function parsePresetAppliesToTags(yamlString: string): string[] {
+	const yamlProps = parsePresetProps(yamlString);
+	if (!yamlProps) {
+		return [];
+	}
+
+	const tagsValue: unknown = yamlProps[YAML_PRESET_APPLIES_TO_TAGS];
+	const tags: unknown[] = Array.isArray(tagsValue) ? tagsValue : [tagsValue];
+	return tags
+		.filter((tag): tag is string | number => typeof tag === 'string' || typeof tag === 'number')
+		.map(tag => String(tag).trim().replace(/^#/, '').toLowerCase())
+		.filter(tag => tag !== '');
+}
+
+/**
+ * Parses the `ufence:` mapping in a note's frontmatter into block defaults.
+ *
+ * Uses the same sections as block settings, and (as in a ufence-ufence
+ * block) accepts a top-level PRESET as shorthand for META.PRESET.
+ *
+ * @param frontmatter - The note's parsed frontmatter
+ * @returns Parsed configuration, or undefined if the note has none
+ */
+export function parseFrontmatterConfig(frontmatter: unknown): ParsedYamlConfig | undefined {
+	if (!frontmatter || typeof frontmatter !== 'object') return undefined;
+
+	const raw: unknown = (frontmatter as Record<string, unknown>)[FRONTMATTER_CONFIG_KEY];
+	if (!raw || typeof raw !== 'object' || Array.isArray(raw)) return undefined;
+
+	// Copy, since the frontmatter object belongs to the metadata cache
+	const record = expandYamlMergeKeys(raw) as Record<string, unknown>;
+	if (record[YAML_META.preset] !== undefined) {
+		const meta = getSection(record, YAML_SECTIONS.meta);
+		record[YAML_SECTIONS.meta] = { ...meta, [YAML_META.preset]: record[YAML_META.preset] };
+		delete record[YAML_META.preset];
+	}
+
+	return parseNestedYamlConfig(record);
 }
 
 /**
@@ -574,35 +1575,84 @@ export function parsePresetYaml(yamlString: string): ParsedYamlConfig {
  * @param parsed - Parsed YAML configuration
  * @param settings - Plugin settings (for defaults)
  * @param defaultLanguage - Default language from processor registration
+ * @param contributedThemes - Highlight themes from other plugins, by key
  * @returns Fully resolved configuration ready for rendering
  */
 export function resolveBlockConfig(
 	parsed: ParsedYamlConfig,
 	settings: PluginSettings,
-	defaultLanguage: string
+	defaultLanguage: string,
+	contributedThemes: Record<string, HighlightTheme | undefined> = {}
 ): ResolvedBlockConfig {
+	// META.SRC names a vault file, URL or git file, with a line range that FILTER.BY_LINES overrides
+	const sourceReference = parsed.META?.SRC ? parseSourceReference(parsed.META.SRC) : null;
+	const sourceRange = parsed.FILTER?.BY_LINES?.RANGE ? null : sourceReference?.range ?? null;
+
 	// Parse line range if specified
 	const lineRange = parsed.FILTER?.BY_LINES?.RANGE
 		? parseLineRange(parsed.FILTER.BY_LINES.RANGE)
-		: null;
+		: sourceRange;
 
 	// Determine if BY_MARKS is enabled (both start and end required)
 	const byMarksEnabled = !!(parsed.FILTER?.BY_MARKS?.START) && !!(parsed.FILTER.BY_MARKS.END);
 
+	const language = parsed.RENDER?.LANG ?? defaultLanguage;
+	// Themes imported in the settings win over those from other plugins
+	const themeKey = parsed.RENDER?.THEME ? highlightThemeKey(parsed.RENDER.THEME) : '';
+	const highlightTheme = themeKey
+		? settings.highlightThemes[themeKey] ?? contributedThemes[themeKey]
+		: undefined;
+
 	return {
 		// META section
-		sourcePath: parsed.META?.PATH ?? null,
+		sourcePath: parsed.META?.PATH ?? sourceReference?.path ?? null,
+		sourceQuery: parsed.META?.QUERY?.trim() ?? '',
+		sourceRefreshInterval: parseRefreshInterval(parsed.META?.REFRESH),
 		titleTemplate: parsed.META?.TITLE ?? '',
 		descriptionText: parsed.META?.DESC ?? '',
+		configMode: resolveConfigMode(parsed.META?.MODE, settings),
 
 		// RENDER section
 		titleBarStyle: (parsed.RENDER?.STYLE ?? settings.defaultTitleBarStyle) as TitleBarStyle,
 		language: parsed.RENDER?.LANG ?? defaultLanguage,
+		languageDetected: false,
 		foldLines: parsed.RENDER?.FOLD ?? settings.foldLines,
 		scrollLines: parsed.RENDER?.SCROLL ?? settings.scrollLines,
+		maxHeight: parseMaxHeight(parsed.RENDER?.MAX_HEIGHT),
 		showZebraStripes: parsed.RENDER?.ZEBRA ?? settings.showZebraStripes,
 		showLineNumbers: parsed.RENDER?.LINES ?? settings.showLineNumbers,
 		showCopyButton: parsed.RENDER?.COPY ?? settings.showCopyButton,
+		showLineCopyButtons: parsed.RENDER?.LINE_COPY ?? settings.showLineCopyButtons,
+		// A META.SRC range numbers its lines as in the file
+		startingLineNumber: parsed.RENDER?.LINE_START ?? sourceRange?.[0] ?? 1,
+		anchorLine: parsed.RENDER?.LINE_ANCHOR ?? 0,
+		foldRegionsMode: parsed.RENDER?.FOLD_REGIONS ?? 'expanded',
+		foldStartPattern: parsed.RENDER?.FOLD_START ? (createSafeRegex(parsed.RENDER.FOLD_START) ?? undefined) : undefined,
+		foldEndPattern: parsed.RENDER?.FOLD_END ? (createSafeRegex(parsed.RENDER.FOLD_END) ?? undefined) : undefined,
+		startCollapsed: parsed.RENDER?.COLLAPSED ?? false,
+		summaryText: parsed.META?.SUMMARY ?? '',
+		blockId: parsed.META?.ID ?? '',
+		showIndentGuides: parsed.RENDER?.INDENT_GUIDES ?? false,
+		indentGuideColour: parsed.RENDER?.INDENT_GUIDE_COLOUR ?? '',
+		indentGuideStyle: parsed.RENDER?.INDENT_GUIDE_STYLE ?? 'solid',
+		whitespaceMode: parsed.RENDER?.WHITESPACE ?? 'none',
+		commentKeywordMode: parsed.RENDER?.COMMENT_KEYWORDS ?? settings.commentKeywordMode,
+		commentKeywords: settings.commentKeywords,
+		bracketPairColours: parsed.RENDER?.BRACKETS ?? settings.bracketPairColours,
+		semanticHighlighting: parsed.RENDER?.SEMANTIC ?? settings.semanticHighlighting,
+		renderVariables: parsed.RENDER?.VARIABLES ?? settings.renderVariables,
+		changedSince: parsed.RENDER?.CHANGED_SINCE ?? settings.changedSince,
+		minimapLines: parsed.RENDER?.MINIMAP ?? settings.minimapLines,
+		zebraColour: parsed.RENDER?.ZEBRA_COLOUR ?? '',
+		showLineHover: parsed.RENDER?.HOVER ?? settings.showLineHover,
+		fontLigatures: parsed.RENDER?.LIGATURES,
+		fontFeatures: parsed.RENDER?.FONT_FEATURES ?? '',
+		fontFamily: parsed.RENDER?.FONT_FAMILY ?? '',
+		fontSize: parseFontSize(parsed.RENDER?.FONT_SIZE),
+		tabSize: parsed.RENDER?.TAB_SIZE ?? getDefaultTabSize(settings, parsed.RENDER?.LANG ?? defaultLanguage),
+		rulerColumns: parseRulerColumns(parsed.RENDER?.RULER),
+		rulerColour: parsed.RENDER?.RULER_COLOUR ?? '',
+		textDirection: parsed.RENDER?.DIRECTION ?? '',
 		shiftCopyJoin: parsed.RENDER?.SHIFT_COPY_JOIN
 			?? settings.languageCopyJoinDefaults[defaultLanguage]?.shiftJoin
 			?? '',
@@ -613,6 +1663,9 @@ export function resolveBlockConfig(
 			?? settings.languageCopyJoinDefaults[defaultLanguage]?.joinIgnoreRegex
 			?? '',
 
+		// Top-level PROMPT (styled on screen, stripped on copy)
+		promptPattern: parsed.PROMPT ? (createSafeRegex(parsed.PROMPT) ?? undefined) : undefined,
+
 		// FILTER section - BY_LINES
 		filterByLines: {
 			enabled: lineRange !== null,
@@ -629,8 +1682,21 @@ export function resolveBlockConfig(
 			inclusive: parsed.FILTER?.BY_MARKS?.INCLUSIVE ?? true,
 		},
 
-		// Print behaviour
-		printBehaviour: parsed.RENDER?.PRINT ?? settings.printBehaviour,
+		// FILTER section - BY_REGION
+		filterByRegion: parsed.FILTER?.BY_REGION ?? sourceReference?.region ?? '',
+
+		// PRINT section (RENDER.PRINT is the older way to set EXPAND)
+		printBehaviour: resolvePrintBehaviour(parsed, settings),
+		printTheme: parsed.PRINT?.THEME ?? settings.printTheme,
+		printLineNumbers: parsed.PRINT?.LINES ?? settings.printLineNumbers,
+		printAvoidBreaks: parsed.PRINT?.AVOID_BREAKS ?? settings.printAvoidBreaks,
+
+		// RUN section
+		runEnabled: parsed.RUN?.ENABLED ?? true,
+		runSaveOutput: parsed.RUN?.SAVE ?? false,
+		runLines: parsed.RUN?.LINES ?? false,
+		runTimeoutMs: parseRunTimeout(parsed.RUN?.TIMEOUT, settings.runTimeoutSeconds * 1000),
+		runEnv: parsed.RUN?.ENV ?? {},
 
 		// CALLOUT section — placeholder; resolved in main.ts with source code
 		calloutConfig: {
@@ -640,9 +1706,262 @@ export function resolveBlockConfig(
 			style: 'standard',
 			entries: [],
 		},
+
+		// COPY section
+		copyAsEntries: resolveCopyAsEntries(parsed.COPY?.AS),
+		copyPlaceholders: parsed.COPY?.PLACEHOLDERS ?? settings.copyPlaceholders,
+		copyVariables: parsed.COPY?.VARIABLES ?? false,
+		stripComments: parsed.COPY?.STRIP_COMMENTS ?? false,
+		redactPatterns: resolveRedactPatterns(parsed.COPY?.REDACT),
+		copyFeedback: resolveCopyFeedback(parsed.COPY, settings),
+
+		// HIGHLIGHT section
+		highlightLines: parsed.HIGHLIGHT?.LINES ? parseLineList(parsed.HIGHLIGHT.LINES) : [],
+		highlightPattern: parsed.HIGHLIGHT?.MATCH ? (createSafeRegex(parsed.HIGHLIGHT.MATCH) ?? undefined) : undefined,
+		highlightTerms: parsed.HIGHLIGHT?.TERMS ?? [],
+		focusLines: parsed.HIGHLIGHT?.FOCUS ? parseLineList(parsed.HIGHLIGHT.FOCUS) : [],
+		showFocusToggle: parsed.HIGHLIGHT?.FOCUS_TOGGLE ?? false,
+
+		// DOWNLOAD section
+		downloadFilenameTemplate: parsed.DOWNLOAD?.FILENAME ?? settings.downloadFilenameTemplate,
+		downloadShebang: resolveShebang(parsed.DOWNLOAD?.SHEBANG, settings.downloadAddShebang, parsed.RENDER?.LANG ?? defaultLanguage),
+		downloadExecutable: parsed.DOWNLOAD?.EXECUTABLE ?? settings.downloadExecutable,
+
+		// HEADER section
+		headerIcon: parsed.HEADER?.ICON,
+		showHeaderTitle: parsed.HEADER?.TITLE ?? true,
+		showLanguageBadge: parsed.HEADER?.LANGUAGE ?? false,
+		buttonsInHeader: parsed.HEADER?.BUTTONS ?? false,
+
+		// FOOTER section
+		footerSource: parsed.FOOTER?.SOURCE ?? '',
+		footerUrl: parsed.FOOTER?.URL ?? '',
+		footerLicense: parsed.FOOTER?.LICENSE ?? '',
+
+		// ANNOTATIONS section
+		annotations: resolveAnnotations(parsed.ANNOTATIONS),
+
+		// BLAME section
+		blame: resolveBlame(parsed.BLAME),
+
+		// TOKENS section
+		tokenStyles: [
+			...resolveTokenStyles(highlightTheme?.tokens, language),
+			...resolveTokenStyles(parsed.TOKENS, language),
+		],
+		highlightTheme,
+
+		// PATTERNS section
+		patternStyles: resolvePatternStyles(parsed.PATTERNS),
+
+		// DIFF section
+		unifiedDiff: DIFF_LANGUAGES.includes((parsed.RENDER?.LANG ?? defaultLanguage).toLowerCase()),
+		diffAddedLines: parsed.DIFF?.ADDED ? parseLineList(parsed.DIFF.ADDED) : [],
+		diffRemovedLines: parsed.DIFF?.REMOVED ? parseLineList(parsed.DIFF.REMOVED) : [],
+		copyDiffAfter: parsed.DIFF?.COPY === 'after',
+		diffWordHighlight: parsed.DIFF?.WORDS ?? true,
+		splitDiff: parsed.DIFF?.VIEW === 'split' || Boolean(parsed.DIFF?.OLD && parsed.DIFF.NEW),
+		diffOldRegion: parsed.DIFF?.OLD && parsed.DIFF.NEW ? parsed.DIFF.OLD : '',
+		diffNewRegion: parsed.DIFF?.OLD && parsed.DIFF.NEW ? parsed.DIFF.NEW : '',
+	};
+}
+
+/**
+ * Resolves ANNOTATIONS entries into one annotation per line.
+ *
+ * Keys are read as line lists, so "3-5: warning" marks three lines.
+ * When two keys cover the same line, the later one wins.
+ *
+ * @param annotations - Parsed ANNOTATIONS section
+ * @returns Annotations sorted by line
+ */
+export function resolveAnnotations(annotations: Record<string, YamlAnnotationEntry> | undefined): LineAnnotation[] {
+	const byLine = new Map<number, LineAnnotation>();
+
+	for (const [lineKey, entry] of Object.entries(annotations ?? {})) {
+		const rawType = entry.TYPE?.trim() ?? '';
+		const type = normalizeCalloutType(rawType || 'note');
+		const text = entry.TEXT?.trim() ?? '';
+
+		for (const line of parseLineList(lineKey)) {
+			byLine.set(line, { line, type, text });
+		}
+	}
+
+	return Array.from(byLine.values()).sort((a, b) => a.line - b.line);
+}
+
+/**
+ * Resolves BLAME entries into metadata per line.
+ *
+ * Keys are read as line lists, so "3-5" covers three lines. When two
+ * keys cover the same line, the later one wins; entries with nothing
+ * to show are dropped.
+ *
+ * @param blame - Parsed BLAME section
+ * @returns Blame metadata sorted by line
+ */
+export function resolveBlame(blame: Record<string, YamlBlameEntry> | undefined): LineBlame[] {
+	const byLine = new Map<number, LineBlame>();
+
+	for (const [lineKey, entry] of Object.entries(blame ?? {})) {
+		const author = entry.AUTHOR?.trim() ?? '';
+		const date = entry.DATE?.trim() ?? '';
+		const ticket = entry.TICKET?.trim() ?? '';
+		if (!author && !date && !ticket) continue;
+
+		for (const line of parseLineList(lineKey)) {
+			byLine.set(line, { line, author, date, ticket });
+		}
+	}
+
+	return Array.from(byLine.values()).sort((a, b) => a.line - b.line);
+}
+
+/**
+ * Resolves META.MODE, falling back to the settings mode when it is unset
+ * or not a known mode.
+ *
+ * @param modeValue - Parsed MODE value
+ * @param settings - Plugin settings
+ * @returns Configuration mode for the block
+ */
+export function resolveConfigMode(modeValue: string | undefined, settings: PluginSettings): ConfigMode {
+	const mode = modeValue?.trim().toLowerCase();
+	return CONFIG_MODES.find(known => known === mode) ?? settings.configMode;
+}
+
+/**
+ * Resolves whether a block prints in full or as displayed.
+ *
+ * PRINT.EXPAND wins over RENDER.PRINT, which wins over the settings.
+ *
+ * @param parsed - Parsed YAML configuration
+ * @param settings - Plugin settings
+ * @returns 'expand' or 'asis'
+ */
+function resolvePrintBehaviour(parsed: ParsedYamlConfig, settings: PluginSettings): string {
+	if (parsed.PRINT?.EXPAND !== undefined) {
+		return parsed.PRINT.EXPAND ? 'expand' : 'asis';
+	}
+	return parsed.RENDER?.PRINT ?? settings.printBehaviour;
+}
+
+/**
+ * Resolves DOWNLOAD.SHEBANG into the line to prepend.
+ *
+ * @param shebangValue - Parsed SHEBANG value ("true", "false", or a custom line)
+ * @param addByDefault - Settings default when SHEBANG is not set
+ * @param language - Block language (for the default interpreter)
+ * @returns Shebang line, or empty string for none
+ */
+function resolveShebang(shebangValue: string | undefined, addByDefault: boolean, language: string): string {
+	const trimmed = shebangValue?.trim() ?? '';
+
+	if (shebangValue === undefined) {
+		return addByDefault ? getDefaultShebang(language) : '';
+	}
+	if (trimmed.toLowerCase() === 'true') return getDefaultShebang(language);
+	if (trimmed === '' || trimmed.toLowerCase() === 'false') return '';
+
+	return trimmed.startsWith('#!') ? trimmed : `#!${trimmed}`;
+}
+
+/**
+ * Resolves how copies are confirmed, from COPY feedback keys and settings.
+ *
+ * FEEDBACK: false turns every confirmation off (e.g. for screencasts).
+ * TOAST: true uses the settings toast text, or the default message if
+ * that is empty. Durations longer than a timer can wait
+ * (MAX_TIMER_DELAY_MS) are lowered to it.
+ *
+ * @param copy - Parsed COPY section (undefined = settings only)
+ * @param settings - Plugin settings
+ * @returns Copy feedback configuration
+ */
+export function resolveCopyFeedback(copy: YamlCopyConfig | undefined, settings: PluginSettings): CopyFeedbackConfig {
+	const durationMs = Math.min(copy?.FEEDBACK_DURATION !== undefined && copy.FEEDBACK_DURATION > 0
+		? copy.FEEDBACK_DURATION
+		: settings.copyFeedbackDuration, MAX_TIMER_DELAY_MS);
+
+	if (copy?.FEEDBACK === false) {
+		return { checkmark: false, toastText: '', statusBar: false, durationMs };
+	}
+
+	const toast = copy?.TOAST?.trim();
+	let toastText = settings.copyFeedbackToast;
+	if (toast !== undefined) {
+		if (toast.toLowerCase() === 'true') {
+			toastText = settings.copyFeedbackToast || DEFAULT_COPY_MESSAGE;
+		} else if (toast.toLowerCase() === 'false') {
+			toastText = '';
+		} else {
+			toastText = toast;
+		}
+	}
+
+	return {
+		checkmark: settings.copyFeedbackCheckmark,
+		toastText,
+		statusBar: settings.copyFeedbackStatusBar,
+		durationMs,
 	};
 }
 
+/**
+ * Resolves COPY.REDACT values into global regexes.
+ *
+ * Built-in names (tokens, passwords, secrets) expand to their pattern
+ * groups; anything else is compiled as a regex. Invalid regexes are
+ * dropped.
+ *
+ * @param values - Parsed COPY.REDACT values
+ * @returns Patterns to redact, in declaration order
+ */
+function resolveRedactPatterns(values: string[] | undefined): RegExp[] {
+	if (!values) return [];
+
+	const patterns: RegExp[] = [];
+
+	for (const value of values) {
+		const builtIn = BUILT_IN_REDACTIONS[value.trim().toLowerCase()];
+
+		if (builtIn) {
+			patterns.push(...builtIn);
+			continue;
+		}
+
+		const pattern = createSafeRegex(value);
+		if (pattern) {
+			patterns.push(new RegExp(pattern.source, 'g'));
+		}
+	}
+
+	return patterns;
+}
+
+/**
+ * Resolves COPY.AS entries, dropping any with a missing or unknown FORMAT.
+ *
+ * @param entries - Parsed COPY.AS entries
+ * @returns Resolved entries in declaration order
+ */
+function resolveCopyAsEntries(entries: YamlCopyAsEntry[] | undefined): ResolvedCopyAsEntry[] {
+	if (!entries) return [];
+
+	return entries
+		.filter(entry => entry.FORMAT !== undefined && isKnownCopyAsFormat(entry.FORMAT))
+		.map(entry => {
+			const format = (entry.FORMAT ?? '').toLowerCase();
+			return {
+				label: entry.LABEL ?? COPY_AS_DEFAULT_LABELS[format] ?? format,
+				format,
+				target: entry.TARGET ?? '',
+				schedule: entry.SCHEDULE ?? '',
+			};
+		});
+}
+
 /**
  * Resolves parsed YAML callout configuration with actual source code.
  *
@@ -779,6 +2098,7 @@ export function resolveCmdoutConfig(
 		// META section
 		titleText: parsed.META?.TITLE,
 		descriptionText: parsed.META?.DESC ?? '',
+		configMode: resolveConfigMode(parsed.META?.MODE, settings),
 
 		// RENDER section (display options)
 		scrollLines: parsed.RENDER?.SCROLL ?? settings.scrollLines,
@@ -788,7 +2108,16 @@ export function resolveCmdoutConfig(
 		promptPattern,
 		styles,
 
-		// Print behaviour
-		printBehaviour: parsed.RENDER?.PRINT ?? settings.printBehaviour,
+		// PRINT section (RENDER.PRINT is the older way to set EXPAND)
+		printBehaviour: resolvePrintBehaviour(parsed, settings),
+		printTheme: parsed.PRINT?.THEME ?? settings.printTheme,
+		printAvoidBreaks: parsed.PRINT?.AVOID_BREAKS ?? settings.printAvoidBreaks,
+
+		// ANSI colour codes in the output
+		renderAnsi: parsed.RENDER?.ANSI ?? true,
+		copyRawAnsi: parsed.RENDER?.ANSI_COPY === 'raw',
+
+		// COPY section (only feedback applies to cmdout copies)
+		copyFeedback: resolveCopyFeedback(parsed.COPY, settings),
 	};
 }
@@ -1,14 +1,18 @@
 /**
  * Ultra Code Fence - Button Renderers
  *
- * Creates copy and fold buttons for code blocks.
+ * Creates copy, per-line copy, region copy, copy-as, download, and fold buttons for code blocks.
  * Handles user interaction and state management.
  */
 
-import { Platform } from 'obsidian';
+import { Menu, Platform } from 'obsidian';
+import type { ResolvedCopyAsEntry, CommentSyntax, CopyFeedbackConfig, MaxHeightLimit } from '../types';
 import { CSS_CLASSES, COPY_SUCCESS_DURATION_MS } from '../constants';
-import { extractCodeText } from '../utils';
+import { extractCodeText, extractLineText, extractDiffAfterText, applyCopyAsTransform, cleanupCopyText, findRegions, buildRichTextHtml } from '../utils';
+import type { CopyCleanupOptions } from '../utils';
 import { setSvgContent } from '../utils/dom';
+import type { RunResult } from '../services';
+import { describeRunResult } from './run-output';
 
 // =============================================================================
 // SVG Icons
@@ -39,6 +43,135 @@ const CHEVRON_UP_SVG = `<svg xmlns="http://www.w3.org/2000/svg" width="14" heigh
  */
 const DOWNLOAD_ICON_SVG = `<svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2" stroke-linecap="round" stroke-linejoin="round"><path d="M21 15v4a2 2 0 0 1-2 2H5a2 2 0 0 1-2-2v-4"></path><polyline points="7 10 12 15 17 10"></polyline><line x1="12" y1="15" x2="12" y2="3"></line></svg>`;
 
+/**
+ * Wrap text icon SVG (soft wrapping toggle).
+ */
+const WRAP_ICON_SVG = `<svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2" stroke-linecap="round" stroke-linejoin="round"><line x1="3" y1="6" x2="21" y2="6"></line><path d="M3 12h15a3 3 0 1 1 0 6h-4"></path><polyline points="16 16 14 18 16 20"></polyline><line x1="3" y1="18" x2="10" y2="18"></line></svg>`;
+
+/**
+ * Eye icon SVG (focus toggle).
+ */
+const EDIT_ICON_SVG = `<svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2" stroke-linecap="round" stroke-linejoin="round"><path d="M12 20h9"></path><path d="M16.5 3.5a2.12 2.12 0 0 1 3 3L7 19l-4 1 1-4Z"></path></svg>`;
+const RUN_ICON_SVG = `<svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2" stroke-linecap="round" stroke-linejoin="round"><polygon points="6 3 20 12 6 21 6 3"></polygon></svg>`;
+const FOCUS_ICON_SVG = `<svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2" stroke-linecap="round" stroke-linejoin="round"><path d="M2 12s3.5-7 10-7 10 7 10 7-3.5 7-10 7-10-7-10-7z"></path><circle cx="12" cy="12" r="3"></circle></svg>`;
+
+/**
+ * Clipboard list icon SVG ("copy as…" menu).
+ */
+const COPY_AS_ICON_SVG = `<svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2" stroke-linecap="round" stroke-linejoin="round"><rect x="8" y="2" width="8" height="4" rx="1" ry="1"></rect><path d="M16 4h2a2 2 0 0 1 2 2v14a2 2 0 0 1-2 2H6a2 2 0 0 1-2-2V6a2 2 0 0 1 2-2h2"></path><path d="M12 11h4"></path><path d="M12 16h4"></path><path d="M8 11h.01"></path><path d="M8 16h.01"></path></svg>`;
+
+// =============================================================================
+// Copy Pipeline
+// =============================================================================
+
+/**
+ * Asks the user for {{PLACEHOLDER}} values and returns the substituted
+ * text, or null if they cancelled.
+ */
+export type PlaceholderFiller = (codeText: string) => Promise<string | null>;
+
+/**
+ * Everything applied to text on its way to the clipboard (or a download).
+ */
+export interface CopyPipelineOptions extends CopyCleanupOptions {
+	/** Prompts for placeholder values before copying (undefined = placeholders copied as-is) */
+	fillPlaceholders?: PlaceholderFiller;
+
+	/** Called with the final text after each successful copy (e.g. for clipboard history) */
+	onCopied?: (text: string) => void;
+
+	/** Button confirmation; only checkmark and durationMs apply here (undefined = checkmark for COPY_SUCCESS_DURATION_MS) */
+	feedback?: CopyFeedbackConfig;
+
+	/** Copy only the new version of a diff block (DIFF.COPY: after) */
+	copyDiffAfter?: boolean;
+
+	/** Copy command output with its ANSI codes (RENDER.ANSI_COPY: raw) */
+	copyRawAnsi?: boolean;
+
+	/** The whole code, for a long file of which only the first lines are shown */
+	fullText?: string;
+}
+
+/**
+ * Reads a block's code for copying or downloading.
+ *
+ * @param codeElement - Code element
+ * @param options - Copy pipeline options
+ * @returns The code (all of it when fullText is set), or a diff's new version when copyDiffAfter is set
+ */
+function extractCopyText(codeElement: HTMLElement, options: CopyPipelineOptions | undefined): string {
+	if (options?.copyDiffAfter) return extractDiffAfterText(codeElement);
+	return options?.fullText ?? extractCodeText(codeElement, options?.copyRawAnsi);
+}
+
+/**
+ * Hands text to a callback once its placeholders are filled in.
+ *
+ * Without a placeholder filler the callback runs synchronously; if the
+ * user cancels the prompt it is not called at all.
+ *
+ * @param codeText - Cleaned text bound for the clipboard
+ * @param options - Copy pipeline options
+ * @param onReady - Receives the final text
+ */
+function withFilledPlaceholders(
+	codeText: string,
+	options: CopyPipelineOptions | undefined,
+	onReady: (text: string) => void
+): void {
+	if (!options?.fillPlaceholders) {
+		onReady(codeText);
+		return;
+	}
+
+	void options.fillPlaceholders(codeText).then(filledText => {
+		if (filledText !== null) {
+			onReady(filledText);
+		}
+	});
+}
+
+/**
+ * Writes text to the clipboard, then reports it to the onCopied hook.
+ *
+ * @param text - Final text for the clipboard
+ * @param options - Copy pipeline options
+ * @returns Resolves once the text is on the clipboard
+ */
+function writeToClipboard(text: string, options: CopyPipelineOptions | undefined): Promise<void> {
+	return navigator.clipboard.writeText(text).then(() => {
+		options?.onCopied?.(text);
+	});
+}
+
+/**
+ * Writes HTML with a plain-text fallback to the clipboard, then reports
+ * the plain text to the onCopied hook.
+ *
+ * Falls back to plain text only where the clipboard cannot take rich
+ * content (no ClipboardItem support).
+ *
+ * @param html - HTML flavour
+ * @param text - Plain-text flavour
+ * @param options - Copy pipeline options
+ * @returns Resolves once the content is on the clipboard
+ */
+function writeRichTextToClipboard(html: string, text: string, options: CopyPipelineOptions | undefined): Promise<void> {
+	if (typeof ClipboardItem === 'undefined') {
+		return writeToClipboard(text, options);
+	}
+
+	const item = new ClipboardItem({
+		'text/html': new Blob([html], { type: 'text/html' }),
+		'text/plain': new Blob([text], { type: 'text/plain' }),
+	});
+
+	return navigator.clipboard.write([item]).then(() => {
+		options?.onCopied?.(text);
+	});
+}
+
 // =============================================================================
 // Copy Button
 // =============================================================================
@@ -46,7 +179,7 @@ const DOWNLOAD_ICON_SVG = `<svg xmlns="http://www.w3.org/2000/svg" width="16" he
 /**
  * Options for copy button behaviour.
  */
-export interface CopyButtonOptions {
+export interface CopyButtonOptions extends CopyPipelineOptions {
 	/** Join operator for Shift+click (e.g., "&&"). Empty = disabled. */
 	shiftCopyJoin?: string;
 
@@ -114,37 +247,31 @@ export function addCopyButton(preElement: HTMLPreElement, options?: CopyButtonOp
 		const codeElement = preElement.querySelector('code');
 
 		if (codeElement) {
-			let codeText = extractCodeText(codeElement);
-
-			// Build ignore regex once (used only for joined copies)
-			let ignoreRegex: RegExp | undefined;
-			if (options?.joinIgnoreRegex) {
-				try {
-					ignoreRegex = new RegExp(options.joinIgnoreRegex);
-				} catch {
-					// Invalid regex — skip line filtering
+			withFilledPlaceholders(cleanupCopyText(extractCopyText(codeElement, options), options), options, (filledText) => {
+				let codeText = filledText;
+
+				// Build ignore regex once (used only for joined copies)
+				let ignoreRegex: RegExp | undefined;
+				if (options?.joinIgnoreRegex) {
+					try {
+						ignoreRegex = new RegExp(options.joinIgnoreRegex);
+					} catch {
+						// Invalid regex — skip line filtering
+					}
+				}
+
+				// Shift+click: join lines with shift operator
+				if (event.shiftKey && options?.shiftCopyJoin) {
+					codeText = joinCodeLines(codeText, options.shiftCopyJoin, ignoreRegex);
 				}
-			}
-
-			// Shift+click: join lines with shift operator
-			if (event.shiftKey && options?.shiftCopyJoin) {
-				codeText = joinCodeLines(codeText, options.shiftCopyJoin, ignoreRegex);
-			}
-			// Alt/Cmd+click: join lines with alt operator
-			else if ((event.altKey || event.metaKey) && options?.altCopyJoin) {
-				codeText = joinCodeLines(codeText, options.altCopyJoin, ignoreRegex);
-			}
-
-			void navigator.clipboard.writeText(codeText).then(() => {
-				// Show success state
-				copyButton.classList.add(CSS_CLASSES.copied);
-				setSvgContent(copyButton, CHECKMARK_ICON_SVG);
-
-				// Reset after delay
-				setTimeout(() => {
-					copyButton.classList.remove(CSS_CLASSES.copied);
-					setSvgContent(copyButton, COPY_ICON_SVG);
-				}, COPY_SUCCESS_DURATION_MS);
+				// Alt/Cmd+click: join lines with alt operator
+				else if ((event.altKey || event.metaKey) && options?.altCopyJoin) {
+					codeText = joinCodeLines(codeText, options.altCopyJoin, ignoreRegex);
+				}
+
+				void writeToClipboard(codeText, options).then(() => {
+					showCopiedState(copyButton, options?.feedback);
+				});
 			});
 		}
 	});
@@ -152,17 +279,303 @@ export function addCopyButton(preElement: HTMLPreElement, options?: CopyButtonOp
 	preElement.appendChild(copyButton);
 }
 
+/**
+ * Switches a copy button to its success state, then resets it.
+ *
+ * Does nothing when the feedback config turns the checkmark off.
+ *
+ * @param button - The copy button that was clicked
+ * @param feedback - Copy feedback configuration
+ * @param swapIcon - Replace the copy icon with a checkmark (false for buttons with their own icon)
+ */
+function showCopiedState(button: HTMLElement, feedback?: CopyFeedbackConfig, swapIcon = true): void {
+	if (feedback && !feedback.checkmark) return;
+
+	button.classList.add(CSS_CLASSES.copied);
+	if (swapIcon) {
+		setSvgContent(button, CHECKMARK_ICON_SVG);
+	}
+
+	setTimeout(() => {
+		button.classList.remove(CSS_CLASSES.copied);
+		if (swapIcon) {
+			setSvgContent(button, COPY_ICON_SVG);
+		}
+	}, feedback?.durationMs ?? COPY_SUCCESS_DURATION_MS);
+}
+
+// =============================================================================
+// Per-Line Copy Buttons
+// =============================================================================
+
+/**
+ * Adds a small copy button to the gutter of every wrapped line.
+ *
+ * Requires the code to have been wrapped into ucf-line spans first
+ * (see processCodeBlock's forceLineWrapping option). Each button copies
+ * only its own line, which suits line-oriented blocks like shell
+ * sessions or config snippets.
+ *
+ * @param preElement - The pre element containing wrapped lines
+ * @param cleanup - Copy pipeline (prompt stripping, placeholders) applied to each line
+ */
+export function addLineCopyButtons(preElement: HTMLPreElement, cleanup?: CopyPipelineOptions): void {
+	const lineElements = Array.from(preElement.querySelectorAll<HTMLElement>(`code > .${CSS_CLASSES.line}`));
+
+	for (const lineElement of lineElements) {
+		const lineText = cleanupCopyText(extractLineText(lineElement), cleanup);
+
+		// Nothing worth copying on blank lines — keep the gutter aligned instead
+		if (lineText.trim() === '') {
+			const spacer = document.createElement('span');
+			spacer.className = CSS_CLASSES.lineCopySpacer;
+			lineElement.insertBefore(spacer, lineElement.firstChild);
+			continue;
+		}
+
+		const lineButton = document.createElement('button');
+		lineButton.className = CSS_CLASSES.lineCopyButton;
+		lineButton.setAttribute('aria-label', 'Copy line');
+		setSvgContent(lineButton, COPY_ICON_SVG);
+
+		lineButton.addEventListener('click', (event) => {
+			event.preventDefault();
+			event.stopPropagation();
+
+			withFilledPlaceholders(lineText, cleanup, (filledText) => {
+				void writeToClipboard(filledText, cleanup).then(() => {
+					showCopiedState(lineButton, cleanup?.feedback);
+				});
+			});
+		});
+
+		lineElement.insertBefore(lineButton, lineElement.firstChild);
+	}
+}
+
+// =============================================================================
+// Region Copy Buttons
+// =============================================================================
+
+/**
+ * Adds one copy button per named region to the block's header.
+ *
+ * Regions are marked in the code with `# region: name` … `# endregion`
+ * (any common comment marker works). Each button copies only its
+ * region, without the marker lines. The buttons sit in the title bar
+ * when the block has one, otherwise in a bar directly above the code.
+ *
+ * @param preElement - The pre element containing the code
+ * @param cleanup - Copy pipeline (prompt stripping, placeholders) applied to each region
+ */
+export function addRegionCopyButtons(preElement: HTMLPreElement, cleanup?: CopyPipelineOptions): void {
+	const codeElement = preElement.querySelector('code');
+	if (!codeElement) return;
+
+	const regions = findRegions(extractCodeText(codeElement));
+	if (regions.length === 0) return;
+
+	const regionBar = document.createElement('div');
+	regionBar.className = CSS_CLASSES.regionBar;
+
+	for (const region of regions) {
+		const regionButton = document.createElement('button');
+		regionButton.className = CSS_CLASSES.regionButton;
+		regionButton.setAttribute('aria-label', `Copy region ${region.name}`);
+
+		const iconSpan = document.createElement('span');
+		setSvgContent(iconSpan, COPY_ICON_SVG);
+		regionButton.appendChild(iconSpan);
+		regionButton.appendChild(document.createTextNode(region.name));
+
+		regionButton.addEventListener('click', (event) => {
+			event.preventDefault();
+			event.stopPropagation();
+
+			withFilledPlaceholders(cleanupCopyText(region.text, cleanup), cleanup, (filledText) => {
+				void writeToClipboard(filledText, cleanup).then(() => {
+					showCopiedState(regionButton, cleanup?.feedback, false);
+				});
+			});
+		});
+
+		regionBar.appendChild(regionButton);
+	}
+
+	const titleElement = preElement.parentElement?.querySelector(`:scope > .${CSS_CLASSES.title}`);
+
+	if (titleElement) {
+		titleElement.appendChild(regionBar);
+	} else {
+		preElement.parentElement?.insertBefore(regionBar, preElement);
+	}
+}
+
+// =============================================================================
+// Copy As Menu
+// =============================================================================
+
+/**
+ * Finds the highlighted lines of a wrapped code block (HIGHLIGHT.LINES).
+ *
+ * @param preElement - The pre element containing wrapped lines
+ * @returns Highlighted line elements in document order
+ */
+function findHighlightedLines(preElement: HTMLPreElement): HTMLElement[] {
+	return Array.from(preElement.querySelectorAll<HTMLElement>(`code > .${CSS_CLASSES.lineHighlight}`));
+}
+
+/**
+ * Creates and attaches a "copy as…" menu button to a pre element.
+ *
+ * Clicking the button opens a menu with one item per transformation.
+ * Choosing an item copies the code through that transformation (e.g. as
+ * a Dockerfile RUN instruction) and flashes the button's success state.
+ *
+ * When the block has highlighted lines, the menu starts with a
+ * "Highlighted lines" item that copies only those lines. A diff block
+ * gets an item for whichever of the diff and its new version the copy
+ * button doesn't copy. The button is added if there is at least one
+ * transformation, highlighted line or diff.
+ *
+ * @param preElement - The pre element to attach the button to
+ * @param entries - Resolved COPY.AS transformations (menu order)
+ * @param cleanup - Copy pipeline (prompt stripping, placeholders) applied before the transform
+ */
+export function addCopyAsButton(
+	preElement: HTMLPreElement,
+	entries: ResolvedCopyAsEntry[],
+	cleanup?: CopyPipelineOptions
+): void {
+	const hasHighlightedLines = findHighlightedLines(preElement).length > 0;
+	const isDiff = preElement.classList.contains(CSS_CLASSES.diff);
+	if (entries.length === 0 && !hasHighlightedLines && !isDiff) return;
+
+	const copyAsButton = document.createElement('button');
+	copyAsButton.className = CSS_CLASSES.copyAsButton;
+	copyAsButton.setAttribute('aria-label', 'Copy as');
+	copyAsButton.setAttribute('title', 'Copy as…');
+	setSvgContent(copyAsButton, COPY_AS_ICON_SVG);
+
+	copyAsButton.addEventListener('click', (event) => {
+		event.preventDefault();
+		event.stopPropagation();
+
+		const menu = new Menu();
+
+		const copyText = (text: string): void => {
+			void writeToClipboard(text, cleanup).then(() => {
+				showCopiedState(copyAsButton, cleanup?.feedback, false);
+			});
+		};
+
+		// Rich text keeps the rendered colours, so it is built from the DOM;
+		// lines the copy pipeline would drop entirely (e.g. comments) are left out,
+		// and lines with placeholders come from the filled-in text
+		const copyRichText = (codeElement: HTMLElement, text: string): void => {
+			const keepLine = (lineText: string): boolean => lineText.trim() === '' || cleanupCopyText(lineText, cleanup) !== '';
+			const html = buildRichTextHtml(codeElement, keepLine, text);
+
+			void writeRichTextToClipboard(html, text, cleanup).then(() => {
+				showCopiedState(copyAsButton, cleanup?.feedback, false);
+			});
+		};
+
+		if (hasHighlightedLines) {
+			menu.addItem(item => item
+				.setTitle('Highlighted lines')
+				.setIcon('highlighter')
+				.onClick(() => {
+					const lineTexts = findHighlightedLines(preElement).map(line => extractLineText(line));
+					withFilledPlaceholders(cleanupCopyText(lineTexts.join('\n'), cleanup), cleanup, copyText);
+				}));
+		}
+
+		if (isDiff) {
+			const copyAfter = !cleanup?.copyDiffAfter;
+			menu.addItem(item => item
+				.setTitle(copyAfter ? 'After changes' : 'Diff')
+				.setIcon('git-compare')
+				.onClick(() => {
+					const codeElement = preElement.querySelector('code');
+					if (!codeElement) return;
+
+					const codeText = copyAfter ? extractDiffAfterText(codeElement) : extractCodeText(codeElement);
+					withFilledPlaceholders(cleanupCopyText(codeText, cleanup), cleanup, copyText);
+				}));
+		}
+
+		for (const entry of entries) {
+			menu.addItem(item => item
+				.setTitle(entry.label)
+				.setIcon('copy')
+				.onClick(() => {
+					const codeElement = preElement.querySelector('code');
+					if (!codeElement) return;
+
+					withFilledPlaceholders(cleanupCopyText(extractCopyText(codeElement, cleanup), cleanup), cleanup, (filledText) => {
+						if (entry.format === 'richtext') {
+							copyRichText(codeElement, filledText);
+							return;
+						}
+						copyText(applyCopyAsTransform(filledText, entry));
+					});
+				}));
+		}
+
+		menu.showAtMouseEvent(event);
+	});
+
+	preElement.appendChild(copyAsButton);
+}
+
+// =============================================================================
+// Copy Count Badge
+// =============================================================================
+
+/**
+ * Adds a badge showing how often the block has been copied.
+ *
+ * The badge stays empty (and hidden) until the first copy.
+ *
+ * @param preElement - The pre element to decorate
+ * @param initialCount - Copies recorded so far
+ * @returns Function that bumps the badge after another copy
+ */
+export function addCopyCountBadge(preElement: HTMLPreElement, initialCount: number): () => void {
+	let count = initialCount;
+
+	const badge = document.createElement('span');
+	badge.className = CSS_CLASSES.copyCount;
+
+	const update = (): void => {
+		badge.textContent = count > 0 ? String(count) : '';
+		badge.setAttribute('title', count === 1 ? 'Copied once' : `Copied ${String(count)} times`);
+	};
+
+	update();
+	preElement.appendChild(badge);
+
+	return () => {
+		count += 1;
+		update();
+	};
+}
+
 // =============================================================================
 // Fold Button
 // =============================================================================
 
+/** Space for the pre's padding below the last visible line when folded. */
+const FOLD_PADDING_PX = 16;
+
 /**
  * Creates the "show more" button content with a chevron icon.
  *
- * @param hiddenLineCount - Number of lines hidden by the fold
- * @returns DocumentFragment with SVG icon and line count label
+ * @param label - Button text, e.g. "Show more (12 more lines)"
+ * @returns DocumentFragment with SVG icon and label
  */
-function buildExpandButtonContent(hiddenLineCount: number): DocumentFragment {
+function buildExpandButtonContent(label: string): DocumentFragment {
 	const fragment = document.createDocumentFragment();
 
 	const parser = new DOMParser();
@@ -173,7 +586,7 @@ function buildExpandButtonContent(hiddenLineCount: number): DocumentFragment {
 	}
 
 	const span = document.createElement('span');
-	span.textContent = `Show more (${String(hiddenLineCount)} more lines)`;
+	span.textContent = label;
 	fragment.appendChild(span);
 
 	return fragment;
@@ -202,37 +615,17 @@ function buildCollapseButtonContent(): DocumentFragment {
 }
 
 /**
- * Creates and attaches a fold button to a pre element.
- *
- * The fold feature collapses long code blocks to a configurable number
- * of visible lines, with a button to expand/collapse.
+ * Limits a pre element to a height and adds a bar that toggles it.
  *
- * @param preElement - The pre element to attach folding to
- * @param totalLineCount - Total number of lines in the code
- * @param visibleLinesWhenFolded - Number of lines to show when folded
+ * @param preElement - The pre element to fold
+ * @param foldedHeight - Height when folded, in pixels
+ * @param expandLabel - Button text while folded
  */
-export function addFoldButton(
-	preElement: HTMLPreElement,
-	totalLineCount: number,
-	visibleLinesWhenFolded: number
-): void {
-	const codeElement = preElement.querySelector('code');
-
-	if (!codeElement) return;
-
-	// Calculate folded height based on line height
-	const computedStyle = getComputedStyle(codeElement);
-	const lineHeight = parseFloat(computedStyle.lineHeight) || 20;
-	const FOLD_PADDING_PX = 16;
-	const foldedHeight = (visibleLinesWhenFolded * lineHeight) + FOLD_PADDING_PX;
-
+function attachFoldBar(preElement: HTMLPreElement, foldedHeight: number, expandLabel: string): void {
 	// Start in folded state
 	preElement.classList.add(CSS_CLASSES.folded);
 	preElement.style.setProperty('--ucf-folded-height', `${String(foldedHeight)}px`);
 
-	// Calculate hidden lines
-	const hiddenLineCount = totalLineCount - visibleLinesWhenFolded;
-
 	// Create fold bar container
 	const foldBar = document.createElement('div');
 	foldBar.className = CSS_CLASSES.foldBar;
@@ -240,7 +633,7 @@ export function addFoldButton(
 	// Create fold button
 	const foldButton = document.createElement('button');
 	foldButton.className = CSS_CLASSES.foldButton;
-	foldButton.appendChild(buildExpandButtonContent(hiddenLineCount));
+	foldButton.appendChild(buildExpandButtonContent(expandLabel));
 
 	foldButton.addEventListener('click', (event) => {
 		event.preventDefault();
@@ -254,7 +647,7 @@ export function addFoldButton(
 		}
 
 		if (isFolded) {
-			foldButton.appendChild(buildExpandButtonContent(hiddenLineCount));
+			foldButton.appendChild(buildExpandButtonContent(expandLabel));
 		} else {
 			foldButton.appendChild(buildCollapseButtonContent());
 		}
@@ -264,6 +657,117 @@ export function addFoldButton(
 	preElement.appendChild(foldBar);
 }
 
+/**
+ * Creates and attaches a fold button to a pre element.
+ *
+ * The fold feature collapses long code blocks to a configurable number
+ * of visible lines, with a button to expand/collapse.
+ *
+ * @param preElement - The pre element to attach folding to
+ * @param totalLineCount - Total number of lines in the code
+ * @param visibleLinesWhenFolded - Number of lines to show when folded
+ */
+export function addFoldButton(
+	preElement: HTMLPreElement,
+	totalLineCount: number,
+	visibleLinesWhenFolded: number
+): void {
+	const codeElement = preElement.querySelector('code');
+
+	if (!codeElement) return;
+
+	// Calculate folded height based on line height
+	const computedStyle = getComputedStyle(codeElement);
+	const lineHeight = parseFloat(computedStyle.lineHeight) || 20;
+	const foldedHeight = (visibleLinesWhenFolded * lineHeight) + FOLD_PADDING_PX;
+
+	// Calculate hidden lines
+	const hiddenLineCount = totalLineCount - visibleLinesWhenFolded;
+
+	attachFoldBar(preElement, foldedHeight, `Show more (${String(hiddenLineCount)} more lines)`);
+}
+
+/**
+ * Clamps a tall block to a height limit (RENDER.MAX_HEIGHT), fading out
+ * at the bottom, with a "Show all (N lines)" expander.
+ *
+ * Blocks that already fit are left alone. The fit is estimated from the
+ * line count, as the block has not been laid out yet.
+ *
+ * @param preElement - The pre element to clamp
+ * @param totalLineCount - Total number of lines in the code
+ * @param maxHeight - Height limit in lines or pixels
+ */
+export function addMaxHeightExpander(
+	preElement: HTMLPreElement,
+	totalLineCount: number,
+	maxHeight: MaxHeightLimit
+): void {
+	const codeElement = preElement.querySelector('code');
+
+	if (!codeElement) return;
+
+	const lineHeight = parseFloat(getComputedStyle(codeElement).lineHeight) || 20;
+	const limitHeight = maxHeight.unit === 'px'
+		? maxHeight.value
+		: (maxHeight.value * lineHeight) + FOLD_PADDING_PX;
+
+	if ((totalLineCount * lineHeight) + FOLD_PADDING_PX <= limitHeight) return;
+
+	attachFoldBar(preElement, limitHeight, `Show all (${String(totalLineCount)} lines)`);
+}
+
+// =============================================================================
+// Collapse Toggle
+// =============================================================================
+
+/**
+ * Collapses a whole block behind a disclosure bar (RENDER.COLLAPSED).
+ *
+ * The bar sits between the title and the code and shows the summary
+ * text, or the line count when there is none. While collapsed the code
+ * and any callout footnotes are hidden; clicking the bar toggles them.
+ *
+ * @param containerElement - The block container
+ * @param preElement - The block's pre element
+ * @param summaryText - Text shown in the bar (empty = line count)
+ * @param totalLineCount - Number of lines in the code
+ */
+export function addCollapseToggle(
+	containerElement: HTMLElement,
+	preElement: HTMLPreElement,
+	summaryText: string,
+	totalLineCount: number
+): void {
+	const collapseBar = document.createElement('div');
+	collapseBar.className = CSS_CLASSES.collapseBar;
+
+	const collapseButton = document.createElement('button');
+	collapseButton.className = CSS_CLASSES.collapseButton;
+
+	const iconSpan = document.createElement('span');
+	const labelSpan = document.createElement('span');
+	labelSpan.textContent = summaryText || `Show code (${String(totalLineCount)} lines)`;
+	collapseButton.appendChild(iconSpan);
+	collapseButton.appendChild(labelSpan);
+
+	const update = (isCollapsed: boolean): void => {
+		containerElement.classList.toggle(CSS_CLASSES.collapsed, isCollapsed);
+		setSvgContent(iconSpan, isCollapsed ? CHEVRON_DOWN_SVG : CHEVRON_UP_SVG);
+		collapseButton.setAttribute('aria-expanded', String(!isCollapsed));
+	};
+
+	collapseButton.addEventListener('click', (event) => {
+		event.preventDefault();
+		event.stopPropagation();
+		update(!containerElement.classList.contains(CSS_CLASSES.collapsed));
+	});
+
+	update(true);
+	collapseBar.appendChild(collapseButton);
+	preElement.parentElement?.insertBefore(collapseBar, preElement);
+}
+
 // =============================================================================
 // Download Button
 // =============================================================================
@@ -283,8 +787,9 @@ export type DownloadCallback = (codeText: string) => void;
  *
  * @param preElement - The pre element to attach the button to
  * @param onDownload - Callback that performs the actual download
+ * @param cleanup - Copy pipeline (prompt stripping, placeholders) also applied to downloads
  */
-export function addDownloadButton(preElement: HTMLPreElement, onDownload: DownloadCallback): void {
+export function addDownloadButton(preElement: HTMLPreElement, onDownload: DownloadCallback, cleanup?: CopyPipelineOptions): void {
 	const downloadButton = document.createElement('button');
 	downloadButton.className = CSS_CLASSES.downloadButton;
 	downloadButton.setAttribute('aria-label', 'Download code');
@@ -298,14 +803,217 @@ export function addDownloadButton(preElement: HTMLPreElement, onDownload: Downlo
 		const codeElement = preElement.querySelector('code');
 
 		if (codeElement) {
-			const codeText = extractCodeText(codeElement);
-			onDownload(codeText);
+			withFilledPlaceholders(cleanupCopyText(extractCopyText(codeElement, cleanup), cleanup), cleanup, onDownload);
 		}
 	});
 
 	preElement.appendChild(downloadButton);
 }
 
+// =============================================================================
+// Edit Button
+// =============================================================================
+
+/**
+ * Adds a button that opens the code of a block embedding a vault file
+ * for editing, so changes can be written back to the file.
+ *
+ * @param preElement - The pre element to attach the button to
+ * @param onEdit - Opens the editor
+ */
+export function addEditButton(preElement: HTMLPreElement, onEdit: () => void): void {
+	const editButton = document.createElement('button');
+	editButton.className = CSS_CLASSES.editButton;
+	editButton.setAttribute('aria-label', 'Edit source file');
+	editButton.setAttribute('title', 'Edit and write back to the file');
+	setSvgContent(editButton, EDIT_ICON_SVG);
+
+	editButton.addEventListener('click', (event) => {
+		event.preventDefault();
+		event.stopPropagation();
+		onEdit();
+	});
+
+	preElement.appendChild(editButton);
+}
+
+// =============================================================================
+// Run Button
+// =============================================================================
+
+/**
+ * Adds a button that runs a block's code, with its output shown under
+ * the block.
+ *
+ * @param preElement - The pre element to attach the button to
+ * @param onRun - Runs the code
+ */
+export function addRunButton(preElement: HTMLPreElement, onRun: () => void): void {
+	const runButton = document.createElement('button');
+	runButton.className = CSS_CLASSES.runButton;
+	runButton.setAttribute('aria-label', 'Run');
+	runButton.setAttribute('title', 'Run the block');
+	setSvgContent(runButton, RUN_ICON_SVG);
+
+	runButton.addEventListener('click', (event) => {
+		event.preventDefault();
+		event.stopPropagation();
+		onRun();
+	});
+
+	preElement.appendChild(runButton);
+}
+
+/**
+ * Adds a run button to the gutter of every wrapped line, for following
+ * a block a step at a time. The line last run is marked, with its exit
+ * status at the end of the line (✓, or ✗ and the exit code). One line
+ * runs at a time: the buttons are disabled until it has finished.
+ *
+ * Requires the code to have been wrapped into ucf-line spans first.
+ *
+ * @param preElement - The pre element containing wrapped lines
+ * @param onRunLine - Runs one line; resolves to undefined if the run didn't start
+ */
+export function addLineRunButtons(preElement: HTMLPreElement, onRunLine: (line: string) => Promise<RunResult | undefined>): void {
+	const lineElements = Array.from(preElement.querySelectorAll<HTMLElement>(`code > .${CSS_CLASSES.line}`));
+	const lineButtons: HTMLButtonElement[] = [];
+	const setButtonsDisabled = (disabled: boolean): void => {
+		for (const button of lineButtons) button.disabled = disabled;
+	};
+
+	for (const lineElement of lineElements) {
+		const lineText = extractLineText(lineElement);
+
+		// Blank lines have nothing to run — keep the gutter aligned instead
+		if (lineText.trim() === '') {
+			const spacer = document.createElement('span');
+			spacer.className = CSS_CLASSES.lineRunSpacer;
+			lineElement.insertBefore(spacer, lineElement.firstChild);
+			continue;
+		}
+
+		const lineButton = document.createElement('button');
+		lineButton.className = CSS_CLASSES.lineRunButton;
+		lineButton.setAttribute('aria-label', 'Run line');
+		setSvgContent(lineButton, RUN_ICON_SVG);
+
+		lineButton.addEventListener('click', (event) => {
+			event.preventDefault();
+			event.stopPropagation();
+			setButtonsDisabled(true);
+
+			const statusElement = document.createElement('span');
+			statusElement.className = CSS_CLASSES.lineRunStatus;
+			statusElement.textContent = '…';
+			statusElement.setAttribute('title', 'Running…');
+			lineElement.appendChild(statusElement);
+
+			void onRunLine(lineText).then((result) => {
+				setButtonsDisabled(false);
+				if (!result) {
+					statusElement.remove();
+					return;
+				}
+
+				// Only the line last run is marked, so the old mark goes once this one has run
+				for (const marked of Array.from(preElement.querySelectorAll(`.${CSS_CLASSES.lineRunCurrent}`))) {
+					marked.classList.remove(CSS_CLASSES.lineRunCurrent);
+				}
+				for (const status of Array.from(preElement.querySelectorAll(`.${CSS_CLASSES.lineRunStatus}`))) {
+					if (status !== statusElement) status.remove();
+				}
+				lineElement.classList.add(CSS_CLASSES.lineRunCurrent);
+
+				const failed = result.exitCode !== 0 || result.stopped !== undefined;
+				statusElement.textContent = !failed ? '✓' : result.exitCode === null ? '✗' : `✗ ${String(result.exitCode)}`;
+				statusElement.setAttribute('title', describeRunResult(result.exitCode, result.errorMessage, result.stopped));
+				statusElement.classList.toggle(CSS_CLASSES.lineRunFailed, failed);
+			});
+		});
+
+		lineButtons.push(lineButton);
+		lineElement.insertBefore(lineButton, lineElement.firstChild);
+	}
+}
+
+// =============================================================================
+// Wrap Toggle
+// =============================================================================
+
+/**
+ * Adds a button that switches the block between horizontal scrolling
+ * and soft wrapping.
+ *
+ * @param preElement - The pre element to attach the button to
+ * @param initialWrapped - Whether the block starts soft-wrapped
+ * @param onToggle - Called with the new state after each click
+ */
+export function addWrapToggleButton(
+	preElement: HTMLPreElement,
+	initialWrapped: boolean,
+	onToggle?: (wrapped: boolean) => void
+): void {
+	const wrapButton = document.createElement('button');
+	wrapButton.className = CSS_CLASSES.wrapButton;
+	setSvgContent(wrapButton, WRAP_ICON_SVG);
+
+	const update = (isWrapped: boolean): void => {
+		preElement.classList.toggle(CSS_CLASSES.softWrapped, isWrapped);
+		wrapButton.setAttribute('aria-pressed', String(isWrapped));
+		wrapButton.setAttribute('aria-label', isWrapped ? 'Disable word wrap' : 'Enable word wrap');
+		wrapButton.setAttribute('title', isWrapped ? 'Scroll long lines' : 'Wrap long lines');
+	};
+
+	wrapButton.addEventListener('click', (event) => {
+		event.preventDefault();
+		event.stopPropagation();
+
+		const isWrapped = !preElement.classList.contains(CSS_CLASSES.softWrapped);
+		update(isWrapped);
+		onToggle?.(isWrapped);
+	});
+
+	update(initialWrapped);
+	preElement.appendChild(wrapButton);
+}
+
+// =============================================================================
+// Focus Toggle
+// =============================================================================
+
+/**
+ * Adds a button that undims the lines around a block's focused lines
+ * (HIGHLIGHT.FOCUS), and dims them again.
+ *
+ * Does nothing for a block without focused lines.
+ *
+ * @param preElement - The pre element to attach the button to
+ */
+export function addFocusToggleButton(preElement: HTMLPreElement): void {
+	if (!preElement.classList.contains(CSS_CLASSES.focus)) return;
+
+	const focusButton = document.createElement('button');
+	focusButton.className = CSS_CLASSES.focusButton;
+	setSvgContent(focusButton, FOCUS_ICON_SVG);
+
+	const update = (isRevealed: boolean): void => {
+		preElement.classList.toggle(CSS_CLASSES.focusRevealed, isRevealed);
+		focusButton.setAttribute('aria-pressed', String(isRevealed));
+		focusButton.setAttribute('aria-label', isRevealed ? 'Dim unfocused lines' : 'Show all lines');
+		focusButton.setAttribute('title', isRevealed ? 'Dim unfocused lines' : 'Show all lines');
+	};
+
+	focusButton.addEventListener('click', (event) => {
+		event.preventDefault();
+		event.stopPropagation();
+		update(!preElement.classList.contains(CSS_CLASSES.focusRevealed));
+	});
+
+	update(false);
+	preElement.appendChild(focusButton);
+}
+
 // =============================================================================
 // Combined Button Addition
 // =============================================================================
@@ -317,6 +1025,12 @@ export interface CodeButtonOptions {
 	/** Whether to show copy button */
 	showCopyButton: boolean;
 
+	/** Whether to show per-line copy buttons (requires wrapped lines) */
+	showLineCopyButtons?: boolean;
+
+	/** Transformations for the "copy as…" menu (shown with the copy button, or when lines are highlighted) */
+	copyAsEntries?: ResolvedCopyAsEntry[];
+
 	/** Whether to show download button */
 	showDownloadButton: boolean;
 
@@ -329,6 +1043,9 @@ export interface CodeButtonOptions {
 	 */
 	foldLines: number;
 
+	/** Height limit with a "show all" expander; used when the fold button isn't shown */
+	maxHeight?: MaxHeightLimit;
+
 	/** Join operator for Shift+click copy */
 	shiftCopyJoin?: string;
 
@@ -338,29 +1055,137 @@ export interface CodeButtonOptions {
 	/** Regex pattern matching lines to ignore during joined copies */
 	joinIgnoreRegex?: string;
 
+	/** Prompt regex; prompts are stripped from everything copied */
+	promptPattern?: RegExp;
+
+	/** Comment syntax; comment lines are left out of everything copied */
+	commentSyntax?: CommentSyntax;
+
+	/** Secret patterns; matches are redacted from everything copied */
+	redactPatterns?: RegExp[];
+
+	/** Prompts for {{PLACEHOLDER}} values before copying or downloading */
+	fillPlaceholders?: PlaceholderFiller;
+
+	/** Called with the final text after each successful copy */
+	onCopied?: (text: string) => void;
+
+	/** Copy and download only the new version of a diff block */
+	copyDiffAfter?: boolean;
+
+	/** Copy and download this instead of the lines shown, for a partly loaded file */
+	fullText?: string;
+
+	/** Copy confirmation (the buttons use checkmark and durationMs) */
+	feedback?: CopyFeedbackConfig;
+
+	/** Copies recorded for this block; shows a count badge (undefined = no badge) */
+	copyCount?: number;
+
 	/** Callback for download button. Required when showDownloadButton is true. */
 	onDownload?: DownloadCallback;
+
+	/** Opens the embedded file's code for editing; shows the edit button (undefined = no button) */
+	onEdit?: () => void;
+
+	/** Runs the block's code; shows the run button (undefined = no button) */
+	onRun?: () => void;
+
+	/** Runs one line of the block; shows a run button on each line (undefined = no buttons) */
+	onRunLine?: (line: string) => Promise<RunResult | undefined>;
+
+	/** Whether the block starts soft-wrapped; shows the wrap toggle (undefined = no toggle) */
+	softWrapped?: boolean;
+
+	/** Called with the new state when the wrap toggle is clicked */
+	onWrapToggled?: (wrapped: boolean) => void;
+
+	/** Show a button that undims the lines around focused lines */
+	showFocusToggle?: boolean;
 }
 
 /**
- * Adds copy, download, and/or fold buttons to a pre element.
+ * Adds copy, per-line copy, region copy, copy-as, download, edit, run, per-line run, wrap, focus and/or fold (or max height) buttons to a pre element.
  *
  * @param preElement - The pre element to enhance
  * @param options - Button configuration options
  */
 export function addCodeBlockButtons(preElement: HTMLPreElement, options: CodeButtonOptions): void {
-	const { showCopyButton, showDownloadButton, totalLineCount, foldLines, shiftCopyJoin, altCopyJoin, joinIgnoreRegex, onDownload } = options;
+	const { showCopyButton, showLineCopyButtons, copyAsEntries, showDownloadButton, totalLineCount, foldLines, maxHeight, shiftCopyJoin, altCopyJoin, joinIgnoreRegex, promptPattern, commentSyntax, redactPatterns, fillPlaceholders, onCopied, copyDiffAfter, fullText, feedback, copyCount, onDownload, onEdit, onRun, onRunLine, softWrapped, onWrapToggled, showFocusToggle } = options;
+	const cleanup: CopyPipelineOptions = { promptPattern, commentSyntax, redactPatterns, fillPlaceholders, onCopied, copyDiffAfter, fullText, feedback };
+
+	if (copyCount !== undefined) {
+		const bumpCopyCount = addCopyCountBadge(preElement, copyCount);
+		cleanup.onCopied = (text: string) => {
+			onCopied?.(text);
+			bumpCopyCount();
+		};
+	}
 
 	if (showCopyButton) {
-		addCopyButton(preElement, { shiftCopyJoin, altCopyJoin, joinIgnoreRegex });
+		addCopyButton(preElement, { shiftCopyJoin, altCopyJoin, joinIgnoreRegex, ...cleanup });
+
+		// Skips itself when there is nothing to offer (no entries, highlights or diff)
+		addCopyAsButton(preElement, copyAsEntries ?? [], cleanup);
+
+		// Skips itself when the code has no region markers
+		addRegionCopyButtons(preElement, cleanup);
+	}
+
+	if (showLineCopyButtons) {
+		addLineCopyButtons(preElement, cleanup);
 	}
 
 	if (showDownloadButton && onDownload) {
-		addDownloadButton(preElement, onDownload);
+		addDownloadButton(preElement, onDownload, cleanup);
+	}
+
+	if (onEdit) {
+		addEditButton(preElement, onEdit);
+	}
+
+	if (onRun) {
+		addRunButton(preElement, onRun);
+	}
+
+	// After the line copy buttons, so the run button comes first in the gutter
+	if (onRunLine) {
+		addLineRunButtons(preElement, onRunLine);
+	}
+
+	if (softWrapped !== undefined) {
+		addWrapToggleButton(preElement, softWrapped, onWrapToggled);
+	}
+
+	if (showFocusToggle) {
+		addFocusToggleButton(preElement);
 	}
 
 	// Show fold button if folding is enabled (foldLines > 0) and code exceeds fold threshold
 	if (foldLines > 0 && totalLineCount > foldLines) {
 		addFoldButton(preElement, totalLineCount, foldLines);
+	} else if (maxHeight) {
+		addMaxHeightExpander(preElement, totalLineCount, maxHeight);
 	}
 }
+
+/**
+ * Moves the copy, copy-as, download, edit, run, wrap and focus buttons and the copy count from
+ * over the code into the title bar (HEADER.BUTTONS), where they stay
+ * visible instead of appearing on hover.
+ *
+ * @param preElement - The pre element holding the buttons
+ * @param titleElement - The block's title bar
+ */
+export function moveButtonsToHeader(preElement: HTMLPreElement, titleElement: HTMLElement): void {
+	const selector = [CSS_CLASSES.copyCount, CSS_CLASSES.copyAsButton, CSS_CLASSES.downloadButton, CSS_CLASSES.editButton, CSS_CLASSES.runButton, CSS_CLASSES.wrapButton, CSS_CLASSES.focusButton, CSS_CLASSES.copyButton]
+		.map(className => `:scope > .${className}`)
+		.join(', ');
+	const buttons = Array.from(preElement.querySelectorAll<HTMLElement>(selector));
+	if (buttons.length === 0) return;
+
+	const buttonGroup = document.createElement('div');
+	buttonGroup.className = CSS_CLASSES.headerButtons;
+	buttons.forEach(button => buttonGroup.appendChild(button));
+	titleElement.appendChild(buttonGroup);
+}
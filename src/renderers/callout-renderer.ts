@@ -38,8 +38,8 @@ import {
  * Must be called AFTER wrapCodeLinesInDom() has created the line structure
  * (i.e., after processCodeBlock or when line numbers/zebra stripes are enabled).
  *
- * For code blocks without line wrapping (no line numbers, no zebra), the
- * ucf-line spans won't exist and callouts cannot be injected.
+ * Blocks with callouts are processed with forceLineWrapping, so the
+ * ucf-line spans exist even without line numbers or zebra stripes.
  *
  * @param codeElement - The <code> element containing wrapped lines
  * @param preElement - The <pre> parent element
@@ -235,9 +235,10 @@ function injectPopoverTrigger(
 // =============================================================================
 
 /**
- * Sets up click interactions for all popover triggers within a pre element.
+ * Sets up click and hover interactions for all popover triggers within a pre element.
  *
- * Click on trigger: toggles the matching popover visibility.
+ * Click on trigger: pins the matching popover open, or closes it again.
+ * Hover on trigger: shows the popover until the pointer leaves (unless one is pinned).
  * Click elsewhere: hides all open popovers.
  *
  * @param preElement - The <pre> element containing triggers and popovers
@@ -247,6 +248,13 @@ function setupPopoverInteractions(preElement: HTMLElement): void {
 	const popovers = Array.from(preElement.querySelectorAll(`.${CSS_CLASSES.calloutPopover}`));
 
 	const VISIBLE_CLASS = 'ucf-popover-visible';
+	let pinnedPopover: Element | null = null;
+
+	const hideAllPopovers = (): void => {
+		for (const p of popovers) {
+			p.classList.remove(VISIBLE_CLASS);
+		}
+	};
 
 	for (const trigger of triggers) {
 		const id = trigger.getAttribute('data-callout-id');
@@ -255,22 +263,33 @@ function setupPopoverInteractions(preElement: HTMLElement): void {
 		if (popover) {
 			trigger.addEventListener('click', (e) => {
 				e.stopPropagation();
-				const isVisible = popover.classList.contains(VISIBLE_CLASS);
+				const wasPinned = pinnedPopover === popover;
 				// Hide all other popovers first
-				for (const p of popovers) {
-					(p as HTMLElement).classList.remove(VISIBLE_CLASS);
+				hideAllPopovers();
+				pinnedPopover = wasPinned ? null : popover;
+				if (!wasPinned) {
+					popover.classList.add(VISIBLE_CLASS);
 				}
-				if (!isVisible) {
+			});
+
+			// Hovering previews the popover; a pinned one stays put
+			trigger.addEventListener('mouseenter', () => {
+				if (!pinnedPopover) {
 					popover.classList.add(VISIBLE_CLASS);
 				}
 			});
+
+			trigger.addEventListener('mouseleave', () => {
+				if (pinnedPopover !== popover) {
+					popover.classList.remove(VISIBLE_CLASS);
+				}
+			});
 		}
 	}
 
 	// Close popovers when clicking outside
 	preElement.addEventListener('click', () => {
-		for (const p of popovers) {
-			(p as HTMLElement).classList.remove(VISIBLE_CLASS);
-		}
+		hideAllPopovers();
+		pinnedPopover = null;
 	});
 }
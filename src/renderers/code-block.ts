@@ -5,8 +5,11 @@
  * scrolling, and other visual enhancements.
  */
 
-import { CSS_CLASSES } from '../constants';
-import { addScrollBehaviour, processCodeElementLines, findCodeElement } from '../utils';
+import { CSS_CLASSES, PLACEHOLDER_PATTERN, BLAME_MAX_WIDTH, BRACKET_COLOUR_COUNT, TERM_COLOUR_COUNT, INDENT_GUIDE_TAB_WIDTH, getCalloutColor, getCalloutIcon, normalizeCalloutType } from '../constants';
+import type { CommentKeywordRule, HighlightTheme, LineAnnotation, LineBlame, PatternStyle, TokenStyle } from '../types';
+import { setSvgContent } from '../utils/dom';
+import { addScrollBehaviour, processCodeElementLines, findCodeElement, findPromptLength, findRedactions, wrapTextRange, computeIndentGuides, findIndentScope, measureIndent, findWhitespaceRuns, findCommentKeywords, findTermMatches, BRACKET_CHARACTERS, pairBrackets, classifyDiffLines, hasDiffMarker, unifiedDiffSteps, buildSplitDiffRows, diffWords, buildFontFeatureSettings } from '../utils';
+import type { FoldRegion, DiffLineKind } from '../utils';
 
 // =============================================================================
 // Code Block Processing
@@ -22,11 +25,152 @@ export interface CodeBlockProcessingOptions {
 	/** Whether to show zebra stripes */
 	showZebraStripes: boolean;
 
+	/** Zebra stripe colour (empty or omitted = theme colour) */
+	zebraColour?: string;
+
+	/** Highlight the line under the pointer */
+	lineHover?: boolean;
+
 	/** Starting line number (default: 1) */
 	startingLineNumber?: number;
 
+	/** Block line that relative line numbers count from (0 or omitted = absolute numbers) */
+	anchorLine?: number;
+
 	/** Scroll lines: 0 = disabled, 1+ = scroll after N lines */
 	scrollLines: number;
+
+	/** Wrap lines into ucf-line spans even without numbers or stripes */
+	forceLineWrapping?: boolean;
+
+	/** Prompt regex; matching prompts are wrapped in ucf-prompt spans */
+	promptPattern?: RegExp;
+
+	/** Line numbers to highlight (1-based, as rendered) */
+	highlightLines?: number[];
+
+	/** Regex; lines whose text matches are highlighted too */
+	highlightPattern?: RegExp;
+
+	/** Terms marked wherever they occur, each in its own colour */
+	highlightTerms?: string[];
+
+	/** Lines changed since an earlier version of the note (1-based, as rendered) */
+	changedLines?: number[];
+
+	/** Line numbers left undimmed; the rest of the block is dimmed (1-based, as rendered) */
+	focusLines?: number[];
+
+	/** Wrap {{PLACEHOLDER}}s in ucf-placeholder spans */
+	markPlaceholders?: boolean;
+
+	/** Secret patterns; matches are masked in ucf-redacted spans */
+	redactPatterns?: RegExp[];
+
+	/** Regions that get a fold toggle on their start line */
+	foldRegions?: FoldRegion[];
+
+	/** Start with the fold regions collapsed */
+	collapseFoldRegions?: boolean;
+
+	/** Draw indentation guides with this look (undefined = no guides) */
+	indentGuides?: IndentGuideStyle;
+
+	/** Whitespace glyphs: 'all', 'trailing' (anything else = none) */
+	whitespaceMode?: string;
+
+	/** Keywords to pick out in comments (TODO, FIXME, …) */
+	commentKeywords?: CommentKeywordRule[];
+
+	/** Show an icon in the gutter of lines with a comment keyword */
+	commentKeywordIcons?: boolean;
+
+	/** Colour bracket pairs by depth and show a bracket's match on hover */
+	bracketPairs?: boolean;
+
+	/** Icons shown in the gutter of annotated lines */
+	annotations?: LineAnnotation[];
+
+	/** Blame metadata shown in a column before the code (BLAME) */
+	blame?: LineBlame[];
+
+	/** Colour the block as a diff (undefined = not a diff) */
+	diff?: DiffMarking;
+
+	/** Font ligatures on or off (undefined = the theme's choice) */
+	ligatures?: boolean;
+
+	/** OpenType features, e.g. "ss01, cv02=3, -calt" */
+	fontFeatures?: string;
+
+	/** Font family of the code (empty or omitted = theme font) */
+	fontFamily?: string;
+
+	/** CSS font size of the code (empty or omitted = theme size) */
+	fontSize?: string;
+
+	/** Columns per hard tab (omitted = 4) */
+	tabSize?: number;
+
+	/** Draw rulers at these columns */
+	rulers?: RulerStyle;
+
+	/** Text direction of each line: 'ltr', 'rtl' or 'auto' (omitted = inherited) */
+	textDirection?: string;
+
+	/** Add line numbers that show only when printing (ignored when showLineNumbers is set) */
+	printLineNumbers?: boolean;
+
+	/** Indent each line's continuation rows to its indentation when soft-wrapped */
+	wrapIndents?: boolean;
+
+	/** Styles for highlighted tokens (TOKENS), applied in order */
+	tokenStyles?: TokenStyle[];
+
+	/** Styles for text matching a regex (PATTERNS), applied in order */
+	patternStyles?: PatternStyle[];
+
+	/** Highlight theme whose background and text colour the block takes (RENDER.THEME) */
+	highlightTheme?: HighlightTheme;
+}
+
+/**
+ * Which lines of a diff block were added or removed.
+ */
+export interface DiffMarking {
+	/** Read the code as a unified diff, with +/- markers on each line */
+	unified: boolean;
+
+	/** Lines marked as added (1-based, as rendered) */
+	addedLines: number[];
+
+	/** Lines marked as removed (1-based, as rendered) */
+	removedLines: number[];
+
+	/** Emphasise the changed words of each removed line and its replacement */
+	words?: boolean;
+}
+
+/**
+ * Look of a block's indentation guides.
+ */
+export interface IndentGuideStyle {
+	/** CSS colour (empty = theme colour) */
+	colour: string;
+
+	/** Line style: 'solid', 'dashed' or 'dotted' */
+	style: string;
+}
+
+/**
+ * Where and how a block's column rulers are drawn.
+ */
+export interface RulerStyle {
+	/** Columns to draw a ruler after (e.g. 80) */
+	columns: number[];
+
+	/** CSS colour (empty = theme colour) */
+	colour: string;
 }
 
 /**
@@ -53,14 +197,991 @@ export function processCodeBlock(containerElement: HTMLElement, options: CodeBlo
 		addScrollBehaviour(preElement, options.scrollLines);
 	}
 
-	// Apply line numbers and zebra stripes
-	if (options.showLineNumbers || options.showZebraStripes) {
+	const highlightLines = options.highlightLines ?? [];
+	const focusLines = options.focusLines ?? [];
+	const redactPatterns = options.redactPatterns ?? [];
+	const foldRegions = options.foldRegions ?? [];
+	const annotations = options.annotations ?? [];
+	const blame = options.blame ?? [];
+	const rulerColumns = options.rulers?.columns ?? [];
+	const showWhitespace = options.whitespaceMode === 'all' || options.whitespaceMode === 'trailing';
+	const textDirection = isTextDirection(options.textDirection) ? options.textDirection : undefined;
+	const printLineNumbers = options.printLineNumbers === true && !options.showLineNumbers;
+	const commentKeywords = options.commentKeywords ?? [];
+	const commentKeywordIcons = options.commentKeywordIcons === true && commentKeywords.length > 0;
+	const patternStyles = options.patternStyles ?? [];
+	const highlightTerms = options.highlightTerms ?? [];
+	const changedLines = options.changedLines ?? [];
+
+	// Hover, prompts, highlights, terms, changed lines, focus, placeholders, secrets, pattern styles, whitespace, annotations, keyword icons, blame, diffs, fold regions, indent guides, rulers, text direction, print line numbers and wrap indents are marked per line, so they need the wrapped structure too
+	const forceLineWrapping = options.forceLineWrapping === true
+		|| options.lineHover === true
+		|| options.promptPattern !== undefined
+		|| highlightLines.length > 0
+		|| options.highlightPattern !== undefined
+		|| highlightTerms.length > 0
+		|| changedLines.length > 0
+		|| focusLines.length > 0
+		|| options.markPlaceholders === true
+		|| redactPatterns.length > 0
+		|| patternStyles.length > 0
+		|| showWhitespace
+		|| annotations.length > 0
+		|| commentKeywordIcons
+		|| blame.length > 0
+		|| options.diff !== undefined
+		|| foldRegions.length > 0
+		|| options.indentGuides !== undefined
+		|| rulerColumns.length > 0
+		|| textDirection !== undefined
+		|| printLineNumbers
+		|| options.wrapIndents === true;
+
+	// Apply line numbers, zebra stripes, and any other per-line structure
+	if (options.showLineNumbers || options.showZebraStripes || forceLineWrapping) {
 		processCodeElementLines(preElement, codeElement, {
 			showLineNumbers: options.showLineNumbers,
 			showZebraStripes: options.showZebraStripes,
 			startingLineNumber: options.startingLineNumber ?? 1,
+			anchorLine: options.anchorLine,
+			forceLineWrapping,
 		});
 	}
+
+	if (options.showZebraStripes && options.zebraColour) {
+		preElement.style.setProperty('--ucf-zebra-colour', options.zebraColour);
+	}
+
+	if (options.lineHover) {
+		preElement.classList.add(CSS_CLASSES.lineHover);
+	}
+
+	if (printLineNumbers) {
+		addPrintLineNumbers(codeElement, options.startingLineNumber ?? 1);
+	}
+
+	applyFontFeatures(preElement, options.ligatures, options.fontFeatures ?? '');
+	applyFontFace(preElement, options.fontFamily ?? '', options.fontSize ?? '');
+
+	if (options.highlightTheme) {
+		applyHighlightTheme(preElement, options.highlightTheme);
+	}
+
+	if (options.tokenStyles && options.tokenStyles.length > 0) {
+		applyTokenStyles(codeElement, options.tokenStyles);
+	}
+
+	if (patternStyles.length > 0) {
+		markPatternStyles(codeElement, patternStyles);
+	}
+
+	if (options.bracketPairs) {
+		colourBracketPairs(codeElement);
+	}
+
+	if (options.tabSize !== undefined) {
+		preElement.style.setProperty('--ucf-tab-size', String(options.tabSize));
+	}
+
+	if (options.promptPattern) {
+		markPrompts(codeElement, options.promptPattern);
+	}
+
+	if (highlightLines.length > 0) {
+		markHighlightedLines(codeElement, highlightLines);
+	}
+
+	if (options.highlightPattern) {
+		markMatchingLines(codeElement, options.highlightPattern);
+	}
+
+	if (highlightTerms.length > 0) {
+		markHighlightTerms(codeElement, highlightTerms);
+	}
+
+	if (changedLines.length > 0) {
+		markChangedLines(codeElement, changedLines);
+	}
+
+	if (focusLines.length > 0) {
+		markFocusedLines(preElement, codeElement, focusLines);
+	}
+
+	if (options.markPlaceholders) {
+		markPlaceholders(codeElement);
+	}
+
+	if (redactPatterns.length > 0) {
+		markRedactions(codeElement, redactPatterns);
+	}
+
+	if (showWhitespace) {
+		markWhitespace(codeElement, options.whitespaceMode === 'trailing');
+	}
+
+	if (commentKeywords.length > 0) {
+		markCommentKeywords(codeElement, commentKeywords);
+	}
+
+	if (annotations.length > 0) {
+		addLineAnnotations(codeElement, annotations);
+	}
+
+	if (commentKeywordIcons) {
+		addCommentKeywordIcons(codeElement);
+	}
+
+	if (blame.length > 0) {
+		addBlameColumn(codeElement, blame);
+	}
+
+	if (options.diff) {
+		markDiffLines(preElement, codeElement, options.diff);
+	}
+
+	if (foldRegions.length > 0) {
+		addFoldRegionToggles(codeElement, foldRegions, options.collapseFoldRegions === true);
+	}
+
+	if (options.indentGuides) {
+		addIndentGuides(preElement, codeElement, options.indentGuides, options.tabSize);
+	}
+
+	if (options.rulers && rulerColumns.length > 0) {
+		addColumnRulers(preElement, codeElement, options.rulers);
+	}
+
+	if (textDirection) {
+		applyTextDirection(preElement, codeElement, textDirection);
+	}
+
+	if (options.wrapIndents) {
+		markWrapIndents(codeElement, options.tabSize);
+	}
+}
+
+/**
+ * Gives a block the editor colours of a highlight theme (RENDER.THEME).
+ *
+ * The colours override Obsidian's code variables on the pre, so
+ * anything drawn from them (selection, fold fades) matches. The theme's
+ * token colours are applied separately, with the TOKENS styles.
+ *
+ * @param preElement - The block's pre element
+ * @param theme - Highlight theme
+ */
+export function applyHighlightTheme(preElement: HTMLPreElement, theme: HighlightTheme): void {
+	preElement.classList.add(CSS_CLASSES.highlightTheme);
+	if (theme.background) {
+		preElement.style.setProperty('--code-background', theme.background);
+	}
+	if (theme.foreground) {
+		preElement.style.setProperty('--code-normal', theme.foreground);
+	}
+}
+
+/**
+ * Restyles the highlighted tokens of a block (TOKENS).
+ *
+ * Prism marks tokens as `span.token.<name>`; each style sets only the
+ * properties it names on matching spans, so the theme's other token
+ * colours stay. Later styles win over earlier ones for the same token.
+ *
+ * @param codeElement - Highlighted code element
+ * @param styles - Token styles, in the order they apply
+ */
+export function applyTokenStyles(codeElement: HTMLElement, styles: readonly TokenStyle[]): void {
+	for (const style of styles) {
+		codeElement.querySelectorAll<HTMLElement>(`.token.${style.token}`).forEach(tokenElement => {
+			setInlineStyle(tokenElement, style);
+		});
+	}
+}
+
+/**
+ * Styles the text of each line that matches a PATTERNS regex, whichever
+ * tokens it falls in, such as ticket IDs or IP addresses. Later rules
+ * win where matches overlap, as their spans sit inside earlier ones.
+ *
+ * @param codeElement - Code element with wrapped ucf-line spans
+ * @param patternStyles - Pattern rules, in the order they apply
+ */
+export function markPatternStyles(codeElement: HTMLElement, patternStyles: readonly PatternStyle[]): void {
+	codeElement.querySelectorAll<HTMLElement>(`.${CSS_CLASSES.lineContent}`).forEach(contentElement => {
+		const lineText = contentElement.textContent ?? '';
+
+		for (const { pattern, style } of patternStyles) {
+			const matcher = new RegExp(pattern.source, pattern.flags.includes('g') ? pattern.flags : `${pattern.flags}g`);
+
+			let match: RegExpExecArray | null;
+			while ((match = matcher.exec(lineText)) !== null) {
+				// Guard against zero-length matches looping forever
+				if (match[0] === '') {
+					matcher.lastIndex++;
+					continue;
+				}
+				for (const wrapper of wrapTextRange(contentElement, match.index, match.index + match[0].length, CSS_CLASSES.patternStyle)) {
+					setInlineStyle(wrapper, style);
+				}
+			}
+		}
+	});
+}
+
+/** Tokens whose brackets are text rather than code. */
+const BRACKET_TEXT_TOKENS = '.token.string, .token.comment, .token.regex, .token.char';
+
+/**
+ * Colours each bracket pair by its nesting depth and, when the pointer
+ * is over a bracket (or it is tapped), highlights the bracket that
+ * matches it. Brackets in strings and comments are left alone, and an
+ * unmatched bracket is marked as such.
+ *
+ * @param codeElement - Highlighted code element
+ */
+export function colourBracketPairs(codeElement: HTMLElement): void {
+	// Only the code of wrapped lines: gutters and line numbers aren't code
+	const wrapped = codeElement.querySelector(`.${CSS_CLASSES.lineContent}`) !== null;
+	const positions: Array<[Text, number]> = [];
+	let brackets = '';
+
+	const walker = document.createTreeWalker(codeElement, NodeFilter.SHOW_TEXT);
+	while (walker.nextNode()) {
+		const textNode = walker.currentNode as Text;
+		const parent = textNode.parentElement;
+		if (!parent || parent.closest(BRACKET_TEXT_TOKENS) || (wrapped && !parent.closest(`.${CSS_CLASSES.lineContent}`))) continue;
+
+		const text = textNode.data;
+		for (let offset = 0; offset < text.length; offset++) {
+			if (BRACKET_CHARACTERS.includes(text[offset])) {
+				positions.push([textNode, offset]);
+				brackets += text[offset];
+			}
+		}
+	}
+	if (positions.length === 0) return;
+
+	// Wrap from the end, so splitting a text node keeps earlier offsets valid
+	const pairs = pairBrackets(brackets);
+	const bracketElements: HTMLSpanElement[] = [];
+	for (let i = positions.length - 1; i >= 0; i--) {
+		const [textNode, offset] = positions[i];
+		const bracketText = textNode.splitText(offset);
+		bracketText.splitText(1);
+
+		const { depth, partner } = pairs[i];
+		const bracketElement = document.createElement('span');
+		bracketElement.className = partner === null
+			? `${CSS_CLASSES.bracket} ${CSS_CLASSES.bracketUnmatched}`
+			: `${CSS_CLASSES.bracket} ${CSS_CLASSES.bracketDepth}${String(depth % BRACKET_COLOUR_COUNT + 1)}`;
+		bracketText.parentNode?.insertBefore(bracketElement, bracketText);
+		bracketElement.appendChild(bracketText);
+		bracketElements[i] = bracketElement;
+	}
+
+	const partners = new Map<HTMLElement, HTMLElement>();
+	pairs.forEach((pair, i) => {
+		if (pair.partner !== null) partners.set(bracketElements[i], bracketElements[pair.partner]);
+	});
+
+	let matched: HTMLElement[] = [];
+	const showMatch = (target: EventTarget | null): void => {
+		const bracketElement = target instanceof Element ? target.closest<HTMLElement>(`.${CSS_CLASSES.bracket}`) : null;
+		const partner = bracketElement ? partners.get(bracketElement) : undefined;
+		for (const element of matched) element.classList.remove(CSS_CLASSES.bracketMatch);
+		matched = bracketElement && partner ? [bracketElement, partner] : [];
+		for (const element of matched) element.classList.add(CSS_CLASSES.bracketMatch);
+	};
+	codeElement.addEventListener('mouseover', event => { showMatch(event.target); });
+	codeElement.addEventListener('click', event => { showMatch(event.target); });
+	codeElement.addEventListener('mouseleave', () => { showMatch(null); });
+}
+
+/**
+ * Sets the properties a token style names on an element.
+ *
+ * @param element - Element to style
+ * @param style - Style to apply; empty properties are left alone
+ */
+function setInlineStyle(element: HTMLElement, style: TokenStyle): void {
+	if (style.colour) element.style.color = style.colour;
+	if (style.background) element.style.backgroundColor = style.background;
+	if (style.fontWeight) element.style.fontWeight = style.fontWeight;
+	if (style.fontStyle) element.style.fontStyle = style.fontStyle;
+	if (style.textDecoration) element.style.textDecoration = style.textDecoration;
+	if (style.opacity !== undefined) element.style.opacity = String(style.opacity);
+}
+
+/**
+ * Records each line's indentation for soft wrapping.
+ *
+ * When the block is soft-wrapped, a long line's continuation rows start
+ * a little past its indentation, behind a wrap marker, rather than at
+ * the left edge. Only the layout changes, so copying still gives the
+ * original line.
+ *
+ * @param codeElement - Code element with wrapped ucf-line spans
+ * @param tabSize - Columns per hard tab
+ */
+export function markWrapIndents(codeElement: HTMLElement, tabSize: number = INDENT_GUIDE_TAB_WIDTH): void {
+	codeElement.querySelectorAll<HTMLElement>(`.${CSS_CLASSES.lineContent}`).forEach(contentElement => {
+		const indent = measureIndent(contentElement.textContent ?? '', tabSize);
+		if (indent) {
+			contentElement.style.setProperty('--ucf-wrap-indent', String(indent));
+		}
+	});
+}
+
+/**
+ * Adds line numbers that are hidden on screen and shown when printing.
+ *
+ * They go in before anything else marks the gutter, so annotation icons
+ * and blame cells line up as they would with normal line numbers.
+ *
+ * @param codeElement - Code element with wrapped ucf-line spans
+ * @param startingLineNumber - Number of the first line
+ */
+export function addPrintLineNumbers(codeElement: HTMLElement, startingLineNumber: number): void {
+	codeElement.querySelectorAll<HTMLElement>(`.${CSS_CLASSES.line}`).forEach((lineElement, index) => {
+		const numberElement = document.createElement('span');
+		numberElement.className = `${CSS_CLASSES.lineNum} ${CSS_CLASSES.printLineNum}`;
+		numberElement.textContent = String(startingLineNumber + index);
+		lineElement.insertBefore(numberElement, lineElement.firstChild);
+	});
+}
+
+/**
+ * Checks for a direction applyTextDirection understands.
+ *
+ * @param direction - RENDER.DIRECTION value
+ * @returns True for 'ltr', 'rtl' and 'auto'
+ */
+function isTextDirection(direction: string | undefined): direction is 'ltr' | 'rtl' | 'auto' {
+	return direction === 'ltr' || direction === 'rtl' || direction === 'auto';
+}
+
+/**
+ * Sets the text direction of each line of a block.
+ *
+ * Every line is its own bidi run, so Arabic or Hebrew text in one line
+ * can't reorder the next, and with 'auto' each line takes its direction
+ * from its first letter. The gutter stays left-to-right, keeping line
+ * numbers on the left even in a right-to-left note.
+ *
+ * @param preElement - The block's pre element
+ * @param codeElement - Code element with wrapped ucf-line spans
+ * @param direction - 'ltr', 'rtl' or 'auto'
+ */
+export function applyTextDirection(preElement: HTMLPreElement, codeElement: HTMLElement, direction: 'ltr' | 'rtl' | 'auto'): void {
+	preElement.classList.add(CSS_CLASSES.bidi);
+	codeElement.querySelectorAll<HTMLElement>(`.${CSS_CLASSES.lineContent}`).forEach(contentElement => {
+		contentElement.setAttribute('dir', direction);
+	});
+}
+
+/**
+ * Applies ligature and OpenType feature options to a block's font.
+ *
+ * @param preElement - The block's pre element
+ * @param ligatures - Ligatures on or off (undefined = the theme's choice)
+ * @param features - Comma-separated features, e.g. "ss01, cv02=3, -calt"
+ */
+export function applyFontFeatures(preElement: HTMLPreElement, ligatures: boolean | undefined, features: string): void {
+	const featureSettings = buildFontFeatureSettings(features, ligatures);
+	if (!featureSettings) return;
+
+	preElement.classList.add(CSS_CLASSES.fontFeatures);
+	preElement.style.fontFeatureSettings = featureSettings;
+	if (ligatures === false) {
+		// Also drops discretionary and historical ligatures
+		preElement.style.setProperty('font-variant-ligatures', 'none');
+	}
+}
+
+/**
+ * Sets the font family and size of a block's code.
+ *
+ * The size goes on the pre, so heights given in lines (FOLD, SCROLL,
+ * MAX_HEIGHT) follow it. The theme's monospace font stays as a fallback
+ * for a family that isn't installed.
+ *
+ * @param preElement - The block's pre element
+ * @param family - Font family (empty = theme font)
+ * @param size - CSS font size (empty = theme size)
+ */
+export function applyFontFace(preElement: HTMLPreElement, family: string, size: string): void {
+	if (!family && !size) return;
+
+	preElement.classList.add(CSS_CLASSES.fontFace);
+	if (family) {
+		preElement.style.fontFamily = `${family}, var(--font-monospace)`;
+	}
+	if (size) {
+		preElement.style.fontSize = size;
+	}
+}
+
+/**
+ * Draws a faint vertical ruler after each given column, to show a line
+ * length limit. Each line gets its own piece of the ruler, so the rulers
+ * start after the line number gutter and run the height of the code.
+ *
+ * @param preElement - The block's pre element
+ * @param codeElement - Code element with wrapped ucf-line spans
+ * @param rulers - Columns and colour
+ */
+export function addColumnRulers(preElement: HTMLPreElement, codeElement: HTMLElement, rulers: RulerStyle): void {
+	preElement.classList.add(CSS_CLASSES.rulers);
+	if (rulers.colour) {
+		preElement.style.setProperty('--ucf-ruler-colour', rulers.colour);
+	}
+
+	codeElement.querySelectorAll(`.${CSS_CLASSES.lineContent}`).forEach(contentElement => {
+		for (const column of rulers.columns) {
+			const ruler = document.createElement('span');
+			ruler.className = CSS_CLASSES.ruler;
+			ruler.setAttribute('aria-hidden', 'true');
+			ruler.style.setProperty('--ucf-ruler-column', String(column));
+			contentElement.appendChild(ruler);
+		}
+	});
+}
+
+/**
+ * Draws a vertical guide at each indentation level of every line.
+ *
+ * Hovering a line emphasises the guide of the scope it sits in, across
+ * all the lines of that scope.
+ *
+ * @param preElement - The block's pre element
+ * @param codeElement - Code element with wrapped ucf-line spans
+ * @param guideStyle - Guide colour and line style
+ * @param tabSize - Columns per hard tab
+ */
+export function addIndentGuides(
+	preElement: HTMLPreElement,
+	codeElement: HTMLElement,
+	guideStyle: IndentGuideStyle,
+	tabSize: number = INDENT_GUIDE_TAB_WIDTH
+): void {
+	const contentElements = Array.from(codeElement.querySelectorAll<HTMLElement>(`.${CSS_CLASSES.lineContent}`));
+	const { unit, depths } = computeIndentGuides(contentElements.map(element => element.textContent ?? ''), tabSize);
+
+	preElement.classList.add(CSS_CLASSES.indentGuides);
+	if (guideStyle.colour) {
+		preElement.style.setProperty('--ucf-indent-guide-colour', guideStyle.colour);
+	}
+	preElement.style.setProperty('--ucf-indent-guide-style', guideStyle.style);
+
+	const guidesByLine = contentElements.map((contentElement, index) => {
+		const guides: HTMLElement[] = [];
+		for (let level = 0; level < depths[index]; level++) {
+			const guide = document.createElement('span');
+			guide.className = CSS_CLASSES.indentGuide;
+			guide.style.setProperty('--ucf-indent-column', String(level * unit));
+			guides.push(guide);
+		}
+		contentElement.prepend(...guides);
+		return guides;
+	});
+
+	const clearActiveGuides = (): void => {
+		codeElement.querySelectorAll(`.${CSS_CLASSES.indentGuideActive}`)
+			.forEach(guide => guide.classList.remove(CSS_CLASSES.indentGuideActive));
+	};
+
+	codeElement.addEventListener('mouseover', (event) => {
+		const contentElement = (event.target as HTMLElement).closest(`.${CSS_CLASSES.line}`)
+			?.querySelector<HTMLElement>(`.${CSS_CLASSES.lineContent}`);
+		clearActiveGuides();
+		if (!contentElement) return;
+
+		const scope = findIndentScope(depths, contentElements.indexOf(contentElement));
+		if (!scope) return;
+
+		for (let index = scope.startIndex; index <= scope.endIndex; index++) {
+			guidesByLine[index][scope.level - 1].classList.add(CSS_CLASSES.indentGuideActive);
+		}
+	});
+
+	codeElement.addEventListener('mouseleave', clearActiveGuides);
+}
+
+/**
+ * Adds an annotation gutter with an icon on each annotated line.
+ *
+ * Every line gets a gutter cell so the code stays aligned; annotated
+ * lines show their type's callout icon in its colour, with the text as
+ * a tooltip. Annotations past the rendered lines are skipped.
+ *
+ * @param codeElement - Code element with wrapped ucf-line spans
+ * @param annotations - Annotations (1-based lines, as rendered)
+ */
+export function addLineAnnotations(codeElement: HTMLElement, annotations: LineAnnotation[]): void {
+	const annotationsByLine = new Map(annotations.map(annotation => [annotation.line, annotation]));
+
+	codeElement.querySelectorAll<HTMLElement>(`.${CSS_CLASSES.line}`).forEach((lineElement, index) => {
+		const gutter = document.createElement('span');
+		gutter.className = CSS_CLASSES.annotationGutter;
+
+		const annotation = annotationsByLine.get(index + 1);
+		if (annotation) {
+			const tooltip = annotation.text || annotation.type.charAt(0).toUpperCase() + annotation.type.slice(1);
+
+			const icon = document.createElement('span');
+			icon.className = CSS_CLASSES.annotation;
+			icon.dataset.ucfAnnotation = annotation.type;
+			icon.style.color = getCalloutColor(annotation.type);
+			icon.setAttribute('role', 'img');
+			icon.setAttribute('aria-label', tooltip);
+			icon.setAttribute('title', tooltip);
+			setSvgContent(icon, getCalloutIcon(annotation.type));
+			gutter.appendChild(icon);
+		}
+
+		lineElement.insertBefore(gutter, lineElement.querySelector(`.${CSS_CLASSES.lineContent}`));
+	});
+}
+
+/**
+ * Picks out TODO, FIXME and the other comment keywords in their colour.
+ *
+ * Only text inside comment tokens is searched, so a keyword in a string
+ * or identifier is left alone. Each keyword is wrapped in a span that
+ * records its callout type, for the gutter icon.
+ *
+ * @param codeElement - Highlighted code element
+ * @param rules - Keyword rules
+ */
+export function markCommentKeywords(codeElement: HTMLElement, rules: readonly CommentKeywordRule[]): void {
+	codeElement.querySelectorAll<HTMLElement>('.token.comment').forEach(commentElement => {
+		for (const match of findCommentKeywords(commentElement.textContent ?? '', rules)) {
+			const colour = match.rule.colour || getCalloutColor(normalizeCalloutType(match.rule.type));
+			for (const wrapper of wrapTextRange(commentElement, match.start, match.end, CSS_CLASSES.commentKeyword)) {
+				wrapper.dataset.ucfKeywordType = normalizeCalloutType(match.rule.type);
+				wrapper.style.setProperty('--ucf-comment-keyword-colour', colour);
+			}
+		}
+	});
+}
+
+/**
+ * Adds an icon to the gutter of each line with a comment keyword.
+ *
+ * Shares the annotation gutter when there is one, where an annotation
+ * takes the cell; otherwise every line gets a cell so the code stays
+ * aligned. The icon is the keyword's callout type icon, in its colour.
+ *
+ * @param codeElement - Code element with wrapped ucf-line spans and marked keywords
+ */
+export function addCommentKeywordIcons(codeElement: HTMLElement): void {
+	codeElement.querySelectorAll<HTMLElement>(`.${CSS_CLASSES.line}`).forEach(lineElement => {
+		let gutter = lineElement.querySelector<HTMLElement>(`:scope > .${CSS_CLASSES.annotationGutter}`);
+		if (!gutter) {
+			gutter = document.createElement('span');
+			gutter.className = CSS_CLASSES.annotationGutter;
+			lineElement.insertBefore(gutter, lineElement.querySelector(`.${CSS_CLASSES.lineContent}`));
+		}
+		if (gutter.childElementCount > 0) return;
+
+		const keyword = lineElement.querySelector<HTMLElement>(`.${CSS_CLASSES.commentKeyword}`);
+		const type = keyword?.dataset.ucfKeywordType;
+		if (!keyword || !type) return;
+
+		const label = keyword.textContent?.replace(/[:(].*$/, '') ?? '';
+		const icon = document.createElement('span');
+		icon.className = CSS_CLASSES.commentKeywordIcon;
+		icon.style.color = keyword.style.getPropertyValue('--ucf-comment-keyword-colour');
+		icon.setAttribute('role', 'img');
+		icon.setAttribute('aria-label', label);
+		icon.setAttribute('title', label);
+		setSvgContent(icon, getCalloutIcon(type));
+		gutter.appendChild(icon);
+	});
+}
+
+/**
+ * Adds a blame column before each line, like git blame.
+ *
+ * A run of lines with the same metadata shows it once, on the run's
+ * first line; the rest of the run stays blank. The column is sized to
+ * its longest entry, up to BLAME_MAX_WIDTH characters. Being outside
+ * the line content, it is never copied.
+ *
+ * @param codeElement - Code element with wrapped ucf-line spans
+ * @param blame - Blame metadata (1-based lines, as rendered)
+ */
+export function addBlameColumn(codeElement: HTMLElement, blame: LineBlame[]): void {
+	if (blame.length === 0) return;
+
+	const blameByLine = new Map(blame.map(entry => [entry.line, entry]));
+	const formatEntry = (entry: LineBlame): string => [entry.author, entry.date, entry.ticket].filter(Boolean).join('  ');
+
+	const width = Math.min(Math.max(...blame.map(entry => formatEntry(entry).length)), BLAME_MAX_WIDTH);
+	codeElement.style.setProperty('--ucf-blame-width', `${String(width)}ch`);
+
+	let previousText: string | null = null;
+	codeElement.querySelectorAll<HTMLElement>(`.${CSS_CLASSES.line}`).forEach((lineElement, index) => {
+		const cell = document.createElement('span');
+		cell.className = CSS_CLASSES.blame;
+
+		const entry = blameByLine.get(index + 1);
+		const text = entry ? formatEntry(entry) : null;
+
+		if (entry && text !== previousText) {
+			cell.classList.add(CSS_CLASSES.blameStart);
+			cell.setAttribute('title', [entry.author, entry.date, entry.ticket].filter(Boolean).join(' · '));
+
+			const parts: [string, string][] = [
+				[entry.author, CSS_CLASSES.blameAuthor],
+				[entry.date, CSS_CLASSES.blameDate],
+				[entry.ticket, CSS_CLASSES.blameTicket],
+			];
+			for (const [value, className] of parts) {
+				if (!value) continue;
+				const part = document.createElement('span');
+				part.className = className;
+				part.textContent = value;
+				cell.appendChild(part);
+			}
+		}
+		previousText = text;
+
+		lineElement.insertBefore(cell, lineElement.firstChild);
+	});
+}
+
+/** Line class for each kind of diff line (context lines have none). */
+const DIFF_LINE_CLASSES: Record<DiffLineKind, string | undefined> = {
+	added: CSS_CLASSES.diffAdded,
+	removed: CSS_CLASSES.diffRemoved,
+	context: undefined,
+	hunk: CSS_CLASSES.diffHunk,
+	header: CSS_CLASSES.diffHeader,
+};
+
+/**
+ * Colours a diff block's added and removed lines and gives each line a
+ * +/- gutter.
+ *
+ * In a unified diff, each line's own +/- or space marker is wrapped in a
+ * span that is hidden in favour of the gutter; it stays in the text, so
+ * copying the block still copies the diff. Lines listed in addedLines or
+ * removedLines are marked whatever their text says. With words set,
+ * removed lines are paired with the added lines after them and the
+ * changed words of each pair are emphasised.
+ *
+ * @param preElement - The block's pre element
+ * @param codeElement - Code element with wrapped ucf-line spans
+ * @param diff - Unified diff reading and added/removed lines
+ */
+export function markDiffLines(preElement: HTMLPreElement, codeElement: HTMLElement, diff: DiffMarking): void {
+	const lineElements = Array.from(codeElement.querySelectorAll<HTMLElement>(`.${CSS_CLASSES.line}`));
+	const lineTexts = lineElements.map(lineElement =>
+		lineElement.querySelector(`.${CSS_CLASSES.lineContent}`)?.textContent ?? ''
+	);
+	const kinds: DiffLineKind[] = diff.unified ? classifyDiffLines(lineTexts) : lineTexts.map((): DiffLineKind => 'context');
+	const addedLines = new Set(diff.addedLines);
+	const removedLines = new Set(diff.removedLines);
+
+	preElement.classList.add(CSS_CLASSES.diff);
+
+	lineElements.forEach((lineElement, index) => {
+		const contentElement = lineElement.querySelector<HTMLElement>(`.${CSS_CLASSES.lineContent}`);
+		let kind = kinds[index];
+
+		if (diff.unified && contentElement && hasDiffMarker(lineTexts[index], kind)) {
+			wrapTextRange(contentElement, 0, 1, CSS_CLASSES.diffMarker);
+		}
+
+		if (addedLines.has(index + 1)) {
+			kind = 'added';
+		} else if (removedLines.has(index + 1)) {
+			kind = 'removed';
+		}
+
+		kinds[index] = kind;
+		const lineClass = DIFF_LINE_CLASSES[kind];
+		if (lineClass) {
+			lineElement.classList.add(lineClass);
+		}
+
+		const gutter = document.createElement('span');
+		gutter.className = CSS_CLASSES.diffGutter;
+		lineElement.insertBefore(gutter, contentElement);
+	});
+
+	if (!diff.words) return;
+
+	for (const row of buildSplitDiffRows(unifiedDiffSteps(kinds))) {
+		if (row.kind !== 'changed' || row.oldIndex === null || row.newIndex === null) continue;
+
+		const removedContent = lineElements[row.oldIndex].querySelector<HTMLElement>(`.${CSS_CLASSES.lineContent}`);
+		const addedContent = lineElements[row.newIndex].querySelector<HTMLElement>(`.${CSS_CLASSES.lineContent}`);
+		if (removedContent && addedContent) {
+			markChangedWords(removedContent, addedContent);
+		}
+	}
+}
+
+/**
+ * Emphasises the words that changed between a removed line and the added
+ * line that replaced it. Hidden diff markers are left out of the
+ * comparison; lines with nothing in common are left as they are.
+ *
+ * @param removedContent - Content span of the removed line
+ * @param addedContent - Content span of the added line
+ */
+export function markChangedWords(removedContent: HTMLElement, addedContent: HTMLElement): void {
+	const removedOffset = removedContent.querySelector(`.${CSS_CLASSES.diffMarker}`) ? 1 : 0;
+	const addedOffset = addedContent.querySelector(`.${CSS_CLASSES.diffMarker}`) ? 1 : 0;
+
+	const changes = diffWords(
+		(removedContent.textContent ?? '').slice(removedOffset),
+		(addedContent.textContent ?? '').slice(addedOffset)
+	);
+	if (!changes) return;
+
+	changes.removed.forEach(range => {
+		wrapTextRange(removedContent, range.start + removedOffset, range.end + removedOffset, CSS_CLASSES.diffWordRemoved);
+	});
+	changes.added.forEach(range => {
+		wrapTextRange(addedContent, range.start + addedOffset, range.end + addedOffset, CSS_CLASSES.diffWordAdded);
+	});
+}
+
+/**
+ * Adds a fold toggle to the start line of each region.
+ *
+ * Collapsing a region hides its lines up to and including the end
+ * marker, leaving the start line showing. The hidden lines stay in the
+ * DOM, so copying the block still copies them. Regions that run past
+ * the rendered lines are skipped.
+ *
+ * @param codeElement - Code element with wrapped ucf-line spans
+ * @param regions - Fold regions (1-based lines, as rendered)
+ * @param collapsed - Start with every region collapsed
+ */
+export function addFoldRegionToggles(codeElement: HTMLElement, regions: FoldRegion[], collapsed: boolean): void {
+	const lineElements = Array.from(codeElement.querySelectorAll<HTMLElement>(`.${CSS_CLASSES.line}`));
+	const foldableRegions = regions.filter(region => region.endLine <= lineElements.length);
+	const collapsedRegions = new Set<FoldRegion>(collapsed ? foldableRegions : []);
+
+	// A line is hidden while any region around it is collapsed
+	const updateHiddenLines = (): void => {
+		lineElements.forEach((lineElement, index) => {
+			const lineNumber = index + 1;
+			const isHidden = foldableRegions.some(region =>
+				collapsedRegions.has(region) && lineNumber > region.startLine && lineNumber <= region.endLine
+			);
+			lineElement.classList.toggle(CSS_CLASSES.foldRegionHidden, isHidden);
+		});
+	};
+
+	for (const region of foldableRegions) {
+		const startElement = lineElements[region.startLine - 1];
+		const label = region.name ? `region ${region.name}` : 'region';
+
+		const toggle = document.createElement('button');
+		toggle.className = CSS_CLASSES.foldRegionToggle;
+
+		const updateToggle = (): void => {
+			const isCollapsed = collapsedRegions.has(region);
+			startElement.classList.toggle(CSS_CLASSES.foldRegionCollapsed, isCollapsed);
+			toggle.setAttribute('aria-expanded', String(!isCollapsed));
+			toggle.setAttribute('aria-label', isCollapsed ? `Expand ${label}` : `Collapse ${label}`);
+		};
+
+		toggle.addEventListener('click', (event) => {
+			event.preventDefault();
+			event.stopPropagation();
+
+			if (collapsedRegions.has(region)) {
+				collapsedRegions.delete(region);
+			} else {
+				collapsedRegions.add(region);
+			}
+
+			updateToggle();
+			updateHiddenLines();
+		});
+
+		startElement.classList.add(CSS_CLASSES.foldRegionStart);
+		startElement.insertBefore(toggle, startElement.querySelector(`.${CSS_CLASSES.lineContent}`));
+		updateToggle();
+	}
+
+	updateHiddenLines();
+}
+
+/**
+ * Wraps spaces and tabs in spans that draw them as faint glyphs (· and →)
+ * and tint trailing whitespace. The text itself is unchanged, so copying
+ * is unaffected.
+ *
+ * @param codeElement - Code element with wrapped ucf-line spans
+ * @param trailingOnly - Only mark trailing whitespace
+ */
+export function markWhitespace(codeElement: HTMLElement, trailingOnly: boolean): void {
+	const contentElements = codeElement.querySelectorAll<HTMLElement>(`.${CSS_CLASSES.lineContent}`);
+
+	contentElements.forEach(contentElement => {
+		for (const run of findWhitespaceRuns(contentElement.textContent ?? '', trailingOnly)) {
+			const kindClass = run.kind === 'tab' ? CSS_CLASSES.whitespaceTab : CSS_CLASSES.whitespaceSpace;
+			const className = run.trailing ? `${kindClass} ${CSS_CLASSES.whitespaceTrailing}` : kindClass;
+			wrapTextRange(contentElement, run.start, run.end, className);
+		}
+	});
+}
+
+/**
+ * Wraps secret pattern matches in ucf-redacted spans, which are masked
+ * on screen as a hint that they are redacted when copied.
+ *
+ * @param codeElement - Code element with wrapped ucf-line spans
+ * @param redactPatterns - Secret patterns
+ */
+export function markRedactions(codeElement: HTMLElement, redactPatterns: RegExp[]): void {
+	const contentElements = codeElement.querySelectorAll<HTMLElement>(`.${CSS_CLASSES.lineContent}`);
+
+	contentElements.forEach(contentElement => {
+		for (const [start, end] of findRedactions(contentElement.textContent ?? '', redactPatterns)) {
+			for (const wrapper of wrapTextRange(contentElement, start, end, CSS_CLASSES.redacted)) {
+				wrapper.setAttribute('title', 'Redacted when copied');
+			}
+		}
+	});
+}
+
+/**
+ * Wraps each {{PLACEHOLDER}} in a ucf-placeholder span so it stands out
+ * as a value to be filled in at copy time.
+ *
+ * @param codeElement - Code element with wrapped ucf-line spans
+ */
+export function markPlaceholders(codeElement: HTMLElement): void {
+	const contentElements = codeElement.querySelectorAll<HTMLElement>(`.${CSS_CLASSES.lineContent}`);
+
+	contentElements.forEach(contentElement => {
+		const pattern = new RegExp(PLACEHOLDER_PATTERN.source, 'g');
+		const lineText = contentElement.textContent ?? '';
+
+		let match: RegExpExecArray | null;
+		while ((match = pattern.exec(lineText)) !== null) {
+			wrapTextRange(contentElement, match.index, match.index + match[0].length, CSS_CLASSES.placeholder);
+		}
+	});
+}
+
+/**
+ * Adds the ucf-line-highlight class to the given lines.
+ *
+ * Line numbers outside the rendered block are ignored.
+ *
+ * @param codeElement - Code element with wrapped ucf-line spans
+ * @param highlightLines - Line numbers to highlight (1-based, as rendered)
+ */
+export function markHighlightedLines(codeElement: HTMLElement, highlightLines: number[]): void {
+	const lineElements = codeElement.querySelectorAll<HTMLElement>(`.${CSS_CLASSES.line}`);
+
+	for (const lineNumber of highlightLines) {
+		lineElements[lineNumber - 1]?.classList.add(CSS_CLASSES.lineHighlight);
+	}
+}
+
+/**
+ * Adds the ucf-line-changed class to lines that changed since an earlier
+ * version of the note.
+ *
+ * Line numbers outside the rendered block are ignored.
+ *
+ * @param codeElement - Code element with wrapped ucf-line spans
+ * @param changedLines - Changed line numbers (1-based, as rendered)
+ */
+export function markChangedLines(codeElement: HTMLElement, changedLines: number[]): void {
+	const lineElements = codeElement.querySelectorAll<HTMLElement>(`.${CSS_CLASSES.line}`);
+
+	for (const lineNumber of changedLines) {
+		lineElements[lineNumber - 1]?.classList.add(CSS_CLASSES.lineChanged);
+	}
+}
+
+/**
+ * Dims every line of a block except the given ones.
+ *
+ * Line numbers outside the rendered block are ignored; when none of them
+ * is inside it, nothing is dimmed.
+ *
+ * @param preElement - The block's pre element
+ * @param codeElement - Code element with wrapped ucf-line spans
+ * @param focusLines - Line numbers to keep undimmed (1-based, as rendered)
+ */
+export function markFocusedLines(preElement: HTMLPreElement, codeElement: HTMLElement, focusLines: number[]): void {
+	const lineElements = codeElement.querySelectorAll<HTMLElement>(`.${CSS_CLASSES.line}`);
+	const focusedElements = focusLines
+		.map(lineNumber => lineElements[lineNumber - 1] as HTMLElement | undefined)
+		.filter((lineElement): lineElement is HTMLElement => lineElement !== undefined);
+	if (focusedElements.length === 0) return;
+
+	focusedElements.forEach(lineElement => lineElement.classList.add(CSS_CLASSES.lineFocused));
+	preElement.classList.add(CSS_CLASSES.focus);
+}
+
+/**
+ * Adds the ucf-line-highlight class to lines whose text matches a regex.
+ *
+ * Matching uses the rendered text, so it follows the snippet as it
+ * changes instead of fixed line numbers.
+ *
+ * @param codeElement - Code element with wrapped ucf-line spans
+ * @param pattern - Regex tested against each line's text
+ */
+export function markMatchingLines(codeElement: HTMLElement, pattern: RegExp): void {
+	const lineElements = codeElement.querySelectorAll<HTMLElement>(`.${CSS_CLASSES.line}`);
+
+	lineElements.forEach(lineElement => {
+		const contentElement = lineElement.querySelector(`.${CSS_CLASSES.lineContent}`);
+		pattern.lastIndex = 0;
+		if (pattern.test(contentElement?.textContent ?? '')) {
+			lineElement.classList.add(CSS_CLASSES.lineHighlight);
+		}
+	});
+}
+
+/**
+ * Marks every occurrence of the HIGHLIGHT.TERMS terms, each term in its
+ * own colour, so a value can be followed through the block.
+ *
+ * @param codeElement - Code element with wrapped ucf-line spans
+ * @param terms - Terms to mark, in order
+ */
+export function markHighlightTerms(codeElement: HTMLElement, terms: readonly string[]): void {
+	codeElement.querySelectorAll<HTMLElement>(`.${CSS_CLASSES.lineContent}`).forEach(contentElement => {
+		for (const match of findTermMatches(contentElement.textContent ?? '', terms)) {
+			const colourClass = `${CSS_CLASSES.termColour}${String(match.term % TERM_COLOUR_COUNT + 1)}`;
+			wrapTextRange(contentElement, match.start, match.end, `${CSS_CLASSES.term} ${colourClass}`);
+		}
+	});
+}
+
+/**
+ * Wraps the prompt at the start of each line in a ucf-prompt span.
+ *
+ * The span is styled muted and excluded from selection, matching the
+ * fact that prompts are also stripped from copied text.
+ *
+ * @param codeElement - Code element with wrapped ucf-line spans
+ * @param promptPattern - Prompt regex
+ */
+export function markPrompts(codeElement: HTMLElement, promptPattern: RegExp): void {
+	const contentElements = codeElement.querySelectorAll<HTMLElement>(`.${CSS_CLASSES.lineContent}`);
+
+	contentElements.forEach(contentElement => {
+		const promptLength = findPromptLength(contentElement.textContent ?? '', promptPattern);
+		if (promptLength > 0) {
+			wrapTextRange(contentElement, 0, promptLength, CSS_CLASSES.prompt);
+		}
+	});
 }
 
 /**
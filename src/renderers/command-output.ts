@@ -6,11 +6,12 @@
  */
 
 import { App, MarkdownRenderer, Component } from 'obsidian';
-import type { CommandOutputStyles, PluginSettings } from '../types';
+import type { CommandOutputStyles, CopyFeedbackConfig, PluginSettings } from '../types';
 import { CSS_CLASSES, styleClass, COMMAND_OUTPUT_ICON } from '../constants';
-import { escapeHtml, buildStyleString, addScrollBehaviour } from '../utils';
+import { escapeHtml, buildStyleString, addScrollBehaviour, createAnsiStyle, hasAnsiCodes, stripAnsi, parseAnsiLine, ansiStyleToCss } from '../utils';
+import type { AnsiSegment, AnsiStyle } from '../utils';
 import { parseHtmlFragment } from '../utils/dom';
-import { addCopyButton } from './buttons';
+import { addCopyButton, addCopyCountBadge } from './buttons';
 
 // =============================================================================
 // Types
@@ -40,6 +41,28 @@ export interface CommandOutputRenderOptions {
 
 	/** Path of containing note (for description rendering) */
 	containingNotePath?: string;
+
+	/** Called with the copied text after each successful copy */
+	onCopied?: (text: string) => void;
+
+	/** Copy button confirmation */
+	copyFeedback?: CopyFeedbackConfig;
+
+	/** Copies recorded for this block; shows a count badge (undefined = no badge) */
+	copyCount?: number;
+
+	/** Show ANSI colour codes in output lines as coloured text (default false) */
+	renderAnsi?: boolean;
+
+	/** Copy lines with their ANSI codes instead of as shown */
+	copyRawAnsi?: boolean;
+}
+
+/**
+ * ANSI style carried from one output line to the next.
+ */
+export interface AnsiLineState {
+	style: AnsiStyle;
 }
 
 // =============================================================================
@@ -49,18 +72,29 @@ export interface CommandOutputRenderOptions {
 /**
  * Processes a single line of command output.
  *
+ * With ANSI state, escape codes are read: output lines are shown in
+ * their colours (the style carrying over to the next line), command
+ * lines have the codes removed, and the line as written is kept for
+ * raw copies.
+ *
  * @param lineContent - The line content
  * @param promptPattern - Regex to identify command lines
  * @param styleStrings - Style strings for each element type
+ * @param ansiState - ANSI style at the start of the line (omit to show codes as text)
  * @returns HTML string for the line
  */
 export function processOutputLine(
 	lineContent: string,
 	promptPattern: RegExp | undefined,
-	styleStrings: { prompt: string; command: string; output: string }
+	styleStrings: { prompt: string; command: string; output: string },
+	ansiState?: AnsiLineState
 ): string {
+	const hasAnsi = ansiState !== undefined && hasAnsiCodes(lineContent);
+	const rawAttr = hasAnsi ? ` data-ucf-ansi-raw="${escapeHtml(lineContent)}"` : '';
+	const visibleContent = hasAnsi ? stripAnsi(lineContent) : lineContent;
+
 	if (promptPattern) {
-		const match = lineContent.match(promptPattern);
+		const match = visibleContent.match(promptPattern);
 
 		if (match && match.length >= 3) {
 			// Command line with prompt and command parts
@@ -79,14 +113,38 @@ export function processOutputLine(
 				styleStrings.command
 			);
 
-			return `<span class="${CSS_CLASSES.line} ${CSS_CLASSES.cmdoutLine} ${CSS_CLASSES.cmdoutCmdLine}">${promptSpan}${commandSpan}</span>`;
+			return `<span class="${CSS_CLASSES.line} ${CSS_CLASSES.cmdoutLine} ${CSS_CLASSES.cmdoutCmdLine}"${rawAttr}>${promptSpan}${commandSpan}</span>`;
 		}
 	}
 
 	// Output line (not matching prompt pattern)
-	const content = lineContent === '' ? '&nbsp;' : escapeHtml(lineContent);
+	let content: string;
+	if (hasAnsi) {
+		const parsed = parseAnsiLine(lineContent, ansiState.style);
+		ansiState.style = parsed.style;
+		content = buildAnsiHtml(parsed.segments);
+	} else {
+		content = escapeHtml(lineContent);
+	}
+	if (content === '') content = '&nbsp;';
+
+	return `<span class="${CSS_CLASSES.line} ${CSS_CLASSES.cmdoutLine} ${CSS_CLASSES.cmdoutOutput}"${styleStrings.output ? ` style="${styleStrings.output}"` : ''}${rawAttr}>${content}</span>`;
+}
 
-	return `<span class="${CSS_CLASSES.line} ${CSS_CLASSES.cmdoutLine} ${CSS_CLASSES.cmdoutOutput}"${styleStrings.output ? ` style="${styleStrings.output}"` : ''}>${content}</span>`;
+/**
+ * Builds the HTML for runs of ANSI-styled text.
+ *
+ * @param segments - Runs of text from parseAnsiLine
+ * @returns HTML with each styled run in its own span
+ */
+function buildAnsiHtml(segments: AnsiSegment[]): string {
+	return segments
+		.map(segment => {
+			const css = ansiStyleToCss(segment.style);
+			const text = escapeHtml(segment.text);
+			return css ? createStyledSpanHtml(CSS_CLASSES.cmdoutAnsi, text, css) : text;
+		})
+		.join('');
 }
 
 /**
@@ -108,13 +166,16 @@ export function createStyledSpanHtml(className: string, content: string, styleAt
  * @param rawCode - The raw code content
  * @param promptPattern - Regex to identify command lines
  * @param styleStrings - Style strings for each element type
+ * @param renderAnsi - Show ANSI colour codes as coloured text
  * @returns HTML string with all processed lines
  */
 export function processAllOutputLines(
 	rawCode: string,
 	promptPattern: RegExp | undefined,
-	styleStrings: { prompt: string; command: string; output: string }
+	styleStrings: { prompt: string; command: string; output: string },
+	renderAnsi = false
 ): string {
+	const ansiState: AnsiLineState | undefined = renderAnsi ? { style: createAnsiStyle() } : undefined;
 	const lines = rawCode.split('\n');
 
 	// Remove trailing empty line
@@ -123,7 +184,7 @@ export function processAllOutputLines(
 	}
 
 	return lines
-		.map(line => processOutputLine(line, promptPattern, styleStrings))
+		.map(line => processOutputLine(line, promptPattern, styleStrings, ansiState))
 		.join('');
 }
 
@@ -230,7 +291,7 @@ export async function renderCommandOutput(
 	};
 
 	// Process lines
-	const processedHtml = processAllOutputLines(rawCode, options.promptPattern, styleStrings);
+	const processedHtml = processAllOutputLines(rawCode, options.promptPattern, styleStrings, options.renderAnsi);
 
 	// Build structure
 	const container = createOutputContainer();
@@ -265,9 +326,20 @@ export async function renderCommandOutput(
 		addScrollBehaviour(preElement, options.scrollLines);
 	}
 
-	// Add copy button if enabled
+	// Add copy button if enabled (prompts are stripped from the copied text)
 	if (options.showCopyButton) {
-		addCopyButton(preElement);
+		const { onCopied, copyCount } = options;
+		const bumpCopyCount = copyCount !== undefined ? addCopyCountBadge(preElement, copyCount) : undefined;
+
+		addCopyButton(preElement, {
+			promptPattern: options.promptPattern,
+			onCopied: (text: string) => {
+				onCopied?.(text);
+				bumpCopyCount?.();
+			},
+			feedback: options.copyFeedback,
+			copyRawAnsi: options.copyRawAnsi,
+		});
 	}
 
 	return container;
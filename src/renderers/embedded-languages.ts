@@ -0,0 +1,85 @@
+/**
+ * Ultra Code Fence - Embedded Language Renderer
+ *
+ * Re-highlights host tokens that hold code in another language: a Go
+ * string holding SQL gets SQL's keywords and operators, while its quotes
+ * keep the string colour. Runs on the highlighted code before it is
+ * split into lines, and never changes the text.
+ */
+
+import { CSS_CLASSES } from '../constants';
+import type { LanguageInjection, PrismToken, PrismTokenStream } from '../services';
+import { splitStringDelimiters } from '../services';
+
+/**
+ * Tokenizes code as a language.
+ *
+ * @returns Prism tokens, or null if the language has no grammar
+ */
+export type EmbeddedTokenizer = (code: string, language: string) => PrismTokenStream | null;
+
+/**
+ * Adds Prism tokens to an element as token spans.
+ *
+ * @param parent - Element to add to
+ * @param tokens - Token or token stream
+ */
+export function appendPrismTokens(parent: HTMLElement, tokens: string | PrismToken | PrismTokenStream): void {
+	if (typeof tokens === 'string') {
+		parent.appendChild(document.createTextNode(tokens));
+		return;
+	}
+	if (Array.isArray(tokens)) {
+		for (const token of tokens) appendPrismTokens(parent, token);
+		return;
+	}
+
+	const aliases = tokens.alias === undefined ? [] : Array.isArray(tokens.alias) ? tokens.alias : [tokens.alias];
+	const span = document.createElement('span');
+	span.classList.add('token', tokens.type, ...aliases);
+	appendPrismTokens(span, tokens.content);
+	parent.appendChild(span);
+}
+
+/**
+ * Highlights the code embedded in a block's host tokens.
+ *
+ * Only tokens holding plain text are touched, so a host token with its
+ * own inner tokens (interpolations) is left as it is; rules are tried
+ * in order and the first that matches a token wins.
+ *
+ * @param codeElement - Highlighted code element, before line wrapping
+ * @param injections - Rules for the block's language
+ * @param tokenize - Tokenizes code as the embedded language
+ * @returns Number of tokens re-highlighted
+ */
+export function highlightEmbeddedLanguages(
+	codeElement: HTMLElement,
+	injections: readonly LanguageInjection[],
+	tokenize: EmbeddedTokenizer
+): number {
+	let count = 0;
+
+	for (const injection of injections) {
+		codeElement.querySelectorAll<HTMLElement>(`.token.${injection.token}`).forEach(tokenElement => {
+			if (tokenElement.childElementCount > 0) return;
+
+			const { open, body, close } = splitStringDelimiters(tokenElement.textContent ?? '');
+			if (!body || (injection.match && !injection.match.test(body))) return;
+
+			const tokens = tokenize(body, injection.language);
+			if (!tokens) return;
+
+			const embedded = document.createElement('span');
+			embedded.classList.add(CSS_CLASSES.embeddedCode, `language-${injection.language}`);
+			appendPrismTokens(embedded, tokens);
+
+			tokenElement.textContent = open;
+			tokenElement.classList.add(CSS_CLASSES.embeddedHost);
+			tokenElement.appendChild(embedded);
+			if (close) tokenElement.appendChild(document.createTextNode(close));
+			count++;
+		});
+	}
+	return count;
+}
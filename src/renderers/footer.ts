@@ -0,0 +1,70 @@
+/**
+ * Ultra Code Fence - Footer Renderer
+ *
+ * Creates the attribution line shown under a block (FOOTER section),
+ * crediting where quoted code comes from and under which licence.
+ */
+
+import { CSS_CLASSES, HTTPS_PREFIX, HTTP_PREFIX } from '../constants';
+
+/**
+ * What the footer credits.
+ */
+export interface FooterOptions {
+	/** Name of the source, e.g. a project or author */
+	source: string;
+
+	/** Link to the source (http/https only) */
+	url: string;
+
+	/** Licence of the code, e.g. "MIT" */
+	license: string;
+}
+
+/**
+ * Creates the attribution footer, e.g. "Source: lodash · License: MIT".
+ *
+ * The source name links to the URL; with a URL but no name the URL
+ * itself is the link text. URLs that aren't http or https are shown as
+ * plain text rather than linked.
+ *
+ * @param options - Source, URL and licence (empty strings are left out)
+ * @returns Footer element, or null when there is nothing to credit
+ */
+export function createFooterElement(options: FooterOptions): HTMLDivElement | null {
+	const source = options.source.trim();
+	const url = options.url.trim();
+	const license = options.license.trim();
+
+	if (!source && !url && !license) return null;
+
+	const footerElement = document.createElement('div');
+	footerElement.className = CSS_CLASSES.footer;
+
+	if (source || url) {
+		const sourceSpan = document.createElement('span');
+		sourceSpan.appendChild(document.createTextNode('Source: '));
+
+		if (url.startsWith(HTTPS_PREFIX) || url.startsWith(HTTP_PREFIX)) {
+			const link = document.createElement('a');
+			link.className = CSS_CLASSES.footerLink;
+			link.href = url;
+			link.textContent = source || url;
+			link.target = '_blank';
+			link.rel = 'noopener';
+			sourceSpan.appendChild(link);
+		} else {
+			sourceSpan.appendChild(document.createTextNode(source || url));
+		}
+
+		footerElement.appendChild(sourceSpan);
+	}
+
+	if (license) {
+		const licenseSpan = document.createElement('span');
+		licenseSpan.textContent = `License: ${license}`;
+		footerElement.appendChild(licenseSpan);
+	}
+
+	return footerElement;
+}
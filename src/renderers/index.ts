@@ -4,13 +4,24 @@
  * Re-exports all renderer functions for convenient importing.
  */
 
-export type { CodeButtonOptions, DownloadCallback } from './buttons';
+export type { CodeButtonOptions, CopyPipelineOptions, DownloadCallback, PlaceholderFiller } from './buttons';
 
 export {
 	addCopyButton,
+	addLineCopyButtons,
+	addRegionCopyButtons,
+	addCopyAsButton,
 	addFoldButton,
+	addMaxHeightExpander,
+	addCollapseToggle,
+	addWrapToggleButton,
+	addFocusToggleButton,
 	addDownloadButton,
+	addRunButton,
+	addLineRunButtons,
+	addCopyCountBadge,
 	addCodeBlockButtons,
+	moveButtonsToHeader,
 } from './buttons';
 
 export type { TitleBarCreationOptions, TitleContainerOptions } from './title-bar';
@@ -22,10 +33,37 @@ export {
 	buildTitleContainer,
 } from './title-bar';
 
-export type { CodeBlockProcessingOptions } from './code-block';
+export type { CodeBlockProcessingOptions, IndentGuideStyle, RulerStyle, DiffMarking } from './code-block';
 
 export {
 	processCodeBlock,
+	markPrompts,
+	markHighlightedLines,
+	markMatchingLines,
+	markHighlightTerms,
+	markChangedLines,
+	markFocusedLines,
+	addLineAnnotations,
+	markCommentKeywords,
+	addCommentKeywordIcons,
+	addBlameColumn,
+	markDiffLines,
+	markChangedWords,
+	addFoldRegionToggles,
+	addIndentGuides,
+	addColumnRulers,
+	applyTextDirection,
+	addPrintLineNumbers,
+	markWrapIndents,
+	applyHighlightTheme,
+	applyTokenStyles,
+	markPatternStyles,
+	colourBracketPairs,
+	applyFontFeatures,
+	applyFontFace,
+	markWhitespace,
+	markPlaceholders,
+	markRedactions,
 	countSourceLines,
 	wrapPreElement,
 	createCodeBlockProcessingOptions,
@@ -40,3 +78,33 @@ export {
 } from './command-output';
 
 export { injectCallouts } from './callout-renderer';
+
+export type { MinimapViewport } from './minimap';
+
+export { addMinimap, measureMinimapViewport } from './minimap';
+
+export type { LineLinkCallback } from './line-links';
+
+export { addLineLinks, revealLines } from './line-links';
+
+export type { SplitDiffRegions } from './split-diff';
+
+export { addSplitDiffView } from './split-diff';
+
+export type { FooterOptions } from './footer';
+
+export { createFooterElement } from './footer';
+
+export type { EmbeddedTokenizer } from './embedded-languages';
+
+export { appendPrismTokens, highlightEmbeddedLanguages } from './embedded-languages';
+
+export { addSemanticTokens } from './semantic-tokens';
+
+export { createRemoteStatusElement } from './remote-status';
+
+export { createLoadMoreElement } from './load-more';
+
+export type { RunOutputPanel } from './run-output';
+
+export { createRunOutputPanel, describeRunResult, formatRunDuration, renderSavedRunOutput } from './run-output';
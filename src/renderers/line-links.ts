@@ -0,0 +1,98 @@
+/**
+ * Ultra Code Fence - Line Link Renderer
+ *
+ * Makes the lines of a block with an ID addressable by links such as
+ * [[Note#^setup:L42]]: each line is tagged with its number, clicking a
+ * line number hands out a link to it, and a followed link scrolls to its
+ * lines and flashes them.
+ */
+
+import { CSS_CLASSES, LINE_FLASH_DURATION_MS } from '../constants';
+
+/**
+ * Called with the lines a clicked line number links to.
+ */
+export type LineLinkCallback = (startLine: number, endLine: number) => void;
+
+/**
+ * Tags a block and its lines so links can find them.
+ *
+ * Lines are numbered as in the gutter, from startingLineNumber. When
+ * onLinkLines is given, clicking a line number reports that line, and
+ * shift-clicking reports the range from the last clicked line.
+ *
+ * @param preElement - The block's pre element
+ * @param codeElement - Code element with wrapped ucf-line spans
+ * @param blockId - Block ID the links use
+ * @param startingLineNumber - Number of the first line
+ * @param onLinkLines - Called when a line number is clicked
+ */
+export function addLineLinks(
+	preElement: HTMLPreElement,
+	codeElement: HTMLElement,
+	blockId: string,
+	startingLineNumber: number,
+	onLinkLines?: LineLinkCallback
+): void {
+	preElement.dataset.ucfBlockId = blockId;
+
+	codeElement.querySelectorAll<HTMLElement>(`.${CSS_CLASSES.line}`).forEach((lineElement, index) => {
+		lineElement.dataset.ucfLine = String(startingLineNumber + index);
+	});
+
+	if (!onLinkLines || !codeElement.querySelector(`.${CSS_CLASSES.lineNum}`)) return;
+
+	preElement.classList.add(CSS_CLASSES.lineLinks);
+	let lastLine: number | null = null;
+
+	codeElement.addEventListener('click', (event) => {
+		const target = event.target as HTMLElement | null;
+		const lineNumber = target?.closest(`.${CSS_CLASSES.lineNum}`);
+		const line = Number(lineNumber?.closest<HTMLElement>(`.${CSS_CLASSES.line}`)?.dataset.ucfLine);
+		if (!lineNumber || !Number.isInteger(line)) return;
+
+		event.preventDefault();
+		event.stopPropagation();
+
+		const startLine = event.shiftKey && lastLine !== null ? lastLine : line;
+		lastLine = line;
+		onLinkLines(Math.min(startLine, line), Math.max(startLine, line));
+	});
+}
+
+/**
+ * Scrolls to lines of a block and flashes them. A folded or collapsed
+ * block is opened first, as its own toggle would.
+ *
+ * @param preElement - The block's pre element, tagged by addLineLinks
+ * @param startLine - First line
+ * @param endLine - Last line
+ * @returns False when the block has none of the lines
+ */
+export function revealLines(preElement: HTMLElement, startLine: number, endLine: number): boolean {
+	const lineElements = Array.from(preElement.querySelectorAll<HTMLElement>(`code > .${CSS_CLASSES.line}`))
+		.filter(lineElement => {
+			const line = Number(lineElement.dataset.ucfLine);
+			return line >= startLine && line <= endLine;
+		});
+	if (lineElements.length === 0) return false;
+
+	if (preElement.classList.contains(CSS_CLASSES.folded)) {
+		preElement.querySelector<HTMLElement>(`.${CSS_CLASSES.foldButton}`)?.click();
+	}
+	preElement.closest(`.${CSS_CLASSES.collapsed}`)?.querySelector<HTMLElement>(`.${CSS_CLASSES.collapseButton}`)?.click();
+
+	lineElements[0].scrollIntoView({ block: 'center', behavior: 'smooth' });
+
+	lineElements.forEach(lineElement => {
+		// Restart the animation when the same line is linked twice in a row
+		lineElement.classList.remove(CSS_CLASSES.lineFlash);
+		void lineElement.offsetWidth;
+		lineElement.classList.add(CSS_CLASSES.lineFlash);
+	});
+	window.setTimeout(() => {
+		lineElements.forEach(lineElement => lineElement.classList.remove(CSS_CLASSES.lineFlash));
+	}, LINE_FLASH_DURATION_MS);
+
+	return true;
+}
@@ -0,0 +1,41 @@
+/**
+ * Ultra Code Fence - Load More Renderer
+ *
+ * Creates the line under a long embedded file that shows only its first
+ * lines, saying how many are shown, with a button that loads the next
+ * lines.
+ */
+
+import { CSS_CLASSES } from '../constants';
+
+/**
+ * Creates the line, e.g. "Showing 2000 of 20000 lines · Load next 500 lines".
+ *
+ * @param shownLines - Lines shown so far
+ * @param totalLines - Lines in the file
+ * @param chunkLines - Lines each load adds
+ * @param onLoadMore - Shows more lines; the button is disabled once clicked
+ * @returns Load more element
+ */
+export function createLoadMoreElement(shownLines: number, totalLines: number, chunkLines: number, onLoadMore: () => void): HTMLDivElement {
+	const loadMoreElement = document.createElement('div');
+	loadMoreElement.className = CSS_CLASSES.loadMore;
+
+	const textElement = document.createElement('span');
+	textElement.textContent = `Showing ${String(shownLines)} of ${String(totalLines)} lines`;
+	loadMoreElement.appendChild(textElement);
+
+	const nextLines = Math.min(chunkLines, totalLines - shownLines);
+	const loadButton = document.createElement('button');
+	loadButton.className = CSS_CLASSES.loadMoreButton;
+	loadButton.textContent = `Load next ${String(nextLines)} ${nextLines === 1 ? 'line' : 'lines'}`;
+	loadButton.addEventListener('click', (event) => {
+		event.preventDefault();
+		event.stopPropagation();
+		loadButton.disabled = true;
+		onLoadMore();
+	});
+	loadMoreElement.appendChild(loadButton);
+
+	return loadMoreElement;
+}
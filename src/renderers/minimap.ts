@@ -0,0 +1,145 @@
+/**
+ * Ultra Code Fence - Minimap Renderer
+ *
+ * Draws a narrow overview column beside very long blocks (RENDER.MINIMAP),
+ * marking the visible part and the highlighted lines. Clicking the
+ * minimap scrolls to that part of the block.
+ */
+
+import { CSS_CLASSES } from '../constants';
+import { measureIndent } from '../utils';
+
+/** Line length, in columns, that fills the minimap's width. */
+const MINIMAP_COLUMNS = 120;
+
+/**
+ * Visible part of a block, as fractions of its height.
+ */
+export interface MinimapViewport {
+	/** Top of the visible part (0-1) */
+	top: number;
+
+	/** Height of the visible part (0-1) */
+	height: number;
+}
+
+/**
+ * Works out which part of a block is visible.
+ *
+ * A block that scrolls itself (SCROLL) reports its scroll position;
+ * otherwise the part of the block inside the window is used.
+ *
+ * @param preElement - The block's pre element
+ * @returns Visible part of the block
+ */
+export function measureMinimapViewport(preElement: HTMLElement): MinimapViewport {
+	if (preElement.scrollHeight > preElement.clientHeight) {
+		return {
+			top: preElement.scrollTop / preElement.scrollHeight,
+			height: preElement.clientHeight / preElement.scrollHeight,
+		};
+	}
+
+	const rect = preElement.getBoundingClientRect();
+	if (rect.height <= 0) return { top: 0, height: 1 };
+
+	const visibleTop = Math.min(Math.max(-rect.top, 0), rect.height);
+	const visibleBottom = Math.min(Math.max(window.innerHeight - rect.top, 0), rect.height);
+
+	return {
+		top: visibleTop / rect.height,
+		height: Math.max(visibleBottom - visibleTop, 0) / rect.height,
+	};
+}
+
+/**
+ * Creates one minimap bar, shaped like its line's indent and length.
+ *
+ * @param lineElement - A wrapped ucf-line span
+ * @returns Bar element
+ */
+function createMinimapLine(lineElement: HTMLElement): HTMLDivElement {
+	const lineText = lineElement.querySelector(`.${CSS_CLASSES.lineContent}`)?.textContent ?? '';
+	const indent = Math.min(measureIndent(lineText) ?? 0, MINIMAP_COLUMNS);
+	const length = Math.min(lineText.trimEnd().length, MINIMAP_COLUMNS);
+
+	const bar = document.createElement('div');
+	bar.className = CSS_CLASSES.minimapLine;
+	bar.style.setProperty('--ucf-minimap-indent', `${String((indent / MINIMAP_COLUMNS) * 100)}%`);
+	bar.style.setProperty('--ucf-minimap-length', `${String((Math.max(length - indent, 0) / MINIMAP_COLUMNS) * 100)}%`);
+
+	if (lineElement.classList.contains(CSS_CLASSES.lineHighlight)) {
+		bar.classList.add(CSS_CLASSES.minimapHighlight);
+	}
+
+	return bar;
+}
+
+/**
+ * Adds a minimap column beside a block.
+ *
+ * The pre element is wrapped in a frame that holds the minimap next to
+ * it, so the minimap stays put while the code scrolls.
+ *
+ * @param preElement - The block's pre element, with wrapped ucf-line spans
+ * @returns The minimap, or null when the block has no wrapped lines
+ */
+export function addMinimap(preElement: HTMLPreElement): HTMLElement | null {
+	const lineElements = Array.from(preElement.querySelectorAll<HTMLElement>(`code > .${CSS_CLASSES.line}`));
+	if (lineElements.length === 0) return null;
+
+	const frame = document.createElement('div');
+	frame.className = CSS_CLASSES.minimapFrame;
+
+	const minimap = document.createElement('div');
+	minimap.className = CSS_CLASSES.minimap;
+	minimap.setAttribute('aria-hidden', 'true');
+	lineElements.forEach(lineElement => minimap.appendChild(createMinimapLine(lineElement)));
+
+	const viewport = document.createElement('div');
+	viewport.className = CSS_CLASSES.minimapViewport;
+	minimap.appendChild(viewport);
+
+	const updateViewport = (): void => {
+		const { top, height } = measureMinimapViewport(preElement);
+		viewport.style.setProperty('--ucf-minimap-top', `${String(top * 100)}%`);
+		viewport.style.setProperty('--ucf-minimap-height', `${String(height * 100)}%`);
+	};
+
+	// Notes scroll inside Obsidian's panes, so listen for any scroll and
+	// stop once the block has been removed
+	let wasConnected = false;
+	const onScroll = (): void => {
+		if (!minimap.isConnected) {
+			if (wasConnected) document.removeEventListener('scroll', onScroll, true);
+			return;
+		}
+		wasConnected = true;
+		updateViewport();
+	};
+	document.addEventListener('scroll', onScroll, { capture: true, passive: true });
+	frame.addEventListener('mouseenter', updateViewport);
+
+	minimap.addEventListener('click', (event) => {
+		event.preventDefault();
+		event.stopPropagation();
+
+		const rect = minimap.getBoundingClientRect();
+		const fraction = rect.height > 0 ? Math.min(Math.max((event.clientY - rect.top) / rect.height, 0), 1) : 0;
+
+		if (preElement.scrollHeight > preElement.clientHeight) {
+			preElement.scrollTop = (fraction * preElement.scrollHeight) - (preElement.clientHeight / 2);
+		} else {
+			const index = Math.min(Math.floor(fraction * lineElements.length), lineElements.length - 1);
+			lineElements[index].scrollIntoView({ block: 'center', behavior: 'smooth' });
+		}
+		updateViewport();
+	});
+
+	preElement.parentElement?.insertBefore(frame, preElement);
+	frame.appendChild(preElement);
+	frame.appendChild(minimap);
+	updateViewport();
+
+	return minimap;
+}
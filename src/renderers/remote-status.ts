@@ -0,0 +1,49 @@
+/**
+ * Ultra Code Fence - Remote Status Renderer
+ *
+ * Creates the line under a block loaded from a URL saying how old the
+ * code shown is, with a button that fetches it again.
+ */
+
+import { CSS_CLASSES } from '../constants';
+import type { RemoteSourceStatus } from '../services';
+import { formatFetchAge } from '../services';
+
+/**
+ * Creates the status line, e.g. "Fetched 5 min ago · Refresh".
+ *
+ * When the fetch failed and the cached copy is shown it says so, and
+ * the line is marked stale so a theme can make it stand out.
+ *
+ * @param status - Fetch time and offline state of the code
+ * @param now - The current time (ms since the epoch)
+ * @param onRefresh - Fetches the file again; the button is disabled until it settles
+ * @returns Status element
+ */
+export function createRemoteStatusElement(status: RemoteSourceStatus, now: number, onRefresh: () => Promise<void>): HTMLDivElement {
+	const statusElement = document.createElement('div');
+	statusElement.className = CSS_CLASSES.remoteStatus;
+	statusElement.classList.toggle(CSS_CLASSES.remoteStatusStale, status.offline);
+	statusElement.title = status.url;
+
+	const ageText = formatFetchAge(Math.max(0, now - status.fetchedAt));
+	const textElement = document.createElement('span');
+	textElement.textContent = status.offline
+		? `Offline: showing the copy fetched ${ageText}`
+		: `Fetched ${ageText}`;
+	statusElement.appendChild(textElement);
+
+	const refreshButton = document.createElement('button');
+	refreshButton.className = CSS_CLASSES.remoteRefresh;
+	refreshButton.textContent = 'Refresh';
+	refreshButton.setAttribute('aria-label', 'Fetch the file again');
+	refreshButton.addEventListener('click', (event) => {
+		event.preventDefault();
+		event.stopPropagation();
+		refreshButton.disabled = true;
+		void onRefresh().finally(() => { refreshButton.disabled = false; });
+	});
+	statusElement.appendChild(refreshButton);
+
+	return statusElement;
+}
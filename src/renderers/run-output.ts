@@ -0,0 +1,150 @@
+/**
+ * Ultra Code Fence - Run Output Renderer
+ *
+ * Creates the panel under a block that has been run, showing its
+ * output as it arrives, stderr set apart, and how the run ended. Output
+ * saved in the note (a ufence-output block) is shown the same way.
+ */
+
+import { CSS_CLASSES, RUN_OUTPUT_MAX_LENGTH } from '../constants';
+import type { RunResult } from '../services';
+import type { SavedRunOutput } from '../utils';
+
+/** A run's output panel, filled in as the run goes. */
+export interface RunOutputPanel {
+	/** The panel element */
+	element: HTMLDivElement;
+
+	/** Adds output; stderr is marked so it can be told apart */
+	append: (text: string, stream: 'stdout' | 'stderr') => void;
+
+	/** Says how the run ended, and takes away the stop button */
+	finish: (exitCode: number | null, errorMessage?: string, stopped?: RunResult['stopped']) => void;
+}
+
+/**
+ * Formats how long a run took, e.g. "30s", "2m" or "1m 30s".
+ *
+ * @param durationMs - Duration in milliseconds
+ * @returns Short duration text
+ */
+export function formatRunDuration(durationMs: number): string {
+	const seconds = Math.round(durationMs / 1000);
+	if (seconds < 60) return `${String(seconds)}s`;
+
+	const minutes = Math.floor(seconds / 60);
+	const rest = seconds % 60;
+	return rest ? `${String(minutes)}m ${String(rest)}s` : `${String(minutes)}m`;
+}
+
+/**
+ * Describes how a run ended, e.g. "Exited with 0", "Failed with exit
+ * code 2" or "Killed after 30s".
+ *
+ * @param exitCode - Exit code (null when there is none)
+ * @param errorMessage - Why the process couldn't be started, if it couldn't
+ * @param stopped - Why the run was stopped early, if it was
+ * @returns Status text
+ */
+export function describeRunResult(exitCode: number | null, errorMessage?: string, stopped?: RunResult['stopped']): string {
+	if (errorMessage) return `Failed: ${errorMessage}`;
+	if (stopped?.reason === 'timeout') return `Killed after ${formatRunDuration(stopped.afterMs)}`;
+	if (stopped?.reason === 'cancelled') return `Cancelled after ${formatRunDuration(stopped.afterMs)}`;
+	if (exitCode === null) return 'Stopped';
+	return exitCode === 0 ? 'Exited with 0' : `Failed with exit code ${String(exitCode)}`;
+}
+
+/**
+ * Creates an output panel, showing "Running…" until the run finishes.
+ *
+ * @param onClear - Called when the panel's clear button is clicked, after it is removed
+ * @param onStop - Cancels the run; shows a stop button while it runs (omitted = no button)
+ * @returns The panel
+ */
+export function createRunOutputPanel(onClear?: () => void, onStop?: () => void): RunOutputPanel {
+	const element = document.createElement('div');
+	element.className = CSS_CLASSES.runOutput;
+
+	const headerElement = document.createElement('div');
+	headerElement.className = CSS_CLASSES.runOutputStatus;
+	const statusElement = document.createElement('span');
+	statusElement.textContent = 'Running…';
+	headerElement.appendChild(statusElement);
+
+	const stopButton = document.createElement('button');
+	stopButton.className = CSS_CLASSES.runOutputStop;
+	stopButton.textContent = 'Stop';
+	stopButton.addEventListener('click', (event) => {
+		event.preventDefault();
+		event.stopPropagation();
+		stopButton.disabled = true;
+		statusElement.textContent = 'Stopping…';
+		onStop?.();
+	});
+	if (onStop) headerElement.appendChild(stopButton);
+
+	const clearButton = document.createElement('button');
+	clearButton.className = CSS_CLASSES.runOutputClear;
+	clearButton.textContent = 'Clear';
+	clearButton.addEventListener('click', (event) => {
+		event.preventDefault();
+		event.stopPropagation();
+		element.remove();
+		onClear?.();
+	});
+	headerElement.appendChild(clearButton);
+	element.appendChild(headerElement);
+
+	const textElement = document.createElement('pre');
+	textElement.className = CSS_CLASSES.runOutputText;
+	element.appendChild(textElement);
+
+	const shown = { length: 0 };
+	const append = (text: string, stream: 'stdout' | 'stderr'): void => {
+		if (shown.length >= RUN_OUTPUT_MAX_LENGTH) return;
+
+		const room = RUN_OUTPUT_MAX_LENGTH - shown.length;
+		const kept = text.length > room ? `${text.slice(0, room)}\n… output truncated` : text;
+		shown.length = Math.min(RUN_OUTPUT_MAX_LENGTH, shown.length + text.length);
+
+		const chunkElement = document.createElement('span');
+		if (stream === 'stderr') chunkElement.className = CSS_CLASSES.runOutputStderr;
+		chunkElement.textContent = kept;
+		textElement.appendChild(chunkElement);
+	};
+
+	const finish = (exitCode: number | null, errorMessage?: string, stopped?: RunResult['stopped']): void => {
+		stopButton.remove();
+		statusElement.textContent = describeRunResult(exitCode, errorMessage, stopped);
+		element.classList.toggle(CSS_CLASSES.runOutputFailed, exitCode !== 0 || stopped !== undefined);
+	};
+
+	return { element, append, finish };
+}
+
+/**
+ * Renders output saved in the note: its status, then what the run
+ * printed. A status other than a clean exit is shown as failed.
+ *
+ * @param saved - Status and output read from the ufence-output block
+ * @returns The panel element
+ */
+export function renderSavedRunOutput(saved: SavedRunOutput): HTMLDivElement {
+	const element = document.createElement('div');
+	element.className = `${CSS_CLASSES.runOutput} ${CSS_CLASSES.runOutputSaved}`;
+	element.classList.toggle(CSS_CLASSES.runOutputFailed, saved.status !== describeRunResult(0));
+
+	const statusElement = document.createElement('div');
+	statusElement.className = CSS_CLASSES.runOutputStatus;
+	statusElement.textContent = saved.status;
+	element.appendChild(statusElement);
+
+	if (saved.output) {
+		const textElement = document.createElement('pre');
+		textElement.className = CSS_CLASSES.runOutputText;
+		textElement.textContent = saved.output;
+		element.appendChild(textElement);
+	}
+
+	return element;
+}
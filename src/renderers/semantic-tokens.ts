@@ -0,0 +1,41 @@
+/**
+ * Ultra Code Fence - Semantic Token Renderer
+ *
+ * Adds IDE-style tokens to highlighted Go, TypeScript/JavaScript and
+ * Python: function definitions, parameters (where declared and where
+ * used) and field reads. Each becomes a `span.token.<name>` inside
+ * Prism's spans, so TOKENS and themes can style it like any token. Runs
+ * on the highlighted code before it is split into lines, and never
+ * changes the text.
+ */
+
+import { CSS_CLASSES } from '../constants';
+import { findSemanticTokens, wrapTextRange } from '../utils';
+
+/** Tokens whose text isn't code (template strings included, interpolations and all). */
+const NON_CODE_TOKENS = '.token.string, .token.template-string, .token.comment, .token.regex, .token.char';
+
+/**
+ * Adds semantic tokens to a block's highlighted code.
+ *
+ * @param codeElement - Highlighted code element, before line wrapping
+ * @param language - The block's language (after aliases)
+ * @returns Number of tokens added
+ */
+export function addSemanticTokens(codeElement: HTMLElement, language: string): number {
+	// The code with strings and comments blanked, so their text isn't read as code
+	let code = '';
+	const walker = document.createTreeWalker(codeElement, NodeFilter.SHOW_TEXT);
+	while (walker.nextNode()) {
+		const text = walker.currentNode.textContent ?? '';
+		const container = walker.currentNode.parentElement;
+		code += container?.closest(NON_CODE_TOKENS) ? text.replace(/[^\n]/g, ' ') : text;
+	}
+
+	const tokens = findSemanticTokens(code, language);
+	for (const token of tokens) {
+		wrapTextRange(codeElement, token.start, token.end, `token ${token.type}`);
+	}
+	if (tokens.length > 0) codeElement.classList.add(CSS_CLASSES.semantic);
+	return tokens.length;
+}
@@ -0,0 +1,172 @@
+/**
+ * Ultra Code Fence - Split Diff Renderer
+ *
+ * Shows a diff block as two columns, the old version on the left and
+ * the new one on the right (DIFF.VIEW: split). The columns are built
+ * from a unified diff, or from two named regions inside the block
+ * (DIFF.OLD and DIFF.NEW), and scroll sideways together.
+ */
+
+import { CSS_CLASSES } from '../constants';
+import { buildSplitDiffRows, diffLineLists, findRegionLineIndices, unifiedDiffSteps } from '../utils';
+import type { DiffLineKind, DiffStep, SplitDiffRow } from '../utils';
+import { markChangedWords } from './code-block';
+
+/**
+ * Regions holding the two versions of a split diff.
+ */
+export interface SplitDiffRegions {
+	/** Region with the old version */
+	oldRegion: string;
+
+	/** Region with the new version */
+	newRegion: string;
+}
+
+/**
+ * Reads the kind of each marked diff line from its classes.
+ *
+ * @param lineElement - A ucf-line span marked by markDiffLines
+ * @returns Kind of the line
+ */
+function readDiffLineKind(lineElement: HTMLElement): DiffLineKind {
+	if (lineElement.classList.contains(CSS_CLASSES.diffAdded)) return 'added';
+	if (lineElement.classList.contains(CSS_CLASSES.diffRemoved)) return 'removed';
+	if (lineElement.classList.contains(CSS_CLASSES.diffHunk)) return 'hunk';
+	if (lineElement.classList.contains(CSS_CLASSES.diffHeader)) return 'header';
+	return 'context';
+}
+
+/**
+ * Compares two regions of a block line by line.
+ *
+ * @param lineTexts - Line texts of the whole block
+ * @param regions - Names of the old and new regions
+ * @returns Edit script with indices into the whole block, or null when a region is missing
+ */
+function diffRegions(lineTexts: string[], regions: SplitDiffRegions): DiffStep[] | null {
+	const oldIndices = findRegionLineIndices(lineTexts, regions.oldRegion);
+	const newIndices = findRegionLineIndices(lineTexts, regions.newRegion);
+	if (oldIndices.length === 0 || newIndices.length === 0) return null;
+
+	const steps = diffLineLists(
+		oldIndices.map(index => lineTexts[index]),
+		newIndices.map(index => lineTexts[index])
+	);
+
+	return steps.map(step => ({
+		kind: step.kind,
+		oldIndex: step.oldIndex === null ? null : oldIndices[step.oldIndex],
+		newIndex: step.newIndex === null ? null : newIndices[step.newIndex],
+	}));
+}
+
+/**
+ * Creates one line of a split diff column.
+ *
+ * @param lineElement - The block line to show, or undefined for a gap
+ * @param changeClass - Added or removed class for a changed line
+ * @returns Line element
+ */
+function createSplitLine(lineElement: HTMLElement | undefined, changeClass: string | null): HTMLElement {
+	if (!lineElement) {
+		const filler = document.createElement('span');
+		filler.className = `${CSS_CLASSES.line} ${CSS_CLASSES.splitDiffFiller}`;
+		filler.setAttribute('aria-hidden', 'true');
+		filler.textContent = '\u00a0';
+		return filler;
+	}
+
+	const copy = lineElement.cloneNode(true) as HTMLElement;
+	if (changeClass) {
+		copy.classList.remove(CSS_CLASSES.diffAdded, CSS_CLASSES.diffRemoved);
+		copy.classList.add(changeClass);
+	}
+	return copy;
+}
+
+/**
+ * Replaces a diff block's code with old and new columns.
+ *
+ * The columns hold copies of the rendered lines, so highlighting and
+ * markers carry over. The original code element stays in the block,
+ * hidden, so copying still copies the block as written.
+ *
+ * @param preElement - The block's pre element
+ * @param codeElement - Code element with lines marked by markDiffLines
+ * @param regions - Regions holding the two versions (omit to split a unified diff)
+ * @param markWords - Emphasise the changed words between the regions (a
+ *   unified diff's lines were marked by markDiffLines, and copies keep that)
+ * @returns The split view, or null when there is nothing to split
+ */
+export function addSplitDiffView(
+	preElement: HTMLPreElement,
+	codeElement: HTMLElement,
+	regions?: SplitDiffRegions,
+	markWords = false
+): HTMLElement | null {
+	const lineElements = Array.from(codeElement.querySelectorAll<HTMLElement>(`.${CSS_CLASSES.line}`));
+	if (lineElements.length === 0) return null;
+
+	let steps: DiffStep[] | null;
+	if (regions) {
+		const lineTexts = lineElements.map(lineElement =>
+			lineElement.querySelector(`.${CSS_CLASSES.lineContent}`)?.textContent ?? ''
+		);
+		steps = diffRegions(lineTexts, regions);
+	} else {
+		steps = unifiedDiffSteps(lineElements.map(readDiffLineKind));
+	}
+
+	const rows: SplitDiffRow[] = steps ? buildSplitDiffRows(steps) : [];
+	if (rows.length === 0) return null;
+
+	const container = document.createElement('div');
+	container.className = CSS_CLASSES.splitDiff;
+
+	const createSide = (label: string): HTMLElement => {
+		const side = document.createElement('code');
+		side.className = `${CSS_CLASSES.splitDiffSide} ${codeElement.className}`.trim();
+		side.setAttribute('aria-label', label);
+		container.appendChild(side);
+		return side;
+	};
+	const oldSide = createSide('Before');
+	const newSide = createSide('After');
+
+	rows.forEach(row => {
+		const oldLine = row.oldIndex === null ? undefined : lineElements[row.oldIndex];
+		const newLine = row.newIndex === null ? undefined : lineElements[row.newIndex];
+		const isChanged = row.kind === 'changed';
+
+		const oldCopy = oldSide.appendChild(createSplitLine(oldLine, isChanged ? CSS_CLASSES.diffRemoved : null));
+		const newCopy = newSide.appendChild(createSplitLine(newLine, isChanged ? CSS_CLASSES.diffAdded : null));
+
+		if (regions && markWords && isChanged && oldLine && newLine) {
+			const oldContent = oldCopy.querySelector<HTMLElement>(`.${CSS_CLASSES.lineContent}`);
+			const newContent = newCopy.querySelector<HTMLElement>(`.${CSS_CLASSES.lineContent}`);
+			if (oldContent && newContent) {
+				markChangedWords(oldContent, newContent);
+			}
+		}
+	});
+
+	// Keep both columns at the same horizontal position
+	let isSyncing = false;
+	const syncScroll = (source: HTMLElement, target: HTMLElement): void => {
+		if (isSyncing) {
+			isSyncing = false;
+			return;
+		}
+		if (target.scrollLeft === source.scrollLeft) return;
+		isSyncing = true;
+		target.scrollLeft = source.scrollLeft;
+	};
+	oldSide.addEventListener('scroll', () => { syncScroll(oldSide, newSide); }, { passive: true });
+	newSide.addEventListener('scroll', () => { syncScroll(newSide, oldSide); }, { passive: true });
+
+	codeElement.insertAdjacentElement('afterend', container);
+	preElement.classList.add(CSS_CLASSES.splitDiffActive);
+
+	return container;
+}
@@ -5,7 +5,7 @@
  * Supports multiple visual styles and optional descriptions.
  */
 
-import { App, MarkdownRenderer, Component, TFile } from 'obsidian';
+import { App, MarkdownRenderer, Component, TFile, setIcon } from 'obsidian';
 import type { TitleBarStyle, SourceFileMetadata, PluginSettings, DescriptionDisplayMode } from '../types';
 import { CSS_CLASSES, styleClass } from '../constants';
 import { createIconFromSettings } from '../services';
@@ -39,6 +39,18 @@ export interface TitleBarCreationOptions {
 
 	/** Path of the containing note (for wiki links) */
 	containingNotePath?: string;
+
+	/** Emoji or Lucide icon name replacing the file icon ("none" = no icon) */
+	headerIcon?: string;
+
+	/** Show the title text (default: true) */
+	showTitleText?: boolean;
+
+	/** Show a badge with the language */
+	showLanguageBadge?: boolean;
+
+	/** Makes the badge a button, for correcting a detected language */
+	onLanguageBadgeClick?: (event: MouseEvent) => void;
 }
 
 /**
@@ -56,13 +68,16 @@ export function createTitleBarElement(
 	options: TitleBarCreationOptions,
 	_component: Component
 ): HTMLDivElement {
-	const { titleText, clickablePath, titleBarStyle, fileMetadata, language } = options;
+	const { clickablePath, titleBarStyle, fileMetadata, language } = options;
+	const titleText = options.showTitleText === false ? '' : options.titleText;
 
 	const titleElement = document.createElement('div');
 	titleElement.className = `${CSS_CLASSES.title} ${styleClass(titleBarStyle)}`;
 
-	// Add file icon if enabled
-	const iconElement = createIconFromSettings(app, settings, language, fileMetadata?.extension);
+	// Add the block's own icon, or the file icon if enabled
+	const iconElement = options.headerIcon !== undefined
+		? createHeaderIconElement(options.headerIcon)
+		: createIconFromSettings(app, settings, language, fileMetadata?.extension);
 
 	if (iconElement) {
 		titleElement.appendChild(iconElement);
@@ -75,6 +90,27 @@ export function createTitleBarElement(
 		buildStandardTitleLayout(titleElement, titleText, clickablePath, settings);
 	}
 
+	if (options.showLanguageBadge && language) {
+		const badge = document.createElement('span');
+		badge.className = CSS_CLASSES.languageBadge;
+		badge.textContent = language;
+
+		const onBadgeClick = options.onLanguageBadgeClick;
+		if (onBadgeClick) {
+			badge.classList.add(CSS_CLASSES.languageBadgeDetected);
+			badge.setAttribute('role', 'button');
+			badge.setAttribute('aria-label', `Detected language: ${language}. Click to change`);
+			badge.setAttribute('title', 'Detected language; click to change');
+			// Don't let the click reach the title's link handler
+			badge.addEventListener('click', (event) => {
+				event.preventDefault();
+				event.stopPropagation();
+				onBadgeClick(event);
+			});
+		}
+		titleElement.appendChild(badge);
+	}
+
 	// Make title clickable if path provided
 	if (clickablePath) {
 		setupTitleClickHandler(app, titleElement, clickablePath);
@@ -83,6 +119,32 @@ export function createTitleBarElement(
 	return titleElement;
 }
 
+/**
+ * Creates the icon given in HEADER.ICON.
+ *
+ * A lowercase name like "terminal" is drawn as a Lucide icon; anything
+ * else (e.g. an emoji) is shown as text.
+ *
+ * @param icon - Emoji or Lucide icon name, or "none"
+ * @returns Icon element, or null for "none"
+ */
+export function createHeaderIconElement(icon: string): HTMLSpanElement | null {
+	const trimmed = icon.trim();
+	if (trimmed === '' || trimmed.toLowerCase() === 'none') return null;
+
+	const iconElement = document.createElement('span');
+	iconElement.className = CSS_CLASSES.icon;
+
+	if (/^[a-z][a-z0-9-]*$/.test(trimmed)) {
+		iconElement.classList.add(CSS_CLASSES.iconLucide);
+		setIcon(iconElement, trimmed);
+	} else {
+		iconElement.textContent = trimmed;
+	}
+
+	return iconElement;
+}
+
 /**
  * Builds the infobar style title (icon + text on left, metadata on right).
  */
@@ -0,0 +1,49 @@
+/**
+ * Ultra Code Fence - Block Change Detection
+ *
+ * Compares a note's ufence blocks before and after an edit, to find the
+ * blocks that need re-rendering that Obsidian won't re-render itself.
+ *
+ * Obsidian re-runs a code block processor when the text between the
+ * fences changes, but not when only the fence line changes (e.g. shorthand
+ * options or the settings format), and never for blocks whose page-level
+ * ufence-ufence defaults changed.
+ */
+
+import type { UfenceBlock } from './fence-lint';
+
+/** Blocks that need re-rendering after an edit. */
+export interface BlockChanges {
+	/** The ufence-ufence block changed, so every block on the page is affected */
+	pageConfigChanged: boolean;
+	/** Blocks whose fence line changed but whose content did not */
+	fenceLineChanged: UfenceBlock[];
+}
+
+/**
+ * Finds the blocks affected by an edit.
+ *
+ * @param previous - Blocks before the edit
+ * @param current - Blocks after the edit
+ * @returns Affected blocks
+ */
+export function diffUfenceBlocks(previous: UfenceBlock[], current: UfenceBlock[]): BlockChanges {
+	const pageConfigText = (blocks: UfenceBlock[]): string => blocks
+		.filter(block => block.blockType === 'ufence')
+		.map(block => `${block.fenceLine}\n${block.content}`)
+		.join('\n\0\n');
+
+	const previousBlocks = new Set(previous.map(block => `${block.fenceLine}\n${block.content}`));
+	const previousContents = new Set(previous.map(block => block.content));
+
+	const fenceLineChanged = current.filter(block =>
+		block.blockType !== 'ufence'
+		&& !previousBlocks.has(`${block.fenceLine}\n${block.content}`)
+		&& previousContents.has(block.content)
+	);
+
+	return {
+		pageConfigChanged: pageConfigText(previous) !== pageConfigText(current),
+		fenceLineChanged,
+	};
+}
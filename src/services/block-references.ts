@@ -0,0 +1,186 @@
+/**
+ * Ultra Code Fence - Block References
+ *
+ * Lets a block show another block by its ID (META.REF), so a runbook
+ * step written once can be shown in many notes. A block's ID is its
+ * META.ID or the Obsidian block ID on the line after its closing fence,
+ * as for line links. The referring block's own settings are laid over
+ * the referenced block's, so a note can retitle it or turn on line
+ * numbers without copying it.
+ */
+
+import { parseBlockContent, parseConfigFormatFromInfoString, parseInfoStringOptions, mergeInfoStringOptions } from '../parsers';
+import { BLOCK_ID_LINE_PATTERN, YAML_META, YAML_SECTIONS } from '../constants';
+import type { UfenceBlock } from './fence-lint';
+import { findUfenceBlocks } from './fence-lint';
+
+/** A block with an ID, and the note it is in. */
+export interface BlockReference {
+	/** Vault path of the note */
+	path: string;
+
+	/** The block's ID */
+	id: string;
+
+	/** The block */
+	block: UfenceBlock;
+}
+
+/** Types of ufence block that can't be shown by reference. */
+const UNREFERENCEABLE_BLOCK_TYPES = new Set(['ufence', 'cmdout']);
+
+// =============================================================================
+// Settings
+// =============================================================================
+
+/**
+ * Reads a block's settings, fence line shorthand included. As in the
+ * default cascade, the shorthand wins over the settings section.
+ *
+ * @param block - The block
+ * @returns Settings, or null if they don't parse
+ */
+export function readBlockSettings(block: UfenceBlock): Record<string, unknown> | null {
+	try {
+		const parsed = parseBlockContent(block.content, parseConfigFormatFromInfoString(block.fenceLine));
+		return mergeInfoStringOptions(parsed.yamlProperties, parseInfoStringOptions(block.fenceLine).settings);
+	} catch {
+		return null;
+	}
+}
+
+/**
+ * Reads a META key from settings.
+ *
+ * @param settings - Block settings
+ * @param key - Key under META
+ * @returns The trimmed value, or "" if unset
+ */
+export function readMetaText(settings: Record<string, unknown>, key: string): string {
+	const meta = settings[YAML_SECTIONS.meta];
+	const value = meta && typeof meta === 'object' ? (meta as Record<string, unknown>)[key] : undefined;
+	return typeof value === 'string' || typeof value === 'number' ? String(value).trim() : '';
+}
+
+/**
+ * Reads the ID of the block a block refers to.
+ *
+ * @param settings - Block settings
+ * @returns The META.REF ID, or "" if the block refers to none
+ */
+export function readReferenceId(settings: Record<string, unknown>): string {
+	return readMetaText(settings, YAML_META.ref);
+}
+
+/**
+ * Lays a referring block's settings over the referenced block's. The
+ * referenced block's ID and reference aren't carried over, so the
+ * referring block neither takes the ID nor follows a chain of references.
+ *
+ * @param referencedSettings - Settings of the referenced block
+ * @param localSettings - Settings of the referring block
+ * @returns Merged settings; the referring block's win
+ */
+export function mergeReferencedSettings(
+	referencedSettings: Record<string, unknown>,
+	localSettings: Record<string, unknown>
+): Record<string, unknown> {
+	const merged = mergeInfoStringOptions(referencedSettings, localSettings);
+	const meta = merged[YAML_SECTIONS.meta];
+	if (meta && typeof meta === 'object') {
+		const mergedMeta = { ...(meta as Record<string, unknown>) };
+		Reflect.deleteProperty(mergedMeta, YAML_META.ref);
+		if (readMetaText(localSettings, YAML_META.id) === '') {
+			Reflect.deleteProperty(mergedMeta, YAML_META.id);
+		}
+		merged[YAML_SECTIONS.meta] = mergedMeta;
+	}
+	return merged;
+}
+
+// =============================================================================
+// Finding Blocks by ID
+// =============================================================================
+
+/**
+ * Finds the blocks in a note that have an ID. Page settings and cmdout
+ * blocks, and blocks that themselves refer to another, are left out.
+ *
+ * @param content - Note content
+ * @returns Each block with its ID, in document order
+ */
+export function findReferenceableBlocks(content: string): { id: string; block: UfenceBlock }[] {
+	const lines = content.split('\n');
+	const found: { id: string; block: UfenceBlock }[] = [];
+
+	for (const block of findUfenceBlocks(content)) {
+		if (UNREFERENCEABLE_BLOCK_TYPES.has(block.blockType)) continue;
+
+		const settings = readBlockSettings(block);
+		if (!settings || readReferenceId(settings)) continue;
+
+		const id = findBlockId(block, settings, lines);
+		if (id) found.push({ id, block });
+	}
+
+	return found;
+}
+
+/**
+ * Finds a block's ID: its META.ID, or the block ID on the line after it.
+ *
+ * @param block - The block
+ * @param settings - The block's settings
+ * @param lines - Lines of the note
+ * @returns The ID, or "" if the block has none
+ */
+export function findBlockId(block: UfenceBlock, settings: Record<string, unknown>, lines: string[]): string {
+	const idLine = block.endLine < lines.length ? lines[block.endLine] : '';
+	return readMetaText(settings, YAML_META.id) || (BLOCK_ID_LINE_PATTERN.exec(idLine)?.[1] ?? '');
+}
+
+/**
+ * The blocks with IDs across the vault, kept up to date note by note.
+ * When two blocks share an ID, the one in the first note by path wins.
+ */
+export class BlockReferenceIndex {
+	private notes = new Map<string, { id: string; block: UfenceBlock }[]>();
+
+	/**
+	 * Reads a note's blocks, replacing what was known of it.
+	 *
+	 * @param path - Vault path of the note
+	 * @param content - Note content
+	 */
+	update(path: string, content: string): void {
+		const blocks = findReferenceableBlocks(content);
+		if (blocks.length > 0) {
+			this.notes.set(path, blocks);
+		} else {
+			this.notes.delete(path);
+		}
+	}
+
+	/**
+	 * Forgets a note's blocks.
+	 *
+	 * @param path - Vault path of the note
+	 */
+	remove(path: string): void {
+		this.notes.delete(path);
+	}
+
+	/**
+	 * Finds a block by ID.
+	 *
+	 * @param id - Block ID
+	 * @returns The block and its note, or null if no block has the ID
+	 */
+	find(id: string): BlockReference | null {
+		for (const path of [...this.notes.keys()].sort()) {
+			const entry = this.notes.get(path)?.find(candidate => candidate.id === id);
+			if (entry) return { path, ...entry };
+		}
+		return null;
+	}
+}
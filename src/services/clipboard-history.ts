@@ -0,0 +1,73 @@
+/**
+ * Ultra Code Fence - Clipboard History
+ *
+ * Keeps the most recent copies from code blocks so they can be copied
+ * again from the command palette. The history lives in memory only and
+ * is cleared when Obsidian restarts.
+ */
+
+import type { ClipboardHistoryEntry } from '../types';
+
+// =============================================================================
+// History
+// =============================================================================
+
+/**
+ * Most-recent-first list of code block copies, capped at a maximum size.
+ */
+export class ClipboardHistory {
+	private entries: ClipboardHistoryEntry[] = [];
+	private maxEntries: number;
+
+	/**
+	 * Creates an empty history.
+	 *
+	 * @param maxEntries - Number of copies to keep (0 = history disabled)
+	 */
+	constructor(maxEntries: number) {
+		this.maxEntries = Math.max(maxEntries, 0);
+	}
+
+	/**
+	 * Changes the maximum size, dropping the oldest entries if needed.
+	 *
+	 * @param maxEntries - Number of copies to keep (0 = history disabled)
+	 */
+	setMaxEntries(maxEntries: number): void {
+		this.maxEntries = Math.max(maxEntries, 0);
+		this.entries = this.entries.slice(0, this.maxEntries);
+	}
+
+	/**
+	 * Records a copy as the newest entry.
+	 *
+	 * Copying the same text again moves the existing entry to the top
+	 * instead of adding a duplicate. Blank text is ignored.
+	 *
+	 * @param text - Text placed on the clipboard
+	 * @param source - Where it was copied from (block title or note name)
+	 */
+	record(text: string, source: string): void {
+		if (this.maxEntries === 0 || text.trim() === '') return;
+
+		this.entries = this.entries.filter(entry => entry.text !== text);
+		this.entries.unshift({ text, source, copiedAt: Date.now() });
+		this.entries = this.entries.slice(0, this.maxEntries);
+	}
+
+	/**
+	 * Lists the recorded copies, newest first.
+	 *
+	 * @returns A copy of the history entries
+	 */
+	getEntries(): ClipboardHistoryEntry[] {
+		return [...this.entries];
+	}
+
+	/**
+	 * Forgets every recorded copy.
+	 */
+	clear(): void {
+		this.entries = [];
+	}
+}
@@ -0,0 +1,366 @@
+/**
+ * Ultra Code Fence - Code Queries
+ *
+ * Builds a block's code from a query over the vault (META.QUERY), such
+ * as every bash block in notes tagged #deploy. A query is either a simple
+ * search, handled here, or a Dataview query (LIST, TABLE, TASK), passed
+ * to the Dataview plugin when it is installed.
+ *
+ * Simple search terms:
+ * - `tag:#deploy` or `#deploy`: notes with the tag (or a nested one)
+ * - `path:Ops/`: notes under a folder
+ * - `lang:bash`: code blocks in the language
+ * - any other word or "quoted phrase": code blocks containing it
+ */
+
+import type { App } from 'obsidian';
+import { getAllTags } from 'obsidian';
+import type { CommentSyntax } from '../types';
+import { parseBlockContent, parseConfigFormatFromInfoString } from '../parsers';
+
+/** A parsed simple search. */
+export interface CodeQuery {
+	/** Tags a note must have (lower case, with #) */
+	tags: string[];
+
+	/** Folders a note must be under (without trailing /) */
+	folders: string[];
+
+	/** Languages a block may be in (lower case; empty = any) */
+	languages: string[];
+
+	/** Text a block must contain (lower case) */
+	terms: string[];
+}
+
+/** A note searched by a query. */
+export interface QueryNote {
+	/** Vault path */
+	path: string;
+
+	/** The note's tags, with # */
+	tags: string[];
+
+	/** Note content */
+	content: string;
+}
+
+/** A fenced code block found in a note. */
+export interface FencedCode {
+	/** Line number of the opening fence (1-based) */
+	line: number;
+
+	/** Language from the fence line (lower case; "" if none) */
+	language: string;
+
+	/** The block's code */
+	code: string;
+}
+
+/** A code block matched by a query. */
+export interface CodeQueryMatch extends FencedCode {
+	/** Vault path of the note it is in */
+	path: string;
+}
+
+/** Words that start a Dataview query. */
+const DATAVIEW_QUERY_PATTERN = /^\s*(?:LIST|TABLE|TASK|CALENDAR)\b/i;
+
+/** One query term: key:value, #tag, "quoted phrase" or a word. */
+const QUERY_TERM_PATTERN = /(\w+):("[^"]*"|\S+)|"([^"]*)"|(\S+)/g;
+
+/** Any fence line: group 1 is the fence, group 2 the info string. */
+const FENCE_LINE_PATTERN = /^\s*(`{3,}|~{3,})(.*)$/;
+
+// =============================================================================
+// Parsing
+// =============================================================================
+
+/**
+ * Checks whether a query is for the Dataview plugin.
+ *
+ * @param query - META.QUERY value
+ * @returns True if it starts with LIST, TABLE, TASK or CALENDAR
+ */
+export function isDataviewQuery(query: string): boolean {
+	return DATAVIEW_QUERY_PATTERN.test(query);
+}
+
+/**
+ * Parses a simple search. Unknown keys are searched for as text.
+ *
+ * @param query - Search, e.g. `tag:#deploy lang:bash "kubectl apply"`
+ * @returns The search terms
+ */
+export function parseCodeQuery(query: string): CodeQuery {
+	const result: CodeQuery = { tags: [], folders: [], languages: [], terms: [] };
+	const termPattern = new RegExp(QUERY_TERM_PATTERN.source, 'g');
+
+	let match: RegExpExecArray | null;
+	while ((match = termPattern.exec(query)) !== null) {
+		const key = match[1]?.toLowerCase();
+		const value = (match[2] ?? '').replace(/^"|"$/g, '');
+
+		if (key === 'tag') {
+			result.tags.push(normaliseTag(value));
+		} else if (key === 'path') {
+			result.folders.push(value.replace(/^\/+|\/+$/g, ''));
+		} else if (key === 'lang') {
+			result.languages.push(value.toLowerCase());
+		} else if (match[3] !== undefined) {
+			result.terms.push(match[3].toLowerCase());
+		} else {
+			const word = match[0];
+			if (word.startsWith('#') && word.length > 1) {
+				result.tags.push(normaliseTag(word));
+			} else {
+				result.terms.push(word.toLowerCase());
+			}
+		}
+	}
+
+	return result;
+}
+
+/**
+ * Normalises a tag for comparison.
+ *
+ * @param tag - Tag with or without #
+ * @returns Lower-case tag with #
+ */
+function normaliseTag(tag: string): string {
+	return `#${tag.replace(/^#/, '').toLowerCase()}`;
+}
+
+// =============================================================================
+// Matching
+// =============================================================================
+
+/**
+ * Finds the fenced code blocks in a note. For ufence blocks it is their
+ * inline code; ufence blocks that load a file, and cmdout or page
+ * settings blocks, are skipped.
+ *
+ * @param content - Note content
+ * @returns Code blocks in document order
+ */
+export function findFencedCode(content: string): FencedCode[] {
+	const lines = content.split('\n');
+	const blocks: FencedCode[] = [];
+	let openFence: { marker: string; start: number; info: string } | null = null;
+
+	for (let i = 0; i < lines.length; i++) {
+		const fenceMatch = FENCE_LINE_PATTERN.exec(lines[i]);
+
+		if (!openFence) {
+			if (fenceMatch) openFence = { marker: fenceMatch[1], start: i, info: fenceMatch[2].trim() };
+			continue;
+		}
+
+		// Closing fence: same character, at least as long, nothing after it
+		const isClosingFence = fenceMatch !== null
+			&& fenceMatch[1][0] === openFence.marker[0]
+			&& fenceMatch[1].length >= openFence.marker.length
+			&& fenceMatch[2].trim() === '';
+		if (!isClosingFence) continue;
+
+		const body = lines.slice(openFence.start + 1, i).join('\n');
+		const infoWord = (/^\S*/.exec(openFence.info)?.[0] ?? '').toLowerCase();
+		const ufenceType = /^ufence-(\S+)/.exec(infoWord)?.[1];
+
+		if (ufenceType === undefined) {
+			blocks.push({ line: openFence.start + 1, language: infoWord.replace(/^\{|\}$/g, ''), code: body });
+		} else if (ufenceType !== 'ufence' && ufenceType !== 'cmdout' && ufenceType !== 'output') {
+			const code = readUfenceCode(body, lines[openFence.start]);
+			if (code !== null) blocks.push({ line: openFence.start + 1, language: ufenceType, code });
+		}
+		openFence = null;
+	}
+
+	return blocks;
+}
+
+/**
+ * Reads a ufence block's inline code.
+ *
+ * @param body - Text between the fences
+ * @param fenceLine - The opening fence line
+ * @returns The code, or null if the block has none or its settings don't parse
+ */
+function readUfenceCode(body: string, fenceLine: string): string | null {
+	try {
+		const parsed = parseBlockContent(body, parseConfigFormatFromInfoString(fenceLine));
+		return parsed.hasEmbeddedCode ? parsed.embeddedCode ?? null : null;
+	} catch {
+		return null;
+	}
+}
+
+/**
+ * Finds the code blocks matching a query, in path order.
+ *
+ * @param query - Parsed search
+ * @param notes - Notes to search
+ * @returns Matching blocks
+ */
+export function matchCodeQuery(query: CodeQuery, notes: readonly QueryNote[]): CodeQueryMatch[] {
+	const matches: CodeQueryMatch[] = [];
+
+	for (const note of [...notes].sort((a, b) => a.path.localeCompare(b.path))) {
+		if (!noteMatchesQuery(query, note)) continue;
+
+		for (const block of findFencedCode(note.content)) {
+			const code = block.code.toLowerCase();
+			const languageMatches = query.languages.length === 0 || query.languages.includes(block.language);
+			if (languageMatches && query.terms.every(term => code.includes(term)) && block.code.trim()) {
+				matches.push({ ...block, path: note.path });
+			}
+		}
+	}
+
+	return matches;
+}
+
+/**
+ * Checks a note against a query's tags and folders.
+ *
+ * @param query - Parsed search
+ * @param note - Note to check
+ * @returns True if the note has every tag and is under one of the folders
+ */
+export function noteMatchesQuery(query: CodeQuery, note: Pick<QueryNote, 'path' | 'tags'>): boolean {
+	const noteTags = note.tags.map(normaliseTag);
+	const hasTags = query.tags.every(tag => noteTags.some(noteTag => noteTag === tag || noteTag.startsWith(`${tag}/`)));
+	const inFolder = query.folders.length === 0
+		|| query.folders.some(folder => folder === '' || note.path.startsWith(`${folder}/`));
+	return hasTags && inFolder;
+}
+
+/**
+ * Joins matched blocks into one block's code. With a comment syntax,
+ * each is headed by a comment naming its note.
+ *
+ * @param matches - Matched blocks
+ * @param commentSyntax - Comment markers of the block's language, if known
+ * @returns The code
+ */
+export function formatCodeQueryMatches(matches: readonly CodeQueryMatch[], commentSyntax: CommentSyntax | undefined): string {
+	return matches
+		.map(match => {
+			const label = formatComment(`From ${match.path.replace(/\.md$/, '')}`, commentSyntax);
+			return label === null ? match.code : `${label}\n${match.code}`;
+		})
+		.join('\n\n');
+}
+
+/**
+ * Writes a line as a comment.
+ *
+ * @param text - Comment text
+ * @param commentSyntax - Comment markers, if known
+ * @returns The comment, or null without markers
+ */
+export function formatComment(text: string, commentSyntax: CommentSyntax | undefined): string | null {
+	const lineMarker = commentSyntax?.line[0];
+	if (lineMarker) return `${lineMarker} ${text}`;
+
+	const blockMarkers = commentSyntax?.block;
+	return blockMarkers ? `${blockMarkers[0]} ${text} ${blockMarkers[1]}` : null;
+}
+
+// =============================================================================
+// Running Queries
+// =============================================================================
+
+/**
+ * Runs a simple search over the vault's notes. Tags and folders are
+ * checked from the metadata cache, so only notes that can match are read.
+ *
+ * @param app - Obsidian app
+ * @param query - Parsed search
+ * @returns Matching blocks
+ */
+export async function runCodeQuery(app: App, query: CodeQuery): Promise<CodeQueryMatch[]> {
+	const notes: QueryNote[] = [];
+
+	for (const file of app.vault.getMarkdownFiles()) {
+		const cache = app.metadataCache.getFileCache(file);
+		const tags = cache ? getAllTags(cache) ?? [] : [];
+		if (!noteMatchesQuery(query, { path: file.path, tags })) continue;
+
+		notes.push({ path: file.path, tags, content: await app.vault.cachedRead(file) });
+	}
+
+	return matchCodeQuery(query, notes);
+}
+
+/** The parts of the Dataview plugin's API used here. */
+interface DataviewApi {
+	query(source: string, originFile?: string): Promise<{ successful: boolean; value?: unknown; error?: string }>;
+}
+
+/**
+ * Runs a Dataview query and writes its results as lines: a list gives a
+ * line per item, a table a line per row with tabs between cells, and
+ * tasks a line per task.
+ *
+ * @param app - Obsidian app
+ * @param source - Dataview query
+ * @param originPath - Note the block is in (for `this` in the query)
+ * @returns The lines, or an error message
+ */
+export async function runDataviewQuery(app: App, source: string, originPath: string): Promise<{ lines: string[] } | { error: string }> {
+	const plugins = (app as unknown as { plugins?: { plugins?: Record<string, { api?: DataviewApi } | undefined> } }).plugins;
+	const api = plugins?.plugins?.dataview?.api;
+	if (!api) return { error: 'Dataview queries need the Dataview plugin' };
+
+	const result = await api.query(source, originPath);
+	if (!result.successful) return { error: `Dataview: ${result.error ?? 'the query failed'}` };
+
+	const { type, values } = (result.value ?? {}) as { type?: string; values?: unknown };
+	if (!Array.isArray(values)) return { error: `Dataview ${type ?? 'results'} can't be shown as code` };
+
+	return {
+		lines: type === 'table'
+			? values.map(row => (Array.isArray(row) ? row : [row]).map(dataviewValueText).join('\t'))
+			: flattenDataviewValues(values).map(dataviewValueText),
+	};
+}
+
+/**
+ * Flattens grouped Dataview results (TASK queries group by note).
+ *
+ * @param values - Result values
+ * @returns The items
+ */
+function flattenDataviewValues(values: readonly unknown[]): unknown[] {
+	const items: unknown[] = [];
+	for (const value of values) {
+		const rows = typeof value === 'object' && value !== null ? (value as { rows?: unknown }).rows : undefined;
+		if (Array.isArray(rows)) {
+			items.push(...flattenDataviewValues(rows));
+		} else {
+			items.push(value);
+		}
+	}
+	return items;
+}
+
+/**
+ * Writes a Dataview value as text: links as their path, tasks and list
+ * items as their text, lists joined with commas.
+ *
+ * @param value - A Dataview value
+ * @returns The text
+ */
+export function dataviewValueText(value: unknown): string {
+	if (value === null || value === undefined) return '';
+	if (Array.isArray(value)) return value.map(dataviewValueText).join(', ');
+	if (typeof value === 'object') {
+		const { text, path, display } = value as { text?: unknown; path?: unknown; display?: unknown };
+		if (typeof text === 'string') return text;
+		if (typeof display === 'string' && display) return display;
+		if (typeof path === 'string') return path.replace(/\.md$/, '');
+	}
+	return String(value);
+}
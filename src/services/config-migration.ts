@@ -0,0 +1,99 @@
+/**
+ * Ultra Code Fence - Settings Migration
+ *
+ * Finds ufence blocks across the vault that still use settings keys from
+ * an earlier release (see CONFIG_RENAMES) and works out the rewritten
+ * note content, so the changes can be previewed before they are applied
+ * and undone afterwards.
+ */
+
+import { detectConfigFormat, parseConfigFormatFromInfoString } from '../parsers';
+import { renameDeprecatedKeys } from '../utils/config-rename';
+import { findUfenceBlocks } from './fence-lint';
+
+// =============================================================================
+// Types
+// =============================================================================
+
+/** A rewritten line in a note. */
+export interface ConfigMigrationChange {
+	/** Line number in the note (1-based) */
+	line: number;
+	/** Line before the migration */
+	before: string;
+	/** Line after the migration */
+	after: string;
+}
+
+/** The migration of one note. */
+export interface ConfigMigrationPlan {
+	/** Vault path of the note */
+	path: string;
+	/** Note content before the migration */
+	original: string;
+	/** Note content after the migration */
+	migrated: string;
+	/** Rewritten lines, in order */
+	changes: ConfigMigrationChange[];
+}
+
+// =============================================================================
+// Migration
+// =============================================================================
+
+/**
+ * Rewrites old settings keys in every YAML-configured ufence block of a
+ * note. TOML and JSON settings are left alone.
+ *
+ * @param content - Note content
+ * @returns Migrated content and the lines that changed
+ */
+export function migrateNoteContent(content: string): { content: string; changes: ConfigMigrationChange[] } {
+	const lines = content.split('\n');
+	const changes: ConfigMigrationChange[] = [];
+
+	for (const block of findUfenceBlocks(content)) {
+		const configFormat = parseConfigFormatFromInfoString(block.fenceLine) ?? detectConfigFormat(block.content);
+		if (configFormat !== 'yaml') continue;
+
+		// block.line is the 1-based fence line, so the 0-based index of the first body line
+		const bodyStart = block.line;
+		const migratedLines = renameDeprecatedKeys(block.content).split('\n');
+
+		migratedLines.forEach((migratedLine, offset) => {
+			const index = bodyStart + offset;
+			if (lines[index] === migratedLine) return;
+
+			changes.push({ line: index + 1, before: lines[index], after: migratedLine });
+			lines[index] = migratedLine;
+		});
+	}
+
+	return { content: lines.join('\n'), changes };
+}
+
+/**
+ * Works out the migration of one note.
+ *
+ * @param path - Vault path of the note
+ * @param content - Note content
+ * @returns Migration plan, or null when nothing needs changing
+ */
+export function planNoteMigration(path: string, content: string): ConfigMigrationPlan | null {
+	const result = migrateNoteContent(content);
+	if (result.changes.length === 0) return null;
+
+	return { path, original: content, migrated: result.content, changes: result.changes };
+}
+
+/**
+ * Works out the content to restore when undoing a migration. A note that
+ * was edited after the migration is not restored, so the edits aren't lost.
+ *
+ * @param plan - Applied migration
+ * @param currentContent - Note content now
+ * @returns Content to restore, or null to leave the note as it is
+ */
+export function getUndoContent(plan: ConfigMigrationPlan, currentContent: string): string | null {
+	return currentContent === plan.migrated ? plan.original : null;
+}
@@ -0,0 +1,318 @@
+/**
+ * Ultra Code Fence - Contributions
+ *
+ * Lets other Obsidian plugins add to ufence blocks without changes in
+ * core: grammars for more languages, highlight themes for RENDER.THEME,
+ * "copy as" formats for COPY.AS, annotations computed from a block's
+ * code (linters, coverage, ownership), and runners that run a block's
+ * code for the run button. ufence has no bridge of its own to other code
+ * running plugins: a runner exists only once some plugin registers one.
+ * A plugin reaches the API through this plugin's instance:
+ *
+ *     const ufence = app.plugins.getPlugin('ultra-code-fence')?.api;
+ *     const dispose = ufence?.registerGrammar('mydsl', grammar);
+ *
+ * Every register call returns a function that removes what it added,
+ * for the contributing plugin to call when it unloads. Contributions
+ * never override the user's own: a grammar from the grammar folder, a
+ * theme imported in the settings and a block's ANNOTATIONS all win.
+ */
+
+import type { HighlightTheme, LineAnnotation } from '../types';
+import type { CopyTransform } from '../utils/copy-transforms';
+import { registerCopyAsTransform, unregisterCopyAsTransform } from '../utils/copy-transforms';
+import { normalizeCalloutType } from '../constants';
+import type { CustomGrammar, PrismGrammar } from './custom-grammars';
+import { highlightThemeKey } from './vscode-theme';
+import type { OutputStream, RunControlOptions, RunResult } from './shell-runner';
+import { superviseRun } from './shell-runner';
+
+/** Version of the API, raised when it changes in a way callers must check. */
+export const PLUGIN_API_VERSION = 1;
+
+/** The block an annotation provider is asked about. */
+export interface AnnotationContext {
+	/** The block's code, as rendered (after filters) */
+	code: string;
+
+	/** The block's language */
+	language: string;
+
+	/** Vault path of the note holding the block */
+	notePath: string;
+}
+
+/** An annotation from a provider; type defaults to "note", text to the type. */
+export interface ContributedAnnotation {
+	/** Annotated line (1-based, as rendered) */
+	line: number;
+
+	/** Callout type for the icon and colour */
+	type?: string;
+
+	/** Tooltip text */
+	text?: string;
+}
+
+/** Computes annotations for a block. */
+export type AnnotationProvider = (block: AnnotationContext) => ContributedAnnotation[];
+
+/** The block a runner is asked to run. */
+export interface RunContext {
+	/** The code to run: the whole block, or the one line run on its own */
+	code: string;
+
+	/** The block's language, aliases resolved */
+	language: string;
+
+	/** Vault path of the note holding the block */
+	notePath: string;
+
+	/** Variables from RUN.ENV, references filled in */
+	env: Record<string, string>;
+}
+
+/** A run handed to a runner. */
+export interface RunRequest extends RunContext {
+	/** Shows output under the block as it arrives */
+	onOutput: (text: string, stream: OutputStream) => void;
+
+	/** Aborted when the run is stopped: the Stop button, its timeout, or this plugin unloading */
+	signal: AbortSignal;
+}
+
+/** How a contributed run ended. */
+export interface ContributedRunResult {
+	/** Exit code, or null if the code couldn't be run */
+	exitCode: number | null;
+
+	/** Why the code couldn't be run, if it couldn't */
+	errorMessage?: string;
+}
+
+/** Runs a block's code, in place of the built-in runners. */
+export type CodeRunner = (run: RunRequest) => Promise<ContributedRunResult>;
+
+/** Removes a contribution. */
+export type Disposer = () => void;
+
+/**
+ * The API other plugins call, exposed as the plugin's `api` property.
+ */
+export interface UltraCodeFenceApi {
+	/** API version ({@link PLUGIN_API_VERSION}) */
+	readonly version: number;
+
+	/**
+	 * Adds a Prism grammar for a language. Languages Prism or the grammar
+	 * folder already define are left alone.
+	 */
+	registerGrammar(language: string, grammar: PrismGrammar): Disposer;
+
+	/** Adds a highlight theme, chosen by name with RENDER.THEME. */
+	registerTheme(theme: HighlightTheme): Disposer;
+
+	/** Adds a COPY.AS format. Built-in formats can't be replaced. */
+	registerCopyTransform(format: string, transform: CopyTransform, label?: string): Disposer;
+
+	/** Adds annotations to blocks, on lines the block doesn't annotate itself. */
+	registerAnnotationProvider(provider: AnnotationProvider): Disposer;
+
+	/**
+	 * Runs blocks of the given languages with the run button, in place of
+	 * the built-in shell and script runners. The latest runner for a
+	 * language is used.
+	 */
+	registerRunner(languages: string[], runner: CodeRunner): Disposer;
+}
+
+// =============================================================================
+// Registry
+// =============================================================================
+
+/**
+ * What other plugins have contributed, and the API they reach it by.
+ */
+export class ContributionRegistry {
+	private grammarsByLanguage = new Map<string, PrismGrammar>();
+	private themesByKey = new Map<string, HighlightTheme>();
+	private annotationProviders: AnnotationProvider[] = [];
+	private runnersByLanguage = new Map<string, CodeRunner>();
+	private onChange: () => void;
+
+	/**
+	 * Creates an empty registry.
+	 *
+	 * @param onChange - Called after a contribution is added or removed
+	 */
+	constructor(onChange: () => void) {
+		this.onChange = onChange;
+	}
+
+	/**
+	 * Lists the contributed grammars, for registering with Prism.
+	 *
+	 * @returns Grammars, with no vault path
+	 */
+	grammars(): CustomGrammar[] {
+		return Array.from(this.grammarsByLanguage, ([language, grammar]) => ({ language, path: '', grammar }));
+	}
+
+	/**
+	 * Looks up the contributed themes by key.
+	 *
+	 * @returns Themes keyed by {@link highlightThemeKey} of their name
+	 */
+	themes(): Record<string, HighlightTheme | undefined> {
+		return Object.fromEntries(this.themesByKey);
+	}
+
+	/**
+	 * Adds contributed annotations to a block's own.
+	 *
+	 * Providers are asked in the order they registered; on a line that
+	 * is already annotated the earlier annotation is kept. A provider
+	 * that throws is skipped.
+	 *
+	 * @param annotations - The block's own annotations
+	 * @param block - The block
+	 * @returns All annotations, sorted by line
+	 */
+	annotate(annotations: readonly LineAnnotation[], block: AnnotationContext): LineAnnotation[] {
+		if (this.annotationProviders.length === 0) return [...annotations];
+
+		const byLine = new Map(annotations.map(annotation => [annotation.line, annotation]));
+		for (const provider of this.annotationProviders) {
+			let contributed: ContributedAnnotation[];
+			try {
+				contributed = provider(block);
+			} catch (error) {
+				console.error('Ultra Code Fence: an annotation provider failed', error);
+				continue;
+			}
+			for (const { line, type, text } of contributed) {
+				if (!Number.isInteger(line) || line < 1 || byLine.has(line)) continue;
+				const rawType = type?.trim() ?? '';
+				byLine.set(line, { line, type: normalizeCalloutType(rawType || 'note'), text: text?.trim() ?? '' });
+			}
+		}
+		return Array.from(byLine.values()).sort((a, b) => a.line - b.line);
+	}
+
+	/**
+	 * Says whether a block of a language is run by a contributed runner.
+	 *
+	 * @param language - The block's language, aliases resolved
+	 * @returns True if a runner is registered for it
+	 */
+	hasRunner(language: string): boolean {
+		return this.runnersByLanguage.has(language.toLowerCase());
+	}
+
+	/**
+	 * Runs a block with the runner contributed for its language. The run
+	 * is stopped here, as built-in runs are: when its time runs out or it
+	 * is cancelled the runner's signal is aborted and the run ends at
+	 * once, and any output after that is dropped. A runner that throws or
+	 * rejects fails the run with its error.
+	 *
+	 * @param block - The block to run
+	 * @param options - Output callback, time limit and abort signal
+	 * @returns How the run ended; never rejects
+	 */
+	run(block: RunContext, options: RunControlOptions): Promise<RunResult> {
+		const runner = this.runnersByLanguage.get(block.language.toLowerCase());
+		return new Promise(resolve => {
+			if (!runner) {
+				resolve({ exitCode: null, errorMessage: `no runner for ${block.language}` });
+				return;
+			}
+			if (options.signal?.aborted) {
+				resolve({ exitCode: null, stopped: { reason: 'cancelled', afterMs: 0 } });
+				return;
+			}
+
+			const controller = new AbortController();
+			const run = { done: false };
+			const finish = (result: RunResult): void => {
+				if (run.done) return;
+				run.done = true;
+				stopWatching();
+				resolve(result);
+			};
+			const stopWatching = superviseRun(options, (stopped) => {
+				controller.abort();
+				finish({ exitCode: null, stopped });
+			});
+
+			const onOutput = (text: string, stream: OutputStream): void => {
+				if (!run.done) options.onOutput(text, stream);
+			};
+			const started = (async () => runner({ ...block, onOutput, signal: controller.signal }))();
+			started.then(
+				({ exitCode, errorMessage }) => { finish(errorMessage ? { exitCode, errorMessage } : { exitCode }); },
+				(error: unknown) => { finish({ exitCode: null, errorMessage: error instanceof Error ? error.message : String(error) }); }
+			);
+		});
+	}
+
+	/**
+	 * Creates the API object handed to other plugins.
+	 *
+	 * @returns The API
+	 */
+	createApi(): UltraCodeFenceApi {
+		return {
+			version: PLUGIN_API_VERSION,
+			registerGrammar: (language, grammar) => {
+				const key = language.trim().toLowerCase();
+				this.grammarsByLanguage.set(key, grammar);
+				this.onChange();
+				return () => {
+					if (this.grammarsByLanguage.get(key) !== grammar) return;
+					this.grammarsByLanguage.delete(key);
+					this.onChange();
+				};
+			},
+			registerTheme: (theme) => {
+				const key = highlightThemeKey(theme.name);
+				this.themesByKey.set(key, theme);
+				this.onChange();
+				return () => {
+					if (this.themesByKey.get(key) !== theme) return;
+					this.themesByKey.delete(key);
+					this.onChange();
+				};
+			},
+			registerCopyTransform: (format, transform, label) => {
+				if (!registerCopyAsTransform(format, transform, label ?? format)) {
+					return () => undefined;
+				}
+				this.onChange();
+				return () => {
+					unregisterCopyAsTransform(format, transform);
+					this.onChange();
+				};
+			},
+			registerAnnotationProvider: (provider) => {
+				this.annotationProviders.push(provider);
+				this.onChange();
+				return () => {
+					this.annotationProviders = this.annotationProviders.filter(entry => entry !== provider);
+					this.onChange();
+				};
+			},
+			registerRunner: (languages, runner) => {
+				const keys = languages.map(language => language.trim().toLowerCase()).filter(Boolean);
+				for (const key of keys) this.runnersByLanguage.set(key, runner);
+				this.onChange();
+				return () => {
+					for (const key of keys) {
+						if (this.runnersByLanguage.get(key) === runner) this.runnersByLanguage.delete(key);
+					}
+					this.onChange();
+				};
+			},
+		};
+	}
+}
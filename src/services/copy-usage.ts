@@ -0,0 +1,201 @@
+/**
+ * Ultra Code Fence - Copy Usage
+ *
+ * Counts how often each code block is copied, so frequently used runbook
+ * commands can be spotted. Counts are stored in the plugin's own data
+ * file and never leave the vault. Counts (and wrap choices, kept under
+ * the same keys) of blocks that have since changed or gone are pruned,
+ * so the data file doesn't grow with every edit.
+ */
+
+/** An opening or closing fence line: group 1 is the fence, group 2 the rest. */
+const FENCE_LINE_PATTERN = /^\s*(`{3,}|~{3,})(.*)$/;
+
+/** One blockquote (or callout) marker at the start of a line, with the space after it. */
+const QUOTE_MARKER_PATTERN = /^[ \t]{0,3}> ?/;
+
+// =============================================================================
+// Block Keys
+// =============================================================================
+
+/**
+ * Hashes text with 32-bit FNV-1a.
+ *
+ * @param text - Text to hash
+ * @returns Hash as 8 hex digits
+ */
+function hashText(text: string): string {
+	let hash = 0x811c9dc5;
+
+	for (let i = 0; i < text.length; i++) {
+		hash ^= text.charCodeAt(i);
+		hash = Math.imul(hash, 0x01000193);
+	}
+
+	return (hash >>> 0).toString(16).padStart(8, '0');
+}
+
+/**
+ * Builds the key a block's copy count is stored under.
+ *
+ * Blocks are identified by their note and the text between their
+ * fences, so editing the block starts a new count; renaming the note
+ * carries its counts over ({@link moveCopyUsage}).
+ *
+ * @param notePath - Path of the note containing the block
+ * @param blockText - Text between the block's fences
+ * @returns Storage key
+ */
+export function buildCopyUsageKey(notePath: string, blockText: string): string {
+	return `${notePath}#${hashText(blockText)}`;
+}
+
+/**
+ * Removes blockquote markers from the start of a line.
+ *
+ * @param line - Note line
+ * @param maxDepth - Most markers to remove
+ * @returns The line without its markers, and how many were removed
+ */
+function stripQuoteMarkers(line: string, maxDepth: number): { text: string; depth: number } {
+	let text = line;
+	let depth = 0;
+	while (depth < maxDepth) {
+		const marker = QUOTE_MARKER_PATTERN.exec(text);
+		if (!marker) break;
+		text = text.slice(marker[0].length);
+		depth++;
+	}
+	return { text, depth };
+}
+
+/**
+ * Builds the keys of every fenced block in a note.
+ *
+ * Blocks inside blockquotes and callouts have their "> " markers
+ * stripped, as they are from the text the block is rendered from, so
+ * their keys match the ones their copy buttons use. Such a block ends
+ * with its quote if it isn't closed first.
+ *
+ * @param notePath - Path of the note
+ * @param content - Note content
+ * @returns Keys from {@link buildCopyUsageKey}
+ */
+export function findCopyUsageKeys(notePath: string, content: string): Set<string> {
+	const lines = content.split('\n');
+	const keys = new Set<string>();
+	let openFence: { marker: string; depth: number; lines: string[] } | null = null;
+
+	for (const line of lines) {
+		if (openFence) {
+			const { text, depth } = stripQuoteMarkers(line, openFence.depth);
+			if (depth < openFence.depth) {
+				// The quote ended, and the block with it
+				keys.add(buildCopyUsageKey(notePath, openFence.lines.join('\n')));
+				openFence = null;
+			} else {
+				const fenceMatch = FENCE_LINE_PATTERN.exec(text);
+
+				// Closing fence: same character, at least as long, nothing after it
+				const isClosingFence = fenceMatch !== null
+					&& fenceMatch[1][0] === openFence.marker[0]
+					&& fenceMatch[1].length >= openFence.marker.length
+					&& fenceMatch[2].trim() === '';
+				if (isClosingFence) {
+					keys.add(buildCopyUsageKey(notePath, openFence.lines.join('\n')));
+					openFence = null;
+				} else {
+					openFence.lines.push(text);
+				}
+				continue;
+			}
+		}
+
+		const { text, depth } = stripQuoteMarkers(line, Infinity);
+		const fenceMatch = FENCE_LINE_PATTERN.exec(text);
+		if (fenceMatch) openFence = { marker: fenceMatch[1], depth, lines: [] };
+	}
+
+	return keys;
+}
+
+/**
+ * Reads the note path out of a key.
+ *
+ * @param key - Key from {@link buildCopyUsageKey}
+ * @returns Path of the note
+ */
+export function copyUsageNotePath(key: string): string {
+	const hashStart = key.lastIndexOf('#');
+	return hashStart === -1 ? key : key.slice(0, hashStart);
+}
+
+// =============================================================================
+// Pruning
+// =============================================================================
+
+/**
+ * Drops a note's records for blocks it no longer has.
+ *
+ * @param records - Records keyed by {@link buildCopyUsageKey} (updated in place)
+ * @param notePath - Path of the note
+ * @param liveKeys - Keys of the note's blocks ({@link findCopyUsageKeys}), or null if the note is gone
+ * @returns True if anything was dropped
+ */
+export function pruneCopyUsage(records: Record<string, unknown>, notePath: string, liveKeys: Set<string> | null): boolean {
+	let pruned = false;
+	for (const key of Object.keys(records)) {
+		if (copyUsageNotePath(key) !== notePath || liveKeys?.has(key)) continue;
+		Reflect.deleteProperty(records, key);
+		pruned = true;
+	}
+	return pruned;
+}
+
+/**
+ * Moves a renamed note's records to its new path.
+ *
+ * @param records - Records keyed by {@link buildCopyUsageKey} (updated in place)
+ * @param oldPath - The note's previous path
+ * @param newPath - The note's path now
+ * @returns True if anything was moved
+ */
+export function moveCopyUsage(records: Record<string, unknown>, oldPath: string, newPath: string): boolean {
+	let moved = false;
+	for (const key of Object.keys(records)) {
+		if (copyUsageNotePath(key) !== oldPath) continue;
+		records[`${newPath}${key.slice(oldPath.length)}`] = records[key];
+		Reflect.deleteProperty(records, key);
+		moved = true;
+	}
+	return moved;
+}
+
+// =============================================================================
+// Counting
+// =============================================================================
+
+/**
+ * Gets the number of recorded copies for a block.
+ *
+ * @param counts - Stored copy counts
+ * @param key - Block key from {@link buildCopyUsageKey}
+ * @returns Copy count (0 if never copied)
+ */
+export function getCopyCount(counts: Record<string, number>, key: string): number {
+	const count: number | undefined = counts[key];
+	return count ?? 0;
+}
+
+/**
+ * Records one more copy of a block.
+ *
+ * @param counts - Stored copy counts (updated in place)
+ * @param key - Block key from {@link buildCopyUsageKey}
+ * @returns New copy count
+ */
+export function incrementCopyCount(counts: Record<string, number>, key: string): number {
+	const count = getCopyCount(counts, key) + 1;
+	counts[key] = count;
+	return count;
+}
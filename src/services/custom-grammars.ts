@@ -0,0 +1,440 @@
+/**
+ * Ultra Code Fence - Custom Grammars
+ *
+ * Loads syntax grammars from a vault folder and registers them with
+ * Obsidian's Prism highlighter, so languages Obsidian doesn't ship
+ * (in-house DSLs, niche config formats) get real highlighting.
+ *
+ * Each file registers one language, named after the file: `mydsl.json`
+ * highlights ```mydsl blocks. Two formats are read:
+ *
+ * - Prism grammars: a JSON object mapping token names to a pattern
+ *   string or a `{ pattern, flags, greedy, lookbehind, alias, inside }`
+ *   object (or a list of them)
+ * - TextMate grammars (`*.tmLanguage.json`): `match` rules and simple
+ *   `begin`/`end` rules are converted, and scope names are mapped to
+ *   Prism token names. Captures and nested rules are not supported
+ *
+ * Language aliases from the settings are registered the same way, as
+ * extra names for an existing grammar.
+ */
+
+import type { App } from 'obsidian';
+import { resolveLanguageAlias } from '../utils/language-aliases';
+
+/** Extension of the files read from the grammar folder. */
+const GRAMMAR_FILE_SUFFIX = '.json';
+
+/** Suffix of TextMate grammar files (stripped from the language code). */
+const TEXTMATE_FILE_SUFFIX = '.tmlanguage.json';
+
+/** Language codes that may be registered: letters, digits, - _ + # */
+const LANGUAGE_CODE_PATTERN = /^[a-z0-9][a-z0-9_+#-]*$/;
+
+/** Leading Oniguruma inline flags, which JavaScript regexes don't accept. */
+const INLINE_FLAGS_PATTERN = /^\(\?([imx]+)\)/;
+
+/**
+ * TextMate scope prefixes and the Prism token they map to, most
+ * specific first. Scopes matching none of them are skipped.
+ */
+const TEXTMATE_SCOPE_TOKENS: ReadonlyArray<readonly [string, string]> = [
+	['comment', 'comment'],
+	['string.regexp', 'regex'],
+	['string', 'string'],
+	['constant.numeric', 'number'],
+	['constant.language', 'boolean'],
+	['constant.character', 'char'],
+	['constant', 'constant'],
+	['keyword.operator', 'operator'],
+	['keyword', 'keyword'],
+	['storage', 'keyword'],
+	['entity.name.function', 'function'],
+	['support.function', 'builtin'],
+	['entity.name.type', 'class-name'],
+	['entity.name.class', 'class-name'],
+	['support.type', 'class-name'],
+	['support.class', 'class-name'],
+	['entity.name.tag', 'tag'],
+	['entity.other.attribute-name', 'attr-name'],
+	['variable', 'variable'],
+	['punctuation', 'punctuation'],
+	['markup.heading', 'title'],
+	['markup.bold', 'bold'],
+	['markup.italic', 'italic'],
+	['markup.inserted', 'inserted'],
+	['markup.deleted', 'deleted'],
+];
+
+/** One Prism token rule. */
+export interface PrismTokenRule {
+	pattern: RegExp;
+	greedy?: boolean;
+	lookbehind?: boolean;
+	alias?: string | string[];
+	inside?: PrismGrammar;
+}
+
+/** A Prism grammar: token names mapped to their rules. */
+export type PrismGrammar = Record<string, RegExp | PrismTokenRule | Array<RegExp | PrismTokenRule>>;
+
+/** The part of Prism's global object grammars are registered on. */
+export interface PrismLanguages {
+	languages: Record<string, unknown>;
+}
+
+/** A grammar read from the grammar folder. */
+export interface CustomGrammar {
+	/** Language code the grammar is registered under */
+	language: string;
+
+	/** Vault path of the grammar file ('' = from another plugin) */
+	path: string;
+
+	/** Converted grammar */
+	grammar: PrismGrammar;
+}
+
+/** Result of loading the grammar folder. */
+export interface CustomGrammarLoadResult {
+	grammars: CustomGrammar[];
+
+	/** Files that could not be read, with the reason */
+	errors: Array<{ path: string; message: string }>;
+}
+
+// =============================================================================
+// Parsing
+// =============================================================================
+
+/**
+ * Works out the language code a grammar file registers.
+ *
+ * @param path - Vault path of the grammar file
+ * @returns Lower-case language code, or null if the file isn't a grammar
+ */
+export function grammarLanguageFromPath(path: string): string | null {
+	const fileName = path.slice(path.lastIndexOf('/') + 1).toLowerCase();
+	if (!fileName.endsWith(GRAMMAR_FILE_SUFFIX)) return null;
+
+	const suffix = fileName.endsWith(TEXTMATE_FILE_SUFFIX) ? TEXTMATE_FILE_SUFFIX : GRAMMAR_FILE_SUFFIX;
+	const language = fileName.slice(0, -suffix.length);
+	return LANGUAGE_CODE_PATTERN.test(language) ? language : null;
+}
+
+/**
+ * Parses a grammar file in either supported format.
+ *
+ * @param content - File content (JSON)
+ * @returns Prism grammar
+ * @throws Error if the file isn't JSON or has no usable rules
+ */
+export function parseGrammarFile(content: string): PrismGrammar {
+	const data: unknown = JSON.parse(content);
+	if (!isRecord(data)) {
+		throw new Error('Grammar must be a JSON object');
+	}
+
+	const grammar = isTextMateGrammar(data) ? convertTextMateGrammar(data) : convertPrismGrammar(data);
+	if (Object.keys(grammar).length === 0) {
+		throw new Error('Grammar has no usable rules');
+	}
+	return grammar;
+}
+
+/**
+ * Converts a JSON Prism grammar, turning pattern strings into regexes.
+ *
+ * @param data - Parsed JSON object
+ * @returns Prism grammar
+ * @throws Error naming the token whose pattern is invalid
+ */
+export function convertPrismGrammar(data: Record<string, unknown>): PrismGrammar {
+	const grammar: PrismGrammar = {};
+
+	for (const [token, value] of Object.entries(data)) {
+		const rules = Array.isArray(value) ? value : [value];
+		const converted = rules.map(rule => convertPrismRule(token, rule));
+		grammar[token] = converted.length === 1 ? converted[0] : converted;
+	}
+	return grammar;
+}
+
+/**
+ * Converts the rules of a TextMate grammar into a flat Prism grammar.
+ *
+ * Top-level patterns are followed through `include` references to the
+ * repository. Rules with a Prism equivalent for their scope are kept
+ * in order; rules whose regex JavaScript can't compile are skipped.
+ *
+ * @param data - Parsed TextMate grammar
+ * @returns Prism grammar
+ */
+export function convertTextMateGrammar(data: Record<string, unknown>): PrismGrammar {
+	const repository = isRecord(data.repository) ? data.repository : {};
+	const grammar: PrismGrammar = {};
+	const visited = new Set<string>();
+
+	const addRule = (token: string, rule: PrismTokenRule): void => {
+		const existing = grammar[token];
+		if (existing === undefined) {
+			grammar[token] = rule;
+		} else {
+			grammar[token] = Array.isArray(existing) ? [...existing, rule] : [existing, rule];
+		}
+	};
+
+	const walk = (patterns: unknown): void => {
+		if (!Array.isArray(patterns)) return;
+
+		for (const pattern of patterns) {
+			if (!isRecord(pattern)) continue;
+
+			if (typeof pattern.include === 'string') {
+				const key = pattern.include.startsWith('#') ? pattern.include.slice(1) : '';
+				const entry = repository[key];
+				if (key && !visited.has(key) && isRecord(entry)) {
+					visited.add(key);
+					walk([entry]);
+				}
+				continue;
+			}
+
+			if (Array.isArray(pattern.patterns) && pattern.match === undefined && pattern.begin === undefined) {
+				walk(pattern.patterns);
+				continue;
+			}
+
+			const token = typeof pattern.name === 'string' ? textMateScopeToToken(pattern.name) : null;
+			if (!token) continue;
+
+			if (typeof pattern.match === 'string') {
+				const regex = compileTextMateRegex(pattern.match);
+				if (regex) addRule(token, { pattern: regex });
+			} else if (typeof pattern.begin === 'string' && typeof pattern.end === 'string') {
+				// A begin/end region becomes one lazy match; greedy so Prism
+				// doesn't let earlier tokens split it (e.g. quotes in comments)
+				const source = `(?:${stripInlineFlags(pattern.begin)})[\\s\\S]*?(?:${stripInlineFlags(pattern.end)})`;
+				const regex = compileTextMateRegex(source, inlineFlags(pattern.begin));
+				if (regex) addRule(token, { pattern: regex, greedy: true });
+			}
+		}
+	};
+
+	walk(data.patterns);
+	return grammar;
+}
+
+/**
+ * Maps a TextMate scope name to a Prism token name.
+ *
+ * @param scope - Scope name, e.g. "keyword.control.mydsl" (the first
+ *   of several space-separated scopes is used)
+ * @returns Prism token name, or null if the scope has no equivalent
+ */
+export function textMateScopeToToken(scope: string): string | null {
+	const first = scope.trim().split(/\s+/)[0];
+	for (const [prefix, token] of TEXTMATE_SCOPE_TOKENS) {
+		if (first === prefix || first.startsWith(prefix + '.')) {
+			return token;
+		}
+	}
+	return null;
+}
+
+// =============================================================================
+// Loading
+// =============================================================================
+
+/**
+ * Reads every grammar file in a vault folder (and its subfolders).
+ *
+ * When two files claim the same language, the first by path wins.
+ *
+ * @param app - Obsidian app instance
+ * @param folder - Vault folder path; empty loads nothing
+ * @returns Loaded grammars and the files that failed
+ */
+export async function loadCustomGrammars(app: App, folder: string): Promise<CustomGrammarLoadResult> {
+	const result: CustomGrammarLoadResult = { grammars: [], errors: [] };
+	const prefix = folder.trim().replace(/^\/+|\/+$/g, '');
+	if (!prefix) return result;
+
+	const files = app.vault.getFiles()
+		.filter(file => isInGrammarFolder(file.path, prefix))
+		.sort((a, b) => a.path.localeCompare(b.path));
+
+	const seen = new Set<string>();
+	for (const file of files) {
+		const language = grammarLanguageFromPath(file.path);
+		if (!language || seen.has(language)) continue;
+
+		try {
+			const grammar = parseGrammarFile(await app.vault.cachedRead(file));
+			result.grammars.push({ language, path: file.path, grammar });
+			seen.add(language);
+		} catch (error) {
+			result.errors.push({ path: file.path, message: error instanceof Error ? error.message : String(error) });
+		}
+	}
+	return result;
+}
+
+/**
+ * Registers grammars with Prism, replacing any the previous load added.
+ *
+ * Languages Prism already knows are left alone unless they were
+ * registered from the grammar folder earlier, so a stray file can't
+ * break built-in highlighting.
+ *
+ * @param prism - Prism global (from Obsidian's loadPrism)
+ * @param grammars - Grammars to register
+ * @param previous - Language codes registered by the previous load
+ * @returns Language codes now registered from the grammar folder
+ */
+export function registerCustomGrammars(
+	prism: PrismLanguages,
+	grammars: readonly CustomGrammar[],
+	previous: ReadonlySet<string> = new Set()
+): Set<string> {
+	for (const language of previous) {
+		if (!grammars.some(grammar => grammar.language === language)) {
+			// The file was removed or renamed since the last load
+			Reflect.deleteProperty(prism.languages, language);
+		}
+	}
+
+	const registered = new Set<string>();
+	for (const { language, grammar } of grammars) {
+		if (language in prism.languages && !previous.has(language)) continue;
+		prism.languages[language] = grammar;
+		registered.add(language);
+	}
+	return registered;
+}
+
+/**
+ * Registers language aliases with Prism, so fences using an alias are
+ * highlighted with the grammar of the language it stands for.
+ *
+ * Aliases replace those from the previous call. An alias is skipped if
+ * its target has no grammar (yet) or if Prism already knows the alias
+ * as a language of its own.
+ *
+ * @param prism - Prism global (from Obsidian's loadPrism)
+ * @param aliases - Target language by alias
+ * @param previous - Aliases registered by the previous call
+ * @returns Aliases now registered
+ */
+export function registerLanguageAliases(
+	prism: PrismLanguages,
+	aliases: Record<string, string | undefined>,
+	previous: ReadonlySet<string> = new Set()
+): Set<string> {
+	for (const alias of previous) {
+		Reflect.deleteProperty(prism.languages, alias);
+	}
+
+	const registered = new Set<string>();
+	for (const key of Object.keys(aliases)) {
+		const alias = key.trim().toLowerCase();
+		const target = resolveLanguageAlias(alias, aliases);
+		const grammar = prism.languages[target];
+		if (target === alias || grammar === undefined || alias in prism.languages) continue;
+
+		prism.languages[alias] = grammar;
+		registered.add(alias);
+	}
+	return registered;
+}
+
+/**
+ * Checks whether a vault path is inside the grammar folder.
+ *
+ * @param path - Vault path of a file
+ * @param folder - Grammar folder (no leading or trailing slash)
+ * @returns True if the file is in the folder or below it
+ */
+export function isInGrammarFolder(path: string, folder: string): boolean {
+	return path.startsWith(folder + '/');
+}
+
+// =============================================================================
+// Helpers
+// =============================================================================
+
+/**
+ * Checks whether a value is a plain object.
+ */
+function isRecord(value: unknown): value is Record<string, unknown> {
+	return typeof value === 'object' && value !== null && !Array.isArray(value);
+}
+
+/**
+ * Checks whether parsed JSON is a TextMate grammar.
+ */
+function isTextMateGrammar(data: Record<string, unknown>): boolean {
+	return typeof data.scopeName === 'string' && Array.isArray(data.patterns);
+}
+
+/**
+ * Converts one Prism rule from JSON.
+ */
+function convertPrismRule(token: string, rule: unknown): PrismTokenRule | RegExp {
+	if (typeof rule === 'string') {
+		return compilePrismRegex(token, rule);
+	}
+	if (!isRecord(rule) || typeof rule.pattern !== 'string') {
+		throw new Error(`Token "${token}" needs a pattern`);
+	}
+
+	const converted: PrismTokenRule = {
+		pattern: compilePrismRegex(token, rule.pattern, typeof rule.flags === 'string' ? rule.flags : ''),
+	};
+	if (rule.greedy === true) converted.greedy = true;
+	if (rule.lookbehind === true) converted.lookbehind = true;
+	if (typeof rule.alias === 'string' || Array.isArray(rule.alias)) {
+		converted.alias = Array.isArray(rule.alias) ? rule.alias.map(String) : rule.alias;
+	}
+	if (isRecord(rule.inside)) {
+		converted.inside = convertPrismGrammar(rule.inside);
+	}
+	return converted;
+}
+
+/**
+ * Compiles a Prism pattern, naming the token if it is invalid.
+ */
+function compilePrismRegex(token: string, source: string, flags = ''): RegExp {
+	try {
+		return new RegExp(source, flags);
+	} catch {
+		throw new Error(`Token "${token}" has an invalid pattern`);
+	}
+}
+
+/**
+ * Compiles a TextMate regex, or returns null if JavaScript can't.
+ */
+function compileTextMateRegex(source: string, flags = inlineFlags(source)): RegExp | null {
+	try {
+		return new RegExp(stripInlineFlags(source), flags);
+	} catch {
+		return null;
+	}
+}
+
+/**
+ * Returns the JavaScript flags for a regex's leading inline flags.
+ */
+function inlineFlags(source: string): string {
+	const match = INLINE_FLAGS_PATTERN.exec(source);
+	return match?.[1].includes('i') ? 'i' : '';
+}
+
+/**
+ * Removes leading inline flags from a regex.
+ */
+function stripInlineFlags(source: string): string {
+	return source.replace(INLINE_FLAGS_PATTERN, '');
+}
@@ -2,9 +2,67 @@
  * Ultra Code Fence - Download Service
  *
  * Handles saving code block content to files using a Blob/anchor approach
- * that works on all platforms (desktop and mobile).
+ * that works on all platforms (desktop and mobile). Scripts can get a
+ * shebang line and, on desktop, be written with the executable bit set.
  */
 
+import { Platform } from 'obsidian';
+
+// =============================================================================
+// Shebangs
+// =============================================================================
+
+/**
+ * Interpreters used for default shebang lines, keyed by language ID.
+ */
+const SHEBANG_INTERPRETERS: Record<string, string | undefined> = {
+	bash: 'bash',
+	sh: 'sh',
+	shell: 'sh',
+	zsh: 'zsh',
+	fish: 'fish',
+	python: 'python3',
+	py: 'python3',
+	javascript: 'node',
+	js: 'node',
+	ruby: 'ruby',
+	rb: 'ruby',
+	perl: 'perl',
+	pl: 'perl',
+	php: 'php',
+	powershell: 'pwsh',
+	ps1: 'pwsh',
+	lua: 'lua',
+	r: 'Rscript',
+};
+
+/**
+ * Builds the default shebang line for a language.
+ *
+ * @param language - The language identifier from the ufence block
+ * @returns Shebang line (e.g. "#!/usr/bin/env bash"), or empty string if unknown
+ */
+export function getDefaultShebang(language: string): string {
+	const interpreter = SHEBANG_INTERPRETERS[language.toLowerCase()];
+	return interpreter ? `#!/usr/bin/env ${interpreter}` : '';
+}
+
+/**
+ * Prepends a shebang line unless the code already starts with one.
+ *
+ * @param codeText - The code content to save
+ * @param shebang - Shebang line; empty string leaves the code unchanged
+ * @returns Code with the shebang as its first line
+ */
+export function injectShebang(codeText: string, shebang: string): string {
+	if (!shebang || codeText.startsWith('#!')) return codeText;
+	return `${shebang}\n${codeText}`;
+}
+
+// =============================================================================
+// Filenames
+// =============================================================================
+
 /**
  * Builds a suggested filename from the resolved title and language.
  *
@@ -34,6 +92,10 @@ export function buildSuggestedFilename(resolvedTitle: string, language: string):
 	return `code.${ext}`;
 }
 
+// =============================================================================
+// Saving
+// =============================================================================
+
 /**
  * Downloads code content to a file via Blob and anchor element.
  *
@@ -61,3 +123,75 @@ export function downloadCodeToFile(
 
 	URL.revokeObjectURL(url);
 }
+
+/**
+ * Minimal shape of Electron's save dialog used on desktop.
+ */
+interface DesktopSaveDialog {
+	showSaveDialog(options: { defaultPath: string }): Promise<{ canceled: boolean; filePath?: string }>;
+}
+
+/**
+ * Minimal shape of Node's fs module used on desktop.
+ */
+interface DesktopFileSystem {
+	promises: {
+		writeFile(path: string, data: string): Promise<void>;
+		chmod(path: string, mode: number): Promise<void>;
+	};
+}
+
+/**
+ * Loads the desktop-only save dialog and file system modules.
+ *
+ * @returns Modules, or null on mobile or when they are unavailable
+ */
+function loadDesktopModules(): { dialog: DesktopSaveDialog; fs: DesktopFileSystem } | null {
+	const nodeRequire = (window as unknown as { require?: (id: string) => unknown }).require;
+	if (!Platform.isDesktopApp || !nodeRequire) return null;
+
+	try {
+		const electron = nodeRequire('electron') as { remote?: { dialog?: DesktopSaveDialog } };
+		const dialog = electron.remote?.dialog;
+		if (!dialog) return null;
+
+		return { dialog, fs: nodeRequire('fs') as DesktopFileSystem };
+	} catch {
+		return null;
+	}
+}
+
+/**
+ * Saves a script with the executable bit set (desktop only).
+ *
+ * Opens a native save dialog, writes the file and marks it executable
+ * (mode 755). Where the dialog or file system is unavailable (mobile),
+ * falls back to a plain download without the executable bit.
+ *
+ * @param codeText - The script content to save
+ * @param suggestedFilename - Default filename for the dialog
+ * @param defaultDirectory - Directory to open the dialog in (empty = system default)
+ * @returns Path the file was written to, or null if cancelled or downloaded instead
+ */
+export async function saveExecutableFile(
+	codeText: string,
+	suggestedFilename: string,
+	defaultDirectory = '',
+): Promise<string | null> {
+	const modules = loadDesktopModules();
+	if (!modules) {
+		downloadCodeToFile(codeText, suggestedFilename);
+		return null;
+	}
+
+	const defaultPath = defaultDirectory
+		? `${defaultDirectory.replace(/[\\/]+$/, '')}/${suggestedFilename}`
+		: suggestedFilename;
+	const result = await modules.dialog.showSaveDialog({ defaultPath });
+	if (result.canceled || !result.filePath) return null;
+
+	await modules.fs.promises.writeFile(result.filePath, codeText);
+	await modules.fs.promises.chmod(result.filePath, 0o755);
+
+	return result.filePath;
+}
@@ -0,0 +1,176 @@
+/**
+ * Ultra Code Fence - Embedded Languages
+ *
+ * Finds the injection rules for a block's language, which highlight text
+ * inside the host's tokens as another language (SQL inside Go strings),
+ * and bundles the grammars for template languages Prism lacks, such as
+ * Helm's Go template actions over YAML. JS and CSS inside HTML need no
+ * rule: Prism's markup grammar embeds them already.
+ */
+
+import type { LanguageInjectionRule } from '../types';
+import type { PrismLanguages } from './custom-grammars';
+
+/** A token from Prism's tokenizer. */
+export interface PrismToken {
+	type: string;
+	content: string | PrismToken | PrismTokenStream;
+	alias?: string | string[];
+}
+
+/** What Prism's tokenizer returns: plain text and tokens, in order. */
+export type PrismTokenStream = Array<string | PrismToken>;
+
+/** Prism's global object, as far as embedding needs it. */
+export interface PrismTokenizer extends PrismLanguages {
+	tokenize(text: string, grammar: unknown): PrismTokenStream;
+}
+
+/** An injection rule that applies to a block. */
+export interface LanguageInjection {
+	/** Host token class that holds the embedded code */
+	token: string;
+
+	/** Language the code is highlighted as */
+	language: string;
+
+	/** Regex the code must match (null = every token) */
+	match: RegExp | null;
+}
+
+/** A token's text split into its quotes and the code between them. */
+export interface DelimitedText {
+	open: string;
+	body: string;
+	close: string;
+}
+
+/** Prism token names (also safe in a class selector). */
+const TOKEN_NAME_PATTERN = /^[a-z][a-z0-9_-]*$/;
+
+/** Language codes (also safe in a class name). */
+const LANGUAGE_PATTERN = /^[a-z0-9][\w+#.-]*$/;
+
+/** A quoted string, with an optional prefix such as f, r, b, @ or $. */
+const QUOTED_STRING_PATTERN = /^([A-Za-z@$]{0,3})("""|'''|[`"'])([\s\S]*)\2$/;
+
+/**
+ * Picks the injection rules for a block's language.
+ *
+ * Rules with a bad token name or language or an invalid regex are
+ * skipped, as is a rule embedding the host in itself.
+ *
+ * @param rules - Injection rules from the settings
+ * @param language - The block's language (after aliases)
+ * @returns Rules that apply, in order
+ */
+export function findLanguageInjections(rules: readonly LanguageInjectionRule[], language: string): LanguageInjection[] {
+	const host = language.trim().toLowerCase();
+	const injections: LanguageInjection[] = [];
+	if (!host) return injections;
+
+	for (const rule of rules) {
+		const hosts = rule.hosts.split(',').map(entry => entry.trim().toLowerCase());
+		const token = rule.token.trim().toLowerCase();
+		const embedded = rule.language.trim().toLowerCase();
+		if (!hosts.includes(host) || !TOKEN_NAME_PATTERN.test(token) || !LANGUAGE_PATTERN.test(embedded) || embedded === host) continue;
+
+		let match: RegExp | null = null;
+		if (rule.match.trim()) {
+			try {
+				match = new RegExp(rule.match, 'i');
+			} catch {
+				continue;
+			}
+		}
+		injections.push({ token, language: embedded, match });
+	}
+	return injections;
+}
+
+/**
+ * Splits a string token into its quotes and contents, so only the
+ * contents are highlighted as the embedded language. Text that isn't
+ * quoted (e.g. the text part of a template literal) is all body.
+ *
+ * @param text - The token's text
+ * @returns Opening quote (with any prefix), body and closing quote
+ */
+export function splitStringDelimiters(text: string): DelimitedText {
+	const match = QUOTED_STRING_PATTERN.exec(text);
+	if (!match) {
+		return { open: '', body: text, close: '' };
+	}
+	return { open: match[1] + match[2], body: match[3], close: match[2] };
+}
+
+// =============================================================================
+// Bundled grammars
+// =============================================================================
+
+/**
+ * Builds the Helm chart template grammar: Go template actions, with the
+ * rest of the file highlighted as YAML.
+ *
+ * @param yaml - Prism's YAML grammar
+ * @returns Prism grammar
+ */
+function createHelmGrammar(yaml: unknown): Record<string, unknown> {
+	return {
+		'template-comment': {
+			pattern: /\{\{-?\s*\/\*[\s\S]*?\*\/\s*-?\}\}/,
+			greedy: true,
+			alias: 'comment',
+		},
+		'template-action': {
+			pattern: /\{\{-?[\s\S]*?-?\}\}/,
+			greedy: true,
+			inside: {
+				delimiter: { pattern: /^\{\{-?|-?\}\}$/, alias: 'punctuation' },
+				string: /"(?:\\.|[^"\\\n])*"|`[^`]*`/,
+				keyword: /\b(?:if|else|end|range|with|define|template|block|include|tpl|required|default|quote|squote|toYaml|toJson|fromYaml|indent|nindent|trim|upper|lower|printf|eq|ne|lt|le|gt|ge|and|or|not|len|index|lookup)\b/,
+				variable: /\$\w*|\.[\w.]*/,
+				number: /\b\d+(?:\.\d+)?\b/,
+				operator: /\||:?=/,
+				punctuation: /[(),]/,
+			},
+		},
+		rest: yaml,
+	};
+}
+
+/**
+ * Template languages bundled with the plugin, by language code, and the
+ * Prism language each builds on.
+ */
+const BUNDLED_GRAMMARS: Record<string, readonly [string, (base: unknown) => Record<string, unknown>]> = {
+	helm: ['yaml', createHelmGrammar],
+};
+
+/**
+ * Checks whether a language comes with the plugin.
+ *
+ * @param language - Language code
+ * @returns True for bundled template languages
+ */
+export function isBundledGrammar(language: string): boolean {
+	return Object.prototype.hasOwnProperty.call(BUNDLED_GRAMMARS, language.trim().toLowerCase());
+}
+
+/**
+ * Registers the bundled grammars with Prism. A language Prism (or a
+ * grammar file) already defines is left alone, as is one whose base
+ * language is missing.
+ *
+ * @param prism - Prism's global object
+ * @returns Languages registered by this call
+ */
+export function registerBundledGrammars(prism: PrismLanguages): string[] {
+	const registered: string[] = [];
+	for (const [language, [base, create]] of Object.entries(BUNDLED_GRAMMARS)) {
+		if (language in prism.languages || !(base in prism.languages)) continue;
+		prism.languages[language] = create(prism.languages[base]);
+		registered.push(language);
+	}
+	return registered;
+}
@@ -0,0 +1,245 @@
+/**
+ * Ultra Code Fence - Vault Lint
+ *
+ * Finds ufence blocks in note content and checks their settings: parse
+ * failures, unknown or mistyped keys, and presets that don't exist. The
+ * results are written up as a markdown report with links to each block.
+ */
+
+import { parseBlockContent, parseConfigFormatFromInfoString, parseConfigText, parseInfoStringOptions, mergeInfoStringOptions, parsePresetReference } from '../parsers';
+import { YAML_META, YAML_SECTIONS } from '../constants';
+import { CODE_BLOCK_SCHEMA, CMDOUT_BLOCK_SCHEMA, validateYamlSchema, formatWarning } from '../ui/yaml-validator';
+
+// =============================================================================
+// Types
+// =============================================================================
+
+/** A ufence block found in a note. */
+export interface UfenceBlock {
+	/** Line number of the opening fence (1-based) */
+	line: number;
+	/** Block type after "ufence-" (e.g. "bash", "cmdout") */
+	blockType: string;
+	/** The opening fence line */
+	fenceLine: string;
+	/** Text between the fences */
+	content: string;
+	/** Line number of the closing fence (1-based) */
+	endLine: number;
+	/** Nearest heading above the block (for linking), if any */
+	heading: string | undefined;
+}
+
+/** Problems found in one block. */
+export interface FenceLintIssue {
+	/** Line number of the opening fence (1-based) */
+	line: number;
+	/** Block type after "ufence-" */
+	blockType: string;
+	/** Nearest heading above the block, if any */
+	heading: string | undefined;
+	/** Problem descriptions */
+	messages: string[];
+}
+
+/** Problems found in one note. */
+export interface FenceLintNoteResult {
+	/** Vault path of the note */
+	path: string;
+	/** Blocks with problems */
+	issues: FenceLintIssue[];
+}
+
+// =============================================================================
+// Block Discovery
+// =============================================================================
+
+/** Any fence line: group 1 is the fence, group 2 the info string */
+const FENCE_LINE_PATTERN = /^\s*(`{3,}|~{3,})(.*)$/;
+
+/** A markdown heading: group 1 is the text */
+const HEADING_PATTERN = /^#{1,6}\s+(.+?)\s*#*\s*$/;
+
+/**
+ * Finds the ufence blocks in a note, skipping other fenced code.
+ *
+ * @param content - Note content
+ * @returns Blocks in document order
+ */
+export function findUfenceBlocks(content: string): UfenceBlock[] {
+	const lines = content.split('\n');
+	const blocks: UfenceBlock[] = [];
+
+	let heading: string | undefined;
+	let openFence: { marker: string; start: number; info: string } | null = null;
+
+	for (let i = 0; i < lines.length; i++) {
+		const line = lines[i];
+		const fenceMatch = FENCE_LINE_PATTERN.exec(line);
+
+		if (!openFence) {
+			if (fenceMatch) {
+				openFence = { marker: fenceMatch[1], start: i, info: fenceMatch[2].trim() };
+				continue;
+			}
+			const headingMatch = HEADING_PATTERN.exec(line);
+			if (headingMatch) heading = headingMatch[1];
+			continue;
+		}
+
+		// Closing fence: same character, at least as long, nothing after it
+		const isClosingFence = fenceMatch !== null
+			&& fenceMatch[1][0] === openFence.marker[0]
+			&& fenceMatch[1].length >= openFence.marker.length
+			&& fenceMatch[2].trim() === '';
+		if (!isClosingFence) continue;
+
+		const typeMatch = /^ufence-(\S+)/.exec(openFence.info);
+		if (typeMatch) {
+			blocks.push({
+				line: openFence.start + 1,
+				blockType: typeMatch[1],
+				fenceLine: lines[openFence.start],
+				content: lines.slice(openFence.start + 1, i).join('\n'),
+				endLine: i + 1,
+				heading,
+			});
+		}
+		openFence = null;
+	}
+
+	return blocks;
+}
+
+// =============================================================================
+// Linting
+// =============================================================================
+
+/**
+ * Reads the preset a block names, from META.PRESET or (in ufence-ufence
+ * blocks) the top-level PRESET shorthand.
+ *
+ * @param settings - Parsed block settings
+ * @returns Preset name, or undefined
+ */
+function getPresetName(settings: Record<string, unknown>): string | undefined {
+	const meta = settings[YAML_SECTIONS.meta];
+	const preset = meta && typeof meta === 'object'
+		? (meta as Record<string, unknown>)[YAML_META.preset]
+		: settings[YAML_META.preset];
+
+	return typeof preset === 'string' || typeof preset === 'number' ? String(preset) : undefined;
+}
+
+/**
+ * Checks one block's settings.
+ *
+ * @param block - Block to check
+ * @param presetNames - Names of the saved presets
+ * @returns Problem descriptions (empty when the block is fine)
+ */
+function lintBlock(block: UfenceBlock, presetNames: string[]): string[] {
+	// Saved run output has no settings
+	if (block.blockType === 'output') return [];
+
+	const configFormat = parseConfigFormatFromInfoString(block.fenceLine);
+	const isPageConfig = block.blockType === 'ufence';
+
+	const shorthand = isPageConfig ? { settings: {}, problems: [] } : parseInfoStringOptions(block.fenceLine);
+
+	let settings: Record<string, unknown>;
+	try {
+		settings = isPageConfig
+			? parseConfigText(block.content, configFormat)
+			: mergeInfoStringOptions(parseBlockContent(block.content, configFormat).yamlProperties, shorthand.settings);
+	} catch (error) {
+		return [`Settings could not be parsed: ${error instanceof Error ? error.message : String(error)}`];
+	}
+
+	const schema = block.blockType === 'cmdout' ? CMDOUT_BLOCK_SCHEMA : CODE_BLOCK_SCHEMA;
+	const messages = [
+		...shorthand.problems,
+		...validateYamlSchema(settings, schema)
+			// ufence-ufence blocks may name their preset at the top level
+			.filter(warning => !(isPageConfig && warning.path === YAML_META.preset))
+			.map(formatWarning),
+	];
+
+	// The name may carry preset arguments, e.g. api-example(title="Create user")
+	const presetName = getPresetName(settings);
+	if (presetName !== undefined && !presetNames.includes(presetName) && !presetNames.includes(parsePresetReference(presetName).name)) {
+		messages.push(`Unknown preset: ${parsePresetReference(presetName).name}`);
+	}
+
+	return messages;
+}
+
+/**
+ * Checks every ufence block in a note.
+ *
+ * @param content - Note content
+ * @param presetNames - Names of the saved presets
+ * @returns Blocks with problems, in document order
+ */
+export function lintNoteContent(content: string, presetNames: string[]): FenceLintIssue[] {
+	const issues: FenceLintIssue[] = [];
+
+	for (const block of findUfenceBlocks(content)) {
+		const messages = lintBlock(block, presetNames);
+		if (messages.length > 0) {
+			issues.push({ line: block.line, blockType: block.blockType, heading: block.heading, messages });
+		}
+	}
+
+	return issues;
+}
+
+// =============================================================================
+// Report
+// =============================================================================
+
+/**
+ * Builds a wikilink to a block: to its nearest heading when there is one,
+ * otherwise to the note.
+ *
+ * @param notePath - Vault path of the note
+ * @param issue - Block with problems
+ * @returns Wikilink labelled with the block's line number
+ */
+function buildBlockLink(notePath: string, issue: FenceLintIssue): string {
+	const target = notePath.replace(/\.md$/, '');
+	// Characters that can't appear in a link target
+	const anchor = issue.heading ? `#${issue.heading.replace(/[#|^[\]:]/g, ' ').replace(/\s+/g, ' ').trim()}` : '';
+	return `[[${target}${anchor}|Line ${String(issue.line)}]]`;
+}
+
+/**
+ * Builds the markdown lint report.
+ *
+ * @param results - Notes with problems
+ * @param notesChecked - Number of notes scanned
+ * @returns Report note content
+ */
+export function buildLintReport(results: FenceLintNoteResult[], notesChecked: number): string {
+	const blockCount = results.reduce((total, result) => total + result.issues.length, 0);
+	const lines = ['# Code block check', ''];
+
+	if (blockCount === 0) {
+		lines.push(`Checked ${String(notesChecked)} notes: no problems found.`);
+		return lines.join('\n') + '\n';
+	}
+
+	lines.push(`Checked ${String(notesChecked)} notes: ${String(blockCount)} ${blockCount === 1 ? 'block' : 'blocks'} with problems in ${String(results.length)} ${results.length === 1 ? 'note' : 'notes'}.`);
+
+	for (const result of results) {
+		lines.push('', `## ${result.path.replace(/\.md$/, '')}`, '');
+		for (const issue of result.issues) {
+			lines.push(`- ${buildBlockLink(result.path, issue)} · \`ufence-${issue.blockType}\``);
+			for (const message of issue.messages) {
+				lines.push(`\t- ${message}`);
+			}
+		}
+	}
+
+	return lines.join('\n') + '\n';
+}
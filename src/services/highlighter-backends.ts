@@ -0,0 +1,205 @@
+/**
+ * Ultra Code Fence - Highlighter Backends
+ *
+ * Lets blocks be highlighted by an engine other than Obsidian's Prism.
+ * A backend returns tokens in Prism's shape, so they render as the same
+ * `span.token.<name>` elements and TOKENS, highlight themes, comment
+ * keywords and embedded languages work whichever engine ran. Prism stays
+ * the fallback: for languages listed to keep it, for languages the
+ * backend doesn't know, and when the backend can't be loaded.
+ *
+ * Shiki is the backend so far. It isn't bundled (with its grammars it is
+ * several megabytes); the settings point at a browser build of it in the
+ * vault, which is loaded on first use. Its TextMate scopes are mapped to
+ * Prism token names the same way as custom TextMate grammars.
+ */
+
+import type { PrismToken, PrismTokenStream } from './embedded-languages';
+import { textMateScopeToToken } from './custom-grammars';
+
+/** Highlighting engines a vault can use. */
+export const HIGHLIGHTER_ENGINES = ['prism', 'shiki'] as const;
+
+/** A highlighting engine. */
+export type HighlighterEngine = typeof HIGHLIGHTER_ENGINES[number];
+
+/** A highlighting engine other than Prism. */
+export interface HighlighterBackend {
+	/** The engine */
+	engine: HighlighterEngine;
+
+	/**
+	 * Tokenizes code.
+	 *
+	 * @returns Prism-shaped tokens, or null if the engine doesn't know the language
+	 */
+	tokenize(code: string, language: string): Promise<PrismTokenStream | null>;
+}
+
+/** A scope in Shiki's explanation of a token. */
+export interface ShikiScope {
+	scopeName: string;
+}
+
+/** A token from Shiki's tokenizer. */
+export interface ShikiToken {
+	content: string;
+
+	/** The TextMate tokens it was merged from, with their scopes (outermost first) */
+	explanation?: Array<{ content: string; scopes: ShikiScope[] }>;
+}
+
+/** A Shiki highlighter, as far as tokenizing needs it. */
+export interface ShikiHighlighter {
+	getLoadedLanguages(): string[];
+	loadLanguage(...languages: string[]): Promise<void>;
+	codeToTokensBase(code: string, options: { lang: string; theme: string; includeExplanation: boolean }): ShikiToken[][];
+}
+
+/** The parts of Shiki's module the backend uses. */
+export interface ShikiModule {
+	/** Languages the build can load, by name and alias */
+	bundledLanguages: Record<string, unknown>;
+
+	createHighlighter(options: { themes: string[]; langs: string[] }): Promise<ShikiHighlighter>;
+}
+
+/**
+ * Checks whether a value names a highlighting engine.
+ *
+ * @param value - Setting value
+ * @returns True for a known engine
+ */
+export function isHighlighterEngine(value: string): value is HighlighterEngine {
+	return (HIGHLIGHTER_ENGINES as readonly string[]).includes(value);
+}
+
+/**
+ * Picks the engine that highlights a language.
+ *
+ * @param engine - Engine chosen in the settings
+ * @param prismLanguages - Comma-separated languages that stay on Prism
+ * @param language - The block's language (after aliases)
+ * @returns The engine to try; an unknown engine reads as Prism
+ */
+export function pickHighlighterEngine(engine: string, prismLanguages: string, language: string): HighlighterEngine {
+	const chosen = engine.trim().toLowerCase();
+	if (!isHighlighterEngine(chosen) || chosen === 'prism') return 'prism';
+
+	const kept = prismLanguages.split(',').map(entry => entry.trim().toLowerCase());
+	return kept.includes(language.trim().toLowerCase()) ? 'prism' : chosen;
+}
+
+/**
+ * Maps a Shiki token's scopes to a Prism token name, trying the
+ * innermost scope first ("string.quoted" inside "source.go" is a string).
+ * The quotes of a string and the marker of a comment take the token
+ * around them, as in Prism, rather than being punctuation.
+ *
+ * @param scopes - The token's scopes, outermost first
+ * @returns Prism token name, or null for plain text
+ */
+function scopesToToken(scopes: readonly ShikiScope[]): string | null {
+	let delimiter: string | null = null;
+	for (let i = scopes.length - 1; i >= 0; i--) {
+		const token = textMateScopeToToken(scopes[i].scopeName);
+		if (!token) continue;
+		if (!scopes[i].scopeName.startsWith('punctuation.definition.')) return token;
+		delimiter = delimiter ?? token;
+	}
+	return delimiter;
+}
+
+/**
+ * Converts Shiki's tokens, line by line, into a Prism token stream.
+ * Neighbouring text with the same token is joined into one token.
+ *
+ * @param lines - Tokens of each line, from codeToTokensBase
+ * @returns Token stream of the whole code, lines joined by newlines
+ */
+export function shikiTokensToPrism(lines: readonly ShikiToken[][]): PrismTokenStream {
+	const stream: PrismTokenStream = [];
+
+	const push = (text: string, type: string | null): void => {
+		if (!text) return;
+		const last = stream.length > 0 ? stream[stream.length - 1] : undefined;
+		if (type === null) {
+			if (typeof last === 'string') {
+				stream[stream.length - 1] = last + text;
+			} else {
+				stream.push(text);
+			}
+		} else if (last !== undefined && typeof last !== 'string' && last.type === type && typeof last.content === 'string') {
+			last.content += text;
+		} else {
+			const token: PrismToken = { type, content: text };
+			stream.push(token);
+		}
+	};
+
+	lines.forEach((line, index) => {
+		if (index > 0) push('\n', null);
+		for (const token of line) {
+			if (token.explanation && token.explanation.length > 0) {
+				for (const part of token.explanation) push(part.content, scopesToToken(part.scopes));
+			} else {
+				push(token.content, null);
+			}
+		}
+	});
+
+	return stream;
+}
+
+/**
+ * Reads Shiki's module, as loaded from its browser build.
+ *
+ * @param value - The module namespace
+ * @returns The module, or null if it isn't Shiki (older builds name
+ *   createHighlighter getHighlighter)
+ */
+export function readShikiModule(value: unknown): ShikiModule | null {
+	if (typeof value !== 'object' || value === null) return null;
+
+	const module = value as Record<string, unknown>;
+	const create = typeof module.createHighlighter === 'function' ? module.createHighlighter
+		: typeof module.getHighlighter === 'function' ? module.getHighlighter
+			: null;
+	const languages = module.bundledLanguages;
+	if (!create || typeof languages !== 'object' || languages === null) return null;
+
+	return {
+		bundledLanguages: languages as Record<string, unknown>,
+		createHighlighter: create as ShikiModule['createHighlighter'],
+	};
+}
+
+/**
+ * Creates the Shiki backend. The highlighter is created on first use,
+ * and each language's grammar is loaded the first time it is needed.
+ * Tokens are read without a theme, since their colours come from the
+ * Obsidian theme (or RENDER.THEME) through the Prism token classes.
+ *
+ * @param shiki - Shiki's module
+ * @returns The backend
+ */
+export function createShikiBackend(shiki: ShikiModule): HighlighterBackend {
+	let highlighter: Promise<ShikiHighlighter> | null = null;
+
+	return {
+		engine: 'shiki',
+		async tokenize(code: string, language: string): Promise<PrismTokenStream | null> {
+			const lang = language.trim().toLowerCase();
+			if (!Object.prototype.hasOwnProperty.call(shiki.bundledLanguages, lang)) return null;
+
+			if (!highlighter) {
+				highlighter = shiki.createHighlighter({ themes: [], langs: [] });
+			}
+			const instance = await highlighter;
+			if (!instance.getLoadedLanguages().includes(lang)) {
+				await instance.loadLanguage(lang);
+			}
+			return shikiTokensToPrism(instance.codeToTokensBase(code, { lang, theme: 'none', includeExplanation: true }));
+		},
+	};
+}
@@ -10,6 +10,10 @@ export {
 	isVaultPath,
 	loadVaultFile,
 	loadRemoteFile,
+	normalizeGitRepositories,
+	gitRepositoryPaths,
+	parseGitSourcePath,
+	loadGitFile,
 	loadSource,
 	extractFileMetadata,
 	createEmbeddedCodeMetadata,
@@ -29,4 +33,103 @@ export {
 export {
 	buildSuggestedFilename,
 	downloadCodeToFile,
+	getDefaultShebang,
+	injectShebang,
+	saveExecutableFile,
 } from './download-service';
+
+export { ClipboardHistory } from './clipboard-history';
+
+export type { UfenceBlock, FenceLintIssue, FenceLintNoteResult } from './fence-lint';
+
+export { findUfenceBlocks, lintNoteContent, buildLintReport } from './fence-lint';
+
+export type { BlockReference } from './block-references';
+
+export { readBlockSettings, readMetaText, readReferenceId, mergeReferencedSettings, findReferenceableBlocks, findBlockId, BlockReferenceIndex } from './block-references';
+
+export type { Snippet } from './snippet-library';
+
+export { findSnippets, buildSnippetReference, matchSnippet, SnippetLibrary } from './snippet-library';
+
+export type { BlockChanges } from './block-changes';
+
+export { diffUfenceBlocks } from './block-changes';
+
+export type { ConfigMigrationChange, ConfigMigrationPlan } from './config-migration';
+
+export { migrateNoteContent, planNoteMigration, getUndoContent } from './config-migration';
+
+export type { PresetConflictAction, PresetImportResult } from './preset-transfer';
+
+export { buildPresetPack, parsePresetPack, findFreePresetName, importPresets } from './preset-transfer';
+
+export type { AnnotationContext, AnnotationProvider, ContributedAnnotation, Disposer, UltraCodeFenceApi, CodeRunner, RunContext, RunRequest, ContributedRunResult } from './contributions';
+
+export { ContributionRegistry, PLUGIN_API_VERSION } from './contributions';
+
+export { parseJsonWithComments, vscodeSettingsToStyle, convertVSCodeTheme, highlightThemeKey } from './vscode-theme';
+
+export type { PrismToken, PrismTokenStream, PrismTokenizer, LanguageInjection, DelimitedText } from './embedded-languages';
+
+export { findLanguageInjections, splitStringDelimiters, isBundledGrammar, registerBundledGrammars } from './embedded-languages';
+
+export type { HighlighterEngine, HighlighterBackend, ShikiScope, ShikiToken, ShikiHighlighter, ShikiModule } from './highlighter-backends';
+
+export { HIGHLIGHTER_ENGINES, isHighlighterEngine, pickHighlighterEngine, shikiTokensToPrism, readShikiModule, createShikiBackend } from './highlighter-backends';
+
+export type { GalleryInstallResult } from './preset-gallery';
+
+export { findInstalledGalleryPreset, installGalleryPreset } from './preset-gallery';
+
+export type { PrismTokenRule, PrismGrammar, PrismLanguages, CustomGrammar, CustomGrammarLoadResult } from './custom-grammars';
+
+export {
+	grammarLanguageFromPath,
+	parseGrammarFile,
+	convertPrismGrammar,
+	convertTextMateGrammar,
+	textMateScopeToToken,
+	loadCustomGrammars,
+	registerCustomGrammars,
+	registerLanguageAliases,
+	isInGrammarFolder,
+} from './custom-grammars';
+
+export {
+	buildCopyUsageKey,
+	findCopyUsageKeys,
+	copyUsageNotePath,
+	pruneCopyUsage,
+	moveCopyUsage,
+	getCopyCount,
+	incrementCopyCount,
+} from './copy-usage';
+
+export type { RemoteCacheEntry, RemoteSourceStatus, CachedRemoteLoadResult, CachedRemoteLoadOptions } from './remote-cache';
+
+export { remoteCacheFileName, isRemoteCacheFresh, formatFetchAge, RemoteSourceCache, loadCachedRemoteFile } from './remote-cache';
+
+export type { CodeQuery, QueryNote, FencedCode, CodeQueryMatch } from './code-query';
+
+export { isDataviewQuery, parseCodeQuery, findFencedCode, matchCodeQuery, noteMatchesQuery, formatCodeQueryMatches, formatComment, runCodeQuery, runDataviewQuery, dataviewValueText } from './code-query';
+
+export type { OutputStream, RunStopReason, RunResult, RunControlOptions, ShellInvocation, ShellEnvironment, ShellRunOptions, SpawnedProcess, SpawnFunction } from './shell-runner';
+
+export { isShellLanguage, resolveShellInvocation, superviseRun, runShellInvocation, loadDesktopShell } from './shell-runner';
+
+export type { ScriptWorker, ScriptWorkerFactory } from './script-runner';
+
+export { SCRIPT_WORKER_SOURCE, isScriptLanguage, prepareScript, runScript } from './script-runner';
+
+export { loadVaultLocalStorage, saveVaultLocalStorage } from './vault-storage';
+
+export { loadRunConsent, saveRunConsent } from './run-consent';
+
+export type { SecretValues, RunEnvironment } from './run-environment';
+
+export { loadSecrets, saveSecrets, resolveRunEnvironment, maskSecrets } from './run-environment';
+
+export type { NoteSnapshot } from './note-snapshots';
+
+export { parseSnapshotAge, pickSnapshot, matchSnapshotBlock, findChangedLines, readFileRecoverySnapshots } from './note-snapshots';
@@ -0,0 +1,166 @@
+/**
+ * Ultra Code Fence - Note Snapshots
+ *
+ * Finds the lines of a block that changed since an earlier version of
+ * its note, for a "what's new in this runbook" view (RENDER.CHANGED_SINCE).
+ *
+ * The earlier versions are the snapshots Obsidian's File recovery core
+ * plugin keeps. Obsidian has no public API for them, so they are read
+ * from the plugin's own store; if the plugin is off, or its store isn't
+ * laid out as expected, there are no snapshots and nothing is marked.
+ * Obsidian Sync's version history isn't open to plugins.
+ */
+
+import type { App } from 'obsidian';
+import type { UfenceBlock } from './fence-lint';
+import { diffLineLists } from '../utils/diff';
+
+/** A saved version of a note. */
+export interface NoteSnapshot {
+	/** When it was saved (ms since the epoch) */
+	time: number;
+
+	/** The note's content */
+	content: string;
+}
+
+/** Length of each CHANGED_SINCE unit in milliseconds. */
+const AGE_UNITS: Record<string, number | undefined> = {
+	m: 60 * 1000,
+	h: 60 * 60 * 1000,
+	d: 24 * 60 * 60 * 1000,
+	w: 7 * 24 * 60 * 60 * 1000,
+};
+
+/** Lines that don't tell blocks apart: blank lines and the ~~~ separator. */
+const UNSHARED_LINE_PATTERN = /^\s*(?:~~~\s*)?$/;
+
+/** Object store and index the File recovery plugin keeps snapshots in. */
+const FILE_RECOVERY_STORE = 'backups';
+const FILE_RECOVERY_PATH_INDEX = 'path';
+
+/**
+ * Reads a CHANGED_SINCE value.
+ *
+ * @param value - "last" (the latest snapshot that differs from the note),
+ *   an age such as "12h", "3d" or "2w", or "off"
+ * @returns The age in milliseconds (0 = "last"), or null when off or unreadable
+ */
+export function parseSnapshotAge(value: string): number | null {
+	const text = value.trim().toLowerCase();
+	if (text === 'last') return 0;
+
+	const match = /^(\d+)\s*([mhdw])$/.exec(text);
+	const unit = match ? AGE_UNITS[match[2]] : undefined;
+	return match && unit !== undefined ? Number(match[1]) * unit : null;
+}
+
+/**
+ * Picks the snapshot to compare a note against.
+ *
+ * With an age of 0 it is the latest snapshot that differs from the note
+ * now. Otherwise it is the latest snapshot at least that old, or the
+ * oldest one when none goes back that far.
+ *
+ * @param snapshots - The note's snapshots, in any order
+ * @param currentContent - The note as it is now
+ * @param age - How far back to look, in milliseconds
+ * @param now - The current time (ms since the epoch)
+ * @returns The snapshot, or null if there is none to compare against
+ */
+export function pickSnapshot(snapshots: readonly NoteSnapshot[], currentContent: string, age: number, now: number): NoteSnapshot | null {
+	const newestFirst = [...snapshots].sort((a, b) => b.time - a.time);
+	if (age === 0) {
+		return newestFirst.find(snapshot => snapshot.content !== currentContent) ?? null;
+	}
+	return newestFirst.find(snapshot => snapshot.time <= now - age) ?? newestFirst[newestFirst.length - 1] ?? null;
+}
+
+/**
+ * Finds a block's earlier version among a snapshot's blocks: the block
+ * of the same type (and fence line, if one still has it) sharing the
+ * most lines with it, so blocks added or moved elsewhere in the note
+ * don't throw the match off. Blank lines and the ~~~ separator, which
+ * most blocks have, don't count.
+ *
+ * @param block - The block in the note as it is now
+ * @param previousBlocks - Every block in the snapshot
+ * @returns The earlier version, or null if the block is new
+ */
+export function matchSnapshotBlock(block: UfenceBlock, previousBlocks: readonly UfenceBlock[]): UfenceBlock | null {
+	const sameType = previousBlocks.filter(candidate => candidate.blockType === block.blockType);
+	const sameFence = sameType.filter(candidate => candidate.fenceLine === block.fenceLine);
+	const candidates = sameFence.length > 0 ? sameFence : sameType;
+
+	const lines = block.content.split('\n');
+	let best: UfenceBlock | null = null;
+	let bestShared = 0;
+	for (const candidate of candidates) {
+		if (candidate.content === block.content) return candidate;
+
+		const shared = diffLineLists(candidate.content.split('\n'), lines)
+			.filter(step => step.kind === 'context' && step.newIndex !== null && !UNSHARED_LINE_PATTERN.test(lines[step.newIndex]))
+			.length;
+		if (shared > bestShared) {
+			best = candidate;
+			bestShared = shared;
+		}
+	}
+	return best;
+}
+
+/**
+ * Finds the lines of code that are new or changed since an earlier
+ * version. Lines that were only removed leave nothing to mark.
+ *
+ * @param previousCode - The earlier code
+ * @param currentCode - The code now
+ * @returns Changed line numbers (1-based)
+ */
+export function findChangedLines(previousCode: string, currentCode: string): number[] {
+	return diffLineLists(previousCode.split('\n'), currentCode.split('\n'))
+		.filter(step => step.kind === 'added' && step.newIndex !== null)
+		.map(step => (step.newIndex ?? 0) + 1);
+}
+
+/**
+ * Reads a note's snapshots from the File recovery core plugin.
+ *
+ * @param app - Obsidian app
+ * @param path - Vault path of the note
+ * @returns Snapshots, or none if they can't be read
+ */
+export async function readFileRecoverySnapshots(app: App, path: string): Promise<NoteSnapshot[]> {
+	const internal = (app as unknown as { internalPlugins?: { getPluginById?(id: string): unknown } }).internalPlugins;
+	const plugin = internal?.getPluginById?.('file-recovery') as { enabled?: boolean; instance?: { db?: unknown } } | null | undefined;
+	const db = plugin?.enabled ? plugin.instance?.db : undefined;
+	if (db === undefined || !(db instanceof IDBDatabase) || !db.objectStoreNames.contains(FILE_RECOVERY_STORE)) return [];
+
+	try {
+		const records = await new Promise<unknown[]>((resolve, reject) => {
+			const request = db.transaction(FILE_RECOVERY_STORE, 'readonly')
+				.objectStore(FILE_RECOVERY_STORE)
+				.index(FILE_RECOVERY_PATH_INDEX)
+				.getAll(path);
+			request.onsuccess = () => { resolve(request.result as unknown[]); };
+			request.onerror = () => { reject(request.error ?? new Error('could not read snapshots')); };
+		});
+		return records
+			.map(readSnapshotRecord)
+			.filter((snapshot): snapshot is NoteSnapshot => snapshot !== null);
+	} catch {
+		return [];
+	}
+}
+
+/**
+ * Reads one record from the File recovery store.
+ *
+ * @param record - Stored record ({ path, ts, data })
+ * @returns The snapshot, or null if the record isn't one
+ */
+function readSnapshotRecord(record: unknown): NoteSnapshot | null {
+	if (typeof record !== 'object' || record === null) return null;
+	const { ts, data } = record as { ts?: unknown; data?: unknown };
+	return typeof ts === 'number' && typeof data === 'string' ? { time: ts, content: data } : null;
+}
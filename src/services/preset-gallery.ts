@@ -0,0 +1,50 @@
+/**
+ * Ultra Code Fence - Preset Gallery
+ *
+ * Installs presets from the curated gallery bundled with the plugin into
+ * the user's saved presets.
+ */
+
+import type { PresetGalleryEntry } from '../types';
+import { findFreePresetName } from './preset-transfer';
+
+/** Result of installing a gallery preset. */
+export interface GalleryInstallResult {
+	/** Saved presets after the install */
+	presets: Record<string, string>;
+	/** Name the preset is saved under */
+	name: string;
+	/** Whether anything changed (false when the same preset is already saved) */
+	installed: boolean;
+}
+
+/**
+ * Finds the saved preset that matches a gallery preset, if any.
+ *
+ * @param existing - Saved presets
+ * @param entry - Gallery preset
+ * @returns Name of a saved preset with the same content, or undefined
+ */
+export function findInstalledGalleryPreset(existing: Record<string, string>, entry: PresetGalleryEntry): string | undefined {
+	if (existing[entry.name] === entry.yaml) return entry.name;
+	return Object.keys(existing).sort().find(name => existing[name] === entry.yaml);
+}
+
+/**
+ * Adds a gallery preset to the saved presets. A saved preset with the
+ * same name is never replaced: the gallery preset gets a free name
+ * instead (name-2, name-3, …).
+ *
+ * @param existing - Saved presets
+ * @param entry - Gallery preset to install
+ * @returns The updated presets and the name used
+ */
+export function installGalleryPreset(existing: Record<string, string>, entry: PresetGalleryEntry): GalleryInstallResult {
+	const installedName = findInstalledGalleryPreset(existing, entry);
+	if (installedName !== undefined) {
+		return { presets: existing, name: installedName, installed: false };
+	}
+
+	const name = entry.name in existing ? findFreePresetName(entry.name, new Set(Object.keys(existing))) : entry.name;
+	return { presets: { ...existing, [name]: entry.yaml }, name, installed: true };
+}
@@ -0,0 +1,158 @@
+/**
+ * Ultra Code Fence - Preset Import and Export
+ *
+ * Writes presets to a JSON preset pack and reads them back, so a set of
+ * presets can be shared between vaults. A pack looks like:
+ *
+ *     {
+ *       "format": "ultra-code-fence-presets",
+ *       "version": 1,
+ *       "presets": { "base": "RENDER:\n  LINES: true\n" }
+ *     }
+ *
+ * Each preset keeps its raw YAML, exactly as stored in the settings.
+ */
+
+import { PRESET_PACK_FORMAT, PRESET_PACK_VERSION } from '../constants';
+
+// =============================================================================
+// Types
+// =============================================================================
+
+/** What to do with an imported preset whose name is already taken. */
+export type PresetConflictAction = 'skip' | 'rename' | 'overwrite';
+
+/** Outcome of importing a preset pack. */
+export interface PresetImportResult {
+	/** Presets after the import */
+	presets: Record<string, string>;
+	/** Names added without a conflict */
+	added: string[];
+	/** Names whose existing preset was replaced */
+	overwritten: string[];
+	/** Presets saved under a new name */
+	renamed: { from: string; to: string }[];
+	/** Names left as they were */
+	skipped: string[];
+}
+
+// =============================================================================
+// Export
+// =============================================================================
+
+/**
+ * Builds a preset pack from some of the saved presets.
+ *
+ * @param presets - Saved presets
+ * @param names - Names of the presets to include (unknown names are ignored)
+ * @returns Pack as formatted JSON
+ */
+export function buildPresetPack(presets: Record<string, string>, names: string[]): string {
+	const packed: Record<string, string> = {};
+	for (const name of names) {
+		const presetYaml: string | undefined = presets[name];
+		if (presetYaml !== undefined) {
+			packed[name] = presetYaml;
+		}
+	}
+
+	return `${JSON.stringify({ format: PRESET_PACK_FORMAT, version: PRESET_PACK_VERSION, presets: packed }, null, 2)}\n`;
+}
+
+// =============================================================================
+// Import
+// =============================================================================
+
+/**
+ * Reads the presets from a preset pack.
+ *
+ * @param text - Contents of the pack file
+ * @returns Presets by name
+ * @throws Error with a readable message if the file isn't a preset pack
+ */
+export function parsePresetPack(text: string): Record<string, string> {
+	let pack: unknown;
+	try {
+		pack = JSON.parse(text);
+	} catch {
+		throw new Error('The file is not valid JSON');
+	}
+
+	if (!pack || typeof pack !== 'object' || (pack as Record<string, unknown>).format !== PRESET_PACK_FORMAT) {
+		throw new Error('The file is not an Ultra Code Fence preset pack');
+	}
+
+	const { version, presets } = pack as Record<string, unknown>;
+	if (typeof version !== 'number' || version > PRESET_PACK_VERSION) {
+		throw new Error('The preset pack was made by a newer version of the plugin');
+	}
+	if (!presets || typeof presets !== 'object' || Array.isArray(presets)) {
+		throw new Error('The preset pack has no presets');
+	}
+
+	const result: Record<string, string> = {};
+	for (const [name, presetYaml] of Object.entries(presets as Record<string, unknown>)) {
+		if (name.trim() && typeof presetYaml === 'string') {
+			result[name] = presetYaml;
+		}
+	}
+	return result;
+}
+
+/**
+ * Finds a name for a renamed preset: the name with the first free
+ * number appended (base-2, base-3, …).
+ *
+ * @param name - Preset name that is taken
+ * @param taken - Names in use
+ * @returns Free name
+ */
+export function findFreePresetName(name: string, taken: ReadonlySet<string>): string {
+	let suffix = 2;
+	while (taken.has(`${name}-${String(suffix)}`)) {
+		suffix++;
+	}
+	return `${name}-${String(suffix)}`;
+}
+
+/**
+ * Adds imported presets to the saved ones.
+ *
+ * A preset whose content matches the saved one with the same name counts
+ * as skipped whatever the action, so importing a pack twice changes nothing.
+ *
+ * @param existing - Saved presets
+ * @param incoming - Presets from the pack
+ * @param action - What to do when a name is already taken
+ * @returns Presets after the import and what happened to each
+ */
+export function importPresets(
+	existing: Record<string, string>,
+	incoming: Record<string, string>,
+	action: PresetConflictAction
+): PresetImportResult {
+	const result: PresetImportResult = { presets: { ...existing }, added: [], overwritten: [], renamed: [], skipped: [] };
+	// New names avoid every name in the pack too, so a later preset can't clash
+	const taken = new Set([...Object.keys(existing), ...Object.keys(incoming)]);
+
+	for (const [name, presetYaml] of Object.entries(incoming)) {
+		const current: string | undefined = existing[name];
+
+		if (current === undefined) {
+			result.presets[name] = presetYaml;
+			result.added.push(name);
+		} else if (current === presetYaml || action === 'skip') {
+			result.skipped.push(name);
+		} else if (action === 'overwrite') {
+			result.presets[name] = presetYaml;
+			result.overwritten.push(name);
+		} else {
+			const newName = findFreePresetName(name, taken);
+			result.presets[newName] = presetYaml;
+			result.renamed.push({ from: name, to: newName });
+			taken.add(newName);
+		}
+	}
+
+	return result;
+}
@@ -0,0 +1,204 @@
+/**
+ * Ultra Code Fence - Remote Cache
+ *
+ * Keeps a copy of every file a block loads from a URL (META.PATH or
+ * META.SRC), so the block still renders offline and doesn't refetch on
+ * every render. Copies are files in the plugin's folder, one per URL.
+ */
+
+import type { DataAdapter } from 'obsidian';
+import type { SourceLoadResult } from '../types';
+import { extractFileMetadata, loadRemoteFile } from './source-loader';
+
+/** A cached copy of a remote file. */
+export interface RemoteCacheEntry {
+	/** URL it was fetched from */
+	url: string;
+
+	/** When it was fetched (ms since the epoch) */
+	fetchedAt: number;
+
+	/** File content */
+	text: string;
+}
+
+/** Where a remote block's code came from, for the status line under it. */
+export interface RemoteSourceStatus {
+	/** URL of the file */
+	url: string;
+
+	/** When the code shown was fetched (ms since the epoch) */
+	fetchedAt: number;
+
+	/** Whether the fetch failed and the cached copy is shown instead */
+	offline: boolean;
+}
+
+/** A remote load, with where the code came from when it succeeded. */
+export interface CachedRemoteLoadResult extends SourceLoadResult {
+	/** Fetch time and offline state of the code */
+	remoteStatus?: RemoteSourceStatus;
+}
+
+/** Options for {@link loadCachedRemoteFile}. */
+export interface CachedRemoteLoadOptions {
+	/** How long a cached copy is used without refetching (ms; 0 = always fetch) */
+	maxAge: number;
+
+	/** Fetch even if the cached copy is fresh (the refresh button) */
+	force?: boolean;
+
+	/** The current time (ms since the epoch) */
+	now: number;
+}
+
+// =============================================================================
+// Cache Keys and Ages
+// =============================================================================
+
+/**
+ * Names the cache file for a URL: a 32-bit FNV-1a hash of it, so any
+ * URL gives a safe file name.
+ *
+ * @param url - Remote file URL
+ * @returns File name, e.g. "3f2a9c1b.json"
+ */
+export function remoteCacheFileName(url: string): string {
+	let hash = 0x811c9dc5;
+	for (let i = 0; i < url.length; i++) {
+		hash ^= url.charCodeAt(i);
+		hash = Math.imul(hash, 0x01000193);
+	}
+	return `${(hash >>> 0).toString(16).padStart(8, '0')}.json`;
+}
+
+/**
+ * Checks whether a cached copy can be shown without refetching.
+ *
+ * @param entry - Cached copy
+ * @param maxAge - How long a copy stays fresh (ms)
+ * @param now - The current time (ms since the epoch)
+ * @returns True if fetched less than maxAge ago
+ */
+export function isRemoteCacheFresh(entry: RemoteCacheEntry, maxAge: number, now: number): boolean {
+	return maxAge > 0 && now - entry.fetchedAt < maxAge;
+}
+
+/**
+ * Describes how long ago something was fetched, for the status line.
+ *
+ * @param age - Time since the fetch (ms)
+ * @returns "just now", "5 min ago", "3 h ago" or "2 d ago"
+ */
+export function formatFetchAge(age: number): string {
+	const minutes = Math.floor(age / 60000);
+	if (minutes < 1) return 'just now';
+	if (minutes < 60) return `${String(minutes)} min ago`;
+
+	const hours = Math.floor(minutes / 60);
+	if (hours < 24) return `${String(hours)} h ago`;
+
+	return `${String(Math.floor(hours / 24))} d ago`;
+}
+
+// =============================================================================
+// Cache Store
+// =============================================================================
+
+/**
+ * Cached remote files in a folder of the vault's config directory.
+ */
+export class RemoteSourceCache {
+	private adapter: DataAdapter;
+	private folder: string;
+
+	/**
+	 * Creates a cache over a folder, made when the first copy is written.
+	 *
+	 * @param adapter - Vault data adapter
+	 * @param folder - Folder the copies are kept in
+	 */
+	constructor(adapter: DataAdapter, folder: string) {
+		this.adapter = adapter;
+		this.folder = folder;
+	}
+
+	/**
+	 * Reads the cached copy of a URL.
+	 *
+	 * @param url - Remote file URL
+	 * @returns The copy, or null if there is none or it can't be read
+	 */
+	async read(url: string): Promise<RemoteCacheEntry | null> {
+		const path = `${this.folder}/${remoteCacheFileName(url)}`;
+		try {
+			if (!(await this.adapter.exists(path))) return null;
+			const entry = JSON.parse(await this.adapter.read(path)) as Partial<RemoteCacheEntry>;
+			// Another URL with the same hash isn't this one's copy
+			return entry.url === url && typeof entry.fetchedAt === 'number' && typeof entry.text === 'string'
+				? { url, fetchedAt: entry.fetchedAt, text: entry.text }
+				: null;
+		} catch {
+			return null;
+		}
+	}
+
+	/**
+	 * Stores a copy, replacing the URL's previous one. A copy that can't
+	 * be written is skipped; the block still shows what was fetched.
+	 *
+	 * @param entry - Copy to store
+	 */
+	async write(entry: RemoteCacheEntry): Promise<void> {
+		try {
+			if (!(await this.adapter.exists(this.folder))) {
+				await this.adapter.mkdir(this.folder);
+			}
+			await this.adapter.write(`${this.folder}/${remoteCacheFileName(entry.url)}`, JSON.stringify(entry));
+		} catch (error) {
+			console.error('Ultra Code Fence: could not cache', entry.url, error);
+		}
+	}
+}
+
+// =============================================================================
+// Loading
+// =============================================================================
+
+/**
+ * Loads a remote file through the cache: a fresh cached copy is used as
+ * is, otherwise the file is fetched and cached, and if the fetch fails
+ * the cached copy is shown however old it is.
+ *
+ * @param url - Remote file URL
+ * @param cache - Cache to read and update
+ * @param options - Freshness, forcing and the current time
+ * @param fetchRemote - Fetches the file (replaceable in tests)
+ * @returns Load result, with the fetch time and offline state on success
+ */
+export async function loadCachedRemoteFile(
+	url: string,
+	cache: RemoteSourceCache,
+	options: CachedRemoteLoadOptions,
+	fetchRemote: (url: string) => Promise<SourceLoadResult> = loadRemoteFile
+): Promise<CachedRemoteLoadResult> {
+	const cached = await cache.read(url);
+	const fromCache = (entry: RemoteCacheEntry, offline: boolean): CachedRemoteLoadResult => ({
+		succeeded: true,
+		sourceCode: entry.text,
+		fileMetadata: extractFileMetadata(url),
+		remoteStatus: { url, fetchedAt: entry.fetchedAt, offline },
+	});
+
+	if (cached && !options.force && isRemoteCacheFresh(cached, options.maxAge, options.now)) {
+		return fromCache(cached, false);
+	}
+
+	const fetched = await fetchRemote(url);
+	if (!fetched.succeeded) {
+		return cached ? fromCache(cached, true) : fetched;
+	}
+
+	await cache.write({ url, fetchedAt: options.now, text: fetched.sourceCode });
+	return { ...fetched, remoteStatus: { url, fetchedAt: options.now, offline: false } };
+}
@@ -0,0 +1,32 @@
+/**
+ * Ultra Code Fence - Run Consent
+ *
+ * Keeps the user's consent to running code blocks in local storage for
+ * this vault, so it stays on this device and out of the plugin settings,
+ * their sync and version control.
+ */
+
+import type { App } from 'obsidian';
+import { RUN_CONSENT_STORE_KEY } from '../constants';
+import { loadVaultLocalStorage, saveVaultLocalStorage } from './vault-storage';
+
+/**
+ * Checks whether the user has agreed to running code in this vault on
+ * this device.
+ *
+ * @param app - Obsidian App instance
+ * @returns True once the user has agreed
+ */
+export function loadRunConsent(app: App): boolean {
+	return loadVaultLocalStorage(app, RUN_CONSENT_STORE_KEY) === true;
+}
+
+/**
+ * Records the user's answer to running code in this vault.
+ *
+ * @param app - Obsidian App instance
+ * @param allowed - Whether running code is allowed
+ */
+export function saveRunConsent(app: App, allowed: boolean): void {
+	saveVaultLocalStorage(app, RUN_CONSENT_STORE_KEY, allowed ? true : null);
+}
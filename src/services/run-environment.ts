@@ -0,0 +1,108 @@
+/**
+ * Ultra Code Fence - Run Environment
+ *
+ * Works out the environment variables a run is given (RUN.ENV), filling
+ * in {{secret.name}} references from the vault's secret store. Secrets
+ * are kept in local storage for this vault, so they stay on
+ * this device and out of the vault folder, its sync and version control.
+ */
+
+import type { App } from 'obsidian';
+import { SECRET_REFERENCE_PATTERN, SECRET_STORE_KEY } from '../constants';
+import { loadVaultLocalStorage, saveVaultLocalStorage } from './vault-storage';
+
+/** Secret values by name. */
+export type SecretValues = Record<string, string | undefined>;
+
+/** A run's environment, with the secrets it needed. */
+export interface RunEnvironment {
+	/** Variables to set for the run */
+	env: Record<string, string>;
+
+	/** Secret references with no value in the store, by name */
+	missingSecrets: string[];
+
+	/** Values of the secrets put in, to be masked in the output */
+	secretValues: string[];
+}
+
+// =============================================================================
+// Secret Store
+// =============================================================================
+
+/**
+ * Reads the vault's secrets.
+ *
+ * @param app - Obsidian App instance
+ * @returns Secret values by name (empty if none are stored)
+ */
+export function loadSecrets(app: App): SecretValues {
+	const stored = loadVaultLocalStorage(app, SECRET_STORE_KEY);
+	if (!stored || typeof stored !== 'object') return {};
+
+	const secrets: SecretValues = {};
+	for (const [name, value] of Object.entries(stored as Record<string, unknown>)) {
+		if (typeof value === 'string') secrets[name] = value;
+	}
+	return secrets;
+}
+
+/**
+ * Stores the vault's secrets, replacing what was there.
+ *
+ * @param app - Obsidian App instance
+ * @param secrets - Secret values by name
+ */
+export function saveSecrets(app: App, secrets: SecretValues): void {
+	saveVaultLocalStorage(app, SECRET_STORE_KEY, Object.keys(secrets).length > 0 ? secrets : null);
+}
+
+// =============================================================================
+// Environment
+// =============================================================================
+
+/**
+ * Fills in the secret references in a block's environment variables.
+ * A reference to a secret that isn't stored is reported, and left as
+ * written.
+ *
+ * @param env - Variables from RUN.ENV, other variables already filled in
+ * @param secrets - The vault's secrets
+ * @returns The variables, with the secrets they used
+ */
+export function resolveRunEnvironment(env: Record<string, string>, secrets: SecretValues): RunEnvironment {
+	const resolved: Record<string, string> = {};
+	const missing = new Set<string>();
+	const used = new Set<string>();
+
+	for (const [name, value] of Object.entries(env)) {
+		resolved[name] = value.replace(SECRET_REFERENCE_PATTERN, (reference, secretName: string) => {
+			const secret = secrets[secretName];
+			if (secret === undefined) {
+				missing.add(secretName);
+				return reference;
+			}
+			if (secret) used.add(secret);
+			return secret;
+		});
+	}
+
+	return { env: resolved, missingSecrets: Array.from(missing), secretValues: Array.from(used) };
+}
+
+/**
+ * Masks secret values in a run's output, so a command that prints one
+ * doesn't show it in the note. Only whole values within one piece of
+ * output are found.
+ *
+ * @param text - A piece of output
+ * @param secretValues - Values to mask
+ * @returns The text with each value replaced by dots
+ */
+export function maskSecrets(text: string, secretValues: string[]): string {
+	let masked = text;
+	for (const value of secretValues) {
+		masked = masked.split(value).join('••••••');
+	}
+	return masked;
+}
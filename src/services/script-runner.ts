@@ -0,0 +1,248 @@
+/**
+ * Ultra Code Fence - Script Runner
+ *
+ * Runs JavaScript and TypeScript blocks in a web worker, away from the
+ * note, the vault and Obsidian, passing what they log on as it arrives.
+ * The worker is started from a sandboxed frame whose content security
+ * policy it takes on, so it can't reach the network or load code.
+ */
+
+import type { OutputStream, RunControlOptions, RunResult } from './shell-runner';
+import { superviseRun } from './shell-runner';
+import { stripTypeAnnotations } from '../utils/type-annotations';
+
+/** Script block languages, and whether each is TypeScript. */
+const SCRIPT_LANGUAGES: Record<string, boolean | undefined> = {
+	javascript: false,
+	js: false,
+	mjs: false,
+	typescript: true,
+	ts: true,
+};
+
+/** A message from the worker. */
+type ScriptWorkerMessage =
+	| { type: 'output'; stream: OutputStream; text: string }
+	| { type: 'done'; failed: boolean };
+
+/** The parts of a Worker a run uses (replaceable in tests). */
+export interface ScriptWorker {
+	onmessage: ((event: MessageEvent) => void) | null;
+	onerror: ((event: ErrorEvent) => void) | null;
+	postMessage(message: unknown): void;
+	terminate(): void;
+}
+
+/** Starts a worker running the given source. */
+export type ScriptWorkerFactory = (source: string) => ScriptWorker;
+
+/** A message from the frame hosting the worker: one of the worker's, or its failure. */
+type ScriptHostMessage = ScriptWorkerMessage | { type: 'error'; message: string };
+
+/**
+ * Source of the worker that runs a script. It removes the network
+ * functions, from the worker's scope and every prototype it inherits
+ * them from, sends console output back line by line, then runs the code
+ * it is sent as the body of an async function, so top-level await works.
+ * The worker reports "done" once the code (and anything it awaits) is
+ * finished, and is then stopped, along with any timers left running.
+ */
+export const SCRIPT_WORKER_SOURCE = `
+const blocked = ['fetch', 'XMLHttpRequest', 'WebSocket', 'WebTransport', 'EventSource', 'importScripts', 'indexedDB', 'caches', 'Worker', 'SharedWorker', 'BroadcastChannel'];
+for (let scope = self; scope; scope = Object.getPrototypeOf(scope)) {
+	for (const name of blocked) {
+		if (!Object.prototype.hasOwnProperty.call(scope, name)) continue;
+		try { Object.defineProperty(scope, name, { value: undefined, writable: false, configurable: false }); } catch (error) { /* left to the policy */ }
+	}
+}
+
+const format = (value) => {
+	if (typeof value === 'string') return value;
+	if (value instanceof Error) return value.stack || String(value);
+	try { return JSON.stringify(value, null, 2) ?? String(value); } catch (error) { return String(value); }
+};
+const send = (stream) => (...values) => {
+	self.postMessage({ type: 'output', stream, text: values.map(format).join(' ') + '\\n' });
+};
+console.log = console.info = console.debug = send('stdout');
+console.warn = console.error = send('stderr');
+
+self.onmessage = async (event) => {
+	try {
+		const AsyncFunction = (async () => undefined).constructor;
+		await new AsyncFunction(event.data.code)();
+		self.postMessage({ type: 'done', failed: false });
+	} catch (error) {
+		console.error(error);
+		self.postMessage({ type: 'done', failed: true });
+	}
+};
+`;
+
+/**
+ * Content security policy of the frame the worker starts from, which
+ * the worker takes on. Nothing can be fetched or connected to, and no
+ * script loaded, so neither a dynamic import() nor anything the worker
+ * source missed reaches the network. Eval is allowed, as the code runs
+ * through an async function.
+ */
+const SCRIPT_SANDBOX_POLICY = "default-src 'none'; script-src 'unsafe-inline' 'unsafe-eval'; worker-src blob:";
+
+/**
+ * Source of the script in the sandboxed frame. It starts the worker from
+ * `source` and passes messages between it and the plugin.
+ */
+const SCRIPT_HOST_SOURCE = `
+try {
+	const worker = new Worker(URL.createObjectURL(new Blob([source], { type: 'text/javascript' })));
+	worker.onmessage = (event) => { parent.postMessage(event.data, '*'); };
+	worker.onerror = (event) => {
+		event.preventDefault();
+		parent.postMessage({ type: 'error', message: event.message }, '*');
+	};
+	window.onmessage = (event) => {
+		if (event.source === parent) worker.postMessage(event.data);
+	};
+} catch (error) {
+	parent.postMessage({ type: 'error', message: "couldn't start a worker: " + String(error) }, '*');
+}
+`;
+
+// =============================================================================
+// Languages
+// =============================================================================
+
+/**
+ * Checks whether blocks in a language run as scripts in a worker.
+ *
+ * @param language - Block language (aliases already resolved)
+ * @returns True for JavaScript and TypeScript
+ */
+export function isScriptLanguage(language: string): boolean {
+	return SCRIPT_LANGUAGES[language.toLowerCase()] !== undefined;
+}
+
+/**
+ * Prepares a script block's code to run: TypeScript has its types
+ * stripped.
+ *
+ * @param code - The block's code
+ * @param language - Block language (aliases already resolved)
+ * @returns JavaScript to run
+ * @throws Error saying why, for TypeScript that can't run without compiling
+ */
+export function prepareScript(code: string, language: string): string {
+	return SCRIPT_LANGUAGES[language.toLowerCase()] ? stripTypeAnnotations(code) : code;
+}
+
+// =============================================================================
+// Running
+// =============================================================================
+
+/**
+ * Starts a worker from source text inside a hidden frame, sandboxed to
+ * an origin of its own and restricted by SCRIPT_SANDBOX_POLICY. Messages
+ * pass through the frame; those sent before it has loaded wait for it.
+ * Stopping the worker removes the frame, which ends the worker with it.
+ *
+ * @param source - Worker source
+ * @returns The worker
+ */
+function createSandboxedWorker(source: string): ScriptWorker {
+	const frame = document.createElement('iframe');
+	frame.setAttribute('sandbox', 'allow-scripts');
+	frame.hidden = true;
+	// Escaped so that a "</script>" in the source can't end the frame's script early
+	const sourceLiteral = JSON.stringify(source).replace(/</g, '\\u003c');
+	frame.srcdoc = `<!DOCTYPE html><meta http-equiv="Content-Security-Policy" content="${SCRIPT_SANDBOX_POLICY}"><script>const source = ${sourceLiteral};${SCRIPT_HOST_SOURCE}</script>`;
+
+	const pending: unknown[] = [];
+	const state = { loaded: false };
+	const relay = (event: MessageEvent): void => {
+		if (event.source !== frame.contentWindow) return;
+		const message = event.data as ScriptHostMessage;
+		if (message.type === 'error') {
+			worker.onerror?.(new ErrorEvent('error', { message: message.message, cancelable: true }));
+		} else {
+			worker.onmessage?.(new MessageEvent('message', { data: message }));
+		}
+	};
+	const worker: ScriptWorker = {
+		onmessage: null,
+		onerror: null,
+		postMessage: (message) => {
+			if (state.loaded) {
+				frame.contentWindow?.postMessage(message, '*');
+			} else {
+				pending.push(message);
+			}
+		},
+		terminate: () => {
+			window.removeEventListener('message', relay);
+			frame.remove();
+		},
+	};
+
+	window.addEventListener('message', relay);
+	frame.addEventListener('load', () => {
+		state.loaded = true;
+		for (const message of pending.splice(0)) {
+			frame.contentWindow?.postMessage(message, '*');
+		}
+	});
+	document.body.appendChild(frame);
+	return worker;
+}
+
+/**
+ * Runs JavaScript in a worker, passing on what it logs: console.log,
+ * info and debug as stdout, warn and error as stderr. An error thrown by
+ * the code (or a syntax error) is logged to stderr and the run fails
+ * with exit code 1. A run that goes past its time limit or is cancelled
+ * has its worker stopped at once.
+ *
+ * @param code - JavaScript to run
+ * @param options - Output callback, time limit and abort signal
+ * @param createWorker - Starts the worker
+ * @returns How the run ended; never rejects
+ */
+export function runScript(code: string, options: RunControlOptions, createWorker: ScriptWorkerFactory = createSandboxedWorker): Promise<RunResult> {
+	return new Promise(resolve => {
+		if (options.signal?.aborted) {
+			resolve({ exitCode: null, stopped: { reason: 'cancelled', afterMs: 0 } });
+			return;
+		}
+
+		let worker: ScriptWorker;
+		try {
+			worker = createWorker(SCRIPT_WORKER_SOURCE);
+		} catch (error) {
+			resolve({ exitCode: null, errorMessage: `couldn't start a worker: ${String(error)}` });
+			return;
+		}
+
+		const run = { done: false };
+		const finish = (result: RunResult): void => {
+			if (run.done) return;
+			run.done = true;
+			stopWatching();
+			worker.terminate();
+			resolve(result);
+		};
+		const stopWatching = superviseRun(options, (stopped) => { finish({ exitCode: null, stopped }); });
+
+		worker.onmessage = (event) => {
+			const message = event.data as ScriptWorkerMessage;
+			if (message.type === 'output') {
+				if (!run.done) options.onOutput(message.text, message.stream);
+				return;
+			}
+			finish({ exitCode: message.failed ? 1 : 0 });
+		};
+		worker.onerror = (event) => {
+			event.preventDefault();
+			finish({ exitCode: null, errorMessage: event.message || 'the worker failed' });
+		};
+		worker.postMessage({ code });
+	});
+}
@@ -0,0 +1,311 @@
+/**
+ * Ultra Code Fence - Shell Runner
+ *
+ * Runs the code of a shell block through the user's shell (desktop
+ * only), passing its output on as it arrives, so a runbook can be
+ * followed from the note itself.
+ */
+
+import { Platform } from 'obsidian';
+import { RUN_KILL_GRACE_MS, MAX_TIMER_DELAY_MS } from '../constants';
+
+/** Which stream a piece of output came from. */
+export type OutputStream = 'stdout' | 'stderr';
+
+/** Why a run was stopped before it finished. */
+export type RunStopReason = 'cancelled' | 'timeout';
+
+/** How a run ended (of a shell block, or a script in a worker). */
+export interface RunResult {
+	/** Exit code (null if the process couldn't be started or was stopped by a signal) */
+	exitCode: number | null;
+
+	/** Why the process couldn't be started, if it couldn't */
+	errorMessage?: string;
+
+	/** Set when the run was stopped: why, and how long it had run */
+	stopped?: { reason: RunStopReason; afterMs: number };
+}
+
+/** Options every run takes, however it runs. */
+export interface RunControlOptions {
+	/** Receives output as it arrives */
+	onOutput: (text: string, stream: OutputStream) => void;
+
+	/** Stops the run after this long (omitted or 0 = no limit) */
+	timeoutMs?: number;
+
+	/** Stops the run when aborted */
+	signal?: AbortSignal;
+}
+
+/** Program and arguments that run a block's code. */
+export interface ShellInvocation {
+	file: string;
+	args: string[];
+}
+
+/** Where a block runs. */
+export interface ShellEnvironment {
+	/** The user's login shell ($SHELL; empty if unset) */
+	userShell: string;
+
+	/** Command interpreter on Windows ($ComSpec; empty if unset) */
+	commandInterpreter: string;
+
+	/** Whether it is Windows */
+	windows: boolean;
+}
+
+/** Options for a shell run. */
+export interface ShellRunOptions extends RunControlOptions {
+	/** Working directory (omitted = the app's) */
+	cwd?: string;
+
+	/** Variables set for the process, on top of the app's own */
+	env?: Record<string, string>;
+}
+
+/**
+ * Minimal shape of a child process started by Node's spawn.
+ */
+export interface SpawnedProcess {
+	stdout: { on(event: 'data', listener: (chunk: unknown) => void): void } | null;
+	stderr: { on(event: 'data', listener: (chunk: unknown) => void): void } | null;
+	on(event: 'error', listener: (error: Error) => void): void;
+	on(event: 'close', listener: (code: number | null) => void): void;
+
+	/** Stops the process and any it started */
+	kill(): void;
+}
+
+/** Starts a process (Node's child_process.spawn, replaceable in tests). */
+export type SpawnFunction = (file: string, args: string[], options: { cwd?: string; env?: Record<string, string> }) => SpawnedProcess;
+
+/**
+ * Interpreters of shell block languages. An empty name means the user's
+ * own shell, for blocks that are just "shell".
+ */
+const SHELL_INTERPRETERS: Record<string, string | undefined> = {
+	bash: 'bash',
+	zsh: 'zsh',
+	fish: 'fish',
+	sh: 'sh',
+	shell: '',
+	powershell: 'pwsh',
+	ps1: 'pwsh',
+	pwsh: 'pwsh',
+};
+
+// =============================================================================
+// Choosing a Shell
+// =============================================================================
+
+/**
+ * Checks whether blocks in a language run in a shell.
+ *
+ * @param language - Block language (aliases already resolved)
+ * @returns True for shell languages
+ */
+export function isShellLanguage(language: string): boolean {
+	return SHELL_INTERPRETERS[language.toLowerCase()] !== undefined;
+}
+
+/**
+ * Works out the program that runs a block's code: the interpreter its
+ * language names, or for plain shell blocks the user's shell ($SHELL,
+ * then /bin/sh; cmd.exe on Windows).
+ *
+ * @param language - Block language (aliases already resolved)
+ * @param code - The code to run
+ * @param environment - The user's shell and platform
+ * @returns Program and arguments, or null if the language can't be run
+ */
+export function resolveShellInvocation(language: string, code: string, environment: ShellEnvironment): ShellInvocation | null {
+	const interpreter = SHELL_INTERPRETERS[language.toLowerCase()];
+	if (interpreter === undefined) return null;
+
+	if (interpreter === 'pwsh') {
+		return { file: 'pwsh', args: ['-NoProfile', '-Command', code] };
+	}
+	if (interpreter) {
+		return { file: interpreter, args: ['-c', code] };
+	}
+	if (environment.windows) {
+		return { file: environment.commandInterpreter || 'cmd.exe', args: ['/d', '/s', '/c', code] };
+	}
+	return { file: environment.userShell || '/bin/sh', args: ['-c', code] };
+}
+
+// =============================================================================
+// Running
+// =============================================================================
+
+/**
+ * Watches a run for its time running out or its cancelling, and stops
+ * it when either happens (only the first counts).
+ *
+ * @param options - Time limit and abort signal
+ * @param stop - Stops the run, told why and how long it had run
+ * @returns Stops watching; call once the run has ended
+ */
+export function superviseRun(options: RunControlOptions, stop: (stopped: { reason: RunStopReason; afterMs: number }) => void): () => void {
+	const startedAt = Date.now();
+	const state = { stopped: false };
+	const stopFor = (reason: RunStopReason): void => {
+		if (state.stopped) return;
+		state.stopped = true;
+		stop({ reason, afterMs: reason === 'timeout' && options.timeoutMs ? options.timeoutMs : Date.now() - startedAt });
+	};
+
+	const onAbort = (): void => { stopFor('cancelled'); };
+	const timer = options.timeoutMs ? setTimeout(() => { stopFor('timeout'); }, Math.min(options.timeoutMs, MAX_TIMER_DELAY_MS)) : undefined;
+	options.signal?.addEventListener('abort', onAbort);
+	if (options.signal?.aborted) onAbort();
+
+	return () => {
+		state.stopped = true;
+		if (timer !== undefined) clearTimeout(timer);
+		options.signal?.removeEventListener('abort', onAbort);
+	};
+}
+
+/**
+ * Runs a program, passing on its output as it arrives. A run that goes
+ * past its time limit or is cancelled has its process killed, and ends
+ * once the process has gone.
+ *
+ * @param invocation - Program and arguments
+ * @param options - Working directory, variables, output callback, time limit and abort signal
+ * @param spawn - Starts the process
+ * @returns How the run ended; never rejects
+ */
+export function runShellInvocation(invocation: ShellInvocation, options: ShellRunOptions, spawn: SpawnFunction): Promise<RunResult> {
+	return new Promise(resolve => {
+		if (options.signal?.aborted) {
+			resolve({ exitCode: null, stopped: { reason: 'cancelled', afterMs: 0 } });
+			return;
+		}
+
+		let child: SpawnedProcess;
+		try {
+			child = spawn(invocation.file, invocation.args, { cwd: options.cwd, env: options.env });
+		} catch (error) {
+			resolve({ exitCode: null, errorMessage: `couldn't start ${invocation.file}: ${String(error)}` });
+			return;
+		}
+
+		child.stdout?.on('data', (chunk) => { options.onOutput(String(chunk), 'stdout'); });
+		child.stderr?.on('data', (chunk) => { options.onOutput(String(chunk), 'stderr'); });
+
+		const run: { stopped?: RunResult['stopped']; done: boolean } = { done: false };
+		const stopWatching = superviseRun(options, (stopped) => {
+			run.stopped = stopped;
+			child.kill();
+		});
+
+		// A process that fails to start reports an error and may then close too
+		child.on('error', (error) => {
+			if (run.done) return;
+			run.done = true;
+			stopWatching();
+			resolve({ exitCode: null, errorMessage: `couldn't start ${invocation.file}: ${error.message}` });
+		});
+		child.on('close', (code) => {
+			if (run.done) return;
+			run.done = true;
+			stopWatching();
+			resolve(run.stopped ? { exitCode: code, stopped: run.stopped } : { exitCode: code });
+		});
+	});
+}
+
+/** The parts of Node's child_process and process a desktop run uses. */
+interface NodeChildProcess {
+	pid?: number;
+	stdout: SpawnedProcess['stdout'];
+	stderr: SpawnedProcess['stderr'];
+	on(event: string, listener: (...values: never[]) => void): void;
+	kill(signal?: string): boolean;
+}
+interface NodeChildProcessModule {
+	spawn(file: string, args: string[], options: { cwd?: string; env?: Record<string, string | undefined>; detached?: boolean; windowsHide?: boolean; stdio?: string[] }): NodeChildProcess;
+}
+interface NodeProcessModule {
+	env: Record<string, string | undefined>;
+	platform: string;
+	kill(pid: number, signal?: string): void;
+}
+
+/**
+ * Wraps a Node child process so that killing it takes whatever it
+ * started with it: on Windows the process tree (taskkill), elsewhere its
+ * process group, first asked to stop, then forced after a grace period.
+ *
+ * @param child - The process, started in its own group outside Windows
+ * @param childProcess - Node's child_process
+ * @param nodeProcess - Node's process
+ * @returns The process as a run uses it
+ */
+function wrapChildProcess(child: NodeChildProcess, childProcess: NodeChildProcessModule, nodeProcess: NodeProcessModule): SpawnedProcess {
+	const state = { closed: false };
+	child.on('close', () => { state.closed = true; });
+
+	const signalGroup = (signal: string): void => {
+		if (state.closed || child.pid === undefined) return;
+		try {
+			nodeProcess.kill(-child.pid, signal);
+		} catch {
+			child.kill(signal);
+		}
+	};
+
+	return {
+		stdout: child.stdout,
+		stderr: child.stderr,
+		on: (event: string, listener: (...values: never[]) => void) => { child.on(event, listener); },
+		kill: () => {
+			if (state.closed || child.pid === undefined) return;
+			if (nodeProcess.platform === 'win32') {
+				childProcess.spawn('taskkill', ['/pid', String(child.pid), '/T', '/F'], { windowsHide: true });
+				return;
+			}
+			signalGroup('SIGTERM');
+			setTimeout(() => { signalGroup('SIGKILL'); }, RUN_KILL_GRACE_MS);
+		},
+	};
+}
+
+/**
+ * Loads Node's spawn and the user's shell (desktop only).
+ *
+ * @returns Spawn and environment, or null on mobile or when unavailable
+ */
+export function loadDesktopShell(): { spawn: SpawnFunction; environment: ShellEnvironment } | null {
+	const nodeRequire = (window as unknown as { require?: (id: string) => unknown }).require;
+	if (!Platform.isDesktopApp || !nodeRequire) return null;
+
+	try {
+		const childProcess = nodeRequire('child_process') as NodeChildProcessModule;
+		const nodeProcess = nodeRequire('process') as NodeProcessModule;
+		const windows = nodeProcess.platform === 'win32';
+		return {
+			// Outside Windows each run leads its own process group, so it can be killed whole.
+			// Nothing is typed into a run, so a command that reads input gets end of file
+			// rather than waiting on it until the timeout.
+			spawn: (file, args, options) => wrapChildProcess(childProcess.spawn(file, args, {
+				cwd: options.cwd,
+				env: options.env ? { ...nodeProcess.env, ...options.env } : undefined,
+				detached: !windows,
+				stdio: ['ignore', 'pipe', 'pipe'],
+			}), childProcess, nodeProcess),
+			environment: {
+				userShell: nodeProcess.env.SHELL ?? '',
+				commandInterpreter: nodeProcess.env.ComSpec ?? '',
+				windows,
+			},
+		};
+	} catch {
+		return null;
+	}
+}
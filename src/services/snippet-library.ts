@@ -0,0 +1,184 @@
+/**
+ * Ultra Code Fence - Snippet Library
+ *
+ * Indexes every titled ufence block in the vault, so a reusable snippet
+ * can be found and put into another note, either as a copy or as a
+ * block showing it by ID (META.REF).
+ */
+
+import { YAML_META } from '../constants';
+import { findBlockId, readBlockSettings, readMetaText, readReferenceId } from './block-references';
+import { findUfenceBlocks } from './fence-lint';
+
+/** A titled block in the vault. */
+export interface Snippet {
+	/** Vault path of the note */
+	path: string;
+
+	/** The block's META.TITLE */
+	title: string;
+
+	/** Block type after "ufence-" (e.g. "bash") */
+	blockType: string;
+
+	/** The block's ID ("" if it has none, so it can only be copied) */
+	id: string;
+
+	/** Line number of the opening fence (1-based) */
+	line: number;
+
+	/** Nearest heading above the block, if any */
+	heading: string | undefined;
+
+	/** The block as written, fences included */
+	text: string;
+
+	/** The block to insert as a copy: as written, less its META.ID */
+	copyText: string;
+}
+
+/** Types of ufence block that aren't snippets. */
+const NON_SNIPPET_BLOCK_TYPES = new Set(['ufence', 'cmdout']);
+
+/** An `id=` option on the fence line, with the space after it. */
+const FENCE_ID_OPTION_PATTERN = /\bid=(?:"[^"]*"|[^\s}]+)\s*/;
+
+/** An ID key in a block's settings, in YAML, TOML or JSON. */
+const SETTINGS_ID_LINE_PATTERN = /^\s*"?ID"?\s*[:=]/;
+
+// =============================================================================
+// Finding Snippets
+// =============================================================================
+
+/**
+ * Finds the snippets in a note: blocks with a title. Page settings and
+ * cmdout blocks, and blocks that show another by reference, are left out.
+ *
+ * @param path - Vault path of the note
+ * @param content - Note content
+ * @returns Each snippet, in document order
+ */
+export function findSnippets(path: string, content: string): Snippet[] {
+	const lines = content.split('\n');
+	const snippets: Snippet[] = [];
+
+	for (const block of findUfenceBlocks(content)) {
+		if (NON_SNIPPET_BLOCK_TYPES.has(block.blockType)) continue;
+
+		const settings = readBlockSettings(block);
+		const title = settings ? readMetaText(settings, YAML_META.title) : '';
+		if (!settings || !title || readReferenceId(settings)) continue;
+
+		const blockLines = lines.slice(block.line - 1, block.endLine);
+		snippets.push({
+			path,
+			title,
+			blockType: block.blockType,
+			id: findBlockId(block, settings, lines),
+			line: block.line,
+			heading: block.heading,
+			text: blockLines.join('\n'),
+			copyText: readMetaText(settings, YAML_META.id) ? removeSettingsId(blockLines).join('\n') : blockLines.join('\n'),
+		});
+	}
+
+	return snippets;
+}
+
+/**
+ * Takes META.ID out of a block, so a copy doesn't claim the original's
+ * ID. The fence line's `id=` option and ID lines of the settings, before
+ * any `~~~` separator, are removed; the code is left alone.
+ *
+ * @param blockLines - The block's lines, fences included
+ * @returns The lines without the ID
+ */
+function removeSettingsId(blockLines: string[]): string[] {
+	const fenceLine = blockLines[0]
+		.replace(FENCE_ID_OPTION_PATTERN, '')
+		.replace(/\s+\}/, '}')
+		.replace(/\s*\{\}/, '');
+
+	const separator = blockLines.findIndex((line, index) => index > 0 && line.trim() === '~~~');
+	const settingsEnd = separator === -1 ? blockLines.length - 1 : separator;
+
+	return [
+		fenceLine,
+		...blockLines.slice(1, settingsEnd).filter(line => !SETTINGS_ID_LINE_PATTERN.test(line)),
+		...blockLines.slice(settingsEnd),
+	];
+}
+
+/**
+ * Builds a block that shows a snippet by reference.
+ *
+ * @param snippet - A snippet with an ID
+ * @returns The block, e.g. "```ufence-bash {ref=deploy}\n```"
+ */
+export function buildSnippetReference(snippet: Snippet): string {
+	const id = /^[\w-]+$/.test(snippet.id) ? snippet.id : `"${snippet.id}"`;
+	return '```ufence-' + snippet.blockType + ' {ref=' + id + '}\n```';
+}
+
+/**
+ * Checks whether a snippet matches a search: every word of it appears
+ * in the title, language, note path or code (case-insensitive).
+ *
+ * @param snippet - The snippet
+ * @param query - Search text
+ * @returns True if it matches (always, for an empty search)
+ */
+export function matchSnippet(snippet: Snippet, query: string): boolean {
+	const haystack = [snippet.title, snippet.blockType, snippet.path, snippet.text].join('\n').toLowerCase();
+	return query.toLowerCase().split(/\s+/).filter(Boolean).every(word => haystack.includes(word));
+}
+
+// =============================================================================
+// Library
+// =============================================================================
+
+/**
+ * The snippets across the vault, kept up to date note by note.
+ */
+export class SnippetLibrary {
+	private notes = new Map<string, Snippet[]>();
+
+	/**
+	 * Reads a note's snippets, replacing what was known of it.
+	 *
+	 * @param path - Vault path of the note
+	 * @param content - Note content
+	 */
+	update(path: string, content: string): void {
+		const snippets = findSnippets(path, content);
+		if (snippets.length > 0) {
+			this.notes.set(path, snippets);
+		} else {
+			this.notes.delete(path);
+		}
+	}
+
+	/**
+	 * Forgets a note's snippets.
+	 *
+	 * @param path - Vault path of the note
+	 */
+	remove(path: string): void {
+		this.notes.delete(path);
+	}
+
+	/**
+	 * Lists every snippet.
+	 *
+	 * @returns Snippets by title, then by note path and line
+	 */
+	all(): Snippet[] {
+		const snippets: Snippet[] = [];
+		for (const noteSnippets of this.notes.values()) {
+			snippets.push(...noteSnippets);
+		}
+		return snippets.sort((a, b) =>
+			a.title.localeCompare(b.title) || a.path.localeCompare(b.path) || a.line - b.line
+		);
+	}
+}
@@ -6,9 +6,15 @@
  * behind a unified interface.
  */
 
-import { App, TFile, requestUrl } from 'obsidian';
-import type { SourceFileMetadata, SourceLoadResult, SourceLocationType } from '../types';
-import { VAULT_PREFIX, HTTPS_PREFIX, HTTP_PREFIX } from '../constants';
+import { App, Platform, TFile, requestUrl } from 'obsidian';
+import type { GitRepository, SourceFileMetadata, SourceLoadResult, SourceLocationType } from '../types';
+import { VAULT_PREFIX, HTTPS_PREFIX, HTTP_PREFIX, GIT_SOURCE_PATTERN } from '../constants';
+
+/** Refs git can be asked for safely: names, tags and hashes, not options. */
+const GIT_REF_PATTERN = /^\w[\w./~^-]*$/;
+
+/** Largest file read from git (bytes). */
+const GIT_MAX_FILE_SIZE = 10 * 1024 * 1024;
 
 // =============================================================================
 // Source Type Detection
@@ -33,6 +39,10 @@ export function detectSourceLocationType(path: string | undefined): SourceLocati
 		return 'remote';
 	}
 
+	if (GIT_SOURCE_PATTERN.test(path)) {
+		return 'git';
+	}
+
 	return 'invalid';
 }
 
@@ -126,14 +136,129 @@ export async function loadRemoteFile(url: string): Promise<SourceLoadResult> {
 	}
 }
 
+// =============================================================================
+// Git Sources
+// =============================================================================
+
+/**
+ * Reads the stored list of git checkouts, moving the earlier
+ * comma-separated "name=path" text to one entry per repository.
+ *
+ * @param stored - Stored setting: the list, or the earlier text
+ * @returns Copy of the list; malformed entries of the earlier text are skipped
+ */
+export function normalizeGitRepositories(stored: GitRepository[] | string | undefined): GitRepository[] {
+	if (Array.isArray(stored)) {
+		return stored.map(repository => ({ name: repository.name, path: repository.path }));
+	}
+
+	const repositories: GitRepository[] = [];
+	for (const entry of (stored ?? '').split(',')) {
+		const match = /^\s*([\w.-]+)\s*=\s*(.+?)\s*$/.exec(entry);
+		if (match) {
+			repositories.push({ name: match[1], path: match[2] });
+		}
+	}
+
+	return repositories;
+}
+
+/**
+ * Looks up checkouts by name, for loading git sources.
+ *
+ * @param repositories - Git repositories from the settings
+ * @returns Checkout path by repository name; rows without a name or path are skipped, and the first row of a name wins
+ */
+export function gitRepositoryPaths(repositories: GitRepository[]): Record<string, string | undefined> {
+	const paths: Record<string, string | undefined> = {};
+
+	for (const repository of repositories) {
+		const name = repository.name.trim();
+		const path = repository.path.trim();
+		if (name && path && paths[name] === undefined) {
+			paths[name] = path;
+		}
+	}
+
+	return paths;
+}
+
+/**
+ * Splits a git source path into its parts.
+ *
+ * @param path - Path such as "tools:bin/install.sh@v1.4.2"
+ * @returns Repository name, file path in the repository and ref, or null if malformed
+ */
+export function parseGitSourcePath(path: string): { repository: string; file: string; ref: string } | null {
+	const match = GIT_SOURCE_PATTERN.exec(path);
+	if (!match || !GIT_REF_PATTERN.test(match[3].trim())) return null;
+
+	const file = match[2].replace(/^\/+/, '');
+	return file ? { repository: match[1], file, ref: match[3].trim() } : null;
+}
+
+/**
+ * Minimal shape of Node's child_process module used on desktop.
+ */
+interface DesktopChildProcess {
+	execFile(
+		file: string,
+		args: string[],
+		options: { maxBuffer: number },
+		callback: (error: Error | null, stdout: string) => void
+	): void;
+}
+
+/**
+ * Reads a file from a local git checkout at a ref (desktop only), with
+ * `git cat-file`, so the working tree isn't touched.
+ *
+ * @param path - Git source path (repository:path@ref)
+ * @param repositories - Checkout path by repository name
+ * @returns Load result with content and metadata
+ */
+export async function loadGitFile(path: string, repositories: Record<string, string | undefined>): Promise<SourceLoadResult> {
+	const failure = (errorMessage: string): SourceLoadResult => ({ succeeded: false, sourceCode: '', fileMetadata: null, errorMessage });
+
+	const source = parseGitSourcePath(path);
+	if (!source) return failure(`invalid git source '${path}', use repository:path@ref`);
+
+	const checkout = repositories[source.repository];
+	if (!checkout) return failure(`no git repository named '${source.repository}' in the settings`);
+
+	const nodeRequire = (window as unknown as { require?: (id: string) => unknown }).require;
+	if (!Platform.isDesktopApp || !nodeRequire) return failure('git sources need the desktop app');
+
+	try {
+		const childProcess = nodeRequire('child_process') as DesktopChildProcess;
+		const sourceCode = await new Promise<string>((resolve, reject) => {
+			childProcess.execFile(
+				'git',
+				['-C', checkout, 'cat-file', 'blob', `${source.ref}:${source.file}`],
+				{ maxBuffer: GIT_MAX_FILE_SIZE },
+				(error, stdout) => { if (error) { reject(error); } else { resolve(stdout); } }
+			);
+		});
+
+		return {
+			succeeded: true,
+			sourceCode,
+			fileMetadata: extractFileMetadata(source.file),
+		};
+	} catch {
+		return failure(`couldn't read '${source.file}' at ${source.ref} from ${source.repository}`);
+	}
+}
+
 /**
  * Loads source code from any supported source type.
  *
  * @param app - Obsidian App instance
- * @param path - Source path (vault:// or http[s]://)
+ * @param path - Source path (vault://, http[s]:// or repository:path@ref)
+ * @param gitRepositories - Checkout path by repository name, for git sources
  * @returns Load result with content and metadata
  */
-export async function loadSource(app: App, path: string): Promise<SourceLoadResult> {
+export async function loadSource(app: App, path: string, gitRepositories: Record<string, string | undefined> = {}): Promise<SourceLoadResult> {
 	const locationType = detectSourceLocationType(path);
 
 	switch (locationType) {
@@ -143,6 +268,9 @@ export async function loadSource(app: App, path: string): Promise<SourceLoadResu
 		case 'remote':
 			return loadRemoteFile(path);
 
+		case 'git':
+			return loadGitFile(path, gitRepositories);
+
 		case 'embedded':
 			return {
 				succeeded: false,
@@ -156,7 +284,7 @@ export async function loadSource(app: App, path: string): Promise<SourceLoadResu
 				succeeded: false,
 				sourceCode: '',
 				fileMetadata: null,
-				errorMessage: "invalid source path, use 'vault://...' or 'http[s]://...'",
+				errorMessage: "invalid source path, use 'vault://...', 'http[s]://...' or 'repository:path@ref'",
 			};
 	}
 }
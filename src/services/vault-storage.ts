@@ -0,0 +1,69 @@
+/**
+ * Ultra Code Fence - Vault Storage
+ *
+ * Keeps values on this device for the open vault, outside the vault
+ * folder and the plugin settings, so they aren't synced or committed.
+ * Uses the app's per-vault local storage where this version of Obsidian
+ * has it (1.8.7 and later), otherwise the window's local storage under a
+ * key that names the vault.
+ */
+
+import type { App } from 'obsidian';
+
+/** The app's own per-vault storage, missing before Obsidian 1.8.7. */
+interface AppLocalStorage {
+	loadLocalStorage?: (key: string) => unknown;
+	saveLocalStorage?: (key: string, data: unknown) => void;
+}
+
+/**
+ * Builds the window local storage key for a value of the open vault.
+ *
+ * @param app - Obsidian App instance
+ * @param key - Name of the value
+ * @returns The key with the vault's name added
+ */
+export function vaultStorageKey(app: App, key: string): string {
+	return `${key}-${app.vault.getName()}`;
+}
+
+/**
+ * Reads a value stored for the open vault on this device.
+ *
+ * @param app - Obsidian App instance
+ * @param key - Name of the value
+ * @returns The value, or null if none is stored (or it can't be read)
+ */
+export function loadVaultLocalStorage(app: App, key: string): unknown {
+	const { loadLocalStorage } = app as unknown as AppLocalStorage;
+	if (loadLocalStorage) return loadLocalStorage.call(app, key);
+
+	const text = window.localStorage.getItem(vaultStorageKey(app, key));
+	if (text === null) return null;
+	try {
+		return JSON.parse(text) as unknown;
+	} catch {
+		return null;
+	}
+}
+
+/**
+ * Stores a value for the open vault on this device.
+ *
+ * @param app - Obsidian App instance
+ * @param key - Name of the value
+ * @param data - Value to keep (JSON-serialisable), or null to remove it
+ */
+export function saveVaultLocalStorage(app: App, key: string, data: unknown): void {
+	const { saveLocalStorage } = app as unknown as AppLocalStorage;
+	if (saveLocalStorage) {
+		saveLocalStorage.call(app, key, data);
+		return;
+	}
+
+	if (data === null) {
+		window.localStorage.removeItem(vaultStorageKey(app, key));
+	} else {
+		window.localStorage.setItem(vaultStorageKey(app, key), JSON.stringify(data));
+	}
+}
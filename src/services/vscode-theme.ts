@@ -0,0 +1,211 @@
+/**
+ * Ultra Code Fence - VS Code Theme Import
+ *
+ * Converts a VS Code colour theme (the JSON file a theme extension ships)
+ * into a highlight theme that blocks pick with RENDER.THEME. The editor
+ * background and foreground colour the block, and each `tokenColors`
+ * rule is mapped from its TextMate scopes to the Prism token it styles,
+ * written in the same words as the TOKENS section.
+ */
+
+import type { HighlightTheme } from '../types';
+import { textMateScopeToToken } from './custom-grammars';
+
+/** A colour VS Code accepts: #rgb, #rgba, #rrggbb or #rrggbbaa. */
+const VSCODE_COLOUR_PATTERN = /^#(?:[0-9a-f]{3,4}|[0-9a-f]{6}|[0-9a-f]{8})$/i;
+
+/**
+ * Checks whether a value is a plain object.
+ *
+ * @param value - Value to check
+ * @returns True for objects that are not arrays
+ */
+function isRecord(value: unknown): value is Record<string, unknown> {
+	return typeof value === 'object' && value !== null && !Array.isArray(value);
+}
+
+/**
+ * Reads a colour value, ignoring anything that isn't a hex colour.
+ *
+ * @param value - Value from the theme file
+ * @returns The colour, or '' if unusable
+ */
+function readColour(value: unknown): string {
+	return typeof value === 'string' && VSCODE_COLOUR_PATTERN.test(value.trim()) ? value.trim() : '';
+}
+
+/**
+ * Checks whether the next non-space character closes an object or list.
+ *
+ * @param text - Text to look in
+ * @param start - Index to look from
+ * @returns True if it is "}" or "]"
+ */
+function closesAfter(text: string, start: number): boolean {
+	let i = start;
+	while (i < text.length && /\s/.test(text[i])) i++;
+	return text[i] === '}' || text[i] === ']';
+}
+
+/**
+ * Parses JSON with comments and trailing commas, as theme files are
+ * usually written.
+ *
+ * @param text - File contents
+ * @returns Parsed value
+ * @throws SyntaxError if the text is not valid JSON once cleaned
+ */
+export function parseJsonWithComments(text: string): unknown {
+	let cleaned = '';
+	let inString = false;
+
+	for (let i = 0; i < text.length; i++) {
+		const char = text[i];
+
+		if (inString) {
+			cleaned += char;
+			if (char === '\\' && i + 1 < text.length) {
+				cleaned += text[i + 1];
+				i++;
+			} else if (char === '"') {
+				inString = false;
+			}
+		} else if (char === '"') {
+			inString = true;
+			cleaned += char;
+		} else if (char === '/' && text[i + 1] === '/') {
+			while (i + 1 < text.length && text[i + 1] !== '\n') i++;
+		} else if (char === '/' && text[i + 1] === '*') {
+			const end = text.indexOf('*/', i + 2);
+			i = end === -1 ? text.length : end + 1;
+		} else if (char === ',' && closesAfter(text, i + 1)) {
+			// Trailing comma: the next thing after it closes the object or list
+			continue;
+		} else {
+			cleaned += char;
+		}
+	}
+
+	return JSON.parse(cleaned);
+}
+
+/**
+ * Turns a `tokenColors` rule's settings into a TOKENS style.
+ *
+ * @param settings - The rule's settings (foreground, background, fontStyle)
+ * @returns Style words, or '' if the rule sets nothing usable
+ */
+export function vscodeSettingsToStyle(settings: Record<string, unknown>): string {
+	const words: string[] = [];
+
+	if (typeof settings.fontStyle === 'string') {
+		const fontStyle = settings.fontStyle.trim().toLowerCase();
+		// An empty fontStyle clears the styles of broader rules
+		if (fontStyle === '') {
+			words.push('normal');
+		}
+		for (const word of fontStyle.split(/\s+/)) {
+			if (word === 'bold' || word === 'italic' || word === 'underline' || word === 'strikethrough') {
+				words.push(word);
+			}
+		}
+	}
+
+	const foreground = readColour(settings.foreground);
+	if (foreground) words.push(foreground);
+
+	const background = readColour(settings.background);
+	if (background) words.push('on', background);
+
+	return words.join(' ');
+}
+
+/**
+ * Lists the scopes of a `tokenColors` rule.
+ *
+ * @param scope - The rule's scope: a string (possibly comma-separated) or a list
+ * @returns Scope selectors, trimmed
+ */
+function readScopes(scope: unknown): string[] {
+	const scopes = typeof scope === 'string' ? scope.split(',') : Array.isArray(scope) ? scope : [];
+	return scopes
+		.filter((entry): entry is string => typeof entry === 'string')
+		.map(entry => entry.trim())
+		.filter(entry => entry !== '');
+}
+
+/**
+ * Converts a VS Code theme into a highlight theme.
+ *
+ * Several scopes often map to one Prism token (comment and
+ * comment.block.documentation are both "comment"); the broadest scope
+ * wins, and among equally broad scopes the later rule wins, as in
+ * VS Code. Scopes with a parent selector ("meta.tag string") are
+ * skipped, since a Prism token can't tell where it sits.
+ *
+ * @param text - Theme file contents
+ * @param fallbackName - Name to use if the theme has none (e.g. the file name)
+ * @returns The highlight theme
+ * @throws Error if the file isn't a VS Code theme
+ */
+export function convertVSCodeTheme(text: string, fallbackName: string): HighlightTheme {
+	const data = parseJsonWithComments(text);
+	if (!isRecord(data)) {
+		throw new Error('Theme must be a JSON object');
+	}
+
+	const colours = isRecord(data.colors) ? data.colors : {};
+	const tokenColours = Array.isArray(data.tokenColors) ? data.tokenColors : [];
+	if (tokenColours.length === 0 && !isRecord(data.colors)) {
+		throw new Error('Not a VS Code colour theme (no colors or tokenColors)');
+	}
+
+	let background = readColour(colours['editor.background']);
+	let foreground = readColour(colours['editor.foreground']);
+	const tokens: Record<string, string> = {};
+	const depths: Record<string, number> = {};
+
+	for (const rule of tokenColours) {
+		if (!isRecord(rule) || !isRecord(rule.settings)) continue;
+
+		// Older themes give the editor colours as a rule without a scope
+		if (rule.scope === undefined) {
+			background = background || readColour(rule.settings.background);
+			foreground = foreground || readColour(rule.settings.foreground);
+			continue;
+		}
+
+		const style = vscodeSettingsToStyle(rule.settings);
+		if (!style) continue;
+
+		for (const scope of readScopes(rule.scope)) {
+			if (/\s/.test(scope)) continue;
+
+			const token = textMateScopeToToken(scope);
+			if (!token) continue;
+
+			const depth = scope.split('.').length;
+			if (!(token in depths) || depth <= depths[token]) {
+				tokens[token] = style;
+				depths[token] = depth;
+			}
+		}
+	}
+
+	return {
+		name: typeof data.name === 'string' && data.name.trim() ? data.name.trim() : fallbackName,
+		background,
+		foreground,
+		tokens,
+	};
+}
+
+/**
+ * Key a highlight theme is stored and looked up under.
+ *
+ * @param name - Theme name, e.g. "One Dark Pro"
+ * @returns Lower-case key, e.g. "one dark pro"
+ */
+export function highlightThemeKey(name: string): string {
+	return name.trim().toLowerCase();
+}
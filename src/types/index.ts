@@ -32,6 +32,24 @@ export type TitleBarStyle = 'tab' | 'integrated' | 'minimal' | 'infobar' | 'none
  */
 export type FileIconStyle = 'emoji' | 'text' | 'filled' | 'outline' | 'custom' | 'none';
 
+/**
+ * Language the settings section of a ufence block is written in.
+ *
+ * - yaml: `KEY: value` with indented sections (default)
+ * - toml: `[SECTION]` tables with `KEY = value`
+ * - json: A single JSON object
+ */
+export type ConfigFormat = 'yaml' | 'toml' | 'json';
+
+/**
+ * How a block with configuration problems (unknown keys, mistyped values)
+ * is handled.
+ *
+ * - lenient: Render with defaults and flag the problems with a badge (default)
+ * - strict: Refuse to render and list the problems instead
+ */
+export type ConfigMode = 'strict' | 'lenient';
+
 /**
  * How to display the optional description text.
  *
@@ -41,6 +59,82 @@ export type FileIconStyle = 'emoji' | 'text' | 'filled' | 'outline' | 'custom' |
  */
 export type DescriptionDisplayMode = 'below' | 'tooltip' | 'none';
 
+/**
+ * A settings layer whose place in the cascade can be changed.
+ * - 'folder': The preset assigned to the note's folder
+ * - 'frontmatter': The note's `ufence:` frontmatter
+ * - 'page': The page's ufence-ufence block
+ * - 'preset': The named (or language/tag default) preset
+ * - 'block': The block's settings section
+ * - 'shorthand': The fence line shorthand ({ln title="…"})
+ */
+export type CascadeLayer = 'folder' | 'frontmatter' | 'page' | 'preset' | 'block' | 'shorthand';
+
+/**
+ * One layer of the settings cascade and whether it is used.
+ */
+export interface CascadeLayerSetting {
+	/** The layer */
+	layer: CascadeLayer;
+
+	/** Whether the layer is applied (the block layer always is) */
+	enabled: boolean;
+}
+
+/**
+ * Default preset for the notes in a folder.
+ */
+export interface FolderPresetRule {
+	/** Folder path or glob (`*`, `**`, `?`), relative to the vault root */
+	folder: string;
+
+	/** Name of the preset applied to notes under the folder */
+	preset: string;
+}
+
+/**
+ * Local git checkout that git sources (repository:path@ref) read from.
+ */
+export interface GitRepository {
+	/** Name used before the colon in a git source */
+	name: string;
+
+	/** Path of the checkout on this computer */
+	path: string;
+}
+
+/**
+ * Highlights text inside a host language's tokens with another
+ * language, e.g. SQL inside Go strings.
+ */
+export interface LanguageInjectionRule {
+	/** Host languages, comma-separated (e.g. "go, python") */
+	hosts: string;
+
+	/** Prism token of the host that holds the embedded code (e.g. "string") */
+	token: string;
+
+	/** Language the token's text is highlighted as */
+	language: string;
+
+	/** Regex the token's text must match, case-insensitive (empty = every token) */
+	match: string;
+}
+
+/**
+ * A keyword picked out in comments, such as TODO or FIXME.
+ */
+export interface CommentKeywordRule {
+	/** The keyword, matched as written (e.g. "TODO") */
+	keyword: string;
+
+	/** Callout type whose icon (and colour, by default) the keyword takes */
+	type: string;
+
+	/** CSS colour (empty = the callout type's colour) */
+	colour: string;
+}
+
 /**
  * Complete plugin settings interface.
  *
@@ -51,6 +145,27 @@ export interface PluginSettings {
 	/** Comma-separated list of languages to register processors for */
 	supportedLanguages: string;
 
+	/** Vault folder of grammar files for extra languages (empty = none) */
+	customGrammarFolder: string;
+
+	/** Highlighting engine: 'prism' (Obsidian's) or 'shiki' */
+	highlighterEngine: string;
+
+	/** Vault path of Shiki's browser build (ES module), for the Shiki engine */
+	shikiModulePath: string;
+
+	/** Comma-separated languages that stay on Prism when another engine is chosen */
+	prismLanguages: string;
+
+	/** Languages highlighted with another language's grammar, by lower-case alias */
+	languageAliases: Record<string, string | undefined>;
+
+	/** Rules for highlighting languages embedded in others, applied in order */
+	languageInjections: LanguageInjectionRule[];
+
+	/** Imported highlight themes (RENDER.THEME), by lower-case name */
+	highlightThemes: Record<string, HighlightTheme | undefined>;
+
 	/** Background colour for title bar (when not using theme colours) */
 	titleBarBackgroundColour: string;
 
@@ -75,6 +190,39 @@ export interface PluginSettings {
 	/** Show copy-to-clipboard button */
 	showCopyButton: boolean;
 
+	/** Show a small copy button in the gutter of every line */
+	showLineCopyButtons: boolean;
+
+	/** Ask for {{PLACEHOLDER}} values when copying */
+	copyPlaceholders: boolean;
+
+	/** Number of recent copies kept for the clipboard history command (0 = disabled) */
+	clipboardHistorySize: number;
+
+	/** Show a badge with each block's copy count */
+	showCopyCount: boolean;
+
+	/** Copy counts per block, keyed by note path and code hash (local only) */
+	copyCounts: Record<string, number>;
+
+	/** Show a button that toggles soft wrapping for each block */
+	showWrapButton: boolean;
+
+	/** Blocks switched to soft wrapping, keyed like copyCounts (local only) */
+	wrapStates: Record<string, boolean>;
+
+	/** Swap the copy button icon for an animated checkmark after copying */
+	copyFeedbackCheckmark: boolean;
+
+	/** Toast text shown after copying (empty = no toast) */
+	copyFeedbackToast: string;
+
+	/** Show a confirmation in the status bar after copying */
+	copyFeedbackStatusBar: boolean;
+
+	/** How long copy confirmations stay visible (ms) */
+	copyFeedbackDuration: number;
+
 	/**
 	 * Default fold line count. 0 = folding disabled, 1+ = enabled showing N lines.
 	 * When FOLD is specified in YAML, it overrides this value.
@@ -89,6 +237,9 @@ export interface PluginSettings {
 	 */
 	scrollLines: number;
 
+	/** Default minimap line count. 0 = no minimap, 1+ = minimap for blocks longer than N lines */
+	minimapLines: number;
+
 	/** Use Obsidian theme colours instead of custom */
 	useThemeColours: boolean;
 
@@ -101,9 +252,48 @@ export interface PluginSettings {
 	/** Alternate row highlighting (zebra stripes) */
 	showZebraStripes: boolean;
 
+	/** Highlight the line under the pointer */
+	showLineHover: boolean;
+
+	/** Comment keywords (TODO, FIXME, …): 'on', 'icons' (with a gutter icon) or 'off' */
+	commentKeywordMode: string;
+
+	/** Keywords picked out in comments, in order */
+	commentKeywords: CommentKeywordRule[];
+
+	/** Colour bracket pairs by depth and show a bracket's match on hover */
+	bracketPairColours: boolean;
+
+	/** Mark function definitions, parameters and field reads in Go, TypeScript and Python */
+	semanticHighlighting: boolean;
+
+	/** Fill in code variables in the code shown */
+	renderVariables: boolean;
+
+	/** Vault variables for {{var.name}}, as comma-separated name=value pairs */
+	codeVariables: string;
+
+	/** Mark lines changed since a note snapshot: 'last', an age such as '7d', or 'off' */
+	changedSince: string;
+
+	/** Columns per hard tab */
+	tabSize: number;
+
+	/** Columns per hard tab for some languages, e.g. "go=4, makefile=8" */
+	languageTabSizes: string;
+
 	/** Default prefix for file paths */
 	defaultPathPrefix: string;
 
+	/** Local git checkouts for git sources, one per repository */
+	gitRepositories: GitRepository[];
+
+	/** Minutes a cached copy of a remote file is shown before it's fetched again (0 = every render) */
+	remoteCacheMinutes: number;
+
+	/** Embedded files longer than this show this many lines at first, loading more on request (0 = all at once) */
+	progressiveLoadLines: number;
+
 	/** Last plugin version seen (for What's New modal) */
 	lastSeenVersion: string;
 
@@ -155,6 +345,21 @@ export interface PluginSettings {
 	/** Show download button on code blocks */
 	showDownloadButton: boolean;
 
+	/** Show an edit button on blocks embedding a vault file, to write changes back */
+	showEditButton: boolean;
+
+	/** Default download filename template (title variables; empty = source filename or title) */
+	downloadFilenameTemplate: string;
+
+	/** Add a language-derived shebang line to downloaded scripts */
+	downloadAddShebang: boolean;
+
+	/** Set the executable bit on downloaded scripts (desktop only) */
+	downloadExecutable: boolean;
+
+	/** Runs are killed after this many seconds (0 = no limit); RUN.TIMEOUT overrides it per block */
+	runTimeoutSeconds: number;
+
 	/** Last-used download directory per note path */
 	downloadPathHistory: Record<string, string>;
 
@@ -165,8 +370,29 @@ export interface PluginSettings {
 	 */
 	printBehaviour: string;
 
+	/** Colours when printing: 'light' (light colours whatever the theme) or 'asis' */
+	printTheme: string;
+
+	/** Print line numbers even when they are hidden on screen */
+	printLineNumbers: boolean;
+
+	/** Keep each block on one page when printing, where it fits */
+	printAvoidBreaks: boolean;
+
+	/** How blocks with configuration problems are handled (META.MODE overrides) */
+	configMode: ConfigMode;
+
 	/** Named YAML presets. Keys are preset names, values are raw YAML strings. */
 	presets: Record<string, string>;
+
+	/** Default presets by folder, first match wins */
+	folderPresets: FolderPresetRule[];
+
+	/** Default presets by language ID (lower case); used before tag presets */
+	languagePresets: Record<string, string | undefined>;
+
+	/** Order of the settings layers above the plugin settings, lowest first */
+	configCascade: CascadeLayerSetting[];
 }
 
 // =============================================================================
@@ -216,7 +442,7 @@ export interface SourceFileMetadata {
  * and extracting inline code from a ufence block.
  */
 export interface ParsedBlockContent {
-	/** Parsed YAML properties as key-value pairs */
+	/** Parsed settings as key-value pairs (from YAML, TOML or JSON) */
 	yamlProperties: Record<string, unknown>;
 
 	/** Inline code content (if using ~~~ separator) */
@@ -364,6 +590,20 @@ export interface ReleaseNotesData {
 	fixed: ChangelogItem[];
 }
 
+/**
+ * A curated preset from the bundled preset gallery.
+ */
+export interface PresetGalleryEntry {
+	/** Name the preset is installed under */
+	name: string;
+
+	/** What the preset is for */
+	description: string;
+
+	/** Preset YAML */
+	yaml: string;
+}
+
 // =============================================================================
 // Formatting Options
 // =============================================================================
@@ -443,7 +683,7 @@ export interface SourceLoadResult {
 /**
  * Type of source for a code block.
  */
-export type SourceLocationType = 'vault' | 'remote' | 'embedded' | 'invalid';
+export type SourceLocationType = 'vault' | 'remote' | 'git' | 'embedded' | 'invalid';
 
 // =============================================================================
 // Nested YAML Configuration
@@ -458,6 +698,18 @@ export interface YamlMetaConfig {
 	/** Source file path (vault:// or https://) - required for file embedding */
 	PATH?: string;
 
+	/** Vault file, URL or git file ("tools:bin/install.sh@v1.4.2") with an optional line range ("#L10-42") */
+	SRC?: string;
+
+	/** Builds the code from the vault: a search ("tag:#deploy lang:bash") or a Dataview query */
+	QUERY?: string;
+
+	/** How often to re-read the embedded file while the note is open, e.g. "30s" or "5m" */
+	REFRESH?: string;
+
+	/** ID of a block elsewhere in the vault to show, with this block's settings on top */
+	REF?: string;
+
 	/** Dynamic title with template variable support (e.g., "{filename} - {size:bytes}") */
 	TITLE?: string;
 
@@ -466,6 +718,15 @@ export interface YamlMetaConfig {
 
 	/** Name of a saved preset to apply as base configuration */
 	PRESET?: string;
+
+	/** Handling of configuration problems: "strict" or "lenient" */
+	MODE?: string;
+
+	/** Text shown in the disclosure bar of a collapsed block */
+	SUMMARY?: string;
+
+	/** Block ID that line links address (default: the ^id line after the fence) */
+	ID?: string;
 }
 
 /**
@@ -506,6 +767,99 @@ export interface YamlRenderDisplayConfig {
 
 	/** Print behaviour override: 'expand' or 'asis' */
 	PRINT?: string;
+
+	/** Show a copy button in the gutter of every line */
+	LINE_COPY?: boolean;
+
+	/** Number of the first line in the line number gutter */
+	LINE_START?: number;
+
+	/** Block line that relative line numbers count from (0 = absolute numbers) */
+	LINE_ANCHOR?: number;
+
+	/** Fold regions: 'expanded', 'collapsed' or 'none' */
+	FOLD_REGIONS?: string;
+
+	/** Regex marking the start of a fold region (replaces #region) */
+	FOLD_START?: string;
+
+	/** Regex marking the end of a fold region (replaces #endregion) */
+	FOLD_END?: string;
+
+	/** Render the whole block collapsed behind a disclosure bar */
+	COLLAPSED?: boolean;
+
+	/** Height limit with a "show all" expander, e.g. "20" (lines) or "400px" */
+	MAX_HEIGHT?: string;
+
+	/** Draw vertical guides at each indentation level */
+	INDENT_GUIDES?: boolean;
+
+	/** CSS colour of the indentation guides */
+	INDENT_GUIDE_COLOUR?: string;
+
+	/** Line style of the indentation guides: 'solid', 'dashed' or 'dotted' */
+	INDENT_GUIDE_STYLE?: string;
+
+	/** Whitespace glyphs: 'all', 'trailing' or 'none' (true = 'all') */
+	WHITESPACE?: string;
+
+	/** Comment keywords: 'on', 'icons' or 'off' (true = 'on') */
+	COMMENT_KEYWORDS?: string;
+
+	/** Colour bracket pairs by depth and show a bracket's match on hover */
+	BRACKETS?: boolean;
+
+	/** Mark function definitions, parameters and field reads */
+	SEMANTIC?: boolean;
+
+	/** Mark lines changed since a note snapshot: 'last', an age such as '7d', or 'off' (true = 'last') */
+	CHANGED_SINCE?: string;
+
+	/** Show a minimap for blocks longer than this many lines (0 = no minimap) */
+	MINIMAP?: number;
+
+	/** CSS colour of the zebra stripes */
+	ZEBRA_COLOUR?: string;
+
+	/** Highlight the line under the pointer */
+	HOVER?: boolean;
+
+	/** Font ligatures: true = on, false = off (unset = the theme's choice) */
+	LIGATURES?: boolean;
+
+	/** OpenType features, e.g. "ss01, cv02=3, -calt" */
+	FONT_FEATURES?: string;
+
+	/** Font family of the code, e.g. "JetBrains Mono" */
+	FONT_FAMILY?: string;
+
+	/** Font size of the code, e.g. "13px", "0.85em" or 13 (pixels) */
+	FONT_SIZE?: string;
+
+	/** Columns per hard tab */
+	TAB_SIZE?: number;
+
+	/** Columns to draw a ruler at, e.g. "80" or "80, 120" */
+	RULER?: string;
+
+	/** CSS colour of the rulers */
+	RULER_COLOUR?: string;
+
+	/** Interpret ANSI colour codes in command output (cmdout blocks) */
+	ANSI?: boolean;
+
+	/** What copying command output emits: 'stripped' or 'raw' (with the ANSI codes) */
+	ANSI_COPY?: string;
+
+	/** Text direction of each line: 'ltr', 'rtl' or 'auto' (from the line's first letter) */
+	DIRECTION?: string;
+
+	/** Name of an imported highlight theme */
+	THEME?: string;
+
+	/** Fill in {{fm.*}}, {{var.*}}, {{date}} and {{time}} variables in the code shown */
+	VARIABLES?: boolean;
 }
 
 /**
@@ -540,7 +894,8 @@ export interface YamlFilterByMarks {
 /**
  * FILTER section - Content extraction options.
  *
- * Filters are applied in order: BY_LINES first, then BY_MARKS on the result.
+ * Filters are applied in order: BY_REGION first, then BY_LINES, then
+ * BY_MARKS on the result.
  */
 export interface YamlFilterConfig {
 	/** Filter by line numbers */
@@ -548,6 +903,9 @@ export interface YamlFilterConfig {
 
 	/** Filter by marker strings */
 	BY_MARKS?: YamlFilterByMarks;
+
+	/** Name of a region marked `ufence:begin name` … `ufence:end name` in the source */
+	BY_REGION?: string;
 }
 
 /**
@@ -682,6 +1040,414 @@ export interface ResolvedCalloutConfig {
 	entries: ResolvedCalloutEntry[];
 }
 
+// =============================================================================
+// Copy Configuration
+// =============================================================================
+
+/**
+ * Single "copy as" transformation in the COPY.AS list.
+ *
+ * Each entry becomes an item in the block's "Copy as…" menu.
+ */
+export interface YamlCopyAsEntry {
+	/** Menu label (defaults to a name derived from FORMAT) */
+	LABEL?: string;
+
+	/** Transformation to apply: dockerfile | makefile | crontab | heredoc | json | base64 | url | richtext */
+	FORMAT?: string;
+
+	/** Makefile target name (makefile format) or file path (heredoc format) */
+	TARGET?: string;
+
+	/** Cron schedule expression (crontab format) */
+	SCHEDULE?: string;
+}
+
+/**
+ * Comment markers for one language (see constants/comments.ts).
+ */
+export interface CommentSyntax {
+	/** Line comment prefixes (e.g. "#", "//") */
+	line: string[];
+
+	/** Block comment delimiters [start, end] (e.g. ["<!--", "-->"]) */
+	block?: [string, string];
+}
+
+/**
+ * COPY section - Clipboard behaviour.
+ *
+ * Controls what ends up on the clipboard, separately from whether
+ * the copy button is shown (RENDER.COPY).
+ */
+export interface YamlCopyConfig {
+	/** Transformations offered in the "Copy as…" menu */
+	AS?: YamlCopyAsEntry[];
+
+	/** Ask for {{PLACEHOLDER}} values when copying */
+	PLACEHOLDERS?: boolean;
+
+	/** Fill in {{fm.*}}, {{var.*}}, {{date}} and {{time}} variables when copying */
+	VARIABLES?: boolean;
+
+	/** Leave whole-line comments out of copies */
+	STRIP_COMMENTS?: boolean;
+
+	/** Secret patterns to redact on copy: built-in names (tokens, passwords, secrets) or regexes */
+	REDACT?: string[];
+
+	/** Copy confirmation on/off (false = no checkmark, toast or status bar message) */
+	FEEDBACK?: boolean;
+
+	/** Toast text, or "true" / "false" to use the default text / show none */
+	TOAST?: string;
+
+	/** How long confirmations stay visible (ms) */
+	FEEDBACK_DURATION?: number;
+}
+
+/**
+ * How a successful copy is confirmed.
+ */
+export interface CopyFeedbackConfig {
+	/** Swap the button icon for an animated checkmark */
+	checkmark: boolean;
+
+	/** Toast text (empty = no toast) */
+	toastText: string;
+
+	/** Show the confirmation in the status bar */
+	statusBar: boolean;
+
+	/** How long confirmations stay visible (ms) */
+	durationMs: number;
+}
+
+/**
+ * Resolved "copy as" transformation with defaults applied.
+ */
+export interface ResolvedCopyAsEntry {
+	/** Menu label */
+	label: string;
+
+	/** Transformation format (lowercase, known to the copy transform registry) */
+	format: string;
+
+	/** Makefile target name or heredoc file path */
+	target: string;
+
+	/** Cron schedule expression */
+	schedule: string;
+}
+
+// =============================================================================
+// Highlight Configuration
+// =============================================================================
+
+/**
+ * HIGHLIGHT section - Lines to emphasise.
+ *
+ * Highlighted lines are marked in the rendered block and can be
+ * copied on their own from the block's copy menu.
+ */
+export interface YamlHighlightConfig {
+	/** Line numbers and ranges, e.g. "3-5, 8" or [3, "5-7"] (1-based, as rendered) */
+	LINES?: string;
+
+	/** Regex; lines whose text matches are highlighted too */
+	MATCH?: string;
+
+	/** Lines kept at full strength while the rest are dimmed, e.g. "3-5, 8" */
+	FOCUS?: string;
+
+	/** Show a button that undims the rest of the block */
+	FOCUS_TOGGLE?: boolean;
+
+	/** Terms marked wherever they occur, e.g. ["userId", "ctx"] */
+	TERMS?: string[];
+}
+
+// =============================================================================
+// Download Configuration
+// =============================================================================
+
+/**
+ * DOWNLOAD section - How the download button saves the block.
+ */
+export interface YamlDownloadConfig {
+	/** Filename template; supports title variables like {basename} */
+	FILENAME?: string;
+
+	/** "true" = language default, "false" = none, anything else = custom shebang line */
+	SHEBANG?: string;
+
+	/** Set the executable bit when saving (desktop only) */
+	EXECUTABLE?: boolean;
+}
+
+// =============================================================================
+// Print Configuration
+// =============================================================================
+
+/**
+ * PRINT section - How the block prints or exports to PDF, apart from how
+ * it looks on screen.
+ */
+export interface YamlPrintConfig {
+	/** Print the full code, with folds, scrolling and collapsed regions opened */
+	EXPAND?: boolean;
+
+	/** Colours: 'light' or 'asis' (as on screen) */
+	THEME?: string;
+
+	/** Print line numbers, even when they are hidden on screen */
+	LINES?: boolean;
+
+	/** Keep the block on one page where it fits */
+	AVOID_BREAKS?: boolean;
+}
+
+// =============================================================================
+// Run Configuration
+// =============================================================================
+
+/**
+ * RUN section - Running a block from the note, once running code is
+ * allowed in the settings.
+ */
+export interface YamlRunConfig {
+	/** Show the run button on this block (default true) */
+	ENABLED?: boolean;
+
+	/** Save the output in a ufence-output block after this one (default false) */
+	SAVE?: boolean;
+
+	/** Show a run button on each line too, to run it alone (default false) */
+	LINES?: boolean;
+
+	/** Kill the run after this long: "30s", "5m", or "off" (default: the plugin setting) */
+	TIMEOUT?: string;
+
+	/** Environment variables for the run, by name; values may use {{secret.name}} and {{var.name}} */
+	ENV?: Record<string, string>;
+}
+
+// =============================================================================
+// Header Configuration
+// =============================================================================
+
+/**
+ * HEADER section - What the title bar above the code shows.
+ */
+export interface YamlHeaderConfig {
+	/** Emoji or Lucide icon name replacing the file icon ("none" = no icon) */
+	ICON?: string;
+
+	/** Show the title text */
+	TITLE?: boolean;
+
+	/** Show a badge with the block's language */
+	LANGUAGE?: boolean;
+
+	/** Show the copy and download buttons in the header instead of over the code */
+	BUTTONS?: boolean;
+}
+
+// =============================================================================
+// Footer Configuration
+// =============================================================================
+
+/**
+ * FOOTER section - Attribution shown under the block.
+ */
+export interface YamlFooterConfig {
+	/** Name of the source the code is quoted from */
+	SOURCE?: string;
+
+	/** Link to the source */
+	URL?: string;
+
+	/** Licence of the quoted code */
+	LICENSE?: string;
+}
+
+// =============================================================================
+// Diff Configuration
+// =============================================================================
+
+/**
+ * DIFF section - Added and removed lines.
+ *
+ * Blocks in the diff or patch language are read as unified diffs; in
+ * other blocks, ADDED and REMOVED mark the changed lines.
+ */
+export interface YamlDiffConfig {
+	/** Added line numbers and ranges, e.g. "3-5, 8" (1-based, as rendered) */
+	ADDED?: string;
+
+	/** Removed line numbers and ranges (1-based, as rendered) */
+	REMOVED?: string;
+
+	/** What copying emits: diff (as shown) | after (the new version) */
+	COPY?: string;
+
+	/** Emphasise the changed words within changed lines (default true) */
+	WORDS?: boolean;
+
+	/** Layout: unified | split (old and new side by side) */
+	VIEW?: string;
+
+	/** Region holding the old version, for a split view of two regions */
+	OLD?: string;
+
+	/** Region holding the new version */
+	NEW?: string;
+}
+
+// =============================================================================
+// Annotation Configuration
+// =============================================================================
+
+/**
+ * One ANNOTATIONS entry - an icon shown in the gutter of a line.
+ *
+ * Written either as just the type ("12: warning") or as a mapping
+ * with TYPE and TEXT.
+ */
+export interface YamlAnnotationEntry {
+	/** Icon type, using the callout type names (note, info, warning, ...) */
+	TYPE?: string;
+
+	/** Tooltip text */
+	TEXT?: string;
+}
+
+/**
+ * A resolved gutter annotation.
+ */
+export interface LineAnnotation {
+	/** Annotated line (1-based, as rendered) */
+	line: number;
+
+	/** Callout type for the icon and colour (resolved, defaults to "note") */
+	type: string;
+
+	/** Tooltip text (empty = the type name) */
+	text: string;
+}
+
+// =============================================================================
+// Blame Configuration
+// =============================================================================
+
+/**
+ * One BLAME entry - who changed a line, when and why.
+ *
+ * Written either as just the author ("12: alice") or as a mapping with
+ * AUTHOR, DATE and TICKET.
+ */
+export interface YamlBlameEntry {
+	/** Who changed the line */
+	AUTHOR?: string;
+
+	/** When it changed */
+	DATE?: string;
+
+	/** Ticket or change ID */
+	TICKET?: string;
+}
+
+/**
+ * Resolved blame metadata for one line.
+ */
+export interface LineBlame {
+	/** Line (1-based, as rendered) */
+	line: number;
+
+	/** Who changed the line */
+	author: string;
+
+	/** When it changed */
+	date: string;
+
+	/** Ticket or change ID */
+	ticket: string;
+}
+
+// =============================================================================
+// Token Style Configuration
+// =============================================================================
+
+/**
+ * TOKENS section - restyles highlighted tokens.
+ *
+ * A token name maps to a style ("keyword: bold #a855f7"); a language
+ * maps to token styles that apply to blocks of that language only
+ * ("sql: { keyword: bold purple }").
+ */
+export type YamlTokensConfig = Record<string, string | Record<string, string>>;
+
+/**
+ * A resolved style for one kind of highlighted token.
+ */
+export interface TokenStyle {
+	/** Prism token name, e.g. "keyword" or "class-name" */
+	token: string;
+
+	/** Text colour ('' = unchanged) */
+	colour: string;
+
+	/** Background colour ('' = unchanged) */
+	background: string;
+
+	/** Font weight ('' = unchanged) */
+	fontWeight: '' | 'bold' | 'normal';
+
+	/** Font style ('' = unchanged) */
+	fontStyle: '' | 'italic' | 'normal';
+
+	/** Text decoration ('' = unchanged) */
+	textDecoration: '' | 'underline' | 'line-through' | 'none';
+
+	/** Opacity from 0 to 1 (undefined = unchanged) */
+	opacity: number | undefined;
+}
+
+/**
+ * PATTERNS section - styles text matching a regex, whatever token it is
+ * in. Each regex maps to a style in the words of the TOKENS section
+ * ("PROJ-\d+: bold #a855f7").
+ */
+export type YamlPatternsConfig = Record<string, string>;
+
+/**
+ * A resolved PATTERNS rule.
+ */
+export interface PatternStyle {
+	/** Text to style (global) */
+	pattern: RegExp;
+
+	/** Style of the matching text (its token name is empty) */
+	style: TokenStyle;
+}
+
+/**
+ * A highlight theme imported from a VS Code colour theme.
+ */
+export interface HighlightTheme {
+	/** Theme name, as shown in settings */
+	name: string;
+
+	/** Code background colour ('' = unchanged) */
+	background: string;
+
+	/** Plain code text colour ('' = unchanged) */
+	foreground: string;
+
+	/** TOKENS styles by Prism token name */
+	tokens: Record<string, string>;
+}
+
 /**
  * Complete parsed YAML configuration from a ufence block.
  *
@@ -692,8 +1458,33 @@ export interface ParsedYamlConfig {
 	RENDER?: YamlRenderDisplayConfig;
 	FILTER?: YamlFilterConfig;
 	CALLOUT?: YamlCalloutConfig;
+	COPY?: YamlCopyConfig;
+	HIGHLIGHT?: YamlHighlightConfig;
+	DOWNLOAD?: YamlDownloadConfig;
+
+	HEADER?: YamlHeaderConfig;
+
+	FOOTER?: YamlFooterConfig;
+
+	DIFF?: YamlDiffConfig;
+
+	PRINT?: YamlPrintConfig;
 
-	/** Top-level PROMPT regex pattern (for cmdout blocks) */
+	RUN?: YamlRunConfig;
+
+	/** Gutter annotations, keyed by line number or line list (e.g. "12" or "3-5") */
+	ANNOTATIONS?: Record<string, YamlAnnotationEntry>;
+
+	/** Blame column entries, keyed by line number or line list */
+	BLAME?: Record<string, YamlBlameEntry>;
+
+	/** Token styles, keyed by token name or language */
+	TOKENS?: YamlTokensConfig;
+
+	/** Styles of text matching a regex, keyed by the regex */
+	PATTERNS?: YamlPatternsConfig;
+
+	/** Top-level PROMPT regex pattern (cmdout command detection; stripped on copy) */
 	PROMPT?: string;
 
 	/** RENDER section for cmdout styling (uses YamlRenderCmdoutConfig internally) */
@@ -734,6 +1525,17 @@ export interface ResolvedFilterByMarks {
 	inclusive: boolean;
 }
 
+/**
+ * Height limit for a tall block (RENDER.MAX_HEIGHT).
+ */
+export interface MaxHeightLimit {
+	/** Limit in the given unit */
+	value: number;
+
+	/** Whether the value counts lines or CSS pixels */
+	unit: 'lines' | 'px';
+}
+
 /**
  * Resolved configuration for code blocks with all defaults applied.
  *
@@ -744,12 +1546,21 @@ export interface ResolvedBlockConfig {
 	/** Source file path (null for embedded code) */
 	sourcePath: string | null;
 
+	/** Query the code is built from (META.QUERY; empty = none) */
+	sourceQuery: string;
+
+	/** Milliseconds between re-reads of the embedded file (META.REFRESH; 0 = never) */
+	sourceRefreshInterval: number;
+
 	/** Title template (may contain variables like {filename}) */
 	titleTemplate: string;
 
 	/** Description text */
 	descriptionText: string;
 
+	/** How configuration problems are handled */
+	configMode: ConfigMode;
+
 	// DISPLAY section
 	/** Title bar style */
 	titleBarStyle: TitleBarStyle;
@@ -757,21 +1568,117 @@ export interface ResolvedBlockConfig {
 	/** Syntax highlighting language */
 	language: string;
 
+	/** The language was guessed from the code (RENDER.LANG: auto) */
+	languageDetected: boolean;
+
 	/** Fold lines: 0 = disabled, 1+ = fold to N lines */
 	foldLines: number;
 
 	/** Scroll lines: 0 = disabled, 1+ = scroll after N lines */
 	scrollLines: number;
 
+	/** Height limit with a "show all" expander (undefined = none; FOLD wins) */
+	maxHeight: MaxHeightLimit | undefined;
+
 	/** Show zebra stripes */
 	showZebraStripes: boolean;
 
 	/** Show line numbers */
 	showLineNumbers: boolean;
 
+	/** Number shown for the first line */
+	startingLineNumber: number;
+
+	/** Block line that relative line numbers count from (0 = absolute numbers) */
+	anchorLine: number;
+
+	/** Fold regions: 'expanded', 'collapsed' or 'none' */
+	foldRegionsMode: string;
+
+	/** Custom fold region start marker (undefined = #region and equivalents) */
+	foldStartPattern: RegExp | undefined;
+
+	/** Custom fold region end marker (undefined = #endregion and equivalents) */
+	foldEndPattern: RegExp | undefined;
+
+	/** Start with the whole block collapsed */
+	startCollapsed: boolean;
+
+	/** Disclosure bar text of a collapsed block (empty = line count) */
+	summaryText: string;
+
+	/** Block ID for line links (empty = the ^id line after the fence, if any) */
+	blockId: string;
+
+	/** Draw indentation guides */
+	showIndentGuides: boolean;
+
+	/** Indentation guide colour (empty = theme colour) */
+	indentGuideColour: string;
+
+	/** Indentation guide line style: 'solid', 'dashed' or 'dotted' */
+	indentGuideStyle: string;
+
+	/** Whitespace glyphs: 'all', 'trailing' or 'none' */
+	whitespaceMode: string;
+
+	/** Comment keywords: 'on', 'icons' or 'off' */
+	commentKeywordMode: string;
+
+	/** Keywords picked out in comments */
+	commentKeywords: CommentKeywordRule[];
+
+	/** Colour bracket pairs by depth and show a bracket's match on hover */
+	bracketPairColours: boolean;
+
+	/** Mark function definitions, parameters and field reads */
+	semanticHighlighting: boolean;
+
+	/** Fill in {{fm.*}}, {{var.*}}, {{date}} and {{time}} variables in the code shown */
+	renderVariables: boolean;
+
+	/** Snapshot to mark changed lines against: 'last', an age such as '7d', or 'off' */
+	changedSince: string;
+
+	/** Minimap lines: 0 = disabled, 1+ = minimap for blocks longer than N lines */
+	minimapLines: number;
+
+	/** Zebra stripe colour (empty = theme colour) */
+	zebraColour: string;
+
+	/** Highlight the line under the pointer */
+	showLineHover: boolean;
+
+	/** Font ligatures on or off (undefined = the theme's choice) */
+	fontLigatures: boolean | undefined;
+
+	/** OpenType features, e.g. "ss01, cv02=3, -calt" (empty = none) */
+	fontFeatures: string;
+
+	/** Font family of the code (empty = theme font) */
+	fontFamily: string;
+
+	/** CSS font size of the code, e.g. "13px" (empty = theme size) */
+	fontSize: string;
+
+	/** Columns per hard tab */
+	tabSize: number;
+
+	/** Columns to draw a ruler at (empty = no rulers) */
+	rulerColumns: number[];
+
+	/** Ruler colour (empty = theme colour) */
+	rulerColour: string;
+
+	/** Text direction of each line: 'ltr', 'rtl' or 'auto' (empty = inherited) */
+	textDirection: string;
+
 	/** Show copy button */
 	showCopyButton: boolean;
 
+	/** Show per-line copy buttons in the gutter */
+	showLineCopyButtons: boolean;
+
 	/** Join operator for Shift+click copy (empty = disabled) */
 	shiftCopyJoin: string;
 
@@ -781,6 +1688,10 @@ export interface ResolvedBlockConfig {
 	/** Regex pattern matching lines to ignore during joined copies (empty = disabled) */
 	joinIgnoreRegex: string;
 
+	// PROMPT (top-level)
+	/** Prompt regex; prompts are styled and stripped from copies (undefined = none) */
+	promptPattern: RegExp | undefined;
+
 	// FILTER section
 	/** BY_LINES filter configuration */
 	filterByLines: ResolvedFilterByLines;
@@ -788,11 +1699,168 @@ export interface ResolvedBlockConfig {
 	/** BY_MARKS filter configuration */
 	filterByMarks: ResolvedFilterByMarks;
 
+	/** Named region to extract (FILTER.BY_REGION or a META.SRC fragment; empty = none) */
+	filterByRegion: string;
+
 	/** Print behaviour: 'expand' or 'asis' */
 	printBehaviour: string;
 
+	/** Colours when printing: 'light' or 'asis' */
+	printTheme: string;
+
+	/** Print line numbers even when they are hidden on screen */
+	printLineNumbers: boolean;
+
+	/** Keep the block on one page when printing */
+	printAvoidBreaks: boolean;
+
+	/** Show a run button on this block (RUN.ENABLED) */
+	runEnabled: boolean;
+
+	/** Save the output in the note after the block (RUN.SAVE) */
+	runSaveOutput: boolean;
+
+	/** Show a run button on each line (RUN.LINES) */
+	runLines: boolean;
+
+	/** Kill a run after this many milliseconds, 0 = no limit (RUN.TIMEOUT) */
+	runTimeoutMs: number;
+
+	/** Environment variables for the run, references not yet filled in (RUN.ENV) */
+	runEnv: Record<string, string>;
+
 	/** CALLOUT section configuration (placeholder; resolved with source code in main.ts) */
 	calloutConfig: ResolvedCalloutConfig;
+
+	// COPY section
+	/** Transformations offered in the "Copy as…" menu (empty = no menu) */
+	copyAsEntries: ResolvedCopyAsEntry[];
+
+	/** Ask for {{PLACEHOLDER}} values when copying */
+	copyPlaceholders: boolean;
+
+	/** Fill in code variables when copying or downloading */
+	copyVariables: boolean;
+
+	/** Leave whole-line comments out of copies */
+	stripComments: boolean;
+
+	/** Secret patterns (global regexes); matches are masked on screen and redacted on copy */
+	redactPatterns: RegExp[];
+
+	/** Copy confirmation (checkmark, toast, status bar) */
+	copyFeedback: CopyFeedbackConfig;
+
+	// HIGHLIGHT section
+	/** Highlighted line numbers, sorted and unique (1-based, as rendered) */
+	highlightLines: number[];
+
+	/** Lines whose text matches are highlighted (undefined = none) */
+	highlightPattern: RegExp | undefined;
+
+	/** Terms marked wherever they occur, each in its own colour */
+	highlightTerms: string[];
+
+	/** Lines left undimmed, sorted and unique (empty = no focus) */
+	focusLines: number[];
+
+	/** Show the button that undims the rest of a focused block */
+	showFocusToggle: boolean;
+
+	// HEADER section
+	/** Header icon: emoji or Lucide name, "none" to hide (undefined = file icon from settings) */
+	headerIcon: string | undefined;
+
+	/** Show the title text in the header */
+	showHeaderTitle: boolean;
+
+	/** Show the language badge in the header */
+	showLanguageBadge: boolean;
+
+	/** Move the copy and download buttons into the header */
+	buttonsInHeader: boolean;
+
+	// FOOTER section
+	/** Attribution source name (empty = none) */
+	footerSource: string;
+
+	/** Attribution link (empty = none) */
+	footerUrl: string;
+
+	/** Attribution licence (empty = none) */
+	footerLicense: string;
+
+	// ANNOTATIONS section
+	/** Gutter annotation icons, sorted by line */
+	annotations: LineAnnotation[];
+
+	// BLAME section
+	/** Blame column metadata, sorted by line */
+	blame: LineBlame[];
+
+	// TOKENS section
+	/** Styles for highlighted tokens, in the order they apply (RENDER.THEME's first) */
+	tokenStyles: TokenStyle[];
+
+	/** Highlight theme picked with RENDER.THEME (undefined = none or not imported) */
+	highlightTheme: HighlightTheme | undefined;
+
+	// PATTERNS section
+	/** Styles of text matching a regex, in the order they apply */
+	patternStyles: PatternStyle[];
+
+	// DIFF section
+	/** Read the block as a unified diff (diff or patch language) */
+	unifiedDiff: boolean;
+
+	/** Lines marked as added (1-based, as rendered) */
+	diffAddedLines: number[];
+
+	/** Lines marked as removed (1-based, as rendered) */
+	diffRemovedLines: number[];
+
+	/** Copy only the new version of a diff (removed lines and markers left out) */
+	copyDiffAfter: boolean;
+
+	/** Emphasise the changed words of each removed line and its replacement */
+	diffWordHighlight: boolean;
+
+	/** Show old and new versions side by side */
+	splitDiff: boolean;
+
+	/** Region holding the old version (empty = split the unified diff) */
+	diffOldRegion: string;
+
+	/** Region holding the new version */
+	diffNewRegion: string;
+
+	// DOWNLOAD section
+	/** Download filename template (empty = source filename or title) */
+	downloadFilenameTemplate: string;
+
+	/** Shebang line prepended to downloads (empty = none) */
+	downloadShebang: string;
+
+	/** Set the executable bit when saving (desktop only) */
+	downloadExecutable: boolean;
+}
+
+// =============================================================================
+// Clipboard History
+// =============================================================================
+
+/**
+ * A copy from a code block, kept for the current session.
+ */
+export interface ClipboardHistoryEntry {
+	/** Text that was placed on the clipboard */
+	text: string;
+
+	/** Where it was copied from (block title or note name) */
+	source: string;
+
+	/** When it was copied (Unix ms) */
+	copiedAt: number;
 }
 
 /**
@@ -806,6 +1874,9 @@ export interface ResolvedCmdoutConfig {
 	/** Description text */
 	descriptionText: string;
 
+	/** How configuration problems are handled */
+	configMode: ConfigMode;
+
 	// DISPLAY section
 	/** Scroll lines: 0 = disabled, 1+ = scroll after N lines */
 	scrollLines: number;
@@ -823,4 +1894,19 @@ export interface ResolvedCmdoutConfig {
 
 	/** Print behaviour: 'expand' or 'asis' */
 	printBehaviour: string;
+
+	/** Colours when printing: 'light' or 'asis' */
+	printTheme: string;
+
+	/** Keep the block on one page when printing */
+	printAvoidBreaks: boolean;
+
+	/** Show ANSI colour codes in output lines as coloured text */
+	renderAnsi: boolean;
+
+	/** Copy output lines with their ANSI codes (false = as shown) */
+	copyRawAnsi: boolean;
+
+	/** Copy confirmation (checkmark, toast, status bar) */
+	copyFeedback: CopyFeedbackConfig;
 }
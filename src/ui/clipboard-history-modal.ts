@@ -0,0 +1,72 @@
+/**
+ * Ultra Code Fence - Clipboard History Modal
+ *
+ * Lists recent code block copies in a searchable picker so one can be
+ * copied again without going back to the note it came from.
+ */
+
+import { App, SuggestModal } from 'obsidian';
+import type { ClipboardHistoryEntry } from '../types';
+import { CSS_CLASSES } from '../constants';
+import { calculateRelativeTime } from '../utils';
+
+// =============================================================================
+// Modal Implementation
+// =============================================================================
+
+/**
+ * Picker over the clipboard history, newest first.
+ *
+ * Typing filters entries by their text or source.
+ */
+export class ClipboardHistoryModal extends SuggestModal<ClipboardHistoryEntry> {
+	private entries: ClipboardHistoryEntry[];
+	private onChoose: (entry: ClipboardHistoryEntry) => void;
+
+	/**
+	 * Creates a new clipboard history modal.
+	 *
+	 * @param app - Obsidian App instance
+	 * @param entries - History entries, newest first
+	 * @param onChoose - Called with the chosen entry
+	 */
+	constructor(app: App, entries: ClipboardHistoryEntry[], onChoose: (entry: ClipboardHistoryEntry) => void) {
+		super(app);
+		this.entries = entries;
+		this.onChoose = onChoose;
+		this.setPlaceholder('Search recent code block copies');
+	}
+
+	/**
+	 * Filters entries by text or source (case-insensitive).
+	 */
+	getSuggestions(query: string): ClipboardHistoryEntry[] {
+		const needle = query.trim().toLowerCase();
+		if (!needle) return this.entries;
+
+		return this.entries.filter(entry =>
+			entry.text.toLowerCase().includes(needle) || entry.source.toLowerCase().includes(needle)
+		);
+	}
+
+	/**
+	 * Shows the first line of the copy, with its source, size and age below.
+	 */
+	renderSuggestion(entry: ClipboardHistoryEntry, element: HTMLElement): void {
+		const lines = entry.text.split('\n');
+		const firstLine = lines.find(line => line.trim() !== '') ?? '';
+
+		element.addClass(CSS_CLASSES.historyItem);
+		element.createEl('div', { cls: CSS_CLASSES.historyText, text: firstLine.trim() });
+
+		const details = [entry.source, lines.length === 1 ? '1 line' : `${String(lines.length)} lines`, calculateRelativeTime(entry.copiedAt)];
+		element.createEl('small', { cls: CSS_CLASSES.historyDetails, text: details.filter(Boolean).join(' · ') });
+	}
+
+	/**
+	 * Hands the chosen entry to the callback.
+	 */
+	onChooseSuggestion(entry: ClipboardHistoryEntry): void {
+		this.onChoose(entry);
+	}
+}
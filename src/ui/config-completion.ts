@@ -0,0 +1,247 @@
+/**
+ * Ultra Code Fence - Settings Autocomplete
+ *
+ * Works out what can be typed at the cursor inside the settings region
+ * of a ufence block: option keys at the current nesting level, or known
+ * values (booleans, styles, formats, preset names) after a key.
+ *
+ * Kept free of editor APIs so it can be tested on plain lines; the
+ * EditorSuggest in config-suggest.ts feeds it the note's lines.
+ */
+
+import { detectConfigFormat } from '../parsers/config-format';
+import { YAML_META } from '../constants';
+import type { ConfigSchema, SchemaKey } from './yaml-validator';
+import { CODE_BLOCK_SCHEMA, CMDOUT_BLOCK_SCHEMA, TYPE_LABELS } from './yaml-validator';
+
+// =============================================================================
+// Types
+// =============================================================================
+
+/** A single autocomplete suggestion. */
+export interface ConfigCompletion {
+	/** Key or value shown in the list */
+	label: string;
+	/** Secondary text, e.g. the value type */
+	detail: string;
+	/** Text that replaces the typed query */
+	insertText: string;
+}
+
+/** Suggestions for the cursor position. */
+export interface ConfigCompletionResult {
+	/** Whether a key or a value is being typed */
+	kind: 'key' | 'value';
+	/** Column where the typed query starts (replaced on selection) */
+	startCh: number;
+	/** The typed query */
+	query: string;
+	/** Matching suggestions */
+	completions: ConfigCompletion[];
+}
+
+// =============================================================================
+// Patterns
+// =============================================================================
+
+/** Opening fence of a ufence block: ```ufence-bash */
+const UFENCE_OPEN_PATTERN = /^\s*(?:`{3,}|~{3,})\s*ufence-(\S+)/;
+
+/** Closing fence (or a fence of another block): ``` */
+const FENCE_PATTERN = /^\s*(?:`{3,}|~{3,})/;
+
+/** A YAML key line: "  - KEY: value" */
+const KEY_LINE_PATTERN = /^(\s*)(-\s+)?([A-Za-z_]\w*)\s*:(.*)$/;
+
+/** Typing a key: "  - KE" */
+const TYPING_KEY_PATTERN = /^(\s*)(-\s+)?([A-Za-z_]*)$/;
+
+/** Typing a value: "  KEY: val" (optionally quoted) */
+const TYPING_VALUE_PATTERN = /^(\s*)(-\s+)?([A-Za-z_]\w*)\s*:\s*["']?([^"'#]*)$/;
+
+// =============================================================================
+// Context
+// =============================================================================
+
+/**
+ * Finds the ufence block the cursor is in, if it is in the settings part.
+ *
+ * @param lines - Note lines up to and including the cursor line
+ * @param cursorLine - Cursor line index
+ * @returns Index of the opening fence line and the block type, or null
+ */
+function findSettingsRegion(lines: string[], cursorLine: number): { fenceLine: number; blockType: string } | null {
+	for (let i = cursorLine - 1; i >= 0; i--) {
+		const line = lines[i];
+
+		const openMatch = UFENCE_OPEN_PATTERN.exec(line);
+		if (openMatch) return { fenceLine: i, blockType: openMatch[1] };
+
+		// A ~~~ separator (code follows) or another fence: not in settings
+		if (FENCE_PATTERN.test(line)) return null;
+	}
+	return null;
+}
+
+/**
+ * Works out the schema level (and enclosing path) for a key typed at
+ * the given column, by walking back to less indented parent keys.
+ *
+ * @param lines - Note lines
+ * @param fenceLine - Opening fence line index
+ * @param cursorLine - Cursor line index
+ * @param keyColumn - Column where the key starts
+ * @param dashColumn - Column of a list dash on the cursor line (-1 = none)
+ * @param schema - Top-level schema for the block type
+ * @returns Schema level, or null if the position is not a known level
+ */
+function resolveSchemaLevel(
+	lines: string[],
+	fenceLine: number,
+	cursorLine: number,
+	keyColumn: number,
+	dashColumn: number,
+	schema: ConfigSchema
+): ConfigSchema | null {
+	const path: string[] = [];
+	let inEntry = dashColumn >= 0;
+	let indent = inEntry ? dashColumn + 1 : keyColumn;
+
+	for (let i = cursorLine - 1; i > fenceLine && indent > 0; i--) {
+		const match = KEY_LINE_PATTERN.exec(lines[i]);
+		if (!match) continue;
+
+		const lineDashColumn = match[2] ? match[1].length : -1;
+		const lineKeyColumn = match[1].length + (match[2] ? match[2].length : 0);
+
+		// An earlier key of the same list entry
+		if (lineDashColumn >= 0) {
+			if (!inEntry && lineKeyColumn === indent) {
+				inEntry = true;
+				indent = lineDashColumn + 1;
+			}
+			continue;
+		}
+
+		if (lineKeyColumn >= indent) continue;
+
+		// A less indented key with a value can't contain the cursor
+		if (match[4].replace(/#.*$/, '').trim() !== '') return null;
+
+		path.unshift(match[3]);
+		indent = lineKeyColumn;
+	}
+
+	if (indent > 0) return null;
+
+	let level = schema;
+	for (let index = 0; index < path.length; index++) {
+		const schemaKey: SchemaKey | undefined = level[path[index]];
+		if (!schemaKey?.keys) return null;
+
+		// Entry keys only apply inside a "- " list item
+		const isLast = index === path.length - 1;
+		if (schemaKey.type === 'entries' && !(isLast && inEntry)) return null;
+		if (schemaKey.type === 'section' && isLast && inEntry) return null;
+
+		level = schemaKey.keys;
+	}
+
+	return level;
+}
+
+// =============================================================================
+// Completions
+// =============================================================================
+
+/**
+ * Lists values that can follow a key.
+ *
+ * @param key - Key name
+ * @param schemaKey - Schema entry for the key
+ * @param presetNames - Saved preset names (for META.PRESET)
+ * @returns Known values
+ */
+function getKnownValues(key: string, schemaKey: SchemaKey, presetNames: string[]): readonly string[] {
+	if (key === YAML_META.preset) return presetNames;
+	if (schemaKey.type === 'boolean') return ['true', 'false'];
+	return schemaKey.values ?? [];
+}
+
+/**
+ * Checks whether a suggestion matches what has been typed so far.
+ *
+ * @param candidate - Suggestion text
+ * @param query - Typed text
+ * @returns True for a case-insensitive prefix match
+ */
+function matchesQuery(candidate: string, query: string): boolean {
+	return candidate.toLowerCase().startsWith(query.toLowerCase());
+}
+
+/**
+ * Gets autocomplete suggestions for the cursor position in a note.
+ *
+ * Only YAML settings are completed; TOML and JSON settings, the code
+ * after a ~~~ separator and text outside ufence blocks get nothing.
+ *
+ * @param lines - Note lines up to and including the cursor line
+ * @param cursorLine - Cursor line index
+ * @param cursorCh - Cursor column
+ * @param presetNames - Saved preset names
+ * @returns Suggestions, or null when the cursor is not in block settings
+ */
+export function getConfigCompletions(
+	lines: string[],
+	cursorLine: number,
+	cursorCh: number,
+	presetNames: string[] = []
+): ConfigCompletionResult | null {
+	const region = findSettingsRegion(lines, cursorLine);
+	if (!region) return null;
+
+	const settingsText = lines.slice(region.fenceLine + 1, cursorLine + 1).join('\n');
+	if (detectConfigFormat(settingsText) !== 'yaml') return null;
+
+	const schema = region.blockType === 'cmdout' ? CMDOUT_BLOCK_SCHEMA : CODE_BLOCK_SCHEMA;
+	const beforeCursor = lines[cursorLine].slice(0, cursorCh);
+
+	const keyMatch = TYPING_KEY_PATTERN.exec(beforeCursor);
+	if (keyMatch) {
+		const dashColumn = keyMatch[2] ? keyMatch[1].length : -1;
+		const keyColumn = keyMatch[1].length + (keyMatch[2] ? keyMatch[2].length : 0);
+		const level = resolveSchemaLevel(lines, region.fenceLine, cursorLine, keyColumn, dashColumn, schema);
+		if (!level) return null;
+
+		const query = keyMatch[3];
+		const completions = Object.entries(level)
+			.filter(([key]) => matchesQuery(key, query))
+			.map(([key, schemaKey]) => ({
+				label: key,
+				detail: TYPE_LABELS[schemaKey.type],
+				insertText: schemaKey.type === 'section' || schemaKey.type === 'entries' ? `${key}:` : `${key}: `,
+			}));
+
+		return { kind: 'key', startCh: keyColumn, query, completions };
+	}
+
+	const valueMatch = TYPING_VALUE_PATTERN.exec(beforeCursor);
+	if (valueMatch) {
+		const dashColumn = valueMatch[2] ? valueMatch[1].length : -1;
+		const keyColumn = valueMatch[1].length + (valueMatch[2] ? valueMatch[2].length : 0);
+		const level = resolveSchemaLevel(lines, region.fenceLine, cursorLine, keyColumn, dashColumn, schema);
+
+		const key = valueMatch[3];
+		const schemaKey: SchemaKey | undefined = level?.[key];
+		if (!schemaKey) return null;
+
+		const query = valueMatch[4].trimStart();
+		const completions = getKnownValues(key, schemaKey, presetNames)
+			.filter(value => matchesQuery(value, query))
+			.map(value => ({ label: value, detail: key === YAML_META.preset ? 'preset' : key, insertText: value }));
+
+		return { kind: 'value', startCh: cursorCh - query.length, query, completions };
+	}
+
+	return null;
+}
@@ -0,0 +1,95 @@
+/**
+ * Ultra Code Fence - Settings Inspector Modal
+ *
+ * Shows a block's effective settings in a table, with the layer each
+ * value comes from, to help work out why a block looks the way it does.
+ */
+
+import { App, Modal } from 'obsidian';
+import type { ConfigInspection, ConfigLayer } from '../utils/config-inspect';
+import { CSS_CLASSES } from '../constants';
+
+/** Column text for each layer */
+const LAYER_LABELS: Record<ConfigLayer, string> = {
+	default: 'Default',
+	settings: 'Plugin settings',
+	folder: 'Folder preset',
+	frontmatter: 'Frontmatter',
+	page: 'Page config',
+	preset: 'Preset',
+	block: 'This block',
+	shorthand: 'Fence line',
+};
+
+/**
+ * Formats a setting value for the table.
+ *
+ * @param value - Setting value
+ * @returns Display text (strings quoted, so empty ones are visible)
+ */
+function formatValue(value: unknown): string {
+	return JSON.stringify(value);
+}
+
+// =============================================================================
+// Modal Implementation
+// =============================================================================
+
+/**
+ * Read-only table of a block's effective settings.
+ */
+export class ConfigInspectModal extends Modal {
+	private blockType: string;
+	private inspection: ConfigInspection;
+
+	/**
+	 * Creates a new inspector modal.
+	 *
+	 * @param app - Obsidian App instance
+	 * @param blockType - Block type after "ufence-" (shown in the heading)
+	 * @param inspection - Effective settings to show
+	 */
+	constructor(app: App, blockType: string, inspection: ConfigInspection) {
+		super(app);
+		this.blockType = blockType;
+		this.inspection = inspection;
+	}
+
+	/**
+	 * Builds the table when opened.
+	 */
+	onOpen(): void {
+		const { contentEl } = this;
+		contentEl.addClass(CSS_CLASSES.inspectModal);
+
+		contentEl.createEl('h2', { text: `Effective settings of this ufence-${this.blockType} block` });
+
+		contentEl.createEl('p', {
+			text: 'Each value comes from the last layer that sets it, in the order of the settings cascade: by default the built-in default, plugin settings, folder preset, frontmatter, page config (the ufence-ufence block), preset, this block, then its fence line.',
+		});
+		if (this.inspection.presetName) {
+			contentEl.createEl('p', { text: `Preset: ${this.inspection.presetName}` });
+		}
+
+		const table = contentEl.createEl('table', { cls: CSS_CLASSES.inspectTable });
+		const headerRow = table.createEl('thead').createEl('tr');
+		for (const heading of ['Setting', 'Value', 'From']) {
+			headerRow.createEl('th', { text: heading });
+		}
+
+		const body = table.createEl('tbody');
+		for (const entry of this.inspection.entries) {
+			const row = body.createEl('tr');
+			row.createEl('td').createEl('code', { text: entry.path });
+			row.createEl('td').createEl('code', { text: formatValue(entry.value) });
+			row.createEl('td', { cls: `${CSS_CLASSES.inspectLayer}-${entry.layer}`, text: LAYER_LABELS[entry.layer] });
+		}
+	}
+
+	/**
+	 * Cleans up when closed.
+	 */
+	onClose(): void {
+		this.contentEl.empty();
+	}
+}
@@ -0,0 +1,104 @@
+/**
+ * Ultra Code Fence - Settings Migration Modal
+ *
+ * Previews the rewrite of old settings keys across the vault, note by
+ * note, and lets the user choose which notes to update. Nothing is
+ * written until the changes are applied.
+ */
+
+import { App, Modal } from 'obsidian';
+import type { ConfigMigrationPlan } from '../services/config-migration';
+import { CSS_CLASSES } from '../constants';
+
+// =============================================================================
+// Modal Implementation
+// =============================================================================
+
+/**
+ * Dry-run preview of a settings migration.
+ *
+ * Each note is listed with the lines that would change and a checkbox
+ * (ticked by default). Applying passes the ticked notes to the callback.
+ */
+export class ConfigMigrationModal extends Modal {
+	private plans: ConfigMigrationPlan[];
+	private onApply: (plans: ConfigMigrationPlan[]) => void;
+	private selectedPaths: Set<string>;
+
+	/**
+	 * Creates a new migration modal.
+	 *
+	 * @param app - Obsidian App instance
+	 * @param plans - Notes that would change
+	 * @param onApply - Receives the notes chosen for updating
+	 */
+	constructor(app: App, plans: ConfigMigrationPlan[], onApply: (plans: ConfigMigrationPlan[]) => void) {
+		super(app);
+		this.plans = plans;
+		this.onApply = onApply;
+		this.selectedPaths = new Set(plans.map(plan => plan.path));
+	}
+
+	/**
+	 * Builds the preview when opened.
+	 */
+	onOpen(): void {
+		const { contentEl } = this;
+		contentEl.addClass(CSS_CLASSES.migrationModal);
+
+		contentEl.createEl('h2', { text: 'Update old code block settings' });
+
+		const noteCount = this.plans.length === 1 ? '1 note uses' : `${String(this.plans.length)} notes use`;
+		contentEl.createEl('p', {
+			text: `${noteCount} settings names from an earlier release. Review the changes below; nothing is written until you apply them, and the update can be undone afterwards.`,
+		});
+
+		for (const plan of this.plans) {
+			const noteElement = contentEl.createEl('div', { cls: CSS_CLASSES.migrationNote });
+
+			const label = noteElement.createEl('label');
+			const checkbox = label.createEl('input', { attr: { type: 'checkbox' } });
+			checkbox.checked = true;
+			checkbox.addEventListener('change', () => {
+				if (checkbox.checked) {
+					this.selectedPaths.add(plan.path);
+				} else {
+					this.selectedPaths.delete(plan.path);
+				}
+			});
+			label.createSpan({ text: plan.path });
+
+			const changeList = noteElement.createEl('ul');
+			for (const change of plan.changes) {
+				const item = changeList.createEl('li');
+				item.createSpan({ cls: CSS_CLASSES.migrationLine, text: `Line ${String(change.line)}` });
+				item.createEl('code', { text: change.before.trim() });
+				item.createSpan({ text: ' → ' });
+				item.createEl('code', { text: change.after.trim() });
+			}
+		}
+
+		const buttonContainer = contentEl.createEl('div', { cls: CSS_CLASSES.modalButtons });
+		const cancelButton = buttonContainer.createEl('button', { text: 'Cancel' });
+		cancelButton.addEventListener('click', () => { this.close(); });
+
+		const applyButton = buttonContainer.createEl('button', {
+			text: 'Apply',
+			cls: 'mod-cta',
+		});
+		applyButton.addEventListener('click', () => {
+			const chosen = this.plans.filter(plan => this.selectedPaths.has(plan.path));
+			this.close();
+			if (chosen.length > 0) {
+				this.onApply(chosen);
+			}
+		});
+	}
+
+	/**
+	 * Cleans up when closed.
+	 */
+	onClose(): void {
+		this.contentEl.empty();
+	}
+}
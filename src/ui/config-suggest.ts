@@ -0,0 +1,77 @@
+/**
+ * Ultra Code Fence - Settings Autocomplete Popup
+ *
+ * Offers option keys, known values and preset names while typing in the
+ * settings region of a ufence block in the editor.
+ */
+
+import { App, Editor, EditorPosition, EditorSuggest, EditorSuggestContext, EditorSuggestTriggerInfo } from 'obsidian';
+import { CSS_CLASSES, CONFIG_SUGGEST_LOOKBACK_LINES } from '../constants';
+import type { ConfigCompletion } from './config-completion';
+import { getConfigCompletions } from './config-completion';
+
+/**
+ * Editor suggest for ufence block settings.
+ */
+export class ConfigSuggest extends EditorSuggest<ConfigCompletion> {
+	/** Returns the names of the saved presets */
+	private getPresetNames: () => string[];
+
+	/** Suggestions worked out in onTrigger, shown by getSuggestions */
+	private pendingCompletions: ConfigCompletion[] = [];
+
+	/**
+	 * Creates the suggest.
+	 *
+	 * @param app - Obsidian app instance
+	 * @param getPresetNames - Returns the names of the saved presets
+	 */
+	constructor(app: App, getPresetNames: () => string[]) {
+		super(app);
+		this.getPresetNames = getPresetNames;
+	}
+
+	/**
+	 * Opens the popup when the cursor is on a key or a value in block
+	 * settings. Keys need at least one typed character; values are offered
+	 * straight after "KEY: ".
+	 */
+	onTrigger(cursor: EditorPosition, editor: Editor): EditorSuggestTriggerInfo | null {
+		const firstLine = Math.max(0, cursor.line - CONFIG_SUGGEST_LOOKBACK_LINES);
+		const lines: string[] = [];
+		for (let line = firstLine; line <= cursor.line; line++) {
+			lines.push(editor.getLine(line));
+		}
+
+		const result = getConfigCompletions(lines, cursor.line - firstLine, cursor.ch, this.getPresetNames());
+		if (!result || result.completions.length === 0) return null;
+		if (result.kind === 'key' && result.query === '') return null;
+
+		// Nothing left to complete once the value is typed in full
+		if (result.completions.length === 1 && result.completions[0].insertText === result.query) return null;
+
+		this.pendingCompletions = result.completions;
+		return {
+			start: { line: cursor.line, ch: result.startCh },
+			end: cursor,
+			query: result.query,
+		};
+	}
+
+	getSuggestions(_context: EditorSuggestContext): ConfigCompletion[] {
+		return this.pendingCompletions;
+	}
+
+	renderSuggestion(completion: ConfigCompletion, element: HTMLElement): void {
+		element.createEl('span', { text: completion.label });
+		element.createEl('small', { cls: CSS_CLASSES.suggestDetail, text: completion.detail });
+	}
+
+	selectSuggestion(completion: ConfigCompletion): void {
+		if (!this.context) return;
+
+		const { editor, start, end } = this.context;
+		editor.replaceRange(completion.insertText, start, end);
+		editor.setCursor({ line: start.line, ch: start.ch + completion.insertText.length });
+	}
+}
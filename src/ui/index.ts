@@ -9,6 +9,68 @@ export {
 	showWhatsNewIfUpdated,
 } from './whats-new-modal';
 
+export {
+	PlaceholderModal,
+	promptForPlaceholders,
+} from './placeholder-modal';
+
+export {
+	ClipboardHistoryModal,
+} from './clipboard-history-modal';
+
+export {
+	SourceEditModal,
+} from './source-edit-modal';
+
+export {
+	SnippetModal,
+	formatSnippetDetails,
+} from './snippet-modal';
+
+export type { SnippetLibraryHost } from './snippet-library-view';
+
+export {
+	SnippetLibraryView,
+} from './snippet-library-view';
+
+export {
+	ConfigMigrationModal,
+} from './config-migration-modal';
+
+export {
+	ConfigInspectModal,
+} from './config-inspect-modal';
+
+export {
+	RunConsentModal,
+} from './run-consent-modal';
+
+export {
+	PresetExportModal,
+	PresetImportModal,
+} from './preset-transfer-modal';
+
+export {
+	PresetGalleryModal,
+} from './preset-gallery-modal';
+
+export type { ConfigSchema, SchemaKey, SchemaValueType, YamlWarning } from './yaml-validator';
+
+export {
+	CODE_BLOCK_SCHEMA,
+	PRESET_SCHEMA,
+	CMDOUT_BLOCK_SCHEMA,
+	validateYamlSchema,
+	formatWarning,
+	formatWarnings,
+} from './yaml-validator';
+
+export type { ConfigCompletion, ConfigCompletionResult } from './config-completion';
+
+export { getConfigCompletions } from './config-completion';
+
+export { ConfigSuggest } from './config-suggest';
+
 export type { SettingsPlugin } from './settings-tab';
 
 export {
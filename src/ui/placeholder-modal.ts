@@ -0,0 +1,133 @@
+/**
+ * Ultra Code Fence - Placeholder Modal
+ *
+ * Asks for {{PLACEHOLDER}} values when copying a block, then hands back
+ * the substituted text. Values are remembered for the rest of the session.
+ */
+
+import { App, Modal } from 'obsidian';
+import { CSS_CLASSES } from '../constants';
+import { findPlaceholders, substitutePlaceholders } from '../utils';
+
+/**
+ * Values entered this session, keyed by placeholder name.
+ * Used to pre-fill the form the next time the same name comes up.
+ */
+const rememberedValues: Record<string, string | undefined> = {};
+
+// =============================================================================
+// Modal Implementation
+// =============================================================================
+
+/**
+ * Modal with one text field per placeholder.
+ *
+ * Submitting (button or Enter) passes the entered values to the callback;
+ * closing any other way passes null.
+ */
+export class PlaceholderModal extends Modal {
+	private placeholderNames: string[];
+	private onDone: (values: Record<string, string> | null) => void;
+	private inputs: Record<string, HTMLInputElement> = {};
+	private submitted = false;
+
+	/**
+	 * Creates a new placeholder modal.
+	 *
+	 * @param app - Obsidian App instance
+	 * @param placeholderNames - Placeholder names to ask for (form order)
+	 * @param onDone - Receives the entered values, or null if cancelled
+	 */
+	constructor(app: App, placeholderNames: string[], onDone: (values: Record<string, string> | null) => void) {
+		super(app);
+		this.placeholderNames = placeholderNames;
+		this.onDone = onDone;
+	}
+
+	/**
+	 * Builds the form when opened.
+	 */
+	onOpen(): void {
+		const { contentEl } = this;
+		contentEl.addClass(CSS_CLASSES.placeholderModal);
+
+		contentEl.createEl('h2', { text: 'Fill in placeholders' });
+
+		for (const name of this.placeholderNames) {
+			const field = contentEl.createEl('label', { cls: CSS_CLASSES.placeholderField });
+			field.createSpan({ text: name });
+
+			const input = field.createEl('input', { attr: { type: 'text' } });
+			input.value = rememberedValues[name] ?? '';
+			input.addEventListener('keydown', (event) => {
+				if (event.key === 'Enter') {
+					event.preventDefault();
+					this.submit();
+				}
+			});
+			this.inputs[name] = input;
+		}
+
+		const buttonContainer = contentEl.createEl('div', { cls: CSS_CLASSES.modalButtons });
+		const submitButton = buttonContainer.createEl('button', {
+			text: 'Apply',
+			cls: 'mod-cta',
+		});
+		submitButton.addEventListener('click', () => { this.submit(); });
+
+		this.inputs[this.placeholderNames[0]]?.focus();
+	}
+
+	/**
+	 * Collects the entered values and closes the modal.
+	 */
+	private submit(): void {
+		const values: Record<string, string> = {};
+		for (const name of this.placeholderNames) {
+			values[name] = this.inputs[name]?.value ?? '';
+			rememberedValues[name] = values[name];
+		}
+
+		this.submitted = true;
+		this.close();
+		this.onDone(values);
+	}
+
+	/**
+	 * Reports a cancel (if not submitted) and cleans up.
+	 */
+	onClose(): void {
+		const { contentEl } = this;
+		contentEl.empty();
+
+		if (!this.submitted) {
+			this.onDone(null);
+		}
+	}
+}
+
+// =============================================================================
+// Helper Functions
+// =============================================================================
+
+/**
+ * Asks for the values of any placeholders in the text and substitutes them.
+ *
+ * Text without placeholders is returned straight away without a prompt.
+ *
+ * @param app - Obsidian App instance
+ * @param codeText - Text bound for the clipboard
+ * @returns Substituted text, or null if the user cancelled
+ */
+export function promptForPlaceholders(app: App, codeText: string): Promise<string | null> {
+	const placeholderNames = findPlaceholders(codeText);
+	if (placeholderNames.length === 0) {
+		return Promise.resolve(codeText);
+	}
+
+	return new Promise(resolve => {
+		new PlaceholderModal(app, placeholderNames, (values) => {
+			resolve(values ? substitutePlaceholders(codeText, values) : null);
+		}).open();
+	});
+}
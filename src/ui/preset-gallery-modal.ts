@@ -0,0 +1,102 @@
+/**
+ * Ultra Code Fence - Preset Gallery Modal
+ *
+ * Lists the curated presets bundled with the plugin, each with a short
+ * description and a sample block rendered with it, and installs one into
+ * the saved presets with a click.
+ */
+
+import { App, Modal } from 'obsidian';
+import type { PresetGalleryEntry } from '../types';
+import { CSS_CLASSES } from '../constants';
+
+/**
+ * Browses the preset gallery.
+ */
+export class PresetGalleryModal extends Modal {
+	private entries: PresetGalleryEntry[];
+	private findInstalled: (entry: PresetGalleryEntry) => string | undefined;
+	private renderPreview: (containerElement: HTMLElement, presetYaml: string) => Promise<void>;
+	private onInstall: (entry: PresetGalleryEntry) => Promise<string>;
+
+	/**
+	 * Creates a new gallery modal.
+	 *
+	 * @param app - Obsidian App instance
+	 * @param entries - Gallery presets
+	 * @param findInstalled - Name of the saved preset matching an entry, if installed
+	 * @param renderPreview - Renders a sample block with a preset's YAML
+	 * @param onInstall - Installs an entry, resolving to the name it was saved under
+	 */
+	constructor(
+		app: App,
+		entries: PresetGalleryEntry[],
+		findInstalled: (entry: PresetGalleryEntry) => string | undefined,
+		renderPreview: (containerElement: HTMLElement, presetYaml: string) => Promise<void>,
+		onInstall: (entry: PresetGalleryEntry) => Promise<string>
+	) {
+		super(app);
+		this.entries = entries;
+		this.findInstalled = findInstalled;
+		this.renderPreview = renderPreview;
+		this.onInstall = onInstall;
+	}
+
+	/**
+	 * Builds the gallery when opened.
+	 */
+	onOpen(): void {
+		const { contentEl } = this;
+		contentEl.addClass(CSS_CLASSES.presetGalleryModal);
+
+		contentEl.createEl('h2', { text: 'Preset gallery' });
+		contentEl.createEl('p', {
+			text: 'Ready-made presets to start from. Installed presets appear in the presets tab, where you can change them like your own.',
+		});
+
+		for (const entry of this.entries) {
+			this.renderEntry(contentEl, entry);
+		}
+	}
+
+	/**
+	 * Adds one gallery preset: name, description, preview and install button.
+	 *
+	 * @param containerElement - Element to add the entry to
+	 * @param entry - Gallery preset
+	 */
+	private renderEntry(containerElement: HTMLElement, entry: PresetGalleryEntry): void {
+		const entryElement = containerElement.createEl('div', { cls: CSS_CLASSES.presetGalleryEntry });
+		entryElement.createEl('h3', { text: entry.name });
+		entryElement.createEl('p', { text: entry.description, cls: CSS_CLASSES.presetGalleryDescription });
+
+		const previewElement = entryElement.createEl('div', { cls: CSS_CLASSES.presetGalleryPreview });
+		void this.renderPreview(previewElement, entry.yaml);
+
+		const buttonContainer = entryElement.createEl('div', { cls: CSS_CLASSES.modalButtons });
+		const installButton = buttonContainer.createEl('button', { cls: 'mod-cta' });
+		const showInstalled = (name: string): void => {
+			installButton.textContent = name === entry.name ? 'Installed' : `Installed as ${name}`;
+			installButton.disabled = true;
+		};
+
+		const installedName = this.findInstalled(entry);
+		if (installedName !== undefined) {
+			showInstalled(installedName);
+			return;
+		}
+
+		installButton.textContent = 'Install';
+		installButton.addEventListener('click', () => {
+			installButton.disabled = true;
+			void this.onInstall(entry).then(showInstalled);
+		});
+	}
+
+	/**
+	 * Cleans up when closed.
+	 */
+	onClose(): void {
+		this.contentEl.empty();
+	}
+}
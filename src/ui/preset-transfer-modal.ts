@@ -0,0 +1,219 @@
+/**
+ * Ultra Code Fence - Preset Import and Export Modals
+ *
+ * Lets the user pick which presets go into an exported preset pack, and
+ * which presets to take from a pack being imported (with what to do about
+ * names that are already taken).
+ */
+
+import { App, Modal } from 'obsidian';
+import type { PresetConflictAction } from '../services/preset-transfer';
+import { CSS_CLASSES } from '../constants';
+
+/** Dropdown text for each conflict action */
+const CONFLICT_ACTION_LABELS: Record<PresetConflictAction, string> = {
+	skip: 'Keep my preset (skip)',
+	rename: 'Import under a new name',
+	overwrite: 'Replace my preset',
+};
+
+/**
+ * Adds a ticked checkbox per name to a list.
+ *
+ * @param container - Element to add the list to
+ * @param names - Names to list
+ * @param selected - Set of ticked names, kept up to date
+ * @param describe - Optional extra text for a name
+ */
+function renderChoiceList(
+	container: HTMLElement,
+	names: string[],
+	selected: Set<string>,
+	describe?: (name: string) => string | undefined
+): void {
+	const list = container.createEl('div', { cls: CSS_CLASSES.presetTransferList });
+
+	for (const name of names) {
+		const label = list.createEl('label');
+		const checkbox = label.createEl('input', { attr: { type: 'checkbox' } });
+		checkbox.checked = true;
+		checkbox.addEventListener('change', () => {
+			if (checkbox.checked) {
+				selected.add(name);
+			} else {
+				selected.delete(name);
+			}
+		});
+		label.createSpan({ text: name });
+
+		const description = describe?.(name);
+		if (description) {
+			label.createSpan({ cls: CSS_CLASSES.presetTransferConflict, text: description });
+		}
+	}
+}
+
+// =============================================================================
+// Export Modal
+// =============================================================================
+
+/**
+ * Picks the presets to export. All are ticked by default.
+ */
+export class PresetExportModal extends Modal {
+	private presetNames: string[];
+	private onExport: (names: string[]) => void;
+	private selectedNames: Set<string>;
+
+	/**
+	 * Creates a new export modal.
+	 *
+	 * @param app - Obsidian App instance
+	 * @param presetNames - Names of the saved presets
+	 * @param onExport - Receives the names chosen for export
+	 */
+	constructor(app: App, presetNames: string[], onExport: (names: string[]) => void) {
+		super(app);
+		this.presetNames = presetNames;
+		this.onExport = onExport;
+		this.selectedNames = new Set(presetNames);
+	}
+
+	/**
+	 * Builds the list when opened.
+	 */
+	onOpen(): void {
+		const { contentEl } = this;
+		contentEl.addClass(CSS_CLASSES.presetTransferModal);
+
+		contentEl.createEl('h2', { text: 'Export presets' });
+		contentEl.createEl('p', {
+			text: 'Choose the presets to save to a preset pack file, which can be imported into another vault.',
+		});
+
+		renderChoiceList(contentEl, this.presetNames, this.selectedNames);
+
+		const buttonContainer = contentEl.createEl('div', { cls: CSS_CLASSES.modalButtons });
+		const cancelButton = buttonContainer.createEl('button', { text: 'Cancel' });
+		cancelButton.addEventListener('click', () => { this.close(); });
+
+		const exportButton = buttonContainer.createEl('button', {
+			text: 'Export',
+			cls: 'mod-cta',
+		});
+		exportButton.addEventListener('click', () => {
+			const chosen = this.presetNames.filter(name => this.selectedNames.has(name));
+			this.close();
+			if (chosen.length > 0) {
+				this.onExport(chosen);
+			}
+		});
+	}
+
+	/**
+	 * Cleans up when closed.
+	 */
+	onClose(): void {
+		this.contentEl.empty();
+	}
+}
+
+// =============================================================================
+// Import Modal
+// =============================================================================
+
+/**
+ * Picks the presets to import from a pack. All are ticked by default;
+ * names that are already taken are marked, with a choice of what to do
+ * about them.
+ */
+export class PresetImportModal extends Modal {
+	private incoming: Record<string, string>;
+	private existing: Record<string, string>;
+	private onImport: (presets: Record<string, string>, action: PresetConflictAction) => void;
+	private selectedNames: Set<string>;
+	private action: PresetConflictAction = 'skip';
+
+	/**
+	 * Creates a new import modal.
+	 *
+	 * @param app - Obsidian App instance
+	 * @param incoming - Presets in the pack
+	 * @param existing - Saved presets
+	 * @param onImport - Receives the chosen presets and the conflict action
+	 */
+	constructor(
+		app: App,
+		incoming: Record<string, string>,
+		existing: Record<string, string>,
+		onImport: (presets: Record<string, string>, action: PresetConflictAction) => void
+	) {
+		super(app);
+		this.incoming = incoming;
+		this.existing = existing;
+		this.onImport = onImport;
+		this.selectedNames = new Set(Object.keys(incoming));
+	}
+
+	/**
+	 * Builds the list when opened.
+	 */
+	onOpen(): void {
+		const { contentEl } = this;
+		contentEl.addClass(CSS_CLASSES.presetTransferModal);
+
+		const names = Object.keys(this.incoming).sort();
+		const conflicts = names.filter(name => name in this.existing && this.existing[name] !== this.incoming[name]);
+
+		contentEl.createEl('h2', { text: 'Import presets' });
+		contentEl.createEl('p', {
+			text: `The pack has ${names.length === 1 ? '1 preset' : `${String(names.length)} presets`}. Choose the ones to import.`,
+		});
+
+		renderChoiceList(contentEl, names, this.selectedNames, (name) => {
+			if (conflicts.includes(name)) return 'A different preset has this name';
+			return name in this.existing ? 'Already saved' : undefined;
+		});
+
+		if (conflicts.length > 0) {
+			const conflictRow = contentEl.createEl('label');
+			conflictRow.createSpan({ text: 'When a name is taken: ' });
+			const select = conflictRow.createEl('select', { cls: 'dropdown' });
+			for (const [action, label] of Object.entries(CONFLICT_ACTION_LABELS)) {
+				select.createEl('option', { text: label, value: action });
+			}
+			select.value = this.action;
+			select.addEventListener('change', () => {
+				this.action = select.value as PresetConflictAction;
+			});
+		}
+
+		const buttonContainer = contentEl.createEl('div', { cls: CSS_CLASSES.modalButtons });
+		const cancelButton = buttonContainer.createEl('button', { text: 'Cancel' });
+		cancelButton.addEventListener('click', () => { this.close(); });
+
+		const importButton = buttonContainer.createEl('button', {
+			text: 'Import',
+			cls: 'mod-cta',
+		});
+		importButton.addEventListener('click', () => {
+			const chosen: Record<string, string> = {};
+			for (const name of names) {
+				if (this.selectedNames.has(name)) {
+					chosen[name] = this.incoming[name];
+				}
+			}
+			this.close();
+			if (Object.keys(chosen).length > 0) {
+				this.onImport(chosen, this.action);
+			}
+		});
+	}
+
+	/**
+	 * Cleans up when closed.
+	 */
+	onClose(): void {
+		this.contentEl.empty();
+	}
+}
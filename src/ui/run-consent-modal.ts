@@ -0,0 +1,71 @@
+/**
+ * Ultra Code Fence - Run Consent Modal
+ *
+ * Asks the user to agree, once per vault, before code blocks can be run
+ * from its notes, saying plainly what running a block does.
+ */
+
+import { App, Modal } from 'obsidian';
+import { CSS_CLASSES } from '../constants';
+
+// =============================================================================
+// Modal Implementation
+// =============================================================================
+
+/**
+ * Confirmation before running code is allowed in the vault.
+ *
+ * Closing the modal any way other than the allow button counts as no.
+ */
+export class RunConsentModal extends Modal {
+	private onAnswer: (allowed: boolean) => void;
+	private answered = { allowed: false };
+
+	/**
+	 * Creates a new consent modal.
+	 *
+	 * @param app - Obsidian App instance
+	 * @param onAnswer - Called once with the user's answer when the modal closes
+	 */
+	constructor(app: App, onAnswer: (allowed: boolean) => void) {
+		super(app);
+		this.onAnswer = onAnswer;
+	}
+
+	/**
+	 * Builds the explanation and buttons when opened.
+	 */
+	onOpen(): void {
+		const { contentEl } = this;
+		contentEl.addClass(CSS_CLASSES.runConsentModal);
+
+		contentEl.createEl('h2', { text: 'Allow running code in this vault?' });
+		contentEl.createEl('p', {
+			text: 'Shell, JavaScript and TypeScript blocks will get a run button. Running a shell block executes its code in your shell, with your permissions, from the vault folder. It can read, change or delete any of your files, just as if you typed it in a terminal. JavaScript and TypeScript run in a sandbox without access to the vault.',
+		});
+		contentEl.createEl('p', {
+			text: 'Only run blocks you have read and trust, especially in notes you didn\'t write. This applies to this vault only, and can be turned off again in the settings.',
+		});
+
+		const buttonContainer = contentEl.createEl('div', { cls: CSS_CLASSES.modalButtons });
+		const cancelButton = buttonContainer.createEl('button', { text: 'Cancel' });
+		cancelButton.addEventListener('click', () => { this.close(); });
+
+		const allowButton = buttonContainer.createEl('button', {
+			text: 'Allow running code',
+			cls: 'mod-warning',
+		});
+		allowButton.addEventListener('click', () => {
+			this.answered.allowed = true;
+			this.close();
+		});
+	}
+
+	/**
+	 * Cleans up and reports the answer when closed.
+	 */
+	onClose(): void {
+		this.contentEl.empty();
+		this.onAnswer(this.answered.allowed);
+	}
+}
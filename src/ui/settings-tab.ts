@@ -7,10 +7,13 @@
  */
 
 import { App, Platform, Plugin, PluginSettingTab, Setting } from 'obsidian';
-import type { PluginSettings, TitleBarStyle, FileIconStyle, DescriptionDisplayMode, ReleaseNotesData } from '../types';
-import { CSS_CLASSES } from '../constants';
+import type { DropdownComponent } from 'obsidian';
+import type { PluginSettings, TitleBarStyle, FileIconStyle, ConfigMode, DescriptionDisplayMode, ReleaseNotesData, CascadeLayer } from '../types';
+import { CALLOUT_TYPE_ICONS, CSS_CLASSES, DEFAULT_SETTINGS, PRESET_PREVIEW_DELAY_MS, SECRET_NAME_PATTERN } from '../constants';
 import { WhatsNewModal } from './whats-new-modal';
+import { RunConsentModal } from './run-consent-modal';
 import { createYamlEditor } from './yaml-editor';
+import { PRESET_SCHEMA } from './yaml-validator';
 
 // =============================================================================
 // Types
@@ -23,6 +26,13 @@ export interface SettingsPlugin {
 	settings: PluginSettings;
 	manifest: { version: string };
 	saveSettings(): Promise<void>;
+	renderPresetPreview?(containerElement: HTMLElement, presetYaml: string, presetName?: string): Promise<void>;
+	openPresetGallery?(): void;
+	pickVSCodeTheme?(onImported: () => void): void;
+	isRunningCodeAllowed?(): boolean;
+	setRunningCodeAllowed?(allowed: boolean): Promise<void>;
+	getSecretNames?(): string[];
+	setSecret?(name: string, value: string | undefined): void;
 }
 
 /**
@@ -33,6 +43,16 @@ interface SettingsTabDefinition {
 	tabLabel: string;
 }
 
+/** Names of the settings cascade layers, as shown in the settings tab. */
+const CASCADE_LAYER_LABELS: Record<CascadeLayer, string> = {
+	folder: 'Folder preset',
+	frontmatter: 'Note frontmatter',
+	page: 'Page settings block',
+	preset: 'Preset (named, or the language or tag default)',
+	block: 'Block settings',
+	shorthand: 'Fence line shorthand',
+};
+
 // =============================================================================
 // Settings Tab Implementation
 // =============================================================================
@@ -195,6 +215,57 @@ export class UltraCodeFenceSettingTab extends PluginSettingTab {
 					void this.plugin.saveSettings();
 				}));
 
+		new Setting(containerElement)
+			.setName('Grammar folder')
+			.setDesc('Folder of Prism (.json) or TextMate (.tmLanguage.json) grammars. Each file adds highlighting for the language it is named after.')
+			.addText(textInput => textInput
+				.setPlaceholder('Assets/grammars')
+				.setValue(this.plugin.settings.customGrammarFolder)
+				.onChange((value) => {
+					this.plugin.settings.customGrammarFolder = value;
+					void this.plugin.saveSettings();
+				}));
+
+		new Setting(containerElement)
+			.setName('Highlighter')
+			.setDesc('Engine that highlights code. Shiki uses VS Code\'s grammars for more accurate highlighting; languages it doesn\'t know stay on Prism.')
+			.addDropdown(dropdown => dropdown
+				.addOption('prism', 'Prism (built in)')
+				.addOption('shiki', 'Shiki')
+				.setValue(this.plugin.settings.highlighterEngine)
+				.onChange((value) => {
+					this.plugin.settings.highlighterEngine = value;
+					void this.plugin.saveSettings().then(() => { this.display(); }); // Refresh to show/hide related settings
+				}));
+
+		if (this.plugin.settings.highlighterEngine === 'shiki') {
+			new Setting(containerElement)
+				.setName('Shiki module')
+				.setDesc('Vault path of a browser build of Shiki (an ES module that bundles its languages).')
+				.addText(textInput => textInput
+					.setPlaceholder('Assets/shiki/shiki.mjs')
+					.setValue(this.plugin.settings.shikiModulePath)
+					.onChange((value) => {
+						this.plugin.settings.shikiModulePath = value;
+						void this.plugin.saveSettings();
+					}));
+
+			new Setting(containerElement)
+				.setName('Languages kept on Prism')
+				.setDesc('Comma-separated. These languages are highlighted by Prism even with Shiki chosen.')
+				.addText(textInput => textInput
+					.setPlaceholder('Helm, mydsl')
+					.setValue(this.plugin.settings.prismLanguages)
+					.onChange((value) => {
+						this.plugin.settings.prismLanguages = value;
+						void this.plugin.saveSettings();
+					}));
+		}
+
+		this.renderLanguageAliases(containerElement);
+		this.renderLanguageInjections(containerElement);
+		this.renderHighlightThemes(containerElement);
+
 		this.createSectionDivider(containerElement);
 
 		// Path section
@@ -211,6 +282,55 @@ export class UltraCodeFenceSettingTab extends PluginSettingTab {
 					void this.plugin.saveSettings();
 				}));
 
+		this.renderGitRepositories(containerElement);
+
+		new Setting(containerElement)
+			.setName('Remote file cache')
+			.setDesc('Minutes a file loaded from a URL is shown from its cached copy before being fetched again (0 to fetch on every render). The cached copy is also shown when offline.')
+			.addText(textInput => textInput
+				.setPlaceholder('60')
+				.setValue(String(this.plugin.settings.remoteCacheMinutes))
+				.onChange((value) => {
+					const parsedValue = parseInt(value, 10);
+					if (!isNaN(parsedValue) && parsedValue >= 0) {
+						this.plugin.settings.remoteCacheMinutes = parsedValue;
+						void this.plugin.saveSettings();
+					}
+				}));
+
+		new Setting(containerElement)
+			.setName('Long file lines')
+			.setDesc('Embedded files longer than this show only their first lines, with a button to load 500 more, so a huge log doesn\'t stall the note (0 to always show every line). Copy and download still take the whole file.')
+			.addText(textInput => textInput
+				.setPlaceholder('2000')
+				.setValue(String(this.plugin.settings.progressiveLoadLines))
+				.onChange((value) => {
+					const parsedValue = parseInt(value, 10);
+					if (!isNaN(parsedValue) && parsedValue >= 0) {
+						this.plugin.settings.progressiveLoadLines = parsedValue;
+						void this.plugin.saveSettings();
+					}
+				}));
+
+		this.createSectionDivider(containerElement);
+
+		// Block settings section
+		this.createSectionHeader(containerElement, 'Block settings');
+
+		new Setting(containerElement)
+			.setName('Configuration problems')
+			.setDesc('What happens when a block has unknown keys or mistyped values. Override per block with META.MODE.')
+			.addDropdown(dropdown => dropdown
+				.addOption('lenient', 'Render with defaults and flag them')
+				.addOption('strict', 'Refuse to render and list them')
+				.setValue(this.plugin.settings.configMode)
+				.onChange((value) => {
+					this.plugin.settings.configMode = value as ConfigMode;
+					void this.plugin.saveSettings();
+				}));
+
+		this.renderConfigCascade(containerElement);
+
 		this.createSectionDivider(containerElement);
 
 		// Help section
@@ -234,6 +354,49 @@ export class UltraCodeFenceSettingTab extends PluginSettingTab {
 		}
 	}
 
+	/**
+	 * Renders the settings cascade, lowest layer first, with buttons to
+	 * move each layer and toggles to switch layers off.
+	 */
+	private renderConfigCascade(containerElement: HTMLElement): void {
+		new Setting(containerElement)
+			.setName('Settings cascade')
+			.setDesc('The order in which settings layers are applied, lowest first; each layer overrides the ones above it. Built-in defaults and then these plugin settings always come first. By default the full chain is: built-in defaults → plugin settings → folder preset → note frontmatter → page settings block → preset → block settings → fence line shorthand.');
+
+		const cascade = this.plugin.settings.configCascade;
+		const swapLayers = (from: number, to: number): void => {
+			const reordered = [...cascade];
+			[reordered[from], reordered[to]] = [reordered[to], reordered[from]];
+			this.plugin.settings.configCascade = reordered;
+			void this.plugin.saveSettings().then(() => { this.display(); });
+		};
+
+		cascade.forEach((entry, index) => {
+			const row = new Setting(containerElement)
+				.setName(`${String(index + 1)}. ${CASCADE_LAYER_LABELS[entry.layer]}`)
+				.addButton(button => button
+					.setButtonText('Move up')
+					.setDisabled(index === 0)
+					.onClick(() => { swapLayers(index, index - 1); }))
+				.addButton(button => button
+					.setButtonText('Move down')
+					.setDisabled(index === cascade.length - 1)
+					.onClick(() => { swapLayers(index, index + 1); }));
+
+			if (entry.layer === 'block') {
+				row.setDesc('Always applied');
+				return;
+			}
+
+			row.addToggle(toggle => toggle
+				.setValue(entry.enabled)
+				.onChange((value) => {
+					entry.enabled = value;
+					void this.plugin.saveSettings();
+				}));
+		});
+	}
+
 	// ===========================================================================
 	// Title Tab
 	// ===========================================================================
@@ -354,6 +517,85 @@ export class UltraCodeFenceSettingTab extends PluginSettingTab {
 					void this.plugin.saveSettings();
 				}));
 
+		new Setting(containerElement)
+			.setName('Line hover highlight')
+			.setDesc('Highlight the line under the pointer, to follow wide rows across')
+			.addToggle(toggle => toggle
+				.setValue(this.plugin.settings.showLineHover)
+				.onChange((value) => {
+					this.plugin.settings.showLineHover = value;
+					void this.plugin.saveSettings();
+				}));
+
+		new Setting(containerElement)
+			.setName('Bracket pair colours')
+			.setDesc('Colour brackets by nesting depth and show a bracket\'s match on hover or tap. Override per block with RENDER.BRACKETS.')
+			.addToggle(toggle => toggle
+				.setValue(this.plugin.settings.bracketPairColours)
+				.onChange((value) => {
+					this.plugin.settings.bracketPairColours = value;
+					void this.plugin.saveSettings();
+				}));
+
+		new Setting(containerElement)
+			.setName('Semantic highlighting')
+			.setDesc('In Go, TypeScript, JavaScript and Python, mark function definitions, parameters and field reads the way an IDE does. Override per block with RENDER.SEMANTIC.')
+			.addToggle(toggle => toggle
+				.setValue(this.plugin.settings.semanticHighlighting)
+				.onChange((value) => {
+					this.plugin.settings.semanticHighlighting = value;
+					void this.plugin.saveSettings();
+				}));
+
+		new Setting(containerElement)
+			.setName('Mark changes since')
+			.setDesc('Mark the lines of embedded code that changed since a File recovery snapshot of the note: "last" for the latest snapshot, an age such as "1d" or "2w", or "off". Override per block with RENDER.CHANGED_SINCE.')
+			.addText(textInput => textInput
+				.setPlaceholder('off')
+				.setValue(this.plugin.settings.changedSince)
+				.onChange((value) => {
+					this.plugin.settings.changedSince = value.trim() || 'off';
+					void this.plugin.saveSettings();
+				}));
+
+		new Setting(containerElement)
+			.setName('Comment keywords')
+			.setDesc('Pick out TODO, FIXME and the other keywords below in comments. Override per block with RENDER.COMMENT_KEYWORDS.')
+			.addDropdown(dropdown => dropdown
+				.addOption('on', 'Coloured')
+				.addOption('icons', 'Coloured, with a gutter icon')
+				.addOption('off', 'Off')
+				.setValue(this.plugin.settings.commentKeywordMode)
+				.onChange((value) => {
+					this.plugin.settings.commentKeywordMode = value;
+					void this.plugin.saveSettings();
+				}));
+
+		new Setting(containerElement)
+			.setName('Tab width')
+			.setDesc('Columns per hard tab. Override per block with RENDER.TAB_SIZE.')
+			.addText(textInput => textInput
+				.setPlaceholder('4')
+				.setValue(String(this.plugin.settings.tabSize))
+				.onChange((value) => {
+					const parsedValue = parseInt(value, 10);
+					if (!isNaN(parsedValue) && parsedValue > 0) {
+						this.plugin.settings.tabSize = parsedValue;
+						void this.plugin.saveSettings();
+					}
+				}));
+
+		new Setting(containerElement)
+			.setName('Tab width by language')
+			.setDesc('Comma-separated language=width pairs, used before the tab width above.')
+			.addText(textInput => textInput
+				.setPlaceholder('go=4, makefile=8')
+				.setValue(this.plugin.settings.languageTabSizes)
+				.onChange((value) => {
+					this.plugin.settings.languageTabSizes = value;
+					void this.plugin.saveSettings();
+				}));
+
 		new Setting(containerElement)
 			.setName('Copy button')
 			.setDesc('Show a button to copy the code block content')
@@ -364,6 +606,87 @@ export class UltraCodeFenceSettingTab extends PluginSettingTab {
 					void this.plugin.saveSettings();
 				}));
 
+		new Setting(containerElement)
+			.setName('Per-line copy buttons')
+			.setDesc('Show a small copy button in the gutter of every line')
+			.addToggle(toggle => toggle
+				.setValue(this.plugin.settings.showLineCopyButtons)
+				.onChange((value) => {
+					this.plugin.settings.showLineCopyButtons = value;
+					void this.plugin.saveSettings();
+				}));
+
+		new Setting(containerElement)
+			.setName('Placeholder prompts')
+			.setDesc('Ask for placeholder values like {{hostname}} when copying')
+			.addToggle(toggle => toggle
+				.setValue(this.plugin.settings.copyPlaceholders)
+				.onChange((value) => {
+					this.plugin.settings.copyPlaceholders = value;
+					void this.plugin.saveSettings();
+				}));
+
+		new Setting(containerElement)
+			.setName('Fill in variables')
+			.setDesc('Show {{fm.field}} frontmatter fields, {{var.name}} vault variables, {{date}} and {{time}} filled in, so copies get the values too. Override per block with RENDER.VARIABLES, or use COPY.VARIABLES to fill them in on copy only.')
+			.addToggle(toggle => toggle
+				.setValue(this.plugin.settings.renderVariables)
+				.onChange((value) => {
+					this.plugin.settings.renderVariables = value;
+					void this.plugin.saveSettings();
+				}));
+
+		new Setting(containerElement)
+			.setName('Vault variables')
+			.setDesc('Values for {{var.name}} in code, as comma-separated name=value pairs')
+			.addText(textInput => textInput
+				.setPlaceholder('registry=ghcr.io/acme, team=platform')
+				.setValue(this.plugin.settings.codeVariables)
+				.onChange((value) => {
+					this.plugin.settings.codeVariables = value;
+					void this.plugin.saveSettings();
+				}));
+
+		new Setting(containerElement)
+			.setName('Clipboard history size')
+			.setDesc('Number of recent code block copies kept for the "copy again from clipboard history" command (0 to disable)')
+			.addText(textInput => textInput
+				.setPlaceholder('10')
+				.setValue(String(this.plugin.settings.clipboardHistorySize))
+				.onChange((value) => {
+					const parsedValue = parseInt(value, 10);
+					if (!isNaN(parsedValue) && parsedValue >= 0) {
+						this.plugin.settings.clipboardHistorySize = parsedValue;
+						void this.plugin.saveSettings();
+					}
+				}));
+
+		new Setting(containerElement)
+			.setName('Copy count badge')
+			.setDesc('Show how many times each block has been copied. Counts are stored in this vault only.')
+			.addToggle(toggle => toggle
+				.setValue(this.plugin.settings.showCopyCount)
+				.onChange((value) => {
+					this.plugin.settings.showCopyCount = value;
+					void this.plugin.saveSettings();
+				}))
+			.addButton(button => button
+				.setButtonText('Reset counts')
+				.onClick(() => {
+					this.plugin.settings.copyCounts = {};
+					void this.plugin.saveSettings();
+				}));
+
+		new Setting(containerElement)
+			.setName('Word wrap button')
+			.setDesc('Show a button that switches a block between scrolling and wrapping long lines. Each block remembers its choice in this vault.')
+			.addToggle(toggle => toggle
+				.setValue(this.plugin.settings.showWrapButton)
+				.onChange((value) => {
+					this.plugin.settings.showWrapButton = value;
+					void this.plugin.saveSettings();
+				}));
+
 		new Setting(containerElement)
 			.setName('Download button')
 			.setDesc('Show a button to save code block content to a file')
@@ -374,6 +697,115 @@ export class UltraCodeFenceSettingTab extends PluginSettingTab {
 					void this.plugin.saveSettings();
 				}));
 
+		new Setting(containerElement)
+			.setName('Edit button')
+			.setDesc('Show a button on blocks embedding a vault file that edits its code and, after you confirm, writes the changes back to the file')
+			.addToggle(toggle => toggle
+				.setValue(this.plugin.settings.showEditButton)
+				.onChange((value) => {
+					this.plugin.settings.showEditButton = value;
+					void this.plugin.saveSettings();
+				}));
+
+		new Setting(containerElement)
+			.setName('Download filename template')
+			.setDesc('Filename for downloads, using title variables like {basename} (empty to use the source filename or title)')
+			.addText(textInput => textInput
+				.setPlaceholder('{basename}.sh')
+				.setValue(this.plugin.settings.downloadFilenameTemplate)
+				.onChange((value) => {
+					this.plugin.settings.downloadFilenameTemplate = value;
+					void this.plugin.saveSettings();
+				}));
+
+		new Setting(containerElement)
+			.setName('Add shebang to downloads')
+			.setDesc('Start downloaded scripts with a shebang line for their language, e.g. #!/usr/bin/env bash')
+			.addToggle(toggle => toggle
+				.setValue(this.plugin.settings.downloadAddShebang)
+				.onChange((value) => {
+					this.plugin.settings.downloadAddShebang = value;
+					void this.plugin.saveSettings();
+				}));
+
+		new Setting(containerElement)
+			.setName('Make downloads executable')
+			.setDesc('Save downloads through a file dialog with the executable bit set (desktop only)')
+			.addToggle(toggle => toggle
+				.setValue(this.plugin.settings.downloadExecutable)
+				.onChange((value) => {
+					this.plugin.settings.downloadExecutable = value;
+					void this.plugin.saveSettings();
+				}));
+
+		this.renderRunConsent(containerElement);
+
+		new Setting(containerElement)
+			.setName('Run timeout')
+			.setDesc('Seconds a run may take before it is killed, along with any processes it started (0 = no limit). Override per block with RUN.TIMEOUT.')
+			.addText(textInput => textInput
+				.setPlaceholder('60')
+				.setValue(String(this.plugin.settings.runTimeoutSeconds))
+				.onChange((value) => {
+					const parsedValue = parseInt(value, 10);
+					if (!isNaN(parsedValue) && parsedValue >= 0) {
+						this.plugin.settings.runTimeoutSeconds = parsedValue;
+						void this.plugin.saveSettings();
+					}
+				}));
+
+		this.renderRunSecrets(containerElement);
+
+		this.createSectionDivider(containerElement);
+
+		// Copy feedback section
+		this.createSectionHeader(containerElement, 'Copy feedback');
+
+		new Setting(containerElement)
+			.setName('Checkmark')
+			.setDesc('Briefly swap the copy button icon for a checkmark after copying')
+			.addToggle(toggle => toggle
+				.setValue(this.plugin.settings.copyFeedbackCheckmark)
+				.onChange((value) => {
+					this.plugin.settings.copyFeedbackCheckmark = value;
+					void this.plugin.saveSettings();
+				}));
+
+		new Setting(containerElement)
+			.setName('Toast message')
+			.setDesc('Notification shown after copying (empty for none)')
+			.addText(textInput => textInput
+				.setPlaceholder('Copied to clipboard')
+				.setValue(this.plugin.settings.copyFeedbackToast)
+				.onChange((value) => {
+					this.plugin.settings.copyFeedbackToast = value;
+					void this.plugin.saveSettings();
+				}));
+
+		new Setting(containerElement)
+			.setName('Status bar message')
+			.setDesc('Also confirm copies in the status bar (desktop only)')
+			.addToggle(toggle => toggle
+				.setValue(this.plugin.settings.copyFeedbackStatusBar)
+				.onChange((value) => {
+					this.plugin.settings.copyFeedbackStatusBar = value;
+					void this.plugin.saveSettings();
+				}));
+
+		new Setting(containerElement)
+			.setName('Feedback duration')
+			.setDesc('How long copy confirmations stay visible, in milliseconds')
+			.addText(textInput => textInput
+				.setPlaceholder('2000')
+				.setValue(String(this.plugin.settings.copyFeedbackDuration))
+				.onChange((value) => {
+					const parsedValue = parseInt(value, 10);
+					if (!isNaN(parsedValue) && parsedValue > 0) {
+						this.plugin.settings.copyFeedbackDuration = parsedValue;
+						void this.plugin.saveSettings();
+					}
+				}));
+
 		this.createSectionDivider(containerElement);
 
 		// Folding section
@@ -407,6 +839,25 @@ export class UltraCodeFenceSettingTab extends PluginSettingTab {
 					}
 				}));
 
+		new Setting(containerElement)
+			.setName('Minimap lines')
+			.setDesc('Show a minimap beside code blocks longer than this many lines (0 to disable)')
+			.addText(textInput => textInput
+				.setPlaceholder('0')
+				.setValue(String(this.plugin.settings.minimapLines))
+				.onChange((value) => {
+					const parsedValue = parseInt(value, 10);
+					if (!isNaN(parsedValue) && parsedValue >= 0) {
+						this.plugin.settings.minimapLines = parsedValue;
+						void this.plugin.saveSettings();
+					}
+				}));
+
+		this.createSectionDivider(containerElement);
+
+		// Print section
+		this.createSectionHeader(containerElement, 'Print', 'How code blocks print and export to PDF; the PRINT section overrides these per block.');
+
 		new Setting(containerElement)
 			.setName('Print behaviour')
 			.setDesc('How folded or scrolled code blocks behave when printing')
@@ -419,6 +870,42 @@ export class UltraCodeFenceSettingTab extends PluginSettingTab {
 					void this.plugin.saveSettings();
 				}));
 
+		new Setting(containerElement)
+			.setName('Print colours')
+			.setDesc('Colours of code blocks when printing or exporting to PDF')
+			.addDropdown(dropdown => dropdown
+				.addOption('light', 'Light (whatever the theme)')
+				.addOption('asis', 'As displayed')
+				.setValue(this.plugin.settings.printTheme)
+				.onChange((value) => {
+					this.plugin.settings.printTheme = value;
+					void this.plugin.saveSettings();
+				}));
+
+		new Setting(containerElement)
+			.setName('Print line numbers')
+			.setDesc('Print line numbers even in blocks that hide them on screen')
+			.addToggle(toggle => toggle
+				.setValue(this.plugin.settings.printLineNumbers)
+				.onChange((value) => {
+					this.plugin.settings.printLineNumbers = value;
+					void this.plugin.saveSettings();
+				}));
+
+		new Setting(containerElement)
+			.setName('Keep blocks on one page')
+			.setDesc('Avoid page breaks inside code blocks when printing, unless a block is longer than a page')
+			.addToggle(toggle => toggle
+				.setValue(this.plugin.settings.printAvoidBreaks)
+				.onChange((value) => {
+					this.plugin.settings.printAvoidBreaks = value;
+					void this.plugin.saveSettings();
+				}));
+
+		this.createSectionDivider(containerElement);
+
+		this.renderCommentKeywords(containerElement);
+
 		this.createSectionDivider(containerElement);
 
 		// Copy join section
@@ -432,6 +919,160 @@ export class UltraCodeFenceSettingTab extends PluginSettingTab {
 		this.renderCopyJoinTable(containerElement, altModLabel);
 	}
 
+	// ===========================================================================
+	// Run Consent
+	// ===========================================================================
+
+	/**
+	 * Renders the toggle that allows running code blocks, asking for the
+	 * user's consent before turning it on. The answer is kept on this
+	 * device, for this vault only.
+	 *
+	 * @param containerElement - Container element
+	 */
+	private renderRunConsent(containerElement: HTMLElement): void {
+		const isRunningCodeAllowed = this.plugin.isRunningCodeAllowed?.bind(this.plugin);
+		const setRunningCodeAllowed = this.plugin.setRunningCodeAllowed?.bind(this.plugin);
+		if (!isRunningCodeAllowed || !setRunningCodeAllowed) return;
+
+		new Setting(containerElement)
+			.setName('Run code blocks')
+			.setDesc('Show a run button that runs a block\'s code, with the output under the block: bash, sh, zsh, fish and PowerShell blocks in your shell (desktop only), JavaScript and TypeScript in a sandbox. You are asked to confirm first; it applies to this vault on this device only')
+			.addToggle(toggle => toggle
+				.setValue(isRunningCodeAllowed())
+				.onChange((value) => {
+					if (!value) {
+						void setRunningCodeAllowed(false);
+						return;
+					}
+
+					new RunConsentModal(this.app, (allowed) => {
+						if (allowed) {
+							void setRunningCodeAllowed(true);
+						} else {
+							toggle.setValue(false);
+						}
+					}).open();
+				}));
+	}
+
+	// ===========================================================================
+	// Comment Keywords
+	// ===========================================================================
+
+	/**
+	 * Renders the comment keyword rules, each with its icon type, colour
+	 * and a remove button, and a row for adding another.
+	 */
+	private renderCommentKeywords(containerElement: HTMLElement): void {
+		this.createSectionHeader(
+			containerElement,
+			'Comment keywords',
+			'Keywords picked out in comments, matched as written. Each takes the colour and icon of a callout type unless given its own colour.'
+		);
+
+		const calloutTypes = Object.keys(CALLOUT_TYPE_ICONS);
+		const addTypeOptions = (dropdown: DropdownComponent): DropdownComponent => {
+			for (const type of calloutTypes) dropdown.addOption(type, type.charAt(0).toUpperCase() + type.slice(1));
+			return dropdown;
+		};
+
+		this.plugin.settings.commentKeywords.forEach((rule, index) => {
+			new Setting(containerElement)
+				.setName(rule.keyword)
+				.addDropdown(dropdown => addTypeOptions(dropdown)
+					.setValue(rule.type)
+					.onChange((value) => {
+						rule.type = value;
+						void this.plugin.saveSettings();
+					}))
+				.addText(text => text
+					.setPlaceholder('Colour (optional)')
+					.setValue(rule.colour)
+					.onChange((value) => {
+						rule.colour = value.trim();
+						void this.plugin.saveSettings();
+					}))
+				.addButton(button => button
+					.setButtonText('Remove')
+					.onClick(() => {
+						this.plugin.settings.commentKeywords = this.plugin.settings.commentKeywords.filter((_, i) => i !== index);
+						void this.plugin.saveSettings().then(() => { this.display(); });
+					}));
+		});
+
+		const newRule = { keyword: '', type: 'note', colour: '' };
+
+		new Setting(containerElement)
+			.addText(text => text
+				.setPlaceholder('Keyword, e.g. REVIEW')
+				.onChange((value) => { newRule.keyword = value.trim(); }))
+			.addDropdown(dropdown => addTypeOptions(dropdown)
+				.setValue(newRule.type)
+				.onChange((value) => { newRule.type = value; }))
+			.addButton(button => button
+				.setButtonText('Add keyword')
+				.onClick(() => {
+					if (!/^\w+$/.test(newRule.keyword)) return;
+					this.plugin.settings.commentKeywords = [...this.plugin.settings.commentKeywords, { ...newRule }];
+					void this.plugin.saveSettings().then(() => { this.display(); });
+				}));
+	}
+
+	// ===========================================================================
+	// Run Secrets
+	// ===========================================================================
+
+	/**
+	 * Renders the vault's secrets for RUN.ENV, by name only, each with a
+	 * remove button, and a row for adding one. Values are typed into a
+	 * password field and never shown again.
+	 *
+	 * @param containerElement - Container element
+	 */
+	private renderRunSecrets(containerElement: HTMLElement): void {
+		const getSecretNames = this.plugin.getSecretNames?.bind(this.plugin);
+		const setSecret = this.plugin.setSecret?.bind(this.plugin);
+		if (!getSecretNames || !setSecret) return;
+
+		this.createSectionHeader(
+			containerElement,
+			'Secrets',
+			'Values for {{secret.name}} in RUN.ENV. They are kept on this device for this vault only, outside the vault folder and the plugin settings, so they are never synced or committed.'
+		);
+
+		for (const name of getSecretNames()) {
+			new Setting(containerElement)
+				.setName(name)
+				.addButton(button => button
+					.setButtonText('Remove')
+					.onClick(() => {
+						setSecret(name, undefined);
+						this.display();
+					}));
+		}
+
+		const newSecret = { name: '', value: '' };
+
+		new Setting(containerElement)
+			.addText(text => text
+				.setPlaceholder('Name, e.g. prod-token')
+				.onChange((value) => { newSecret.name = value.trim(); }))
+			.addText(text => {
+				text.inputEl.type = 'password';
+				text
+					.setPlaceholder('Value')
+					.onChange((value) => { newSecret.value = value; });
+			})
+			.addButton(button => button
+				.setButtonText('Add secret')
+				.onClick(() => {
+					if (!SECRET_NAME_PATTERN.test(newSecret.name) || !newSecret.value) return;
+					setSecret(newSecret.name, newSecret.value);
+					this.display();
+				}));
+	}
+
 	// ===========================================================================
 	// Copy Join Table
 	// ===========================================================================
@@ -590,9 +1231,9 @@ export class UltraCodeFenceSettingTab extends PluginSettingTab {
 
 		new Setting(containerElement)
 			.setName('Default language')
-			.setDesc('Fallback language for ufence-code blocks without a language override')
+			.setDesc('Fallback language for ufence-code blocks without a language override; auto guesses it from the code')
 			.addText(textInput => textInput
-				.setPlaceholder('Text')
+				.setPlaceholder('Auto')
 				.setValue(this.plugin.settings.defaultLanguage)
 				.onChange((value) => {
 					this.plugin.settings.defaultLanguage = value || 'text';
@@ -780,6 +1421,16 @@ export class UltraCodeFenceSettingTab extends PluginSettingTab {
 			cls: CSS_CLASSES.tabIntro,
 		});
 
+		const openGallery = this.plugin.openPresetGallery?.bind(this.plugin);
+		if (openGallery) {
+			new Setting(containerElement)
+				.setName('Preset gallery')
+				.setDesc('Preview ready-made presets and install them with a click.')
+				.addButton(button => button
+					.setButtonText('Browse gallery')
+					.onClick(() => { openGallery(); }));
+		}
+
 		this.createSectionDivider(containerElement);
 
 		// Existing presets
@@ -796,6 +1447,16 @@ export class UltraCodeFenceSettingTab extends PluginSettingTab {
 			this.createSectionDivider(containerElement);
 		}
 
+		// Default presets per folder
+		this.renderFolderPresets(containerElement, names);
+
+		this.createSectionDivider(containerElement);
+
+		// Default presets per language
+		this.renderLanguagePresets(containerElement, names);
+
+		this.createSectionDivider(containerElement);
+
 		// Add new preset
 		this.createSectionHeader(containerElement, 'Add new preset');
 
@@ -811,11 +1472,17 @@ export class UltraCodeFenceSettingTab extends PluginSettingTab {
 
 		// YAML editor with syntax highlighting + validation
 		const yamlContainer = containerElement.createEl('div');
+		const previewNewPreset = this.createPresetPreview(containerElement);
 		createYamlEditor(yamlContainer, {
 			initialValue: '',
 			placeholder: 'RENDER:\n  LINES: true\n  ZEBRA: true\n  FOLD: 20',
-			onChange: (value) => { newYaml = value; },
+			onChange: (value) => {
+				newYaml = value;
+				previewNewPreset(value);
+			},
+			schema: PRESET_SCHEMA,
 		});
+		previewNewPreset('');
 
 		new Setting(containerElement)
 			.addButton(button => button
@@ -834,6 +1501,347 @@ export class UltraCodeFenceSettingTab extends PluginSettingTab {
 				}));
 	}
 
+	/**
+	 * Adds a live preview of a preset: a sample code block rendered with
+	 * the preset's settings, redrawn shortly after each edit.
+	 *
+	 * @param containerElement - Element to add the preview to
+	 * @param presetName - The preset's saved name (none for a new preset)
+	 * @returns Function that shows the preview for the given YAML
+	 */
+	private createPresetPreview(containerElement: HTMLElement, presetName?: string): (presetYaml: string) => void {
+		const renderPreview = this.plugin.renderPresetPreview?.bind(this.plugin);
+		if (!renderPreview) return () => { /* preview needs the plugin */ };
+
+		const previewElement = containerElement.createEl('div', { cls: 'ucf-preset-preview' });
+		let timer: number | undefined;
+
+		return (presetYaml: string) => {
+			window.clearTimeout(timer);
+			timer = window.setTimeout(() => {
+				void renderPreview(previewElement, presetYaml, presetName);
+			}, PRESET_PREVIEW_DELAY_MS);
+		};
+	}
+
+	/**
+	 * Renders the git checkouts, one row each, with an add button.
+	 */
+	private renderGitRepositories(containerElement: HTMLElement): void {
+		new Setting(containerElement)
+			.setName('Git repositories')
+			.setDesc('Local checkouts that META.SRC can read from at a tag or commit (desktop only). For example, tools:bin/install.sh@v1.4.2 reads bin/install.sh from the checkout named tools at v1.4.2.')
+			.addButton(button => button
+				.setButtonText('Add repository')
+				.onClick(() => {
+					this.plugin.settings.gitRepositories = [...this.plugin.settings.gitRepositories, { name: '', path: '' }];
+					void this.plugin.saveSettings().then(() => { this.display(); });
+				}));
+
+		const repositories = this.plugin.settings.gitRepositories;
+
+		repositories.forEach((repository, index) => {
+			new Setting(containerElement)
+				.addText(text => text
+					.setPlaceholder('tools')
+					.setValue(repository.name)
+					.onChange((value) => {
+						repository.name = value.trim();
+						void this.plugin.saveSettings();
+					}))
+				.addText(text => text
+					.setPlaceholder('/Users/me/src/tools')
+					.setValue(repository.path)
+					.onChange((value) => {
+						repository.path = value.trim();
+						void this.plugin.saveSettings();
+					}))
+				.addButton(button => button
+					.setButtonText('Remove')
+					.onClick(() => {
+						this.plugin.settings.gitRepositories = repositories.filter((_repository, i) => i !== index);
+						void this.plugin.saveSettings().then(() => { this.display(); });
+					}));
+		});
+	}
+
+	/**
+	 * Renders the folder → preset rules, one row each, with an add button.
+	 */
+	private renderFolderPresets(containerElement: HTMLElement, presetNames: string[]): void {
+		this.createSectionHeader(
+			containerElement,
+			'Folder presets',
+			'Give every note under a folder a default preset. Folders can use * (within a folder name) and ** (across folders). The first matching folder wins. The folder preset is the lowest layer of the settings cascade, so the note's settings, any other preset and the block are applied over it.'
+		);
+
+		const rules = this.plugin.settings.folderPresets;
+
+		rules.forEach((rule, index) => {
+			new Setting(containerElement)
+				.addText(text => text
+					.setPlaceholder('Work/Runbooks')
+					.setValue(rule.folder)
+					.onChange((value) => {
+						rule.folder = value.trim();
+						void this.plugin.saveSettings();
+					}))
+				.addDropdown(dropdown => {
+					dropdown.addOption('', 'Choose a preset');
+					// Keep a rule's preset visible even after the preset is deleted
+					for (const name of rule.preset && !presetNames.includes(rule.preset) ? [...presetNames, rule.preset] : presetNames) {
+						dropdown.addOption(name, name);
+					}
+					dropdown
+						.setValue(rule.preset)
+						.onChange((value) => {
+							rule.preset = value;
+							void this.plugin.saveSettings();
+						});
+				})
+				.addButton(button => button
+					.setButtonText('Remove')
+					.onClick(() => {
+						this.plugin.settings.folderPresets = rules.filter((_rule, i) => i !== index);
+						void this.plugin.saveSettings().then(() => { this.display(); });
+					}));
+		});
+
+		new Setting(containerElement)
+			.addButton(button => button
+				.setButtonText('Add folder')
+				.onClick(() => {
+					this.plugin.settings.folderPresets = [...rules, { folder: '', preset: '' }];
+					void this.plugin.saveSettings().then(() => { this.display(); });
+				}));
+	}
+
+	/**
+	 * Renders the language → preset assignments, one row each, with a row
+	 * for adding a language.
+	 */
+	private renderLanguagePresets(containerElement: HTMLElement, presetNames: string[]): void {
+		this.createSectionHeader(
+			containerElement,
+			'Language presets',
+			'Give every block of a language a default preset, e.g. ufence-sql blocks. A language preset is used before a tag preset and is applied over any folder preset; a preset named in the note or block still takes precedence.'
+		);
+
+		const assigned = this.plugin.settings.languagePresets;
+
+		for (const language of Object.keys(assigned).sort()) {
+			const preset = assigned[language];
+			if (preset === undefined) continue;
+
+			new Setting(containerElement)
+				.setName(language)
+				.addDropdown(dropdown => {
+					// Keep the preset visible even after it is deleted
+					for (const name of presetNames.includes(preset) ? presetNames : [...presetNames, preset]) {
+						dropdown.addOption(name, name);
+					}
+					dropdown
+						.setValue(preset)
+						.onChange((value) => {
+							this.plugin.settings.languagePresets[language] = value;
+							void this.plugin.saveSettings();
+						});
+				})
+				.addButton(button => button
+					.setButtonText('Remove')
+					.onClick(() => {
+						this.plugin.settings.languagePresets = Object.fromEntries(
+							Object.entries(this.plugin.settings.languagePresets).filter(([k]) => k !== language),
+						);
+						void this.plugin.saveSettings().then(() => { this.display(); });
+					}));
+		}
+
+		if (presetNames.length === 0) return;
+
+		let newLanguage = '';
+		let newPreset = presetNames[0];
+
+		new Setting(containerElement)
+			.addText(text => text
+				.setPlaceholder('Language, e.g. sql')
+				.onChange((value) => { newLanguage = value.trim().toLowerCase(); }))
+			.addDropdown(dropdown => {
+				for (const name of presetNames) {
+					dropdown.addOption(name, name);
+				}
+				dropdown
+					.setValue(newPreset)
+					.onChange((value) => { newPreset = value; });
+			})
+			.addButton(button => button
+				.setButtonText('Add language')
+				.onClick(() => {
+					if (!newLanguage) return;
+					this.plugin.settings.languagePresets[newLanguage] = newPreset;
+					void this.plugin.saveSettings().then(() => { this.display(); });
+				}));
+	}
+
+	/**
+	 * Renders the language alias → language mappings, one row each, with a
+	 * row for adding an alias.
+	 */
+	private renderLanguageAliases(containerElement: HTMLElement): void {
+		this.createSectionHeader(
+			containerElement,
+			'Language aliases',
+			'Highlight fences of one language with another language\'s grammar, e.g. zsh as bash or jsonc as json, without editing the notes.'
+		);
+
+		const aliases = this.plugin.settings.languageAliases;
+
+		for (const alias of Object.keys(aliases).sort()) {
+			const target = aliases[alias];
+			if (target === undefined) continue;
+
+			new Setting(containerElement)
+				.setName(alias)
+				.addText(text => text
+					.setPlaceholder('Language')
+					.setValue(target)
+					.onChange((value) => {
+						this.plugin.settings.languageAliases[alias] = value.trim().toLowerCase();
+						void this.plugin.saveSettings();
+					}))
+				.addButton(button => button
+					.setButtonText('Remove')
+					.onClick(() => {
+						this.plugin.settings.languageAliases = Object.fromEntries(
+							Object.entries(this.plugin.settings.languageAliases).filter(([k]) => k !== alias),
+						);
+						void this.plugin.saveSettings().then(() => { this.display(); });
+					}));
+		}
+
+		let newAlias = '';
+		let newTarget = '';
+
+		new Setting(containerElement)
+			.addText(text => text
+				.setPlaceholder('Alias, e.g. zsh')
+				.onChange((value) => { newAlias = value.trim().toLowerCase(); }))
+			.addText(text => text
+				.setPlaceholder('Language, e.g. bash')
+				.onChange((value) => { newTarget = value.trim().toLowerCase(); }))
+			.addButton(button => button
+				.setButtonText('Add alias')
+				.onClick(() => {
+					if (!newAlias || !newTarget || newAlias === newTarget) return;
+					this.plugin.settings.languageAliases[newAlias] = newTarget;
+					void this.plugin.saveSettings().then(() => { this.display(); });
+				}));
+	}
+
+	/**
+	 * Renders the embedded language rules, each with a remove button,
+	 * and a row for adding another.
+	 */
+	private renderLanguageInjections(containerElement: HTMLElement): void {
+		this.createSectionHeader(
+			containerElement,
+			'Embedded languages',
+			'Highlight code inside another language\'s tokens, e.g. SQL inside Go strings. Rules apply to the listed host languages; the first rule that matches a token wins.'
+		);
+
+		this.plugin.settings.languageInjections.forEach((rule, index) => {
+			new Setting(containerElement)
+				.setName(rule.hosts)
+				.setDesc(`${rule.token} → ${rule.language}${rule.match ? ` when it matches ${rule.match}` : ''}`)
+				.addButton(button => button
+					.setButtonText('Remove')
+					.onClick(() => {
+						this.plugin.settings.languageInjections = this.plugin.settings.languageInjections.filter((_, i) => i !== index);
+						void this.plugin.saveSettings().then(() => { this.display(); });
+					}));
+		});
+
+		const newRule = { hosts: '', token: 'string', language: '', match: '' };
+
+		new Setting(containerElement)
+			.addText(text => text
+				.setPlaceholder('Hosts, e.g. go, python')
+				.onChange((value) => { newRule.hosts = value.trim().toLowerCase(); }))
+			.addText(text => text
+				.setPlaceholder('Token')
+				.setValue(newRule.token)
+				.onChange((value) => { newRule.token = value.trim().toLowerCase(); }))
+			.addText(text => text
+				.setPlaceholder('Language, e.g. sql')
+				.onChange((value) => { newRule.language = value.trim().toLowerCase(); }))
+			.addText(text => text
+				.setPlaceholder('Match regex (optional)')
+				.onChange((value) => { newRule.match = value.trim(); }))
+			.addButton(button => button
+				.setButtonText('Add rule')
+				.onClick(() => {
+					if (!newRule.hosts || !newRule.token || !newRule.language) return;
+					this.plugin.settings.languageInjections = [...this.plugin.settings.languageInjections, { ...newRule }];
+					void this.plugin.saveSettings().then(() => { this.display(); });
+				}));
+
+		new Setting(containerElement)
+			.setDesc('Put back the rules the plugin ships with, keeping your own.')
+			.addButton(button => button
+				.setButtonText('Restore default rules')
+				.onClick(() => {
+					const rules = this.plugin.settings.languageInjections;
+					const missing = DEFAULT_SETTINGS.languageInjections.filter(rule =>
+						!rules.some(existing => JSON.stringify(existing) === JSON.stringify(rule)));
+					this.plugin.settings.languageInjections = [...missing.map(rule => ({ ...rule })), ...rules];
+					void this.plugin.saveSettings().then(() => { this.display(); });
+				}));
+	}
+
+	/**
+	 * Renders the imported highlight themes, each with a remove button,
+	 * and a button to import another from a VS Code theme file.
+	 */
+	private renderHighlightThemes(containerElement: HTMLElement): void {
+		this.createSectionHeader(
+			containerElement,
+			'Highlight themes',
+			'Colour themes imported from VS Code. Pick one for a block or preset with RENDER.THEME and its name.'
+		);
+
+		const themes = this.plugin.settings.highlightThemes;
+
+		for (const key of Object.keys(themes).sort()) {
+			const theme = themes[key];
+			if (theme === undefined) continue;
+
+			const tokenCount = Object.keys(theme.tokens).length;
+			new Setting(containerElement)
+				.setName(theme.name)
+				.setDesc(`RENDER.THEME: ${key} (${tokenCount === 1 ? '1 token style' : `${String(tokenCount)} token styles`})`)
+				.addButton(button => button
+					.setButtonText('Remove')
+					.onClick(() => {
+						this.plugin.settings.highlightThemes = Object.fromEntries(
+							Object.entries(this.plugin.settings.highlightThemes).filter(([k]) => k !== key),
+						);
+						void this.plugin.saveSettings().then(() => { this.display(); });
+					}));
+		}
+
+		const pickVSCodeTheme = this.plugin.pickVSCodeTheme?.bind(this.plugin);
+		if (pickVSCodeTheme) {
+			new Setting(containerElement)
+				.setName('Import a VS Code theme')
+				.setDesc('The theme\'s JSON file, found in the extension\'s themes folder.')
+				.addButton(button => button
+					.setButtonText('Import')
+					.onClick(() => {
+						pickVSCodeTheme(() => { this.display(); });
+					}));
+		}
+	}
+
 	/**
 	 * Renders a single preset entry with an editable textarea and delete button.
 	 */
@@ -845,10 +1853,13 @@ export class UltraCodeFenceSettingTab extends PluginSettingTab {
 
 		// Editable YAML editor with syntax highlighting + validation
 		const editorContainer = wrapper.createEl('div', { cls: 'ucf-preset-editor' });
+		const previewPreset = this.createPresetPreview(wrapper, name);
 		const editor = createYamlEditor(editorContainer, {
 			initialValue: yamlContent,
-			onChange: () => { /* live update — save on button click */ },
+			onChange: (value) => { previewPreset(value); },  // save on button click
+			schema: PRESET_SCHEMA,
 		});
+		previewPreset(yamlContent);
 
 		// Button row
 		const buttonRow = wrapper.createEl('div', { cls: 'ucf-preset-buttons' });
@@ -0,0 +1,145 @@
+/**
+ * Ultra Code Fence - Snippet Library View
+ *
+ * A sidebar view listing every titled ufence block in the vault, with a
+ * search box. Each snippet can be opened where it is, or put into the
+ * note being edited as a copy or as a block showing it by ID.
+ */
+
+import { ItemView } from 'obsidian';
+import type { WorkspaceLeaf } from 'obsidian';
+import type { Snippet } from '../services';
+import { matchSnippet } from '../services';
+import { CSS_CLASSES, SNIPPET_LIBRARY_VIEW_TYPE } from '../constants';
+import { formatSnippetDetails } from './snippet-modal';
+
+/** What the view needs from the plugin. */
+export interface SnippetLibraryHost {
+	/** Lists every snippet in the vault */
+	loadSnippets: () => Promise<Snippet[]>;
+
+	/** Opens the note a snippet is in, at the snippet */
+	openSnippet: (snippet: Snippet) => void;
+
+	/** Puts a snippet into the note being edited, as a copy or by reference */
+	insertSnippet: (snippet: Snippet, asReference: boolean) => void;
+}
+
+// =============================================================================
+// View Implementation
+// =============================================================================
+
+/**
+ * Searchable list of the vault's snippets.
+ */
+export class SnippetLibraryView extends ItemView {
+	private host: SnippetLibraryHost;
+	private snippets: Snippet[] = [];
+	private query = '';
+	private listElement: HTMLElement | null = null;
+
+	/**
+	 * Creates the view.
+	 *
+	 * @param leaf - Workspace leaf it is shown in
+	 * @param host - Loads, opens and inserts snippets
+	 */
+	constructor(leaf: WorkspaceLeaf, host: SnippetLibraryHost) {
+		super(leaf);
+		this.host = host;
+	}
+
+	getViewType(): string {
+		return SNIPPET_LIBRARY_VIEW_TYPE;
+	}
+
+	getDisplayText(): string {
+		return 'Snippet library';
+	}
+
+	getIcon(): string {
+		return 'library';
+	}
+
+	/**
+	 * Builds the search box and loads the list when opened.
+	 */
+	async onOpen(): Promise<void> {
+		const { contentEl } = this;
+		contentEl.empty();
+		contentEl.addClass(CSS_CLASSES.snippetLibrary);
+
+		const searchInput = contentEl.createEl('input', {
+			cls: CSS_CLASSES.snippetSearch,
+			attr: { type: 'search', placeholder: 'Search snippets' },
+		});
+		searchInput.value = this.query;
+		searchInput.addEventListener('input', () => {
+			this.query = searchInput.value;
+			this.renderList();
+		});
+
+		this.listElement = contentEl.createEl('div', { cls: CSS_CLASSES.snippetList });
+		await this.refresh();
+	}
+
+	/**
+	 * Reloads the snippets, keeping the search.
+	 */
+	async refresh(): Promise<void> {
+		this.snippets = await this.host.loadSnippets();
+		this.renderList();
+	}
+
+	/**
+	 * Shows the snippets matching the search.
+	 */
+	private renderList(): void {
+		const listElement = this.listElement;
+		if (!listElement) return;
+		listElement.empty();
+
+		const matches = this.snippets.filter(snippet => matchSnippet(snippet, this.query));
+		if (matches.length === 0) {
+			listElement.createEl('p', {
+				cls: CSS_CLASSES.snippetEmpty,
+				text: this.snippets.length === 0
+					? 'No snippets yet. Give a ufence block a META.TITLE to add it.'
+					: 'No snippets match.',
+			});
+			return;
+		}
+
+		for (const snippet of matches) {
+			const itemElement = listElement.createEl('div', { cls: CSS_CLASSES.snippetItem });
+
+			const titleElement = itemElement.createEl('a', { cls: CSS_CLASSES.snippetTitle, text: snippet.title, href: '#' });
+			titleElement.addEventListener('click', (event) => {
+				event.preventDefault();
+				this.host.openSnippet(snippet);
+			});
+			itemElement.createEl('small', { cls: CSS_CLASSES.snippetDetails, text: formatSnippetDetails(snippet) });
+
+			const actionsElement = itemElement.createEl('div', { cls: CSS_CLASSES.snippetActions });
+			const copyButton = actionsElement.createEl('button', { text: 'Insert copy' });
+			copyButton.addEventListener('click', () => { this.host.insertSnippet(snippet, false); });
+
+			const referenceButton = actionsElement.createEl('button', { text: 'Insert reference' });
+			if (snippet.id) {
+				referenceButton.addEventListener('click', () => { this.host.insertSnippet(snippet, true); });
+			} else {
+				referenceButton.disabled = true;
+				referenceButton.title = 'Give the block an ID to show it by reference';
+			}
+		}
+	}
+
+	/**
+	 * Cleans up when closed.
+	 */
+	onClose(): Promise<void> {
+		this.listElement = null;
+		this.contentEl.empty();
+		return Promise.resolve();
+	}
+}
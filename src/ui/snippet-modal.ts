@@ -0,0 +1,74 @@
+/**
+ * Ultra Code Fence - Snippet Modal
+ *
+ * Lists the snippets in the vault in a searchable picker, to put one into
+ * the current note.
+ */
+
+import { App, SuggestModal } from 'obsidian';
+import type { Snippet } from '../services';
+import { matchSnippet } from '../services';
+import { CSS_CLASSES } from '../constants';
+
+// =============================================================================
+// Modal Implementation
+// =============================================================================
+
+/**
+ * Picker over the snippet library.
+ *
+ * Typing filters snippets by title, language, note path or code.
+ */
+export class SnippetModal extends SuggestModal<Snippet> {
+	private snippets: Snippet[];
+	private onChoose: (snippet: Snippet) => void;
+
+	/**
+	 * Creates a new snippet modal.
+	 *
+	 * @param app - Obsidian App instance
+	 * @param snippets - Snippets to offer
+	 * @param placeholder - Search box text, saying what choosing does
+	 * @param onChoose - Called with the chosen snippet
+	 */
+	constructor(app: App, snippets: Snippet[], placeholder: string, onChoose: (snippet: Snippet) => void) {
+		super(app);
+		this.snippets = snippets;
+		this.onChoose = onChoose;
+		this.setPlaceholder(placeholder);
+	}
+
+	/**
+	 * Filters snippets by the search words.
+	 */
+	getSuggestions(query: string): Snippet[] {
+		return this.snippets.filter(snippet => matchSnippet(snippet, query));
+	}
+
+	/**
+	 * Shows the title, with the language and note below.
+	 */
+	renderSuggestion(snippet: Snippet, element: HTMLElement): void {
+		element.addClass(CSS_CLASSES.snippetItem);
+		element.createEl('div', { cls: CSS_CLASSES.snippetTitle, text: snippet.title });
+		element.createEl('small', { cls: CSS_CLASSES.snippetDetails, text: formatSnippetDetails(snippet) });
+	}
+
+	/**
+	 * Hands the chosen snippet to the callback.
+	 */
+	onChooseSuggestion(snippet: Snippet): void {
+		this.onChoose(snippet);
+	}
+}
+
+/**
+ * Describes where a snippet is, e.g. "bash · Ops/Runbook.md › Bootstrap".
+ *
+ * @param snippet - The snippet
+ * @returns Language, note and heading
+ */
+export function formatSnippetDetails(snippet: Snippet): string {
+	const location = snippet.heading ? `${snippet.path} › ${snippet.heading}` : snippet.path;
+	return `${snippet.blockType} · ${location}`;
+}
@@ -0,0 +1,134 @@
+/**
+ * Ultra Code Fence - Source Edit Modal
+ *
+ * Edits the code a block embeds from a vault file, then asks before
+ * writing it back: the edit step shows the code in a text area, and the
+ * review step says how many lines change in which file. Nothing is
+ * written until the change is confirmed.
+ */
+
+import { App, Modal } from 'obsidian';
+import { CSS_CLASSES } from '../constants';
+import { diffLineLists } from '../utils/diff';
+
+// =============================================================================
+// Modal Implementation
+// =============================================================================
+
+/**
+ * Two-step editor for a block's embedded code.
+ *
+ * Writing passes the edited code to the callback, which reports whether
+ * it was written; the modal stays open when it wasn't.
+ */
+export class SourceEditModal extends Modal {
+	private filePath: string;
+	private originalCode: string;
+	private editedCode: string;
+	private onWrite: (code: string) => Promise<boolean>;
+
+	/**
+	 * Creates a new source edit modal.
+	 *
+	 * @param app - Obsidian App instance
+	 * @param filePath - Vault path of the file, for the headings
+	 * @param code - The code as the block shows it
+	 * @param onWrite - Writes the edited code back; resolves true on success
+	 */
+	constructor(app: App, filePath: string, code: string, onWrite: (code: string) => Promise<boolean>) {
+		super(app);
+		this.filePath = filePath;
+		this.originalCode = code;
+		this.editedCode = code;
+		this.onWrite = onWrite;
+	}
+
+	/**
+	 * Shows the edit step when opened.
+	 */
+	onOpen(): void {
+		this.contentEl.addClass(CSS_CLASSES.sourceEditModal);
+		this.showEditor();
+	}
+
+	/**
+	 * Shows the code in a text area, with Cancel and Review buttons.
+	 */
+	private showEditor(): void {
+		const { contentEl } = this;
+		contentEl.empty();
+
+		contentEl.createEl('h2', { text: `Edit ${this.filePath}` });
+
+		const textArea = contentEl.createEl('textarea', { cls: CSS_CLASSES.sourceEditInput });
+		textArea.value = this.editedCode;
+		textArea.spellcheck = false;
+		textArea.rows = Math.min(30, Math.max(8, this.editedCode.split('\n').length + 1));
+
+		const buttonContainer = contentEl.createEl('div', { cls: CSS_CLASSES.modalButtons });
+		const cancelButton = buttonContainer.createEl('button', { text: 'Cancel' });
+		cancelButton.addEventListener('click', () => { this.close(); });
+
+		const reviewButton = buttonContainer.createEl('button', { text: 'Review', cls: 'mod-cta' });
+		reviewButton.addEventListener('click', () => {
+			this.editedCode = textArea.value;
+			this.showReview();
+		});
+
+		textArea.focus();
+	}
+
+	/**
+	 * Says what writing the edit changes, with Back and Write buttons.
+	 */
+	private showReview(): void {
+		const { contentEl } = this;
+		contentEl.empty();
+
+		contentEl.createEl('h2', { text: `Write to ${this.filePath}?` });
+
+		const steps = diffLineLists(this.originalCode.split('\n'), this.editedCode.split('\n'));
+		const added = steps.filter(step => step.kind === 'added').length;
+		const removed = steps.filter(step => step.kind === 'removed').length;
+		contentEl.createEl('p', {
+			cls: CSS_CLASSES.sourceEditSummary,
+			text: added === 0 && removed === 0
+				? 'Nothing has changed.'
+				: `${formatLineCount(added)} added and ${formatLineCount(removed)} removed. Only the lines this block shows are replaced; the rest of the file is left as it is.`,
+		});
+
+		const buttonContainer = contentEl.createEl('div', { cls: CSS_CLASSES.modalButtons });
+		const backButton = buttonContainer.createEl('button', { text: 'Back' });
+		backButton.addEventListener('click', () => { this.showEditor(); });
+
+		const writeButton = buttonContainer.createEl('button', { text: 'Write', cls: 'mod-warning' });
+		writeButton.disabled = added === 0 && removed === 0;
+		writeButton.addEventListener('click', () => {
+			writeButton.disabled = true;
+			void this.onWrite(this.editedCode).then((written) => {
+				if (written) {
+					this.close();
+				} else {
+					writeButton.disabled = false;
+				}
+			});
+		});
+	}
+
+	/**
+	 * Cleans up when closed.
+	 */
+	onClose(): void {
+		this.contentEl.empty();
+	}
+}
+
+/**
+ * Formats a line count for the review step.
+ *
+ * @param count - Number of lines
+ * @returns "1 line" or "3 lines"
+ */
+function formatLineCount(count: number): string {
+	return count === 1 ? '1 line' : `${String(count)} lines`;
+}
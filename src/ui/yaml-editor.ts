@@ -16,9 +16,10 @@
  * shows through, but the user still types into the real textarea.
  */
 
-import { parseYaml } from 'obsidian';
+import { parseYamlSettings } from '../parsers/config-format';
 import { highlightYaml } from './yaml-highlighter';
 import { validateYamlSchema, formatWarnings } from './yaml-validator';
+import type { ConfigSchema } from './yaml-validator';
 import { parseHtmlFragment } from '../utils/dom';
 
 // =============================================================================
@@ -32,6 +33,8 @@ export interface YamlEditorOptions {
 	placeholder?: string;
 	/** Called on every input change */
 	onChange: (value: string) => void;
+	/** Schema the content is checked against (defaults to ufence code blocks) */
+	schema?: ConfigSchema;
 }
 
 export interface YamlEditorHandle {
@@ -128,14 +131,14 @@ export function createYamlEditor(
 		}
 
 		try {
-			const parsed: unknown = parseYaml(value);
+			const parsed: unknown = parseYamlSettings(value);
 			if (parsed === null || parsed === undefined) {
 				setStatus('Empty YAML', 'invalid');
 			} else if (typeof parsed !== 'object') {
 				setStatus('Not a YAML mapping — expected key: value pairs', 'invalid');
 			} else {
 				// Syntax is fine — now check for unrecognised keys
-				const warnings = validateYamlSchema(parsed);
+				const warnings = validateYamlSchema(parsed, options.schema);
 				if (warnings.length > 0) {
 					setStatus(formatWarnings(warnings), 'warning');
 				} else {
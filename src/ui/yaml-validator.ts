@@ -1,10 +1,11 @@
 /**
  * Ultra Code Fence - YAML Schema Validator
  *
- * Validates that a parsed YAML object contains only recognised keys
- * from the Ultra Code Fence configuration schema. This catches typos
- * like "NUMBER: true" (should be "LINES: true") that are valid YAML
- * but not valid plugin configuration.
+ * Validates a parsed YAML object against the Ultra Code Fence
+ * configuration schema. This catches typos like "NUMBER: true" (should
+ * be "LINES: true") and values of the wrong type like "FOLD: abc" that
+ * are valid YAML but not valid plugin configuration. Unknown keys come
+ * with a "did you mean" suggestion when a valid key is close enough.
  *
  * Uses the canonical key constants from src/constants/patterns.ts
  * as the single source of truth.
@@ -14,36 +15,341 @@ import {
 	YAML_SECTIONS,
 	YAML_META,
 	YAML_RENDER_DISPLAY,
+	YAML_RENDER_CMDOUT,
+	YAML_TEXT_STYLE,
 	YAML_FILTER,
 	YAML_FILTER_BY_LINES,
 	YAML_FILTER_BY_MARKS,
 	YAML_CALLOUT,
 	YAML_CALLOUT_ENTRY,
+	YAML_COPY,
+	YAML_COPY_AS_ENTRY,
+	YAML_HIGHLIGHT,
+	YAML_HEADER,
+	YAML_FOOTER,
+	YAML_DIFF,
+	YAML_DOWNLOAD,
+	YAML_PRINT,
+	YAML_RUN,
+	YAML_PROMPT,
+	YAML_PRESET_EXTENDS,
+	YAML_PRESET_APPLIES_TO_TAGS,
+	YAML_PRESET_PARAMS,
+	YAML_PRESET_WHEN,
+	PRESET_CONDITIONS,
+	PRESET_PARAM_PATTERN,
+	CONFIG_MODES,
+	CONFIG_RENAMES,
+	YAML_SCRATCH_KEY_PREFIX,
+	CALLOUT_TYPE_COLORS,
 } from '../constants';
+import { COPY_AS_DEFAULT_LABELS } from '../utils/copy-transforms';
 
 // =============================================================================
 // Schema — built from the canonical constants
 // =============================================================================
 
-/** Set of recognised top-level section names. */
-const VALID_SECTIONS = new Set<string>(Object.values(YAML_SECTIONS));
+/**
+ * Kind of value a configuration key accepts.
+ *
+ * - text: Any scalar (strings, numbers and booleans are all read as text)
+ * - boolean: true/false (or the strings "true"/"false")
+ * - number: A number (or a string of digits)
+ * - list: A single value or a list of values
+ * - section: A nested mapping of further keys
+ * - entries: A list of mappings, each checked against the same keys
+ */
+export type SchemaValueType = 'text' | 'boolean' | 'number' | 'list' | 'section' | 'entries';
+
+/** A recognised configuration key. */
+export interface SchemaKey {
+	/** Kind of value the key accepts */
+	type: SchemaValueType;
+	/** Keys within a section, or within each entry of an entries list */
+	keys?: ConfigSchema;
+	/** Known values, offered by editor autocomplete */
+	values?: readonly string[];
+}
+
+/** Recognised keys at one level of the configuration, by key name. */
+export type ConfigSchema = Record<string, SchemaKey>;
+
+/** Human-readable names for each value type, used in warnings. */
+export const TYPE_LABELS: Record<SchemaValueType, string> = {
+	text: 'text',
+	boolean: 'true or false',
+	number: 'a number',
+	list: 'a list',
+	section: 'a section',
+	entries: 'a list of entries',
+};
+
+/**
+ * Builds a schema level from key constants, treating keys as text
+ * unless overridden.
+ *
+ * @param keys - Key constants (e.g. YAML_META)
+ * @param overrides - Keys that are not plain text
+ * @returns Schema for the level
+ */
+function buildSchema(keys: Record<string, string>, overrides: ConfigSchema = {}): ConfigSchema {
+	const schema: ConfigSchema = {};
+	for (const key of Object.values(keys)) {
+		const override: SchemaKey | undefined = overrides[key];
+		schema[key] = override ?? { type: 'text' };
+	}
+	return schema;
+}
+
+/** Callout display modes (CALLOUT.DISPLAY and per-entry DISPLAY). */
+const CALLOUT_DISPLAY_VALUES = ['inline', 'footnote', 'popover'];
+
+/** Print behaviours (RENDER.PRINT). */
+const PRINT_VALUES = ['expand', 'asis'];
+
+/** Fold region behaviours (RENDER.FOLD_REGIONS). */
+const FOLD_REGION_VALUES = ['expanded', 'collapsed', 'none'];
+
+/** Indentation guide line styles (RENDER.INDENT_GUIDE_STYLE). */
+const INDENT_GUIDE_STYLE_VALUES = ['solid', 'dashed', 'dotted'];
+
+/** Whitespace glyph modes (RENDER.WHITESPACE; booleans are accepted too). */
+const WHITESPACE_VALUES = ['all', 'trailing', 'none'];
+
+/** Comment keyword modes (RENDER.COMMENT_KEYWORDS; booleans are accepted too). */
+const COMMENT_KEYWORD_VALUES = ['on', 'icons', 'off'];
+
+/** What copying a diff emits (DIFF.COPY). */
+const DIFF_COPY_VALUES = ['diff', 'after'];
+
+/** Diff layouts (DIFF.VIEW). */
+const DIFF_VIEW_VALUES = ['unified', 'split'];
+
+/** Print colours (PRINT.THEME). */
+const PRINT_THEME_VALUES = ['light', 'asis'];
+
+/** Line text directions (RENDER.DIRECTION). */
+const DIRECTION_VALUES = ['ltr', 'rtl', 'auto'];
+
+/** What copying command output emits (RENDER.ANSI_COPY in cmdout blocks). */
+const ANSI_COPY_VALUES = ['stripped', 'raw'];
+
+/** Sections shared by ufence and cmdout blocks. */
+const SHARED_SECTIONS: ConfigSchema = {
+	[YAML_SECTIONS.meta]: {
+		type: 'section',
+		keys: buildSchema(YAML_META, { [YAML_META.mode]: { type: 'text', values: CONFIG_MODES } }),
+	},
+	[YAML_SECTIONS.filter]: {
+		type: 'section',
+		keys: {
+			[YAML_FILTER.byLines]: {
+				type: 'section',
+				keys: buildSchema(YAML_FILTER_BY_LINES, {
+					[YAML_FILTER_BY_LINES.range]: { type: 'list' },
+					[YAML_FILTER_BY_LINES.inclusive]: { type: 'boolean' },
+				}),
+			},
+			[YAML_FILTER.byMarks]: {
+				type: 'section',
+				keys: buildSchema(YAML_FILTER_BY_MARKS, {
+					[YAML_FILTER_BY_MARKS.inclusive]: { type: 'boolean' },
+				}),
+			},
+			[YAML_FILTER.byRegion]: { type: 'text' },
+		},
+	},
+	[YAML_SECTIONS.callout]: {
+		type: 'section',
+		keys: buildSchema(YAML_CALLOUT, {
+			[YAML_CALLOUT.display]: { type: 'text', values: CALLOUT_DISPLAY_VALUES },
+			[YAML_CALLOUT.printDisplay]: { type: 'text', values: ['inline', 'footnote'] },
+			[YAML_CALLOUT.style]: { type: 'text', values: ['standard', 'border'] },
+			[YAML_CALLOUT.entries]: {
+				type: 'entries',
+				keys: buildSchema(YAML_CALLOUT_ENTRY, {
+					[YAML_CALLOUT_ENTRY.line]: { type: 'number' },
+					[YAML_CALLOUT_ENTRY.lines]: { type: 'list' },
+					[YAML_CALLOUT_ENTRY.replace]: { type: 'boolean' },
+					[YAML_CALLOUT_ENTRY.display]: { type: 'text', values: CALLOUT_DISPLAY_VALUES },
+					[YAML_CALLOUT_ENTRY.type]: { type: 'text', values: Object.keys(CALLOUT_TYPE_COLORS) },
+				}),
+			},
+		}),
+	},
+	[YAML_SECTIONS.copy]: {
+		type: 'section',
+		keys: buildSchema(YAML_COPY, {
+			[YAML_COPY.as]: {
+				type: 'entries',
+				keys: buildSchema(YAML_COPY_AS_ENTRY, {
+					// Read each time, since other plugins can add formats
+					[YAML_COPY_AS_ENTRY.format]: { type: 'text', get values() { return Object.keys(COPY_AS_DEFAULT_LABELS); } },
+				}),
+			},
+			[YAML_COPY.placeholders]: { type: 'boolean' },
+			[YAML_COPY.variables]: { type: 'boolean' },
+			[YAML_COPY.stripComments]: { type: 'boolean' },
+			[YAML_COPY.redact]: { type: 'list' },
+			[YAML_COPY.feedback]: { type: 'boolean' },
+			[YAML_COPY.feedbackDuration]: { type: 'number' },
+		}),
+	},
+	[YAML_SECTIONS.highlight]: {
+		type: 'section',
+		keys: buildSchema(YAML_HIGHLIGHT, {
+			[YAML_HIGHLIGHT.lines]: { type: 'list' },
+			[YAML_HIGHLIGHT.focus]: { type: 'list' },
+			[YAML_HIGHLIGHT.focusToggle]: { type: 'boolean' },
+			[YAML_HIGHLIGHT.terms]: { type: 'list' },
+		}),
+	},
+	[YAML_SECTIONS.download]: {
+		type: 'section',
+		keys: buildSchema(YAML_DOWNLOAD, { [YAML_DOWNLOAD.executable]: { type: 'boolean' } }),
+	},
+	[YAML_SECTIONS.print]: {
+		type: 'section',
+		keys: buildSchema(YAML_PRINT, {
+			[YAML_PRINT.expand]: { type: 'boolean' },
+			[YAML_PRINT.theme]: { type: 'text', values: PRINT_THEME_VALUES },
+			[YAML_PRINT.lines]: { type: 'boolean' },
+			[YAML_PRINT.avoidBreaks]: { type: 'boolean' },
+		}),
+	},
+	[YAML_PROMPT]: { type: 'text' },
+};
 
-/** Recognised keys within each section. */
-const SECTION_KEYS: Record<string, Set<string>> = {
-	[YAML_SECTIONS.meta]: new Set<string>(Object.values(YAML_META)),
-	[YAML_SECTIONS.render]: new Set<string>(Object.values(YAML_RENDER_DISPLAY)),
-	[YAML_SECTIONS.filter]: new Set<string>(Object.values(YAML_FILTER)),
-	[YAML_SECTIONS.callout]: new Set<string>(Object.values(YAML_CALLOUT)),
+/** Schema for ufence code blocks. */
+export const CODE_BLOCK_SCHEMA: ConfigSchema = {
+	...SHARED_SECTIONS,
+	[YAML_SECTIONS.render]: {
+		type: 'section',
+		keys: buildSchema(YAML_RENDER_DISPLAY, {
+			[YAML_RENDER_DISPLAY.fold]: { type: 'number' },
+			[YAML_RENDER_DISPLAY.scroll]: { type: 'number' },
+			[YAML_RENDER_DISPLAY.zebra]: { type: 'boolean' },
+			[YAML_RENDER_DISPLAY.lines]: { type: 'boolean' },
+			[YAML_RENDER_DISPLAY.copy]: { type: 'boolean' },
+			[YAML_RENDER_DISPLAY.lineCopy]: { type: 'boolean' },
+			[YAML_RENDER_DISPLAY.lineStart]: { type: 'number' },
+			[YAML_RENDER_DISPLAY.lineAnchor]: { type: 'number' },
+			[YAML_RENDER_DISPLAY.foldRegions]: { type: 'text', values: FOLD_REGION_VALUES },
+			[YAML_RENDER_DISPLAY.collapsed]: { type: 'boolean' },
+			[YAML_RENDER_DISPLAY.indentGuides]: { type: 'boolean' },
+			[YAML_RENDER_DISPLAY.indentGuideStyle]: { type: 'text', values: INDENT_GUIDE_STYLE_VALUES },
+			[YAML_RENDER_DISPLAY.whitespace]: { type: 'text', values: WHITESPACE_VALUES },
+			[YAML_RENDER_DISPLAY.commentKeywords]: { type: 'text', values: COMMENT_KEYWORD_VALUES },
+			[YAML_RENDER_DISPLAY.brackets]: { type: 'boolean' },
+			[YAML_RENDER_DISPLAY.semantic]: { type: 'boolean' },
+			[YAML_RENDER_DISPLAY.variables]: { type: 'boolean' },
+			[YAML_RENDER_DISPLAY.changedSince]: { type: 'text', values: ['last', '1d', '7d', 'off'] },
+			[YAML_RENDER_DISPLAY.minimap]: { type: 'number' },
+			[YAML_RENDER_DISPLAY.hover]: { type: 'boolean' },
+			[YAML_RENDER_DISPLAY.ligatures]: { type: 'boolean' },
+			[YAML_RENDER_DISPLAY.fontFeatures]: { type: 'list' },
+			[YAML_RENDER_DISPLAY.direction]: { type: 'text', values: DIRECTION_VALUES },
+			[YAML_RENDER_DISPLAY.tabSize]: { type: 'number' },
+			[YAML_RENDER_DISPLAY.ruler]: { type: 'list' },
+			[YAML_RENDER_DISPLAY.style]: { type: 'text', values: ['tab', 'integrated', 'minimal', 'infobar', 'none'] },
+			[YAML_RENDER_DISPLAY.print]: { type: 'text', values: PRINT_VALUES },
+		}),
+	},
+	[YAML_SECTIONS.header]: {
+		type: 'section',
+		keys: buildSchema(YAML_HEADER, {
+			[YAML_HEADER.title]: { type: 'boolean' },
+			[YAML_HEADER.language]: { type: 'boolean' },
+			[YAML_HEADER.buttons]: { type: 'boolean' },
+		}),
+	},
+	[YAML_SECTIONS.footer]: {
+		type: 'section',
+		keys: buildSchema(YAML_FOOTER),
+	},
+	[YAML_SECTIONS.diff]: {
+		type: 'section',
+		keys: buildSchema(YAML_DIFF, {
+			[YAML_DIFF.added]: { type: 'list' },
+			[YAML_DIFF.removed]: { type: 'list' },
+			[YAML_DIFF.copy]: { type: 'text', values: DIFF_COPY_VALUES },
+			[YAML_DIFF.words]: { type: 'boolean' },
+			[YAML_DIFF.view]: { type: 'text', values: DIFF_VIEW_VALUES },
+		}),
+	},
+	// Keyed by line number, so their keys aren't checked
+	[YAML_SECTIONS.annotations]: { type: 'section' },
+	[YAML_SECTIONS.blame]: { type: 'section' },
+	// Keyed by token name or language
+	[YAML_SECTIONS.tokens]: { type: 'section' },
+	// Keyed by regex
+	[YAML_SECTIONS.patterns]: { type: 'section' },
+	[YAML_SECTIONS.run]: {
+		type: 'section',
+		keys: buildSchema(YAML_RUN, {
+			[YAML_RUN.enabled]: { type: 'boolean' },
+			[YAML_RUN.save]: { type: 'boolean' },
+			[YAML_RUN.lines]: { type: 'boolean' },
+			[YAML_RUN.timeout]: { type: 'text' },
+			// Keyed by variable name
+			[YAML_RUN.env]: { type: 'section' },
+		}),
+	},
 };
 
-/** Recognised keys within FILTER subsections. */
-const FILTER_SUB_KEYS: Record<string, Set<string>> = {
-	[YAML_FILTER.byLines]: new Set<string>(Object.values(YAML_FILTER_BY_LINES)),
-	[YAML_FILTER.byMarks]: new Set<string>(Object.values(YAML_FILTER_BY_MARKS)),
+/**
+ * WHEN keys: each condition alone, and pairs of conditions in either order
+ * (e.g. "dark mobile"), each holding code block settings.
+ */
+const PRESET_WHEN_SCHEMA: ConfigSchema = {};
+for (const first of PRESET_CONDITIONS) {
+	PRESET_WHEN_SCHEMA[first] = { type: 'section', keys: CODE_BLOCK_SCHEMA };
+	for (const second of PRESET_CONDITIONS) {
+		if (second !== first) {
+			PRESET_WHEN_SCHEMA[`${first} ${second}`] = { type: 'section', keys: CODE_BLOCK_SCHEMA };
+		}
+	}
+}
+
+/**
+ * Schema for presets: code block settings plus the presets they extend,
+ * the parameters they declare (any names, so PARAMS has no keys), their
+ * conditional settings and the note tags they apply to.
+ */
+export const PRESET_SCHEMA: ConfigSchema = {
+	...CODE_BLOCK_SCHEMA,
+	[YAML_PRESET_EXTENDS]: { type: 'list' },
+	[YAML_PRESET_PARAMS]: { type: 'section' },
+	[YAML_PRESET_WHEN]: { type: 'section', keys: PRESET_WHEN_SCHEMA },
+	[YAML_PRESET_APPLIES_TO_TAGS]: { type: 'list' },
 };
 
-/** Recognised keys within CALLOUT.ENTRIES items. */
-const CALLOUT_ENTRY_KEYS = new Set<string>(Object.values(YAML_CALLOUT_ENTRY));
+/** Text style keys for cmdout RENDER subsections. */
+const TEXT_STYLE_SCHEMA: ConfigSchema = buildSchema(YAML_TEXT_STYLE, {
+	[YAML_TEXT_STYLE.bold]: { type: 'boolean' },
+	[YAML_TEXT_STYLE.italic]: { type: 'boolean' },
+});
+
+/**
+ * Schema for ufence-cmdout blocks, whose RENDER section styles each part
+ * (and shares the scroll, copy and print options with code blocks).
+ */
+export const CMDOUT_BLOCK_SCHEMA: ConfigSchema = {
+	...SHARED_SECTIONS,
+	[YAML_SECTIONS.render]: {
+		type: 'section',
+		keys: {
+			[YAML_RENDER_CMDOUT.prompt]: { type: 'section', keys: TEXT_STYLE_SCHEMA },
+			[YAML_RENDER_CMDOUT.command]: { type: 'section', keys: TEXT_STYLE_SCHEMA },
+			[YAML_RENDER_CMDOUT.output]: { type: 'section', keys: TEXT_STYLE_SCHEMA },
+			[YAML_RENDER_DISPLAY.scroll]: { type: 'number' },
+			[YAML_RENDER_DISPLAY.copy]: { type: 'boolean' },
+			[YAML_RENDER_DISPLAY.print]: { type: 'text', values: PRINT_VALUES },
+			[YAML_RENDER_DISPLAY.ansi]: { type: 'boolean' },
+			[YAML_RENDER_DISPLAY.ansiCopy]: { type: 'text', values: ANSI_COPY_VALUES },
+		},
+	},
+};
 
 // =============================================================================
 // Validation
@@ -51,102 +357,248 @@ const CALLOUT_ENTRY_KEYS = new Set<string>(Object.values(YAML_CALLOUT_ENTRY));
 
 /**
  * A single validation warning (not a hard error — the YAML is parseable
- * but contains an unrecognised key).
+ * but contains an unrecognised key or a value of the wrong type).
  */
 export interface YamlWarning {
 	/** Path to the problematic key, e.g. "RENDER.NUMBER" */
 	path: string;
 	/** The unrecognised key name */
 	key: string;
+	/** Closest valid key, when the key looks like a typo */
+	suggestion?: string;
+	/** Expected value type (of the suggested key, or of a mistyped value) */
+	expectedType?: string;
+	/** True when the key is valid but its value has the wrong type */
+	wrongType?: boolean;
+	/** New name of a key that was renamed in a later release */
+	renamedTo?: string;
 }
 
 /**
- * Validates a parsed YAML object against the UCF configuration schema.
+ * Checks whether a value is a plain mapping (not null or a list).
  *
- * Returns an array of warnings for unrecognised keys. An empty array
- * means everything is valid (or the input is empty/non-object).
+ * @param value - Value to check
+ * @returns True for plain objects
+ */
+function isMapping(value: unknown): value is Record<string, unknown> {
+	return !!value && typeof value === 'object' && !Array.isArray(value);
+}
+
+/**
+ * Checks whether a scalar value fits the type a key expects.
  *
- * @param parsed - The parsed YAML object (from `parseYaml()`)
- * @returns Array of warnings for unrecognised keys
+ * Sections and entries are checked structurally by the caller.
+ *
+ * @param value - Value from the configuration
+ * @param type - Expected type
+ * @returns True if the value is acceptable
  */
-export function validateYamlSchema(parsed: unknown): YamlWarning[] {
-	if (!parsed || typeof parsed !== 'object' || Array.isArray(parsed)) {
-		return [];
+function matchesType(value: unknown, type: SchemaValueType): boolean {
+	switch (type) {
+		case 'boolean':
+			return typeof value === 'boolean' || value === 'true' || value === 'false';
+		case 'number':
+			return typeof value === 'number' || (typeof value === 'string' && /^\s*-?\d+\s*$/.test(value));
+		case 'text':
+			return typeof value !== 'object';
+		case 'list':
+			return !isMapping(value);
+		default:
+			return true;
 	}
+}
 
-	const warnings: YamlWarning[] = [];
-	const obj = parsed as Record<string, unknown>;
+/**
+ * Calculates the edit distance between two strings, counting a swap of
+ * two neighbouring characters (the commonest typo) as one edit.
+ *
+ * @param a - First string
+ * @param b - Second string
+ * @returns Number of single-character insertions, deletions, substitutions or swaps
+ */
+function editDistance(a: string, b: string): number {
+	const distances: number[][] = [];
 
-	for (const key of Object.keys(obj)) {
-		if (!VALID_SECTIONS.has(key)) {
-			warnings.push({ path: key, key });
-			continue;
+	for (let i = 0; i <= a.length; i++) {
+		distances.push([i]);
+		for (let j = 1; j <= b.length; j++) {
+			if (i === 0) {
+				distances[i].push(j);
+				continue;
+			}
+			const cost = a[i - 1] === b[j - 1] ? 0 : 1;
+			let distance = Math.min(distances[i - 1][j] + 1, distances[i][j - 1] + 1, distances[i - 1][j - 1] + cost);
+			if (i > 1 && j > 1 && a[i - 1] === b[j - 2] && a[i - 2] === b[j - 1]) {
+				distance = Math.min(distance, distances[i - 2][j - 2] + 1);
+			}
+			distances[i].push(distance);
 		}
+	}
 
-		const sectionValue = obj[key];
-		if (!sectionValue || typeof sectionValue !== 'object' || Array.isArray(sectionValue)) {
-			continue;
+	return distances[a.length][b.length];
+}
+
+/**
+ * Finds the valid key closest to an unknown one.
+ *
+ * Comparison ignores case, so "higlight" suggests "HIGHLIGHT". Keys
+ * further away than a third of the key's length are not suggested.
+ *
+ * @param key - Unknown key
+ * @param schema - Valid keys at the same level
+ * @returns Closest valid key, or undefined if none is close
+ */
+function findNearestKey(key: string, schema: ConfigSchema): string | undefined {
+	const target = key.toUpperCase();
+	const maxDistance = Math.max(1, Math.floor(target.length / 3));
+
+	let nearest: string | undefined;
+	let nearestDistance = maxDistance + 1;
+
+	for (const candidate of Object.keys(schema)) {
+		const distance = editDistance(target, candidate);
+		if (distance < nearestDistance) {
+			nearest = candidate;
+			nearestDistance = distance;
 		}
+	}
 
-		const sectionObj = sectionValue as Record<string, unknown>;
-		const validKeys = SECTION_KEYS[key];
+	return nearest;
+}
 
-		if (!(key in SECTION_KEYS)) continue;
+/**
+ * Validates one level of configuration against its schema.
+ *
+ * @param obj - Mapping at this level
+ * @param schema - Valid keys at this level
+ * @param pathPrefix - Path of this level ("" at the top)
+ * @param warnings - Array that warnings are appended to
+ * @param paramNames - Preset parameters, whose ${name} values skip type checks
+ */
+function validateLevel(
+	obj: Record<string, unknown>,
+	schema: ConfigSchema,
+	pathPrefix: string,
+	warnings: YamlWarning[],
+	paramNames: ReadonlySet<string>
+): void {
+	for (const key of Object.keys(obj)) {
+		// Top-level scratch keys only hold anchors for reuse
+		if (!pathPrefix && key.startsWith(YAML_SCRATCH_KEY_PREFIX)) continue;
+
+		const path = pathPrefix ? `${pathPrefix}.${key}` : key;
+		let schemaKey: SchemaKey | undefined = schema[key];
 
-		for (const subKey of Object.keys(sectionObj)) {
-			if (!validKeys.has(subKey)) {
-				warnings.push({ path: `${key}.${subKey}`, key: subKey });
+		if (!schemaKey) {
+			// Renamed keys still work, so check their value under the new name
+			const renamedTo: string | undefined = CONFIG_RENAMES[path];
+			const renamedKey: SchemaKey | undefined = renamedTo !== undefined ? schema[renamedTo] : undefined;
+			if (renamedTo !== undefined && renamedKey) {
+				warnings.push({ path, key, renamedTo });
+				schemaKey = renamedKey;
+			} else {
+				const suggestion = findNearestKey(key, schema);
+				warnings.push(suggestion
+					? { path, key, suggestion, expectedType: TYPE_LABELS[schema[suggestion].type] }
+					: { path, key });
 				continue;
 			}
+		}
 
-			// Validate deeper nesting for FILTER subsections
-			if (key === YAML_SECTIONS.filter && subKey in FILTER_SUB_KEYS) {
-				const filterSub = sectionObj[subKey];
-				if (filterSub && typeof filterSub === 'object' && !Array.isArray(filterSub)) {
-					const filterSubObj = filterSub as Record<string, unknown>;
-					const validSubKeys = FILTER_SUB_KEYS[subKey];
-					for (const filterKey of Object.keys(filterSubObj)) {
-						if (!validSubKeys.has(filterKey)) {
-							warnings.push({ path: `${key}.${subKey}.${filterKey}`, key: filterKey });
-						}
-					}
-				}
+		const value = obj[key];
+		if (value === null || value === undefined) continue;
+
+		if (schemaKey.type === 'section') {
+			// Non-object sections are a structural issue, not a schema one
+			if (isMapping(value) && schemaKey.keys) {
+				validateLevel(value, schemaKey.keys, path, warnings, paramNames);
 			}
+			continue;
+		}
 
-			// Validate CALLOUT.ENTRIES items
-			if (key === YAML_SECTIONS.callout && subKey === YAML_CALLOUT.entries) {
-				const entries = sectionObj[subKey];
-				if (Array.isArray(entries)) {
-					for (let i = 0; i < entries.length; i++) {
-						const entry: unknown = entries[i];
-						if (entry && typeof entry === 'object' && !Array.isArray(entry)) {
-							const entryObj = entry as Record<string, unknown>;
-							for (const entryKey of Object.keys(entryObj)) {
-								if (!CALLOUT_ENTRY_KEYS.has(entryKey)) {
-									warnings.push({
-										path: `${key}.${subKey}[${String(i)}].${entryKey}`,
-										key: entryKey,
-									});
-								}
-							}
-						}
-					}
-				}
+		if (schemaKey.type === 'entries') {
+			if (!Array.isArray(value)) {
+				warnings.push({ path, key, expectedType: TYPE_LABELS.entries, wrongType: true });
+				continue;
 			}
+			const entryKeys = schemaKey.keys;
+			value.forEach((entry: unknown, index) => {
+				if (isMapping(entry) && entryKeys) {
+					validateLevel(entry, entryKeys, `${path}[${String(index)}]`, warnings, paramNames);
+				}
+			});
+			continue;
+		}
+
+		// A preset parameter's type is only known once a block sets it
+		const paramMatch = typeof value === 'string' ? new RegExp(`^${PRESET_PARAM_PATTERN.source}$`).exec(value.trim()) : null;
+		if (paramMatch && paramNames.has(paramMatch[1])) continue;
+
+		if (!matchesType(value, schemaKey.type)) {
+			warnings.push({ path, key, expectedType: TYPE_LABELS[schemaKey.type], wrongType: true });
 		}
 	}
+}
 
+/**
+ * Validates a parsed YAML object against the UCF configuration schema.
+ *
+ * Returns an array of warnings for unrecognised keys and mistyped values,
+ * in the order they appear. An empty array means everything is valid
+ * (or the input is empty/non-object).
+ *
+ * @param parsed - The parsed YAML object (from `parseYaml()`)
+ * @param schema - Schema to validate against (defaults to ufence code blocks)
+ * @returns Array of warnings
+ */
+export function validateYamlSchema(parsed: unknown, schema: ConfigSchema = CODE_BLOCK_SCHEMA): YamlWarning[] {
+	if (!isMapping(parsed)) {
+		return [];
+	}
+
+	// Presets may declare parameters used as ${name} values
+	const params = schema[YAML_PRESET_PARAMS] ? parsed[YAML_PRESET_PARAMS] : undefined;
+	const paramNames = new Set(isMapping(params) ? Object.keys(params) : []);
+
+	const warnings: YamlWarning[] = [];
+	validateLevel(parsed, schema, '', warnings, paramNames);
 	return warnings;
 }
 
+/**
+ * Formats a single validation warning.
+ *
+ * @param warning - Validation warning
+ * @returns Message, e.g. "Unknown key: higlight — did you mean HIGHLIGHT (a section)?"
+ */
+export function formatWarning(warning: YamlWarning): string {
+	if (warning.renamedTo) {
+		return `${warning.path} was renamed to ${warning.renamedTo}`;
+	}
+	if (warning.wrongType) {
+		return `${warning.path} should be ${warning.expectedType ?? 'a different type'}`;
+	}
+	if (warning.suggestion) {
+		const typeHint = warning.expectedType ? ` (${warning.expectedType})` : '';
+		return `Unknown key: ${warning.path} — did you mean ${warning.suggestion}${typeHint}?`;
+	}
+	return `Unknown key: ${warning.path}`;
+}
+
 /**
  * Formats validation warnings into a human-readable string.
  *
+ * Plain unknown keys are listed together; suggestions, type problems
+ * and renamed keys are written out one by one.
+ *
  * @param warnings - Array of validation warnings
  * @returns Formatted string, e.g. "Unknown key: RENDER.NUMBER"
  */
 export function formatWarnings(warnings: YamlWarning[]): string {
 	if (warnings.length === 0) return '';
+	if (warnings.some(w => w.suggestion || w.wrongType || w.renamedTo)) {
+		return warnings.map(formatWarning).join('; ');
+	}
 	if (warnings.length === 1) {
 		return `Unknown key: ${warnings[0].path}`;
 	}
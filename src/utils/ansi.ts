@@ -0,0 +1,258 @@
+/**
+ * ANSI escape codes for Ultra Code Fence
+ *
+ * Reads the colour and style codes (SGR, e.g. ESC[32m) in terminal
+ * output, so command output can be shown in colour instead of as raw
+ * escape noise. Codes pasted as text (\x1b[, \033[, \e[, ^[[) are read
+ * too. Cursor movement and other control sequences are dropped.
+ */
+
+/**
+ * Text style in effect at a point in terminal output.
+ */
+export interface AnsiStyle {
+	/** CSS text colour (empty = default) */
+	foreground: string;
+
+	/** CSS background colour (empty = default) */
+	background: string;
+
+	bold: boolean;
+	dim: boolean;
+	italic: boolean;
+	underline: boolean;
+	strikethrough: boolean;
+
+	/** Swap the text and background colours */
+	inverse: boolean;
+}
+
+/**
+ * A run of text in one style.
+ */
+export interface AnsiSegment {
+	text: string;
+	style: AnsiStyle;
+}
+
+/** Escape character, built at runtime so the patterns hold no control characters. */
+const ESC = String.fromCharCode(27);
+
+/** Bell character, which ends operating system commands (e.g. window titles). */
+const BEL = String.fromCharCode(7);
+
+/** What starts an escape: the character itself or the ways it is written as text. */
+const ESCAPE_INTRODUCER = `(?:${ESC}|\\\\x1[bB]|\\\\033|\\\\e|\\\\u001[bB]|\\^\\[)`;
+
+/**
+ * An escape sequence. Groups: CSI parameters and final letter (absent
+ * for operating system commands).
+ */
+const ANSI_SEQUENCE_SOURCE = `${ESCAPE_INTRODUCER}(?:\\[([0-9;?]*)([A-Za-z])|\\][^${BEL}${ESC}]*(?:${BEL}|${ESC}\\\\))`;
+
+/** Levels of each channel in the 6x6x6 colour cube of the 256-colour palette. */
+const COLOUR_CUBE_LEVELS = [0, 95, 135, 175, 215, 255];
+
+/**
+ * Creates the default (unstyled) text style.
+ *
+ * @returns Style with no colours or attributes
+ */
+export function createAnsiStyle(): AnsiStyle {
+	return {
+		foreground: '',
+		background: '',
+		bold: false,
+		dim: false,
+		italic: false,
+		underline: false,
+		strikethrough: false,
+		inverse: false,
+	};
+}
+
+/**
+ * Checks whether text holds any escape sequences.
+ *
+ * @param text - Terminal output
+ * @returns True when there is something to interpret or strip
+ */
+export function hasAnsiCodes(text: string): boolean {
+	return new RegExp(ANSI_SEQUENCE_SOURCE).test(text);
+}
+
+/**
+ * Removes all escape sequences from text.
+ *
+ * @param text - Terminal output
+ * @returns Text as it would appear in the terminal, without colours
+ */
+export function stripAnsi(text: string): string {
+	return text.replace(new RegExp(ANSI_SEQUENCE_SOURCE, 'g'), '');
+}
+
+/**
+ * Turns a colour of the 256-colour palette into CSS. The first 16 use
+ * the theme's terminal colours (--ucf-ansi-0 to --ucf-ansi-15).
+ *
+ * @param index - Palette index (0-255)
+ * @returns CSS colour, or an empty string for an invalid index
+ */
+export function ansiPaletteColour(index: number): string {
+	if (!Number.isInteger(index) || index < 0 || index > 255) return '';
+	if (index < 16) return `var(--ucf-ansi-${String(index)})`;
+
+	if (index >= 232) {
+		const grey = 8 + (index - 232) * 10;
+		return `rgb(${String(grey)}, ${String(grey)}, ${String(grey)})`;
+	}
+
+	const cubeIndex = index - 16;
+	const red = COLOUR_CUBE_LEVELS[Math.floor(cubeIndex / 36)];
+	const green = COLOUR_CUBE_LEVELS[Math.floor(cubeIndex / 6) % 6];
+	const blue = COLOUR_CUBE_LEVELS[cubeIndex % 6];
+	return `rgb(${String(red)}, ${String(green)}, ${String(blue)})`;
+}
+
+/**
+ * Reads an extended colour (38;5;n or 38;2;r;g;b) from SGR parameters.
+ *
+ * @param codes - All parameters of the sequence
+ * @param index - Position of the 38 or 48
+ * @returns The colour and how many parameters it used after the 38/48
+ */
+function readExtendedColour(codes: number[], index: number): { colour: string; used: number } {
+	if (codes[index + 1] === 5 && index + 2 < codes.length) {
+		return { colour: ansiPaletteColour(codes[index + 2]), used: 2 };
+	}
+
+	if (codes[index + 1] === 2 && index + 4 < codes.length) {
+		const [red, green, blue] = codes.slice(index + 2, index + 5).map(value => Math.min(255, value));
+		return { colour: `rgb(${String(red)}, ${String(green)}, ${String(blue)})`, used: 4 };
+	}
+
+	return { colour: '', used: codes.length - index - 1 };
+}
+
+/**
+ * Applies the parameters of a colour and style (SGR) sequence.
+ *
+ * @param style - Style before the sequence
+ * @param parameters - Parameters, e.g. "1;32" ("" = reset)
+ * @returns Style after the sequence
+ */
+export function applySgrCodes(style: AnsiStyle, parameters: string): AnsiStyle {
+	const next = { ...style };
+	const codes = parameters === '' ? [0] : parameters.split(';').map(code => (code === '' ? 0 : parseInt(code, 10)));
+
+	for (let index = 0; index < codes.length; index++) {
+		const code = codes[index];
+
+		if (code === 0) {
+			Object.assign(next, createAnsiStyle());
+		} else if (code === 1) {
+			next.bold = true;
+		} else if (code === 2) {
+			next.dim = true;
+		} else if (code === 3) {
+			next.italic = true;
+		} else if (code === 4) {
+			next.underline = true;
+		} else if (code === 7) {
+			next.inverse = true;
+		} else if (code === 9) {
+			next.strikethrough = true;
+		} else if (code === 22) {
+			next.bold = false;
+			next.dim = false;
+		} else if (code === 23) {
+			next.italic = false;
+		} else if (code === 24) {
+			next.underline = false;
+		} else if (code === 27) {
+			next.inverse = false;
+		} else if (code === 29) {
+			next.strikethrough = false;
+		} else if (code >= 30 && code <= 37) {
+			next.foreground = ansiPaletteColour(code - 30);
+		} else if (code >= 90 && code <= 97) {
+			next.foreground = ansiPaletteColour(code - 90 + 8);
+		} else if (code === 39) {
+			next.foreground = '';
+		} else if (code >= 40 && code <= 47) {
+			next.background = ansiPaletteColour(code - 40);
+		} else if (code >= 100 && code <= 107) {
+			next.background = ansiPaletteColour(code - 100 + 8);
+		} else if (code === 49) {
+			next.background = '';
+		} else if (code === 38 || code === 48) {
+			const { colour, used } = readExtendedColour(codes, index);
+			if (code === 38) {
+				next.foreground = colour;
+			} else {
+				next.background = colour;
+			}
+			index += used;
+		}
+	}
+
+	return next;
+}
+
+/**
+ * Splits a line of terminal output into runs of styled text.
+ *
+ * Styles carry over from line to line as they do in a terminal, so pass
+ * the style returned for one line in with the next.
+ *
+ * @param line - Line of terminal output
+ * @param style - Style in effect at the start of the line
+ * @returns The line's runs (escape sequences removed) and the style at its end
+ */
+export function parseAnsiLine(line: string, style: AnsiStyle): { segments: AnsiSegment[]; style: AnsiStyle } {
+	const segments: AnsiSegment[] = [];
+	const sequencePattern = new RegExp(ANSI_SEQUENCE_SOURCE, 'g');
+	let currentStyle = style;
+	let lastIndex = 0;
+	let match: RegExpExecArray | null;
+
+	const pushText = (text: string): void => {
+		if (text) segments.push({ text, style: currentStyle });
+	};
+
+	while ((match = sequencePattern.exec(line)) !== null) {
+		pushText(line.slice(lastIndex, match.index));
+		lastIndex = match.index + match[0].length;
+
+		// Only colour and style (m) sequences change the text; others are dropped
+		if (match[2] === 'm') {
+			currentStyle = applySgrCodes(currentStyle, match[1]);
+		}
+	}
+	pushText(line.slice(lastIndex));
+
+	return { segments, style: currentStyle };
+}
+
+/**
+ * Builds the inline CSS for a style.
+ *
+ * @param style - Text style
+ * @returns CSS declarations, or an empty string for the default style
+ */
+export function ansiStyleToCss(style: AnsiStyle): string {
+	const rules: string[] = [];
+	const foreground = style.inverse ? (style.background || 'var(--code-background, var(--background-primary))') : style.foreground;
+	const background = style.inverse ? (style.foreground || 'var(--text-normal)') : style.background;
+
+	if (foreground) rules.push(`color: ${foreground}`);
+	if (background) rules.push(`background-color: ${background}`);
+	if (style.bold) rules.push('font-weight: bold');
+	if (style.dim) rules.push('opacity: 0.7');
+	if (style.italic) rules.push('font-style: italic');
+
+	const decorations = [style.underline ? 'underline' : '', style.strikethrough ? 'line-through' : ''].filter(Boolean);
+	if (decorations.length > 0) rules.push(`text-decoration: ${decorations.join(' ')}`);
+
+	return rules.join('; ');
+}
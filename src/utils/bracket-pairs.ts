@@ -0,0 +1,65 @@
+/**
+ * Bracket pairs for Ultra Code Fence
+ *
+ * Pairs up the brackets of a block — (), [] and {} — so each pair can be
+ * coloured by its nesting depth, and hovering one bracket can show the
+ * other. The caller passes the brackets only, in order, having left out
+ * those in strings and comments.
+ */
+
+/** Opening brackets and the bracket that closes each. */
+const CLOSING_BRACKETS: Record<string, string> = {
+	'(': ')',
+	'[': ']',
+	'{': '}',
+};
+
+/** Every bracket character. */
+export const BRACKET_CHARACTERS = '()[]{}';
+
+/**
+ * A bracket and its partner.
+ */
+export interface BracketPair {
+	/** Nesting depth of the pair (0 = outermost) */
+	depth: number;
+
+	/** Index of the matching bracket (null = unmatched) */
+	partner: number | null;
+}
+
+/**
+ * Pairs up a sequence of brackets.
+ *
+ * A closing bracket that doesn't close the innermost open bracket is
+ * unmatched and leaves it open, as is an opening bracket never closed.
+ *
+ * @param brackets - The brackets, in order (other characters are ignored)
+ * @returns One entry per character of the input
+ */
+export function pairBrackets(brackets: string): BracketPair[] {
+	const pairs: BracketPair[] = [];
+	const open: number[] = [];
+
+	for (let i = 0; i < brackets.length; i++) {
+		const bracket = brackets[i];
+		pairs.push({ depth: open.length, partner: null });
+
+		if (bracket in CLOSING_BRACKETS) {
+			open.push(i);
+			continue;
+		}
+		if (!BRACKET_CHARACTERS.includes(bracket)) continue;
+
+		const innermost = open.length > 0 ? open[open.length - 1] : undefined;
+		if (innermost !== undefined && CLOSING_BRACKETS[brackets[innermost]] === bracket) {
+			open.pop();
+			pairs[i] = { depth: open.length, partner: innermost };
+			pairs[innermost].partner = i;
+		} else {
+			pairs[i] = { depth: 0, partner: null };
+		}
+	}
+
+	return pairs;
+}
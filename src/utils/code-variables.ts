@@ -0,0 +1,129 @@
+/**
+ * Ultra Code Fence - Code Variables
+ *
+ * Fills in variables written in a block's code, so values such as a
+ * cluster name are kept in one place (RENDER.VARIABLES, COPY.VARIABLES):
+ *
+ * - `{{fm.cluster}}`: the note's `cluster` frontmatter field (nested
+ *   fields as `{{fm.deploy.region}}`)
+ * - `{{var.registry}}`: a vault variable from the settings
+ * - `{{date}}` and `{{time}}`, with an optional format such as
+ *   `{{date:YYYY-MM}}`
+ *
+ * Anything else in braces, including copy-time placeholders such as
+ * `{{HOSTNAME}}`, is left alone, as are variables with no value.
+ */
+
+import { CODE_VARIABLE_PATTERN } from '../constants';
+
+/** Where variable values come from. */
+export interface CodeVariableContext {
+	/** The note's frontmatter */
+	frontmatter: Record<string, unknown>;
+
+	/** Vault variables by name */
+	variables: Record<string, string | undefined>;
+
+	/** The current time */
+	now: Date;
+}
+
+/** Format of {{date}} and {{time}} without one of their own. */
+const DEFAULT_DATE_FORMAT = 'YYYY-MM-DD';
+const DEFAULT_TIME_FORMAT = 'HH:mm';
+
+/** Date tokens, longest first so YYYY isn't read as two YYs. */
+const DATE_TOKEN_PATTERN = /YYYY|YY|MM|DD|HH|mm|ss/g;
+
+/**
+ * Parses the vault variables setting, e.g. "registry=ghcr.io/acme, team=platform".
+ *
+ * @param listText - Comma-separated name=value pairs
+ * @returns Value by variable name
+ */
+export function parseCodeVariables(listText: string): Record<string, string | undefined> {
+	const variables: Record<string, string | undefined> = {};
+
+	for (const entry of listText.split(',')) {
+		const match = /^\s*([\w.-]+)\s*=\s*(.*?)\s*$/.exec(entry);
+		if (match) {
+			variables[match[1]] = match[2];
+		}
+	}
+
+	return variables;
+}
+
+/**
+ * Formats a date with YYYY, YY, MM, DD, HH, mm and ss tokens.
+ *
+ * @param date - The date
+ * @param format - Format, e.g. "YYYY-MM-DD HH:mm"
+ * @returns The formatted date, in local time
+ */
+export function formatDateTokens(date: Date, format: string): string {
+	const pad = (value: number): string => String(value).padStart(2, '0');
+	const tokens: Record<string, string> = {
+		YYYY: String(date.getFullYear()),
+		YY: pad(date.getFullYear() % 100),
+		MM: pad(date.getMonth() + 1),
+		DD: pad(date.getDate()),
+		HH: pad(date.getHours()),
+		mm: pad(date.getMinutes()),
+		ss: pad(date.getSeconds()),
+	};
+	return format.replace(DATE_TOKEN_PATTERN, token => tokens[token]);
+}
+
+/**
+ * Reads a frontmatter field by a dotted path.
+ *
+ * @param frontmatter - The note's frontmatter
+ * @param fieldPath - Field path, e.g. "deploy.region"
+ * @returns The value as text, or undefined if missing or not a plain value
+ */
+function readFrontmatterField(frontmatter: Record<string, unknown>, fieldPath: string): string | undefined {
+	let value: unknown = frontmatter;
+	for (const key of fieldPath.split('.')) {
+		value = value && typeof value === 'object' && !Array.isArray(value)
+			? (value as Record<string, unknown>)[key]
+			: undefined;
+	}
+
+	if (Array.isArray(value)) return value.map(item => String(item)).join(', ');
+	return typeof value === 'string' || typeof value === 'number' || typeof value === 'boolean' ? String(value) : undefined;
+}
+
+/**
+ * Finds a variable's value.
+ *
+ * @param name - Variable, e.g. "fm.cluster", "var.registry" or "date"
+ * @param format - Date or time format, if given
+ * @param context - Where values come from
+ * @returns The value, or undefined if the variable has none
+ */
+function resolveCodeVariable(name: string, format: string | undefined, context: CodeVariableContext): string | undefined {
+	if (name === 'date' || name === 'time') {
+		return formatDateTokens(context.now, format?.trim() || (name === 'date' ? DEFAULT_DATE_FORMAT : DEFAULT_TIME_FORMAT));
+	}
+	if (name.startsWith('fm.')) return readFrontmatterField(context.frontmatter, name.slice(3));
+	if (name.startsWith('var.')) return context.variables[name.slice(4)];
+	return undefined;
+}
+
+/**
+ * Fills in the variables in code.
+ *
+ * @param code - Code to fill in
+ * @param context - Where values come from
+ * @returns The code with every variable that has a value filled in
+ *
+ * @example
+ * interpolateCodeVariables('kubectl --context {{fm.cluster}} get pods', context)
+ * // "kubectl --context prod-eu get pods"
+ */
+export function interpolateCodeVariables(code: string, context: CodeVariableContext): string {
+	return code.replace(new RegExp(CODE_VARIABLE_PATTERN.source, 'g'), (variable, name: string, format: string | undefined) =>
+		resolveCodeVariable(name, format, context) ?? variable
+	);
+}
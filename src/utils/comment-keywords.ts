@@ -0,0 +1,63 @@
+/**
+ * Comment keywords for Ultra Code Fence
+ *
+ * Finds the conventional action words in comments — TODO, FIXME, NOTE
+ * and the like — so they can be picked out in their own colour. A
+ * keyword counts when it stands alone in capitals, with an optional
+ * owner and colon after it: "TODO:", "FIXME(alice):".
+ */
+
+import type { CommentKeywordRule } from '../types';
+
+/**
+ * A comment keyword found in some text.
+ */
+export interface CommentKeywordMatch {
+	/** Start offset (inclusive) */
+	start: number;
+
+	/** End offset (exclusive), after any owner and colon */
+	end: number;
+
+	/** The rule for the keyword */
+	rule: CommentKeywordRule;
+}
+
+/** Keywords are words: letters, digits and underscores. */
+const KEYWORD_PATTERN = /^\w+$/;
+
+/**
+ * Finds the comment keywords in some text.
+ *
+ * Keywords match case-sensitively, as written in the rules; rules with
+ * an empty or non-word keyword are skipped, and the first rule for a
+ * keyword wins.
+ *
+ * @param text - Comment text
+ * @param rules - Keyword rules
+ * @returns Matches in text order
+ */
+export function findCommentKeywords(text: string, rules: readonly CommentKeywordRule[]): CommentKeywordMatch[] {
+	const rulesByKeyword = new Map<string, CommentKeywordRule>();
+	for (const rule of rules) {
+		const keyword = rule.keyword.trim();
+		if (KEYWORD_PATTERN.test(keyword) && !rulesByKeyword.has(keyword)) {
+			rulesByKeyword.set(keyword, rule);
+		}
+	}
+	if (rulesByKeyword.size === 0) return [];
+
+	// Longest first, so FIXME_LATER isn't cut short by FIXME
+	const keywords = [...rulesByKeyword.keys()].sort((a, b) => b.length - a.length);
+	const pattern = new RegExp(`(?<![\\w-])(${keywords.join('|')})(?:\\([^)\\n]*\\))?:?(?![\\w-])`, 'g');
+
+	const matches: CommentKeywordMatch[] = [];
+	let match: RegExpExecArray | null;
+	while ((match = pattern.exec(text)) !== null) {
+		const rule = rulesByKeyword.get(match[1]);
+		if (rule) {
+			matches.push({ start: match.index, end: match.index + match[0].length, rule });
+		}
+	}
+	return matches;
+}
@@ -0,0 +1,65 @@
+/**
+ * Ultra Code Fence - Settings Cascade
+ *
+ * The order in which a block's settings layers are merged. Built-in
+ * defaults and plugin settings always sit underneath; above them come the
+ * folder preset, the note's frontmatter, the page's ufence-ufence block,
+ * the preset, the block's settings section and its fence line shorthand.
+ * Each later layer overrides the ones before it. The order can be changed
+ * in settings, and every layer but the block can be switched off.
+ */
+
+import type { CascadeLayer, CascadeLayerSetting } from '../types';
+
+/** The layers that can be ordered, in their default order (lowest first). */
+export const CASCADE_LAYERS: readonly CascadeLayer[] = ['folder', 'frontmatter', 'page', 'preset', 'block', 'shorthand'];
+
+/**
+ * The default order before the folder and shorthand layers had their own
+ * place: the preset lowest, with folder presets and shorthand in it.
+ */
+const FORMER_DEFAULT_LAYERS: readonly CascadeLayer[] = ['preset', 'frontmatter', 'page', 'block'];
+
+/**
+ * Cleans up a stored cascade: drops unknown and repeated layers, adds any
+ * missing layer in its default place, and keeps the block layer enabled.
+ * The former default order, all enabled, becomes the current default.
+ *
+ * @param cascade - Cascade from the settings (possibly from an older release)
+ * @returns A cascade listing each layer once, lowest first
+ */
+export function normalizeConfigCascade(cascade: readonly CascadeLayerSetting[] | undefined): CascadeLayerSetting[] {
+	const isFormerDefault = cascade?.length === FORMER_DEFAULT_LAYERS.length
+		&& cascade.every((entry, index) => entry.layer === FORMER_DEFAULT_LAYERS[index] && entry.enabled);
+	if (isFormerDefault) {
+		return CASCADE_LAYERS.map(layer => ({ layer, enabled: true }));
+	}
+
+	const result: CascadeLayerSetting[] = [];
+
+	for (const entry of cascade ?? []) {
+		if (!CASCADE_LAYERS.includes(entry.layer) || result.some(existing => existing.layer === entry.layer)) continue;
+		result.push({ layer: entry.layer, enabled: entry.layer === 'block' || entry.enabled });
+	}
+
+	CASCADE_LAYERS.forEach((layer, defaultIndex) => {
+		if (result.some(existing => existing.layer === layer)) return;
+		// Insert before the first layer that follows it by default
+		const nextIndex = result.findIndex(existing => CASCADE_LAYERS.indexOf(existing.layer) > defaultIndex);
+		result.splice(nextIndex === -1 ? result.length : nextIndex, 0, { layer, enabled: true });
+	});
+
+	return result;
+}
+
+/**
+ * Lists the enabled layers of a cascade, lowest first.
+ *
+ * @param cascade - Cascade from the settings
+ * @returns Enabled layers
+ */
+export function getActiveCascadeLayers(cascade: readonly CascadeLayerSetting[] | undefined): CascadeLayer[] {
+	return normalizeConfigCascade(cascade)
+		.filter(entry => entry.enabled)
+		.map(entry => entry.layer);
+}
@@ -0,0 +1,203 @@
+/**
+ * Ultra Code Fence - Settings Inspector
+ *
+ * Works out a block's effective settings and which layer each one comes
+ * from: built-in defaults, the plugin settings, the folder preset, the
+ * note's frontmatter, the page's ufence-ufence block, a named preset, the
+ * block's settings section or its fence line shorthand. Mirrors the
+ * cascade order of resolvePreset and the fallbacks of resolveBlockConfig.
+ */
+
+import type { ParsedYamlConfig, PluginSettings, CascadeLayer } from '../types';
+import { resolvePresetConfig } from './preset-resolver';
+import { getActiveCascadeLayers } from './config-cascade';
+import { getDefaultTabSize } from './tab-size';
+
+// =============================================================================
+// Types
+// =============================================================================
+
+/** Where a setting's value comes from (defaults and plugin settings come first). */
+export type ConfigLayer = 'default' | 'settings' | CascadeLayer;
+
+/** A setting with its effective value and the layer that set it. */
+export interface ConfigSourceEntry {
+	/** Dotted setting path, e.g. "RENDER.FOLD" */
+	path: string;
+	/** Effective value */
+	value: unknown;
+	/** Layer the value comes from */
+	layer: ConfigLayer;
+}
+
+/** The layers that make up a block's settings. */
+export interface ConfigInspection {
+	/** Preset the block uses, if any */
+	presetName: string | undefined;
+	/** Effective settings, sorted by path */
+	entries: ConfigSourceEntry[];
+}
+
+// =============================================================================
+// Flattening
+// =============================================================================
+
+/**
+ * Flattens parsed settings into dotted paths. Arrays count as single
+ * values; unset keys are left out. RENDER_CMDOUT keys are listed under
+ * RENDER, where they are written in a block.
+ *
+ * @param config - Parsed settings
+ * @param pathPrefix - Path of this level ("" at the top)
+ * @returns Values keyed by dotted path
+ */
+export function flattenConfig(config: object, pathPrefix = ''): Record<string, unknown> {
+	const flat: Record<string, unknown> = {};
+
+	for (const [key, value] of Object.entries(config)) {
+		if (value === undefined) continue;
+
+		const name = !pathPrefix && key === 'RENDER_CMDOUT' ? 'RENDER' : key;
+		const path = pathPrefix ? `${pathPrefix}.${name}` : name;
+
+		if (value && typeof value === 'object' && !Array.isArray(value)) {
+			Object.assign(flat, flattenConfig(value as object, path));
+		} else {
+			flat[path] = value;
+		}
+	}
+
+	return flat;
+}
+
+// =============================================================================
+// Layers
+// =============================================================================
+
+/**
+ * Lists the settings a block falls back to from the plugin settings.
+ *
+ * @param settings - Plugin settings
+ * @param isCmdout - Whether the block is a ufence-cmdout block
+ * @returns Values keyed by dotted path
+ */
+export function buildSettingsLayer(settings: PluginSettings, isCmdout: boolean): Record<string, unknown> {
+	const shared: Record<string, unknown> = {
+		'META.MODE': settings.configMode,
+		'RENDER.SCROLL': settings.scrollLines,
+		'RENDER.COPY': settings.showCopyButton,
+		'RENDER.PRINT': settings.printBehaviour,
+		'PRINT.THEME': settings.printTheme,
+		'PRINT.AVOID_BREAKS': settings.printAvoidBreaks,
+	};
+
+	if (isCmdout) {
+		return {
+			...shared,
+			'RENDER.PROMPT.COLOUR': settings.commandPromptColour,
+			'RENDER.PROMPT.BOLD': settings.commandPromptBold,
+			'RENDER.PROMPT.ITALIC': settings.commandPromptItalic,
+			'RENDER.COMMAND.COLOUR': settings.commandTextColour,
+			'RENDER.COMMAND.BOLD': settings.commandTextBold,
+			'RENDER.COMMAND.ITALIC': settings.commandTextItalic,
+			'RENDER.OUTPUT.COLOUR': settings.outputTextColour,
+			'RENDER.OUTPUT.BOLD': settings.outputTextBold,
+			'RENDER.OUTPUT.ITALIC': settings.outputTextItalic,
+		};
+	}
+
+	return {
+		...shared,
+		'RENDER.STYLE': settings.defaultTitleBarStyle,
+		'RENDER.FOLD': settings.foldLines,
+		'RENDER.MINIMAP': settings.minimapLines,
+		'RENDER.ZEBRA': settings.showZebraStripes,
+		'RENDER.HOVER': settings.showLineHover,
+		'RENDER.TAB_SIZE': settings.tabSize,
+		'RENDER.LINES': settings.showLineNumbers,
+		'RENDER.LINE_COPY': settings.showLineCopyButtons,
+		'PRINT.LINES': settings.printLineNumbers,
+		'COPY.PLACEHOLDERS': settings.copyPlaceholders,
+		'RENDER.VARIABLES': settings.renderVariables,
+		'DOWNLOAD.FILENAME': settings.downloadFilenameTemplate,
+		'DOWNLOAD.EXECUTABLE': settings.downloadExecutable,
+		'RUN.TIMEOUT': `${String(settings.runTimeoutSeconds)}s`,
+	};
+}
+
+/**
+ * Works out a block's effective settings and where each comes from.
+ *
+ * Plugin settings still at their default value are reported as defaults,
+ * so the "settings" layer shows only what was changed in the settings tab.
+ *
+ * @param blockConfig - Parsed settings of the block
+ * @param settings - Plugin settings
+ * @param defaultSettings - Plugin settings defaults
+ * @param options - Block type, default language, shorthand, page and frontmatter config, default and folder presets, conditions
+ * @returns Preset name and effective settings
+ */
+export function inspectBlockConfig(
+	blockConfig: ParsedYamlConfig,
+	settings: PluginSettings,
+	defaultSettings: PluginSettings,
+	options: { isCmdout: boolean; defaultLanguage?: string; shorthandConfig?: ParsedYamlConfig; pageConfig?: ParsedYamlConfig; frontmatterConfig?: ParsedYamlConfig; defaultPreset?: string; folderPreset?: string; conditions?: ReadonlySet<string> }
+): ConfigInspection {
+	const sources = new Map<string, { value: unknown; layer: ConfigLayer }>();
+	const applyLayer = (values: Record<string, unknown>, layer: ConfigLayer): void => {
+		for (const [path, value] of Object.entries(values)) {
+			sources.set(path, { value, layer });
+		}
+	};
+
+	// Plugin settings, split into untouched defaults and changed values
+	const defaults = buildSettingsLayer(defaultSettings, options.isCmdout);
+	const current = buildSettingsLayer(settings, options.isCmdout);
+	if (!options.isCmdout && options.defaultLanguage) {
+		defaults['RENDER.LANG'] = options.defaultLanguage;
+		current['RENDER.TAB_SIZE'] = getDefaultTabSize(settings, options.defaultLanguage);
+	}
+	applyLayer(defaults, 'default');
+	for (const [path, value] of Object.entries(current)) {
+		if (value !== defaults[path]) {
+			sources.set(path, { value, layer: 'settings' });
+		}
+	}
+
+	// Presets, frontmatter and page config only apply to code blocks (same cascade as resolvePreset)
+	const activeLayers = getActiveCascadeLayers(settings.configCascade)
+		.filter(layer => !options.isCmdout || layer === 'block' || layer === 'shorthand');
+	const ownConfigs: Partial<Record<CascadeLayer, ParsedYamlConfig>> = {
+		frontmatter: options.frontmatterConfig,
+		page: options.pageConfig,
+		block: blockConfig,
+		shorthand: options.shorthandConfig,
+	};
+	const presetName = activeLayers.includes('preset')
+		? activeLayers.reduce<string | undefined>((name, layer) => ownConfigs[layer]?.META?.PRESET ?? name, options.defaultPreset)
+		: undefined;
+
+	for (const layer of activeLayers) {
+		if (layer === 'preset' || layer === 'folder') {
+			const layerPreset = layer === 'preset' ? presetName : options.folderPreset;
+			if (layerPreset) {
+				applyLayer(flattenConfig(resolvePresetConfig(layerPreset, settings.presets, options.conditions)), layer);
+			}
+			continue;
+		}
+
+		const layerConfig = ownConfigs[layer];
+		if (!layerConfig) continue;
+		const layerValues = flattenConfig(layerConfig);
+		if (layer === 'frontmatter' || layer === 'page') {
+			delete layerValues['META.PRESET'];
+		}
+		applyLayer(layerValues, layer);
+	}
+
+	const entries = [...sources.entries()]
+		.map(([path, source]) => ({ path, value: source.value, layer: source.layer }))
+		.sort((a, b) => a.path.localeCompare(b.path));
+
+	return { presetName, entries };
+}
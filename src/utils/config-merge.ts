@@ -7,6 +7,7 @@
 
 import type {
 	ParsedYamlConfig,
+	YamlTokensConfig,
 } from '../types';
 
 /**
@@ -48,6 +49,30 @@ function mergeSection<T>(
 	return merged as T;
 }
 
+/**
+ * Merges two TOKENS sections. Token styles replace each other; the token
+ * styles of the same language are merged token by token.
+ *
+ * @param base The base section
+ * @param override The override section
+ * @returns Merged section, or undefined if both are undefined
+ */
+function mergeTokens(
+	base: YamlTokensConfig | undefined,
+	override: YamlTokensConfig | undefined
+): YamlTokensConfig | undefined {
+	const merged = mergeSection(base, override);
+	if (!merged || !base || !override) return merged;
+
+	for (const [key, value] of Object.entries(override)) {
+		const baseValue = base[key];
+		if (typeof value === 'object' && typeof baseValue === 'object') {
+			merged[key] = { ...baseValue, ...value };
+		}
+	}
+	return merged;
+}
+
 /**
  * Deep-merges two ParsedYamlConfig objects.
  * Override values take precedence over base values.
@@ -55,6 +80,7 @@ function mergeSection<T>(
  *
  * Special handling:
  * - CALLOUT.ENTRIES: if override has ENTRIES, they replace base entirely
+ * - COPY.AS: if override has AS, it replaces base entirely
  * - Arrays: override replaces base (no element-wise merge)
  * - FILTER nested objects (BY_LINES, BY_MARKS): merge property-by-property
  * - RENDER_CMDOUT nested objects (PROMPT, COMMAND, OUTPUT): merge property-by-property
@@ -163,6 +189,69 @@ export function deepMergeYamlConfigs(
 		}
 	}
 
+	// =========================================================================
+	// COPY section (AS is an array, so override replaces base entirely)
+	// =========================================================================
+	result.COPY = mergeSection(base.COPY, override.COPY);
+
+	// =========================================================================
+	// HIGHLIGHT section
+	// =========================================================================
+	result.HIGHLIGHT = mergeSection(base.HIGHLIGHT, override.HIGHLIGHT);
+
+	// =========================================================================
+	// DOWNLOAD section
+	// =========================================================================
+	result.DOWNLOAD = mergeSection(base.DOWNLOAD, override.DOWNLOAD);
+
+	// =========================================================================
+	// HEADER section
+	// =========================================================================
+	result.HEADER = mergeSection(base.HEADER, override.HEADER);
+
+	// =========================================================================
+	// FOOTER section
+	// =========================================================================
+	result.FOOTER = mergeSection(base.FOOTER, override.FOOTER);
+
+	// =========================================================================
+	// ANNOTATIONS section
+	// =========================================================================
+	result.ANNOTATIONS = mergeSection(base.ANNOTATIONS, override.ANNOTATIONS);
+
+	// =========================================================================
+	// BLAME section
+	// =========================================================================
+	result.BLAME = mergeSection(base.BLAME, override.BLAME);
+
+	// =========================================================================
+	// TOKENS section (a language's token styles merge token by token)
+	// =========================================================================
+	result.TOKENS = mergeTokens(base.TOKENS, override.TOKENS);
+
+	// =========================================================================
+	// PATTERNS section
+	// =========================================================================
+	result.PATTERNS = mergeSection(base.PATTERNS, override.PATTERNS);
+
+	// =========================================================================
+	// DIFF section
+	// =========================================================================
+	result.DIFF = mergeSection(base.DIFF, override.DIFF);
+
+	// =========================================================================
+	// PRINT section
+	// =========================================================================
+	result.PRINT = mergeSection(base.PRINT, override.PRINT);
+
+	// =========================================================================
+	// RUN section (ENV merges variable by variable, so a block can add to a preset's)
+	// =========================================================================
+	result.RUN = mergeSection(base.RUN, override.RUN);
+	if (result.RUN && base.RUN?.ENV && override.RUN?.ENV) {
+		result.RUN = { ...result.RUN, ENV: { ...base.RUN.ENV, ...override.RUN.ENV } };
+	}
+
 	// =========================================================================
 	// Top-level PROMPT
 	// =========================================================================
@@ -0,0 +1,94 @@
+/**
+ * Ultra Code Fence - Renamed Settings
+ *
+ * Rewrites settings keys that were renamed between releases (see
+ * CONFIG_RENAMES) to their new names in a block's YAML source, keeping
+ * the rest of the text — values, comments, indentation — untouched.
+ */
+
+import { CONFIG_RENAMES } from '../constants';
+
+/** A YAML key line: "  - KEY: value" */
+const KEY_LINE_PATTERN = /^(\s*)(-\s+)?([A-Za-z_]\w*)(\s*:.*)$/;
+
+/** Stands in for a list entry in key paths, so renames never match inside lists */
+const LIST_ENTRY_SEGMENT = '-';
+
+/** A YAML key line with its dotted path. */
+export interface KeyLine {
+	/** Line index */
+	index: number;
+	/** Dotted path of the key's parent ("" at the top) */
+	parentPath: string;
+	/** Text before the key (indentation and list dash) */
+	prefix: string;
+	/** Key name */
+	key: string;
+	/** Text after the key (colon and value) */
+	rest: string;
+}
+
+/**
+ * Finds the key lines of a YAML settings section and works out each
+ * key's path from its indentation.
+ *
+ * @param lines - Settings lines (stops at a ~~~ separator)
+ * @returns Key lines in order
+ */
+export function findKeyLines(lines: string[]): KeyLine[] {
+	const keyLines: KeyLine[] = [];
+	const stack: { indent: number; key: string }[] = [];
+
+	for (let index = 0; index < lines.length; index++) {
+		if (lines[index].trimStart().startsWith('~~~')) break;
+
+		const match = KEY_LINE_PATTERN.exec(lines[index]);
+		if (!match) continue;
+
+		const [, indentText, dash, key, rest] = match;
+		const dashText = dash ?? '';
+		const keyColumn = indentText.length + dashText.length;
+
+		while (stack.length > 0 && stack[stack.length - 1].indent >= (dash ? indentText.length : keyColumn)) {
+			stack.pop();
+		}
+		if (dash) stack.push({ indent: indentText.length, key: LIST_ENTRY_SEGMENT });
+
+		keyLines.push({
+			index,
+			parentPath: stack.map(entry => entry.key).join('.'),
+			prefix: indentText + dashText,
+			key,
+			rest,
+		});
+		stack.push({ indent: keyColumn, key });
+	}
+
+	return keyLines;
+}
+
+/**
+ * Rewrites renamed keys in a YAML settings section to their new names.
+ *
+ * A key is left alone when its new name is already used at the same
+ * level, so the result never has duplicate keys.
+ *
+ * @param blockText - Block text (settings, optionally followed by ~~~ and code)
+ * @returns Block text with renamed keys updated
+ */
+export function renameDeprecatedKeys(blockText: string): string {
+	const lines = blockText.split('\n');
+	const keyLines = findKeyLines(lines);
+	const paths = new Set(keyLines.map(line => `${line.parentPath}.${line.key}`));
+
+	for (const line of keyLines) {
+		const path = line.parentPath ? `${line.parentPath}.${line.key}` : line.key;
+		const renamedTo: string | undefined = CONFIG_RENAMES[path];
+		if (renamedTo === undefined || paths.has(`${line.parentPath}.${renamedTo}`)) continue;
+
+		lines[line.index] = line.prefix + renamedTo + line.rest;
+		paths.add(`${line.parentPath}.${renamedTo}`);
+	}
+
+	return lines.join('\n');
+}
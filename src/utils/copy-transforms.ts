@@ -0,0 +1,609 @@
+/**
+ * Ultra Code Fence - Copy Transforms
+ *
+ * Pure text transformations applied to code before it is placed on the
+ * clipboard. Clean-up steps (like prompt stripping) apply to every copy;
+ * "Copy as…" transforms turn a block of shell commands into a snippet
+ * ready to paste into another file format, or encode the whole block
+ * for pasting into API bodies and URLs.
+ */
+
+import type { ResolvedCopyAsEntry, CommentSyntax } from '../types';
+import { PLACEHOLDER_PATTERN, REGION_START_PATTERN, REGION_END_PATTERN, REDACTION_MARK } from '../constants';
+
+// =============================================================================
+// Types
+// =============================================================================
+
+/**
+ * A copy transformation: takes the block text and the entry's options,
+ * returns the text to put on the clipboard.
+ */
+export type CopyTransform = (codeText: string, entry: ResolvedCopyAsEntry) => string;
+
+/**
+ * A named region of a block, delimited by `region:` / `endregion` comments.
+ */
+export interface CodeRegion {
+	/** Name given after `region:` */
+	name: string;
+
+	/** Lines inside the region, without any region marker lines */
+	text: string;
+}
+
+/**
+ * Clean-up applied to every copy from a block (whole block, per line,
+ * and "copy as…"), before any join or transform.
+ */
+export interface CopyCleanupOptions {
+	/** Prompt regex; matching prompts are removed from copied lines */
+	promptPattern?: RegExp;
+
+	/** Comment syntax; whole-line comments are removed from copies */
+	commentSyntax?: CommentSyntax;
+
+	/** Secret patterns; matches are replaced with «REDACTED» */
+	redactPatterns?: RegExp[];
+}
+
+// =============================================================================
+// Prompt Stripping
+// =============================================================================
+
+/**
+ * Finds the length of the prompt at the start of a line.
+ *
+ * Uses the same PROMPT convention as cmdout blocks: with two capture
+ * groups `(prompt)(command)` the first group is the prompt; otherwise
+ * the whole match is. Only matches at the start of the line count.
+ *
+ * @param line - A single line of code
+ * @param promptPattern - Prompt regex
+ * @returns Number of prompt characters (0 = no prompt)
+ */
+export function findPromptLength(line: string, promptPattern: RegExp): number {
+	const match = promptPattern.exec(line);
+	if (!match || match.index !== 0) return 0;
+
+	const prompt = match.length >= 3 && match[1] !== undefined ? match[1] : match[0];
+	return line.startsWith(prompt) ? prompt.length : 0;
+}
+
+/**
+ * Removes prompts from every line of code.
+ *
+ * @param codeText - Raw code text
+ * @param promptPattern - Prompt regex
+ * @returns Code with prompts removed
+ */
+export function stripPrompts(codeText: string, promptPattern: RegExp): string {
+	return codeText
+		.split('\n')
+		.map(line => line.slice(findPromptLength(line, promptPattern)))
+		.join('\n');
+}
+
+// =============================================================================
+// Comment Stripping
+// =============================================================================
+
+/**
+ * Removes whole-line comments from code.
+ *
+ * A line is dropped if, ignoring indentation, it starts with a line
+ * comment prefix or lies within a block comment that starts at the
+ * beginning of a line. Only lines that are all comment go: a line with
+ * code after a block comment keeps its code, trailing comments after
+ * code are kept, and so is a shebang on the first line.
+ *
+ * @param codeText - Raw code text
+ * @param syntax - Comment syntax for the block's language
+ * @returns Code without comment lines
+ */
+export function stripCommentLines(codeText: string, syntax: CommentSyntax): string {
+	const keptLines: string[] = [];
+	const { block } = syntax;
+	let inBlock = false;
+
+	const isLineComment = (text: string): boolean => syntax.line.some(prefix => text.startsWith(prefix));
+
+	codeText.split('\n').forEach((line, index) => {
+		const trimmed = line.trim();
+
+		if (inBlock) {
+			const closeAt = block ? line.indexOf(block[1]) : -1;
+			if (closeAt === -1 || !block) return;
+			inBlock = false;
+
+			// Code after the comment's end is kept, at the line's indentation
+			const after = line.slice(closeAt + block[1].length).trim();
+			if (after && !isLineComment(after)) {
+				keptLines.push(`${line.slice(0, line.length - line.trimStart().length)}${after}`);
+			}
+			return;
+		}
+
+		if (index === 0 && trimmed.startsWith('#!')) {
+			keptLines.push(line);
+			return;
+		}
+
+		if (block && trimmed.startsWith(block[0])) {
+			const closeAt = trimmed.indexOf(block[1], block[0].length);
+			if (closeAt === -1) {
+				inBlock = true;
+				return;
+			}
+
+			const after = trimmed.slice(closeAt + block[1].length).trim();
+			if (after && !isLineComment(after)) keptLines.push(line);
+			return;
+		}
+
+		if (isLineComment(trimmed)) {
+			return;
+		}
+
+		keptLines.push(line);
+	});
+
+	return keptLines.join('\n');
+}
+
+/**
+ * Applies a block's copy-time clean-up to text bound for the clipboard.
+ *
+ * @param codeText - Raw code text (whole block or a single line)
+ * @param options - Clean-up options
+ * @returns Cleaned text
+ */
+export function cleanupCopyText(codeText: string, options?: CopyCleanupOptions): string {
+	let text = codeText;
+
+	if (options?.promptPattern) {
+		text = stripPrompts(text, options.promptPattern);
+	}
+
+	// After prompts, so "$ # note" counts as a comment line
+	if (options?.commentSyntax) {
+		text = stripCommentLines(text, options.commentSyntax);
+	}
+
+	if (options?.redactPatterns && options.redactPatterns.length > 0) {
+		text = redactSecrets(text, options.redactPatterns);
+	}
+
+	return text;
+}
+
+// =============================================================================
+// Redaction
+// =============================================================================
+
+/** A match with the group offsets the d flag adds (ES2022, newer than the lib this targets). */
+type IndexedMatch = RegExpExecArray & { indices?: ([number, number] | undefined)[] };
+
+/**
+ * Finds the text ranges matched by secret patterns.
+ *
+ * If a pattern has a capture group, only the first group is redacted,
+ * so `password: (\S+)` keeps the key visible. Overlapping matches are
+ * merged.
+ *
+ * @param codeText - Raw code text
+ * @param patterns - Secret patterns
+ * @returns Sorted, non-overlapping [start, end) ranges
+ */
+export function findRedactions(codeText: string, patterns: RegExp[]): [number, number][] {
+	const ranges: [number, number][] = [];
+
+	for (const source of patterns) {
+		// The d flag gives each group's offsets, so the group itself is redacted, not an earlier copy of its text
+		const flags = ['g', 'd'].reduce((all, flag) => (all.includes(flag) ? all : all + flag), source.flags);
+		const pattern = new RegExp(source.source, flags);
+
+		let match: IndexedMatch | null;
+		while ((match = pattern.exec(codeText)) !== null) {
+			// Guard against zero-length matches looping forever
+			if (match[0] === '') {
+				pattern.lastIndex++;
+				continue;
+			}
+
+			const groupRange = match.indices?.[1];
+			const range: [number, number] = groupRange ?? [match.index, match.index + match[0].length];
+			if (range[1] > range[0]) {
+				ranges.push([range[0], range[1]]);
+			}
+		}
+	}
+
+	ranges.sort((a, b) => a[0] - b[0]);
+
+	const merged: [number, number][] = [];
+	for (const range of ranges) {
+		const previous = merged.length > 0 ? merged[merged.length - 1] : undefined;
+		if (previous && range[0] <= previous[1]) {
+			previous[1] = Math.max(previous[1], range[1]);
+		} else {
+			merged.push([range[0], range[1]]);
+		}
+	}
+
+	return merged;
+}
+
+/**
+ * Replaces secret pattern matches with «REDACTED».
+ *
+ * @param codeText - Raw code text
+ * @param patterns - Secret patterns
+ * @returns Code with secrets redacted
+ */
+export function redactSecrets(codeText: string, patterns: RegExp[]): string {
+	let text = codeText;
+
+	// Replace from the end so earlier offsets stay valid
+	for (const [start, end] of findRedactions(codeText, patterns).reverse()) {
+		text = text.slice(0, start) + REDACTION_MARK + text.slice(end);
+	}
+
+	return text;
+}
+
+// =============================================================================
+// Placeholders
+// =============================================================================
+
+/**
+ * Lists the {{PLACEHOLDER}} names used in code, in order of first use.
+ *
+ * @param codeText - Raw code text
+ * @returns Unique placeholder names
+ */
+export function findPlaceholders(codeText: string): string[] {
+	const pattern = new RegExp(PLACEHOLDER_PATTERN.source, 'g');
+	const names: string[] = [];
+
+	let match: RegExpExecArray | null;
+	while ((match = pattern.exec(codeText)) !== null) {
+		if (!names.includes(match[1])) {
+			names.push(match[1]);
+		}
+	}
+
+	return names;
+}
+
+/**
+ * Replaces {{PLACEHOLDER}}s with supplied values.
+ *
+ * Placeholders without a value are left in place.
+ *
+ * @param codeText - Raw code text
+ * @param values - Values keyed by placeholder name
+ * @returns Code with placeholders substituted
+ */
+export function substitutePlaceholders(codeText: string, values: Record<string, string | undefined>): string {
+	return codeText.replace(new RegExp(PLACEHOLDER_PATTERN.source, 'g'), (placeholder, name: string) =>
+		values[name] ?? placeholder
+	);
+}
+
+// =============================================================================
+// Regions
+// =============================================================================
+
+/**
+ * Finds the named regions in code, in order of their start markers.
+ *
+ * Regions may nest; an inner region's lines also belong to the outer
+ * one, but marker lines are never part of any region's text. A region
+ * without an `endregion` runs to the end of the block.
+ *
+ * @param codeText - Raw code text
+ * @returns Regions with their text
+ */
+export function findRegions(codeText: string): CodeRegion[] {
+	type OpenRegion = { name: string; lines: string[] };
+	const regions: OpenRegion[] = [];
+	const openRegions: OpenRegion[] = [];
+
+	for (const line of codeText.split('\n')) {
+		const startMatch = REGION_START_PATTERN.exec(line);
+
+		if (startMatch) {
+			const region: OpenRegion = { name: startMatch[1] || `Region ${String(regions.length + 1)}`, lines: [] };
+			regions.push(region);
+			openRegions.push(region);
+			continue;
+		}
+
+		if (REGION_END_PATTERN.test(line)) {
+			openRegions.pop();
+			continue;
+		}
+
+		openRegions.forEach(region => region.lines.push(line));
+	}
+
+	return regions.map(region => ({ name: region.name, text: region.lines.join('\n') }));
+}
+
+/**
+ * Finds which lines of a block belong to a named region.
+ *
+ * Names match case-insensitively; lines of regions nested inside it
+ * count, marker lines never do.
+ *
+ * @param lines - Line texts
+ * @param name - Region name
+ * @returns 0-based indices of the region's lines (empty if there is no such region)
+ */
+export function findRegionLineIndices(lines: string[], name: string): number[] {
+	const target = name.trim().toLowerCase();
+	const openNames: string[] = [];
+	const indices: number[] = [];
+
+	lines.forEach((line, index) => {
+		const startMatch = REGION_START_PATTERN.exec(line);
+
+		if (startMatch) {
+			openNames.push(startMatch[1].toLowerCase());
+			return;
+		}
+
+		if (REGION_END_PATTERN.test(line)) {
+			openNames.pop();
+			return;
+		}
+
+		if (openNames.includes(target)) indices.push(index);
+	});
+
+	return indices;
+}
+
+// =============================================================================
+// Helpers
+// =============================================================================
+
+/**
+ * Splits code into trimmed command lines, dropping blanks and shell
+ * comments. A line ending in `\` is joined to the next, so a command
+ * continued over several lines comes back as one.
+ *
+ * @param codeText - Raw code text
+ * @returns Non-empty, non-comment lines
+ */
+export function extractCommandLines(codeText: string): string[] {
+	const commands: string[] = [];
+	let pending = '';
+
+	for (const rawLine of codeText.split('\n')) {
+		const line = rawLine.trim();
+		if (!pending && (line.length === 0 || line.startsWith('#'))) continue;
+
+		if (line.endsWith('\\')) {
+			pending += `${line.slice(0, -1).trimEnd()} `;
+			continue;
+		}
+
+		const command = (pending + line).trim();
+		pending = '';
+		if (command) commands.push(command);
+	}
+
+	const last = pending.trim();
+	if (last) commands.push(last);
+	return commands;
+}
+
+// =============================================================================
+// Transforms
+// =============================================================================
+
+/**
+ * Wraps commands as a single Dockerfile RUN instruction.
+ *
+ * @example
+ * // apt update / apt install -y curl  →
+ * // RUN apt update \
+ * //     && apt install -y curl
+ */
+function toDockerfileRun(codeText: string): string {
+	const commands = extractCommandLines(codeText);
+	if (commands.length === 0) return '';
+
+	return `RUN ${commands.join(' \\\n    && ')}`;
+}
+
+/**
+ * Emits commands as a phony Makefile target.
+ *
+ * Recipe lines are tab-indented and `$` is doubled so shell variables
+ * survive Make's own variable expansion.
+ */
+function toMakefileTarget(codeText: string, entry: ResolvedCopyAsEntry): string {
+	const commands = extractCommandLines(codeText);
+	const target = entry.target || 'run';
+	const recipe = commands.map(command => `\t${command.replace(/\$/g, '$$$$')}`);
+
+	return [`.PHONY: ${target}`, `${target}:`, ...recipe].join('\n');
+}
+
+/**
+ * Emits commands as a single crontab entry.
+ *
+ * Commands are chained with `&&` and `%` is escaped, since cron treats
+ * an unescaped `%` as a newline.
+ */
+function toCrontabEntry(codeText: string, entry: ResolvedCopyAsEntry): string {
+	const commands = extractCommandLines(codeText);
+	const schedule = entry.schedule || '0 0 * * *';
+	const command = commands.join(' && ').replace(/%/g, '\\%');
+
+	return `${schedule} ${command}`;
+}
+
+/**
+ * Wraps the block in a quoted heredoc that writes it to TARGET.
+ *
+ * The quoted delimiter stops the shell expanding `$` and backticks, so
+ * the content lands exactly as written. The delimiter is renamed if
+ * the content already contains an `EOF` line. Without a TARGET the
+ * heredoc is simply printed.
+ *
+ * @example
+ * // TARGET: /etc/app.conf  →
+ * // cat <<'EOF' > /etc/app.conf
+ * // …block content…
+ * // EOF
+ */
+function toHeredoc(codeText: string, entry: ResolvedCopyAsEntry): string {
+	const lines = codeText.split('\n');
+
+	let delimiter = 'EOF';
+	for (let suffix = 1; lines.includes(delimiter); suffix++) {
+		delimiter = `EOF_${String(suffix)}`;
+	}
+
+	const redirect = entry.target ? ` > ${quoteShellPath(entry.target)}` : '';
+	return [`cat <<'${delimiter}'${redirect}`, codeText, delimiter].join('\n');
+}
+
+/**
+ * Single-quotes a path for the shell if it contains special characters.
+ */
+function quoteShellPath(path: string): string {
+	if (/^[\w@%+=:,./~-]+$/.test(path)) return path;
+	return `'${path.replace(/'/g, `'\\''`)}'`;
+}
+
+/**
+ * Emits the block as a JSON string literal, quotes included.
+ */
+function toJsonString(codeText: string): string {
+	return JSON.stringify(codeText);
+}
+
+/**
+ * Emits the block as base64 of its UTF-8 bytes.
+ */
+function toBase64(codeText: string): string {
+	const bytes = new TextEncoder().encode(codeText);
+
+	let binary = '';
+	bytes.forEach(byte => {
+		binary += String.fromCharCode(byte);
+	});
+
+	return btoa(binary);
+}
+
+/**
+ * Emits the block percent-encoded for use in a URL or form body.
+ */
+function toUrlEncoded(codeText: string): string {
+	return encodeURIComponent(codeText);
+}
+
+/**
+ * Plain-text flavour of a rich text copy. The HTML flavour, with syntax
+ * colours, is built from the rendered block by the copy-as button.
+ */
+function toRichTextPlain(codeText: string): string {
+	return codeText;
+}
+
+// =============================================================================
+// Registry
+// =============================================================================
+
+/**
+ * Available copy transforms, keyed by COPY.AS FORMAT value.
+ */
+export const COPY_AS_TRANSFORMS: Record<string, CopyTransform | undefined> = {
+	dockerfile: toDockerfileRun,
+	makefile: toMakefileTarget,
+	crontab: toCrontabEntry,
+	heredoc: toHeredoc,
+	json: toJsonString,
+	base64: toBase64,
+	url: toUrlEncoded,
+	richtext: toRichTextPlain,
+};
+
+/**
+ * Default menu labels per format, used when an entry has no LABEL.
+ */
+export const COPY_AS_DEFAULT_LABELS: Record<string, string | undefined> = {
+	dockerfile: 'Dockerfile RUN',
+	makefile: 'Makefile target',
+	crontab: 'Crontab entry',
+	heredoc: 'Heredoc',
+	json: 'JSON string',
+	base64: 'Base64',
+	url: 'URL-encoded',
+	richtext: 'Rich text',
+};
+
+/** Formats built into the plugin, which other plugins can't replace. */
+const BUILT_IN_COPY_AS_FORMATS = new Set(Object.keys(COPY_AS_TRANSFORMS));
+
+/**
+ * Adds a "copy as" format contributed by another plugin.
+ *
+ * @param format - Format name, as written in COPY.AS FORMAT (case-insensitive)
+ * @param transform - The transformation
+ * @param label - Default menu label
+ * @returns True if added; false for a built-in format
+ */
+export function registerCopyAsTransform(format: string, transform: CopyTransform, label: string): boolean {
+	const key = format.trim().toLowerCase();
+	if (key === '' || BUILT_IN_COPY_AS_FORMATS.has(key)) return false;
+
+	COPY_AS_TRANSFORMS[key] = transform;
+	COPY_AS_DEFAULT_LABELS[key] = label;
+	return true;
+}
+
+/**
+ * Removes a "copy as" format contributed by another plugin, if it is
+ * still the one registered.
+ *
+ * @param format - Format name (case-insensitive)
+ * @param transform - The transformation that was registered
+ */
+export function unregisterCopyAsTransform(format: string, transform: CopyTransform): void {
+	const key = format.trim().toLowerCase();
+	if (BUILT_IN_COPY_AS_FORMATS.has(key) || COPY_AS_TRANSFORMS[key] !== transform) return;
+
+	Reflect.deleteProperty(COPY_AS_TRANSFORMS, key);
+	Reflect.deleteProperty(COPY_AS_DEFAULT_LABELS, key);
+}
+
+/**
+ * Checks whether a format name has a registered transform.
+ *
+ * @param format - Format name (case-insensitive)
+ * @returns True if the format can be applied
+ */
+export function isKnownCopyAsFormat(format: string): boolean {
+	return COPY_AS_TRANSFORMS[format.toLowerCase()] !== undefined;
+}
+
+/**
+ * Applies a "copy as" transformation to code text.
+ *
+ * Unknown formats return the text unchanged.
+ *
+ * @param codeText - Raw code text
+ * @param entry - Resolved transformation entry
+ * @returns Transformed text for the clipboard
+ */
+export function applyCopyAsTransform(codeText: string, entry: ResolvedCopyAsEntry): string {
+	const transform = COPY_AS_TRANSFORMS[entry.format];
+	return transform ? transform(codeText, entry) : codeText;
+}
@@ -0,0 +1,288 @@
+/**
+ * Diff rendering for Ultra Code Fence
+ *
+ * Works out what each line of a unified diff is (added, removed, context,
+ * hunk header or file header), so diff blocks can be coloured with +/-
+ * gutters like a code review tool.
+ */
+
+import { WORD_DIFF_MAX_TOKENS } from '../constants';
+
+/**
+ * What a line of a diff is.
+ */
+export type DiffLineKind = 'added' | 'removed' | 'context' | 'hunk' | 'header';
+
+/** Lines inside a hunk that start with one of these carry a +/- or space marker. */
+const DIFF_MARKERS = ['+', '-', ' '];
+
+/**
+ * Classifies each line of a unified diff.
+ *
+ * Outside a hunk, lines are file headers (diff --git, index, ---, +++).
+ * A snippet with no @@ hunk headers at all is read as one hunk, so a
+ * plain list of +/- lines works too.
+ *
+ * @param lines - Line texts
+ * @returns Kind of each line
+ */
+export function classifyDiffLines(lines: string[]): DiffLineKind[] {
+	let inHunk = !lines.some(line => line.startsWith('@@'));
+
+	return lines.map(line => {
+		if (line.startsWith('@@')) {
+			inHunk = true;
+			return 'hunk';
+		}
+
+		if (line.startsWith('diff ')) {
+			inHunk = false;
+			return 'header';
+		}
+
+		if (!inHunk || line.startsWith('\\')) return 'header';
+		if (line.startsWith('+')) return 'added';
+		if (line.startsWith('-')) return 'removed';
+		return 'context';
+	});
+}
+
+/**
+ * Checks whether a diff line starts with a +/- or space marker.
+ *
+ * @param line - Line text
+ * @param kind - Kind of the line (from classifyDiffLines)
+ * @returns True when the first character is the line's marker
+ */
+export function hasDiffMarker(line: string, kind: DiffLineKind): boolean {
+	return (kind === 'added' || kind === 'removed' || kind === 'context')
+		&& DIFF_MARKERS.includes(line.charAt(0));
+}
+
+// =============================================================================
+// Side-by-side Rows
+// =============================================================================
+
+/**
+ * One step of an edit script between an old and a new list of lines.
+ */
+export interface DiffStep {
+	/** What the step does; hunk steps are unified diff @@ headers */
+	kind: 'context' | 'removed' | 'added' | 'hunk';
+
+	/** Line on the old side (null for added lines) */
+	oldIndex: number | null;
+
+	/** Line on the new side (null for removed lines) */
+	newIndex: number | null;
+}
+
+/**
+ * One row of a side-by-side diff.
+ */
+export interface SplitDiffRow {
+	/** Context rows show the same text on both sides; changed rows pair a removed and an added line */
+	kind: 'context' | 'changed' | 'hunk';
+
+	/** Line shown on the old side (null = a gap) */
+	oldIndex: number | null;
+
+	/** Line shown on the new side (null = a gap) */
+	newIndex: number | null;
+}
+
+/**
+ * Turns the lines of a unified diff into an edit script. Both sides
+ * refer to the diff's own lines; file headers are left out.
+ *
+ * @param kinds - Kind of each line (from classifyDiffLines)
+ * @returns Edit script
+ */
+export function unifiedDiffSteps(kinds: DiffLineKind[]): DiffStep[] {
+	const steps: DiffStep[] = [];
+
+	kinds.forEach((kind, index) => {
+		if (kind === 'header') return;
+		steps.push({
+			kind,
+			oldIndex: kind === 'added' ? null : index,
+			newIndex: kind === 'removed' ? null : index,
+		});
+	});
+
+	return steps;
+}
+
+/**
+ * Compares two lists of lines, keeping the longest run of unchanged
+ * lines in common.
+ *
+ * @param oldLines - Lines before the change
+ * @param newLines - Lines after the change
+ * @returns Edit script, with indices into each list
+ */
+export function diffLineLists(oldLines: string[], newLines: string[]): DiffStep[] {
+	// common[i][j] = unchanged lines shared by oldLines from i and newLines from j
+	const common = Array.from({ length: oldLines.length + 1 }, () => new Array<number>(newLines.length + 1).fill(0));
+	for (let i = oldLines.length - 1; i >= 0; i--) {
+		for (let j = newLines.length - 1; j >= 0; j--) {
+			common[i][j] = oldLines[i] === newLines[j]
+				? common[i + 1][j + 1] + 1
+				: Math.max(common[i + 1][j], common[i][j + 1]);
+		}
+	}
+
+	const steps: DiffStep[] = [];
+	let i = 0;
+	let j = 0;
+
+	while (i < oldLines.length || j < newLines.length) {
+		if (i < oldLines.length && j < newLines.length && oldLines[i] === newLines[j]) {
+			steps.push({ kind: 'context', oldIndex: i++, newIndex: j++ });
+		} else if (j >= newLines.length || (i < oldLines.length && common[i + 1][j] >= common[i][j + 1])) {
+			steps.push({ kind: 'removed', oldIndex: i++, newIndex: null });
+		} else {
+			steps.push({ kind: 'added', oldIndex: null, newIndex: j++ });
+		}
+	}
+
+	return steps;
+}
+
+/**
+ * Lays an edit script out as side-by-side rows. Each run of removed
+ * lines is paired with the added lines that follow it, so a changed line
+ * sits opposite its replacement; the shorter side gets gaps.
+ *
+ * @param steps - Edit script
+ * @returns Rows, top to bottom
+ */
+export function buildSplitDiffRows(steps: DiffStep[]): SplitDiffRow[] {
+	const rows: SplitDiffRow[] = [];
+	let removed: number[] = [];
+	let added: number[] = [];
+
+	const flushChanges = (): void => {
+		for (let k = 0; k < Math.max(removed.length, added.length); k++) {
+			rows.push({
+				kind: 'changed',
+				oldIndex: k < removed.length ? removed[k] : null,
+				newIndex: k < added.length ? added[k] : null,
+			});
+		}
+		removed = [];
+		added = [];
+	};
+
+	for (const step of steps) {
+		if (step.kind === 'removed' && step.oldIndex !== null) {
+			// A removal after additions starts a new change
+			if (added.length > 0) flushChanges();
+			removed.push(step.oldIndex);
+		} else if (step.kind === 'added' && step.newIndex !== null) {
+			added.push(step.newIndex);
+		} else {
+			flushChanges();
+			rows.push({ kind: step.kind === 'hunk' ? 'hunk' : 'context', oldIndex: step.oldIndex, newIndex: step.newIndex });
+		}
+	}
+	flushChanges();
+
+	return rows;
+}
+
+// =============================================================================
+// Word Changes
+// =============================================================================
+
+/**
+ * A run of characters within a line.
+ */
+export interface DiffTextRange {
+	/** Start offset (inclusive) */
+	start: number;
+
+	/** End offset (exclusive) */
+	end: number;
+}
+
+/**
+ * Changed parts of a removed line and the added line that replaced it.
+ */
+export interface WordDiff {
+	/** Parts of the old line that were removed */
+	removed: DiffTextRange[];
+
+	/** Parts of the new line that were added */
+	added: DiffTextRange[];
+}
+
+/** Words, runs of whitespace and single punctuation characters. */
+const WORD_TOKEN_PATTERN = /\w+|\s+|[^\w\s]/g;
+
+/**
+ * Turns token indices into character ranges, joining neighbouring tokens.
+ *
+ * @param tokens - The line's tokens
+ * @param changed - Indices of the changed tokens, in order
+ * @returns Changed character ranges
+ */
+function tokenRanges(tokens: string[], changed: number[]): DiffTextRange[] {
+	const offsets: number[] = [];
+	let offset = 0;
+	for (const token of tokens) {
+		offsets.push(offset);
+		offset += token.length;
+	}
+
+	const ranges: DiffTextRange[] = [];
+	for (const index of changed) {
+		const start = offsets[index];
+		const end = start + tokens[index].length;
+		const last = ranges.length > 0 ? ranges[ranges.length - 1] : null;
+
+		if (last?.end === start) {
+			last.end = end;
+		} else {
+			ranges.push({ start, end });
+		}
+	}
+
+	return ranges;
+}
+
+/**
+ * Finds the words that changed between a removed line and its
+ * replacement.
+ *
+ * Lines are compared a word at a time. When they share no words, or are
+ * too long to compare, null is returned: the whole line changed, and
+ * marking every word wouldn't help.
+ *
+ * @param oldText - Removed line, without its diff marker
+ * @param newText - Added line, without its diff marker
+ * @returns Changed ranges on each side, or null
+ */
+export function diffWords(oldText: string, newText: string): WordDiff | null {
+	const oldTokens = oldText.match(WORD_TOKEN_PATTERN) ?? [];
+	const newTokens = newText.match(WORD_TOKEN_PATTERN) ?? [];
+	if (oldTokens.length > WORD_DIFF_MAX_TOKENS || newTokens.length > WORD_DIFF_MAX_TOKENS) return null;
+
+	const removed: number[] = [];
+	const added: number[] = [];
+	let sharesWords = false;
+
+	for (const step of diffLineLists(oldTokens, newTokens)) {
+		if (step.kind === 'context' && step.oldIndex !== null) {
+			sharesWords = sharesWords || oldTokens[step.oldIndex].trim() !== '';
+		} else if (step.kind === 'removed' && step.oldIndex !== null) {
+			removed.push(step.oldIndex);
+		} else if (step.kind === 'added' && step.newIndex !== null) {
+			added.push(step.newIndex);
+		}
+	}
+
+	if (!sharesWords || (removed.length === 0 && added.length === 0)) return null;
+
+	return { removed: tokenRanges(oldTokens, removed), added: tokenRanges(newTokens, added) };
+}
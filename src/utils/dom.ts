@@ -44,6 +44,25 @@ export function addScrollBehaviour(preElement: HTMLPreElement, maxVisibleLines:
 	});
 }
 
+// =============================================================================
+// Print Profile
+// =============================================================================
+
+/**
+ * Marks a block for the print stylesheet (PRINT.THEME, PRINT.AVOID_BREAKS).
+ *
+ * Everything happens in @media print, so the block looks the same on
+ * screen either way.
+ *
+ * @param blockElement - Element holding the block's title bar and code
+ * @param theme - 'light' to print in light colours, anything else as displayed
+ * @param avoidBreaks - Keep the block on one page where it fits
+ */
+export function applyPrintProfile(blockElement: HTMLElement, theme: string, avoidBreaks: boolean): void {
+	blockElement.classList.toggle(CSS_CLASSES.printLight, theme === 'light');
+	blockElement.classList.toggle(CSS_CLASSES.printAvoidBreaks, avoidBreaks);
+}
+
 // =============================================================================
 // Line Wrapping
 // =============================================================================
@@ -60,6 +79,16 @@ export interface LineWrappingOptions {
 
 	/** Starting line number (default: 1) */
 	startingLineNumber?: number;
+
+	/**
+	 * Block line (1-based) that numbers count from. It shows its own number
+	 * and the other lines their distance from it, e.g. "+3" (default: 0,
+	 * absolute numbers)
+	 */
+	anchorLine?: number;
+
+	/** Wrap lines even when numbers and stripes are off (for per-line features) */
+	forceLineWrapping?: boolean;
 }
 
 /**
@@ -80,7 +109,7 @@ export interface LineWrappingOptions {
  * @param options - Line wrapping options
  */
 export function wrapCodeLinesInDom(codeElement: HTMLElement, options: LineWrappingOptions): void {
-	const { showLineNumbers, showZebraStripes, startingLineNumber = 1 } = options;
+	const { showLineNumbers, showZebraStripes, startingLineNumber = 1, anchorLine = 0 } = options;
 
 	// Extract lines by walking the DOM and splitting on newlines
 	const lines = extractLinesFromElement(codeElement);
@@ -95,11 +124,18 @@ export function wrapCodeLinesInDom(codeElement: HTMLElement, options: LineWrappi
 		codeElement.removeChild(codeElement.firstChild);
 	}
 
+	// Relative numbering only applies when the anchor is inside the block
+	const useAnchor = anchorLine >= 1 && anchorLine <= lines.length;
+
 	lines.forEach((lineNodes, index) => {
 		const lineNumber = startingLineNumber + index;
 		const lineSpan = document.createElement('span');
 		lineSpan.className = CSS_CLASSES.line;
 
+		if (useAnchor && index + 1 === anchorLine) {
+			lineSpan.classList.add(CSS_CLASSES.lineAnchor);
+		}
+
 		if (showZebraStripes && index % 2 === 1) {
 			lineSpan.classList.add(CSS_CLASSES.lineAlt);
 		}
@@ -108,7 +144,9 @@ export function wrapCodeLinesInDom(codeElement: HTMLElement, options: LineWrappi
 		if (showLineNumbers) {
 			const numSpan = document.createElement('span');
 			numSpan.className = CSS_CLASSES.lineNum;
-			numSpan.textContent = String(lineNumber);
+			numSpan.textContent = useAnchor
+				? formatRelativeLineNumber(index + 1 - anchorLine, lineNumber)
+				: String(lineNumber);
 			lineSpan.appendChild(numSpan);
 		}
 
@@ -128,6 +166,18 @@ export function wrapCodeLinesInDom(codeElement: HTMLElement, options: LineWrappi
 	});
 }
 
+/**
+ * Formats a line number relative to the anchor line.
+ *
+ * @param offset - Distance from the anchor line (negative above it)
+ * @param lineNumber - Absolute number, shown for the anchor line itself
+ * @returns e.g. "-2", "14" or "+3"
+ */
+function formatRelativeLineNumber(offset: number, lineNumber: number): string {
+	if (offset === 0) return String(lineNumber);
+	return offset > 0 ? `+${String(offset)}` : String(offset);
+}
+
 /**
  * Extracts lines from a code element, preserving syntax highlighting.
  *
@@ -265,7 +315,7 @@ export function processCodeElementLines(
 	codeElement: HTMLElement,
 	options: LineWrappingOptions
 ): void {
-	const { showLineNumbers, showZebraStripes } = options;
+	const { showLineNumbers, showZebraStripes, forceLineWrapping = false } = options;
 
 	// Add classes to pre for CSS styling
 	if (showLineNumbers) {
@@ -276,8 +326,9 @@ export function processCodeElementLines(
 		preElement.classList.add(CSS_CLASSES.zebra);
 	}
 
-	// Only wrap lines if we need to show line numbers or zebra stripes
-	if (showLineNumbers || showZebraStripes) {
+	// Only wrap lines if something needs the per-line structure
+	if (showLineNumbers || showZebraStripes || forceLineWrapping) {
+		preElement.classList.add(CSS_CLASSES.lineWrapped);
 		wrapCodeLinesInDom(codeElement, options);
 	}
 }
@@ -370,11 +421,121 @@ export function createCodeBlockContainer(
 /**
  * Gets the text content of a code element, stripping HTML.
  *
+ * When the code has been wrapped into line spans, only the line content
+ * is read (line numbers and gutter buttons are skipped) and the lines
+ * are re-joined with newlines.
+ *
  * @param codeElement - Code element
+ * @param rawAnsi - Read colour-rendered command output with its ANSI codes
  * @returns Plain text content
  */
-export function extractCodeText(codeElement: HTMLElement): string {
-	return codeElement.textContent || '';
+export function extractCodeText(codeElement: HTMLElement, rawAnsi = false): string {
+	const lineElements = Array.from(codeElement.querySelectorAll<HTMLElement>(`.${CSS_CLASSES.line}`));
+
+	if (lineElements.length === 0) {
+		return codeElement.textContent || '';
+	}
+
+	return lineElements.map(lineElement => extractLineText(lineElement, rawAnsi)).join('\n');
+}
+
+/**
+ * Gets the text of a single wrapped line.
+ *
+ * Empty lines are rendered as a non-breaking space to keep their height,
+ * so that placeholder is mapped back to an empty string.
+ *
+ * @param lineElement - A ucf-line span
+ * @param rawAnsi - Use the line as written, with its ANSI codes, when it was rendered in colour
+ * @returns Plain text of the line content
+ */
+export function extractLineText(lineElement: HTMLElement, rawAnsi = false): string {
+	if (rawAnsi && lineElement.dataset.ucfAnsiRaw !== undefined) {
+		return lineElement.dataset.ucfAnsiRaw;
+	}
+
+	// cmdout lines have no separate content span — the line itself is the content
+	const contentElement = lineElement.querySelector(`.${CSS_CLASSES.lineContent}`) ?? lineElement;
+	const text = contentElement.textContent ?? '';
+	return text === '\u00a0' ? '' : text;
+}
+
+/**
+ * Gets the new version of a diff block's code: removed lines, hunk and
+ * file headers are left out, and the leading +/- or space markers of a
+ * unified diff are stripped.
+ *
+ * @param codeElement - Code element with lines marked by markDiffLines
+ * @returns Plain text of the code after the changes
+ */
+export function extractDiffAfterText(codeElement: HTMLElement): string {
+	const droppedClasses = [CSS_CLASSES.diffRemoved, CSS_CLASSES.diffHunk, CSS_CLASSES.diffHeader];
+
+	return Array.from(codeElement.querySelectorAll<HTMLElement>(`.${CSS_CLASSES.line}`))
+		.filter(lineElement => !droppedClasses.some(className => lineElement.classList.contains(className)))
+		.map(lineElement => {
+			const text = extractLineText(lineElement);
+			return lineElement.querySelector(`.${CSS_CLASSES.diffMarker}`) ? text.slice(1) : text;
+		})
+		.join('\n');
+}
+
+// =============================================================================
+// Text Range Wrapping
+// =============================================================================
+
+/**
+ * Wraps a character range of an element's text in spans.
+ *
+ * Offsets refer to the element's textContent. Syntax highlighting means
+ * a range can cross several text nodes, so each text node piece inside
+ * the range gets its own wrapper span; the existing highlight spans are
+ * left intact around them.
+ *
+ * @param element - Element whose text should be wrapped
+ * @param start - Start offset (inclusive)
+ * @param end - End offset (exclusive)
+ * @param className - Class for the wrapper spans
+ * @returns The wrapper spans created, in document order
+ */
+export function wrapTextRange(element: HTMLElement, start: number, end: number, className: string): HTMLSpanElement[] {
+	const wrappers: HTMLSpanElement[] = [];
+	if (end <= start) return wrappers;
+
+	// Collect text nodes first — wrapping while walking would confuse the walker
+	const textNodes: Text[] = [];
+	const walker = document.createTreeWalker(element, NodeFilter.SHOW_TEXT);
+	while (walker.nextNode()) {
+		textNodes.push(walker.currentNode as Text);
+	}
+
+	let offset = 0;
+	for (const textNode of textNodes) {
+		const length = textNode.length;
+		const nodeStart = offset;
+		offset += length;
+
+		if (offset <= start || nodeStart >= end) continue;
+
+		let target = textNode;
+		const localStart = Math.max(start - nodeStart, 0);
+		const localEnd = Math.min(end - nodeStart, length);
+
+		if (localStart > 0) {
+			target = target.splitText(localStart);
+		}
+		if (localEnd - localStart < target.length) {
+			target.splitText(localEnd - localStart);
+		}
+
+		const wrapper = document.createElement('span');
+		wrapper.className = className;
+		target.parentNode?.insertBefore(wrapper, target);
+		wrapper.appendChild(target);
+		wrappers.push(wrapper);
+	}
+
+	return wrappers;
 }
 
 // =============================================================================
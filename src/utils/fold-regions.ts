@@ -0,0 +1,63 @@
+/**
+ * Fold regions for Ultra Code Fence
+ *
+ * Finds `#region` / `#endregion` style markers in a block's code, so the
+ * lines between them can be collapsed in the rendered block.
+ */
+
+import { FOLD_REGION_START_PATTERN, FOLD_REGION_END_PATTERN } from '../constants';
+
+/**
+ * A foldable region of a block, from its start marker to its end marker.
+ */
+export interface FoldRegion {
+	/** Name given after the start marker (empty if none) */
+	name: string;
+
+	/** Line of the start marker (1-based) */
+	startLine: number;
+
+	/** Line of the end marker (1-based) */
+	endLine: number;
+}
+
+/**
+ * Finds the fold regions in a block's code.
+ *
+ * Regions can nest; each end marker closes the innermost open region.
+ * Start markers without an end, and end markers without a start, are
+ * ignored. End markers are checked first, so a custom start pattern may
+ * also match the end marker (e.g. "region" and "endregion").
+ *
+ * @param codeText - Code as rendered
+ * @param startPattern - Start marker (default: #region and equivalents)
+ * @param endPattern - End marker (default: #endregion and equivalents)
+ * @returns Regions ordered by start line
+ */
+export function findFoldRegions(
+	codeText: string,
+	startPattern: RegExp = FOLD_REGION_START_PATTERN,
+	endPattern: RegExp = FOLD_REGION_END_PATTERN
+): FoldRegion[] {
+	const regions: FoldRegion[] = [];
+	const openRegions: Omit<FoldRegion, 'endLine'>[] = [];
+
+	codeText.split('\n').forEach((line, index) => {
+		const lineNumber = index + 1;
+
+		if (endPattern.test(line)) {
+			const region = openRegions.pop();
+			if (region) {
+				regions.push({ ...region, endLine: lineNumber });
+			}
+			return;
+		}
+
+		const startMatch = startPattern.exec(line);
+		if (startMatch) {
+			openRegions.push({ name: (startMatch[1] || '').trim(), startLine: lineNumber });
+		}
+	});
+
+	return regions.sort((a, b) => a.startLine - b.startLine);
+}
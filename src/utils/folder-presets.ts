@@ -0,0 +1,114 @@
+/**
+ * Ultra Code Fence - Default Presets
+ *
+ * Picks a default preset for a block from its language or its note's
+ * tags, and the preset of the folder its note is in. Language presets map
+ * a language ID to a preset name. Presets name the tags they apply to in
+ * APPLIES_TO_TAGS. Folder rules pair a folder path (which may use glob
+ * wildcards) with a preset name; the first rule whose folder contains the
+ * note wins. The folder preset is a layer of its own in the cascade, under
+ * the note's frontmatter.
+ */
+
+import type { FolderPresetRule, PluginSettings } from '../types';
+import { parsePresetAppliesToTags } from '../parsers/yaml-parser';
+
+/** Characters with a special meaning in regular expressions. */
+const REGEX_SPECIAL_PATTERN = /[.+^${}()|[\]\\]/g;
+
+/**
+ * Converts a folder glob into a regular expression matching the folder
+ * and everything below it.
+ *
+ * `*` matches within one folder name, `**` across folders and `?` a single
+ * character. A pattern without wildcards is a plain folder path. Leading
+ * and trailing slashes are ignored.
+ *
+ * @param pattern - Folder path or glob, e.g. "Work/Runbooks" or "Projects/*\/Docs"
+ * @returns Expression tested against a note's folder path
+ */
+export function folderGlobToRegExp(pattern: string): RegExp {
+	const trimmed = pattern.trim().replace(/^\/+|\/+$/g, '');
+
+	let source = '';
+	for (let i = 0; i < trimmed.length; i++) {
+		const char = trimmed[i];
+		if (char === '*' && trimmed[i + 1] === '*') {
+			source += '.*';
+			i++;
+		} else if (char === '*') {
+			source += '[^/]*';
+		} else if (char === '?') {
+			source += '[^/]';
+		} else {
+			source += char.replace(REGEX_SPECIAL_PATTERN, '\\$&');
+		}
+	}
+
+	// An empty pattern is the vault root, which contains every note
+	return source ? new RegExp(`^${source}(?:/.*)?$`) : /^/;
+}
+
+/**
+ * Finds the preset assigned to a note's folder.
+ *
+ * @param notePath - Vault-relative path of the note
+ * @param rules - Folder rules, in priority order (use "**" for the whole vault)
+ * @returns Preset name of the first matching rule, or undefined
+ */
+export function findFolderPreset(notePath: string, rules: readonly FolderPresetRule[]): string | undefined {
+	const slash = notePath.lastIndexOf('/');
+	const folder = slash === -1 ? '' : notePath.slice(0, slash);
+
+	for (const rule of rules) {
+		// Half-filled rules (e.g. just added in settings) don't apply
+		if (!rule.preset || !rule.folder.trim()) continue;
+		if (folderGlobToRegExp(rule.folder).test(folder)) {
+			return rule.preset;
+		}
+	}
+	return undefined;
+}
+
+/**
+ * Finds the preset that applies to a note's tags.
+ *
+ * A preset tag also covers its nested tags (#runbook covers
+ * #runbook/database). When several presets match, the first by name wins.
+ *
+ * @param noteTags - The note's tags, with or without the leading #
+ * @param presets - Map of preset names to raw YAML strings
+ * @returns Preset name, or undefined if no preset applies to the tags
+ */
+export function findTagPreset(noteTags: readonly string[], presets: Record<string, string>): string | undefined {
+	if (noteTags.length === 0) return undefined;
+
+	const tags = noteTags.map(tag => tag.replace(/^#/, '').toLowerCase());
+	for (const name of Object.keys(presets).sort()) {
+		const presetTags = parsePresetAppliesToTags(presets[name]);
+		if (presetTags.some(presetTag => tags.some(tag => tag === presetTag || tag.startsWith(`${presetTag}/`)))) {
+			return name;
+		}
+	}
+	return undefined;
+}
+
+/**
+ * Finds the default preset for a block: the preset assigned to its
+ * language, else one applying to its note's tags. The folder preset isn't
+ * one of them; see {@link findFolderPreset}.
+ *
+ * @param language - Language of the block (e.g. "sql"), if known
+ * @param settings - Plugin settings holding the presets and the language presets
+ * @param noteTags - The note's tags
+ * @returns Preset name, or undefined if none applies
+ */
+export function findDefaultPreset(
+	language: string | undefined,
+	settings: Pick<PluginSettings, 'presets' | 'languagePresets'>,
+	noteTags: readonly string[] = []
+): string | undefined {
+	const languagePreset = language ? settings.languagePresets[language.toLowerCase()] : undefined;
+	if (languagePreset) return languagePreset;
+	return findTagPreset(noteTags, settings.presets);
+}
@@ -2,9 +2,10 @@
  * Ultra Code Fence - Formatting Utilities
  *
  * Functions for formatting values into human-readable strings.
- * Includes file sizes, dates, and relative times.
+ * Includes file sizes, dates, relative times and font feature settings.
  */
 
+import { FONT_FEATURE_PATTERN } from '../constants';
 import type { TextCaseFormat, FileSizeFormat, DateDisplayFormat } from '../types';
 
 // =============================================================================
@@ -234,6 +235,41 @@ export function buildStyleString(colour: string, isBold: boolean, isItalic: bool
 	return styleRules.join('; ');
 }
 
+/**
+ * Builds a font-feature-settings value from ligature and feature options.
+ *
+ * Turning ligatures on or off sets both standard (`liga`) and contextual
+ * (`calt`) ligatures, since programming fonts draw most of theirs with
+ * `calt`. Listed features come after, so they can override either one.
+ * Malformed entries are skipped.
+ *
+ * @param features - Comma-separated features: `tag`, `tag=value` or `-tag` (off)
+ * @param ligatures - Ligatures on or off (undefined = leave them alone)
+ * @returns CSS value, or an empty string when there is nothing to set
+ *
+ * @example
+ * buildFontFeatureSettings('ss01, cv02=3')  // '"ss01" 1, "cv02" 3'
+ * buildFontFeatureSettings('', false)       // '"liga" 0, "calt" 0'
+ */
+export function buildFontFeatureSettings(features: string, ligatures?: boolean): string {
+	const settings: string[] = [];
+
+	if (ligatures !== undefined) {
+		const ligatureValue = ligatures ? '1' : '0';
+		settings.push(`"liga" ${ligatureValue}`, `"calt" ${ligatureValue}`);
+	}
+
+	for (const feature of features.split(',')) {
+		const match = FONT_FEATURE_PATTERN.exec(feature.trim());
+		if (!match) continue;
+
+		const featureValue = match[1] ? '0' : (match[3] || '1');
+		settings.push(`"${match[2]}" ${featureValue}`);
+	}
+
+	return settings.join(', ');
+}
+
 // =============================================================================
 // Callout Markdown Formatting
 // =============================================================================
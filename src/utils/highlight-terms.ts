@@ -0,0 +1,68 @@
+/**
+ * Highlighted terms for Ultra Code Fence
+ *
+ * Finds each occurrence of the terms listed in HIGHLIGHT.TERMS, usually
+ * identifiers such as "userId", so a walkthrough can follow a value
+ * through the block. A term that starts or ends with a word character
+ * only matches whole words there: "ctx" doesn't match inside "ctxA".
+ */
+
+/**
+ * An occurrence of a term.
+ */
+export interface TermMatch {
+	/** Start offset (inclusive) */
+	start: number;
+
+	/** End offset (exclusive) */
+	end: number;
+
+	/** Index of the term in the list */
+	term: number;
+}
+
+/** Characters that continue an identifier. */
+const IDENTIFIER_CHAR = '[\\w$]';
+
+/**
+ * Escapes a term for use in a regex.
+ *
+ * @param term - Literal text
+ * @returns Regex source matching the text
+ */
+function escapeTerm(term: string): string {
+	return term.replace(/[.*+?^${}()|[\]\\]/g, '\\$&');
+}
+
+/**
+ * Finds the occurrences of terms in some text.
+ *
+ * Matching is case-sensitive, like identifiers. Where terms overlap,
+ * the longer one wins ("userId" over "user").
+ *
+ * @param text - Text to search (one line)
+ * @param terms - Terms to find
+ * @returns Occurrences, in order
+ */
+export function findTermMatches(text: string, terms: readonly string[]): TermMatch[] {
+	const order = terms
+		.map((term, index) => ({ term, index }))
+		.filter(entry => entry.term !== '')
+		.sort((a, b) => b.term.length - a.term.length);
+	if (order.length === 0) return [];
+
+	const source = order.map(({ term }) => {
+		const before = /^[\w$]/.test(term) ? `(?<!${IDENTIFIER_CHAR})` : '';
+		const after = /[\w$]$/.test(term) ? `(?!${IDENTIFIER_CHAR})` : '';
+		return `${before}(${escapeTerm(term)})${after}`;
+	}).join('|');
+	const pattern = new RegExp(source, 'g');
+
+	const matches: TermMatch[] = [];
+	let match: RegExpExecArray | null;
+	while ((match = pattern.exec(text)) !== null) {
+		const group = match.slice(1).findIndex(value => value !== undefined);
+		matches.push({ start: match.index, end: match.index + match[0].length, term: order[group].index });
+	}
+	return matches;
+}
@@ -0,0 +1,113 @@
+/**
+ * Indentation guides for Ultra Code Fence
+ *
+ * Works out how deeply each line of a block is indented, so vertical
+ * guides can be drawn at each indentation level like code editors do.
+ */
+
+import { INDENT_GUIDE_TAB_WIDTH } from '../constants';
+
+/**
+ * Indentation of a block, as needed to draw its guides.
+ */
+export interface IndentGuideLayout {
+	/** Columns per indentation level */
+	unit: number;
+
+	/** Number of guides on each line */
+	depths: number[];
+}
+
+/**
+ * A run of lines sharing an indentation guide.
+ */
+export interface IndentScope {
+	/** Guide number (1 = the outermost guide) */
+	level: number;
+
+	/** First line of the scope (0-based) */
+	startIndex: number;
+
+	/** Last line of the scope (0-based) */
+	endIndex: number;
+}
+
+/**
+ * Measures a line's leading whitespace in columns.
+ *
+ * @param line - Line text
+ * @param tabWidth - Columns per tab stop
+ * @returns Indent in columns, or null for a blank line
+ */
+export function measureIndent(line: string, tabWidth: number = INDENT_GUIDE_TAB_WIDTH): number | null {
+	let columns = 0;
+
+	for (const character of line) {
+		if (character === ' ') {
+			columns += 1;
+		} else if (character === '\t') {
+			columns += tabWidth - (columns % tabWidth);
+		} else {
+			return columns;
+		}
+	}
+
+	return null;
+}
+
+/**
+ * Works out the indentation guides for a block's lines.
+ *
+ * The indentation unit is the smallest indent in the block (so YAML
+ * indented by two gets a guide every two columns). Blank lines carry the
+ * guides shared by the lines around them, so guides don't break at
+ * empty lines inside a block.
+ *
+ * @param lines - Line texts
+ * @param tabWidth - Columns per tab stop
+ * @returns Indentation unit and guide count per line
+ */
+export function computeIndentGuides(lines: string[], tabWidth: number = INDENT_GUIDE_TAB_WIDTH): IndentGuideLayout {
+	const indents = lines.map(line => measureIndent(line, tabWidth));
+	const nonZeroIndents = indents.filter((indent): indent is number => indent !== null && indent > 0);
+	const unit = nonZeroIndents.length > 0 ? Math.min(...nonZeroIndents) : tabWidth;
+
+	const depths = indents.map(indent => (indent === null ? -1 : Math.floor(indent / unit)));
+
+	depths.forEach((depth, index) => {
+		if (depth >= 0) return;
+
+		const previousDepth = depths.slice(0, index).reverse().find(value => value >= 0) ?? 0;
+		const nextDepth = depths.slice(index + 1).find(value => value >= 0) ?? 0;
+		depths[index] = Math.min(previousDepth, nextDepth);
+	});
+
+	return { unit, depths };
+}
+
+/**
+ * Finds the scope a line belongs to: its innermost guide and the lines
+ * around it that share that guide.
+ *
+ * @param depths - Guide count per line (from computeIndentGuides)
+ * @param lineIndex - Line to start from (0-based)
+ * @returns The scope, or null for a line without guides
+ */
+export function findIndentScope(depths: number[], lineIndex: number): IndentScope | null {
+	if (lineIndex < 0 || lineIndex >= depths.length) return null;
+
+	const level = depths[lineIndex];
+	if (level === 0) return null;
+
+	let startIndex = lineIndex;
+	while (startIndex > 0 && depths[startIndex - 1] >= level) {
+		startIndex--;
+	}
+
+	let endIndex = lineIndex;
+	while (endIndex < depths.length - 1 && depths[endIndex + 1] >= level) {
+		endIndex++;
+	}
+
+	return { level, startIndex, endIndex };
+}
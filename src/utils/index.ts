@@ -12,6 +12,7 @@ export {
 	formatTimestamp,
 	escapeHtml,
 	buildStyleString,
+	buildFontFeatureSettings,
 } from './formatting';
 
 export {
@@ -23,6 +24,7 @@ export type { LineWrappingOptions } from './dom';
 
 export {
 	addScrollBehaviour,
+	applyPrintProfile,
 	wrapCodeLinesInDom,
 	processCodeElementLines,
 	findCodeElement,
@@ -30,6 +32,9 @@ export {
 	removeExistingTitleElements,
 	createCodeBlockContainer,
 	extractCodeText,
+	extractLineText,
+	extractDiffAfterText,
+	wrapTextRange,
 } from './dom';
 
 export {
@@ -46,4 +51,106 @@ export {
 
 export { deepMergeYamlConfigs } from './config-merge';
 
-export { resolvePreset } from './preset-resolver';
+export type { CopyTransform, CopyCleanupOptions, CodeRegion } from './copy-transforms';
+
+export {
+	COPY_AS_TRANSFORMS,
+	COPY_AS_DEFAULT_LABELS,
+	extractCommandLines,
+	findPromptLength,
+	stripPrompts,
+	stripCommentLines,
+	cleanupCopyText,
+	findRedactions,
+	redactSecrets,
+	findPlaceholders,
+	substitutePlaceholders,
+	findRegions,
+	findRegionLineIndices,
+	isKnownCopyAsFormat,
+	applyCopyAsTransform,
+	registerCopyAsTransform,
+	unregisterCopyAsTransform,
+} from './copy-transforms';
+
+export { resolvePreset, resolvePresetConfig, resolvePresetReference } from './preset-resolver';
+
+export { folderGlobToRegExp, findFolderPreset, findTagPreset, findDefaultPreset } from './folder-presets';
+
+export { CASCADE_LAYERS, normalizeConfigCascade, getActiveCascadeLayers } from './config-cascade';
+
+export { buildRichTextHtml } from './rich-text';
+
+export { renameDeprecatedKeys } from './config-rename';
+
+export { setBlockPreset, setBlockLanguage } from './preset-switch';
+
+export type { IndentGuideLayout, IndentScope } from './indent-guides';
+
+export { measureIndent, computeIndentGuides, findIndentScope } from './indent-guides';
+
+export { parseLanguageTabSizes, getDefaultTabSize } from './tab-size';
+
+export { resolveLanguageAlias, languageFromPath } from './language-aliases';
+
+export { DETECTABLE_LANGUAGES, detectLanguage, scoreLanguages } from './language-detect';
+
+export { parseTokenStyle, resolvePatternStyles, resolveTokenStyles } from './token-styles';
+
+export type { AnsiStyle, AnsiSegment } from './ansi';
+
+export {
+	createAnsiStyle,
+	hasAnsiCodes,
+	stripAnsi,
+	ansiPaletteColour,
+	applySgrCodes,
+	parseAnsiLine,
+	ansiStyleToCss,
+} from './ansi';
+
+export type { WhitespaceRun } from './whitespace';
+
+export { findWhitespaceRuns } from './whitespace';
+
+export type { CommentKeywordMatch } from './comment-keywords';
+
+export { findCommentKeywords } from './comment-keywords';
+
+export type { TermMatch } from './highlight-terms';
+
+export { findTermMatches } from './highlight-terms';
+
+export type { BracketPair } from './bracket-pairs';
+
+export { BRACKET_CHARACTERS, pairBrackets } from './bracket-pairs';
+
+export type { SemanticToken, SemanticTokenType, SemanticLanguage } from './semantic-tokens';
+
+export { findSemanticTokens, semanticLanguage } from './semantic-tokens';
+
+export type { DiffLineKind, DiffStep, SplitDiffRow, DiffTextRange, WordDiff } from './diff';
+
+export { classifyDiffLines, hasDiffMarker, unifiedDiffSteps, diffLineLists, buildSplitDiffRows, diffWords } from './diff';
+
+export type { LineLink } from './line-links';
+
+export { parseLineLink, formatLineLinkSubpath, findFenceBlockId } from './line-links';
+
+export type { FoldRegion } from './fold-regions';
+
+export { findFoldRegions } from './fold-regions';
+
+export type { CodeVariableContext } from './code-variables';
+
+export { parseCodeVariables, formatDateTokens, interpolateCodeVariables } from './code-variables';
+
+export { stripTypeAnnotations } from './type-annotations';
+
+export type { SavedRunOutput } from './output-block';
+
+export { formatOutputBlock, parseOutputBlock, findOutputBlock, writeOutputBlock } from './output-block';
+
+export type { ConfigLayer, ConfigSourceEntry, ConfigInspection } from './config-inspect';
+
+export { flattenConfig, buildSettingsLayer, inspectBlockConfig } from './config-inspect';
@@ -0,0 +1,71 @@
+/**
+ * Language aliases for Ultra Code Fence
+ *
+ * Maps fence language codes with no grammar of their own to one that
+ * has one (zsh → bash, tf → hcl), set in the plugin settings, and finds
+ * the language of an embedded file from its name.
+ */
+
+/**
+ * Language of a file by lower-case extension, or by whole name for files
+ * that have none (Dockerfile, Makefile).
+ */
+const FILE_LANGUAGES: Record<string, string | undefined> = {
+	sh: 'bash', bash: 'bash', zsh: 'bash', ksh: 'bash',
+	ps1: 'powershell', psm1: 'powershell', bat: 'batch', cmd: 'batch',
+	py: 'python', pyw: 'python', rb: 'ruby', pl: 'perl', pm: 'perl', php: 'php', lua: 'lua', r: 'r',
+	js: 'javascript', mjs: 'javascript', cjs: 'javascript', jsx: 'jsx',
+	ts: 'typescript', mts: 'typescript', cts: 'typescript', tsx: 'tsx',
+	go: 'go', rs: 'rust', java: 'java', kt: 'kotlin', kts: 'kotlin', scala: 'scala', swift: 'swift', dart: 'dart',
+	c: 'c', h: 'c', cpp: 'cpp', cc: 'cpp', cxx: 'cpp', hpp: 'cpp', hh: 'cpp', cs: 'csharp', fs: 'fsharp',
+	ex: 'elixir', exs: 'elixir', erl: 'erlang', hs: 'haskell', clj: 'clojure', ml: 'ocaml',
+	sql: 'sql', graphql: 'graphql', gql: 'graphql', proto: 'protobuf',
+	json: 'json', jsonc: 'json', yaml: 'yaml', yml: 'yaml', toml: 'toml', ini: 'ini', cfg: 'ini', conf: 'ini',
+	xml: 'xml', html: 'html', htm: 'html', svg: 'xml', css: 'css', scss: 'scss', sass: 'sass', less: 'less',
+	md: 'markdown', markdown: 'markdown', tex: 'latex',
+	tf: 'hcl', tfvars: 'hcl', hcl: 'hcl', nix: 'nix', vim: 'vim', diff: 'diff', patch: 'diff',
+	dockerfile: 'docker', makefile: 'makefile', mk: 'makefile', cmake: 'cmake', gradle: 'groovy', groovy: 'groovy',
+};
+
+/**
+ * Finds the language an alias stands for.
+ *
+ * Aliases may chain (zshrc → zsh → bash); a loop stops at the last
+ * language before it repeats.
+ *
+ * @param language - Fence language code
+ * @param aliases - Target language by lower-case alias
+ * @returns The target language, or the language itself if it isn't an alias
+ */
+export function resolveLanguageAlias(language: string, aliases: Record<string, string | undefined>): string {
+	let current = language.trim().toLowerCase();
+	const seen = new Set<string>([current]);
+
+	for (;;) {
+		const target = aliases[current]?.trim().toLowerCase();
+		if (!target || seen.has(target)) return current;
+		seen.add(target);
+		current = target;
+	}
+}
+
+/**
+ * Finds the language of a file from its name.
+ *
+ * An extension in the alias map is resolved through it first, so an
+ * alias can also correct a wrong guess (conf → nginx).
+ *
+ * @param path - Vault path, URL or git source path of the file
+ * @param aliases - Target language by lower-case alias
+ * @returns The file's language, or undefined if its name doesn't say
+ */
+export function languageFromPath(path: string, aliases: Record<string, string | undefined>): string | undefined {
+	// Drop a URL's query or fragment and a git source's @ref
+	const fileName = (path.replace(/[?#].*$/, '').replace(/@[^@/]*$/, '').split('/').pop() ?? '').toLowerCase();
+	const dot = fileName.lastIndexOf('.');
+	const extension = dot > 0 ? fileName.slice(dot + 1) : fileName;
+
+	if (aliases[extension] !== undefined) return resolveLanguageAlias(extension, aliases);
+	const language = FILE_LANGUAGES[extension];
+	return language ? resolveLanguageAlias(language, aliases) : undefined;
+}
@@ -0,0 +1,236 @@
+/**
+ * Language detection for Ultra Code Fence
+ *
+ * Guesses the language of a block marked RENDER.LANG: auto from its
+ * code. A shebang line decides outright; otherwise each language scores
+ * points for the telltale patterns it matches (keywords, punctuation,
+ * common idioms) and the best score wins if it is clear enough. This is
+ * a cheap heuristic, not a parser — the guess can be corrected from the
+ * header badge.
+ */
+
+/** Characters of code looked at; the start of a long file is enough. */
+const DETECTION_SAMPLE_LENGTH = 5000;
+
+/** Lowest score that counts as a guess. */
+const MIN_DETECTION_SCORE = 3;
+
+/**
+ * Interpreters named on a shebang line and the language they run.
+ */
+const SHEBANG_LANGUAGES: ReadonlyArray<readonly [RegExp, string]> = [
+	[/\b(?:ba|z|k|da)?sh\b/, 'bash'],
+	[/\bpython[0-9.]*\b/, 'python'],
+	[/\b(?:node|deno|bun)\b/, 'javascript'],
+	[/\b(?:ts-node|tsx)\b/, 'typescript'],
+	[/\bruby\b/, 'ruby'],
+	[/\bperl\b/, 'perl'],
+	[/\bphp\b/, 'php'],
+	[/\bpwsh\b/, 'powershell'],
+];
+
+/**
+ * Telltale patterns per language, with the points each is worth. A
+ * pattern scores once however often it matches.
+ */
+const LANGUAGE_SIGNALS: Record<string, ReadonlyArray<readonly [RegExp, number]>> = {
+	json: [
+		[/^\s*[{[]/, 1],
+		[/^\s*"[^"\n]+"\s*:/m, 3],
+		[/[}\]]\s*$/, 1],
+	],
+	yaml: [
+		[/^---\s*$/m, 2],
+		[/^[\w.-]+:\s*$/m, 2],
+		[/^\s+[\w.-]+:\s+\S/m, 1],
+		[/^\s*-\s+[\w.-]+:\s/m, 2],
+	],
+	bash: [
+		[/^\s*(?:sudo|apt(?:-get)?|brew|yum|dnf|cd|ls|mkdir|rm|cp|mv|chmod|chown|export|source|curl|wget|grep|cat|echo|git|docker|kubectl|npm|pip)\s/m, 2],
+		[/\$\{?\w+\}?/, 1],
+		[/^\s*(?:if|while|for)\b.*;\s*(?:then|do)\b/m, 3],
+		[/^\s*(?:fi|done|esac)\s*$/m, 3],
+		[/\s(?:&&|\|\|)\s/, 1],
+		[/\s-{1,2}[a-z][\w-]*/, 1],
+	],
+	powershell: [
+		[/\b(?:Get|Set|New|Remove|Write|Invoke|Start|Import)-[A-Z]\w+/, 4],
+		[/\$\w+\s*=/, 1],
+		[/\s-(?:eq|ne|gt|lt|like|match)\s/, 2],
+	],
+	python: [
+		[/^\s*def\s+\w+\s*\(.*\)\s*(?:->\s*[\w[\], .]+)?:\s*$/m, 4],
+		[/^\s*(?:from\s+[\w.]+\s+)?import\s+[\w.]+(?:\s+as\s+\w+)?\s*$/m, 2],
+		[/^\s*(?:elif|except|finally)\b.*:\s*$/m, 3],
+		[/\bself\./, 2],
+		[/\bprint\(/, 1],
+		[/\b(?:None|True|False)\b/, 1],
+		[/^\s*class\s+\w+(?:\(.*\))?:\s*$/m, 3],
+	],
+	javascript: [
+		[/\b(?:const|let)\s+\w+\s*=/, 2],
+		[/\bfunction\s*\w*\s*\(/, 2],
+		[/=>\s*[{(\w]/, 2],
+		[/\bconsole\.\w+\(/, 3],
+		[/\brequire\(['"]/, 3],
+		[/^\s*(?:import|export)\s.*\bfrom\s+['"]/m, 2],
+		[/===|!==/, 1],
+	],
+	typescript: [
+		[/\b(?:interface|type)\s+\w+\s*(?:<[^>]*>)?\s*[={]/, 3],
+		[/:\s*(?:string|number|boolean|void|unknown|any)\b/, 3],
+		[/\b(?:private|public|readonly)\s+\w+/, 2],
+		[/\b(?:const|let)\s+\w+\s*:\s*\w+/, 2],
+	],
+	sql: [
+		[/\bSELECT\b[\s\S]+?\bFROM\b/i, 4],
+		[/\b(?:INSERT\s+INTO|UPDATE\s+\w+\s+SET|DELETE\s+FROM|CREATE\s+(?:TABLE|INDEX|VIEW)|ALTER\s+TABLE)\b/i, 4],
+		[/\b(?:WHERE|JOIN|GROUP\s+BY|ORDER\s+BY)\b/i, 1],
+	],
+	html: [
+		[/<!DOCTYPE\s+html/i, 5],
+		[/<(?:html|head|body|div|span|p|a|ul|li|script|style)\b[^>]*>/i, 3],
+		[/<\/\w+>/, 1],
+	],
+	xml: [
+		[/^\s*<\?xml\b/, 5],
+		[/<\w+(?::\w+)?(?:\s+[\w:]+="[^"]*")*\s*\/?>/, 1],
+		[/<\/\w+(?::\w+)?>/, 1],
+	],
+	css: [
+		[/^\s*[.#]?[\w-]+(?:[\s>+~:.#[\]="\w-]*)\s*\{\s*$/m, 2],
+		[/^\s*[\w-]+\s*:\s*[^;\n]+;\s*$/m, 2],
+		[/@(?:media|import|keyframes|font-face)\b/, 3],
+	],
+	go: [
+		[/^package\s+\w+\s*$/m, 4],
+		[/\bfunc\s+(?:\(\w+\s+\*?\w+\)\s*)?\w+\(/, 4],
+		[/:=/, 1],
+		[/\bfmt\.\w+\(/, 3],
+	],
+	rust: [
+		[/\bfn\s+\w+\s*(?:<[^>]*>)?\(/, 3],
+		[/\blet\s+mut\b/, 4],
+		[/\b(?:impl|pub\s+fn|use\s+\w+::)\b/, 3],
+		[/\w+!\(/, 1],
+	],
+	java: [
+		[/\bpublic\s+(?:static\s+)?(?:class|void|final)\b/, 3],
+		[/\bSystem\.out\.print/, 4],
+		[/^\s*import\s+java\./m, 4],
+		[/@Override\b/, 3],
+	],
+	cs: [
+		[/^\s*using\s+System(?:\.\w+)*;\s*$/m, 4],
+		[/\bnamespace\s+[\w.]+/, 2],
+		[/\bConsole\.Write(?:Line)?\(/, 4],
+		[/\{\s*get;\s*(?:set;)?\s*\}/, 3],
+	],
+	cpp: [
+		[/^\s*#include\s*<(?:iostream|vector|string|map|memory)>/m, 4],
+		[/\bstd::/, 3],
+		[/\b(?:cout|cin)\s*<</, 2],
+		[/\btemplate\s*</, 2],
+	],
+	c: [
+		[/^\s*#include\s*<\w+\.h>/m, 4],
+		[/\bint\s+main\s*\(/, 2],
+		[/\bprintf\s*\(/, 2],
+		[/\b(?:malloc|free|sizeof)\s*\(/, 2],
+	],
+	php: [
+		[/<\?php\b/, 6],
+		[/\$\w+\s*->/, 2],
+		[/\becho\s+\$/, 2],
+	],
+	ruby: [
+		[/^\s*def\s+\w+[!?]?(?:\(.*\))?\s*$/m, 3],
+		[/^\s*end\s*$/m, 2],
+		[/\bputs\s/, 2],
+		[/\brequire\s+['"]/, 2],
+		[/\bdo\s*\|\w+(?:,\s*\w+)*\|/, 3],
+	],
+	diff: [
+		[/^(?:---|\+\+\+)\s+\S/m, 2],
+		[/^@@\s+-\d+(?:,\d+)?\s+\+\d+(?:,\d+)?\s+@@/m, 5],
+		[/^diff\s+--git\b/m, 5],
+	],
+	toml: [
+		[/^\s*\[[\w.-]+\]\s*$/m, 2],
+		[/^\s*[\w.-]+\s*=\s*(?:"[^"]*"|\d+|true|false|\[)/m, 2],
+	],
+	ini: [
+		[/^\s*\[[\w .-]+\]\s*$/m, 1],
+		[/^\s*[\w.-]+\s*=\s*[^"\n[]+$/m, 1],
+		[/^\s*;/m, 1],
+	],
+	dockerfile: [
+		[/^\s*FROM\s+[\w./:-]+/m, 3],
+		[/^\s*(?:RUN|COPY|WORKDIR|ENTRYPOINT|CMD|EXPOSE|ENV)\s/m, 3],
+	],
+	markdown: [
+		[/^#{1,6}\s+\S/m, 2],
+		[/^\s*[-*]\s+\S/m, 1],
+		[/\[[^\]]+\]\([^)]+\)/, 2],
+	],
+};
+
+/**
+ * Languages the detector can guess, for the correction menu.
+ */
+export const DETECTABLE_LANGUAGES: readonly string[] = Object.keys(LANGUAGE_SIGNALS).sort();
+
+/**
+ * Guesses a block's language from its code.
+ *
+ * @param code - The block's code
+ * @returns Language ID, or null if no language stands out
+ */
+export function detectLanguage(code: string): string | null {
+	const sample = code.slice(0, DETECTION_SAMPLE_LENGTH);
+
+	const shebang = /^#!\s*(\S+)(?:\s+(\S+))?/.exec(sample);
+	if (shebang) {
+		// "#!/usr/bin/env python3" names the interpreter second
+		const interpreter = shebang[1].endsWith('/env') && shebang[2] ? shebang[2] : shebang[1];
+		for (const [pattern, language] of SHEBANG_LANGUAGES) {
+			if (pattern.test(interpreter)) return language;
+		}
+	}
+
+	const scores = scoreLanguages(sample);
+	let best: string | null = null;
+	let bestScore = MIN_DETECTION_SCORE - 1;
+
+	// Ties go to the language listed first (JSON before YAML, etc.)
+	for (const language of Object.keys(LANGUAGE_SIGNALS)) {
+		if (scores[language] > bestScore) {
+			best = language;
+			bestScore = scores[language];
+		}
+	}
+	return best;
+}
+
+/**
+ * Scores every language against a sample of code.
+ *
+ * @param sample - Code to score
+ * @returns Score by language ID
+ */
+export function scoreLanguages(sample: string): Record<string, number> {
+	const scores: Record<string, number> = {};
+	for (const [language, signals] of Object.entries(LANGUAGE_SIGNALS)) {
+		let score = 0;
+		for (const [pattern, points] of signals) {
+			if (pattern.test(sample)) score += points;
+		}
+		scores[language] = score;
+	}
+
+	// TypeScript is JavaScript with types: only prefer it when types show
+	if (scores.typescript > 0) {
+		scores.typescript += scores.javascript;
+	}
+	return scores;
+}
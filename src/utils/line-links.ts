@@ -0,0 +1,79 @@
+/**
+ * Line links for Ultra Code Fence
+ *
+ * Reads and writes links to lines of a block, e.g. [[Note#^setup:L42]].
+ * The block is named by its Obsidian block ID (the ^id line after the
+ * fence) or META.ID; lines follow the numbers shown in the gutter.
+ */
+
+import { BLOCK_ID_LINE_PATTERN, LINE_LINK_PATTERN } from '../constants';
+
+/**
+ * Lines of a block addressed by a link.
+ */
+export interface LineLink {
+	/** Note part of the link (empty = the note the link is in) */
+	path: string;
+
+	/** Block ID */
+	blockId: string;
+
+	/** First line (as numbered in the gutter) */
+	startLine: number;
+
+	/** Last line (same as startLine for a single line) */
+	endLine: number;
+}
+
+/**
+ * Reads a line link from a link target such as `Note#^setup:L42`.
+ *
+ * @param linkText - Link target (the part inside [[ ]], without an alias)
+ * @returns The addressed lines, or null when the link doesn't address lines
+ */
+export function parseLineLink(linkText: string): LineLink | null {
+	const hashIndex = linkText.indexOf('#');
+	if (hashIndex < 0) return null;
+
+	const match = LINE_LINK_PATTERN.exec(linkText.slice(hashIndex + 1).trim());
+	if (!match) return null;
+
+	const first = parseInt(match[2], 10);
+	const last = match[3] ? parseInt(match[3], 10) : first;
+
+	return {
+		path: linkText.slice(0, hashIndex).trim(),
+		blockId: match[1],
+		startLine: Math.min(first, last),
+		endLine: Math.max(first, last),
+	};
+}
+
+/**
+ * Builds the link subpath for lines of a block.
+ *
+ * @param blockId - Block ID
+ * @param startLine - First line
+ * @param endLine - Last line (omit for a single line)
+ * @returns Subpath, e.g. `#^setup:L42` or `#^setup:L40-L45`
+ */
+export function formatLineLinkSubpath(blockId: string, startLine: number, endLine: number = startLine): string {
+	const first = Math.min(startLine, endLine);
+	const last = Math.max(startLine, endLine);
+	const lines = first === last ? `L${String(first)}` : `L${String(first)}-L${String(last)}`;
+
+	return `#^${blockId}:${lines}`;
+}
+
+/**
+ * Finds the Obsidian block ID given to a fence, on the line after its
+ * closing fence.
+ *
+ * @param noteLines - Lines of the note
+ * @param fenceEndLine - Line of the closing fence (0-based)
+ * @returns The block ID, or an empty string
+ */
+export function findFenceBlockId(noteLines: string[], fenceEndLine: number): string {
+	const nextLine = fenceEndLine + 1 < noteLines.length ? noteLines[fenceEndLine + 1] : '';
+	return BLOCK_ID_LINE_PATTERN.exec(nextLine)?.[1] ?? '';
+}
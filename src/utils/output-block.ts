@@ -0,0 +1,105 @@
+/**
+ * Ultra Code Fence - Output Blocks
+ *
+ * Reads and writes the companion ufence-output block a run saves its
+ * output in, just after the block that was run, so the output is kept
+ * in the note itself.
+ */
+
+import { OUTPUT_BLOCK_LANGUAGE } from '../constants';
+
+/** A saved run's output, as read from its block. */
+export interface SavedRunOutput {
+	/** How the run ended, e.g. "Exited with 0" */
+	status: string;
+
+	/** What the run printed */
+	output: string;
+}
+
+/** Opening fence of an output block: its fence characters, then the block language. */
+const OUTPUT_FENCE_PATTERN = new RegExp(`^\\s*(\`{3,}|~{3,})\\s*${OUTPUT_BLOCK_LANGUAGE}\\s*$`);
+
+// =============================================================================
+// Formatting
+// =============================================================================
+
+/**
+ * Formats a run's output as an output block: the status on the first
+ * line, then the output. The fence is made longer than any run of
+ * backticks in the output, so the output can't close it early.
+ *
+ * @param status - How the run ended
+ * @param output - What the run printed
+ * @returns The block's lines, fences included
+ */
+export function formatOutputBlock(status: string, output: string): string[] {
+	const longestRun = (output.match(/`+/g) ?? []).reduce((longest, run) => Math.max(longest, run.length), 0);
+	const fence = '`'.repeat(Math.max(3, longestRun + 1));
+	const outputLines = output ? output.replace(/\n$/, '').split('\n') : [];
+	return [`${fence}${OUTPUT_BLOCK_LANGUAGE}`, status, ...outputLines, fence];
+}
+
+/**
+ * Reads an output block's content back.
+ *
+ * @param content - Text between the block's fences
+ * @returns Status and output
+ */
+export function parseOutputBlock(content: string): SavedRunOutput {
+	const newline = content.indexOf('\n');
+	if (newline === -1) return { status: content.trim(), output: '' };
+	return { status: content.slice(0, newline).trim(), output: content.slice(newline + 1) };
+}
+
+// =============================================================================
+// Writing
+// =============================================================================
+
+/**
+ * Finds the output block that belongs to a block: the next block after
+ * it, with only blank lines between, if it is an output block.
+ *
+ * @param lines - The note's lines
+ * @param blockEndLine - Index of the run block's closing fence
+ * @returns First and last line of the output block, or null if it has none
+ */
+export function findOutputBlock(lines: string[], blockEndLine: number): { start: number; end: number } | null {
+	let start = blockEndLine + 1;
+	while (start < lines.length && !lines[start].trim()) start++;
+
+	const fenceMatch = OUTPUT_FENCE_PATTERN.exec(lines[start] ?? '');
+	if (!fenceMatch) return null;
+
+	const fence = fenceMatch[1];
+	for (let end = start + 1; end < lines.length; end++) {
+		const closing = lines[end].trim();
+		if (closing.startsWith(fence) && closing.split('').every(char => char === fence[0])) {
+			return { start, end };
+		}
+	}
+	return null;
+}
+
+/**
+ * Saves a run's output in the note: replaces the block's output block
+ * if it has one, or adds one after it, a blank line between.
+ *
+ * @param lines - The note's lines
+ * @param blockEndLine - Index of the run block's closing fence
+ * @param status - How the run ended
+ * @param output - What the run printed
+ * @returns The note's new lines
+ */
+export function writeOutputBlock(lines: string[], blockEndLine: number, status: string, output: string): string[] {
+	const blockLines = formatOutputBlock(status, output);
+	const existing = findOutputBlock(lines, blockEndLine);
+
+	const updated = [...lines];
+	if (existing) {
+		updated.splice(existing.start, existing.end - existing.start + 1, ...blockLines);
+	} else {
+		updated.splice(blockEndLine + 1, 0, '', ...blockLines);
+	}
+	return updated;
+}
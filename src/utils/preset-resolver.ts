@@ -1,70 +1,184 @@
 /**
  * Preset resolver for Ultra Code Fence
  *
- * Resolves presets and page-level config, merging them with block configuration.
- * Presets and page configs provide base configuration that can be overridden by
- * block-level settings.
+ * Resolves presets, note frontmatter and page-level config, merging them with
+ * block configuration. Presets, frontmatter and page configs provide base
+ * configuration that can be overridden by block-level settings, and the
+ * fence line shorthand overrides those in turn.
  */
 
-import type { ParsedYamlConfig } from '../types';
-import { parsePresetYaml } from '../parsers/yaml-parser';
+import type { ParsedYamlConfig, CascadeLayer, CascadeLayerSetting } from '../types';
+import { parsePresetYaml, parsePresetExtends, parsePresetParamDefaults, parsePresetConditions } from '../parsers/yaml-parser';
+import type { PresetParamValue, PresetReference } from '../parsers/preset-params';
+import { parsePresetReference, mergePresetParams } from '../parsers/preset-params';
 import { deepMergeYamlConfigs } from './config-merge';
+import { getActiveCascadeLayers } from './config-cascade';
 
 /**
- * Resolves presets and page-level config, merging them with block configuration.
+ * Removes META.PRESET from a config layer so it doesn't cascade.
  *
- * Looks up the preset name from either the block's META.PRESET or the
- * page-level config's META.PRESET. Parses the preset YAML and deep-merges
- * it as the lowest layer, then page config, then block config on top.
+ * @param config - Config layer
+ * @returns The layer without META.PRESET
+ */
+function stripPresetName(config: ParsedYamlConfig): ParsedYamlConfig {
+	if (!config.META?.PRESET) return config;
+
+	const restMeta = { ...config.META };
+	delete restMeta.PRESET;
+	return {
+		...config,
+		META: Object.keys(restMeta).length > 0 ? restMeta : undefined,
+	};
+}
+
+/**
+ * Splits a preset reference into the preset name and its arguments.
+ *
+ * A saved preset whose name contains brackets is matched by its full
+ * name first, so it keeps working.
+ *
+ * @param presetReference - e.g. "teaching" or `api-example(title="Create user")`
+ * @param presets - Map of preset names to raw YAML strings
+ * @returns Preset name and arguments
+ */
+export function resolvePresetReference(presetReference: string, presets: Record<string, string>): PresetReference {
+	return presetReference in presets
+		? { name: presetReference, args: {} }
+		: parsePresetReference(presetReference);
+}
+
+/**
+ * Resolves a named preset, including the presets it extends.
+ *
+ * Each preset named in EXTENDS is resolved (with its own EXTENDS) and
+ * merged in order, so later ones override earlier ones, then the preset's
+ * own settings are merged on top. Unknown names are skipped, as is a
+ * preset that would extend itself through a loop.
+ *
+ * Parameter values given in the reference fill the preset's ${name}
+ * references, and also reach the presets it extends (where an argument
+ * in the EXTENDS entry itself takes precedence).
+ *
+ * Each preset's WHEN entries whose conditions all hold are merged on top
+ * of its own settings, in the order written.
+ *
+ * @param presetReference - Name of the preset, optionally with arguments
+ * @param presets - Map of preset names to raw YAML strings
+ * @param conditions - Conditions that currently hold (e.g. "dark", "desktop")
+ * @param chain - Presets already being resolved (loop detection)
+ * @param inheritedParams - Parameter values from the preset that extends this one
+ * @returns Merged configuration (empty if the preset doesn't exist)
+ */
+export function resolvePresetConfig(
+	presetReference: string,
+	presets: Record<string, string>,
+	conditions: ReadonlySet<string> = new Set(),
+	chain: readonly string[] = [],
+	inheritedParams: Record<string, PresetParamValue | undefined> = {}
+): ParsedYamlConfig {
+	const { name, args } = resolvePresetReference(presetReference, presets);
+	const presetYaml: string | undefined = presets[name];
+	if (!presetYaml || chain.includes(name)) return {};
+
+	const params = mergePresetParams(inheritedParams, args);
+	const parentParams = mergePresetParams(parsePresetParamDefaults(presetYaml), params);
+
+	const innerChain = [...chain, name];
+	let result: ParsedYamlConfig = {};
+	for (const parentReference of parsePresetExtends(presetYaml)) {
+		result = deepMergeYamlConfigs(result, resolvePresetConfig(parentReference, presets, conditions, innerChain, parentParams));
+	}
+
+	result = deepMergeYamlConfigs(result, parsePresetYaml(presetYaml, params));
+	for (const entry of parsePresetConditions(presetYaml, params)) {
+		if (entry.conditions.length > 0 && entry.conditions.every(condition => conditions.has(condition))) {
+			result = deepMergeYamlConfigs(result, entry.config);
+		}
+	}
+	return result;
+}
+
+/**
+ * Resolves presets, frontmatter and page-level config, merging them with
+ * block configuration.
+ *
+ * Looks up the preset name from the highest layer that names one in
+ * META.PRESET (by default the fence line shorthand, then the block, then
+ * the page-level config, then the frontmatter), else the default preset
+ * for the block's language or the note's tags. Resolves the preset (with
+ * any presets it extends) and deep-merges the layers in cascade order,
+ * each overriding the ones before it. The note's folder preset is a layer
+ * of its own.
  *
- * Priority (lowest → highest):
- *   named preset ← page config (ufence-ufence inline) ← block config
+ * Default priority (lowest → highest):
+ *   folder preset ← note frontmatter (ufence:) ← page config (ufence-ufence inline) ← preset ← block config ← fence line shorthand
+ *
+ * The cascade setting can reorder the layers or switch off all but the
+ * block. A switched-off layer doesn't supply a preset name either, and
+ * switching off the preset layer ignores named and default presets.
+ *
+ * The default preset only supplies a preset name, so it never overrides a
+ * PRESET named anywhere else.
  *
  * @param blockConfig - Parsed YAML from the code block
  * @param presets - Map of preset names to raw YAML strings
  * @param pageConfig - Optional page-level config from a ufence-ufence block
- * @returns Merged configuration (or blockConfig unchanged if no preset/page config)
+ * @param frontmatterConfig - Optional config from the note's `ufence:` frontmatter
+ * @param defaultPreset - Optional preset assigned to the block's language or note's tags
+ * @param conditions - Conditions that currently hold, for the preset's WHEN entries
+ * @param cascade - Layer order from the settings (default order when omitted)
+ * @param folderPreset - Optional preset assigned to the note's folder
+ * @param shorthandConfig - Optional config from the fence line shorthand
+ * @returns Merged configuration (or blockConfig unchanged if there is nothing else to merge)
  */
 export function resolvePreset(
 	blockConfig: ParsedYamlConfig,
 	presets: Record<string, string>,
-	pageConfig?: ParsedYamlConfig
+	pageConfig?: ParsedYamlConfig,
+	frontmatterConfig?: ParsedYamlConfig,
+	defaultPreset?: string,
+	conditions?: ReadonlySet<string>,
+	cascade?: readonly CascadeLayerSetting[],
+	folderPreset?: string,
+	shorthandConfig?: ParsedYamlConfig
 ): ParsedYamlConfig {
-	// 1. Determine preset name: block META.PRESET > page config META.PRESET
-	const presetName = blockConfig.META?.PRESET ?? pageConfig?.META?.PRESET;
+	const activeLayers = getActiveCascadeLayers(cascade);
+	const ownConfigs: Partial<Record<CascadeLayer, ParsedYamlConfig>> = {
+		frontmatter: frontmatterConfig,
+		page: pageConfig,
+		block: blockConfig,
+		shorthand: shorthandConfig,
+	};
 
-	// 2. Start building the merged result from the bottom up
-	let result: ParsedYamlConfig = {};
-
-	// Layer 1: Named preset (lowest priority base)
-	if (presetName) {
-		const presetYaml = presets[presetName];
-		if (presetYaml) {
-			result = parsePresetYaml(presetYaml);
-		}
+	// 1. Determine preset name: the highest active layer naming one, else the language/tag default
+	let presetName: string | undefined;
+	if (activeLayers.includes('preset')) {
+		presetName = activeLayers.reduce<string | undefined>((name, layer) => ownConfigs[layer]?.META?.PRESET ?? name, defaultPreset);
 	}
+	const activeFolderPreset = activeLayers.includes('folder') ? folderPreset : undefined;
 
-	// Layer 2: Page-level inline config (middle priority)
-	if (pageConfig) {
-		// Strip META.PRESET from page config before merging (prevent cascading)
-		let cleanPageConfig = pageConfig;
-		if (pageConfig.META?.PRESET) {
-			const restMeta = { ...pageConfig.META };
-			delete restMeta.PRESET;
-			cleanPageConfig = {
-				...pageConfig,
-				META: Object.keys(restMeta).length > 0 ? restMeta : undefined,
-			};
-		}
-		result = deepMergeYamlConfigs(result, cleanPageConfig);
+	// If there is nothing to merge (no preset and no other active layer), return blockConfig as-is
+	if (!presetName && !activeFolderPreset && !activeLayers.some(layer => layer !== 'block' && ownConfigs[layer])) {
+		return blockConfig;
 	}
 
-	// Layer 3: Block-level config (highest priority)
-	result = deepMergeYamlConfigs(result, blockConfig);
+	// 2. Merge the layers in cascade order, lowest first. META.PRESET is
+	// stripped from frontmatter and page config (prevent cascading)
+	const layerConfigs: Record<CascadeLayer, ParsedYamlConfig | undefined> = {
+		folder: activeFolderPreset ? resolvePresetConfig(activeFolderPreset, presets, conditions) : undefined,
+		frontmatter: frontmatterConfig ? stripPresetName(frontmatterConfig) : undefined,
+		page: pageConfig ? stripPresetName(pageConfig) : undefined,
+		preset: presetName ? resolvePresetConfig(presetName, presets, conditions) : undefined,
+		block: blockConfig,
+		shorthand: shorthandConfig,
+	};
 
-	// If nothing was merged (no preset and no page config), return blockConfig as-is
-	if (!presetName && !pageConfig) {
-		return blockConfig;
+	let result: ParsedYamlConfig = {};
+	for (const layer of activeLayers) {
+		const layerConfig = layerConfigs[layer];
+		if (layerConfig) {
+			result = deepMergeYamlConfigs(result, layerConfig);
+		}
 	}
 
 	// Strip PRESET from final merged META (prevent cascading)
@@ -0,0 +1,108 @@
+/**
+ * Ultra Code Fence - Preset Switcher
+ *
+ * Sets or removes the preset named in a block's YAML settings
+ * (META.PRESET), or its language (RENDER.LANG), keeping the rest of the
+ * text — other settings, comments, the embedded code — untouched.
+ */
+
+import { YAML_SECTIONS, YAML_META, YAML_RENDER_DISPLAY } from '../constants';
+import { findKeyLines } from './config-rename';
+
+/** Indentation used for a META section the switcher adds. */
+const ADDED_INDENT = '  ';
+
+/** A preset name or language that can be written without quotes. */
+const PLAIN_VALUE_PATTERN = /^[A-Za-z0-9_][\w.+#-]*$/;
+
+/**
+ * Writes a preset name or language as a YAML value, quoting it when needed.
+ *
+ * @param value - Preset name or language
+ * @returns YAML scalar
+ */
+function formatPlainValue(value: string): string {
+	return PLAIN_VALUE_PATTERN.test(value) ? value : JSON.stringify(value);
+}
+
+/**
+ * Sets the preset a block's YAML settings name, or removes it.
+ *
+ * An existing PRESET line is rewritten in place; otherwise the line is
+ * added under META (which is added at the top when missing). Removing
+ * the preset also removes a META section left empty. A block of plain
+ * code gets a settings section and a ~~~ separator in front of the code.
+ *
+ * @param blockText - Block text (settings, optionally followed by ~~~ and code)
+ * @param presetName - Preset to use, or undefined to remove it
+ * @param hasSettings - Whether the block has a settings section (false for plain code)
+ * @returns Updated block text, or undefined when META isn't written as a block mapping
+ */
+export function setBlockPreset(blockText: string, presetName: string | undefined, hasSettings: boolean): string | undefined {
+	return setBlockSetting(blockText, YAML_SECTIONS.meta, YAML_META.preset, presetName, hasSettings);
+}
+
+/**
+ * Sets the language a block's YAML settings name (RENDER.LANG), or
+ * removes it, in the same way as {@link setBlockPreset}.
+ *
+ * @param blockText - Block text (settings, optionally followed by ~~~ and code)
+ * @param language - Language to use, or undefined to remove it
+ * @param hasSettings - Whether the block has a settings section (false for plain code)
+ * @returns Updated block text, or undefined when RENDER isn't written as a block mapping
+ */
+export function setBlockLanguage(blockText: string, language: string | undefined, hasSettings: boolean): string | undefined {
+	return setBlockSetting(blockText, YAML_SECTIONS.render, YAML_RENDER_DISPLAY.lang, language, hasSettings);
+}
+
+/**
+ * Sets or removes one key of a top-level settings section.
+ *
+ * @param blockText - Block text (settings, optionally followed by ~~~ and code)
+ * @param section - Section key, e.g. "META"
+ * @param key - Key within the section, e.g. "PRESET"
+ * @param value - Value to write, or undefined to remove the key
+ * @param hasSettings - Whether the block has a settings section (false for plain code)
+ * @returns Updated block text, or undefined when the section isn't written as a block mapping
+ */
+function setBlockSetting(
+	blockText: string,
+	section: string,
+	key: string,
+	value: string | undefined,
+	hasSettings: boolean
+): string | undefined {
+	if (!hasSettings) {
+		if (value === undefined) return blockText;
+		return `${section}:\n${ADDED_INDENT}${key}: ${formatPlainValue(value)}\n~~~\n${blockText}`;
+	}
+
+	const lines = blockText.split('\n');
+	const keyLines = findKeyLines(lines);
+	const sectionLine = keyLines.find(line => line.parentPath === '' && line.key === section);
+	const sectionChildren = keyLines.filter(line => line.parentPath === section);
+	const valueLine = sectionChildren.find(line => line.key === key);
+
+	// A flow mapping (META: {TITLE: x}) can't be edited line by line
+	if (sectionLine && !/^\s*:\s*(#.*)?$/.test(sectionLine.rest)) return undefined;
+
+	if (value === undefined) {
+		if (!valueLine) return blockText;
+		const removed = new Set([valueLine.index]);
+		if (sectionLine && sectionChildren.length === 1) removed.add(sectionLine.index);
+		return lines.filter((_line, index) => !removed.has(index)).join('\n');
+	}
+
+	const valueText = `${key}: ${formatPlainValue(value)}`;
+
+	if (valueLine) {
+		lines[valueLine.index] = valueLine.prefix + valueText;
+	} else if (sectionLine) {
+		const childIndent = sectionChildren.length > 0 ? sectionChildren[0].prefix : sectionLine.prefix + ADDED_INDENT;
+		lines.splice(sectionLine.index + 1, 0, childIndent + valueText);
+	} else {
+		lines.unshift(`${section}:`, ADDED_INDENT + valueText);
+	}
+
+	return lines.join('\n');
+}
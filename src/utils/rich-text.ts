@@ -0,0 +1,161 @@
+/**
+ * Ultra Code Fence - Rich Text Copy
+ *
+ * Turns a rendered code block into self-contained HTML for the clipboard.
+ * Colours come from the computed styles of the highlighted tokens, so the
+ * pasted snippet keeps the current theme's syntax colours in apps that
+ * accept rich text (email, Google Docs, Confluence).
+ */
+
+import { CSS_CLASSES, REDACTION_MARK } from '../constants';
+import { escapeHtml } from './formatting';
+
+// =============================================================================
+// Constants
+// =============================================================================
+
+/** Token styles copied inline when they differ from the parent element */
+const TOKEN_STYLE_PROPERTIES = ['color', 'background-color', 'font-weight', 'font-style', 'text-decoration-line'] as const;
+
+/** Computed values that mean "nothing to copy" for a token style */
+const EMPTY_STYLE_VALUES = new Set(['', 'none', 'normal', 'rgba(0, 0, 0, 0)', 'transparent']);
+
+// =============================================================================
+// HTML Building
+// =============================================================================
+
+/**
+ * Builds inline style declarations for an element, keeping only values
+ * that differ from its parent so the HTML stays small.
+ *
+ * @param element - Token element
+ * @param parentStyle - Computed style of the parent
+ * @returns Style attribute value (empty if nothing differs)
+ */
+function buildTokenStyle(element: Element, parentStyle: CSSStyleDeclaration): string {
+	const style = window.getComputedStyle(element);
+	const declarations: string[] = [];
+
+	for (const property of TOKEN_STYLE_PROPERTIES) {
+		const value = style.getPropertyValue(property);
+		if (EMPTY_STYLE_VALUES.has(value) || value === parentStyle.getPropertyValue(property)) continue;
+
+		const name = property === 'text-decoration-line' ? 'text-decoration' : property;
+		declarations.push(`${name}: ${value}`);
+	}
+
+	return declarations.join('; ');
+}
+
+/**
+ * Serialises the children of a node as HTML with inline token styles.
+ *
+ * Prompts are dropped and redacted text is replaced by the redaction
+ * mark, matching what the plain-text copy contains.
+ *
+ * @param node - Node whose children to serialise
+ * @returns HTML fragment
+ */
+function serialiseChildren(node: Node): string {
+	const parentStyle = node instanceof Element ? window.getComputedStyle(node) : null;
+	let html = '';
+
+	node.childNodes.forEach(child => {
+		if (child.nodeType === Node.TEXT_NODE) {
+			html += escapeHtml(child.textContent ?? '');
+			return;
+		}
+		if (!(child instanceof HTMLElement)) return;
+
+		if (child.classList.contains(CSS_CLASSES.prompt)) return;
+		if (child.classList.contains(CSS_CLASSES.redacted)) {
+			html += escapeHtml(REDACTION_MARK);
+			return;
+		}
+
+		const inner = serialiseChildren(child);
+		const style = parentStyle ? buildTokenStyle(child, parentStyle) : '';
+		html += style ? `<span style="${escapeHtml(style)}">${inner}</span>` : inner;
+	});
+
+	return html;
+}
+
+/**
+ * Reads the text of a node as {@link serialiseChildren} writes it:
+ * prompts dropped, redacted text replaced by the redaction mark.
+ *
+ * @param node - Node whose text to read
+ * @returns Plain text
+ */
+function serialiseText(node: Node): string {
+	let text = '';
+
+	node.childNodes.forEach(child => {
+		if (child.nodeType === Node.TEXT_NODE) {
+			text += child.textContent ?? '';
+			return;
+		}
+		if (!(child instanceof HTMLElement) || child.classList.contains(CSS_CLASSES.prompt)) return;
+		text += child.classList.contains(CSS_CLASSES.redacted) ? REDACTION_MARK : serialiseText(child);
+	});
+
+	return text;
+}
+
+/**
+ * Builds the HTML for a rich text copy of a rendered code block.
+ *
+ * The block keeps its theme background, text colour and monospace font.
+ * Lines for which `keepLine` returns false are left out (e.g. comment
+ * lines when comments are stripped from copies).
+ *
+ * The rendered block still shows {{NAME}} placeholders and variables
+ * that the copy fills in, so when the copied text is given, lines it
+ * changes are written from it, without token colours. If its lines
+ * can't be matched to the block's, all of it is written that way.
+ *
+ * @param codeElement - Code element of the rendered block
+ * @param keepLine - Decides per line text whether the line is copied
+ * @param copiedText - The plain text being copied, placeholders filled in
+ * @returns HTML document fragment for the clipboard
+ */
+export function buildRichTextHtml(
+	codeElement: HTMLElement,
+	keepLine: (lineText: string) => boolean = () => true,
+	copiedText?: string
+): string {
+	const blockStyle = window.getComputedStyle(codeElement.closest('pre') ?? codeElement);
+	const preStyle = [
+		`font-family: ${blockStyle.fontFamily || 'monospace'}`,
+		blockStyle.color ? `color: ${blockStyle.color}` : '',
+		EMPTY_STYLE_VALUES.has(blockStyle.backgroundColor) ? '' : `background-color: ${blockStyle.backgroundColor}`,
+		'padding: 8px 12px',
+		'white-space: pre',
+	].filter(Boolean).join('; ');
+
+	const lineElements = Array.from(codeElement.querySelectorAll<HTMLElement>(`.${CSS_CLASSES.line}`));
+	const lineContents = lineElements.length > 0
+		? lineElements.map(line => line.querySelector<HTMLElement>(`.${CSS_CLASSES.lineContent}`) ?? line)
+		: [codeElement];
+
+	const lines: { html: string; text: string }[] = [];
+	for (const content of lineContents) {
+		// Empty lines are rendered as a non-breaking space (see extractLineText)
+		const rawText = content.textContent ?? '';
+		const text = rawText === '\u00a0' ? '' : rawText;
+		if (!keepLine(text)) continue;
+		lines.push(text === '' ? { html: '', text: '' } : { html: serialiseChildren(content), text: serialiseText(content) });
+	}
+
+	let htmlLines = lines.map(line => line.html);
+	if (copiedText !== undefined && copiedText !== lines.map(line => line.text).join('\n')) {
+		const copiedLines = copiedText.split('\n');
+		const matched = copiedLines.length === lines.length && lines.every(line => !line.text.includes('\n'));
+		htmlLines = matched
+			? lines.map((line, index) => (line.text === copiedLines[index] ? line.html : escapeHtml(copiedLines[index])))
+			: copiedLines.map(escapeHtml);
+	}
+
+	return `<pre style="${escapeHtml(preStyle)}"><code>${htmlLines.join('\n')}</code></pre>`;
+}
@@ -0,0 +1,498 @@
+/**
+ * Semantic tokens for Ultra Code Fence
+ *
+ * Prism highlights code word by word, so a parameter looks like any
+ * other variable. A light scan of Go, TypeScript/JavaScript and Python
+ * finds what an IDE would colour by meaning:
+ *
+ * - function-definition: the name of a function or method where it is
+ *   defined (func, def, function, methods and named arrow functions)
+ * - parameter: a parameter, and its uses inside the function body
+ * - property-access: a field read after a dot (`user.name`, not calls)
+ *
+ * The names match Prism's token names, so TOKENS can restyle them. The
+ * scan works on the code with its strings and comments blanked out, so
+ * brackets and words inside them aren't read as code.
+ */
+
+/** Kinds of semantic token. */
+export type SemanticTokenType = 'function-definition' | 'parameter' | 'property-access';
+
+/**
+ * A semantic token found in code.
+ */
+export interface SemanticToken {
+	/** Start offset (inclusive) */
+	start: number;
+
+	/** End offset (exclusive) */
+	end: number;
+
+	/** What the text is */
+	type: SemanticTokenType;
+}
+
+/** Languages the scan understands. */
+export type SemanticLanguage = 'go' | 'python' | 'typescript';
+
+/** Language codes and the scan each uses. */
+const SEMANTIC_LANGUAGES: Record<string, SemanticLanguage | undefined> = {
+	go: 'go',
+	golang: 'go',
+	python: 'python',
+	py: 'python',
+	typescript: 'typescript',
+	ts: 'typescript',
+	tsx: 'typescript',
+	javascript: 'typescript',
+	js: 'typescript',
+	jsx: 'typescript',
+	mjs: 'typescript',
+};
+
+/** Words that are followed by brackets without being method names. */
+const TS_STATEMENT_WORDS = new Set(['if', 'for', 'while', 'switch', 'catch', 'with', 'function', 'return', 'typeof', 'await', 'new', 'super', 'import']);
+
+/** TypeScript words that can come before a parameter name. */
+const TS_PARAMETER_MODIFIERS = /^(?:(?:public|private|protected|readonly|override)\s+)*(?:\.\.\.)?/;
+
+/** An identifier at the start of some text. */
+const LEADING_IDENTIFIER = /^[A-Za-z_$][\w$]*/;
+
+/** A field read: a dot after a value, then a name not followed by a call. */
+const PROPERTY_ACCESS_PATTERN = /([\w$)\]])\s*\??\.\s*([A-Za-z_$][\w$]*)(?![\w$]|\s*\()/g;
+
+/**
+ * Finds the scan that applies to a language.
+ *
+ * @param language - Language code
+ * @returns The scan, or null if the language isn't covered
+ */
+export function semanticLanguage(language: string): SemanticLanguage | null {
+	return SEMANTIC_LANGUAGES[language.trim().toLowerCase()] ?? null;
+}
+
+/**
+ * Finds the bracket that closes the one at an index.
+ *
+ * @param code - Code with strings and comments blanked
+ * @param openIndex - Index of the opening bracket
+ * @returns Index of the closing bracket, or -1 if it isn't closed
+ */
+function findClosingBracket(code: string, openIndex: number): number {
+	const open = code[openIndex];
+	const close = open === '(' ? ')' : open === '[' ? ']' : '}';
+	let depth = 0;
+	for (let i = openIndex; i < code.length; i++) {
+		if (code[i] === open) depth++;
+		else if (code[i] === close && --depth === 0) return i;
+	}
+	return -1;
+}
+
+/**
+ * Splits a bracketed list at its top-level commas.
+ *
+ * @param code - Code with strings and comments blanked
+ * @param start - Index just inside the opening bracket
+ * @param end - Index of the closing bracket
+ * @returns Start and end of each item, untrimmed
+ */
+function splitList(code: string, start: number, end: number): Array<[number, number]> {
+	const items: Array<[number, number]> = [];
+	let depth = 0;
+	let itemStart = start;
+	for (let i = start; i < end; i++) {
+		const char = code[i];
+		if (char === '(' || char === '[' || char === '{' || char === '<') depth++;
+		else if (char === ')' || char === ']' || char === '}' || (char === '>' && code[i - 1] !== '=')) depth--;
+		else if (char === ',' && depth <= 0) {
+			items.push([itemStart, i]);
+			itemStart = i + 1;
+		}
+	}
+	items.push([itemStart, end]);
+	return items;
+}
+
+/**
+ * Finds the parameter name at the start of a list item.
+ *
+ * @param code - Code with strings and comments blanked
+ * @param item - Start and end of the item
+ * @param skip - Pattern of what may come before the name (modifiers, * or ...)
+ * @returns Start and end of the name, or null for destructuring and the like
+ */
+function itemName(code: string, item: [number, number], skip: RegExp): [number, number] | null {
+	const text = code.slice(item[0], item[1]);
+	const leading = text.length - text.trimStart().length;
+	const skipped = skip.exec(text.slice(leading))?.[0].length ?? 0;
+	const name = LEADING_IDENTIFIER.exec(text.slice(leading + skipped));
+	if (!name) return null;
+	const start = item[0] + leading + skipped;
+	return [start, start + name[0].length];
+}
+
+/**
+ * Reads the parameter names of a Go parameter list. Names are only
+ * present when some item has a type after a name, as in `(a, b int)`;
+ * a list of bare types such as `(int, error)` has none.
+ *
+ * @param code - Code with strings and comments blanked
+ * @param open - Index of the opening bracket
+ * @param close - Index of the closing bracket
+ * @returns Start and end of each name
+ */
+function goParameters(code: string, open: number, close: number): Array<[number, number]> {
+	const items = splitList(code, open + 1, close).filter(([start, end]) => code.slice(start, end).trim() !== '');
+	const named = items.some(([start, end]) => /^\s*[A-Za-z_]\w*\s+\S/.test(code.slice(start, end)));
+	if (!named) return [];
+
+	const names: Array<[number, number]> = [];
+	for (const item of items) {
+		const name = itemName(code, item, /^/);
+		if (name) names.push(name);
+	}
+	return names;
+}
+
+/**
+ * Reads the parameter names of a Python or TypeScript parameter list.
+ *
+ * @param code - Code with strings and comments blanked
+ * @param open - Index of the opening bracket
+ * @param close - Index of the closing bracket
+ * @param skip - What may come before a name
+ * @returns Start and end of each name
+ */
+function listParameters(code: string, open: number, close: number, skip: RegExp): Array<[number, number]> {
+	const names: Array<[number, number]> = [];
+	for (const item of splitList(code, open + 1, close)) {
+		const name = itemName(code, item, skip);
+		if (!name) continue;
+		// A name must be followed by its type, default or nothing
+		const rest = code.slice(name[1], item[1]);
+		if (/^\s*(?:[?:=]|$)/.test(rest)) names.push(name);
+	}
+	return names;
+}
+
+/**
+ * Finds the brace-delimited body after a signature, when it starts on
+ * the same line (after any return type).
+ *
+ * @param code - Code with strings and comments blanked
+ * @param from - Index after the parameter list
+ * @returns Start and end of the body, or null if there is none
+ */
+function braceBody(code: string, from: number): [number, number] | null {
+	let depth = 0;
+	for (let i = from; i < code.length; i++) {
+		const char = code[i];
+		if (char === '(' || char === '[') depth++;
+		else if (char === ')' || char === ']') depth--;
+		else if (depth === 0 && (char === ';' || char === '\n' || char === '=')) return null;
+		else if (depth === 0 && char === '{') {
+			const end = findClosingBracket(code, i);
+			return end === -1 ? [i, code.length] : [i, end];
+		}
+	}
+	return null;
+}
+
+/**
+ * Finds an indented Python body after a signature.
+ *
+ * @param code - Code with strings and comments blanked
+ * @param defStart - Index of the def keyword
+ * @param from - Index after the parameter list
+ * @returns Start and end of the body
+ */
+function indentedBody(code: string, defStart: number, from: number): [number, number] {
+	const lineStart = code.lastIndexOf('\n', defStart) + 1;
+	const indent = /^[ \t]*/.exec(code.slice(lineStart))?.[0].length ?? 0;
+
+	const firstLineEnd = code.indexOf('\n', from);
+	if (firstLineEnd === -1) return [from, code.length];
+
+	let end = firstLineEnd;
+	let position = firstLineEnd + 1;
+	while (position < code.length) {
+		const nextBreak = code.indexOf('\n', position);
+		const lineEnd = nextBreak === -1 ? code.length : nextBreak;
+		const line = code.slice(position, lineEnd);
+		if (line.trim() !== '') {
+			if ((/^[ \t]*/.exec(line)?.[0].length ?? 0) <= indent) break;
+			end = lineEnd;
+		}
+		position = lineEnd + 1;
+	}
+	return [from, end];
+}
+
+/**
+ * Finds the uses of parameters inside a function body.
+ *
+ * @param code - Code with strings and comments blanked
+ * @param names - Parameter names
+ * @param body - Start and end of the body
+ * @returns Start and end of each use
+ */
+function parameterUses(code: string, names: readonly string[], body: [number, number]): Array<[number, number]> {
+	const wanted = names.filter(name => name !== '_' && name !== '');
+	if (wanted.length === 0) return [];
+
+	const uses: Array<[number, number]> = [];
+	const pattern = /[A-Za-z_$][\w$]*/g;
+	pattern.lastIndex = body[0];
+	let match: RegExpExecArray | null;
+	while ((match = pattern.exec(code)) !== null && match.index < body[1]) {
+		if (!wanted.includes(match[0])) continue;
+		// Not a field of something else, nor a key in an object literal
+		const before = code.slice(0, match.index).trimEnd();
+		const isKey = /[{,]$/.test(before) && /^\s*:/.test(code.slice(match.index + match[0].length));
+		if (before.endsWith('.') || isKey) continue;
+		uses.push([match.index, match.index + match[0].length]);
+	}
+	return uses;
+}
+
+/**
+ * Collects the tokens of a function: its name, its parameters and their
+ * uses in its body.
+ *
+ * @param code - Code with strings and comments blanked
+ * @param tokens - Tokens found so far
+ * @param name - Start and end of the name (null for anonymous functions)
+ * @param parameters - Start and end of each parameter name
+ * @param body - Start and end of the body (null if there is none)
+ */
+function addFunction(
+	code: string,
+	tokens: SemanticToken[],
+	name: [number, number] | null,
+	parameters: Array<[number, number]>,
+	body: [number, number] | null
+): void {
+	if (name) tokens.push({ start: name[0], end: name[1], type: 'function-definition' });
+	for (const [start, end] of parameters) tokens.push({ start, end, type: 'parameter' });
+	if (body) {
+		const names = parameters.map(([start, end]) => code.slice(start, end));
+		for (const [start, end] of parameterUses(code, names, body)) tokens.push({ start, end, type: 'parameter' });
+	}
+}
+
+/**
+ * Scans Go: func declarations, methods (with their receiver) and
+ * function literals.
+ *
+ * @param code - Code with strings and comments blanked
+ * @param tokens - Tokens to add to
+ */
+function scanGo(code: string, tokens: SemanticToken[]): void {
+	const pattern = /\bfunc\b/g;
+	let match: RegExpExecArray | null;
+	while ((match = pattern.exec(code)) !== null) {
+		let position = match.index + match[0].length;
+		const skipSpace = (): void => {
+			while (position < code.length && /[ \t]/.test(code[position])) position++;
+		};
+		const parameters: Array<[number, number]> = [];
+		let name: [number, number] | null = null;
+
+		skipSpace();
+		let paramsOpen: number;
+		if (code[position] === '(') {
+			const close = findClosingBracket(code, position);
+			if (close === -1) continue;
+			const group: [number, number] = [position, close];
+			position = close + 1;
+			skipSpace();
+			const method = /^([A-Za-z_]\w*)\s*(?:\[[^\]]*\]\s*)?\(/.exec(code.slice(position));
+			if (method) {
+				// A method: the first group was its receiver
+				parameters.push(...goParameters(code, group[0], group[1]));
+				name = [position, position + method[1].length];
+				paramsOpen = position + method[0].length - 1;
+			} else {
+				paramsOpen = group[0];
+			}
+		} else {
+			const declared = /^([A-Za-z_]\w*)\s*(?:\[[^\]]*\]\s*)?\(/.exec(code.slice(position));
+			if (!declared) continue;
+			name = [position, position + declared[1].length];
+			paramsOpen = position + declared[0].length - 1;
+		}
+
+		const paramsClose = findClosingBracket(code, paramsOpen);
+		if (paramsClose === -1) continue;
+		parameters.push(...goParameters(code, paramsOpen, paramsClose));
+		addFunction(code, tokens, name, parameters, braceBody(code, paramsClose + 1));
+	}
+}
+
+/**
+ * Scans Python: def statements and lambdas.
+ *
+ * @param code - Code with strings and comments blanked
+ * @param tokens - Tokens to add to
+ */
+function scanPython(code: string, tokens: SemanticToken[]): void {
+	const skip = /^\*{0,2}/;
+	const defPattern = /\bdef\s+([A-Za-z_]\w*)\s*(?:\[[^\]]*\]\s*)?\(/g;
+	let match: RegExpExecArray | null;
+	while ((match = defPattern.exec(code)) !== null) {
+		const nameStart = match.index + match[0].indexOf(match[1], 3);
+		const open = match.index + match[0].length - 1;
+		const close = findClosingBracket(code, open);
+		if (close === -1) continue;
+		addFunction(code, tokens, [nameStart, nameStart + match[1].length], listParameters(code, open, close, skip), indentedBody(code, match.index, close + 1));
+	}
+
+	const lambdaPattern = /\blambda\b([^:\n]*):/g;
+	while ((match = lambdaPattern.exec(code)) !== null) {
+		const listStart = match.index + 'lambda'.length;
+		const listEnd = match.index + match[0].length - 1;
+		const lineEnd = code.indexOf('\n', listEnd);
+		const parameters = splitList(code, listStart, listEnd)
+			.map(item => itemName(code, item, skip))
+			.filter((name): name is [number, number] => name !== null);
+		addFunction(code, tokens, null, parameters, [listEnd + 1, lineEnd === -1 ? code.length : lineEnd]);
+	}
+}
+
+/**
+ * Scans TypeScript and JavaScript: function declarations and
+ * expressions, class and object methods, and arrow functions.
+ *
+ * @param code - Code with strings and comments blanked
+ * @param tokens - Tokens to add to
+ */
+function scanTypeScript(code: string, tokens: SemanticToken[]): void {
+	const seen = new Set<number>();
+	const addSignature = (name: [number, number] | null, open: number): void => {
+		if (seen.has(open)) return;
+		const close = findClosingBracket(code, open);
+		if (close === -1) return;
+		seen.add(open);
+		addFunction(code, tokens, name, listParameters(code, open, close, TS_PARAMETER_MODIFIERS), braceBody(code, close + 1));
+	};
+
+	// function name(…) and function (…)
+	const functionPattern = /\bfunction\b\s*\*?\s*([A-Za-z_$][\w$]*)?\s*(?:<[^>()]*>)?\s*\(/g;
+	let match: RegExpExecArray | null;
+	while ((match = functionPattern.exec(code)) !== null) {
+		const name = match[1] as string | undefined;
+		const nameStart = name ? match.index + match[0].indexOf(name, 'function'.length) : -1;
+		addSignature(name ? [nameStart, nameStart + name.length] : null, match.index + match[0].length - 1);
+	}
+
+	// Methods: name(…) { at the start of a line, with optional modifiers
+	const methodPattern = /^[ \t]*(?:(?:public|private|protected|static|async|readonly|override|get|set)\s+)*\*?([A-Za-z_$][\w$]*)\s*(?:<[^>()]*>)?\s*\(/gm;
+	while ((match = methodPattern.exec(code)) !== null) {
+		if (TS_STATEMENT_WORDS.has(match[1])) continue;
+		const open = match.index + match[0].length - 1;
+		const close = findClosingBracket(code, open);
+		if (close === -1 || !/^\s*(?::[^{;=]+)?\{/.test(code.slice(close + 1))) continue;
+		const nameStart = match.index + match[0].lastIndexOf(match[1], match[0].length - 1);
+		addSignature([nameStart, nameStart + match[1].length], open);
+	}
+
+	// Arrow functions: (…) => and x =>, named when assigned to a variable
+	const arrowPattern = /=>/g;
+	while ((match = arrowPattern.exec(code)) !== null) {
+		let before = code.slice(0, match.index).trimEnd();
+		// Step back over a return type: (a): number =>
+		const returnType = /\)\s*:[^()=;{}]*$/.exec(before);
+		if (returnType) before = before.slice(0, returnType.index + 1);
+
+		let parameters: Array<[number, number]> = [];
+		let listStart: number;
+		if (before.endsWith(')')) {
+			// Find the opening bracket by walking back
+			let depth = 0;
+			let open = -1;
+			for (let i = before.length - 1; i >= 0; i--) {
+				if (before[i] === ')') depth++;
+				else if (before[i] === '(' && --depth === 0) {
+					open = i;
+					break;
+				}
+			}
+			if (open === -1) continue;
+			parameters = listParameters(code, open, before.length - 1, TS_PARAMETER_MODIFIERS);
+			listStart = open;
+		} else {
+			const single = /([A-Za-z_$][\w$]*)$/.exec(before);
+			if (!single || /:\s*$/.test(before.slice(0, single.index))) continue;
+			parameters = [[single.index, single.index + single[1].length]];
+			listStart = single.index;
+		}
+
+		// The name it is assigned to on the same line, if any
+		const lineStart = code.lastIndexOf('\n', listStart - 1) + 1;
+		const assigned = /^(.*\b(?:const|let|var)\s+|[ \t]*|.*[{,]\s*)([A-Za-z_$][\w$]*)\s*(?::[^=\n]+)?[=:]\s*(?:async\s*)?$/.exec(code.slice(lineStart, listStart));
+		const nameStart = assigned ? lineStart + assigned[1].length : -1;
+		const name: [number, number] | null = assigned ? [nameStart, nameStart + assigned[2].length] : null;
+
+		let bodyStart = match.index + 2;
+		while (bodyStart < code.length && /[ \t]/.test(code[bodyStart])) bodyStart++;
+		let body: [number, number];
+		if (code[bodyStart] === '{') {
+			const end = findClosingBracket(code, bodyStart);
+			body = [bodyStart, end === -1 ? code.length : end];
+		} else {
+			const lineEnd = code.indexOf('\n', bodyStart);
+			body = [bodyStart, lineEnd === -1 ? code.length : lineEnd];
+		}
+		addFunction(code, tokens, name, parameters, body);
+	}
+}
+
+/**
+ * Finds field reads after a dot.
+ *
+ * @param code - Code with strings and comments blanked
+ * @param tokens - Tokens to add to
+ */
+function scanPropertyAccess(code: string, tokens: SemanticToken[]): void {
+	const pattern = new RegExp(PROPERTY_ACCESS_PATTERN.source, 'g');
+	let match: RegExpExecArray | null;
+	while ((match = pattern.exec(code)) !== null) {
+		// A number's decimal point is not a field read
+		if (/\d/.test(match[1]) && /(?:^|[^\w$])\d+$/.test(code.slice(0, match.index + 1))) continue;
+		const end = match.index + match[0].length;
+		tokens.push({ start: end - match[2].length, end, type: 'property-access' });
+	}
+}
+
+/**
+ * Finds the semantic tokens of some code.
+ *
+ * @param code - The code, with the text of strings and comments blanked
+ *   out (replaced by spaces, keeping line breaks)
+ * @param language - The block's language
+ * @returns Tokens in order, without overlaps; none for other languages
+ */
+export function findSemanticTokens(code: string, language: string): SemanticToken[] {
+	const scan = semanticLanguage(language);
+	if (!scan) return [];
+
+	const tokens: SemanticToken[] = [];
+	if (scan === 'go') scanGo(code, tokens);
+	else if (scan === 'python') scanPython(code, tokens);
+	else scanTypeScript(code, tokens);
+	scanPropertyAccess(code, tokens);
+
+	// Earlier scans win where tokens overlap (a parameter over a field read)
+	const ordered = tokens
+		.map((token, index) => ({ token, index }))
+		.sort((a, b) => a.token.start - b.token.start || a.index - b.index);
+	const result: SemanticToken[] = [];
+	for (const { token } of ordered) {
+		const last = result.length > 0 ? result[result.length - 1] : undefined;
+		if (last && token.start < last.end) continue;
+		result.push(token);
+	}
+	return result;
+}
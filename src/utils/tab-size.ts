@@ -0,0 +1,42 @@
+/**
+ * Tab widths for Ultra Code Fence
+ *
+ * Picks the width hard tabs render at: RENDER.TAB_SIZE on the block, then
+ * the language's width from settings ("go=4, makefile=8"), then the
+ * default width from settings.
+ */
+
+import type { PluginSettings } from '../types';
+
+/**
+ * Reads a list of language tab widths.
+ *
+ * @param listText - Comma-separated entries, e.g. "go=4, makefile: 8"
+ * @returns Width by lower-case language; malformed entries are skipped
+ */
+export function parseLanguageTabSizes(listText: string): Record<string, number | undefined> {
+	const sizes: Record<string, number | undefined> = {};
+
+	for (const entry of listText.split(',')) {
+		const match = /^\s*([\w+#.-]+)\s*[=:]\s*(\d+)\s*$/.exec(entry);
+		if (!match) continue;
+
+		const size = parseInt(match[2], 10);
+		if (size > 0) {
+			sizes[match[1].toLowerCase()] = size;
+		}
+	}
+
+	return sizes;
+}
+
+/**
+ * Finds the tab width for a language from the plugin settings.
+ *
+ * @param settings - Plugin settings
+ * @param language - Block language
+ * @returns The language's width, or the default width
+ */
+export function getDefaultTabSize(settings: PluginSettings, language: string): number {
+	return parseLanguageTabSizes(settings.languageTabSizes)[language.toLowerCase()] ?? settings.tabSize;
+}
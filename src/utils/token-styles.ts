@@ -0,0 +1,139 @@
+/**
+ * Token styles for Ultra Code Fence
+ *
+ * Reads the TOKENS section, which restyles highlighted tokens without a
+ * CSS snippet. Each style is a short list of words:
+ *
+ * - bold, italic, underline, strike: turn the style on
+ * - normal: undo the theme's bold, italic and underline
+ * - dim, faint or a percentage (60%): fade the token
+ * - on <colour>: background colour
+ * - any other colour (#a855f7, purple, rgb(…), var(--…)): text colour
+ *
+ * Styles for all languages come first; a language's own styles are
+ * applied after them, so they win for the same token. The PATTERNS
+ * section uses the same words for text matching a regex.
+ */
+
+import type { PatternStyle, TokenStyle, YamlPatternsConfig, YamlTokensConfig } from '../types';
+
+/** Prism token names that can be styled (also safe in a class selector). */
+const TOKEN_NAME_PATTERN = /^[a-z][a-z0-9_-]*$/;
+
+/** Words of a style; a colour function with spaces stays one word. */
+const STYLE_WORD_PATTERN = /[\w-]+\([^)]*\)|\S+/g;
+
+/** Words that read as a colour. */
+const COLOUR_PATTERN = /^(?:#[0-9a-f]{3,8}|[a-z]+|(?:rgba?|hsla?|var)\([^)]*\))$/i;
+
+/** Opacity of dim tokens. */
+const DIM_OPACITY = 0.6;
+
+/** Opacity of faint tokens. */
+const FAINT_OPACITY = 0.4;
+
+/**
+ * Reads one token's style words.
+ *
+ * @param token - Prism token name
+ * @param styleText - Style words, e.g. "bold #a855f7" or "dim italic"
+ * @returns Resolved style; unknown words are ignored
+ */
+export function parseTokenStyle(token: string, styleText: string): TokenStyle {
+	const style: TokenStyle = {
+		token,
+		colour: '',
+		background: '',
+		fontWeight: '',
+		fontStyle: '',
+		textDecoration: '',
+		opacity: undefined,
+	};
+
+	const words = styleText.match(STYLE_WORD_PATTERN) ?? [];
+	for (let i = 0; i < words.length; i++) {
+		const word = words[i];
+		const lower = word.toLowerCase();
+
+		if (lower === 'bold') {
+			style.fontWeight = 'bold';
+		} else if (lower === 'italic') {
+			style.fontStyle = 'italic';
+		} else if (lower === 'underline') {
+			style.textDecoration = 'underline';
+		} else if (lower === 'strike' || lower === 'strikethrough') {
+			style.textDecoration = 'line-through';
+		} else if (lower === 'normal') {
+			style.fontWeight = 'normal';
+			style.fontStyle = 'normal';
+			style.textDecoration = 'none';
+		} else if (lower === 'dim') {
+			style.opacity = DIM_OPACITY;
+		} else if (lower === 'faint') {
+			style.opacity = FAINT_OPACITY;
+		} else if (/^\d{1,3}%$/.test(lower)) {
+			style.opacity = Math.min(parseInt(lower, 10), 100) / 100;
+		} else if (lower === 'on') {
+			if (i + 1 < words.length && COLOUR_PATTERN.test(words[i + 1])) {
+				style.background = words[i + 1];
+				i++;
+			}
+		} else if (COLOUR_PATTERN.test(word)) {
+			style.colour = word;
+		}
+	}
+
+	return style;
+}
+
+/**
+ * Picks the token styles that apply to a block.
+ *
+ * @param tokens - Parsed TOKENS section
+ * @param language - The block's language
+ * @returns Styles to apply in order: all-language styles, then the language's own
+ */
+export function resolveTokenStyles(tokens: YamlTokensConfig | undefined, language: string): TokenStyle[] {
+	if (!tokens) return [];
+
+	const styles: TokenStyle[] = [];
+	const languageKey = language.trim().toLowerCase();
+
+	for (const [token, value] of Object.entries(tokens)) {
+		if (typeof value === 'string' && TOKEN_NAME_PATTERN.test(token)) {
+			styles.push(parseTokenStyle(token, value));
+		}
+	}
+
+	const languageTokens = tokens[languageKey];
+	if (languageTokens && typeof languageTokens === 'object') {
+		for (const [token, value] of Object.entries(languageTokens)) {
+			if (TOKEN_NAME_PATTERN.test(token)) {
+				styles.push(parseTokenStyle(token, value));
+			}
+		}
+	}
+
+	return styles;
+}
+
+/**
+ * Reads the PATTERNS rules of a block.
+ *
+ * @param patterns - Parsed PATTERNS section
+ * @returns Rules in order; invalid regexes are skipped
+ */
+export function resolvePatternStyles(patterns: YamlPatternsConfig | undefined): PatternStyle[] {
+	if (!patterns) return [];
+
+	const styles: PatternStyle[] = [];
+	for (const [source, styleText] of Object.entries(patterns)) {
+		if (!source) continue;
+		try {
+			styles.push({ pattern: new RegExp(source, 'g'), style: parseTokenStyle('', styleText) });
+		} catch {
+			// Invalid regex: skip the rule
+		}
+	}
+	return styles;
+}
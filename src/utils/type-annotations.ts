@@ -0,0 +1,401 @@
+/**
+ * Ultra Code Fence - Type Annotations
+ *
+ * Strips TypeScript's type syntax from code, so a TypeScript block can
+ * be run as JavaScript without a compiler. Only the common forms are
+ * understood: annotations on variables, parameters and return values,
+ * `as` casts, `!` assertions, generic parameters of function and method
+ * declarations, interfaces, type aliases and type-only imports. Enums and
+ * generic arguments of calls would need compiling, so they are refused
+ * with an error rather than left to fail as a syntax error.
+ */
+
+/** Words whose parentheses hold a condition or expression, not parameters. */
+const NON_PARAMETER_KEYWORDS = new Set(['if', 'for', 'while', 'switch', 'with', 'return', 'typeof', 'await', 'yield', 'new', 'in', 'of']);
+
+/** Modifiers that only TypeScript allows before a class member. */
+const MEMBER_MODIFIERS = new Set(['public', 'private', 'protected', 'override', 'readonly', 'abstract']);
+
+/** Modifiers that only TypeScript allows before a constructor parameter. */
+const PARAMETER_MODIFIER_PATTERN = /^(\s*)(?:(?:public|private|protected|readonly|override)\s+)+/;
+
+/** Declarations removed whole, up to their closing brace or end. */
+const INTERFACE_PATTERN = /^(?:export\s+)?(?:declare\s+)?interface\s+[\w$]+[^{]*\{/;
+const TYPE_ALIAS_PATTERN = /^(?:export\s+)?(?:declare\s+)?type\s+[\w$]+\s*(?:<[^>]*>)?\s*=/;
+const TYPE_IMPORT_PATTERN = /^(?:import|export)\s+type\s[^;\n]*;?/;
+
+/** The type after `as`: const, a name with optional generic arguments, and array brackets. */
+const CAST_TYPE_PATTERN = /^\s+(?:const\b|[\w$.]+(?:<[^<>]*>)?(?:\[\])*)/;
+
+/** Words after which a `/` starts a regex literal rather than dividing. */
+const REGEX_PRECEDING_KEYWORDS = new Set(['return', 'typeof', 'case', 'do', 'else', 'in', 'of', 'new', 'delete', 'void', 'throw', 'yield', 'await', 'instanceof']);
+
+/** An enum declaration, after any `export`, `declare` or `const`. */
+const ENUM_PATTERN = /^enum\s+[\w$]+\s*\{/;
+
+/** Text between a call's angle brackets that reads as type arguments. */
+const TYPE_ARGUMENTS_PATTERN = /^[\w$\s.,|&[\]<>'"{}:;?]*$/;
+
+/** Closing brackets by opening bracket. */
+const CLOSERS: Record<string, string | undefined> = { '(': ')', '[': ']', '{': '}', '<': '>' };
+
+// =============================================================================
+// Scanning
+// =============================================================================
+
+/**
+ * Checks whether a `/` starts a regex literal: it does where a value is
+ * expected, so not after a name, number or closing bracket unless the
+ * name is a keyword such as `return`.
+ *
+ * @param code - The code
+ * @param index - Index of the `/`
+ * @returns True if a regex literal starts there
+ */
+function startsRegex(code: string, index: number): boolean {
+	const before = code.slice(0, index).replace(/\s+$/, '');
+	if (!before || !/[\w$)\]]$/.test(before)) return true;
+
+	const word = /[\w$]+$/.exec(before)?.[0];
+	return word !== undefined && REGEX_PRECEDING_KEYWORDS.has(word) && !/[\w$.]$/.test(before.slice(0, -word.length));
+}
+
+/**
+ * Finds the end of a regex literal, with its flags. A `/` inside a
+ * character class doesn't end it.
+ *
+ * @param code - The code
+ * @param index - Index of the opening `/`
+ * @returns Index just past it, or the index itself if the line ends first
+ */
+function skipRegex(code: string, index: number): number {
+	let inClass = false;
+	for (let position = index + 1; position < code.length; position++) {
+		const char = code[position];
+		if (char === '\n') return index;
+		if (char === '\\') {
+			position++;
+		} else if (char === '[') {
+			inClass = true;
+		} else if (char === ']') {
+			inClass = false;
+		} else if (char === '/' && !inClass) {
+			return position + 1 + (/^[a-z]*/.exec(code.slice(position + 1))?.[0].length ?? 0);
+		}
+	}
+	return index;
+}
+
+/**
+ * Finds the end of a string, template literal, regex literal or comment
+ * starting at an index.
+ *
+ * @param code - The code
+ * @param index - Where to look
+ * @returns Index just past it, or the index itself if none starts there
+ */
+function skipLiteral(code: string, index: number): number {
+	const char = code[index];
+	if (char === '/' && code[index + 1] === '/') {
+		const end = code.indexOf('\n', index);
+		return end === -1 ? code.length : end;
+	}
+	if (char === '/' && code[index + 1] === '*') {
+		const end = code.indexOf('*/', index + 2);
+		return end === -1 ? code.length : end + 2;
+	}
+	if (char === '/') {
+		return startsRegex(code, index) ? skipRegex(code, index) : index;
+	}
+	if (char !== '"' && char !== "'" && char !== '`') return index;
+
+	for (let position = index + 1; position < code.length; position++) {
+		if (code[position] === '\\') {
+			position++;
+		} else if (code[position] === char) {
+			return position + 1;
+		} else if (char !== '`' && code[position] === '\n') {
+			return position;
+		}
+	}
+	return code.length;
+}
+
+/**
+ * Finds the bracket closing one, skipping strings and comments.
+ *
+ * @param code - The code
+ * @param openIndex - Index of the opening bracket
+ * @returns Index of the closing bracket, or -1 if it isn't closed
+ */
+function findClosingBracket(code: string, openIndex: number): number {
+	const stack: string[] = [];
+	for (let position = openIndex; position < code.length; position++) {
+		const literalEnd = skipLiteral(code, position);
+		if (literalEnd > position) {
+			position = literalEnd - 1;
+			continue;
+		}
+
+		const char = code[position];
+		const closer = CLOSERS[char];
+		if (char === '>' && code[position - 1] === '=') {
+			// The arrow of a function type inside angle brackets
+			continue;
+		}
+		if (closer && (char !== '<' || code[openIndex] === '<')) {
+			stack.push(closer);
+		} else if (char === stack[stack.length - 1]) {
+			stack.pop();
+			if (stack.length === 0) return position;
+		}
+	}
+	return -1;
+}
+
+/**
+ * Finds where a type ends: at a character of a stop set outside any
+ * brackets, or the end of the code.
+ *
+ * @param code - The code
+ * @param start - Where the type starts
+ * @param stops - Characters that end it
+ * @param stopAtArrow - Whether `=>` ends it too (after a return type) rather than being part of a function type
+ * @returns Index of the stop character
+ */
+function findTypeEnd(code: string, start: number, stops: string, stopAtArrow = false): number {
+	for (let position = start; position < code.length; position++) {
+		const char = code[position];
+		const isArrow = char === '=' && code[position + 1] === '>';
+		if (isArrow ? stopAtArrow : stops.includes(char)) {
+			return position;
+		}
+		if (isArrow) {
+			position++;
+		} else if (CLOSERS[char]) {
+			const close = findClosingBracket(code, position);
+			if (close === -1) return code.length;
+			position = close;
+		} else {
+			const literalEnd = skipLiteral(code, position);
+			if (literalEnd > position) position = literalEnd - 1;
+		}
+	}
+	return code.length;
+}
+
+/**
+ * Splits text at commas outside any brackets.
+ *
+ * @param text - e.g. a parameter list
+ * @returns The parts, commas left out
+ */
+function splitTopLevel(text: string): string[] {
+	const parts: string[] = [];
+	let start = 0;
+	for (let position = 0; position < text.length; position++) {
+		const literalEnd = skipLiteral(text, position);
+		if (literalEnd > position) {
+			position = literalEnd - 1;
+		} else if (text[position] === ',') {
+			parts.push(text.slice(start, position));
+			start = position + 1;
+		} else if (text[position] !== '<' && CLOSERS[text[position]]) {
+			const close = findClosingBracket(text, position);
+			if (close !== -1) position = close;
+		}
+	}
+	parts.push(text.slice(start));
+	return parts;
+}
+
+// =============================================================================
+// Stripping
+// =============================================================================
+
+/**
+ * Finds the end of the return type after a parameter list, if the
+ * parentheses are a function's and it has one. After an arrow
+ * function's parameters the type runs to the `=>`; after a function or
+ * method's, to the `{` of its body. A colon anywhere else, as in a
+ * ternary, isn't a return type.
+ *
+ * @param code - The code
+ * @param close - Index of the closing parenthesis (-1 = unclosed)
+ * @param output - Code stripped so far, up to the opening parenthesis
+ * @returns Index of the `=>` or `{` after the type, or -1 if there is no return type
+ */
+function findReturnTypeEnd(code: string, close: number, output: string): number {
+	if (close === -1) return -1;
+	const colonMatch = /^\s*:/.exec(code.slice(close + 1));
+	if (!colonMatch) return -1;
+
+	const typeEnd = findTypeEnd(code, close + 1 + colonMatch[0].length, '{;,)\n', true);
+	if (code.startsWith('=>', typeEnd)) return typeEnd;
+
+	const line = output.slice(output.lastIndexOf('\n') + 1);
+	const isDeclaration = /\bfunction\s*\*?\s*[\w$]*\s*$/.test(output)
+		|| /^\s*(?:(?:async|static|get|set)\s+)*\*?\s*[\w$]+\s*$/.test(line);
+	return code[typeEnd] === '{' && isDeclaration ? typeEnd : -1;
+}
+
+/**
+ * Strips the types from a parameter list.
+ *
+ * @param parameters - Text between the parentheses
+ * @returns The parameters as JavaScript
+ */
+function stripParameters(parameters: string): string {
+	return splitTopLevel(parameters).map((parameter) => {
+		const bare = parameter.replace(PARAMETER_MODIFIER_PATTERN, '$1');
+		const colon = findTypeEnd(bare, 0, ':=');
+		if (bare[colon] !== ':') return stripTypeAnnotations(bare);
+
+		const typeEnd = findTypeEnd(bare, colon + 1, '=');
+		const name = bare.slice(0, colon).replace(/\?\s*$/, '');
+		const trailingSpace = /\s*$/.exec(bare.slice(colon, typeEnd))?.[0] ?? '';
+		const defaultValue = typeEnd < bare.length ? ' ' + stripTypeAnnotations(bare.slice(typeEnd)).trimStart() : trailingSpace;
+		return stripTypeAnnotations(name) + defaultValue;
+	}).join(',');
+}
+
+/**
+ * Strips TypeScript's type syntax from code, leaving JavaScript.
+ *
+ * Strings, template literals, regex literals and comments are left
+ * alone. Other types the stripper doesn't understand (namespaces, class
+ * field types) are kept, and will fail when run.
+ *
+ * @param code - TypeScript code
+ * @returns The code as JavaScript
+ * @throws Error naming the code, for an enum or generic arguments of a call
+ */
+export function stripTypeAnnotations(code: string): string {
+	let output = '';
+	let index = 0;
+
+	while (index < code.length) {
+		const literalEnd = skipLiteral(code, index);
+		if (literalEnd > index) {
+			output += code.slice(index, literalEnd);
+			index = literalEnd;
+			continue;
+		}
+
+		const char = code[index];
+		const rest = code.slice(index);
+		const wordMatch = /^[\w$]+/.exec(rest);
+
+		if (wordMatch && (index === 0 || !/[\w$.]/.test(code[index - 1]))) {
+			const word = wordMatch[0];
+
+			// Interfaces, type aliases and type-only imports go whole
+			const interfaceMatch = INTERFACE_PATTERN.exec(rest);
+			if (interfaceMatch) {
+				const close = findClosingBracket(code, index + interfaceMatch[0].length - 1);
+				index = close === -1 ? code.length : close + 1;
+				continue;
+			}
+			const aliasMatch = TYPE_ALIAS_PATTERN.exec(rest);
+			if (aliasMatch) {
+				const start = index + aliasMatch[0].length;
+				let end = findTypeEnd(code, start, ';\n');
+				// A union or intersection can go on over the next lines
+				while (code[end] === '\n' && (!code.slice(start, end).trim() || /^\s*[|&]/.test(code.slice(end + 1)))) {
+					end = findTypeEnd(code, end + 1, ';\n');
+				}
+				index = code[end] === ';' ? end + 1 : end;
+				continue;
+			}
+			const importMatch = TYPE_IMPORT_PATTERN.exec(rest);
+			if (importMatch) {
+				index += importMatch[0].length;
+				continue;
+			}
+
+			if (ENUM_PATTERN.test(rest)) {
+				throw new Error(`Can't run '${/^[^{]*/.exec(rest)?.[0].trim() ?? word}' without compiling: enums aren't supported, use an object instead`);
+			}
+
+			// pick<T>(items: T[]): T { in a class, or a call such as useState<number>(0)
+			const typeArgumentsClose = code[index + word.length] === '<' ? findClosingBracket(code, index + word.length) : -1;
+			const typeArguments = typeArgumentsClose === -1 ? '' : code.slice(index + word.length + 1, typeArgumentsClose);
+			if (typeArgumentsClose !== -1 && code[typeArgumentsClose + 1] === '(' && TYPE_ARGUMENTS_PATTERN.test(typeArguments) && !/&&|\|\|/.test(typeArguments)) {
+				const parametersClose = findClosingBracket(code, typeArgumentsClose + 1);
+				const line = output.slice(output.lastIndexOf('\n') + 1);
+				const isMethod = parametersClose !== -1 && /^\s*[:{]/.test(code.slice(parametersClose + 1))
+					&& /^\s*(?:(?:async|static|get|set)\s+)*\*?\s*$/.test(line);
+				if (!isMethod) {
+					throw new Error(`Can't run '${code.slice(index, typeArgumentsClose + 2)}' without compiling: generic arguments of calls aren't supported, leave them out`);
+				}
+				output += word;
+				index = typeArgumentsClose + 1;
+				continue;
+			}
+
+			// private helper(): void {
+			if (MEMBER_MODIFIERS.has(word) && /(?:^|\n)[ \t]*$/.test(output) && /^[\w$]+\s+[\w$*]/.test(rest)) {
+				index += word.length;
+				while (code[index] === ' ' || code[index] === '\t') index++;
+				continue;
+			}
+
+			// let total: number = 0
+			const declarationMatch = /^(?:let|const|var)\s+[\w$]+(\s*):/.exec(rest);
+			if (declarationMatch) {
+				output += declarationMatch[0].slice(0, -1 - declarationMatch[1].length);
+				const typeEnd = findTypeEnd(code, index + declarationMatch[0].length, '=;,\n');
+				index = typeEnd;
+				if (code[typeEnd] === '=') output += ' ';
+				continue;
+			}
+
+			// function pick<T>(
+			const functionMatch = /^function\s*\*?\s*[\w$]*\s*</.exec(rest);
+			if (functionMatch) {
+				output += functionMatch[0].slice(0, -1).replace(/\s+$/, '');
+				const close = findClosingBracket(code, index + functionMatch[0].length - 1);
+				index = close === -1 ? code.length : close + 1;
+				continue;
+			}
+
+			// value as Type
+			const castMatch = word === 'as' ? CAST_TYPE_PATTERN.exec(code.slice(index + 2)) : null;
+			if (castMatch && /[\w$)\]'"`]\s*$/.test(output)) {
+				output = output.replace(/\s+$/, '');
+				index += 2 + castMatch[0].length;
+				continue;
+			}
+
+			output += word;
+			index += word.length;
+			continue;
+		}
+
+		// Parameter lists, with any return type after them
+		if (char === '(') {
+			const close = findClosingBracket(code, index);
+			const after = close === -1 ? '' : code.slice(close + 1);
+			const before = /([\w$]+)\s*$/.exec(output)?.[1] ?? '';
+			const returnTypeEnd = findReturnTypeEnd(code, close, output);
+
+			if (close !== -1 && !NON_PARAMETER_KEYWORDS.has(before) && (returnTypeEnd !== -1 || /^\s*(?:=>|\{)/.test(after))) {
+				output += '(' + stripParameters(code.slice(index + 1, close)) + ')';
+				index = returnTypeEnd === -1 ? close + 1 : returnTypeEnd;
+				if (returnTypeEnd !== -1) output += ' ';
+				continue;
+			}
+		}
+
+		// value!.property
+		if (char === '!' && /[\w$)\]]$/.test(output) && /^[.),;\]]/.test(code.slice(index + 1))) {
+			index++;
+			continue;
+		}
+
+		output += char;
+		index++;
+	}
+
+	return output;
+}
@@ -0,0 +1,54 @@
+/**
+ * Whitespace visualisation for Ultra Code Fence
+ *
+ * Finds the spaces and tabs in a line, so they can be drawn as faint
+ * glyphs where invisible characters matter (Makefiles, YAML, diffs).
+ */
+
+/**
+ * A run of spaces, or a single tab, within a line.
+ */
+export interface WhitespaceRun {
+	/** Start offset (inclusive) */
+	start: number;
+
+	/** End offset (exclusive) */
+	end: number;
+
+	/** Whether the run is spaces or tabs */
+	kind: 'space' | 'tab';
+
+	/** Whether the run is part of the line's trailing whitespace */
+	trailing: boolean;
+}
+
+/**
+ * Finds the runs of spaces and the tabs in a line. Each tab is its own
+ * run, so each gets its own arrow.
+ *
+ * A line made only of whitespace counts as trailing whitespace.
+ *
+ * @param lineText - Line text
+ * @param trailingOnly - Only return the trailing whitespace
+ * @returns Runs in line order
+ */
+export function findWhitespaceRuns(lineText: string, trailingOnly: boolean): WhitespaceRun[] {
+	const runs: WhitespaceRun[] = [];
+	const trailingStart = lineText.replace(/[ \t]+$/, '').length;
+	const pattern = / +|\t/g;
+
+	let match: RegExpExecArray | null;
+	while ((match = pattern.exec(lineText)) !== null) {
+		const trailing = match.index >= trailingStart;
+		if (trailingOnly && !trailing) continue;
+
+		runs.push({
+			start: match.index,
+			end: match.index + match[0].length,
+			kind: match[0] === '\t' ? 'tab' : 'space',
+			trailing,
+		});
+	}
+
+	return runs;
+}
@@ -0,0 +1,32 @@
+/**
+ * Tests for src/constants/comments.ts
+ *
+ * Covers: getCommentSyntax
+ */
+
+import { describe, it, expect } from 'vitest';
+import { getCommentSyntax } from '../../src/constants/comments';
+
+describe('getCommentSyntax', () => {
+	it('returns hash comments for shell and Python', () => {
+		expect(getCommentSyntax('bash')?.line).toEqual(['#']);
+		expect(getCommentSyntax('python')?.line).toEqual(['#']);
+	});
+
+	it('returns line and block comments for C-family languages', () => {
+		expect(getCommentSyntax('typescript')).toEqual({ line: ['//'], block: ['/*', '*/'] });
+	});
+
+	it('returns dash comments for SQL', () => {
+		expect(getCommentSyntax('sql')?.line).toEqual(['--']);
+	});
+
+	it('is case-insensitive', () => {
+		expect(getCommentSyntax('JS')).toBe(getCommentSyntax('js'));
+	});
+
+	it('returns undefined for unknown or missing languages', () => {
+		expect(getCommentSyntax('markdown')).toBeUndefined();
+		expect(getCommentSyntax(undefined)).toBeUndefined();
+	});
+});
@@ -0,0 +1,123 @@
+/**
+ * Tests for src/parsers/config-format.ts
+ *
+ * Covers: detectConfigFormat, parseConfigFormatFromInfoString, parseConfigText,
+ *         expandYamlMergeKeys
+ */
+
+import { describe, it, expect } from 'vitest';
+import {
+	detectConfigFormat,
+	parseConfigFormatFromInfoString,
+	parseConfigText,
+	expandYamlMergeKeys,
+} from '../../src/parsers/config-format';
+
+describe('detectConfigFormat', () => {
+	it('detects JSON from a leading brace', () => {
+		expect(detectConfigFormat('{ "RENDER": { "LINES": true } }')).toBe('json');
+	});
+
+	it('detects TOML from a table header', () => {
+		expect(detectConfigFormat('[RENDER]\nLINES = true')).toBe('toml');
+		expect(detectConfigFormat('[[COPY.AS]]\nFORMAT = "json"')).toBe('toml');
+	});
+
+	it('detects TOML from a key = value line', () => {
+		expect(detectConfigFormat('PROMPT = "^\\\\$ "')).toBe('toml');
+	});
+
+	it('skips leading comments and blank lines', () => {
+		expect(detectConfigFormat('\n# settings\n[RENDER]')).toBe('toml');
+	});
+
+	it('defaults to YAML', () => {
+		expect(detectConfigFormat('RENDER:\n  LINES: true')).toBe('yaml');
+		expect(detectConfigFormat('[a, b]')).toBe('yaml');
+		expect(detectConfigFormat('')).toBe('yaml');
+	});
+});
+
+describe('parseConfigFormatFromInfoString', () => {
+	it('reads a format word after the language', () => {
+		expect(parseConfigFormatFromInfoString('```ufence-bash toml')).toBe('toml');
+		expect(parseConfigFormatFromInfoString('~~~ufence-python JSON')).toBe('json');
+	});
+
+	it('returns undefined when no format is named', () => {
+		expect(parseConfigFormatFromInfoString('```ufence-bash')).toBeUndefined();
+		expect(parseConfigFormatFromInfoString('```ufence-bash title')).toBeUndefined();
+	});
+});
+
+describe('parseConfigText', () => {
+	const expected = { RENDER: { LINES: true }, COPY: { AS: [{ FORMAT: 'json' }] } };
+
+	it('parses the same settings from YAML, TOML and JSON', () => {
+		expect(parseConfigText('RENDER:\n  LINES: true\nCOPY:\n  AS:\n    - FORMAT: json')).toEqual(expected);
+		expect(parseConfigText('[RENDER]\nLINES = true\n\n[[COPY.AS]]\nFORMAT = "json"')).toEqual(expected);
+		expect(parseConfigText('{"RENDER": {"LINES": true}, "COPY": {"AS": [{"FORMAT": "json"}]}}')).toEqual(expected);
+	});
+
+	it('uses the given format instead of detecting one', () => {
+		expect(() => parseConfigText('RENDER:\n  LINES: true', 'json')).toThrow();
+	});
+
+	it('reads a YAML flow mapping that was taken for JSON as YAML', () => {
+		expect(parseConfigText('{META: {TITLE: x}}')).toEqual({ META: { TITLE: 'x' } });
+		expect(() => parseConfigText('{META: {TITLE: x}}', 'json')).toThrow();
+		expect(() => parseConfigText('{"META": ')).toThrow(SyntaxError);
+	});
+
+	it('returns an empty object for empty YAML', () => {
+		expect(parseConfigText('', 'yaml')).toEqual({});
+	});
+
+	it('rejects settings that are not an object', () => {
+		expect(() => parseConfigText('[1, 2]', 'json')).toThrow('Block settings must be a JSON object');
+	});
+});
+
+describe('expandYamlMergeKeys', () => {
+	it('merges an anchored mapping, with explicit keys winning', () => {
+		const muted = { COLOUR: '#888', ITALIC: true };
+		expect(expandYamlMergeKeys({ PROMPT: { '<<': muted, ITALIC: false, BOLD: true } }))
+			.toEqual({ PROMPT: { COLOUR: '#888', ITALIC: false, BOLD: true } });
+	});
+
+	it('lets earlier mappings in a merge list win', () => {
+		expect(expandYamlMergeKeys({ '<<': [{ A: 1 }, { A: 2, B: 2 }] })).toEqual({ A: 1, B: 2 });
+	});
+
+	it('copies aliased values so they are no longer shared', () => {
+		const shared = { COLOUR: '#888' };
+		const result = expandYamlMergeKeys({ PROMPT: shared, OUTPUT: shared }) as Record<string, Record<string, unknown>>;
+		result.PROMPT.COLOUR = '#fff';
+		expect(result.OUTPUT.COLOUR).toBe('#888');
+		expect(shared.COLOUR).toBe('#888');
+	});
+
+	it('leaves scalars, lists and dates alone', () => {
+		const date = new Date(0);
+		expect(expandYamlMergeKeys({ LIST: [1, 'a'], WHEN: date, N: null })).toEqual({ LIST: [1, 'a'], WHEN: date, N: null });
+	});
+});
+
+describe('parseConfigText — anchors and aliases', () => {
+	it('resolves aliases and merge keys in YAML settings', () => {
+		const yaml = [
+			'_muted: &muted',
+			'  COLOUR: "#888"',
+			'  ITALIC: true',
+			'RENDER:',
+			'  PROMPT:',
+			'    <<: *muted',
+			'    BOLD: true',
+			'  OUTPUT: *muted',
+		].join('\n');
+		expect(parseConfigText(yaml).RENDER).toEqual({
+			PROMPT: { COLOUR: '#888', ITALIC: true, BOLD: true },
+			OUTPUT: { COLOUR: '#888', ITALIC: true },
+		});
+	});
+});
@@ -0,0 +1,94 @@
+/**
+ * Tests for src/parsers/info-string.ts
+ *
+ * Covers: parseInfoStringOptions, mergeInfoStringOptions, stripInfoStringOptions
+ */
+
+import { describe, it, expect } from 'vitest';
+import {
+	parseInfoStringOptions,
+	mergeInfoStringOptions,
+	stripInfoStringOptions,
+} from '../../src/parsers/info-string';
+import { parseConfigFormatFromInfoString } from '../../src/parsers/config-format';
+
+describe('parseInfoStringOptions', () => {
+	it('returns nothing without a brace group', () => {
+		expect(parseInfoStringOptions('```ufence-bash toml')).toEqual({ settings: {}, problems: [] });
+	});
+
+	it('reads flags, quoted text and numbers', () => {
+		const { settings, problems } = parseInfoStringOptions('```ufence-bash {ln zebra title="Install it" fold=20}');
+		expect(problems).toEqual([]);
+		expect(settings).toEqual({
+			META: { TITLE: 'Install it' },
+			RENDER: { LINES: true, ZEBRA: true, FOLD: 20 },
+		});
+	});
+
+	it('negates flags with ! and accepts true/false values', () => {
+		const { settings } = parseInfoStringOptions("```ufence-bash {!copy, linecopy=false, desc='x'}");
+		expect(settings).toEqual({ META: { DESC: 'x' }, RENDER: { COPY: false, LINE_COPY: false } });
+	});
+
+	it('names a vault file with src=', () => {
+		expect(parseInfoStringOptions('```ufence-bash {src=scripts/deploy.sh#L10-42}').settings)
+			.toEqual({ META: { SRC: 'scripts/deploy.sh#L10-42' } });
+	});
+
+	it('sets the first line number with start=', () => {
+		expect(parseInfoStringOptions('```ufence-bash {ln start=120}').settings)
+			.toEqual({ RENDER: { LINES: true, LINE_START: 120 } });
+	});
+
+	it('sets the relative numbering anchor with anchor=', () => {
+		expect(parseInfoStringOptions('```ufence-bash {ln anchor=4}').settings)
+			.toEqual({ RENDER: { LINES: true, LINE_ANCHOR: 4 } });
+	});
+
+	it('collapses a block with a summary', () => {
+		expect(parseInfoStringOptions("```ufence-sql {collapsed summary='Full schema'}").settings)
+			.toEqual({ META: { SUMMARY: 'Full schema' }, RENDER: { COLLAPSED: true } });
+	});
+
+	it('turns copy=<format> into a Copy as entry', () => {
+		expect(parseInfoStringOptions('```ufence-bash {copy=dockerfile}').settings)
+			.toEqual({ COPY: { AS: [{ FORMAT: 'dockerfile' }] } });
+	});
+
+	it('sets top-level PROMPT for cmdout blocks', () => {
+		expect(parseInfoStringOptions('```ufence-cmdout {prompt="^\\\\$ "}').settings)
+			.toEqual({ PROMPT: '^\\\\$ ' });
+	});
+
+	it('reports unknown and malformed options', () => {
+		const { settings, problems } = parseInfoStringOptions('```ufence-bash {lnz fold=lots title !zebra=maybe}');
+		expect(settings).toEqual({});
+		expect(problems).toEqual([
+			'Unknown shorthand option: lnz',
+			'Shorthand option fold should be a number',
+			'Shorthand option title needs a value, e.g. title=…',
+			'Shorthand option zebra should be true or false',
+		]);
+	});
+});
+
+describe('mergeInfoStringOptions', () => {
+	it('lets the settings section win key by key', () => {
+		const shorthand = { META: { TITLE: 'Short' }, RENDER: { LINES: true, FOLD: 5 } };
+		const yamlProps = { RENDER: { FOLD: 10 }, HIGHLIGHT: { LINES: '2' } };
+		expect(mergeInfoStringOptions(shorthand, yamlProps)).toEqual({
+			META: { TITLE: 'Short' },
+			RENDER: { LINES: true, FOLD: 10 },
+			HIGHLIGHT: { LINES: '2' },
+		});
+		expect(shorthand.RENDER.FOLD).toBe(5);
+	});
+});
+
+describe('stripInfoStringOptions', () => {
+	it('keeps format words outside the braces and ignores those inside', () => {
+		expect(parseConfigFormatFromInfoString(stripInfoStringOptions('```ufence-bash {title="json"} toml'))).toBe('toml');
+		expect(parseConfigFormatFromInfoString('```ufence-bash {title="json"}')).toBeUndefined();
+	});
+});
@@ -1,16 +1,18 @@
 /**
  * Tests for src/parsers/line-extractor.ts
  *
- * Covers: extractBetweenMarkersWithOptions, extractLines,
- *         parseLineSpec, extractLineRange, countLines,
+ * Covers: extractBetweenMarkersWithOptions, extractNamedRegion, extractLines,
+ *         parseLineSpec, extractLineRange, replaceLineRange, countLines,
  *         trimTrailingEmptyLines, applyFilterChain
  */
 
 import { describe, it, expect } from 'vitest';
 import {
 	extractBetweenMarkersWithOptions,
+	extractNamedRegion,
 	extractLines,
 	extractLineRange,
+	replaceLineRange,
 	countLines,
 	trimTrailingEmptyLines,
 	applyFilterChain,
@@ -44,6 +46,7 @@ function testConfig(
 		sourcePath: null,
 		titleTemplate: '',
 		descriptionText: '',
+		configMode: 'lenient',
 		titleBarStyle: 'tab',
 		language: 'txt',
 		foldLines: 0,
@@ -146,6 +149,36 @@ describe('extractBetweenMarkersWithOptions', () => {
 	});
 });
 
+// =============================================================================
+// extractNamedRegion
+// =============================================================================
+
+describe('extractNamedRegion', () => {
+	const REGION_CODE = [
+		'import x;',
+		'// ufence:begin setup',
+		'init();',
+		'# ufence:begin inner',
+		'configure();',
+		'# ufence:end inner',
+		'// ufence:end setup',
+		'run();',
+	].join('\n');
+
+	it('extracts the lines between the markers, without them', () => {
+		expect(extractNamedRegion(REGION_CODE, 'inner')).toEqual({ content: 'configure();', error: null });
+	});
+
+	it('leaves out the markers of nested regions', () => {
+		expect(extractNamedRegion(REGION_CODE, 'setup').content).toBe('init();\nconfigure();');
+	});
+
+	it('reports a missing region or end marker', () => {
+		expect(extractNamedRegion(REGION_CODE, 'Setup').error).toContain('not found');
+		expect(extractNamedRegion('<!-- ufence:begin docs -->\ntext', 'docs').error).toContain('ufence:end docs');
+	});
+});
+
 // =============================================================================
 // extractLines
 // =============================================================================
@@ -306,6 +339,23 @@ describe('extractLineRange', () => {
 	});
 });
 
+describe('replaceLineRange', () => {
+	const code = 'a\nb\nc\nd';
+
+	it('replaces the lines extractLineRange extracts', () => {
+		expect(replaceLineRange(code, 2, 3, 'B\nC\nC2')).toBe('a\nB\nC\nC2\nd');
+		expect(replaceLineRange(code, 1, 4, 'B', { inclusive: false })).toBe('a\nB\nd');
+	});
+
+	it('clamps the range to the source', () => {
+		expect(replaceLineRange(code, 3, 10, 'x')).toBe('a\nb\nx');
+	});
+
+	it('leaves the source alone when the range holds no lines', () => {
+		expect(replaceLineRange(code, 2, 2, 'x', { inclusive: false })).toBe(code);
+	});
+});
+
 // =============================================================================
 // countLines
 // =============================================================================
@@ -403,6 +453,12 @@ describe('applyFilterChain', () => {
 		expect(result.error).toBeNull();
 	});
 
+	it('applies BY_REGION before BY_LINES', () => {
+		const code = 'header\n// ufence:begin body\nfirst\nsecond\n// ufence:end body';
+		const config = { ...testConfig({ enabled: true, start: 2, end: 2, inclusive: true }), filterByRegion: 'body' };
+		expect(applyFilterChain(code, config)).toEqual({ content: 'second', error: null });
+	});
+
 	it('returns error when BY_MARKS markers not found', () => {
 		const config = testConfig(undefined, {
 			enabled: true, startMarker: '// MISSING', endMarker: '// END', inclusive: false,
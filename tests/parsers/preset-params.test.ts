@@ -0,0 +1,68 @@
+/**
+ * Tests for src/parsers/preset-params.ts
+ *
+ * Covers: parsePresetReference, substitutePresetParams, getPresetParamDefaults, mergePresetParams
+ */
+
+import { describe, it, expect } from 'vitest';
+import {
+	parsePresetReference,
+	substitutePresetParams,
+	getPresetParamDefaults,
+	mergePresetParams,
+} from '../../src/parsers/preset-params';
+
+describe('parsePresetReference', () => {
+	it('returns a plain name without arguments', () => {
+		expect(parsePresetReference(' teaching ')).toEqual({ name: 'teaching', args: {} });
+	});
+
+	it('reads quoted and bare arguments', () => {
+		expect(parsePresetReference('api-example(title="Create user, v2", lang=\'http\', startLine=5, wrap=false)')).toEqual({
+			name: 'api-example',
+			args: { title: 'Create user, v2', lang: 'http', startLine: 5, wrap: false },
+		});
+	});
+
+	it('accepts empty brackets', () => {
+		expect(parsePresetReference('api-example()')).toEqual({ name: 'api-example', args: {} });
+	});
+});
+
+describe('substitutePresetParams', () => {
+	const params = { title: 'Create user', start: 5, missing: undefined };
+
+	it('keeps the type of a value that is only a reference', () => {
+		expect(substitutePresetParams({ FILTER: { BY_LINES: { START: '${start}' } } }, params))
+			.toEqual({ FILTER: { BY_LINES: { START: 5 } } });
+	});
+
+	it('substitutes references inside text and lists', () => {
+		expect(substitutePresetParams({ META: { TITLE: 'API: ${title} (from ${ start })' }, HIGHLIGHT: { LINES: ['${start}'] } }, params))
+			.toEqual({ META: { TITLE: 'API: Create user (from 5)' }, HIGHLIGHT: { LINES: [5] } });
+	});
+
+	it('leaves unknown and unset references alone', () => {
+		expect(substitutePresetParams('${missing} ${other}', params)).toBe('${missing} ${other}');
+		expect(substitutePresetParams(true, params)).toBe(true);
+	});
+});
+
+describe('getPresetParamDefaults', () => {
+	it('reads scalar defaults and lists other parameters as unset', () => {
+		expect(getPresetParamDefaults({ PARAMS: { title: 'Example', start: 1, lines: null, list: [1] } }))
+			.toEqual({ title: 'Example', start: 1, lines: undefined, list: undefined });
+	});
+
+	it('returns nothing without a PARAMS mapping', () => {
+		expect(getPresetParamDefaults({ PARAMS: ['title'] })).toEqual({});
+		expect(getPresetParamDefaults({})).toEqual({});
+	});
+});
+
+describe('mergePresetParams', () => {
+	it('lets set values override and keeps lower values for unset ones', () => {
+		expect(mergePresetParams({ title: 'Example', start: 1 }, { title: 'Create user', start: undefined }))
+			.toEqual({ title: 'Create user', start: 1 });
+	});
+});
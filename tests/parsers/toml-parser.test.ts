@@ -0,0 +1,97 @@
+/**
+ * Tests for src/parsers/toml-parser.ts
+ *
+ * Covers: parseToml (tables, arrays of tables, keys, strings, numbers,
+ *         arrays, inline tables, errors)
+ */
+
+import { describe, it, expect } from 'vitest';
+import { parseToml } from '../../src/parsers/toml-parser';
+
+describe('parseToml — tables and keys', () => {
+	it('parses top-level keys and tables', () => {
+		expect(parseToml('PROMPT = "^\\\\$ "\n\n[RENDER]\nLINES = true\nFOLD = 10\n')).toEqual({
+			PROMPT: '^\\$ ',
+			RENDER: { LINES: true, FOLD: 10 },
+		});
+	});
+
+	it('parses dotted table headers and dotted keys', () => {
+		expect(parseToml('[FILTER.BY_LINES]\nRANGE = "1, 10"\n\n[META]\nTITLE.TEXT = "x"')).toEqual({
+			FILTER: { BY_LINES: { RANGE: '1, 10' } },
+			META: { TITLE: { TEXT: 'x' } },
+		});
+	});
+
+	it('parses arrays of tables', () => {
+		expect(parseToml('[[COPY.AS]]\nFORMAT = "json"\n\n[[COPY.AS]]\nFORMAT = "heredoc"\nTARGET = "/etc/app.conf"')).toEqual({
+			COPY: { AS: [{ FORMAT: 'json' }, { FORMAT: 'heredoc', TARGET: '/etc/app.conf' }] },
+		});
+	});
+
+	it('accepts quoted keys', () => {
+		expect(parseToml('"my key" = 1\n\'other\' = 2')).toEqual({ 'my key': 1, other: 2 });
+	});
+
+	it('ignores comments and blank lines', () => {
+		expect(parseToml('# settings\n\n[RENDER] # display\nZEBRA = false # stripes\n')).toEqual({ RENDER: { ZEBRA: false } });
+	});
+});
+
+describe('parseToml — values', () => {
+	it('parses basic strings with escapes', () => {
+		expect(parseToml('a = "tab\\there \\"q\\" \\u00e9"')).toEqual({ a: 'tab\there "q" é' });
+	});
+
+	it('parses literal strings without escapes', () => {
+		expect(parseToml('a = \'C:\\\\path\\\\\'')).toEqual({ a: 'C:\\\\path\\\\' });
+	});
+
+	it('parses multi-line strings, dropping the first newline', () => {
+		expect(parseToml('a = """\nline 1\nline 2"""\nb = \'\'\'\nraw \\n\'\'\'')).toEqual({ a: 'line 1\nline 2', b: 'raw \\n' });
+	});
+
+	it('joins lines ending in a backslash in multi-line basic strings', () => {
+		expect(parseToml('a = """one \\\n    two"""')).toEqual({ a: 'one two' });
+	});
+
+	it('parses integers, floats and booleans', () => {
+		expect(parseToml('a = 42\nb = -7\nc = 1_000\nd = 0x1F\ne = 3.5\nf = 1e3\ng = true\nh = false')).toEqual({
+			a: 42, b: -7, c: 1000, d: 31, e: 3.5, f: 1000, g: true, h: false,
+		});
+	});
+
+	it('keeps dates as strings', () => {
+		expect(parseToml('a = 2024-05-01')).toEqual({ a: '2024-05-01' });
+	});
+
+	it('parses multi-line arrays with comments and a trailing comma', () => {
+		expect(parseToml('REDACT = [\n  "tokens", # built-in\n  "pin: (\\\\d+)",\n]')).toEqual({ REDACT: ['tokens', 'pin: (\\d+)'] });
+	});
+
+	it('parses inline tables', () => {
+		expect(parseToml('TEXT = { COLOUR = "#fff", BOLD = true }')).toEqual({ TEXT: { COLOUR: '#fff', BOLD: true } });
+	});
+});
+
+describe('parseToml — errors', () => {
+	it('rejects duplicate keys', () => {
+		expect(() => parseToml('a = 1\na = 2')).toThrow('TOML line 2: duplicate key "a"');
+	});
+
+	it('rejects unterminated strings', () => {
+		expect(() => parseToml('a = "open')).toThrow(/unterminated string/);
+	});
+
+	it('rejects bare words as values', () => {
+		expect(() => parseToml('a = yes')).toThrow(/invalid value "yes"/);
+	});
+
+	it('rejects trailing text after a value', () => {
+		expect(() => parseToml('a = 1 2')).toThrow(/expected end of line/);
+	});
+
+	it('rejects a missing equals sign', () => {
+		expect(() => parseToml('[RENDER]\nLINES true')).toThrow('TOML line 2: expected "="');
+	});
+});
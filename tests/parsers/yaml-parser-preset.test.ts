@@ -2,6 +2,10 @@ import { describe, it, expect } from 'vitest';
 import {
 	parseMetaSection,
 	parsePresetYaml,
+	parsePresetExtends,
+	parsePresetConditions,
+	parsePresetAppliesToTags,
+	parseFrontmatterConfig,
 } from '../../src/parsers/yaml-parser';
 import type { ParsedYamlConfig } from '../../src/types';
 
@@ -240,3 +244,84 @@ describe('parsePresetYaml — additional edge cases', () => {
 		expect(result.FILTER).toBeDefined();
 	});
 });
+
+// =============================================================================
+// parseFrontmatterConfig tests
+// =============================================================================
+
+describe('parseFrontmatterConfig', () => {
+	it('returns undefined when the note has no ufence mapping', () => {
+		expect(parseFrontmatterConfig(undefined)).toBeUndefined();
+		expect(parseFrontmatterConfig({ tags: ['a'] })).toBeUndefined();
+		expect(parseFrontmatterConfig({ ufence: 'teaching' })).toBeUndefined();
+	});
+
+	it('parses sections from the ufence mapping', () => {
+		const result = parseFrontmatterConfig({ ufence: { RENDER: { LINES: true, FOLD: 30 } } });
+		expect(result?.RENDER?.LINES).toBe(true);
+		expect(result?.RENDER?.FOLD).toBe(30);
+	});
+
+	it('moves a top-level PRESET into META without changing the frontmatter', () => {
+		const frontmatter = { ufence: { PRESET: 'teaching', META: { TITLE: 'x' } } };
+		const result = parseFrontmatterConfig(frontmatter);
+		expect(result?.META?.PRESET).toBe('teaching');
+		expect(result?.META?.TITLE).toBe('x');
+		expect(frontmatter.ufence).toEqual({ PRESET: 'teaching', META: { TITLE: 'x' } });
+	});
+});
+
+// =============================================================================
+// parsePresetExtends
+// =============================================================================
+
+describe('parsePresetExtends', () => {
+	it('reads a single preset name', () => {
+		expect(parsePresetExtends('EXTENDS: base\nRENDER:\n  LINES: true')).toEqual(['base']);
+	});
+
+	it('reads a list of preset names in order', () => {
+		expect(parsePresetExtends('EXTENDS:\n  - base\n  - " wide "')).toEqual(['base', 'wide']);
+	});
+
+	it('returns nothing without EXTENDS or for unparseable YAML', () => {
+		expect(parsePresetExtends('RENDER:\n  LINES: true')).toEqual([]);
+		expect(parsePresetExtends('')).toEqual([]);
+		expect(parsePresetExtends('EXTENDS: [unclosed')).toEqual([]);
+	});
+});
+
+// =============================================================================
+// parsePresetConditions
+// =============================================================================
+
+describe('parsePresetConditions', () => {
+	it('reads each WHEN entry with its conditions', () => {
+		const yaml = 'PARAMS:\n  fold: 5\nWHEN:\n  Dark:\n    RENDER:\n      ZEBRA: false\n  dark  mobile:\n    RENDER:\n      FOLD: ${fold}';
+		expect(parsePresetConditions(yaml)).toEqual([
+			{ conditions: ['dark'], config: expect.objectContaining({ RENDER: expect.objectContaining({ ZEBRA: false }) }) },
+			{ conditions: ['dark', 'mobile'], config: expect.objectContaining({ RENDER: expect.objectContaining({ FOLD: 5 }) }) },
+		]);
+	});
+
+	it('returns nothing without a WHEN mapping', () => {
+		expect(parsePresetConditions('RENDER:\n  LINES: true')).toEqual([]);
+		expect(parsePresetConditions('WHEN:\n  - dark')).toEqual([]);
+	});
+});
+
+// =============================================================================
+// parsePresetAppliesToTags
+// =============================================================================
+
+describe('parsePresetAppliesToTags', () => {
+	it('reads a single tag or a list, without # and in lower case', () => {
+		expect(parsePresetAppliesToTags('APPLIES_TO_TAGS: Runbook')).toEqual(['runbook']);
+		expect(parsePresetAppliesToTags('APPLIES_TO_TAGS: ["#runbook", " Ops/DB ", 2024]')).toEqual(['runbook', 'ops/db', '2024']);
+	});
+
+	it('returns nothing without APPLIES_TO_TAGS or for unparseable YAML', () => {
+		expect(parsePresetAppliesToTags('RENDER:\n  LINES: true')).toEqual([]);
+		expect(parsePresetAppliesToTags('APPLIES_TO_TAGS: [unclosed')).toEqual([]);
+	});
+});
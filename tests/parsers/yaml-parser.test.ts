@@ -8,15 +8,40 @@ import {
 	parseMetaSection,
 	parseRenderDisplaySection,
 	parseLineRange,
+	parseSourceReference,
+	parseRefreshInterval,
+	parseRunTimeout,
+	parseMaxHeight,
+	parseFontSize,
+	parseRulerColumns,
+	parseLineList,
 	parseFilterSection,
 	parseRenderCmdoutSection,
+	parseCopySection,
+	parseHighlightSection,
+	parseDownloadSection,
+	parsePrintSection,
+	parseRunSection,
+	parseHeaderSection,
+	parseFooterSection,
+	parseAnnotationsSection,
+	resolveAnnotations,
+	parseBlameSection,
+	resolveBlame,
+	parseTokensSection,
+	parsePatternsSection,
+	parseDiffSection,
 	parseBlockContent,
 	parseNestedYamlConfig,
+	applyConfigRenames,
 	resolveBlockConfig,
 	resolveCmdoutConfig,
+	resolveCopyFeedback,
+	resolveConfigMode,
 } from '../../src/parsers/yaml-parser';
 import type { ParsedYamlConfig } from '../../src/types';
 import { testSettings } from '../helpers/test-settings';
+import { BUILT_IN_REDACTIONS, DEFAULT_COPY_MESSAGE } from '../../src/constants';
 
 // =============================================================================
 // Value Resolution
@@ -227,6 +252,10 @@ describe('parseMetaSection', () => {
 		expect(result.DESC).toBeUndefined();
 	});
 
+	it('extracts ID without a leading caret', () => {
+		expect(parseMetaSection({ META: { ID: ' ^setup ' } }).ID).toBe('setup');
+	});
+
 	it('returns all undefined when META is missing', () => {
 		const result = parseMetaSection({});
 		expect(result.PATH).toBeUndefined();
@@ -250,6 +279,10 @@ describe('parseMetaSection', () => {
 		expect(result.DESC).toBe('42');
 	});
 
+	it('extracts MODE', () => {
+		expect(parseMetaSection({ META: { MODE: 'strict' } }).MODE).toBe('strict');
+	});
+
 	it('handles META as non-object (ignored)', () => {
 		expect(parseMetaSection({ META: 'not an object' }).PATH).toBeUndefined();
 		expect(parseMetaSection({ META: [1, 2] }).PATH).toBeUndefined();
@@ -335,6 +368,62 @@ describe('parseRenderDisplaySection', () => {
 		expect(result.ALT_COPY_JOIN).toBe('cmd-op');
 	});
 
+	it('resolves LINE_START as a whole number', () => {
+		expect(parseRenderDisplaySection({ RENDER: { LINE_START: 120 } }).LINE_START).toBe(120);
+		expect(parseRenderDisplaySection({ RENDER: { LINE_START: '42' } }).LINE_START).toBe(42);
+		expect(parseRenderDisplaySection({ RENDER: { LINE_START: 7.9 } }).LINE_START).toBe(7);
+		expect(parseRenderDisplaySection({ RENDER: {} }).LINE_START).toBeUndefined();
+	});
+
+	it('reads WHITESPACE as a mode, mapping booleans to all or none', () => {
+		expect(parseRenderDisplaySection({ RENDER: { WHITESPACE: 'Trailing' } }).WHITESPACE).toBe('trailing');
+		expect(parseRenderDisplaySection({ RENDER: { WHITESPACE: true } }).WHITESPACE).toBe('all');
+		expect(parseRenderDisplaySection({ RENDER: { WHITESPACE: false } }).WHITESPACE).toBe('none');
+		expect(parseRenderDisplaySection({ RENDER: {} }).WHITESPACE).toBeUndefined();
+	});
+
+	it('reads COMMENT_KEYWORDS as a mode, mapping booleans to on or off', () => {
+		expect(parseRenderDisplaySection({ RENDER: { COMMENT_KEYWORDS: 'Icons' } }).COMMENT_KEYWORDS).toBe('icons');
+		expect(parseRenderDisplaySection({ RENDER: { COMMENT_KEYWORDS: true } }).COMMENT_KEYWORDS).toBe('on');
+		expect(parseRenderDisplaySection({ RENDER: { COMMENT_KEYWORDS: false } }).COMMENT_KEYWORDS).toBe('off');
+	});
+
+	it('reads BRACKETS as a boolean', () => {
+		expect(parseRenderDisplaySection({ RENDER: { BRACKETS: true } }).BRACKETS).toBe(true);
+		expect(parseRenderDisplaySection({ RENDER: {} }).BRACKETS).toBeUndefined();
+	});
+
+	it('reads LIGATURES and FONT_FEATURES written as a string or a list', () => {
+		expect(parseRenderDisplaySection({ RENDER: { LIGATURES: false } }).LIGATURES).toBe(false);
+		expect(parseRenderDisplaySection({ RENDER: {} }).LIGATURES).toBeUndefined();
+		expect(parseRenderDisplaySection({ RENDER: { FONT_FEATURES: 'ss01, zero' } }).FONT_FEATURES).toBe('ss01, zero');
+		expect(parseRenderDisplaySection({ RENDER: { FONT_FEATURES: ['ss01', 'cv02=3'] } }).FONT_FEATURES).toBe('ss01, cv02=3');
+	});
+
+	it('reads MAX_HEIGHT numbers as text', () => {
+		expect(parseRenderDisplaySection({ RENDER: { MAX_HEIGHT: 30 } }).MAX_HEIGHT).toBe('30');
+	});
+
+	it('resolves LINE_ANCHOR as a whole number, never negative', () => {
+		expect(parseRenderDisplaySection({ RENDER: { LINE_ANCHOR: 4 } }).LINE_ANCHOR).toBe(4);
+		expect(parseRenderDisplaySection({ RENDER: { LINE_ANCHOR: '3.5' } }).LINE_ANCHOR).toBe(3);
+		expect(parseRenderDisplaySection({ RENDER: { LINE_ANCHOR: -2 } }).LINE_ANCHOR).toBe(0);
+		expect(parseRenderDisplaySection({ RENDER: {} }).LINE_ANCHOR).toBeUndefined();
+	});
+
+	it('extracts the fold region settings', () => {
+		const result = parseRenderDisplaySection({ RENDER: { FOLD_REGIONS: 'Collapsed', FOLD_START: '<editor-fold', FOLD_END: '</editor-fold>' } });
+		expect(result.FOLD_REGIONS).toBe('collapsed');
+		expect(result.FOLD_START).toBe('<editor-fold');
+		expect(result.FOLD_END).toBe('</editor-fold>');
+	});
+
+	it('resolves LINE_COPY as boolean', () => {
+		expect(parseRenderDisplaySection({ RENDER: { LINE_COPY: true } }).LINE_COPY).toBe(true);
+		expect(parseRenderDisplaySection({ RENDER: { LINE_COPY: 'false' } }).LINE_COPY).toBe(false);
+		expect(parseRenderDisplaySection({ RENDER: {} }).LINE_COPY).toBeUndefined();
+	});
+
 	it('lowercases PRINT property', () => {
 		expect(parseRenderDisplaySection({ RENDER: { PRINT: 'Expand' } }).PRINT).toBe('expand');
 		expect(parseRenderDisplaySection({ RENDER: { PRINT: 'ASIS' } }).PRINT).toBe('asis');
@@ -408,6 +497,156 @@ describe('parseLineRange', () => {
 	});
 });
 
+describe('parseSourceReference', () => {
+	it('reads a path with a line range', () => {
+		expect(parseSourceReference('scripts/deploy.sh#L10-42')).toEqual({ path: 'vault://scripts/deploy.sh', range: [10, 42] });
+		expect(parseSourceReference('scripts/deploy.sh#L10-L42')).toEqual({ path: 'vault://scripts/deploy.sh', range: [10, 42] });
+		expect(parseSourceReference('scripts/deploy.sh#L7')).toEqual({ path: 'vault://scripts/deploy.sh', range: [7, 7] });
+	});
+
+	it('reads a path without a range as the whole file', () => {
+		expect(parseSourceReference('scripts/deploy.sh')).toEqual({ path: 'vault://scripts/deploy.sh', range: null });
+		expect(parseSourceReference('vault://scripts/deploy.sh')).toEqual({ path: 'vault://scripts/deploy.sh', range: null });
+		expect(parseSourceReference('/scripts/deploy.sh#L3')).toEqual({ path: 'vault://scripts/deploy.sh', range: [3, 3] });
+	});
+
+	it('reads a URL, keeping a fragment that is not a line range', () => {
+		expect(parseSourceReference('https://example.com/install.sh#L5-9')).toEqual({ path: 'https://example.com/install.sh', range: [5, 9] });
+		expect(parseSourceReference('https://example.com/docs#usage')).toEqual({ path: 'https://example.com/docs#usage', range: null });
+	});
+
+	it('reads a file in a git checkout at a ref', () => {
+		expect(parseSourceReference('tools:bin/install.sh@v1.4.2')).toEqual({ path: 'tools:bin/install.sh@v1.4.2', range: null });
+		expect(parseSourceReference('tools:bin/install.sh@3f2a9c1#L4-8')).toEqual({ path: 'tools:bin/install.sh@3f2a9c1', range: [4, 8] });
+	});
+
+	it('returns null for an empty path or an invalid range', () => {
+		expect(parseSourceReference('  ')).toBeNull();
+		expect(parseSourceReference('#L1-2')).toBeNull();
+		expect(parseSourceReference('deploy.sh#L20-10')).toBeNull();
+		expect(parseSourceReference('deploy.sh#two words')).toBeNull();
+	});
+
+	it('reads a fragment that is not a line range as a region name', () => {
+		expect(parseSourceReference('src/app.ts#setup')).toEqual({ path: 'vault://src/app.ts', range: null, region: 'setup' });
+		expect(parseSourceReference('tools:bin/install.sh@v1.4.2#deps')).toEqual({ path: 'tools:bin/install.sh@v1.4.2', range: null, region: 'deps' });
+	});
+});
+
+describe('parseRefreshInterval', () => {
+	it('reads seconds, minutes and hours', () => {
+		expect(parseRefreshInterval('30s')).toBe(30000);
+		expect(parseRefreshInterval('30')).toBe(30000);
+		expect(parseRefreshInterval(' 5M ')).toBe(300000);
+		expect(parseRefreshInterval('1.5h')).toBe(5400000);
+	});
+
+	it('raises short intervals to the minimum', () => {
+		expect(parseRefreshInterval('1s')).toBe(5000);
+	});
+
+	it('lowers intervals longer than a timer can wait to the maximum', () => {
+		expect(parseRefreshInterval('1000h')).toBe(2147483647);
+	});
+
+	it('treats missing, zero and malformed values as never', () => {
+		expect(parseRefreshInterval(undefined)).toBe(0);
+		expect(parseRefreshInterval('0s')).toBe(0);
+		expect(parseRefreshInterval('off')).toBe(0);
+		expect(parseRefreshInterval('2d')).toBe(0);
+	});
+});
+
+describe('parseRunTimeout', () => {
+	it('reads durations, falling back to the default', () => {
+		expect(parseRunTimeout('30s', 60000)).toBe(30000);
+		expect(parseRunTimeout('2m', 60000)).toBe(120000);
+		expect(parseRunTimeout(undefined, 60000)).toBe(60000);
+		expect(parseRunTimeout('soon', 60000)).toBe(60000);
+	});
+
+	it('treats off, none and zero as no limit', () => {
+		expect(parseRunTimeout('off', 60000)).toBe(0);
+		expect(parseRunTimeout('None', 60000)).toBe(0);
+		expect(parseRunTimeout('0', 60000)).toBe(0);
+	});
+
+	it('lowers timeouts longer than a timer can wait to the maximum', () => {
+		expect(parseRunTimeout('1000h', 60000)).toBe(2147483647);
+		expect(parseRunTimeout(undefined, 1e12)).toBe(2147483647);
+	});
+});
+
+describe('parseMaxHeight', () => {
+	it('reads line counts and pixel heights', () => {
+		expect(parseMaxHeight('20')).toEqual({ value: 20, unit: 'lines' });
+		expect(parseMaxHeight('25 lines')).toEqual({ value: 25, unit: 'lines' });
+		expect(parseMaxHeight('400px')).toEqual({ value: 400, unit: 'px' });
+		expect(parseMaxHeight(' 250 PX ')).toEqual({ value: 250, unit: 'px' });
+	});
+
+	it('rejects missing, zero and malformed values', () => {
+		expect(parseMaxHeight(undefined)).toBeUndefined();
+		expect(parseMaxHeight('0')).toBeUndefined();
+		expect(parseMaxHeight('50%')).toBeUndefined();
+		expect(parseMaxHeight('tall')).toBeUndefined();
+	});
+});
+
+describe('parseRulerColumns', () => {
+	it('reads columns in order without repeats', () => {
+		expect(parseRulerColumns('120, 80, 80')).toEqual([80, 120]);
+		expect(parseRulerColumns('100')).toEqual([100]);
+	});
+
+	it('skips zero and malformed columns', () => {
+		expect(parseRulerColumns('0, wide, 72')).toEqual([72]);
+		expect(parseRulerColumns(undefined)).toEqual([]);
+	});
+});
+
+describe('parseFontSize', () => {
+	it('reads CSS lengths, taking a bare number as pixels', () => {
+		expect(parseFontSize('13px')).toBe('13px');
+		expect(parseFontSize('0.85 EM')).toBe('0.85em');
+		expect(parseFontSize('90%')).toBe('90%');
+		expect(parseFontSize('13')).toBe('13px');
+	});
+
+	it('rejects missing, zero and malformed sizes', () => {
+		expect(parseFontSize(undefined)).toBe('');
+		expect(parseFontSize('0')).toBe('');
+		expect(parseFontSize('small; color: red')).toBe('');
+	});
+});
+
+describe('parseLineList', () => {
+	it('parses single numbers and dash ranges', () => {
+		expect(parseLineList('3-5')).toEqual([3, 4, 5]);
+		expect(parseLineList('1, 3-4, 8')).toEqual([1, 3, 4, 8]);
+		expect(parseLineList(7)).toEqual([7]);
+	});
+
+	it('parses arrays of numbers and ranges', () => {
+		expect(parseLineList([2, '5-6'])).toEqual([2, 5, 6]);
+	});
+
+	it('sorts and removes duplicates', () => {
+		expect(parseLineList('5, 1-3, 2')).toEqual([1, 2, 3, 5]);
+	});
+
+	it('skips invalid and reversed items', () => {
+		expect(parseLineList('abc, 0, 4-2, 6')).toEqual([6]);
+		expect(parseLineList(undefined)).toEqual([]);
+		expect(parseLineList({})).toEqual([]);
+	});
+
+	it('stops huge ranges at the most lines a list takes', () => {
+		const lines = parseLineList('99999-999999999');
+		expect(lines).toEqual([99999, 100000]);
+	});
+});
+
 describe('parseFilterSection', () => {
 	it('extracts BY_LINES with RANGE and INCLUSIVE', () => {
 		const result = parseFilterSection({
@@ -481,6 +720,360 @@ describe('parseFilterSection', () => {
 	});
 });
 
+describe('parseCopySection', () => {
+	it('extracts AS entries with all properties', () => {
+		const result = parseCopySection({
+			COPY: {
+				AS: [
+					{ LABEL: 'Dockerfile', FORMAT: 'Dockerfile' },
+					{ FORMAT: 'makefile', TARGET: 'install' },
+					{ FORMAT: 'crontab', SCHEDULE: '0 3 * * *' },
+				],
+			},
+		});
+		expect(result.AS).toHaveLength(3);
+		expect(result.AS?.[0]).toEqual({ LABEL: 'Dockerfile', FORMAT: 'dockerfile' });
+		expect(result.AS?.[1].TARGET).toBe('install');
+		expect(result.AS?.[2].SCHEDULE).toBe('0 3 * * *');
+	});
+
+	it('returns empty object when COPY is missing', () => {
+		expect(parseCopySection({})).toEqual({});
+	});
+
+	it('extracts PLACEHOLDERS as a boolean', () => {
+		expect(parseCopySection({ COPY: { PLACEHOLDERS: 'true' } })).toEqual({ PLACEHOLDERS: true });
+	});
+
+	it('extracts REDACT as a list', () => {
+		expect(parseCopySection({ COPY: { REDACT: ['tokens', 'x\\d+'] } })).toEqual({ REDACT: ['tokens', 'x\\d+'] });
+	});
+
+	it('extracts a single REDACT pattern as a one-item list', () => {
+		expect(parseCopySection({ COPY: { REDACT: 'passwords' } })).toEqual({ REDACT: ['passwords'] });
+	});
+
+	it('extracts STRIP_COMMENTS as a boolean', () => {
+		expect(parseCopySection({ COPY: { STRIP_COMMENTS: true } })).toEqual({ STRIP_COMMENTS: true });
+	});
+
+	it('extracts FEEDBACK, TOAST and FEEDBACK_DURATION', () => {
+		expect(parseCopySection({ COPY: { FEEDBACK: 'false', TOAST: true, FEEDBACK_DURATION: '3000' } }))
+			.toEqual({ FEEDBACK: false, TOAST: 'true', FEEDBACK_DURATION: 3000 });
+	});
+
+	it('ignores non-object AS items', () => {
+		const result = parseCopySection({ COPY: { AS: ['dockerfile', null, { FORMAT: 'crontab' }] } });
+		expect(result.AS).toEqual([{ FORMAT: 'crontab' }]);
+	});
+});
+
+describe('resolveCopyFeedback', () => {
+	it('uses settings when COPY is absent', () => {
+		const settings = testSettings({ copyFeedbackToast: 'Done', copyFeedbackStatusBar: true, copyFeedbackDuration: 1500 });
+		expect(resolveCopyFeedback(undefined, settings)).toEqual({
+			checkmark: true,
+			toastText: 'Done',
+			statusBar: true,
+			durationMs: 1500,
+		});
+	});
+
+	it('turns everything off with FEEDBACK: false', () => {
+		const settings = testSettings({ copyFeedbackToast: 'Done', copyFeedbackStatusBar: true });
+		const feedback = resolveCopyFeedback({ FEEDBACK: false, TOAST: 'Copied!' }, settings);
+		expect(feedback.checkmark).toBe(false);
+		expect(feedback.toastText).toBe('');
+		expect(feedback.statusBar).toBe(false);
+	});
+
+	it('uses the settings text, then the default message, for TOAST: true', () => {
+		expect(resolveCopyFeedback({ TOAST: 'true' }, testSettings({ copyFeedbackToast: 'Done' })).toastText).toBe('Done');
+		expect(resolveCopyFeedback({ TOAST: 'true' }, testSettings()).toastText).toBe(DEFAULT_COPY_MESSAGE);
+	});
+
+	it('turns the toast off with TOAST: false', () => {
+		expect(resolveCopyFeedback({ TOAST: 'false' }, testSettings({ copyFeedbackToast: 'Done' })).toastText).toBe('');
+	});
+
+	it('uses custom TOAST text', () => {
+		expect(resolveCopyFeedback({ TOAST: ' Paste it in the terminal ' }, testSettings()).toastText).toBe('Paste it in the terminal');
+	});
+
+	it('overrides the duration with FEEDBACK_DURATION, ignoring non-positive values', () => {
+		expect(resolveCopyFeedback({ FEEDBACK_DURATION: 4000 }, testSettings()).durationMs).toBe(4000);
+		expect(resolveCopyFeedback({ FEEDBACK_DURATION: 0 }, testSettings()).durationMs).toBe(testSettings().copyFeedbackDuration);
+	});
+
+	it('lowers durations longer than a timer can wait', () => {
+		expect(resolveCopyFeedback({ FEEDBACK_DURATION: 1e12 }, testSettings()).durationMs).toBe(2147483647);
+		expect(resolveCopyFeedback(undefined, testSettings({ copyFeedbackDuration: 1e12 })).durationMs).toBe(2147483647);
+	});
+
+	it('is resolved for code and cmdout blocks', () => {
+		expect(resolveBlockConfig({ COPY: { FEEDBACK: false } }, testSettings(), 'bash').copyFeedback.checkmark).toBe(false);
+		expect(resolveCmdoutConfig({ COPY: { TOAST: 'Hi' } }, testSettings()).copyFeedback.toastText).toBe('Hi');
+	});
+});
+
+describe('resolveConfigMode', () => {
+	it('uses the settings mode when MODE is absent', () => {
+		expect(resolveConfigMode(undefined, testSettings())).toBe('lenient');
+		expect(resolveConfigMode(undefined, testSettings({ configMode: 'strict' }))).toBe('strict');
+	});
+
+	it('lets MODE override the settings mode, ignoring case', () => {
+		expect(resolveConfigMode(' Strict ', testSettings())).toBe('strict');
+		expect(resolveConfigMode('lenient', testSettings({ configMode: 'strict' }))).toBe('lenient');
+	});
+
+	it('falls back to the settings mode for unknown values', () => {
+		expect(resolveConfigMode('picky', testSettings({ configMode: 'strict' }))).toBe('strict');
+	});
+
+	it('is resolved for code and cmdout blocks', () => {
+		expect(resolveBlockConfig({ META: { MODE: 'strict' } }, testSettings(), 'bash').configMode).toBe('strict');
+		expect(resolveCmdoutConfig({}, testSettings({ configMode: 'strict' })).configMode).toBe('strict');
+	});
+});
+
+describe('parseHighlightSection', () => {
+	it('extracts LINES as a string', () => {
+		expect(parseHighlightSection({ HIGHLIGHT: { LINES: '3-5' } })).toEqual({ LINES: '3-5' });
+		expect(parseHighlightSection({ HIGHLIGHT: { LINES: 4 } })).toEqual({ LINES: '4' });
+	});
+
+	it('normalises array LINES to the comma-separated form', () => {
+		expect(parseHighlightSection({ HIGHLIGHT: { LINES: [2, '5-7'] } })).toEqual({ LINES: '2, 5-7' });
+	});
+
+	it('extracts MATCH as a string', () => {
+		expect(parseHighlightSection({ HIGHLIGHT: { MATCH: 'TODO|FIXME' } })).toEqual({ MATCH: 'TODO|FIXME' });
+	});
+
+	it('reads TERMS from a list or a comma-separated string', () => {
+		expect(parseHighlightSection({ HIGHLIGHT: { TERMS: ['userId', ' ctx ', '', 42] } })).toEqual({ TERMS: ['userId', 'ctx', '42'] });
+		expect(parseHighlightSection({ HIGHLIGHT: { TERMS: 'userId, ctx' } })).toEqual({ TERMS: ['userId', 'ctx'] });
+	});
+
+	it('extracts FOCUS as a line list and FOCUS_TOGGLE as a boolean', () => {
+		expect(parseHighlightSection({ HIGHLIGHT: { FOCUS: [3, '7-9'], FOCUS_TOGGLE: 'true' } })).toEqual({ FOCUS: '3, 7-9', FOCUS_TOGGLE: true });
+	});
+
+	it('returns empty object when HIGHLIGHT is missing', () => {
+		expect(parseHighlightSection({})).toEqual({});
+	});
+});
+
+describe('parseHeaderSection', () => {
+	it('extracts the icon and the toggles', () => {
+		expect(parseHeaderSection({ HEADER: { ICON: 'terminal', TITLE: false, LANGUAGE: 'true', BUTTONS: true } }))
+			.toEqual({ ICON: 'terminal', TITLE: false, LANGUAGE: true, BUTTONS: true });
+	});
+
+	it('returns empty object when HEADER is missing', () => {
+		expect(parseHeaderSection({})).toEqual({});
+	});
+});
+
+describe('parseFooterSection', () => {
+	it('extracts the source, URL and licence', () => {
+		expect(parseFooterSection({ FOOTER: { SOURCE: 'lodash', URL: 'https://lodash.com', LICENSE: 'MIT' } }))
+			.toEqual({ SOURCE: 'lodash', URL: 'https://lodash.com', LICENSE: 'MIT' });
+	});
+
+	it('returns empty object when FOOTER is missing', () => {
+		expect(parseFooterSection({})).toEqual({});
+	});
+});
+
+describe('parseAnnotationsSection', () => {
+	it('reads bare types and TYPE/TEXT mappings', () => {
+		expect(parseAnnotationsSection({
+			ANNOTATIONS: { 12: 'warning', 30: { TYPE: 'info', TEXT: 'Safe to re-run' } },
+		})).toEqual({
+			12: { TYPE: 'warning' },
+			30: { TYPE: 'info', TEXT: 'Safe to re-run' },
+		});
+	});
+
+	it('returns empty object when ANNOTATIONS is missing', () => {
+		expect(parseAnnotationsSection({})).toEqual({});
+	});
+});
+
+describe('resolveAnnotations', () => {
+	it('resolves types and sorts by line', () => {
+		expect(resolveAnnotations({ 30: { TYPE: 'Caution', TEXT: ' Careful ' }, 12: { TYPE: 'info' } })).toEqual([
+			{ line: 12, type: 'info', text: '' },
+			{ line: 30, type: 'warning', text: 'Careful' },
+		]);
+	});
+
+	it('expands line lists and defaults the type to note', () => {
+		expect(resolveAnnotations({ '3-4': {} })).toEqual([
+			{ line: 3, type: 'note', text: '' },
+			{ line: 4, type: 'note', text: '' },
+		]);
+	});
+
+	it('skips keys that are not line numbers', () => {
+		expect(resolveAnnotations({ first: { TYPE: 'bug' } })).toEqual([]);
+		expect(resolveAnnotations(undefined)).toEqual([]);
+	});
+});
+
+describe('parseBlameSection', () => {
+	it('reads plain values as the author and mappings as full entries', () => {
+		expect(parseBlameSection({
+			BLAME: { 5: 'bob', '1-4': { AUTHOR: 'alice', DATE: '2024-03-01', TICKET: 'INC-42' } },
+		})).toEqual({
+			5: { AUTHOR: 'bob' },
+			'1-4': { AUTHOR: 'alice', DATE: '2024-03-01', TICKET: 'INC-42' },
+		});
+	});
+
+	it('formats unquoted YAML dates', () => {
+		expect(parseBlameSection({ BLAME: { 1: { DATE: new Date('2024-03-01T00:00:00Z') } } })[1].DATE).toBe('2024-03-01');
+	});
+
+	it('returns empty object when BLAME is missing', () => {
+		expect(parseBlameSection({})).toEqual({});
+	});
+});
+
+describe('resolveBlame', () => {
+	it('expands line lists and sorts by line', () => {
+		expect(resolveBlame({ 5: { AUTHOR: ' bob ' }, '1-2': { AUTHOR: 'alice', TICKET: 'INC-42' } })).toEqual([
+			{ line: 1, author: 'alice', date: '', ticket: 'INC-42' },
+			{ line: 2, author: 'alice', date: '', ticket: 'INC-42' },
+			{ line: 5, author: 'bob', date: '', ticket: '' },
+		]);
+	});
+
+	it('drops empty entries and keys that are not line numbers', () => {
+		expect(resolveBlame({ 1: { AUTHOR: ' ' }, first: { AUTHOR: 'alice' } })).toEqual([]);
+		expect(resolveBlame(undefined)).toEqual([]);
+	});
+});
+
+describe('parseTokensSection', () => {
+	it('reads token styles and language maps, lowercasing names', () => {
+		expect(parseTokensSection({
+			TOKENS: { Keyword: 'bold purple', SQL: { Comment: 'dim', Number: 3 } },
+		})).toEqual({
+			keyword: 'bold purple',
+			sql: { comment: 'dim', number: '3' },
+		});
+	});
+
+	it('returns empty object when TOKENS is missing', () => {
+		expect(parseTokensSection({})).toEqual({});
+	});
+});
+
+describe('parsePatternsSection', () => {
+	it('reads styles by regex, keeping the regex as written', () => {
+		expect(parsePatternsSection({
+			PATTERNS: { 'PROJ-\\d+': 'bold purple', '\\bprod\\b': 'red', nested: { a: 'b' } },
+		})).toEqual({
+			'PROJ-\\d+': 'bold purple',
+			'\\bprod\\b': 'red',
+		});
+	});
+
+	it('returns empty object when PATTERNS is missing', () => {
+		expect(parsePatternsSection({})).toEqual({});
+	});
+});
+
+describe('parseDiffSection', () => {
+	it('extracts line lists and the copy mode', () => {
+		expect(parseDiffSection({ DIFF: { ADDED: [4, '6-7'], REMOVED: 3, COPY: 'After' } }))
+			.toEqual({ ADDED: '4, 6-7', REMOVED: '3', COPY: 'after' });
+	});
+
+	it('extracts the view and region names', () => {
+		expect(parseDiffSection({ DIFF: { VIEW: 'Split', OLD: ' before ', NEW: 'after' } }))
+			.toEqual({ VIEW: 'split', OLD: 'before', NEW: 'after' });
+	});
+
+	it('extracts WORDS as a boolean', () => {
+		expect(parseDiffSection({ DIFF: { WORDS: 'false' } })).toEqual({ WORDS: false });
+	});
+
+	it('returns empty object when DIFF is missing', () => {
+		expect(parseDiffSection({})).toEqual({});
+	});
+});
+
+describe('parseDownloadSection', () => {
+	it('extracts FILENAME, SHEBANG and EXECUTABLE', () => {
+		const result = parseDownloadSection({
+			DOWNLOAD: { FILENAME: '{basename}.sh', SHEBANG: true, EXECUTABLE: 'true' },
+		});
+		expect(result).toEqual({ FILENAME: '{basename}.sh', SHEBANG: 'true', EXECUTABLE: true });
+	});
+
+	it('keeps custom SHEBANG lines as strings', () => {
+		expect(parseDownloadSection({ DOWNLOAD: { SHEBANG: '#!/bin/sh -e' } }).SHEBANG).toBe('#!/bin/sh -e');
+	});
+
+	it('returns empty object when DOWNLOAD is missing', () => {
+		expect(parseDownloadSection({})).toEqual({});
+	});
+});
+
+describe('parsePrintSection', () => {
+	it('extracts EXPAND, THEME, LINES and AVOID_BREAKS', () => {
+		const result = parsePrintSection({
+			PRINT: { EXPAND: 'false', THEME: 'Light', LINES: true, AVOID_BREAKS: false },
+		});
+		expect(result).toEqual({ EXPAND: false, THEME: 'light', LINES: true, AVOID_BREAKS: false });
+	});
+
+	it('returns empty object when PRINT is missing', () => {
+		expect(parsePrintSection({})).toEqual({});
+	});
+});
+
+describe('parseRunSection', () => {
+	it('extracts ENABLED, SAVE and LINES', () => {
+		expect(parseRunSection({ RUN: { ENABLED: 'false' } })).toEqual({ ENABLED: false });
+		expect(parseRunSection({ RUN: { SAVE: 'true' } })).toEqual({ SAVE: true });
+		expect(parseRunSection({ RUN: { LINES: true } })).toEqual({ LINES: true });
+		expect(parseRunSection({})).toEqual({});
+	});
+
+	it('shows the run button unless a block turns it off', () => {
+		expect(resolveBlockConfig({}, testSettings(), 'bash').runEnabled).toBe(true);
+		expect(resolveBlockConfig({ RUN: { ENABLED: false } }, testSettings(), 'bash').runEnabled).toBe(false);
+	});
+
+	it('saves output in the note only when a block asks to', () => {
+		expect(resolveBlockConfig({}, testSettings(), 'bash').runSaveOutput).toBe(false);
+		expect(resolveBlockConfig({ RUN: { SAVE: true } }, testSettings(), 'bash').runSaveOutput).toBe(true);
+	});
+
+	it('shows per-line run buttons only when a block asks for them', () => {
+		expect(resolveBlockConfig({}, testSettings(), 'bash').runLines).toBe(false);
+		expect(resolveBlockConfig({ RUN: { LINES: true } }, testSettings(), 'bash').runLines).toBe(true);
+	});
+
+	it('kills runs after the block timeout, or the setting', () => {
+		expect(resolveBlockConfig({}, testSettings({ runTimeoutSeconds: 60 }), 'bash').runTimeoutMs).toBe(60000);
+		expect(resolveBlockConfig({ RUN: { TIMEOUT: '5m' } }, testSettings(), 'bash').runTimeoutMs).toBe(300000);
+	});
+
+	it('reads ENV variables, skipping bad names and non-scalar values', () => {
+		expect(parseRunSection({ RUN: { ENV: { API_URL: 'https://example.com', PORT: 8080, 'BAD NAME': 'x', LIST: ['a'] } } }))
+			.toEqual({ ENV: { API_URL: 'https://example.com', PORT: '8080' } });
+		expect(resolveBlockConfig({}, testSettings(), 'bash').runEnv).toEqual({});
+		expect(resolveBlockConfig({ RUN: { ENV: { TOKEN: '{{secret.prod-token}}' } } }, testSettings(), 'bash').runEnv)
+			.toEqual({ TOKEN: '{{secret.prod-token}}' });
+	});
+});
+
 describe('parseRenderCmdoutSection', () => {
 	it('extracts PROMPT, COMMAND, OUTPUT styling', () => {
 		const result = parseRenderCmdoutSection({
@@ -626,6 +1219,29 @@ describe('parseBlockContent', () => {
 		const result = parseBlockContent(content);
 		expect(result.embeddedCode).toBe('line 1\nline 2\nline 3');
 	});
+
+	it('parses TOML settings with embedded code', () => {
+		const result = parseBlockContent('[META]\nTITLE = "Example"\n~~~\necho hi');
+		expect(result.yamlProperties).toEqual({ META: { TITLE: 'Example' } });
+		expect(result.embeddedCode).toBe('echo hi');
+	});
+
+	it('parses a JSON-only block', () => {
+		const result = parseBlockContent('{ "META": { "PATH": "vault://Scripts/test.ts" } }');
+		expect(result.hasEmbeddedCode).toBe(false);
+		expect(result.yamlProperties).toEqual({ META: { PATH: 'vault://Scripts/test.ts' } });
+	});
+
+	it('uses the given settings format', () => {
+		const result = parseBlockContent('META:\n  TITLE: "x"\n~~~\ncode', 'yaml');
+		expect(result.yamlProperties.META).toEqual({ TITLE: 'x' });
+		expect(() => parseBlockContent('META:\n  TITLE: "x"\n~~~\ncode', 'toml')).toThrow();
+	});
+
+	it('treats code that looks like TOML but is not as plain code', () => {
+		const code = 'x = 1\nprint(x)';
+		expect(parseBlockContent(code).embeddedCode).toBe(code);
+	});
 });
 
 // =============================================================================
@@ -679,6 +1295,35 @@ describe('parseNestedYamlConfig', () => {
 		const result = parseNestedYamlConfig({ META: { TITLE: 'Test' } });
 		expect(result.PROMPT).toBeUndefined();
 	});
+
+	it('reads settings under a renamed section', () => {
+		const result = parseNestedYamlConfig({ DISPLAY: { LINES: true, FOLD: 5 } });
+		expect(result.RENDER?.LINES).toBe(true);
+		expect(result.RENDER?.FOLD).toBe(5);
+	});
+});
+
+describe('applyConfigRenames', () => {
+	it('moves a renamed section to its new name', () => {
+		expect(applyConfigRenames({ STYLE: { ZEBRA: true }, META: { TITLE: 'x' } }))
+			.toEqual({ RENDER: { ZEBRA: true }, META: { TITLE: 'x' } });
+	});
+
+	it('leaves the old key alone when the new one is present', () => {
+		expect(applyConfigRenames({ DISPLAY: { LINES: true }, RENDER: { FOLD: 3 } }))
+			.toEqual({ DISPLAY: { LINES: true }, RENDER: { FOLD: 3 } });
+	});
+
+	it('moves only the first of two old names for the same key', () => {
+		expect(applyConfigRenames({ DISPLAY: { LINES: true }, STYLE: { ZEBRA: true } }))
+			.toEqual({ RENDER: { LINES: true }, STYLE: { ZEBRA: true } });
+	});
+
+	it('does not modify its input', () => {
+		const yamlProps = { DISPLAY: { LINES: true } };
+		applyConfigRenames(yamlProps);
+		expect(yamlProps).toEqual({ DISPLAY: { LINES: true } });
+	});
 });
 
 // =============================================================================
@@ -735,6 +1380,335 @@ describe('resolveBlockConfig', () => {
 		expect(result.language).toBe('javascript');
 	});
 
+	it('resolves startingLineNumber from LINE_START, defaulting to 1', () => {
+		expect(resolveBlockConfig({}, testSettings(), 'text').startingLineNumber).toBe(1);
+		expect(resolveBlockConfig({ RENDER: { LINE_START: 120 } }, testSettings(), 'text').startingLineNumber).toBe(120);
+	});
+
+	it('resolves indentation guides, defaulting to off with solid lines', () => {
+		const config = resolveBlockConfig({ RENDER: { INDENT_GUIDES: true, INDENT_GUIDE_STYLE: 'dotted' } }, testSettings(), 'yaml');
+		expect(config.showIndentGuides).toBe(true);
+		expect(config.indentGuideStyle).toBe('dotted');
+		expect(config.indentGuideColour).toBe('');
+
+		const defaults = resolveBlockConfig({}, testSettings(), 'yaml');
+		expect(defaults.showIndentGuides).toBe(false);
+		expect(defaults.indentGuideStyle).toBe('solid');
+	});
+
+	it('resolves minimapLines from MINIMAP, falling back to the setting', () => {
+		expect(resolveBlockConfig({ RENDER: { MINIMAP: 200 } }, testSettings(), 'text').minimapLines).toBe(200);
+		expect(resolveBlockConfig({}, testSettings({ minimapLines: 50 }), 'text').minimapLines).toBe(50);
+	});
+
+	it('resolves HOVER from the block or the setting, and ZEBRA_COLOUR', () => {
+		expect(resolveBlockConfig({ RENDER: { HOVER: true } }, testSettings(), 'text').showLineHover).toBe(true);
+		expect(resolveBlockConfig({}, testSettings({ showLineHover: true }), 'text').showLineHover).toBe(true);
+		expect(resolveBlockConfig({ RENDER: { ZEBRA_COLOUR: '#eef' } }, testSettings(), 'text').zebraColour).toBe('#eef');
+	});
+
+	it('resolves BRACKETS from the block or the setting', () => {
+		expect(resolveBlockConfig({ RENDER: { BRACKETS: true } }, testSettings(), 'text').bracketPairColours).toBe(true);
+		expect(resolveBlockConfig({ RENDER: { BRACKETS: false } }, testSettings({ bracketPairColours: true }), 'text').bracketPairColours).toBe(false);
+		expect(resolveBlockConfig({}, testSettings({ bracketPairColours: true }), 'text').bracketPairColours).toBe(true);
+	});
+
+	it('resolves CHANGED_SINCE from the block or the setting, reading booleans', () => {
+		expect(resolveBlockConfig({ RENDER: { CHANGED_SINCE: '7d' } }, testSettings(), 'text').changedSince).toBe('7d');
+		expect(resolveBlockConfig({}, testSettings({ changedSince: 'last' }), 'text').changedSince).toBe('last');
+		expect(parseNestedYamlConfig({ RENDER: { CHANGED_SINCE: true } }).RENDER?.CHANGED_SINCE).toBe('last');
+		expect(parseNestedYamlConfig({ RENDER: { CHANGED_SINCE: false } }).RENDER?.CHANGED_SINCE).toBe('off');
+		expect(parseNestedYamlConfig({ RENDER: { CHANGED_SINCE: ' 2W ' } }).RENDER?.CHANGED_SINCE).toBe('2w');
+	});
+
+	it('resolves SEMANTIC from the block or the setting', () => {
+		expect(resolveBlockConfig({ RENDER: { SEMANTIC: true } }, testSettings(), 'go').semanticHighlighting).toBe(true);
+		expect(resolveBlockConfig({ RENDER: { SEMANTIC: false } }, testSettings({ semanticHighlighting: true }), 'go').semanticHighlighting).toBe(false);
+		expect(resolveBlockConfig({}, testSettings(), 'go').semanticHighlighting).toBe(false);
+	});
+
+	it('resolves font ligatures and features, leaving the theme alone by default', () => {
+		const config = resolveBlockConfig({ RENDER: { LIGATURES: false, FONT_FEATURES: 'zero' } }, testSettings(), 'text');
+		expect(config.fontLigatures).toBe(false);
+		expect(config.fontFeatures).toBe('zero');
+
+		const defaults = resolveBlockConfig({}, testSettings(), 'text');
+		expect(defaults.fontLigatures).toBeUndefined();
+		expect(defaults.fontFeatures).toBe('');
+	});
+
+	it('resolves tabSize from TAB_SIZE, then the language, then the setting', () => {
+		const settings = testSettings({ tabSize: 2, languageTabSizes: 'go=4' });
+
+		expect(resolveBlockConfig({ RENDER: { TAB_SIZE: 3 } }, settings, 'go').tabSize).toBe(3);
+		expect(resolveBlockConfig({}, settings, 'go').tabSize).toBe(4);
+		expect(resolveBlockConfig({ RENDER: { LANG: 'go' } }, settings, 'text').tabSize).toBe(4);
+		expect(resolveBlockConfig({}, settings, 'python').tabSize).toBe(2);
+	});
+
+	it('resolves the print profile from PRINT, then settings', () => {
+		const result = resolveBlockConfig({ PRINT: { THEME: 'asis', LINES: true } }, testSettings({ printAvoidBreaks: false }), 'text');
+		expect(result.printTheme).toBe('asis');
+		expect(result.printLineNumbers).toBe(true);
+		expect(result.printAvoidBreaks).toBe(false);
+
+		const defaults = resolveBlockConfig({}, testSettings(), 'text');
+		expect(defaults.printTheme).toBe('light');
+		expect(defaults.printLineNumbers).toBe(false);
+		expect(defaults.printAvoidBreaks).toBe(true);
+	});
+
+	it('lets PRINT.EXPAND override RENDER.PRINT', () => {
+		expect(resolveBlockConfig({ RENDER: { PRINT: 'expand' }, PRINT: { EXPAND: false } }, testSettings(), 'text').printBehaviour).toBe('asis');
+		expect(resolveBlockConfig({ PRINT: { EXPAND: true } }, testSettings({ printBehaviour: 'asis' }), 'text').printBehaviour).toBe('expand');
+	});
+
+	it('resolves the text direction from DIRECTION', () => {
+		expect(parseRenderDisplaySection({ RENDER: { DIRECTION: 'RTL' } }).DIRECTION).toBe('rtl');
+		expect(resolveBlockConfig({ RENDER: { DIRECTION: 'auto' } }, testSettings(), 'text').textDirection).toBe('auto');
+		expect(resolveBlockConfig({}, testSettings(), 'text').textDirection).toBe('');
+	});
+
+	it('resolves rulers from RULER written as a number or a list', () => {
+		expect(resolveBlockConfig({ RENDER: { RULER: '80' } }, testSettings(), 'text').rulerColumns).toEqual([80]);
+		expect(parseRenderDisplaySection({ RENDER: { RULER: [80, 120] } }).RULER).toBe('80, 120');
+		expect(resolveBlockConfig({}, testSettings(), 'text').rulerColumns).toEqual([]);
+	});
+
+	it('resolves the font family and size, falling back to the theme', () => {
+		const config = resolveBlockConfig({ RENDER: { FONT_FAMILY: 'JetBrains Mono', FONT_SIZE: '13' } }, testSettings(), 'text');
+		expect(config.fontFamily).toBe('JetBrains Mono');
+		expect(config.fontSize).toBe('13px');
+
+		const defaults = resolveBlockConfig({}, testSettings(), 'text');
+		expect(defaults.fontFamily).toBe('');
+		expect(defaults.fontSize).toBe('');
+	});
+
+	it('resolves maxHeight from MAX_HEIGHT', () => {
+		expect(resolveBlockConfig({ RENDER: { MAX_HEIGHT: '400px' } }, testSettings(), 'text').maxHeight).toEqual({ value: 400, unit: 'px' });
+		expect(resolveBlockConfig({ RENDER: {} }, testSettings(), 'text').maxHeight).toBeUndefined();
+	});
+
+	it('resolves anchorLine from LINE_ANCHOR, defaulting to 0', () => {
+		expect(resolveBlockConfig({}, testSettings(), 'text').anchorLine).toBe(0);
+		expect(resolveBlockConfig({ RENDER: { LINE_ANCHOR: 4 } }, testSettings(), 'text').anchorLine).toBe(4);
+	});
+
+	it('resolves fold regions, expanded by default with the built-in markers', () => {
+		const defaults = resolveBlockConfig({}, testSettings(), 'text');
+		expect(defaults.foldRegionsMode).toBe('expanded');
+		expect(defaults.foldStartPattern).toBeUndefined();
+		expect(defaults.foldEndPattern).toBeUndefined();
+
+		const custom = resolveBlockConfig({ RENDER: { FOLD_REGIONS: 'collapsed', FOLD_START: 'BEGIN', FOLD_END: '([' } }, testSettings(), 'text');
+		expect(custom.foldRegionsMode).toBe('collapsed');
+		expect(custom.foldStartPattern?.source).toBe('BEGIN');
+		expect(custom.foldEndPattern).toBeUndefined();
+	});
+
+	it('resolves blockId from META.ID, empty by default', () => {
+		expect(resolveBlockConfig({}, testSettings(), 'bash').blockId).toBe('');
+		expect(resolveBlockConfig({ META: { ID: 'setup' } }, testSettings(), 'bash').blockId).toBe('setup');
+	});
+
+	it('resolves startCollapsed and summaryText from RENDER.COLLAPSED and META.SUMMARY', () => {
+		expect(resolveBlockConfig({}, testSettings(), 'text').startCollapsed).toBe(false);
+		const result = resolveBlockConfig({ META: { SUMMARY: 'Full schema' }, RENDER: { COLLAPSED: true } }, testSettings(), 'text');
+		expect(result.startCollapsed).toBe(true);
+		expect(result.summaryText).toBe('Full schema');
+	});
+
+	it('resolves the header options, showing only the title by default', () => {
+		const defaults = resolveBlockConfig({}, testSettings(), 'bash');
+		expect(defaults.headerIcon).toBeUndefined();
+		expect(defaults.showHeaderTitle).toBe(true);
+		expect(defaults.showLanguageBadge).toBe(false);
+		expect(defaults.buttonsInHeader).toBe(false);
+
+		const result = resolveBlockConfig({ HEADER: { ICON: '🚀', TITLE: false, LANGUAGE: true, BUTTONS: true } }, testSettings(), 'bash');
+		expect(result.headerIcon).toBe('🚀');
+		expect(result.showHeaderTitle).toBe(false);
+		expect(result.showLanguageBadge).toBe(true);
+		expect(result.buttonsInHeader).toBe(true);
+	});
+
+	it('resolves the footer attribution, empty by default', () => {
+		expect(resolveBlockConfig({}, testSettings(), 'bash').footerSource).toBe('');
+
+		const result = resolveBlockConfig({ FOOTER: { SOURCE: 'lodash', URL: 'https://lodash.com', LICENSE: 'MIT' } }, testSettings(), 'bash');
+		expect(result.footerSource).toBe('lodash');
+		expect(result.footerUrl).toBe('https://lodash.com');
+		expect(result.footerLicense).toBe('MIT');
+	});
+
+	it('reads diff and patch blocks as unified diffs', () => {
+		expect(resolveBlockConfig({}, testSettings(), 'diff').unifiedDiff).toBe(true);
+		expect(resolveBlockConfig({ RENDER: { LANG: 'patch' } }, testSettings(), 'code').unifiedDiff).toBe(true);
+		expect(resolveBlockConfig({}, testSettings(), 'bash').unifiedDiff).toBe(false);
+	});
+
+	it('resolves DIFF lines and copy mode, with copying the diff by default', () => {
+		const defaults = resolveBlockConfig({}, testSettings(), 'bash');
+		expect(defaults.diffAddedLines).toEqual([]);
+		expect(defaults.copyDiffAfter).toBe(false);
+
+		const result = resolveBlockConfig({ DIFF: { ADDED: '4-5', REMOVED: '3', COPY: 'after' } }, testSettings(), 'bash');
+		expect(result.diffAddedLines).toEqual([4, 5]);
+		expect(result.diffRemovedLines).toEqual([3]);
+		expect(result.copyDiffAfter).toBe(true);
+	});
+
+	it('resolves word highlighting, on by default', () => {
+		expect(resolveBlockConfig({}, testSettings(), 'diff').diffWordHighlight).toBe(true);
+		expect(resolveBlockConfig({ DIFF: { WORDS: false } }, testSettings(), 'diff').diffWordHighlight).toBe(false);
+	});
+
+	it('resolves the split diff view', () => {
+		const defaults = resolveBlockConfig({}, testSettings(), 'diff');
+		expect(defaults.splitDiff).toBe(false);
+		expect(defaults.diffOldRegion).toBe('');
+
+		expect(resolveBlockConfig({ DIFF: { VIEW: 'split' } }, testSettings(), 'diff').splitDiff).toBe(true);
+
+		const regions = resolveBlockConfig({ DIFF: { OLD: 'before', NEW: 'after' } }, testSettings(), 'yaml');
+		expect(regions.splitDiff).toBe(true);
+		expect(regions.diffOldRegion).toBe('before');
+		expect(regions.diffNewRegion).toBe('after');
+
+		// One region alone can't be compared
+		const oneRegion = resolveBlockConfig({ DIFF: { OLD: 'before' } }, testSettings(), 'yaml');
+		expect(oneRegion.splitDiff).toBe(false);
+		expect(oneRegion.diffOldRegion).toBe('');
+	});
+
+	it('resolves blame metadata, none by default', () => {
+		expect(resolveBlockConfig({}, testSettings(), 'bash').blame).toEqual([]);
+		expect(resolveBlockConfig({ BLAME: { 2: { AUTHOR: 'alice' } } }, testSettings(), 'bash').blame)
+			.toEqual([{ line: 2, author: 'alice', date: '', ticket: '' }]);
+	});
+
+	it('resolves gutter annotations, none by default', () => {
+		expect(resolveBlockConfig({}, testSettings(), 'bash').annotations).toEqual([]);
+		expect(resolveBlockConfig({ ANNOTATIONS: { 2: { TYPE: 'danger' } } }, testSettings(), 'bash').annotations)
+			.toEqual([{ line: 2, type: 'danger', text: '' }]);
+	});
+
+	it('resolves showLineCopyButtons from YAML, falling back to settings', () => {
+		const settings = testSettings({ showLineCopyButtons: true });
+		expect(resolveBlockConfig({}, settings, 'text').showLineCopyButtons).toBe(true);
+		expect(resolveBlockConfig({ RENDER: { LINE_COPY: false } }, settings, 'text').showLineCopyButtons).toBe(false);
+	});
+
+	it('resolves COPY.AS entries with default labels and drops unknown formats', () => {
+		const parsed: ParsedYamlConfig = {
+			COPY: {
+				AS: [
+					{ FORMAT: 'dockerfile' },
+					{ FORMAT: 'makefile', LABEL: 'make install', TARGET: 'install' },
+					{ FORMAT: 'unknown' },
+					{ LABEL: 'No format' },
+				],
+			},
+		};
+		const result = resolveBlockConfig(parsed, testSettings(), 'bash');
+		expect(result.copyAsEntries).toEqual([
+			{ label: 'Dockerfile RUN', format: 'dockerfile', target: '', schedule: '' },
+			{ label: 'make install', format: 'makefile', target: 'install', schedule: '' },
+		]);
+	});
+
+	it('resolves promptPattern from PROMPT', () => {
+		const result = resolveBlockConfig({ PROMPT: '^\\$\\s' }, testSettings(), 'bash');
+		expect(result.promptPattern?.test('$ ls')).toBe(true);
+	});
+
+	it('leaves promptPattern undefined when PROMPT is absent or invalid', () => {
+		expect(resolveBlockConfig({}, testSettings(), 'bash').promptPattern).toBeUndefined();
+		expect(resolveBlockConfig({ PROMPT: '([' }, testSettings(), 'bash').promptPattern).toBeUndefined();
+	});
+
+	it('resolves highlightLines from HIGHLIGHT.LINES', () => {
+		const result = resolveBlockConfig({ HIGHLIGHT: { LINES: '3-5, 8' } }, testSettings(), 'bash');
+		expect(result.highlightLines).toEqual([3, 4, 5, 8]);
+		expect(resolveBlockConfig({}, testSettings(), 'bash').highlightLines).toEqual([]);
+	});
+
+	it('resolves highlightPattern from HIGHLIGHT.MATCH, dropping invalid regexes', () => {
+		expect(resolveBlockConfig({ HIGHLIGHT: { MATCH: 'TODO|FIXME' } }, testSettings(), 'bash').highlightPattern?.source).toBe('TODO|FIXME');
+		expect(resolveBlockConfig({ HIGHLIGHT: { MATCH: '([' } }, testSettings(), 'bash').highlightPattern).toBeUndefined();
+		expect(resolveBlockConfig({}, testSettings(), 'bash').highlightPattern).toBeUndefined();
+	});
+
+	it('resolves focusLines and the focus toggle from HIGHLIGHT', () => {
+		const result = resolveBlockConfig({ HIGHLIGHT: { FOCUS: '4-6', FOCUS_TOGGLE: true } }, testSettings(), 'bash');
+		expect(result.focusLines).toEqual([4, 5, 6]);
+		expect(result.showFocusToggle).toBe(true);
+
+		const defaults = resolveBlockConfig({}, testSettings(), 'bash');
+		expect(defaults.focusLines).toEqual([]);
+		expect(defaults.showFocusToggle).toBe(false);
+	});
+
+	it('resolves download options from DOWNLOAD, falling back to settings', () => {
+		const settings = testSettings({ downloadFilenameTemplate: '{basename}.txt', downloadExecutable: true });
+		expect(resolveBlockConfig({}, settings, 'bash').downloadFilenameTemplate).toBe('{basename}.txt');
+		expect(resolveBlockConfig({}, settings, 'bash').downloadExecutable).toBe(true);
+
+		const result = resolveBlockConfig({ DOWNLOAD: { FILENAME: 'install.sh', EXECUTABLE: false } }, settings, 'bash');
+		expect(result.downloadFilenameTemplate).toBe('install.sh');
+		expect(result.downloadExecutable).toBe(false);
+	});
+
+	it('resolves downloadShebang from DOWNLOAD.SHEBANG', () => {
+		const settings = testSettings();
+		expect(resolveBlockConfig({}, settings, 'bash').downloadShebang).toBe('');
+		expect(resolveBlockConfig({ DOWNLOAD: { SHEBANG: 'true' } }, settings, 'bash').downloadShebang).toBe('#!/usr/bin/env bash');
+		expect(resolveBlockConfig({ DOWNLOAD: { SHEBANG: '/bin/sh -e' } }, settings, 'bash').downloadShebang).toBe('#!/bin/sh -e');
+		expect(resolveBlockConfig({ DOWNLOAD: { SHEBANG: 'false' } }, testSettings({ downloadAddShebang: true }), 'bash').downloadShebang).toBe('');
+	});
+
+	it('uses the language default shebang when enabled in settings', () => {
+		const settings = testSettings({ downloadAddShebang: true });
+		expect(resolveBlockConfig({ RENDER: { LANG: 'python' } }, settings, 'bash').downloadShebang).toBe('#!/usr/bin/env python3');
+	});
+
+	it('resolves copyPlaceholders from COPY.PLACEHOLDERS, falling back to settings', () => {
+		expect(resolveBlockConfig({}, testSettings(), 'bash').copyPlaceholders).toBe(false);
+		expect(resolveBlockConfig({ COPY: { PLACEHOLDERS: true } }, testSettings(), 'bash').copyPlaceholders).toBe(true);
+		expect(resolveBlockConfig({ COPY: { PLACEHOLDERS: false } }, testSettings({ copyPlaceholders: true }), 'bash').copyPlaceholders).toBe(false);
+	});
+
+	it('resolves renderVariables from RENDER.VARIABLES, falling back to settings, and copyVariables from COPY.VARIABLES', () => {
+		expect(resolveBlockConfig({}, testSettings(), 'bash').renderVariables).toBe(false);
+		expect(resolveBlockConfig({ RENDER: { VARIABLES: true } }, testSettings(), 'bash').renderVariables).toBe(true);
+		expect(resolveBlockConfig({ RENDER: { VARIABLES: false } }, testSettings({ renderVariables: true }), 'bash').renderVariables).toBe(false);
+		expect(resolveBlockConfig({}, testSettings(), 'bash').copyVariables).toBe(false);
+		expect(resolveBlockConfig({ COPY: { VARIABLES: true } }, testSettings(), 'bash').copyVariables).toBe(true);
+	});
+
+	it('resolves redactPatterns from built-in names and regexes, dropping invalid ones', () => {
+		const config = resolveBlockConfig({ COPY: { REDACT: ['Tokens', 'x\\d+', '(unclosed'] } }, testSettings(), 'bash');
+		const custom = config.redactPatterns[config.redactPatterns.length - 1];
+
+		expect(config.redactPatterns.length).toBe((BUILT_IN_REDACTIONS.tokens ?? []).length + 1);
+		expect(custom.source).toBe('x\\d+');
+		expect(custom.flags).toBe('g');
+	});
+
+	it('resolves no redactPatterns when REDACT is absent', () => {
+		expect(resolveBlockConfig({}, testSettings(), 'bash').redactPatterns).toEqual([]);
+	});
+
+	it('resolves stripComments from COPY.STRIP_COMMENTS (off by default)', () => {
+		expect(resolveBlockConfig({}, testSettings(), 'bash').stripComments).toBe(false);
+		expect(resolveBlockConfig({ COPY: { STRIP_COMMENTS: true } }, testSettings(), 'bash').stripComments).toBe(true);
+	});
+
+	it('resolves empty copyAsEntries when COPY is absent', () => {
+		expect(resolveBlockConfig({}, testSettings(), 'bash').copyAsEntries).toEqual([]);
+	});
+
 	it('uses defaultLanguage when LANG not in YAML', () => {
 		const settings = testSettings({
 			languageCopyJoinDefaults: {
@@ -757,6 +1731,38 @@ describe('resolveBlockConfig', () => {
 		expect(result.descriptionText).toBe('');
 	});
 
+	it('resolves META.SRC to a vault path, line range and first line number', () => {
+		const result = resolveBlockConfig({ META: { SRC: 'scripts/deploy.sh#L10-42' } }, testSettings(), 'bash');
+		expect(result.sourcePath).toBe('vault://scripts/deploy.sh');
+		expect(result.filterByLines).toEqual({ enabled: true, start: 10, end: 42, inclusive: true });
+		expect(result.startingLineNumber).toBe(10);
+	});
+
+	it('resolves a META.SRC URL as a remote source', () => {
+		const result = resolveBlockConfig({ META: { SRC: 'https://example.com/install.sh#L5-9' } }, testSettings(), 'bash');
+		expect(result.sourcePath).toBe('https://example.com/install.sh');
+		expect(result.filterByLines.start).toBe(5);
+	});
+
+	it('resolves a META.SRC region, which FILTER.BY_REGION overrides', () => {
+		expect(resolveBlockConfig({ META: { SRC: 'src/app.ts#setup' } }, testSettings(), 'ts').filterByRegion).toBe('setup');
+		expect(resolveBlockConfig({
+			META: { SRC: 'src/app.ts#setup' },
+			FILTER: { BY_REGION: 'teardown' },
+		}, testSettings(), 'ts').filterByRegion).toBe('teardown');
+		expect(resolveBlockConfig({}, testSettings(), 'ts').filterByRegion).toBe('');
+	});
+
+	it('lets META.PATH and FILTER.BY_LINES win over META.SRC', () => {
+		const result = resolveBlockConfig({
+			META: { PATH: 'vault://other.sh', SRC: 'scripts/deploy.sh#L10-42' },
+			FILTER: { BY_LINES: { RANGE: [2, 4] } },
+		}, testSettings(), 'bash');
+		expect(result.sourcePath).toBe('vault://other.sh');
+		expect(result.filterByLines.start).toBe(2);
+		expect(result.startingLineNumber).toBe(1);
+	});
+
 	it('resolves BY_LINES filter', () => {
 		const parsed: ParsedYamlConfig = {
 			FILTER: {
@@ -944,6 +1950,43 @@ describe('resolveBlockConfig', () => {
 		const result = resolveBlockConfig({}, settings, 'text');
 		expect(result.printBehaviour).toBe('expand');
 	});
+
+	it('puts RENDER.THEME\'s token styles before TOKENS', () => {
+		const theme = { name: 'One Dark', background: '#282c34', foreground: '#abb2bf', tokens: { keyword: '#c678dd', comment: 'italic #5c6370' } };
+		const settings = testSettings({ highlightThemes: { 'one dark': theme } });
+
+		const result = resolveBlockConfig({ RENDER: { THEME: 'One Dark' }, TOKENS: { keyword: 'bold' } }, settings, 'text');
+
+		expect(result.highlightTheme).toBe(theme);
+		expect(result.tokenStyles.map(style => `${style.token} ${style.colour || style.fontWeight}`)).toEqual([
+			'keyword #c678dd',
+			'comment #5c6370',
+			'keyword bold',
+		]);
+		expect(resolveBlockConfig({ RENDER: { THEME: 'missing' } }, settings, 'text').highlightTheme).toBeUndefined();
+	});
+
+	it('falls back to themes from other plugins, preferring the settings\' own', () => {
+		const imported = { name: 'Dracula', background: '#282a36', foreground: '', tokens: {} };
+		const contributed = { ...imported, background: '#000' };
+		const nord = { name: 'Nord', background: '#2e3440', foreground: '', tokens: {} };
+		const settings = testSettings({ highlightThemes: { dracula: imported } });
+		const themes = { dracula: contributed, nord };
+
+		expect(resolveBlockConfig({ RENDER: { THEME: 'Dracula' } }, settings, 'text', themes).highlightTheme).toBe(imported);
+		expect(resolveBlockConfig({ RENDER: { THEME: 'Nord' } }, settings, 'text', themes).highlightTheme).toBe(nord);
+	});
+
+	it('resolves PATTERNS into global regexes, skipping invalid ones', () => {
+		const result = resolveBlockConfig({ PATTERNS: { 'PROJ-\\d+': 'bold #a855f7', '(': 'red' } }, testSettings(), 'text');
+
+		expect(result.patternStyles).toHaveLength(1);
+		expect(result.patternStyles[0].pattern.source).toBe('PROJ-\\d+');
+		expect(result.patternStyles[0].pattern.flags).toBe('g');
+		expect(result.patternStyles[0].style.colour).toBe('#a855f7');
+		expect(result.patternStyles[0].style.fontWeight).toBe('bold');
+		expect(resolveBlockConfig({}, testSettings(), 'text').patternStyles).toEqual([]);
+	});
 });
 
 describe('resolveCmdoutConfig', () => {
@@ -1049,4 +2092,23 @@ describe('resolveCmdoutConfig', () => {
 		const result = resolveCmdoutConfig({}, testSettings());
 		expect(result.printBehaviour).toBe('expand');
 	});
+
+	it('resolves the print theme and page breaks from PRINT', () => {
+		const result = resolveCmdoutConfig({ PRINT: { THEME: 'asis', AVOID_BREAKS: false, EXPAND: false } }, testSettings());
+		expect(result.printTheme).toBe('asis');
+		expect(result.printAvoidBreaks).toBe(false);
+		expect(result.printBehaviour).toBe('asis');
+	});
+
+	it('renders ANSI codes and copies them stripped by default', () => {
+		const result = resolveCmdoutConfig({}, testSettings());
+		expect(result.renderAnsi).toBe(true);
+		expect(result.copyRawAnsi).toBe(false);
+	});
+
+	it('reads ANSI and ANSI_COPY from RENDER', () => {
+		const result = resolveCmdoutConfig({ RENDER: parseRenderDisplaySection({ RENDER: { ANSI: 'false', ANSI_COPY: 'Raw' } }) }, testSettings());
+		expect(result.renderAnsi).toBe(false);
+		expect(result.copyRawAnsi).toBe(true);
+	});
 });
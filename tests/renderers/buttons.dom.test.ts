@@ -3,19 +3,33 @@
 /**
  * Tests for src/renderers/buttons.ts - DOM Functions
  *
- * Tests: addCopyButton, addDownloadButton, addFoldButton, addCodeBlockButtons
+ * Tests: addCopyButton, addLineCopyButtons, addLineRunButtons, addRegionCopyButtons, addCopyAsButton, addDownloadButton,
+ *        addEditButton, addFoldButton, addMaxHeightExpander, addCollapseToggle, addWrapToggleButton, addFocusToggleButton, addCodeBlockButtons (including the
+ *        copy count badge), moveButtonsToHeader
  * These tests verify DOM manipulation, event handling, and button state management.
  */
 
 import { describe, it, expect, beforeEach, afterEach, vi } from 'vitest';
-import { Platform } from 'obsidian';
+import { Menu, Platform } from 'obsidian';
 import {
 	addCopyButton,
+	addLineCopyButtons,
+	addLineRunButtons,
+	addRegionCopyButtons,
+	addCopyAsButton,
 	addDownloadButton,
+	addEditButton,
 	addFoldButton,
+	addMaxHeightExpander,
+	addCollapseToggle,
+	addWrapToggleButton,
+	addFocusToggleButton,
 	addCodeBlockButtons,
+	moveButtonsToHeader,
 } from '../../src/renderers/buttons';
 import { CSS_CLASSES, COPY_SUCCESS_DURATION_MS } from '../../src/constants';
+import { wrapCodeLinesInDom } from '../../src/utils/dom';
+import { markDiffLines } from '../../src/renderers/code-block';
 
 // Mock navigator.clipboard
 Object.assign(navigator, {
@@ -130,6 +144,71 @@ describe('addCopyButton', () => {
 		expect(navigator.clipboard.writeText).toHaveBeenCalledWith('test code');
 	});
 
+	it('strips prompts from copied text when promptPattern is set', async () => {
+		codeElement.textContent = '$ cd /tmp\n$ ls';
+		addCopyButton(preElement, { promptPattern: /^\$\s/ });
+
+		const button = preElement.querySelector(
+			`.${CSS_CLASSES.copyButton}`
+		) as HTMLButtonElement;
+		button.click();
+
+		await new Promise(resolve => setTimeout(resolve, 0));
+
+		expect(navigator.clipboard.writeText).toHaveBeenCalledWith('cd /tmp\nls');
+	});
+
+	it('copies the text returned by fillPlaceholders', async () => {
+		codeElement.textContent = 'ping {{HOST}}';
+		const fillPlaceholders = vi.fn((text: string) => Promise.resolve(text.replace('{{HOST}}', 'example.com')));
+		addCopyButton(preElement, { fillPlaceholders });
+
+		(preElement.querySelector(`.${CSS_CLASSES.copyButton}`) as HTMLButtonElement).click();
+		await new Promise(resolve => setTimeout(resolve, 0));
+
+		expect(fillPlaceholders).toHaveBeenCalledWith('ping {{HOST}}');
+		expect(navigator.clipboard.writeText).toHaveBeenCalledWith('ping example.com');
+	});
+
+	it('does not copy when the placeholder prompt is cancelled', async () => {
+		addCopyButton(preElement, { fillPlaceholders: () => Promise.resolve(null) });
+
+		(preElement.querySelector(`.${CSS_CLASSES.copyButton}`) as HTMLButtonElement).click();
+		await new Promise(resolve => setTimeout(resolve, 0));
+
+		expect(navigator.clipboard.writeText).not.toHaveBeenCalled();
+	});
+
+	it('reports the copied text to onCopied', async () => {
+		const onCopied = vi.fn();
+		addCopyButton(preElement, { onCopied });
+
+		(preElement.querySelector(`.${CSS_CLASSES.copyButton}`) as HTMLButtonElement).click();
+		await new Promise(resolve => setTimeout(resolve, 0));
+
+		expect(onCopied).toHaveBeenCalledWith('test code');
+	});
+
+	it('redacts secrets from the copy when redactPatterns is set', async () => {
+		codeElement.textContent = 'login --token abc123';
+		addCopyButton(preElement, { redactPatterns: [/abc\d+/g] });
+
+		(preElement.querySelector(`.${CSS_CLASSES.copyButton}`) as HTMLButtonElement).click();
+		await new Promise(resolve => setTimeout(resolve, 0));
+
+		expect(navigator.clipboard.writeText).toHaveBeenCalledWith('login --token «REDACTED»');
+	});
+
+	it('leaves comment lines out of the copy when commentSyntax is set', async () => {
+		codeElement.textContent = '# Update\napt update';
+		addCopyButton(preElement, { commentSyntax: { line: ['#'] } });
+
+		(preElement.querySelector(`.${CSS_CLASSES.copyButton}`) as HTMLButtonElement).click();
+		await new Promise(resolve => setTimeout(resolve, 0));
+
+		expect(navigator.clipboard.writeText).toHaveBeenCalledWith('apt update');
+	});
+
 	it('adds copied class after successful copy', async () => {
 		addCopyButton(preElement);
 
@@ -199,6 +278,43 @@ describe('addCopyButton', () => {
 		vi.useRealTimers();
 	});
 
+	it('keeps the copy icon when feedback turns the checkmark off', async () => {
+		addCopyButton(preElement, { feedback: { checkmark: false, toastText: '', statusBar: false, durationMs: 2000 } });
+
+		const button = preElement.querySelector(
+			`.${CSS_CLASSES.copyButton}`
+		) as HTMLButtonElement;
+		const originalHTML = button.innerHTML;
+
+		button.click();
+		await vi.waitFor(() => {
+			expect(navigator.clipboard.writeText).toHaveBeenCalled();
+		});
+		await new Promise(resolve => setTimeout(resolve, 10));
+
+		expect(button.classList.contains(CSS_CLASSES.copied)).toBe(false);
+		expect(button.innerHTML).toBe(originalHTML);
+	});
+
+	it('uses the feedback duration for the copied state', async () => {
+		vi.useFakeTimers({ shouldAdvanceTime: true });
+		addCopyButton(preElement, { feedback: { checkmark: true, toastText: '', statusBar: false, durationMs: 5000 } });
+
+		const button = preElement.querySelector(
+			`.${CSS_CLASSES.copyButton}`
+		) as HTMLButtonElement;
+		button.click();
+		await vi.waitFor(() => button.classList.contains(CSS_CLASSES.copied));
+
+		vi.advanceTimersByTime(4000);
+		expect(button.classList.contains(CSS_CLASSES.copied)).toBe(true);
+
+		vi.advanceTimersByTime(1000);
+		expect(button.classList.contains(CSS_CLASSES.copied)).toBe(false);
+
+		vi.useRealTimers();
+	});
+
 	it('handles click event asynchronously', async () => {
 		addCopyButton(preElement);
 
@@ -351,6 +467,15 @@ describe('addDownloadButton', () => {
 		expect(onDownloadMock).toHaveBeenCalledWith('downloadable code');
 	});
 
+	it('downloads the whole code of a partly shown file', async () => {
+		addDownloadButton(preElement, onDownloadMock, { fullText: 'downloadable code\nand the rest' });
+
+		(preElement.querySelector(`.${CSS_CLASSES.downloadButton}`) as HTMLButtonElement).click();
+		await new Promise(resolve => setTimeout(resolve, 0));
+
+		expect(onDownloadMock).toHaveBeenCalledWith('downloadable code\nand the rest');
+	});
+
 	it('handles click event asynchronously', async () => {
 		addDownloadButton(preElement, onDownloadMock);
 
@@ -365,6 +490,47 @@ describe('addDownloadButton', () => {
 		// Verify the click handler was called by checking the callback
 		expect(onDownloadMock).toHaveBeenCalled();
 	});
+
+	it('strips prompts from downloaded code', () => {
+		codeElement.textContent = '$ make install';
+		addDownloadButton(preElement, onDownloadMock, { promptPattern: /^\$\s/ });
+
+		const button = preElement.querySelector(`.${CSS_CLASSES.downloadButton}`) as HTMLButtonElement;
+		button.click();
+
+		expect(onDownloadMock).toHaveBeenCalledWith('make install');
+	});
+});
+
+describe('addEditButton', () => {
+	afterEach(() => {
+		document.body.innerHTML = '';
+	});
+
+	it('calls the edit callback when clicked', () => {
+		const preElement = document.createElement('pre');
+		const onEdit = vi.fn();
+		addEditButton(preElement, onEdit);
+
+		const button = preElement.querySelector(`.${CSS_CLASSES.editButton}`) as HTMLButtonElement;
+		expect(button.getAttribute('aria-label')).toBe('Edit source file');
+		button.click();
+
+		expect(onEdit).toHaveBeenCalledTimes(1);
+	});
+
+	it('is only added by addCodeBlockButtons when there is an edit callback', () => {
+		const withEdit = document.createElement('pre');
+		withEdit.appendChild(document.createElement('code'));
+		addCodeBlockButtons(withEdit, { showCopyButton: false, showDownloadButton: false, totalLineCount: 1, foldLines: 0, onEdit: vi.fn() });
+
+		const withoutEdit = document.createElement('pre');
+		withoutEdit.appendChild(document.createElement('code'));
+		addCodeBlockButtons(withoutEdit, { showCopyButton: false, showDownloadButton: false, totalLineCount: 1, foldLines: 0 });
+
+		expect(withEdit.querySelector(`.${CSS_CLASSES.editButton}`)).not.toBeNull();
+		expect(withoutEdit.querySelector(`.${CSS_CLASSES.editButton}`)).toBeNull();
+	});
 });
 
 describe('addFoldButton', () => {
@@ -512,6 +678,208 @@ describe('addFoldButton', () => {
 	});
 });
 
+describe('addMaxHeightExpander', () => {
+	let preElement: HTMLPreElement;
+
+	beforeEach(() => {
+		preElement = document.createElement('pre');
+		preElement.appendChild(document.createElement('code'));
+		document.body.appendChild(preElement);
+
+		vi.spyOn(window, 'getComputedStyle').mockReturnValue({
+			lineHeight: '20px',
+		} as CSSStyleDeclaration);
+	});
+
+	afterEach(() => {
+		document.body.innerHTML = '';
+		vi.clearAllMocks();
+	});
+
+	it('clamps to a pixel height with a "show all" button', () => {
+		addMaxHeightExpander(preElement, 412, { value: 400, unit: 'px' });
+
+		expect(preElement.classList.contains(CSS_CLASSES.folded)).toBe(true);
+		expect(preElement.style.getPropertyValue('--ucf-folded-height')).toBe('400px');
+		expect(preElement.querySelector(`.${CSS_CLASSES.foldButton}`)?.textContent).toBe('Show all (412 lines)');
+	});
+
+	it('clamps to a line count', () => {
+		addMaxHeightExpander(preElement, 100, { value: 10, unit: 'lines' });
+
+		expect(preElement.style.getPropertyValue('--ucf-folded-height')).toBe('216px');
+	});
+
+	it('expands and folds again on click', () => {
+		addMaxHeightExpander(preElement, 100, { value: 10, unit: 'lines' });
+		const foldButton = preElement.querySelector(`.${CSS_CLASSES.foldButton}`) as HTMLButtonElement;
+
+		foldButton.click();
+		expect(preElement.classList.contains(CSS_CLASSES.folded)).toBe(false);
+		expect(foldButton.textContent).toBe('Show less');
+
+		foldButton.click();
+		expect(foldButton.textContent).toBe('Show all (100 lines)');
+	});
+
+	it('leaves blocks that fit alone', () => {
+		addMaxHeightExpander(preElement, 10, { value: 400, unit: 'px' });
+		addMaxHeightExpander(preElement, 10, { value: 10, unit: 'lines' });
+
+		expect(preElement.classList.contains(CSS_CLASSES.folded)).toBe(false);
+		expect(preElement.querySelector(`.${CSS_CLASSES.foldBar}`)).toBeNull();
+	});
+});
+
+describe('addCollapseToggle', () => {
+	let containerElement: HTMLElement;
+	let preElement: HTMLPreElement;
+
+	beforeEach(() => {
+		containerElement = document.createElement('div');
+		const titleElement = document.createElement('div');
+		titleElement.className = 'ucf-title';
+		preElement = document.createElement('pre');
+		preElement.appendChild(document.createElement('code'));
+		containerElement.appendChild(titleElement);
+		containerElement.appendChild(preElement);
+		document.body.appendChild(containerElement);
+	});
+
+	afterEach(() => {
+		document.body.innerHTML = '';
+	});
+
+	it('starts collapsed with the bar between the title and the code', () => {
+		addCollapseToggle(containerElement, preElement, 'Full schema', 240);
+
+		const collapseBar = containerElement.querySelector(`.${CSS_CLASSES.collapseBar}`);
+		expect(collapseBar?.previousElementSibling?.className).toBe('ucf-title');
+		expect(collapseBar?.nextElementSibling).toBe(preElement);
+		expect(containerElement.classList.contains(CSS_CLASSES.collapsed)).toBe(true);
+		expect(collapseBar?.textContent).toContain('Full schema');
+	});
+
+	it('shows the line count when there is no summary', () => {
+		addCollapseToggle(containerElement, preElement, '', 240);
+
+		const collapseButton = containerElement.querySelector(`.${CSS_CLASSES.collapseButton}`);
+		expect(collapseButton?.textContent).toBe('Show code (240 lines)');
+	});
+
+	it('toggles the block on click', () => {
+		addCollapseToggle(containerElement, preElement, 'Appendix', 10);
+		const collapseButton = containerElement.querySelector(`.${CSS_CLASSES.collapseButton}`) as HTMLButtonElement;
+		expect(collapseButton.getAttribute('aria-expanded')).toBe('false');
+
+		collapseButton.click();
+		expect(containerElement.classList.contains(CSS_CLASSES.collapsed)).toBe(false);
+		expect(collapseButton.getAttribute('aria-expanded')).toBe('true');
+
+		collapseButton.click();
+		expect(containerElement.classList.contains(CSS_CLASSES.collapsed)).toBe(true);
+	});
+});
+
+describe('addWrapToggleButton', () => {
+	let preElement: HTMLPreElement;
+
+	beforeEach(() => {
+		preElement = document.createElement('pre');
+		preElement.appendChild(document.createElement('code'));
+		document.body.appendChild(preElement);
+	});
+
+	afterEach(() => {
+		document.body.innerHTML = '';
+	});
+
+	it('switches soft wrapping on and off and reports each change', () => {
+		const onToggle = vi.fn();
+		addWrapToggleButton(preElement, false, onToggle);
+		const wrapButton = preElement.querySelector(`.${CSS_CLASSES.wrapButton}`) as HTMLButtonElement;
+		expect(preElement.classList.contains(CSS_CLASSES.softWrapped)).toBe(false);
+		expect(wrapButton.getAttribute('aria-pressed')).toBe('false');
+
+		wrapButton.click();
+		expect(preElement.classList.contains(CSS_CLASSES.softWrapped)).toBe(true);
+		expect(wrapButton.getAttribute('aria-pressed')).toBe('true');
+		expect(onToggle).toHaveBeenLastCalledWith(true);
+
+		wrapButton.click();
+		expect(preElement.classList.contains(CSS_CLASSES.softWrapped)).toBe(false);
+		expect(onToggle).toHaveBeenLastCalledWith(false);
+	});
+
+	it('starts wrapped when the block remembered that choice', () => {
+		addWrapToggleButton(preElement, true);
+
+		expect(preElement.classList.contains(CSS_CLASSES.softWrapped)).toBe(true);
+		expect(preElement.querySelector(`.${CSS_CLASSES.wrapButton}`)?.getAttribute('aria-label')).toBe('Disable word wrap');
+	});
+});
+
+describe('addFocusToggleButton', () => {
+	afterEach(() => {
+		document.body.innerHTML = '';
+	});
+
+	it('undims and dims the block\'s unfocused lines', () => {
+		const preElement = document.createElement('pre');
+		preElement.classList.add(CSS_CLASSES.focus);
+
+		addFocusToggleButton(preElement);
+		const focusButton = preElement.querySelector(`.${CSS_CLASSES.focusButton}`) as HTMLButtonElement;
+		expect(focusButton.getAttribute('aria-label')).toBe('Show all lines');
+
+		focusButton.click();
+		expect(preElement.classList.contains(CSS_CLASSES.focusRevealed)).toBe(true);
+		expect(focusButton.getAttribute('aria-pressed')).toBe('true');
+
+		focusButton.click();
+		expect(preElement.classList.contains(CSS_CLASSES.focusRevealed)).toBe(false);
+	});
+
+	it('skips blocks without focused lines', () => {
+		const preElement = document.createElement('pre');
+
+		addFocusToggleButton(preElement);
+
+		expect(preElement.querySelector(`.${CSS_CLASSES.focusButton}`)).toBeNull();
+	});
+});
+
+describe('moveButtonsToHeader', () => {
+	afterEach(() => {
+		document.body.innerHTML = '';
+	});
+
+	it('moves the copy and download buttons into a group in the title bar', () => {
+		const preElement = document.createElement('pre');
+		preElement.appendChild(document.createElement('code'));
+		const titleElement = document.createElement('div');
+		document.body.append(titleElement, preElement);
+		addCopyButton(preElement);
+		addDownloadButton(preElement, vi.fn());
+
+		moveButtonsToHeader(preElement, titleElement);
+
+		const buttonGroup = titleElement.querySelector(`.${CSS_CLASSES.headerButtons}`);
+		expect(buttonGroup?.querySelector(`.${CSS_CLASSES.copyButton}`)).not.toBeNull();
+		expect(buttonGroup?.querySelector(`.${CSS_CLASSES.downloadButton}`)).not.toBeNull();
+		expect(preElement.querySelector(`.${CSS_CLASSES.copyButton}`)).toBeNull();
+	});
+
+	it('adds nothing when the block has no buttons', () => {
+		const preElement = document.createElement('pre');
+		const titleElement = document.createElement('div');
+
+		moveButtonsToHeader(preElement, titleElement);
+
+		expect(titleElement.children.length).toBe(0);
+	});
+});
+
 describe('addCodeBlockButtons', () => {
 	let preElement: HTMLPreElement;
 	let codeElement: HTMLCodeElement;
@@ -536,6 +904,60 @@ describe('addCodeBlockButtons', () => {
 		vi.clearAllMocks();
 	});
 
+	it('adds the wrap toggle only when a wrap state is given', () => {
+		addCodeBlockButtons(preElement, { showCopyButton: false, showDownloadButton: false, totalLineCount: 1, foldLines: 0 });
+		expect(preElement.querySelector(`.${CSS_CLASSES.wrapButton}`)).toBeNull();
+
+		addCodeBlockButtons(preElement, { showCopyButton: false, showDownloadButton: false, totalLineCount: 1, foldLines: 0, softWrapped: true });
+		expect(preElement.querySelector(`.${CSS_CLASSES.wrapButton}`)).not.toBeNull();
+		expect(preElement.classList.contains(CSS_CLASSES.softWrapped)).toBe(true);
+	});
+
+	it('shows a copy count badge that goes up after each copy', async () => {
+		const onCopied = vi.fn();
+		addCodeBlockButtons(preElement, {
+			showCopyButton: true,
+			showDownloadButton: false,
+			totalLineCount: 1,
+			foldLines: 0,
+			copyCount: 2,
+			onCopied,
+		});
+
+		const badge = preElement.querySelector(`.${CSS_CLASSES.copyCount}`);
+		expect(badge?.textContent).toBe('2');
+
+		(preElement.querySelector(`.${CSS_CLASSES.copyButton}`) as HTMLButtonElement).click();
+		await new Promise(resolve => setTimeout(resolve, 10));
+
+		expect(onCopied).toHaveBeenCalledWith('test code content');
+		expect(badge?.textContent).toBe('3');
+		expect(badge?.getAttribute('title')).toBe('Copied 3 times');
+	});
+
+	it('leaves the copy count badge empty before the first copy', () => {
+		addCodeBlockButtons(preElement, {
+			showCopyButton: true,
+			showDownloadButton: false,
+			totalLineCount: 1,
+			foldLines: 0,
+			copyCount: 0,
+		});
+
+		expect(preElement.querySelector(`.${CSS_CLASSES.copyCount}`)?.textContent).toBe('');
+	});
+
+	it('adds no copy count badge without copyCount', () => {
+		addCodeBlockButtons(preElement, {
+			showCopyButton: true,
+			showDownloadButton: false,
+			totalLineCount: 1,
+			foldLines: 0,
+		});
+
+		expect(preElement.querySelector(`.${CSS_CLASSES.copyCount}`)).toBeNull();
+	});
+
 	it('adds copy button when showCopyButton is true', () => {
 		addCodeBlockButtons(preElement, {
 			showCopyButton: true,
@@ -702,3 +1124,418 @@ describe('addCodeBlockButtons', () => {
 		expect(navigator.clipboard.writeText).toHaveBeenCalledWith('line1 && line2');
 	});
 });
+
+describe('addRegionCopyButtons', () => {
+	const REGION_CODE = '# region: setup\napt update\n# endregion\n\n# region: run\n$ ./start.sh\n# endregion';
+
+	let containerElement: HTMLDivElement;
+	let preElement: HTMLPreElement;
+
+	beforeEach(() => {
+		containerElement = document.createElement('div');
+		preElement = document.createElement('pre');
+		const codeElement = document.createElement('code');
+		codeElement.textContent = REGION_CODE;
+		preElement.appendChild(codeElement);
+		containerElement.appendChild(preElement);
+		document.body.appendChild(containerElement);
+		vi.clearAllMocks();
+	});
+
+	afterEach(() => {
+		document.body.innerHTML = '';
+	});
+
+	it('adds one button per region, labelled with its name', () => {
+		addRegionCopyButtons(preElement);
+
+		const buttons = Array.from(containerElement.querySelectorAll(`.${CSS_CLASSES.regionButton}`));
+		expect(buttons.map(button => button.textContent)).toEqual(['setup', 'run']);
+	});
+
+	it('places the bar above the code when there is no title', () => {
+		addRegionCopyButtons(preElement);
+
+		expect(preElement.previousElementSibling?.classList.contains(CSS_CLASSES.regionBar)).toBe(true);
+	});
+
+	it('places the bar in the title bar when there is one', () => {
+		const titleElement = document.createElement('div');
+		titleElement.className = CSS_CLASSES.title;
+		containerElement.insertBefore(titleElement, preElement);
+
+		addRegionCopyButtons(preElement);
+
+		expect(titleElement.querySelector(`.${CSS_CLASSES.regionBar}`)).not.toBeNull();
+	});
+
+	it('adds nothing when the code has no regions', () => {
+		(preElement.querySelector('code') as HTMLElement).textContent = 'ls';
+		addRegionCopyButtons(preElement);
+
+		expect(containerElement.querySelector(`.${CSS_CLASSES.regionBar}`)).toBeNull();
+	});
+
+	it('copies only the region, through the cleanup pipeline', async () => {
+		addRegionCopyButtons(preElement, { promptPattern: /^\$\s/ });
+
+		const buttons = containerElement.querySelectorAll<HTMLButtonElement>(`.${CSS_CLASSES.regionButton}`);
+		buttons[1].click();
+		await new Promise(resolve => setTimeout(resolve, 0));
+
+		expect(navigator.clipboard.writeText).toHaveBeenCalledWith('./start.sh');
+	});
+
+	it('is added by addCodeBlockButtons with the copy button', () => {
+		addCodeBlockButtons(preElement, { showCopyButton: true, showDownloadButton: false, totalLineCount: 7, foldLines: 0 });
+		expect(containerElement.querySelectorAll(`.${CSS_CLASSES.regionButton}`).length).toBe(2);
+	});
+
+	it('is not added when the copy button is off', () => {
+		addCodeBlockButtons(preElement, { showCopyButton: false, showDownloadButton: false, totalLineCount: 7, foldLines: 0 });
+		expect(containerElement.querySelector(`.${CSS_CLASSES.regionBar}`)).toBeNull();
+	});
+});
+
+describe('addLineCopyButtons', () => {
+	let preElement: HTMLPreElement;
+	let codeElement: HTMLCodeElement;
+
+	beforeEach(() => {
+		preElement = document.createElement('pre');
+		codeElement = document.createElement('code');
+		codeElement.textContent = 'echo one\n\necho three';
+		preElement.appendChild(codeElement);
+		document.body.appendChild(preElement);
+		wrapCodeLinesInDom(codeElement, { showLineNumbers: true, showZebraStripes: false });
+
+		vi.clearAllMocks();
+	});
+
+	afterEach(() => {
+		document.body.innerHTML = '';
+	});
+
+	it('adds a button to each non-empty line and a spacer to blank lines', () => {
+		addLineCopyButtons(preElement);
+
+		const lines = codeElement.querySelectorAll(`.${CSS_CLASSES.line}`);
+		expect(lines[0].firstElementChild?.classList.contains(CSS_CLASSES.lineCopyButton)).toBe(true);
+		expect(lines[1].firstElementChild?.classList.contains(CSS_CLASSES.lineCopySpacer)).toBe(true);
+		expect(lines[2].firstElementChild?.classList.contains(CSS_CLASSES.lineCopyButton)).toBe(true);
+	});
+
+	it('copies only the clicked line', async () => {
+		addLineCopyButtons(preElement);
+
+		const buttons = codeElement.querySelectorAll<HTMLButtonElement>(`.${CSS_CLASSES.lineCopyButton}`);
+		buttons[1].click();
+
+		await new Promise(resolve => setTimeout(resolve, 10));
+
+		expect(navigator.clipboard.writeText).toHaveBeenCalledWith('echo three');
+	});
+
+	it('does nothing when lines are not wrapped', () => {
+		const plainPre = document.createElement('pre');
+		const plainCode = document.createElement('code');
+		plainCode.textContent = 'echo one';
+		plainPre.appendChild(plainCode);
+
+		addLineCopyButtons(plainPre);
+
+		expect(plainPre.querySelector(`.${CSS_CLASSES.lineCopyButton}`)).toBeNull();
+	});
+
+	it('does not leak line buttons into the whole-block copy', async () => {
+		addLineCopyButtons(preElement);
+		addCopyButton(preElement);
+
+		const button = preElement.querySelector(`.${CSS_CLASSES.copyButton}`) as HTMLButtonElement;
+		button.click();
+
+		await new Promise(resolve => setTimeout(resolve, 10));
+
+		expect(navigator.clipboard.writeText).toHaveBeenCalledWith('echo one\n\necho three');
+	});
+
+	it('strips the prompt from the copied line', async () => {
+		const promptPre = document.createElement('pre');
+		const promptCode = document.createElement('code');
+		promptCode.textContent = '$ echo one';
+		promptPre.appendChild(promptCode);
+		wrapCodeLinesInDom(promptCode, { showLineNumbers: false, showZebraStripes: false });
+
+		addLineCopyButtons(promptPre, { promptPattern: /^\$\s/ });
+		(promptPre.querySelector(`.${CSS_CLASSES.lineCopyButton}`) as HTMLButtonElement).click();
+
+		await new Promise(resolve => setTimeout(resolve, 10));
+
+		expect(navigator.clipboard.writeText).toHaveBeenCalledWith('echo one');
+	});
+});
+
+describe('addLineRunButtons', () => {
+	let preElement: HTMLPreElement;
+	let codeElement: HTMLCodeElement;
+
+	beforeEach(() => {
+		preElement = document.createElement('pre');
+		codeElement = document.createElement('code');
+		codeElement.textContent = 'cd build\n\nmake';
+		preElement.appendChild(codeElement);
+		document.body.appendChild(preElement);
+		wrapCodeLinesInDom(codeElement, { showLineNumbers: true, showZebraStripes: false });
+	});
+
+	afterEach(() => {
+		document.body.innerHTML = '';
+	});
+
+	it('adds a button to each non-empty line and a spacer to blank lines', () => {
+		addLineRunButtons(preElement, () => Promise.resolve({ exitCode: 0 }));
+
+		const lines = codeElement.querySelectorAll(`.${CSS_CLASSES.line}`);
+		expect(lines[0].firstElementChild?.classList.contains(CSS_CLASSES.lineRunButton)).toBe(true);
+		expect(lines[1].firstElementChild?.classList.contains(CSS_CLASSES.lineRunSpacer)).toBe(true);
+		expect(lines[2].firstElementChild?.classList.contains(CSS_CLASSES.lineRunButton)).toBe(true);
+	});
+
+	it('runs only the clicked line, marking it with its exit status', async () => {
+		const onRunLine = vi.fn((line: string) => Promise.resolve({ exitCode: line === 'make' ? 2 : 0 }));
+		addLineRunButtons(preElement, onRunLine);
+
+		const lines = codeElement.querySelectorAll(`.${CSS_CLASSES.line}`);
+		const buttons = codeElement.querySelectorAll<HTMLButtonElement>(`.${CSS_CLASSES.lineRunButton}`);
+		buttons[0].click();
+		await new Promise(resolve => setTimeout(resolve, 0));
+
+		expect(onRunLine).toHaveBeenCalledWith('cd build');
+		expect(lines[0].classList.contains(CSS_CLASSES.lineRunCurrent)).toBe(true);
+		expect(lines[0].querySelector(`.${CSS_CLASSES.lineRunStatus}`)?.textContent).toBe('✓');
+
+		buttons[1].click();
+		await new Promise(resolve => setTimeout(resolve, 0));
+
+		expect(lines[0].classList.contains(CSS_CLASSES.lineRunCurrent)).toBe(false);
+		expect(lines[0].querySelector(`.${CSS_CLASSES.lineRunStatus}`)).toBeNull();
+		const status = lines[2].querySelector(`.${CSS_CLASSES.lineRunStatus}`);
+		expect(status?.textContent).toBe('✗ 2');
+		expect(status?.classList.contains(CSS_CLASSES.lineRunFailed)).toBe(true);
+	});
+
+	it('clears the mark when the run does not start', async () => {
+		addLineRunButtons(preElement, () => Promise.resolve(undefined));
+		codeElement.querySelector<HTMLButtonElement>(`.${CSS_CLASSES.lineRunButton}`)?.click();
+		await new Promise(resolve => setTimeout(resolve, 0));
+
+		expect(codeElement.querySelector(`.${CSS_CLASSES.lineRunCurrent}`)).toBeNull();
+		expect(codeElement.querySelector(`.${CSS_CLASSES.lineRunStatus}`)).toBeNull();
+	});
+
+	it('keeps the last mark when the next run does not start', async () => {
+		const onRunLine = vi.fn((line: string) => Promise.resolve(line === 'make' ? undefined : { exitCode: 0 }));
+		addLineRunButtons(preElement, onRunLine);
+
+		const lines = codeElement.querySelectorAll(`.${CSS_CLASSES.line}`);
+		const buttons = codeElement.querySelectorAll<HTMLButtonElement>(`.${CSS_CLASSES.lineRunButton}`);
+		buttons[0].click();
+		await new Promise(resolve => setTimeout(resolve, 0));
+		buttons[1].click();
+		await new Promise(resolve => setTimeout(resolve, 0));
+
+		expect(lines[0].classList.contains(CSS_CLASSES.lineRunCurrent)).toBe(true);
+		expect(lines[0].querySelector(`.${CSS_CLASSES.lineRunStatus}`)?.textContent).toBe('✓');
+		expect(lines[2].querySelector(`.${CSS_CLASSES.lineRunStatus}`)).toBeNull();
+	});
+
+	it('disables the line buttons until the line has run', async () => {
+		const finished: { resolve?: (result: { exitCode: number }) => void } = {};
+		addLineRunButtons(preElement, () => new Promise((resolve) => { finished.resolve = resolve; }));
+
+		const buttons = codeElement.querySelectorAll<HTMLButtonElement>(`.${CSS_CLASSES.lineRunButton}`);
+		buttons[0].click();
+		expect(Array.from(buttons).every(button => button.disabled)).toBe(true);
+
+		finished.resolve?.({ exitCode: 0 });
+		await new Promise(resolve => setTimeout(resolve, 0));
+		expect(Array.from(buttons).some(button => button.disabled)).toBe(false);
+	});
+});
+
+describe('addCopyAsButton', () => {
+	let preElement: HTMLPreElement;
+
+	beforeEach(() => {
+		preElement = document.createElement('pre');
+		const codeElement = document.createElement('code');
+		codeElement.textContent = 'apt-get update\napt-get install -y curl';
+		preElement.appendChild(codeElement);
+		document.body.appendChild(preElement);
+
+		Menu.lastShown = null;
+		vi.clearAllMocks();
+	});
+
+	afterEach(() => {
+		document.body.innerHTML = '';
+	});
+
+	it('does not add a button when there are no entries', () => {
+		addCopyAsButton(preElement, []);
+		expect(preElement.querySelector(`.${CSS_CLASSES.copyAsButton}`)).toBeNull();
+	});
+
+	it('opens a menu with one item per entry', () => {
+		addCopyAsButton(preElement, [
+			{ label: 'Dockerfile RUN', format: 'dockerfile', target: '', schedule: '' },
+			{ label: 'Crontab entry', format: 'crontab', target: '', schedule: '' },
+		]);
+
+		const button = preElement.querySelector(`.${CSS_CLASSES.copyAsButton}`) as HTMLButtonElement;
+		button.click();
+
+		expect(Menu.lastShown?.items.map(item => item.title)).toEqual(['Dockerfile RUN', 'Crontab entry']);
+	});
+
+	it('copies the transformed text when a menu item is chosen', async () => {
+		addCopyAsButton(preElement, [
+			{ label: 'Dockerfile RUN', format: 'dockerfile', target: '', schedule: '' },
+		]);
+
+		const button = preElement.querySelector(`.${CSS_CLASSES.copyAsButton}`) as HTMLButtonElement;
+		button.click();
+		Menu.lastShown?.items[0].callback?.();
+
+		await new Promise(resolve => setTimeout(resolve, 10));
+
+		expect(navigator.clipboard.writeText).toHaveBeenCalledWith(
+			'RUN apt-get update \\\n    && apt-get install -y curl'
+		);
+	});
+
+	it('falls back to plain text for richtext without ClipboardItem support', async () => {
+		addCopyAsButton(preElement, [
+			{ label: 'Rich text', format: 'richtext', target: '', schedule: '' },
+		]);
+
+		const button = preElement.querySelector(`.${CSS_CLASSES.copyAsButton}`) as HTMLButtonElement;
+		button.click();
+		Menu.lastShown?.items[0].callback?.();
+
+		await new Promise(resolve => setTimeout(resolve, 10));
+
+		expect(navigator.clipboard.writeText).toHaveBeenCalledWith('apt-get update\napt-get install -y curl');
+	});
+
+	it('writes HTML and plain text for richtext', async () => {
+		const items: Record<string, Blob>[] = [];
+		vi.stubGlobal('ClipboardItem', class {
+			constructor(data: Record<string, Blob>) { items.push(data); }
+		});
+		const write = vi.fn(() => Promise.resolve());
+		Object.assign(navigator.clipboard, { write });
+		const onCopied = vi.fn();
+
+		addCopyAsButton(preElement, [
+			{ label: 'Rich text', format: 'richtext', target: '', schedule: '' },
+		], { onCopied });
+
+		const button = preElement.querySelector(`.${CSS_CLASSES.copyAsButton}`) as HTMLButtonElement;
+		button.click();
+		Menu.lastShown?.items[0].callback?.();
+
+		await new Promise(resolve => setTimeout(resolve, 10));
+
+		expect(write).toHaveBeenCalledTimes(1);
+		expect(Object.keys(items[0])).toEqual(['text/html', 'text/plain']);
+		expect(items[0]['text/html'].type).toBe('text/html');
+		expect(onCopied).toHaveBeenCalledWith('apt-get update\napt-get install -y curl');
+
+		vi.unstubAllGlobals();
+	});
+
+	it('is only added by addCodeBlockButtons alongside the copy button', () => {
+		const entries = [{ label: 'Crontab entry', format: 'crontab', target: '', schedule: '' }];
+
+		addCodeBlockButtons(preElement, {
+			showCopyButton: false,
+			showDownloadButton: false,
+			totalLineCount: 2,
+			foldLines: 0,
+			copyAsEntries: entries,
+		});
+		expect(preElement.querySelector(`.${CSS_CLASSES.copyAsButton}`)).toBeNull();
+
+		addCodeBlockButtons(preElement, {
+			showCopyButton: true,
+			showDownloadButton: false,
+			totalLineCount: 2,
+			foldLines: 0,
+			copyAsEntries: entries,
+		});
+		expect(preElement.querySelector(`.${CSS_CLASSES.copyAsButton}`)).not.toBeNull();
+	});
+
+	it('offers highlighted lines first and copies only those lines', async () => {
+		const codeElement = preElement.querySelector('code') as HTMLElement;
+		codeElement.textContent = 'echo one\necho two\necho three';
+		wrapCodeLinesInDom(codeElement, { showLineNumbers: false, showZebraStripes: false });
+		const lines = codeElement.querySelectorAll(`.${CSS_CLASSES.line}`);
+		lines[0].classList.add(CSS_CLASSES.lineHighlight);
+		lines[2].classList.add(CSS_CLASSES.lineHighlight);
+
+		addCopyAsButton(preElement, [
+			{ label: 'Crontab entry', format: 'crontab', target: '', schedule: '' },
+		]);
+
+		const button = preElement.querySelector(`.${CSS_CLASSES.copyAsButton}`) as HTMLButtonElement;
+		button.click();
+		expect(Menu.lastShown?.items.map(item => item.title)).toEqual(['Highlighted lines', 'Crontab entry']);
+
+		Menu.lastShown?.items[0].callback?.();
+		await new Promise(resolve => setTimeout(resolve, 10));
+
+		expect(navigator.clipboard.writeText).toHaveBeenCalledWith('echo one\necho three');
+	});
+
+	it('adds the button for highlighted lines even without entries', () => {
+		const codeElement = preElement.querySelector('code') as HTMLElement;
+		wrapCodeLinesInDom(codeElement, { showLineNumbers: false, showZebraStripes: false });
+		codeElement.querySelector(`.${CSS_CLASSES.line}`)?.classList.add(CSS_CLASSES.lineHighlight);
+
+		addCopyAsButton(preElement, []);
+
+		expect(preElement.querySelector(`.${CSS_CLASSES.copyAsButton}`)).not.toBeNull();
+	});
+
+	it('offers the new version of a diff block', async () => {
+		const codeElement = preElement.querySelector('code') as HTMLElement;
+		codeElement.textContent = ' keep\n-old\n+new';
+		wrapCodeLinesInDom(codeElement, { showLineNumbers: false, showZebraStripes: false });
+		markDiffLines(preElement, codeElement, { unified: true, addedLines: [], removedLines: [] });
+
+		addCopyAsButton(preElement, []);
+
+		const button = preElement.querySelector(`.${CSS_CLASSES.copyAsButton}`) as HTMLButtonElement;
+		button.click();
+		expect(Menu.lastShown?.items.map(item => item.title)).toEqual(['After changes']);
+
+		Menu.lastShown?.items[0].callback?.();
+		await new Promise(resolve => setTimeout(resolve, 10));
+
+		expect(navigator.clipboard.writeText).toHaveBeenCalledWith('keep\nnew');
+	});
+
+	it('offers the whole diff when the copy button copies the new version', () => {
+		const codeElement = preElement.querySelector('code') as HTMLElement;
+		codeElement.textContent = '+new';
+		wrapCodeLinesInDom(codeElement, { showLineNumbers: false, showZebraStripes: false });
+		markDiffLines(preElement, codeElement, { unified: true, addedLines: [], removedLines: [] });
+
+		addCopyAsButton(preElement, [], { copyDiffAfter: true });
+
+		(preElement.querySelector(`.${CSS_CLASSES.copyAsButton}`) as HTMLButtonElement).click();
+		expect(Menu.lastShown?.items.map(item => item.title)).toEqual(['Diff']);
+	});
+});
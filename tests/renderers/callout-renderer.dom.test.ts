@@ -5,7 +5,7 @@
  *
  * Tests the injectCallouts() function which injects callouts into code block DOM.
  * Covers: inline, footnote, and popover display modes; replace functionality;
- * popover interactions (click to show/hide, click outside to close).
+ * popover interactions (click to show/hide, hover to preview, click outside to close).
  */
 
 import { describe, it, expect, beforeEach } from 'vitest';
@@ -651,6 +651,60 @@ describe('injectCallouts', () => {
 			expect(popovers[1].classList.contains('ucf-popover-visible')).toBe(false);
 		});
 
+		it('hovering a trigger previews the popover until the pointer leaves', () => {
+			const config = createCalloutConfig({
+				entries: [
+					createCalloutEntry({
+						targetLines: [1],
+						displayMode: 'popover',
+						text: 'Content',
+					}),
+				],
+			});
+
+			injectCallouts(code, pre, container, config);
+
+			const trigger = pre.querySelector('.ucf-callout-trigger') as HTMLElement;
+			const popover = pre.querySelector('.ucf-callout-popover') as HTMLElement;
+
+			trigger.dispatchEvent(new MouseEvent('mouseenter'));
+			expect(popover.classList.contains('ucf-popover-visible')).toBe(true);
+
+			trigger.dispatchEvent(new MouseEvent('mouseleave'));
+			expect(popover.classList.contains('ucf-popover-visible')).toBe(false);
+		});
+
+		it('keeps a clicked popover open when the pointer leaves', () => {
+			const config = createCalloutConfig({
+				entries: [
+					createCalloutEntry({
+						targetLines: [1],
+						displayMode: 'popover',
+						text: 'Pop 1',
+					}),
+					createCalloutEntry({
+						targetLines: [2],
+						displayMode: 'popover',
+						text: 'Pop 2',
+					}),
+				],
+			});
+
+			injectCallouts(code, pre, container, config);
+
+			const triggers = pre.querySelectorAll('.ucf-callout-trigger') as NodeListOf<HTMLElement>;
+			const popovers = pre.querySelectorAll('.ucf-callout-popover') as NodeListOf<HTMLElement>;
+
+			triggers[0].dispatchEvent(new MouseEvent('mouseenter'));
+			triggers[0].click();
+			triggers[0].dispatchEvent(new MouseEvent('mouseleave'));
+			expect(popovers[0].classList.contains('ucf-popover-visible')).toBe(true);
+
+			// Another trigger doesn't preview over the pinned popover
+			triggers[1].dispatchEvent(new MouseEvent('mouseenter'));
+			expect(popovers[1].classList.contains('ucf-popover-visible')).toBe(false);
+		});
+
 		it('trigger click uses stopPropagation to prevent pre handler', () => {
 			const config = createCalloutConfig({
 				entries: [
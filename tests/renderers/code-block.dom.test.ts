@@ -8,6 +8,30 @@
  * - countSourceLines (pure function for line counting)
  * - wrapPreElement (DOM manipulation for container wrapping)
  * - createCodeBlockProcessingOptions (pure factory function)
+ * - markPrompts (prompt marking on wrapped lines)
+ * - markHighlightedLines (HIGHLIGHT.LINES marking)
+ * - markFocusedLines (HIGHLIGHT.FOCUS dimming)
+ * - markMatchingLines (HIGHLIGHT.MATCH marking)
+ * - markHighlightTerms (HIGHLIGHT.TERMS marking)
+ * - markChangedLines (RENDER.CHANGED_SINCE marking)
+ * - addFoldRegionToggles (#region folding)
+ * - addIndentGuides (RENDER.INDENT_GUIDES)
+ * - addColumnRulers (RENDER.RULER)
+ * - applyTextDirection (RENDER.DIRECTION)
+ * - addPrintLineNumbers (PRINT.LINES)
+ * - markWrapIndents (hanging indent when soft-wrapped)
+ * - applyHighlightTheme (RENDER.THEME)
+ * - applyTokenStyles (TOKENS)
+ * - markPatternStyles (PATTERNS)
+ * - colourBracketPairs (RENDER.BRACKETS)
+ * - markWhitespace (RENDER.WHITESPACE)
+ * - addLineAnnotations (ANNOTATIONS gutter icons)
+ * - markCommentKeywords, addCommentKeywordIcons (TODO, FIXME, …)
+ * - addBlameColumn (BLAME metadata column)
+ * - markDiffLines (diff blocks and DIFF.ADDED / DIFF.REMOVED)
+ * - markChangedWords (DIFF.WORDS)
+ * - markPlaceholders ({{PLACEHOLDER}} marking)
+ * - markRedactions (COPY.REDACT masking)
  */
 
 import { describe, it, expect, vi, beforeEach } from 'vitest';
@@ -16,15 +40,61 @@ import {
 	countSourceLines,
 	wrapPreElement,
 	createCodeBlockProcessingOptions,
+	markPrompts,
+	markHighlightedLines,
+	markFocusedLines,
+	markMatchingLines,
+	markHighlightTerms,
+	markChangedLines,
+	addFoldRegionToggles,
+	addIndentGuides,
+	addColumnRulers,
+	applyTextDirection,
+	addPrintLineNumbers,
+	markWrapIndents,
+	applyHighlightTheme,
+	applyTokenStyles,
+	markPatternStyles,
+	colourBracketPairs,
+	markWhitespace,
+	addLineAnnotations,
+	markCommentKeywords,
+	addCommentKeywordIcons,
+	addBlameColumn,
+	markDiffLines,
+	markChangedWords,
+	markPlaceholders,
+	markRedactions,
 	type CodeBlockProcessingOptions,
 } from '../../src/renderers/code-block';
 
-// Mock the utility functions that are called by processCodeBlock
-vi.mock('../../src/utils', () => ({
-	addScrollBehaviour: vi.fn(),
-	processCodeElementLines: vi.fn(),
-	findCodeElement: vi.fn(),
-}));
+// Mock the utility functions that are called by processCodeBlock;
+// the pure text helpers used by markPrompts stay real
+vi.mock('../../src/utils', async (importOriginal) => {
+	const actual = await importOriginal<typeof import('../../src/utils')>();
+	return {
+		addScrollBehaviour: vi.fn(),
+		processCodeElementLines: vi.fn(),
+		findCodeElement: vi.fn(),
+		findPromptLength: actual.findPromptLength,
+		findRedactions: actual.findRedactions,
+		wrapTextRange: actual.wrapTextRange,
+		computeIndentGuides: actual.computeIndentGuides,
+		measureIndent: actual.measureIndent,
+		findIndentScope: actual.findIndentScope,
+		findWhitespaceRuns: actual.findWhitespaceRuns,
+		findCommentKeywords: actual.findCommentKeywords,
+		findTermMatches: actual.findTermMatches,
+		BRACKET_CHARACTERS: actual.BRACKET_CHARACTERS,
+		pairBrackets: actual.pairBrackets,
+		classifyDiffLines: actual.classifyDiffLines,
+		hasDiffMarker: actual.hasDiffMarker,
+		unifiedDiffSteps: actual.unifiedDiffSteps,
+		buildSplitDiffRows: actual.buildSplitDiffRows,
+		diffWords: actual.diffWords,
+		buildFontFeatureSettings: actual.buildFontFeatureSettings,
+	};
+});
 
 // Import mocked functions for spy verification
 import * as utils from '../../src/utils';
@@ -69,6 +139,88 @@ describe('processCodeBlock', () => {
 		expect(preElement?.classList.contains('ucf-code')).toBe(true);
 	});
 
+	it('adds the hover class and zebra colour, wrapping lines for hover', () => {
+		vi.mocked(utils.findCodeElement).mockReturnValue(container.querySelector('code') as HTMLElement);
+
+		processCodeBlock(container, {
+			showLineNumbers: false,
+			showZebraStripes: true,
+			zebraColour: 'rgba(0, 128, 255, 0.08)',
+			lineHover: true,
+			scrollLines: 0,
+		});
+
+		const preElement = container.querySelector('pre') as HTMLPreElement;
+		expect(preElement.classList.contains('ucf-line-hover')).toBe(true);
+		expect(preElement.style.getPropertyValue('--ucf-zebra-colour')).toBe('rgba(0, 128, 255, 0.08)');
+		expect(utils.processCodeElementLines).toHaveBeenCalledWith(
+			preElement,
+			expect.anything(),
+			expect.objectContaining({ forceLineWrapping: true })
+		);
+	});
+
+	it('applies font ligature and feature options to the pre', () => {
+		vi.mocked(utils.findCodeElement).mockReturnValue(container.querySelector('code') as HTMLElement);
+
+		processCodeBlock(container, {
+			showLineNumbers: false,
+			showZebraStripes: false,
+			scrollLines: 0,
+			ligatures: false,
+			fontFeatures: 'zero',
+		});
+
+		const preElement = container.querySelector('pre') as HTMLPreElement;
+		expect(preElement.classList.contains('ucf-font-features')).toBe(true);
+		expect(utils.processCodeElementLines).not.toHaveBeenCalled();
+	});
+
+	it('sets the font family and size on the pre', () => {
+		vi.mocked(utils.findCodeElement).mockReturnValue(container.querySelector('code') as HTMLElement);
+
+		processCodeBlock(container, {
+			showLineNumbers: false,
+			showZebraStripes: false,
+			scrollLines: 0,
+			fontFamily: 'Iosevka',
+			fontSize: '13px',
+		});
+
+		const preElement = container.querySelector('pre') as HTMLPreElement;
+		expect(preElement.classList.contains('ucf-font-face')).toBe(true);
+		expect(preElement.style.fontSize).toBe('13px');
+	});
+
+	it('sets the tab width on the pre', () => {
+		vi.mocked(utils.findCodeElement).mockReturnValue(container.querySelector('code') as HTMLElement);
+
+		processCodeBlock(container, { showLineNumbers: false, showZebraStripes: false, scrollLines: 0, tabSize: 2 });
+
+		const preElement = container.querySelector('pre') as HTMLPreElement;
+		expect(preElement.style.getPropertyValue('--ucf-tab-size')).toBe('2');
+	});
+
+	it('ignores an unknown text direction', () => {
+		vi.mocked(utils.findCodeElement).mockReturnValue(container.querySelector('code') as HTMLElement);
+
+		processCodeBlock(container, { showLineNumbers: false, showZebraStripes: false, scrollLines: 0, textDirection: 'sideways' });
+
+		const preElement = container.querySelector('pre') as HTMLPreElement;
+		expect(preElement.classList.contains('ucf-bidi')).toBe(false);
+	});
+
+	it('leaves the font alone without font options', () => {
+		vi.mocked(utils.findCodeElement).mockReturnValue(container.querySelector('code') as HTMLElement);
+
+		processCodeBlock(container, { showLineNumbers: false, showZebraStripes: false, scrollLines: 0 });
+
+		const preElement = container.querySelector('pre') as HTMLPreElement;
+		expect(preElement.classList.contains('ucf-font-features')).toBe(false);
+		expect(preElement.classList.contains('ucf-font-face')).toBe(false);
+		expect(preElement.getAttribute('style')).toBeNull();
+	});
+
 	it('does not call addScrollBehaviour when scrollLines is 0', () => {
 		const options: CodeBlockProcessingOptions = {
 			showLineNumbers: false,
@@ -508,6 +660,838 @@ describe('createCodeBlockProcessingOptions', () => {
 	});
 });
 
+describe('markPrompts', () => {
+	function wrappedCode(lines: string[]): HTMLElement {
+		const code = document.createElement('code');
+		for (const text of lines) {
+			const line = document.createElement('span');
+			line.className = 'ucf-line';
+			const content = document.createElement('span');
+			content.className = 'ucf-line-content';
+			content.textContent = text;
+			line.appendChild(content);
+			code.appendChild(line);
+		}
+		return code;
+	}
+
+	it('wraps the prompt of each matching line', () => {
+		const code = wrappedCode(['$ ls', 'file.txt', '$ pwd']);
+
+		markPrompts(code, /^\$\s/);
+
+		const prompts = code.querySelectorAll('.ucf-prompt');
+		expect(prompts).toHaveLength(2);
+		expect(prompts[0].textContent).toBe('$ ');
+		expect(code.textContent).toBe('$ lsfile.txt$ pwd');
+	});
+
+	it('uses the first capture group as the prompt', () => {
+		const code = wrappedCode(['PS> Get-Item']);
+
+		markPrompts(code, /^(PS>\s)(.*)/);
+
+		expect(code.querySelector('.ucf-prompt')?.textContent).toBe('PS> ');
+	});
+});
+
+describe('markHighlightedLines', () => {
+	function wrappedCode(lineCount: number): HTMLElement {
+		const code = document.createElement('code');
+		for (let i = 0; i < lineCount; i++) {
+			const line = document.createElement('span');
+			line.className = 'ucf-line';
+			code.appendChild(line);
+		}
+		return code;
+	}
+
+	it('marks the given 1-based lines', () => {
+		const code = wrappedCode(4);
+
+		markHighlightedLines(code, [2, 3]);
+
+		const lines = Array.from(code.children);
+		expect(lines.map(line => line.classList.contains('ucf-line-highlight'))).toEqual([false, true, true, false]);
+	});
+
+	it('ignores lines outside the block', () => {
+		const code = wrappedCode(2);
+
+		markHighlightedLines(code, [5]);
+
+		expect(code.querySelector('.ucf-line-highlight')).toBeNull();
+	});
+});
+
+describe('markChangedLines', () => {
+	it('marks the given 1-based lines and ignores lines outside the block', () => {
+		const code = document.createElement('code');
+		for (let i = 0; i < 3; i++) {
+			const line = document.createElement('span');
+			line.className = 'ucf-line';
+			code.appendChild(line);
+		}
+
+		markChangedLines(code, [1, 3, 9]);
+
+		const lines = Array.from(code.children);
+		expect(lines.map(line => line.classList.contains('ucf-line-changed'))).toEqual([true, false, true]);
+	});
+});
+
+describe('markFocusedLines', () => {
+	function wrappedBlock(lineCount: number): { pre: HTMLPreElement; code: HTMLElement } {
+		const pre = document.createElement('pre');
+		const code = document.createElement('code');
+		for (let i = 0; i < lineCount; i++) {
+			const line = document.createElement('span');
+			line.className = 'ucf-line';
+			code.appendChild(line);
+		}
+		pre.appendChild(code);
+		return { pre, code };
+	}
+
+	it('marks the focused lines and dims the block', () => {
+		const { pre, code } = wrappedBlock(4);
+
+		markFocusedLines(pre, code, [2, 3]);
+
+		expect(pre.classList.contains('ucf-focus')).toBe(true);
+		expect(Array.from(code.children).map(line => line.classList.contains('ucf-line-focused'))).toEqual([false, true, true, false]);
+	});
+
+	it('dims nothing when no focused line is in the block', () => {
+		const { pre, code } = wrappedBlock(2);
+
+		markFocusedLines(pre, code, [5]);
+
+		expect(pre.classList.contains('ucf-focus')).toBe(false);
+	});
+});
+
+describe('markMatchingLines', () => {
+	function wrappedCode(texts: string[]): HTMLElement {
+		const code = document.createElement('code');
+		for (const text of texts) {
+			const line = document.createElement('span');
+			line.className = 'ucf-line';
+			const content = document.createElement('span');
+			content.className = 'ucf-line-content';
+			content.textContent = text;
+			line.appendChild(content);
+			code.appendChild(line);
+		}
+		return code;
+	}
+
+	it('marks lines whose text matches the pattern', () => {
+		const code = wrappedCode(['run()', '# TODO: retry', 'done()', '# FIXME later']);
+
+		markMatchingLines(code, /TODO|FIXME/);
+
+		const lines = Array.from(code.children);
+		expect(lines.map(line => line.classList.contains('ucf-line-highlight'))).toEqual([false, true, false, true]);
+	});
+
+	it('handles global patterns on every line', () => {
+		const code = wrappedCode(['TODO one', 'TODO two']);
+
+		markMatchingLines(code, /TODO/g);
+
+		expect(code.querySelectorAll('.ucf-line-highlight').length).toBe(2);
+	});
+});
+
+describe('markHighlightTerms', () => {
+	it('marks whole-word occurrences, each term in its own colour', () => {
+		const code = document.createElement('code');
+		for (const html of ['<span class="ucf-line-content">const <span class="token variable">userId</span> = ctx.userIds[0];</span>', '<span class="ucf-line-content">load(ctx, userId)</span>']) {
+			const line = document.createElement('span');
+			line.className = 'ucf-line';
+			line.innerHTML = html;
+			code.appendChild(line);
+		}
+
+		markHighlightTerms(code, ['userId', 'ctx']);
+
+		const terms = Array.from(code.querySelectorAll('.ucf-term'));
+		expect(terms.map(el => `${el.textContent ?? ''} ${el.classList[1]}`)).toEqual([
+			'userId ucf-term-1',
+			'ctx ucf-term-2',
+			'ctx ucf-term-2',
+			'userId ucf-term-1',
+		]);
+		expect(code.textContent).toBe('const userId = ctx.userIds[0];load(ctx, userId)');
+	});
+});
+
+describe('addFoldRegionToggles', () => {
+	function wrappedCode(lineCount: number): HTMLElement {
+		const code = document.createElement('code');
+		for (let i = 0; i < lineCount; i++) {
+			const line = document.createElement('span');
+			line.className = 'ucf-line';
+			const content = document.createElement('span');
+			content.className = 'ucf-line-content';
+			content.textContent = `line ${String(i + 1)}`;
+			line.appendChild(content);
+			code.appendChild(line);
+		}
+		return code;
+	}
+
+	function hiddenLines(code: HTMLElement): boolean[] {
+		return Array.from(code.children).map(line => line.classList.contains('ucf-fold-region-hidden'));
+	}
+
+	it('puts a toggle before the content of the start line', () => {
+		const code = wrappedCode(4);
+
+		addFoldRegionToggles(code, [{ name: 'setup', startLine: 2, endLine: 4 }], false);
+
+		const startLine = code.children[1] as HTMLElement;
+		const toggle = startLine.querySelector('.ucf-fold-region-toggle') as HTMLElement;
+		expect(toggle.nextElementSibling?.classList.contains('ucf-line-content')).toBe(true);
+		expect(toggle.getAttribute('aria-expanded')).toBe('true');
+		expect(toggle.getAttribute('aria-label')).toBe('Collapse region setup');
+		expect(hiddenLines(code)).toEqual([false, false, false, false]);
+	});
+
+	it('starts collapsed when asked, hiding the lines after the start marker', () => {
+		const code = wrappedCode(5);
+
+		addFoldRegionToggles(code, [{ name: '', startLine: 2, endLine: 4 }], true);
+
+		expect(hiddenLines(code)).toEqual([false, false, true, true, false]);
+		expect(code.children[1].classList.contains('ucf-fold-region-collapsed')).toBe(true);
+	});
+
+	it('toggles a region on click', () => {
+		const code = wrappedCode(4);
+		addFoldRegionToggles(code, [{ name: '', startLine: 1, endLine: 3 }], false);
+		const toggle = code.querySelector('.ucf-fold-region-toggle') as HTMLElement;
+
+		toggle.click();
+		expect(hiddenLines(code)).toEqual([false, true, true, false]);
+		expect(toggle.getAttribute('aria-label')).toBe('Expand region');
+
+		toggle.click();
+		expect(hiddenLines(code)).toEqual([false, false, false, false]);
+	});
+
+	it('keeps lines of an expanded inner region hidden while the outer one is collapsed', () => {
+		const code = wrappedCode(6);
+		addFoldRegionToggles(code, [
+			{ name: 'outer', startLine: 1, endLine: 6 },
+			{ name: 'inner', startLine: 2, endLine: 4 },
+		], true);
+		const [outerToggle, innerToggle] = Array.from(code.querySelectorAll<HTMLElement>('.ucf-fold-region-toggle'));
+
+		innerToggle.click();
+		expect(hiddenLines(code)).toEqual([false, true, true, true, true, true]);
+
+		outerToggle.click();
+		expect(hiddenLines(code)).toEqual([false, false, false, false, false, false]);
+	});
+
+	it('skips regions that run past the rendered lines', () => {
+		const code = wrappedCode(2);
+
+		addFoldRegionToggles(code, [{ name: '', startLine: 1, endLine: 5 }], true);
+
+		expect(code.querySelector('.ucf-fold-region-toggle')).toBeNull();
+		expect(hiddenLines(code)).toEqual([false, false]);
+	});
+});
+
+describe('addColumnRulers', () => {
+	it('adds a ruler per column to every line without changing the text', () => {
+		const pre = document.createElement('pre');
+		const code = document.createElement('code');
+		for (const text of ['short', 'a much longer line']) {
+			const line = document.createElement('span');
+			line.className = 'ucf-line';
+			const content = document.createElement('span');
+			content.className = 'ucf-line-content';
+			content.textContent = text;
+			line.appendChild(content);
+			code.appendChild(line);
+		}
+		pre.appendChild(code);
+
+		addColumnRulers(pre, code, { columns: [80, 120], colour: 'red' });
+
+		expect(pre.classList.contains('ucf-rulers')).toBe(true);
+		expect(pre.style.getPropertyValue('--ucf-ruler-colour')).toBe('red');
+		const columns = Array.from(code.querySelectorAll<HTMLElement>('.ucf-line-content'))
+			.map(content => Array.from(content.querySelectorAll<HTMLElement>('.ucf-ruler'))
+				.map(ruler => ruler.style.getPropertyValue('--ucf-ruler-column')));
+		expect(columns).toEqual([['80', '120'], ['80', '120']]);
+		expect(code.textContent).toBe('shorta much longer line');
+	});
+});
+
+describe('addPrintLineNumbers', () => {
+	it('numbers each line from the starting number, ahead of its content', () => {
+		const code = document.createElement('code');
+		for (const text of ['a', 'b']) {
+			const line = document.createElement('span');
+			line.className = 'ucf-line';
+			const content = document.createElement('span');
+			content.className = 'ucf-line-content';
+			content.textContent = text;
+			line.appendChild(content);
+			code.appendChild(line);
+		}
+
+		addPrintLineNumbers(code, 41);
+
+		const numbers = Array.from(code.querySelectorAll('.ucf-line > :first-child'));
+		expect(numbers.map(number => number.textContent)).toEqual(['41', '42']);
+		expect(numbers.every(number => number.classList.contains('ucf-line-num') && number.classList.contains('ucf-print-line-num'))).toBe(true);
+	});
+});
+
+describe('markWrapIndents', () => {
+	it('records each line\'s indentation without changing its text', () => {
+		const code = document.createElement('code');
+		for (const text of ['if true; then', '\techo "a long line"', '      six', '']) {
+			const line = document.createElement('span');
+			line.className = 'ucf-line';
+			const content = document.createElement('span');
+			content.className = 'ucf-line-content';
+			content.textContent = text;
+			line.appendChild(content);
+			code.appendChild(line);
+		}
+
+		markWrapIndents(code, 2);
+
+		const indents = Array.from(code.querySelectorAll<HTMLElement>('.ucf-line-content'))
+			.map(content => content.style.getPropertyValue('--ucf-wrap-indent'));
+		expect(indents).toEqual(['', '2', '6', '']);
+		expect(code.textContent).toBe('if true; then\techo "a long line"      six');
+	});
+});
+
+describe('applyHighlightTheme', () => {
+	it('sets the theme\'s editor colours on the block', () => {
+		const pre = document.createElement('pre');
+
+		applyHighlightTheme(pre, { name: 'One Dark', background: '#282c34', foreground: '', tokens: {} });
+
+		expect(pre.classList.contains('ucf-highlight-theme')).toBe(true);
+		expect(pre.style.getPropertyValue('--code-background')).toBe('#282c34');
+		expect(pre.style.getPropertyValue('--code-normal')).toBe('');
+	});
+});
+
+describe('applyTokenStyles', () => {
+	it('sets only the properties each style names, later styles winning', () => {
+		const code = document.createElement('code');
+		code.innerHTML = '<span class="token keyword">SELECT</span> <span class="token comment">-- all</span>';
+
+		applyTokenStyles(code, [
+			{ token: 'keyword', colour: 'purple', background: '', fontWeight: 'bold', fontStyle: '', textDecoration: '', opacity: undefined },
+			{ token: 'keyword', colour: 'red', background: '', fontWeight: '', fontStyle: '', textDecoration: '', opacity: undefined },
+			{ token: 'comment', colour: '', background: '', fontWeight: '', fontStyle: 'italic', textDecoration: '', opacity: 0.6 },
+		]);
+
+		const keyword = code.querySelector<HTMLElement>('.keyword');
+		const comment = code.querySelector<HTMLElement>('.comment');
+		expect(keyword?.style.color).toBe('red');
+		expect(keyword?.style.fontWeight).toBe('bold');
+		expect(comment?.style.color).toBe('');
+		expect(comment?.style.fontStyle).toBe('italic');
+		expect(comment?.style.opacity).toBe('0.6');
+	});
+});
+
+describe('markPatternStyles', () => {
+	it('styles matching text across tokens and keeps the text', () => {
+		const code = document.createElement('code');
+		const line = document.createElement('span');
+		line.className = 'ucf-line';
+		const content = document.createElement('span');
+		content.className = 'ucf-line-content';
+		content.innerHTML = '<span class="token comment">// see PROJ-12 and PROJ-7</span> deploy(prod)';
+		line.appendChild(content);
+		code.appendChild(line);
+
+		const style = { token: '', colour: 'purple', background: '', fontWeight: 'bold' as const, fontStyle: '' as const, textDecoration: '' as const, opacity: undefined };
+		markPatternStyles(code, [
+			{ pattern: /PROJ-\d+/g, style },
+			{ pattern: /\bprod\b/, style: { ...style, colour: 'red', fontWeight: '' } },
+			{ pattern: /x*/g, style },
+		]);
+
+		const marks = Array.from(code.querySelectorAll<HTMLElement>('.ucf-pattern-style'));
+		expect(marks.map(el => el.textContent)).toEqual(['PROJ-12', 'PROJ-7', 'prod']);
+		expect(marks[0].style.color).toBe('purple');
+		expect(marks[0].style.fontWeight).toBe('bold');
+		expect(marks[2].style.color).toBe('red');
+		expect(marks[2].style.fontWeight).toBe('');
+		expect(code.textContent).toBe('// see PROJ-12 and PROJ-7 deploy(prod)');
+	});
+});
+
+describe('colourBracketPairs', () => {
+	function bracketBlock(): HTMLElement {
+		const code = document.createElement('code');
+		for (const html of ['<span class="ucf-line-num">(1)</span><span class="ucf-line-content">f({a: [1]}, <span class="token string">"(x"</span>)</span>', '<span class="ucf-line-content">)</span>']) {
+			const line = document.createElement('span');
+			line.className = 'ucf-line';
+			line.innerHTML = html;
+			code.appendChild(line);
+		}
+		return code;
+	}
+
+	it('colours pairs by depth, skipping strings, gutters and unmatched brackets', () => {
+		const code = bracketBlock();
+
+		colourBracketPairs(code);
+
+		const brackets = Array.from(code.querySelectorAll('.ucf-bracket'));
+		expect(brackets.map(el => `${el.textContent ?? ''}${el.className.replace('ucf-bracket ', ' ')}`)).toEqual([
+			'( ucf-bracket-depth-1',
+			'{ ucf-bracket-depth-2',
+			'[ ucf-bracket-depth-3',
+			'] ucf-bracket-depth-3',
+			'} ucf-bracket-depth-2',
+			') ucf-bracket-depth-1',
+			') ucf-bracket-unmatched',
+		]);
+		expect(code.textContent).toBe('(1)f({a: [1]}, "(x"))');
+	});
+
+	it('highlights a bracket\'s match while the pointer is over it', () => {
+		const code = bracketBlock();
+		colourBracketPairs(code);
+		const brackets = Array.from(code.querySelectorAll('.ucf-bracket'));
+
+		brackets[1].dispatchEvent(new MouseEvent('mouseover', { bubbles: true }));
+		expect(Array.from(code.querySelectorAll('.ucf-bracket-match'))).toEqual([brackets[1], brackets[4]]);
+
+		brackets[6].dispatchEvent(new MouseEvent('click', { bubbles: true }));
+		expect(code.querySelectorAll('.ucf-bracket-match')).toHaveLength(0);
+
+		brackets[0].dispatchEvent(new MouseEvent('click', { bubbles: true }));
+		code.dispatchEvent(new MouseEvent('mouseleave'));
+		expect(code.querySelectorAll('.ucf-bracket-match')).toHaveLength(0);
+	});
+});
+
+describe('applyTextDirection', () => {
+	it('gives every line its own direction and marks the block', () => {
+		const pre = document.createElement('pre');
+		const code = document.createElement('code');
+		for (const text of ['// שלום', 'print("hi")']) {
+			const line = document.createElement('span');
+			line.className = 'ucf-line';
+			const number = document.createElement('span');
+			number.className = 'ucf-line-num';
+			const content = document.createElement('span');
+			content.className = 'ucf-line-content';
+			content.textContent = text;
+			line.append(number, content);
+			code.appendChild(line);
+		}
+		pre.appendChild(code);
+
+		applyTextDirection(pre, code, 'auto');
+
+		expect(pre.classList.contains('ucf-bidi')).toBe(true);
+		expect(Array.from(code.querySelectorAll('.ucf-line-content')).map(content => content.getAttribute('dir'))).toEqual(['auto', 'auto']);
+		expect(code.querySelector('.ucf-line-num')?.hasAttribute('dir')).toBe(false);
+	});
+});
+
+describe('addIndentGuides', () => {
+	function wrappedCode(lines: string[]): HTMLElement {
+		const code = document.createElement('code');
+		for (const text of lines) {
+			const line = document.createElement('span');
+			line.className = 'ucf-line';
+			const content = document.createElement('span');
+			content.className = 'ucf-line-content';
+			content.textContent = text;
+			line.appendChild(content);
+			code.appendChild(line);
+		}
+		return code;
+	}
+
+	function guideColumns(code: HTMLElement): string[][] {
+		return Array.from(code.querySelectorAll('.ucf-line-content')).map(content =>
+			Array.from(content.querySelectorAll<HTMLElement>('.ucf-indent-guide'))
+				.map(guide => guide.style.getPropertyValue('--ucf-indent-column'))
+		);
+	}
+
+	it('adds a guide per indentation level without changing the text', () => {
+		const pre = document.createElement('pre');
+		const code = wrappedCode(['a:', '  b:', '    c: 1', 'd: 2']);
+		pre.appendChild(code);
+
+		addIndentGuides(pre, code, { colour: '', style: 'solid' });
+
+		expect(pre.classList.contains('ucf-indent-guides')).toBe(true);
+		expect(guideColumns(code)).toEqual([[], ['0'], ['0', '2'], []]);
+		expect(code.textContent).toBe('a:  b:    c: 1d: 2');
+	});
+
+	it('counts tabs at the given tab width', () => {
+		const pre = document.createElement('pre');
+		const code = wrappedCode(['a', '\tb', '\t\tc']);
+		pre.appendChild(code);
+
+		addIndentGuides(pre, code, { colour: '', style: 'solid' }, 8);
+
+		expect(guideColumns(code)).toEqual([[], ['0'], ['0', '8']]);
+	});
+
+	it('sets the colour and line style on the block', () => {
+		const pre = document.createElement('pre');
+		const code = wrappedCode(['x', '    y']);
+		pre.appendChild(code);
+
+		addIndentGuides(pre, code, { colour: 'red', style: 'dashed' });
+
+		expect(pre.style.getPropertyValue('--ucf-indent-guide-colour')).toBe('red');
+		expect(pre.style.getPropertyValue('--ucf-indent-guide-style')).toBe('dashed');
+	});
+
+	it('emphasises the hovered line\'s scope and clears it on leave', () => {
+		const pre = document.createElement('pre');
+		const code = wrappedCode(['def f():', '    a = 1', '    b = 2', 'g()']);
+		pre.appendChild(code);
+		addIndentGuides(pre, code, { colour: '', style: 'solid' });
+
+		code.querySelectorAll('.ucf-line-content')[1].dispatchEvent(new MouseEvent('mouseover', { bubbles: true }));
+		expect(code.querySelectorAll('.ucf-indent-guide-active')).toHaveLength(2);
+
+		code.dispatchEvent(new MouseEvent('mouseleave'));
+		expect(code.querySelectorAll('.ucf-indent-guide-active')).toHaveLength(0);
+	});
+});
+
+describe('markPlaceholders', () => {
+	it('wraps every placeholder on a line', () => {
+		const code = document.createElement('code');
+		const line = document.createElement('span');
+		line.className = 'ucf-line';
+		const content = document.createElement('span');
+		content.className = 'ucf-line-content';
+		content.textContent = 'ssh {{USER}}@{{HOSTNAME}}';
+		line.appendChild(content);
+		code.appendChild(line);
+
+		markPlaceholders(code);
+
+		const placeholders = Array.from(code.querySelectorAll('.ucf-placeholder')).map(el => el.textContent);
+		expect(placeholders).toEqual(['{{USER}}', '{{HOSTNAME}}']);
+		expect(code.textContent).toBe('ssh {{USER}}@{{HOSTNAME}}');
+	});
+});
+
+describe('markWhitespace', () => {
+	function wrappedLine(text: string): HTMLElement {
+		const code = document.createElement('code');
+		const line = document.createElement('span');
+		line.className = 'ucf-line';
+		const content = document.createElement('span');
+		content.className = 'ucf-line-content';
+		content.textContent = text;
+		line.appendChild(content);
+		code.appendChild(line);
+		return code;
+	}
+
+	it('marks spaces, tabs and trailing whitespace without changing the text', () => {
+		const code = wrappedLine('\tgo build  ');
+
+		markWhitespace(code, false);
+
+		expect(code.querySelectorAll('.ucf-ws-tab')).toHaveLength(1);
+		expect(Array.from(code.querySelectorAll('.ucf-ws-space')).map(el => el.textContent)).toEqual([' ', '  ']);
+		expect(code.querySelector('.ucf-ws-trailing')?.textContent).toBe('  ');
+		expect(code.textContent).toBe('\tgo build  ');
+	});
+
+	it('marks only trailing whitespace when asked', () => {
+		const code = wrappedLine('key: value ');
+
+		markWhitespace(code, true);
+
+		expect(Array.from(code.querySelectorAll('.ucf-ws-space')).map(el => el.textContent)).toEqual([' ']);
+		expect(code.querySelector('.ucf-ws-space')?.classList.contains('ucf-ws-trailing')).toBe(true);
+	});
+});
+
+describe('addLineAnnotations', () => {
+	function wrappedLines(count: number): HTMLElement {
+		const code = document.createElement('code');
+		for (let i = 0; i < count; i++) {
+			const line = document.createElement('span');
+			line.className = 'ucf-line';
+			const content = document.createElement('span');
+			content.className = 'ucf-line-content';
+			content.textContent = `line ${String(i + 1)}`;
+			line.appendChild(content);
+			code.appendChild(line);
+		}
+		return code;
+	}
+
+	it('adds a gutter cell to every line and an icon to annotated lines', () => {
+		const code = wrappedLines(3);
+
+		addLineAnnotations(code, [{ line: 2, type: 'warning', text: 'Deletes data' }]);
+
+		const lines = code.querySelectorAll('.ucf-line');
+		expect(code.querySelectorAll('.ucf-annotation-gutter')).toHaveLength(3);
+		expect(lines[0].firstElementChild?.classList.contains('ucf-annotation-gutter')).toBe(true);
+
+		const icon = lines[1].querySelector<HTMLElement>('.ucf-annotation');
+		expect(icon?.dataset.ucfAnnotation).toBe('warning');
+		expect(icon?.getAttribute('title')).toBe('Deletes data');
+		expect(icon?.querySelector('svg')).not.toBeNull();
+		expect(code.querySelectorAll('.ucf-annotation')).toHaveLength(1);
+		expect(code.textContent).toBe('line 1line 2line 3');
+	});
+
+	it('uses the type name as the tooltip when there is no text', () => {
+		const code = wrappedLines(1);
+
+		addLineAnnotations(code, [{ line: 1, type: 'info', text: '' }]);
+
+		expect(code.querySelector('.ucf-annotation')?.getAttribute('aria-label')).toBe('Info');
+	});
+
+	it('skips annotations past the rendered lines', () => {
+		const code = wrappedLines(2);
+
+		addLineAnnotations(code, [{ line: 5, type: 'bug', text: '' }]);
+
+		expect(code.querySelector('.ucf-annotation')).toBeNull();
+	});
+});
+
+describe('markCommentKeywords', () => {
+	const rules = [
+		{ keyword: 'TODO', type: 'todo', colour: '' },
+		{ keyword: 'FIXME', type: 'bug', colour: '#ff0000' },
+	];
+
+	/** Builds wrapped lines, each with one comment token. */
+	function commentLines(comments: string[]): HTMLElement {
+		const code = document.createElement('code');
+		for (const comment of comments) {
+			const line = document.createElement('span');
+			line.className = 'ucf-line';
+			const content = document.createElement('span');
+			content.className = 'ucf-line-content';
+			content.innerHTML = `x = "TODO"; <span class="token comment">${comment}</span>`;
+			line.appendChild(content);
+			code.appendChild(line);
+		}
+		return code;
+	}
+
+	it('wraps keywords in comments only, in their colour', () => {
+		const code = commentLines(['// TODO(alice): tidy, FIXME later', '// todo and TODOS']);
+
+		markCommentKeywords(code, rules);
+
+		const keywords = Array.from(code.querySelectorAll<HTMLElement>('.ucf-comment-keyword'));
+		expect(keywords.map(keyword => keyword.textContent)).toEqual(['TODO(alice):', 'FIXME']);
+		expect(keywords[0].dataset.ucfKeywordType).toBe('todo');
+		expect(keywords[0].style.getPropertyValue('--ucf-comment-keyword-colour')).toBe('rgb(68, 138, 255)');
+		expect(keywords[1].style.getPropertyValue('--ucf-comment-keyword-colour')).toBe('#ff0000');
+		expect(code.textContent).toBe('x = "TODO"; // TODO(alice): tidy, FIXME laterx = "TODO"; // todo and TODOS');
+	});
+
+	it('puts an icon in the gutter of lines with a keyword', () => {
+		const code = commentLines(['// FIXME: crash', '// fine']);
+		markCommentKeywords(code, rules);
+
+		addCommentKeywordIcons(code);
+
+		const gutters = Array.from(code.querySelectorAll('.ucf-annotation-gutter'));
+		expect(gutters).toHaveLength(2);
+		const icon = gutters[0].querySelector<HTMLElement>('.ucf-comment-keyword-icon');
+		expect(icon?.getAttribute('aria-label')).toBe('FIXME');
+		expect(icon?.querySelector('svg')).not.toBeNull();
+		expect(gutters[1].childElementCount).toBe(0);
+	});
+});
+
+describe('addBlameColumn', () => {
+	function wrappedLines(count: number): HTMLElement {
+		const code = document.createElement('code');
+		for (let i = 0; i < count; i++) {
+			const line = document.createElement('span');
+			line.className = 'ucf-line';
+			const content = document.createElement('span');
+			content.className = 'ucf-line-content';
+			content.textContent = `line ${String(i + 1)}`;
+			line.appendChild(content);
+			code.appendChild(line);
+		}
+		return code;
+	}
+
+	it('adds a cell to every line, first in the line', () => {
+		const code = wrappedLines(3);
+
+		addBlameColumn(code, [{ line: 2, author: 'alice', date: '', ticket: '' }]);
+
+		const lines = code.querySelectorAll('.ucf-line');
+		expect(code.querySelectorAll('.ucf-blame')).toHaveLength(3);
+		expect(lines[0].firstElementChild?.classList.contains('ucf-blame')).toBe(true);
+		expect(lines[1].querySelector('.ucf-blame-author')?.textContent).toBe('alice');
+		expect(lines[0].querySelector('.ucf-blame')?.textContent).toBe('');
+	});
+
+	it('shows a run of matching entries once, with the full entry as a tooltip', () => {
+		const code = wrappedLines(3);
+		const entry = { author: 'alice', date: '2024-03-01', ticket: 'INC-42' };
+
+		addBlameColumn(code, [{ line: 1, ...entry }, { line: 2, ...entry }, { line: 3, ...entry, author: 'bob' }]);
+
+		const cells = Array.from(code.querySelectorAll('.ucf-blame'));
+		expect(cells.map(cell => cell.classList.contains('ucf-blame-start'))).toEqual([true, false, true]);
+		expect(cells[0].getAttribute('title')).toBe('alice · 2024-03-01 · INC-42');
+		expect(cells[0].querySelector('.ucf-blame-ticket')?.textContent).toBe('INC-42');
+		expect(cells[1].textContent).toBe('');
+	});
+
+	it('sizes the column to its longest entry, up to a limit', () => {
+		const code = wrappedLines(2);
+
+		addBlameColumn(code, [{ line: 1, author: 'al', date: '', ticket: 'X-1' }, { line: 2, author: 'a'.repeat(80), date: '', ticket: '' }]);
+
+		expect(code.style.getPropertyValue('--ucf-blame-width')).toBe('36ch');
+	});
+});
+
+describe('markDiffLines', () => {
+	function wrappedBlock(lines: string[]): { pre: HTMLPreElement; code: HTMLElement } {
+		const pre = document.createElement('pre');
+		const code = document.createElement('code');
+		for (const text of lines) {
+			const line = document.createElement('span');
+			line.className = 'ucf-line';
+			const content = document.createElement('span');
+			content.className = 'ucf-line-content';
+			content.textContent = text;
+			line.appendChild(content);
+			code.appendChild(line);
+		}
+		pre.appendChild(code);
+		return { pre, code };
+	}
+
+	it('marks the lines of a unified diff and hides their markers', () => {
+		const { pre, code } = wrappedBlock(['@@ -1,2 +1,2 @@', ' keep', '-old', '+new']);
+
+		markDiffLines(pre, code, { unified: true, addedLines: [], removedLines: [] });
+
+		const lines = code.querySelectorAll('.ucf-line');
+		expect(pre.classList.contains('ucf-diff')).toBe(true);
+		expect(lines[0].classList.contains('ucf-diff-hunk')).toBe(true);
+		expect(lines[2].classList.contains('ucf-diff-removed')).toBe(true);
+		expect(lines[3].classList.contains('ucf-diff-added')).toBe(true);
+		expect(Array.from(code.querySelectorAll('.ucf-diff-marker')).map(el => el.textContent)).toEqual([' ', '-', '+']);
+		expect(code.querySelectorAll('.ucf-diff-gutter')).toHaveLength(4);
+		expect(code.textContent).toBe('@@ -1,2 +1,2 @@ keep-old+new');
+	});
+
+	it('marks listed lines in code that is not a unified diff', () => {
+		const { pre, code } = wrappedBlock(['port = 8080', 'port = 9090', 'host = "x"']);
+
+		markDiffLines(pre, code, { unified: false, addedLines: [2], removedLines: [1] });
+
+		const lines = code.querySelectorAll('.ucf-line');
+		expect(lines[0].classList.contains('ucf-diff-removed')).toBe(true);
+		expect(lines[1].classList.contains('ucf-diff-added')).toBe(true);
+		expect(lines[2].className).toBe('ucf-line');
+		expect(code.querySelector('.ucf-diff-marker')).toBeNull();
+	});
+
+	it('emphasises the changed words of paired lines', () => {
+		const { pre, code } = wrappedBlock(['-port: 8080', '+port: 9090', '+added: line']);
+
+		markDiffLines(pre, code, { unified: true, addedLines: [], removedLines: [], words: true });
+
+		expect(Array.from(code.querySelectorAll('.ucf-diff-word-removed')).map(el => el.textContent)).toEqual(['8080']);
+		expect(Array.from(code.querySelectorAll('.ucf-diff-word-added')).map(el => el.textContent)).toEqual(['9090']);
+		expect(code.textContent).toBe('-port: 8080+port: 9090+added: line');
+	});
+
+	it('leaves words alone unless asked', () => {
+		const { pre, code } = wrappedBlock(['-port: 8080', '+port: 9090']);
+
+		markDiffLines(pre, code, { unified: true, addedLines: [], removedLines: [] });
+
+		expect(code.querySelector('.ucf-diff-word-added')).toBeNull();
+	});
+});
+
+describe('markChangedWords', () => {
+	function content(text: string): HTMLElement {
+		const span = document.createElement('span');
+		span.className = 'ucf-line-content';
+		span.textContent = text;
+		return span;
+	}
+
+	it('wraps changed words across highlight spans', () => {
+		const removed = content('');
+		removed.innerHTML = '<span class="token keyword">const</span> total = sum(a);';
+		const added = content('const total = sum(a, b);');
+
+		markChangedWords(removed, added);
+
+		expect(removed.querySelector('.ucf-diff-word-removed')).toBeNull();
+		expect(Array.from(added.querySelectorAll('.ucf-diff-word-added')).map(el => el.textContent)).toEqual([', b']);
+		expect(removed.querySelector('.token.keyword')?.textContent).toBe('const');
+	});
+
+	it('marks nothing when the lines share no words', () => {
+		const removed = content('alpha');
+		const added = content('beta gamma');
+
+		markChangedWords(removed, added);
+
+		expect(removed.querySelector('.ucf-diff-word-removed')).toBeNull();
+		expect(added.querySelector('.ucf-diff-word-added')).toBeNull();
+	});
+});
+
+describe('markRedactions', () => {
+	it('wraps secret matches in masked spans and keeps the text', () => {
+		const code = document.createElement('code');
+		const line = document.createElement('span');
+		line.className = 'ucf-line';
+		const content = document.createElement('span');
+		content.className = 'ucf-line-content';
+		content.textContent = 'export TOKEN=abc123';
+		line.appendChild(content);
+		code.appendChild(line);
+
+		markRedactions(code, [/(?<=TOKEN=)\w+/g]);
+
+		const redacted = Array.from(code.querySelectorAll('.ucf-redacted'));
+		expect(redacted.map(el => el.textContent)).toEqual(['abc123']);
+		expect(redacted[0].getAttribute('title')).toBe('Redacted when copied');
+		expect(code.textContent).toBe('export TOKEN=abc123');
+	});
+});
+
 // Helper for afterEach in processCodeBlock describe block
 function afterEach(fn: () => void): void;
 function afterEach(name: string, fn: () => void): void;
@@ -495,6 +495,28 @@ describe('renderCommandOutput - Copy Button', () => {
 
 		expect(copyButton).not.toBeNull();
 	});
+
+	it('strips prompts from copied commands', async () => {
+		const writeText = vi.fn(() => Promise.resolve());
+		Object.assign(navigator, { clipboard: { writeText } });
+
+		const app = new App();
+		const component = new Component();
+		const options = {
+			styles: defaultStyles(),
+			showCopyButton: true,
+			scrollLines: 0,
+			promptPattern: /^(\$\s)(.*)/,
+		};
+
+		const container = await renderCommandOutput(app, '$ ls\nfile.txt', options, component);
+		const copyButton = container.querySelector(`.${CSS_CLASSES.copyButton}`) as HTMLButtonElement;
+		copyButton.click();
+
+		await new Promise(resolve => setTimeout(resolve, 0));
+
+		expect(writeText).toHaveBeenCalledWith('ls\nfile.txt');
+	});
 });
 
 // =============================================================================
@@ -150,6 +150,31 @@ describe('processAllOutputLines', () => {
 		const html = processAllOutputLines('a\n\nb', undefined, noStyles);
 		expect(html).toContain('&nbsp;');
 	});
+
+	it('leaves ANSI codes as text unless asked to render them', () => {
+		const html = processAllOutputLines('\x1b[31merror\x1b[0m', undefined, noStyles);
+		expect(html).toContain('\x1b[31m');
+		expect(html).not.toContain('ucf-cmdout-ansi');
+	});
+
+	it('renders ANSI colours in output lines and keeps the raw line', () => {
+		const html = processAllOutputLines('\x1b[1;31merror\x1b[0m: failed', undefined, noStyles, true);
+		expect(html).toContain('<span class="ucf-cmdout-ansi" style="color: var(--ucf-ansi-1); font-weight: bold">error</span>: failed');
+		expect(html).toContain('data-ucf-ansi-raw="\x1b[1;31merror\x1b[0m: failed"');
+	});
+
+	it('carries an ANSI style over to the next line', () => {
+		const html = processAllOutputLines('\x1b[32mone\ntwo\x1b[0m\nthree', undefined, noStyles, true);
+		expect(html.match(/color: var\(--ucf-ansi-2\)/g)).toHaveLength(2);
+		expect(html).toContain('>three</span>');
+	});
+
+	it('strips ANSI codes from command lines so the prompt still matches', () => {
+		const pattern = /^(\$\s)(.*)/;
+		const html = processAllOutputLines('$ \x1b[1mls\x1b[0m', pattern, noStyles, true);
+		expect(html).toContain('ucf-cmdout-cmdline');
+		expect(html).toContain('>ls</span>');
+	});
 });
 
 // =============================================================================
@@ -0,0 +1,57 @@
+// @vitest-environment jsdom
+
+/**
+ * Tests for src/renderers/embedded-languages.ts
+ *
+ * Covers: appendPrismTokens, highlightEmbeddedLanguages
+ */
+
+import { describe, it, expect } from 'vitest';
+import { appendPrismTokens, highlightEmbeddedLanguages } from '../../src/renderers/embedded-languages';
+import type { PrismTokenStream } from '../../src/services';
+
+/** A stand-in SQL tokenizer: SELECT and FROM are keywords. */
+function tokenizeSql(code: string, language: string): PrismTokenStream | null {
+	if (language !== 'sql') return null;
+	return code.split(/\b(SELECT|FROM)\b/).filter(part => part !== '')
+		.map(part => (part === 'SELECT' || part === 'FROM' ? { type: 'keyword', content: part } : part));
+}
+
+describe('appendPrismTokens', () => {
+	it('builds token spans with their aliases', () => {
+		const parent = document.createElement('code');
+
+		appendPrismTokens(parent, ['a ', { type: 'string', alias: ['template', 'x'], content: [{ type: 'punctuation', content: '`' }, 'b'] }]);
+
+		expect(parent.innerHTML).toBe('a <span class="token string template x"><span class="token punctuation">`</span>b</span>');
+	});
+});
+
+describe('highlightEmbeddedLanguages', () => {
+	function goCode(): HTMLElement {
+		const code = document.createElement('code');
+		code.innerHTML = 'db.Query(<span class="token string">`SELECT id\nFROM users`</span>, <span class="token string">"hello"</span>)';
+		return code;
+	}
+
+	it('highlights matching tokens inside their quotes', () => {
+		const code = goCode();
+
+		const count = highlightEmbeddedLanguages(code, [{ token: 'string', language: 'sql', match: /^\s*SELECT\b/i }], tokenizeSql);
+
+		expect(count).toBe(1);
+		const host = code.querySelector('.ucf-embedded-host');
+		expect(host?.textContent).toBe('`SELECT id\nFROM users`');
+		expect(host?.querySelector('.ucf-embedded-code.language-sql')?.querySelectorAll('.token.keyword')).toHaveLength(2);
+		expect(code.textContent).toBe('db.Query(`SELECT id\nFROM users`, "hello")');
+	});
+
+	it('leaves tokens alone without a grammar or with inner tokens', () => {
+		const code = document.createElement('code');
+		code.innerHTML = '<span class="token string">"SELECT 1"</span><span class="token string"><span class="token interpolation">${x}</span></span>';
+
+		expect(highlightEmbeddedLanguages(code, [{ token: 'string', language: 'graphql', match: null }], tokenizeSql)).toBe(0);
+		expect(highlightEmbeddedLanguages(code, [{ token: 'string', language: 'sql', match: null }], tokenizeSql)).toBe(1);
+		expect(code.querySelectorAll('.ucf-embedded-host')).toHaveLength(1);
+	});
+});
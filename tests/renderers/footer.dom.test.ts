@@ -0,0 +1,49 @@
+// @vitest-environment jsdom
+
+/**
+ * Tests for src/renderers/footer.ts
+ *
+ * Covers: createFooterElement
+ */
+
+import { describe, it, expect } from 'vitest';
+import { createFooterElement } from '../../src/renderers/footer';
+import { CSS_CLASSES } from '../../src/constants';
+
+describe('createFooterElement', () => {
+	it('links the source name to the URL and adds the licence', () => {
+		const footer = createFooterElement({ source: 'lodash', url: 'https://github.com/lodash/lodash', license: 'MIT' });
+
+		expect(footer?.classList.contains(CSS_CLASSES.footer)).toBe(true);
+		expect(footer?.textContent).toBe('Source: lodashLicense: MIT');
+
+		const link = footer?.querySelector('a');
+		expect(link?.textContent).toBe('lodash');
+		expect(link?.getAttribute('href')).toBe('https://github.com/lodash/lodash');
+		expect(link?.target).toBe('_blank');
+	});
+
+	it('uses the URL as link text when there is no source name', () => {
+		const footer = createFooterElement({ source: '', url: 'https://example.com/snippet', license: '' });
+
+		expect(footer?.querySelector('a')?.textContent).toBe('https://example.com/snippet');
+	});
+
+	it('does not link URLs that are not http or https', () => {
+		const footer = createFooterElement({ source: 'Tool', url: 'javascript:alert(1)', license: '' });
+
+		expect(footer?.querySelector('a')).toBeNull();
+		expect(footer?.textContent).toBe('Source: Tool');
+	});
+
+	it('shows only the licence when that is all there is', () => {
+		const footer = createFooterElement({ source: '', url: '', license: 'Apache-2.0' });
+
+		expect(footer?.children.length).toBe(1);
+		expect(footer?.textContent).toBe('License: Apache-2.0');
+	});
+
+	it('returns null when there is nothing to credit', () => {
+		expect(createFooterElement({ source: ' ', url: '', license: '' })).toBeNull();
+	});
+});
@@ -0,0 +1,128 @@
+// @vitest-environment jsdom
+
+/**
+ * Tests for src/renderers/line-links.ts
+ *
+ * Covers: addLineLinks, revealLines
+ */
+
+import { describe, it, expect, vi, afterEach } from 'vitest';
+import { addLineLinks, revealLines } from '../../src/renderers/line-links';
+import { CSS_CLASSES } from '../../src/constants';
+
+/** Builds a pre with wrapped, optionally numbered lines. */
+function wrappedBlock(count: number, numbered = true): { pre: HTMLPreElement; code: HTMLElement } {
+	const pre = document.createElement('pre');
+	const code = document.createElement('code');
+	for (let i = 0; i < count; i++) {
+		const line = document.createElement('span');
+		line.className = CSS_CLASSES.line;
+		if (numbered) {
+			const number = document.createElement('span');
+			number.className = CSS_CLASSES.lineNum;
+			number.textContent = String(i + 1);
+			line.appendChild(number);
+		}
+		const content = document.createElement('span');
+		content.className = CSS_CLASSES.lineContent;
+		content.textContent = `line ${String(i + 1)}`;
+		line.appendChild(content);
+		code.appendChild(line);
+	}
+	pre.appendChild(code);
+	return { pre, code };
+}
+
+/** Clicks the line number of a line (0-based). */
+function clickNumber(code: HTMLElement, index: number, shiftKey = false): void {
+	code.querySelectorAll(`.${CSS_CLASSES.lineNum}`)[index].dispatchEvent(new MouseEvent('click', { bubbles: true, shiftKey }));
+}
+
+describe('addLineLinks', () => {
+	it('tags the block and numbers its lines from the starting line', () => {
+		const { pre, code } = wrappedBlock(3);
+
+		addLineLinks(pre, code, 'setup', 10);
+
+		expect(pre.dataset.ucfBlockId).toBe('setup');
+		expect(Array.from(code.querySelectorAll<HTMLElement>(`.${CSS_CLASSES.line}`)).map(line => line.dataset.ucfLine))
+			.toEqual(['10', '11', '12']);
+		expect(pre.classList.contains(CSS_CLASSES.lineLinks)).toBe(false);
+	});
+
+	it('reports clicked line numbers, and ranges on shift-click', () => {
+		const { pre, code } = wrappedBlock(5);
+		const onLinkLines = vi.fn();
+
+		addLineLinks(pre, code, 'setup', 1, onLinkLines);
+		clickNumber(code, 3);
+		clickNumber(code, 1, true);
+
+		expect(pre.classList.contains(CSS_CLASSES.lineLinks)).toBe(true);
+		expect(onLinkLines.mock.calls).toEqual([[4, 4], [2, 4]]);
+	});
+
+	it('ignores clicks on the code itself', () => {
+		const { pre, code } = wrappedBlock(2);
+		const onLinkLines = vi.fn();
+
+		addLineLinks(pre, code, 'setup', 1, onLinkLines);
+		code.querySelector(`.${CSS_CLASSES.lineContent}`)?.dispatchEvent(new MouseEvent('click', { bubbles: true }));
+
+		expect(onLinkLines).not.toHaveBeenCalled();
+	});
+
+	it('does not offer links without line numbers', () => {
+		const { pre, code } = wrappedBlock(2, false);
+
+		addLineLinks(pre, code, 'setup', 1, vi.fn());
+
+		expect(pre.classList.contains(CSS_CLASSES.lineLinks)).toBe(false);
+	});
+});
+
+describe('revealLines', () => {
+	afterEach(() => {
+		vi.useRealTimers();
+	});
+
+	it('scrolls to the first line and flashes the range for a while', () => {
+		vi.useFakeTimers();
+		const { pre, code } = wrappedBlock(5);
+		addLineLinks(pre, code, 'setup', 1);
+		const lines = Array.from(code.querySelectorAll<HTMLElement>(`.${CSS_CLASSES.line}`));
+		const scrollIntoView = vi.fn();
+		lines[1].scrollIntoView = scrollIntoView;
+
+		expect(revealLines(pre, 2, 3)).toBe(true);
+
+		expect(scrollIntoView).toHaveBeenCalled();
+		expect(lines.map(line => line.classList.contains(CSS_CLASSES.lineFlash))).toEqual([false, true, true, false, false]);
+
+		vi.runAllTimers();
+		expect(code.querySelector(`.${CSS_CLASSES.lineFlash}`)).toBeNull();
+	});
+
+	it('opens a folded block first', () => {
+		const { pre, code } = wrappedBlock(2);
+		addLineLinks(pre, code, 'setup', 1);
+		pre.classList.add(CSS_CLASSES.folded);
+		const foldButton = document.createElement('button');
+		foldButton.className = CSS_CLASSES.foldButton;
+		const onFoldClick = vi.fn();
+		foldButton.addEventListener('click', onFoldClick);
+		pre.appendChild(foldButton);
+		code.querySelectorAll<HTMLElement>(`.${CSS_CLASSES.line}`)[0].scrollIntoView = vi.fn();
+
+		revealLines(pre, 1, 1);
+
+		expect(onFoldClick).toHaveBeenCalled();
+	});
+
+	it('returns false when the block has none of the lines', () => {
+		const { pre, code } = wrappedBlock(2);
+		addLineLinks(pre, code, 'setup', 1);
+
+		expect(revealLines(pre, 7, 9)).toBe(false);
+	});
+});
@@ -0,0 +1,36 @@
+// @vitest-environment jsdom
+
+/**
+ * Tests for src/renderers/load-more.ts
+ *
+ * Covers: createLoadMoreElement
+ */
+
+import { describe, it, expect, vi } from 'vitest';
+import { createLoadMoreElement } from '../../src/renderers/load-more';
+import { CSS_CLASSES } from '../../src/constants';
+
+describe('createLoadMoreElement', () => {
+	it('says how many lines are shown and how many the button loads', () => {
+		const element = createLoadMoreElement(2000, 20000, 500, () => undefined);
+
+		expect(element.classList.contains(CSS_CLASSES.loadMore)).toBe(true);
+		expect(element.querySelector('span')?.textContent).toBe('Showing 2000 of 20000 lines');
+		expect(element.querySelector(`.${CSS_CLASSES.loadMoreButton}`)?.textContent).toBe('Load next 500 lines');
+	});
+
+	it('offers only the lines that are left', () => {
+		const element = createLoadMoreElement(2000, 2120, 500, () => undefined);
+		expect(element.querySelector(`.${CSS_CLASSES.loadMoreButton}`)?.textContent).toBe('Load next 120 lines');
+	});
+
+	it('loads once and disables the button', () => {
+		const onLoadMore = vi.fn();
+		const element = createLoadMoreElement(2000, 20000, 500, onLoadMore);
+		const button = element.querySelector<HTMLButtonElement>(`.${CSS_CLASSES.loadMoreButton}`);
+
+		button?.click();
+		expect(onLoadMore).toHaveBeenCalledTimes(1);
+		expect(button?.disabled).toBe(true);
+	});
+});
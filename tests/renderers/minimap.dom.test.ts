@@ -0,0 +1,104 @@
+// @vitest-environment jsdom
+
+/**
+ * Tests for src/renderers/minimap.ts
+ *
+ * Covers: addMinimap, measureMinimapViewport
+ */
+
+import { describe, it, expect, afterEach, vi } from 'vitest';
+import { addMinimap, measureMinimapViewport } from '../../src/renderers/minimap';
+import { CSS_CLASSES } from '../../src/constants';
+
+/** Builds a pre with wrapped lines; highlighted lines are 1-based. */
+function wrappedBlock(lines: string[], highlighted: number[] = []): HTMLPreElement {
+	const pre = document.createElement('pre');
+	const code = document.createElement('code');
+	lines.forEach((text, index) => {
+		const line = document.createElement('span');
+		line.className = CSS_CLASSES.line;
+		if (highlighted.includes(index + 1)) line.classList.add(CSS_CLASSES.lineHighlight);
+		const content = document.createElement('span');
+		content.className = CSS_CLASSES.lineContent;
+		content.textContent = text;
+		line.appendChild(content);
+		code.appendChild(line);
+	});
+	pre.appendChild(code);
+	return pre;
+}
+
+/** Fakes the pre's scroll metrics, which jsdom doesn't lay out. */
+function fakeScrollBox(pre: HTMLElement, scrollHeight: number, clientHeight: number): void {
+	Object.defineProperty(pre, 'scrollHeight', { configurable: true, value: scrollHeight });
+	Object.defineProperty(pre, 'clientHeight', { configurable: true, value: clientHeight });
+	Object.defineProperty(pre, 'scrollTop', { configurable: true, value: 0, writable: true });
+}
+
+describe('addMinimap', () => {
+	afterEach(() => {
+		document.body.innerHTML = '';
+		vi.restoreAllMocks();
+	});
+
+	it('puts the minimap beside the pre, with a bar per line', () => {
+		const container = document.createElement('div');
+		const pre = wrappedBlock(['def f():', '    return 1', '', 'f()'], [2]);
+		container.appendChild(pre);
+		document.body.appendChild(container);
+
+		const minimap = addMinimap(pre);
+
+		const frame = container.firstElementChild;
+		expect(frame?.classList.contains(CSS_CLASSES.minimapFrame)).toBe(true);
+		expect(frame?.firstElementChild).toBe(pre);
+		expect(frame?.lastElementChild).toBe(minimap);
+
+		const bars = Array.from(minimap?.querySelectorAll<HTMLElement>(`.${CSS_CLASSES.minimapLine}`) ?? []);
+		expect(bars).toHaveLength(4);
+		expect(bars.map(bar => bar.classList.contains(CSS_CLASSES.minimapHighlight))).toEqual([false, true, false, false]);
+		expect(bars[0].style.getPropertyValue('--ucf-minimap-indent')).toBe('0%');
+		expect(parseFloat(bars[1].style.getPropertyValue('--ucf-minimap-indent'))).toBeGreaterThan(0);
+		expect(bars[2].style.getPropertyValue('--ucf-minimap-length')).toBe('0%');
+	});
+
+	it('returns null when the lines are not wrapped', () => {
+		const pre = document.createElement('pre');
+		pre.appendChild(document.createElement('code'));
+
+		expect(addMinimap(pre)).toBeNull();
+	});
+
+	it('scrolls a scrolling block to the clicked point', () => {
+		const container = document.createElement('div');
+		const pre = wrappedBlock(Array.from({ length: 100 }, (_, index) => `line ${String(index)}`));
+		container.appendChild(pre);
+		document.body.appendChild(container);
+		fakeScrollBox(pre, 2000, 400);
+
+		const minimap = addMinimap(pre) as HTMLElement;
+		vi.spyOn(minimap, 'getBoundingClientRect').mockReturnValue({ top: 0, height: 200 } as DOMRect);
+
+		minimap.dispatchEvent(new MouseEvent('click', { clientY: 100, bubbles: true }));
+
+		expect(pre.scrollTop).toBe(800);
+	});
+});
+
+describe('measureMinimapViewport', () => {
+	it('uses the scroll position of a scrolling block', () => {
+		const pre = document.createElement('pre');
+		fakeScrollBox(pre, 1000, 250);
+		pre.scrollTop = 500;
+
+		expect(measureMinimapViewport(pre)).toEqual({ top: 0.5, height: 0.25 });
+	});
+
+	it('uses the part of the block inside the window otherwise', () => {
+		const pre = document.createElement('pre');
+		fakeScrollBox(pre, 0, 0);
+		vi.spyOn(pre, 'getBoundingClientRect').mockReturnValue({ top: -window.innerHeight, height: window.innerHeight * 4 } as DOMRect);
+
+		expect(measureMinimapViewport(pre)).toEqual({ top: 0.25, height: 0.25 });
+	});
+});
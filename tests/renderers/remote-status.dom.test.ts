@@ -0,0 +1,47 @@
+// @vitest-environment jsdom
+
+/**
+ * Tests for src/renderers/remote-status.ts
+ *
+ * Covers: createRemoteStatusElement
+ */
+
+import { describe, it, expect, vi } from 'vitest';
+import { createRemoteStatusElement } from '../../src/renderers/remote-status';
+import { CSS_CLASSES } from '../../src/constants';
+
+const SCRIPT_URL = 'https://example.com/install.sh';
+const MINUTE = 60 * 1000;
+
+describe('createRemoteStatusElement', () => {
+	it('says how long ago the code was fetched', () => {
+		const status = createRemoteStatusElement({ url: SCRIPT_URL, fetchedAt: 0, offline: false }, 5 * MINUTE, async () => undefined);
+
+		expect(status.classList.contains(CSS_CLASSES.remoteStatus)).toBe(true);
+		expect(status.classList.contains(CSS_CLASSES.remoteStatusStale)).toBe(false);
+		expect(status.querySelector('span')?.textContent).toBe('Fetched 5 min ago');
+		expect(status.title).toBe(SCRIPT_URL);
+	});
+
+	it('marks an offline copy as stale', () => {
+		const status = createRemoteStatusElement({ url: SCRIPT_URL, fetchedAt: 0, offline: true }, 2 * 24 * 60 * MINUTE, async () => undefined);
+
+		expect(status.classList.contains(CSS_CLASSES.remoteStatusStale)).toBe(true);
+		expect(status.querySelector('span')?.textContent).toBe('Offline: showing the copy fetched 2 d ago');
+	});
+
+	it('disables the refresh button until the refresh settles', async () => {
+		let settle = (): void => undefined;
+		const onRefresh = vi.fn(() => new Promise<void>(resolve => { settle = resolve; }));
+		const status = createRemoteStatusElement({ url: SCRIPT_URL, fetchedAt: 0, offline: false }, 0, onRefresh);
+		const button = status.querySelector<HTMLButtonElement>(`.${CSS_CLASSES.remoteRefresh}`);
+
+		button?.click();
+		expect(onRefresh).toHaveBeenCalledTimes(1);
+		expect(button?.disabled).toBe(true);
+
+		settle();
+		await new Promise(resolve => setTimeout(resolve, 0));
+		expect(button?.disabled).toBe(false);
+	});
+});
@@ -0,0 +1,85 @@
+// @vitest-environment jsdom
+
+/**
+ * Tests for src/renderers/run-output.ts
+ *
+ * Covers: formatRunDuration, describeRunResult, createRunOutputPanel
+ */
+
+import { describe, it, expect, vi } from 'vitest';
+import { createRunOutputPanel, describeRunResult, formatRunDuration } from '../../src/renderers/run-output';
+import { CSS_CLASSES, RUN_OUTPUT_MAX_LENGTH } from '../../src/constants';
+
+describe('describeRunResult', () => {
+	it('describes success, failure and errors', () => {
+		expect(describeRunResult(0)).toBe('Exited with 0');
+		expect(describeRunResult(2)).toBe('Failed with exit code 2');
+		expect(describeRunResult(null)).toBe('Stopped');
+		expect(describeRunResult(null, 'no such shell')).toBe('Failed: no such shell');
+		expect(describeRunResult(null, undefined, { reason: 'timeout', afterMs: 30000 })).toBe('Killed after 30s');
+		expect(describeRunResult(null, undefined, { reason: 'cancelled', afterMs: 4200 })).toBe('Cancelled after 4s');
+	});
+});
+
+describe('formatRunDuration', () => {
+	it('uses seconds, then minutes and seconds', () => {
+		expect(formatRunDuration(30000)).toBe('30s');
+		expect(formatRunDuration(120000)).toBe('2m');
+		expect(formatRunDuration(90000)).toBe('1m 30s');
+	});
+});
+
+describe('createRunOutputPanel', () => {
+	it('shows output as it arrives, with stderr marked', () => {
+		const panel = createRunOutputPanel();
+		expect(panel.element.querySelector(`.${CSS_CLASSES.runOutputStatus} span`)?.textContent).toBe('Running…');
+
+		panel.append('hello\n', 'stdout');
+		panel.append('warning\n', 'stderr');
+
+		const text = panel.element.querySelector(`.${CSS_CLASSES.runOutputText}`);
+		expect(text?.textContent).toBe('hello\nwarning\n');
+		expect(text?.querySelector(`.${CSS_CLASSES.runOutputStderr}`)?.textContent).toBe('warning\n');
+	});
+
+	it('marks failed runs', () => {
+		const panel = createRunOutputPanel();
+		panel.finish(1);
+		expect(panel.element.classList.contains(CSS_CLASSES.runOutputFailed)).toBe(true);
+		expect(panel.element.querySelector(`.${CSS_CLASSES.runOutputStatus} span`)?.textContent).toBe('Failed with exit code 1');
+	});
+
+	it('stops adding output past the limit', () => {
+		const panel = createRunOutputPanel();
+		panel.append('x'.repeat(RUN_OUTPUT_MAX_LENGTH - 1), 'stdout');
+		panel.append('yy', 'stdout');
+		panel.append('z', 'stdout');
+
+		const text = panel.element.querySelector(`.${CSS_CLASSES.runOutputText}`)?.textContent ?? '';
+		expect(text.endsWith('y\n… output truncated')).toBe(true);
+		expect(text).not.toContain('z');
+	});
+
+	it('shows a stop button until the run finishes', () => {
+		const onStop = vi.fn();
+		const panel = createRunOutputPanel(undefined, onStop);
+		panel.element.querySelector<HTMLButtonElement>(`.${CSS_CLASSES.runOutputStop}`)?.click();
+		expect(onStop).toHaveBeenCalledTimes(1);
+
+		panel.finish(null, undefined, { reason: 'cancelled', afterMs: 1000 });
+		expect(panel.element.querySelector(`.${CSS_CLASSES.runOutputStop}`)).toBeNull();
+		expect(panel.element.classList.contains(CSS_CLASSES.runOutputFailed)).toBe(true);
+		expect(panel.element.querySelector(`.${CSS_CLASSES.runOutputStatus} span`)?.textContent).toBe('Cancelled after 1s');
+	});
+
+	it('removes itself when cleared', () => {
+		const onClear = vi.fn();
+		const container = document.createElement('div');
+		const panel = createRunOutputPanel(onClear);
+		container.appendChild(panel.element);
+
+		panel.element.querySelector<HTMLButtonElement>(`.${CSS_CLASSES.runOutputClear}`)?.click();
+		expect(container.children).toHaveLength(0);
+		expect(onClear).toHaveBeenCalledTimes(1);
+	});
+});
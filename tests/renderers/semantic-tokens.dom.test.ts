@@ -0,0 +1,41 @@
+// @vitest-environment jsdom
+
+/**
+ * Tests for src/renderers/semantic-tokens.ts
+ *
+ * Covers: addSemanticTokens
+ */
+
+import { describe, it, expect } from 'vitest';
+import { addSemanticTokens } from '../../src/renderers/semantic-tokens';
+
+describe('addSemanticTokens', () => {
+	it('wraps definitions and parameters inside the highlighted tokens', () => {
+		const code = document.createElement('code');
+		code.innerHTML = '<span class="token keyword">def</span> <span class="token function">f</span>(x): <span class="token comment"># x.y</span>';
+
+		expect(addSemanticTokens(code, 'python')).toBe(2);
+		expect(code.innerHTML).toBe(
+			'<span class="token keyword">def</span> <span class="token function"><span class="token function-definition">f</span></span>'
+			+ '(<span class="token parameter">x</span>): <span class="token comment"># x.y</span>'
+		);
+		expect(code.classList.contains('ucf-semantic')).toBe(true);
+	});
+
+	it('ignores code inside strings', () => {
+		const code = document.createElement('code');
+		code.innerHTML = 'log(<span class="token string">"func f(a int) { a.b }"</span>)';
+
+		expect(addSemanticTokens(code, 'go')).toBe(0);
+		expect(code.textContent).toBe('log("func f(a int) { a.b }")');
+		expect(code.classList.contains('ucf-semantic')).toBe(false);
+	});
+
+	it('leaves other languages alone', () => {
+		const code = document.createElement('code');
+		code.textContent = 'fn f(a: i32) {}';
+
+		expect(addSemanticTokens(code, 'rust')).toBe(0);
+		expect(code.innerHTML).toBe('fn f(a: i32) {}');
+	});
+});
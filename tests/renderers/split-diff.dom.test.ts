@@ -0,0 +1,115 @@
+// @vitest-environment jsdom
+
+/**
+ * Tests for src/renderers/split-diff.ts
+ *
+ * Covers: addSplitDiffView
+ */
+
+import { describe, it, expect } from 'vitest';
+import { addSplitDiffView } from '../../src/renderers/split-diff';
+import { markDiffLines } from '../../src/renderers/code-block';
+import { CSS_CLASSES } from '../../src/constants';
+
+/** Builds a pre with wrapped lines. */
+function wrappedBlock(lines: string[]): { pre: HTMLPreElement; code: HTMLElement } {
+	const pre = document.createElement('pre');
+	const code = document.createElement('code');
+	for (const text of lines) {
+		const line = document.createElement('span');
+		line.className = CSS_CLASSES.line;
+		const content = document.createElement('span');
+		content.className = CSS_CLASSES.lineContent;
+		content.textContent = text;
+		line.appendChild(content);
+		code.appendChild(line);
+	}
+	pre.appendChild(code);
+	return { pre, code };
+}
+
+/** Text of each line in one column ('' for gaps). */
+function columnTexts(side: Element): string[] {
+	return Array.from(side.children).map(line =>
+		line.classList.contains(CSS_CLASSES.splitDiffFiller)
+			? ''
+			: line.querySelector(`.${CSS_CLASSES.lineContent}`)?.textContent ?? ''
+	);
+}
+
+describe('addSplitDiffView', () => {
+	it('splits a unified diff into old and new columns', () => {
+		const { pre, code } = wrappedBlock(['--- a/app', '+++ b/app', '@@ -1,3 +1,3 @@', ' keep', '-old', '-gone', '+new']);
+		markDiffLines(pre, code, { unified: true, addedLines: [], removedLines: [] });
+
+		const view = addSplitDiffView(pre, code);
+
+		expect(view?.parentElement).toBe(pre);
+		expect(pre.classList.contains(CSS_CLASSES.splitDiffActive)).toBe(true);
+
+		const [oldSide, newSide] = Array.from(view?.querySelectorAll(`.${CSS_CLASSES.splitDiffSide}`) ?? []);
+		expect(columnTexts(oldSide)).toEqual(['@@ -1,3 +1,3 @@', ' keep', '-old', '-gone']);
+		expect(columnTexts(newSide)).toEqual(['@@ -1,3 +1,3 @@', ' keep', '+new', '']);
+		expect(newSide.children[3].getAttribute('aria-hidden')).toBe('true');
+	});
+
+	it('leaves the original code in place for copying', () => {
+		const { pre, code } = wrappedBlock(['-old', '+new']);
+		markDiffLines(pre, code, { unified: true, addedLines: [], removedLines: [] });
+
+		addSplitDiffView(pre, code);
+
+		expect(code.parentElement).toBe(pre);
+		expect(code.querySelectorAll(`.${CSS_CLASSES.line}`)).toHaveLength(2);
+	});
+
+	it('compares two named regions', () => {
+		const { pre, code } = wrappedBlock([
+			'# region: before', 'port: 8080', 'host: localhost', '# endregion',
+			'# region: after', 'port: 9090', 'host: localhost', '# endregion',
+		]);
+		markDiffLines(pre, code, { unified: false, addedLines: [], removedLines: [] });
+
+		const view = addSplitDiffView(pre, code, { oldRegion: 'before', newRegion: 'after' });
+
+		const [oldSide, newSide] = Array.from(view?.querySelectorAll(`.${CSS_CLASSES.splitDiffSide}`) ?? []);
+		expect(columnTexts(oldSide)).toEqual(['port: 8080', 'host: localhost']);
+		expect(columnTexts(newSide)).toEqual(['port: 9090', 'host: localhost']);
+		expect(oldSide.children[0].classList.contains(CSS_CLASSES.diffRemoved)).toBe(true);
+		expect(newSide.children[0].classList.contains(CSS_CLASSES.diffAdded)).toBe(true);
+		expect(newSide.children[1].classList.contains(CSS_CLASSES.diffAdded)).toBe(false);
+	});
+
+	it('emphasises changed words between the regions when asked', () => {
+		const { pre, code } = wrappedBlock(['# region: a', 'port: 8080', '# endregion', '# region: b', 'port: 9090', '# endregion']);
+
+		const view = addSplitDiffView(pre, code, { oldRegion: 'a', newRegion: 'b' }, true);
+
+		expect(view?.querySelector(`.${CSS_CLASSES.diffWordRemoved}`)?.textContent).toBe('8080');
+		expect(view?.querySelector(`.${CSS_CLASSES.diffWordAdded}`)?.textContent).toBe('9090');
+		expect(code.querySelector(`.${CSS_CLASSES.diffWordAdded}`)).toBeNull();
+	});
+
+	it('does nothing when a region is missing', () => {
+		const { pre, code } = wrappedBlock(['# region: before', 'a', '# endregion']);
+
+		expect(addSplitDiffView(pre, code, { oldRegion: 'before', newRegion: 'after' })).toBeNull();
+		expect(pre.classList.contains(CSS_CLASSES.splitDiffActive)).toBe(false);
+	});
+
+	it('syncs the columns\' horizontal scroll', () => {
+		const { pre, code } = wrappedBlock(['-old', '+new']);
+		markDiffLines(pre, code, { unified: true, addedLines: [], removedLines: [] });
+		const view = addSplitDiffView(pre, code);
+		const [oldSide, newSide] = Array.from(view?.querySelectorAll<HTMLElement>(`.${CSS_CLASSES.splitDiffSide}`) ?? []);
+
+		// jsdom doesn't lay out, so give the columns plain scroll positions
+		for (const side of [oldSide, newSide]) {
+			Object.defineProperty(side, 'scrollLeft', { configurable: true, value: 0, writable: true });
+		}
+		oldSide.scrollLeft = 40;
+		oldSide.dispatchEvent(new Event('scroll'));
+
+		expect(newSide.scrollLeft).toBe(40);
+	});
+});
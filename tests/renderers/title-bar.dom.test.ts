@@ -7,6 +7,7 @@
  * - createDescriptionElement: Renders markdown into a description div
  * - createTooltipDescriptionElement: Creates tooltip container with markdown content
  * - createTitleBarElement: Creates title element with style and optional link indicator
+ * - createHeaderIconElement: HEADER.ICON as an emoji or Lucide icon
  * - buildTitleContainer: Builds complete container with title and description
  */
 
@@ -16,6 +17,7 @@ import {
 	createDescriptionElement,
 	createTooltipDescriptionElement,
 	createTitleBarElement,
+	createHeaderIconElement,
 	buildTitleContainer,
 	type TitleBarCreationOptions,
 	type TitleContainerOptions,
@@ -350,6 +352,89 @@ describe('Title Bar DOM Renderer', () => {
 				expect(indicator?.textContent).toBe('↗');
 			});
 		});
+
+		describe('HEADER options', () => {
+			it('uses the header icon instead of the file icon', async () => {
+				const element = await createTitleBarElement(app, mockSettings, {
+					titleText: 'deploy.sh',
+					titleBarStyle: 'tab',
+					headerIcon: '🚀',
+				}, component);
+
+				expect(element.querySelector(`.${CSS_CLASSES.icon}`)?.textContent).toBe('🚀');
+			});
+
+			it('hides the title text when showTitleText is false', async () => {
+				const element = await createTitleBarElement(app, mockSettings, {
+					titleText: 'deploy.sh',
+					titleBarStyle: 'tab',
+					showTitleText: false,
+				}, component);
+
+				expect(element.querySelector(`.${CSS_CLASSES.titleText}`)?.textContent).toBe('');
+			});
+
+			it('adds a language badge when asked', async () => {
+				const element = await createTitleBarElement(app, mockSettings, {
+					titleText: 'deploy.sh',
+					titleBarStyle: 'tab',
+					language: 'bash',
+					showLanguageBadge: true,
+				}, component);
+
+				expect(element.querySelector(`.${CSS_CLASSES.languageBadge}`)?.textContent).toBe('bash');
+			});
+
+			it('adds no language badge by default', async () => {
+				const element = await createTitleBarElement(app, mockSettings, {
+					titleText: 'deploy.sh',
+					titleBarStyle: 'tab',
+					language: 'bash',
+				}, component);
+
+				expect(element.querySelector(`.${CSS_CLASSES.languageBadge}`)).toBeNull();
+			});
+
+			it('makes the badge of a detected language a button', async () => {
+				const onLanguageBadgeClick = vi.fn();
+				const element = await createTitleBarElement(app, mockSettings, {
+					titleText: 'snippet',
+					titleBarStyle: 'tab',
+					language: 'python',
+					showLanguageBadge: true,
+					onLanguageBadgeClick,
+				}, component);
+
+				const badge = element.querySelector<HTMLElement>(`.${CSS_CLASSES.languageBadge}`);
+				expect(badge?.classList.contains(CSS_CLASSES.languageBadgeDetected)).toBe(true);
+				expect(badge?.getAttribute('role')).toBe('button');
+
+				badge?.click();
+				expect(onLanguageBadgeClick).toHaveBeenCalledTimes(1);
+			});
+		});
+	});
+
+	// ==========================================================================
+	// createHeaderIconElement Tests
+	// ==========================================================================
+
+	describe('createHeaderIconElement', () => {
+		it('draws a lowercase name as a Lucide icon', () => {
+			const icon = createHeaderIconElement('terminal');
+
+			expect(icon?.classList.contains(CSS_CLASSES.iconLucide)).toBe(true);
+			expect(icon?.dataset.icon).toBe('terminal');
+		});
+
+		it('shows anything else as text', () => {
+			expect(createHeaderIconElement('⚙️')?.textContent).toBe('⚙️');
+		});
+
+		it('returns null for none or an empty value', () => {
+			expect(createHeaderIconElement('none')).toBeNull();
+			expect(createHeaderIconElement('  ')).toBeNull();
+		});
 	});
 
 	// ==========================================================================
@@ -0,0 +1,65 @@
+/**
+ * Tests for src/services/block-changes.ts
+ *
+ * Covers: diffUfenceBlocks
+ */
+
+import { describe, it, expect } from 'vitest';
+import { diffUfenceBlocks } from '../../src/services/block-changes';
+import { findUfenceBlocks } from '../../src/services/fence-lint';
+
+const note = (...lines: string[]): string => lines.join('\n');
+
+const before = note(
+	'```ufence-ufence',
+	'RENDER:',
+	'  ZEBRA: true',
+	'```',
+	'',
+	'```ufence-bash {ln}',
+	'make deploy',
+	'```',
+	'',
+	'```ufence-python',
+	'print("hi")',
+	'```',
+);
+
+describe('diffUfenceBlocks', () => {
+	it('reports nothing when the blocks are unchanged', () => {
+		const blocks = findUfenceBlocks(before);
+		expect(diffUfenceBlocks(blocks, findUfenceBlocks(before))).toEqual({
+			pageConfigChanged: false,
+			fenceLineChanged: [],
+		});
+	});
+
+	it('reports blocks whose fence line changed', () => {
+		const after = before.replace('```ufence-bash {ln}', '```ufence-bash {ln zebra}');
+		const changes = diffUfenceBlocks(findUfenceBlocks(before), findUfenceBlocks(after));
+
+		expect(changes.pageConfigChanged).toBe(false);
+		expect(changes.fenceLineChanged.map(block => block.fenceLine)).toEqual(['```ufence-bash {ln zebra}']);
+	});
+
+	it('leaves blocks whose content changed to Obsidian', () => {
+		const after = before.replace('make deploy', 'make test');
+		const changes = diffUfenceBlocks(findUfenceBlocks(before), findUfenceBlocks(after));
+
+		expect(changes.fenceLineChanged).toEqual([]);
+	});
+
+	it('reports a changed ufence-ufence block', () => {
+		const after = before.replace('ZEBRA: true', 'ZEBRA: false');
+		const changes = diffUfenceBlocks(findUfenceBlocks(before), findUfenceBlocks(after));
+
+		expect(changes.pageConfigChanged).toBe(true);
+	});
+
+	it('reports an added or removed ufence-ufence block', () => {
+		const withoutPageConfig = before.split('\n').slice(5).join('\n');
+
+		expect(diffUfenceBlocks(findUfenceBlocks(before), findUfenceBlocks(withoutPageConfig)).pageConfigChanged).toBe(true);
+		expect(diffUfenceBlocks(findUfenceBlocks(withoutPageConfig), findUfenceBlocks(before)).pageConfigChanged).toBe(true);
+	});
+});
@@ -0,0 +1,92 @@
+/**
+ * Tests for src/services/block-references.ts
+ *
+ * Covers: readReferenceId, mergeReferencedSettings, findReferenceableBlocks, BlockReferenceIndex
+ */
+
+import { describe, it, expect } from 'vitest';
+import {
+	readReferenceId,
+	mergeReferencedSettings,
+	findReferenceableBlocks,
+	BlockReferenceIndex,
+} from '../../src/services/block-references';
+
+const RUNBOOK = [
+	'# Bootstrap',
+	'```ufence-bash',
+	'META:',
+	'  ID: install-steps',
+	'  TITLE: Install',
+	'~~~',
+	'apt update',
+	'```',
+	'',
+	'```ufence-python',
+	'print("hi")',
+	'```',
+	'^greeting',
+	'',
+	'```ufence-bash',
+	'META:',
+	'  REF: install-steps',
+	'```',
+].join('\n');
+
+describe('readReferenceId', () => {
+	it('reads META.REF', () => {
+		expect(readReferenceId({ META: { REF: ' install-steps ' } })).toBe('install-steps');
+		expect(readReferenceId({ META: { TITLE: 'x' } })).toBe('');
+		expect(readReferenceId({})).toBe('');
+	});
+});
+
+describe('mergeReferencedSettings', () => {
+	it('lays the local settings over the referenced ones', () => {
+		expect(mergeReferencedSettings(
+			{ META: { ID: 'install-steps', TITLE: 'Install' }, RENDER: { ZEBRA: true } },
+			{ META: { REF: 'install-steps', TITLE: 'Install on staging' }, RENDER: { LINES: true } },
+		)).toEqual({
+			META: { TITLE: 'Install on staging' },
+			RENDER: { ZEBRA: true, LINES: true },
+		});
+	});
+
+	it('keeps an ID the referring block gives itself', () => {
+		expect(mergeReferencedSettings({ META: { ID: 'a' } }, { META: { REF: 'a', ID: 'b' } })).toEqual({ META: { ID: 'b' } });
+	});
+});
+
+describe('findReferenceableBlocks', () => {
+	it('finds blocks by META.ID and by the block ID line after them', () => {
+		const found = findReferenceableBlocks(RUNBOOK);
+		expect(found.map(entry => `${entry.id}:${entry.block.blockType}`)).toEqual(['install-steps:bash', 'greeting:python']);
+	});
+
+	it('skips blocks that refer to another', () => {
+		expect(findReferenceableBlocks('```ufence-bash\nMETA:\n  ID: copy\n  REF: install-steps\n```')).toEqual([]);
+	});
+});
+
+describe('BlockReferenceIndex', () => {
+	it('finds a block by ID, taking the first note by path when IDs clash', () => {
+		const index = new BlockReferenceIndex();
+		index.update('Runbooks/B.md', RUNBOOK);
+		index.update('Runbooks/A.md', '```ufence-sh\nMETA:\n  ID: install-steps\n~~~\nbrew update\n```');
+
+		expect(index.find('install-steps')?.path).toBe('Runbooks/A.md');
+		expect(index.find('greeting')?.block.content).toBe('print("hi")');
+		expect(index.find('missing')).toBeNull();
+	});
+
+	it('forgets removed notes and blocks whose ID is gone', () => {
+		const index = new BlockReferenceIndex();
+		index.update('Runbook.md', RUNBOOK);
+		index.update('Runbook.md', '```ufence-bash\necho\n```');
+		expect(index.find('install-steps')).toBeNull();
+
+		index.update('Runbook.md', RUNBOOK);
+		index.remove('Runbook.md');
+		expect(index.find('greeting')).toBeNull();
+	});
+});
@@ -0,0 +1,79 @@
+/**
+ * Tests for src/services/clipboard-history.ts
+ *
+ * Covers: ClipboardHistory (record, de-duplication, size cap, clear)
+ */
+
+import { describe, it, expect } from 'vitest';
+import { ClipboardHistory } from '../../src/services/clipboard-history';
+
+describe('ClipboardHistory', () => {
+	it('lists copies newest first with their source', () => {
+		const history = new ClipboardHistory(5);
+		history.record('apt update', 'Setup');
+		history.record('make deploy', 'Deploy');
+
+		const entries = history.getEntries();
+		expect(entries.map(entry => entry.text)).toEqual(['make deploy', 'apt update']);
+		expect(entries[0].source).toBe('Deploy');
+		expect(typeof entries[0].copiedAt).toBe('number');
+	});
+
+	it('moves a repeated copy to the top instead of duplicating it', () => {
+		const history = new ClipboardHistory(5);
+		history.record('a', 'X');
+		history.record('b', 'X');
+		history.record('a', 'Y');
+
+		expect(history.getEntries().map(entry => `${entry.text}:${entry.source}`)).toEqual(['a:Y', 'b:X']);
+	});
+
+	it('keeps at most the configured number of entries', () => {
+		const history = new ClipboardHistory(2);
+		history.record('1', '');
+		history.record('2', '');
+		history.record('3', '');
+
+		expect(history.getEntries().map(entry => entry.text)).toEqual(['3', '2']);
+	});
+
+	it('drops the oldest entries when the size is reduced', () => {
+		const history = new ClipboardHistory(3);
+		history.record('1', '');
+		history.record('2', '');
+		history.record('3', '');
+		history.setMaxEntries(1);
+
+		expect(history.getEntries().map(entry => entry.text)).toEqual(['3']);
+	});
+
+	it('records nothing when the size is 0', () => {
+		const history = new ClipboardHistory(0);
+		history.record('ls', 'X');
+
+		expect(history.getEntries()).toEqual([]);
+	});
+
+	it('ignores blank copies', () => {
+		const history = new ClipboardHistory(5);
+		history.record('  \n', 'X');
+
+		expect(history.getEntries()).toEqual([]);
+	});
+
+	it('returns a copy of the entries', () => {
+		const history = new ClipboardHistory(5);
+		history.record('ls', 'X');
+		history.getEntries().pop();
+
+		expect(history.getEntries()).toHaveLength(1);
+	});
+
+	it('forgets everything on clear', () => {
+		const history = new ClipboardHistory(5);
+		history.record('ls', 'X');
+		history.clear();
+
+		expect(history.getEntries()).toEqual([]);
+	});
+});
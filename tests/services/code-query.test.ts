@@ -0,0 +1,139 @@
+/**
+ * Tests for src/services/code-query.ts
+ *
+ * Covers: isDataviewQuery, parseCodeQuery, findFencedCode, noteMatchesQuery,
+ *         matchCodeQuery, formatCodeQueryMatches, formatComment, dataviewValueText
+ */
+
+import { describe, it, expect } from 'vitest';
+import {
+	isDataviewQuery,
+	parseCodeQuery,
+	findFencedCode,
+	noteMatchesQuery,
+	matchCodeQuery,
+	formatCodeQueryMatches,
+	formatComment,
+	dataviewValueText,
+} from '../../src/services/code-query';
+import type { QueryNote } from '../../src/services/code-query';
+
+const DEPLOY_NOTE: QueryNote = {
+	path: 'Ops/Deploy.md',
+	tags: ['#deploy/prod'],
+	content: [
+		'# Deploy',
+		'```bash',
+		'kubectl apply -f app.yaml',
+		'```',
+		'```python',
+		'print("hi")',
+		'```',
+		'```ufence-bash',
+		'TITLE: Rollback',
+		'~~~',
+		'kubectl rollout undo deploy/app',
+		'```',
+	].join('\n'),
+};
+
+const OTHER_NOTE: QueryNote = {
+	path: 'Notes/Scratch.md',
+	tags: [],
+	content: '```bash\necho scratch\n```',
+};
+
+describe('isDataviewQuery', () => {
+	it('recognises Dataview query types', () => {
+		expect(isDataviewQuery('LIST FROM #deploy')).toBe(true);
+		expect(isDataviewQuery('  table file.mtime')).toBe(true);
+		expect(isDataviewQuery('tag:#deploy lang:bash')).toBe(false);
+		expect(isDataviewQuery('listing')).toBe(false);
+	});
+});
+
+describe('parseCodeQuery', () => {
+	it('reads tags, folders, languages and text', () => {
+		expect(parseCodeQuery('tag:#Deploy #ops path:/Runbooks/ lang:BASH "kubectl apply" rollout')).toEqual({
+			tags: ['#deploy', '#ops'],
+			folders: ['Runbooks'],
+			languages: ['bash'],
+			terms: ['kubectl apply', 'rollout'],
+		});
+	});
+
+	it('returns an empty search for an empty query', () => {
+		expect(parseCodeQuery('  ')).toEqual({ tags: [], folders: [], languages: [], terms: [] });
+	});
+});
+
+describe('findFencedCode', () => {
+	it('finds plain fences and ufence inline code, with their languages', () => {
+		expect(findFencedCode(DEPLOY_NOTE.content)).toEqual([
+			{ line: 2, language: 'bash', code: 'kubectl apply -f app.yaml' },
+			{ line: 5, language: 'python', code: 'print("hi")' },
+			{ line: 8, language: 'bash', code: 'kubectl rollout undo deploy/app' },
+		]);
+	});
+
+	it('skips page settings, cmdout and saved output blocks', () => {
+		expect(findFencedCode('```ufence-ufence\nPRESET: x\n```\n```ufence-cmdout\n$ ls\n```\n```ufence-output\nExited with 0\n```')).toEqual([]);
+	});
+});
+
+describe('noteMatchesQuery', () => {
+	it('matches nested tags and folders', () => {
+		expect(noteMatchesQuery(parseCodeQuery('#deploy'), DEPLOY_NOTE)).toBe(true);
+		expect(noteMatchesQuery(parseCodeQuery('path:Ops'), DEPLOY_NOTE)).toBe(true);
+		expect(noteMatchesQuery(parseCodeQuery('#dep'), DEPLOY_NOTE)).toBe(false);
+		expect(noteMatchesQuery(parseCodeQuery('path:Op'), DEPLOY_NOTE)).toBe(false);
+	});
+});
+
+describe('matchCodeQuery', () => {
+	it('collects matching blocks from matching notes, in path order', () => {
+		const matches = matchCodeQuery(parseCodeQuery('lang:bash'), [DEPLOY_NOTE, OTHER_NOTE]);
+		expect(matches.map(match => `${match.path}:${String(match.line)}`)).toEqual([
+			'Notes/Scratch.md:1',
+			'Ops/Deploy.md:2',
+			'Ops/Deploy.md:8',
+		]);
+	});
+
+	it('requires every text term', () => {
+		const matches = matchCodeQuery(parseCodeQuery('#deploy kubectl undo'), [DEPLOY_NOTE, OTHER_NOTE]);
+		expect(matches.map(match => match.code)).toEqual(['kubectl rollout undo deploy/app']);
+	});
+});
+
+describe('formatCodeQueryMatches', () => {
+	const matches = matchCodeQuery(parseCodeQuery('tag:deploy lang:bash'), [DEPLOY_NOTE]);
+
+	it('heads each block with a comment naming its note', () => {
+		expect(formatCodeQueryMatches(matches, { line: ['#'] })).toBe(
+			'# From Ops/Deploy\nkubectl apply -f app.yaml\n\n# From Ops/Deploy\nkubectl rollout undo deploy/app'
+		);
+	});
+
+	it('leaves the headings out without comment syntax', () => {
+		expect(formatCodeQueryMatches(matches, undefined)).toBe('kubectl apply -f app.yaml\n\nkubectl rollout undo deploy/app');
+	});
+});
+
+describe('formatComment', () => {
+	it('uses a line comment, else a block comment', () => {
+		expect(formatComment('note', { line: ['//'], block: ['/*', '*/'] })).toBe('// note');
+		expect(formatComment('note', { line: [], block: ['<!--', '-->'] })).toBe('<!-- note -->');
+		expect(formatComment('note', undefined)).toBeNull();
+	});
+});
+
+describe('dataviewValueText', () => {
+	it('writes links, list items and lists as text', () => {
+		expect(dataviewValueText({ path: 'Ops/Deploy.md' })).toBe('Ops/Deploy');
+		expect(dataviewValueText({ path: 'Ops/Deploy.md', display: 'Deploy' })).toBe('Deploy');
+		expect(dataviewValueText({ text: 'kubectl get pods' })).toBe('kubectl get pods');
+		expect(dataviewValueText(['a', 2, null])).toBe('a, 2, ');
+		expect(dataviewValueText(undefined)).toBe('');
+	});
+});
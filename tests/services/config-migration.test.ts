@@ -0,0 +1,77 @@
+/**
+ * Tests for src/services/config-migration.ts
+ *
+ * Covers: migrateNoteContent, planNoteMigration, getUndoContent
+ */
+
+import { describe, it, expect } from 'vitest';
+import { migrateNoteContent, planNoteMigration, getUndoContent } from '../../src/services/config-migration';
+
+const note = (...lines: string[]): string => lines.join('\n');
+
+describe('migrateNoteContent', () => {
+	it('rewrites old keys in ufence blocks and reports each line', () => {
+		const content = note(
+			'# Scripts',
+			'```ufence-bash',
+			'DISPLAY:',
+			'  LINES: true',
+			'~~~',
+			'echo hi',
+			'```',
+		);
+		const result = migrateNoteContent(content);
+		expect(result.content).toBe(content.replace('DISPLAY:', 'RENDER:'));
+		expect(result.changes).toEqual([{ line: 3, before: 'DISPLAY:', after: 'RENDER:' }]);
+	});
+
+	it('leaves other code blocks and the code after ~~~ alone', () => {
+		const content = note(
+			'```yaml',
+			'DISPLAY: x',
+			'```',
+			'```ufence-bash',
+			'META:',
+			'  TITLE: Demo',
+			'~~~',
+			'DISPLAY: y',
+			'```',
+		);
+		expect(migrateNoteContent(content)).toEqual({ content, changes: [] });
+	});
+
+	it('skips blocks with TOML settings', () => {
+		const content = note(
+			'```ufence-bash toml',
+			'[DISPLAY]',
+			'LINES = true',
+			'```',
+		);
+		expect(migrateNoteContent(content).changes).toEqual([]);
+	});
+});
+
+describe('planNoteMigration', () => {
+	it('returns null when nothing changes', () => {
+		expect(planNoteMigration('a.md', 'No blocks here')).toBeNull();
+	});
+
+	it('keeps the original and migrated content', () => {
+		const content = note('```ufence-cmdout', 'STYLE:', '  COPY: false', '```');
+		const plan = planNoteMigration('a.md', content);
+		expect(plan?.original).toBe(content);
+		expect(plan?.migrated).toBe(note('```ufence-cmdout', 'RENDER:', '  COPY: false', '```'));
+	});
+});
+
+describe('getUndoContent', () => {
+	const plan = { path: 'a.md', original: 'old', migrated: 'new', changes: [] };
+
+	it('restores a note that is unchanged since the migration', () => {
+		expect(getUndoContent(plan, 'new')).toBe('old');
+	});
+
+	it('leaves a note that was edited since', () => {
+		expect(getUndoContent(plan, 'new and edited')).toBeNull();
+	});
+});
@@ -0,0 +1,140 @@
+/**
+ * Tests for src/services/contributions.ts
+ *
+ * Covers: ContributionRegistry (grammars, themes, copy formats, annotations, runners, disposers)
+ */
+
+import { describe, it, expect, vi } from 'vitest';
+import { ContributionRegistry, PLUGIN_API_VERSION } from '../../src/services/contributions';
+import { isKnownCopyAsFormat } from '../../src/utils/copy-transforms';
+import type { HighlightTheme } from '../../src/types';
+import type { RunRequest } from '../../src/services/contributions';
+
+const BLOCK = { code: 'a\nb\nc', language: 'go', notePath: 'Runbook.md' };
+
+const RUN_BLOCK = { code: 'print(1)', language: 'python', notePath: 'Runbook.md', env: { REGION: 'eu' } };
+
+const THEME: HighlightTheme = { name: 'Night Owl', background: '#011627', foreground: '#d6deeb', tokens: {} };
+
+describe('ContributionRegistry', () => {
+	it('exposes the API version', () => {
+		expect(new ContributionRegistry(() => undefined).createApi().version).toBe(PLUGIN_API_VERSION);
+	});
+
+	it('lists grammars by lower-case language until they are disposed', () => {
+		const onChange = vi.fn();
+		const registry = new ContributionRegistry(onChange);
+		const grammar = { keyword: /\bmy\b/ };
+
+		const dispose = registry.createApi().registerGrammar(' MyDSL ', grammar);
+		expect(registry.grammars()).toEqual([{ language: 'mydsl', path: '', grammar }]);
+
+		dispose();
+		expect(registry.grammars()).toEqual([]);
+		expect(onChange).toHaveBeenCalledTimes(2);
+	});
+
+	it('keys themes by name, and a stale disposer leaves a newer theme alone', () => {
+		const registry = new ContributionRegistry(() => undefined);
+		const api = registry.createApi();
+
+		const disposeFirst = api.registerTheme(THEME);
+		const newer = { ...THEME, background: '#000' };
+		api.registerTheme(newer);
+		disposeFirst();
+
+		expect(registry.themes()['night owl']).toBe(newer);
+	});
+
+	it('adds and removes copy formats', () => {
+		const api = new ContributionRegistry(() => undefined).createApi();
+
+		const dispose = api.registerCopyTransform('shouty', codeText => codeText.toUpperCase(), 'Shouty');
+		expect(isKnownCopyAsFormat('shouty')).toBe(true);
+
+		dispose();
+		expect(isKnownCopyAsFormat('shouty')).toBe(false);
+	});
+
+	it('adds annotations on lines the block leaves free', () => {
+		const registry = new ContributionRegistry(() => undefined);
+		const provider = vi.fn(() => [
+			{ line: 1, type: 'warning', text: 'ignored' },
+			{ line: 3, type: 'Error', text: ' nil check ' },
+			{ line: 2 },
+			{ line: 0 },
+		]);
+		registry.createApi().registerAnnotationProvider(provider);
+
+		const annotations = registry.annotate([{ line: 1, type: 'note', text: 'mine' }], BLOCK);
+
+		expect(provider).toHaveBeenCalledWith(BLOCK);
+		expect(annotations).toEqual([
+			{ line: 1, type: 'note', text: 'mine' },
+			{ line: 2, type: 'note', text: '' },
+			{ line: 3, type: 'danger', text: 'nil check' },
+		]);
+	});
+
+	it('skips a provider that throws', () => {
+		const registry = new ContributionRegistry(() => undefined);
+		const api = registry.createApi();
+		const errors = vi.spyOn(console, 'error').mockImplementation(() => undefined);
+		api.registerAnnotationProvider(() => {
+			throw new Error('broken');
+		});
+		const dispose = api.registerAnnotationProvider(() => [{ line: 2, text: 'ok' }]);
+
+		expect(registry.annotate([], BLOCK)).toEqual([{ line: 2, type: 'note', text: 'ok' }]);
+		expect(errors).toHaveBeenCalled();
+
+		dispose();
+		expect(registry.annotate([], BLOCK)).toEqual([]);
+		errors.mockRestore();
+	});
+
+	it('runs blocks of a runner\'s languages with it until it is disposed', async () => {
+		const registry = new ContributionRegistry(() => undefined);
+		const runner = vi.fn(({ code, onOutput }: RunRequest) => {
+			onOutput(`ran ${code}\n`, 'stdout');
+			return Promise.resolve({ exitCode: 0 });
+		});
+		const dispose = registry.createApi().registerRunner(['Python', 'py'], runner);
+		const output: string[] = [];
+
+		expect(registry.hasRunner('python')).toBe(true);
+		const result = await registry.run({ ...RUN_BLOCK, language: 'py' }, { onOutput: (text) => { output.push(text); } });
+
+		expect(result).toEqual({ exitCode: 0 });
+		expect(output).toEqual(['ran print(1)\n']);
+		expect(runner.mock.calls[0][0]).toMatchObject({ ...RUN_BLOCK, language: 'py' });
+
+		dispose();
+		expect(registry.hasRunner('py')).toBe(false);
+	});
+
+	it('fails a run whose runner throws', async () => {
+		const registry = new ContributionRegistry(() => undefined);
+		registry.createApi().registerRunner(['python'], () => { throw new Error('no kernel'); });
+
+		expect(await registry.run(RUN_BLOCK, { onOutput: () => undefined }))
+			.toEqual({ exitCode: null, errorMessage: 'no kernel' });
+	});
+
+	it('stops a run that goes past its time limit, aborting the runner and dropping later output', async () => {
+		const registry = new ContributionRegistry(() => undefined);
+		const late = { onOutput: (_text: string) => undefined, aborted: false };
+		registry.createApi().registerRunner(['python'], ({ onOutput, signal }) => new Promise(() => {
+			late.onOutput = (text) => { onOutput(text, 'stdout'); };
+			signal.addEventListener('abort', () => { late.aborted = true; });
+		}));
+		const output: string[] = [];
+
+		const result = await registry.run(RUN_BLOCK, { onOutput: (text) => { output.push(text); }, timeoutMs: 20 });
+		late.onOutput('too late');
+
+		expect(result).toEqual({ exitCode: null, stopped: { reason: 'timeout', afterMs: 20 } });
+		expect(late.aborted).toBe(true);
+		expect(output).toEqual([]);
+	});
+});
@@ -0,0 +1,89 @@
+/**
+ * Tests for src/services/copy-usage.ts
+ *
+ * Covers: buildCopyUsageKey, findCopyUsageKeys, copyUsageNotePath, pruneCopyUsage, moveCopyUsage, getCopyCount, incrementCopyCount
+ */
+
+import { describe, it, expect } from 'vitest';
+import { buildCopyUsageKey, findCopyUsageKeys, copyUsageNotePath, pruneCopyUsage, moveCopyUsage, getCopyCount, incrementCopyCount } from '../../src/services/copy-usage';
+
+describe('buildCopyUsageKey', () => {
+	it('combines the note path with a hash of the code', () => {
+		expect(buildCopyUsageKey('Runbooks/Deploy.md', 'make deploy')).toMatch(/^Runbooks\/Deploy\.md#[0-9a-f]{8}$/);
+	});
+
+	it('is stable for the same block', () => {
+		expect(buildCopyUsageKey('a.md', 'ls')).toBe(buildCopyUsageKey('a.md', 'ls'));
+	});
+
+	it('differs when the code or the note differs', () => {
+		expect(buildCopyUsageKey('a.md', 'ls')).not.toBe(buildCopyUsageKey('a.md', 'ls -la'));
+		expect(buildCopyUsageKey('a.md', 'ls')).not.toBe(buildCopyUsageKey('b.md', 'ls'));
+	});
+});
+
+describe('getCopyCount / incrementCopyCount', () => {
+	it('starts at zero for an unknown block', () => {
+		expect(getCopyCount({}, 'a.md#00000000')).toBe(0);
+	});
+
+	it('counts copies in place', () => {
+		const counts: Record<string, number> = {};
+		expect(incrementCopyCount(counts, 'k')).toBe(1);
+		expect(incrementCopyCount(counts, 'k')).toBe(2);
+		expect(counts).toEqual({ k: 2 });
+		expect(getCopyCount(counts, 'k')).toBe(2);
+	});
+});
+
+describe('findCopyUsageKeys', () => {
+	it('keys every fenced block by the text between its fences', () => {
+		const note = '# Deploy\n```bash\nmake deploy\n```\n\n~~~~ufence-sh\nTITLE: x\n~~~\nls\n~~~~';
+		expect(findCopyUsageKeys('a.md', note)).toEqual(new Set([
+			buildCopyUsageKey('a.md', 'make deploy'),
+			buildCopyUsageKey('a.md', 'TITLE: x\n~~~\nls'),
+		]));
+	});
+
+	it('strips the quote markers of blocks inside callouts and blockquotes', () => {
+		const note = '> [!note] Deploy\n> ```bash\n> make deploy\n>\n>   > log.txt\n> ```\n\n> > ```sh\n> > ls\n> > ```';
+		expect(findCopyUsageKeys('a.md', note)).toEqual(new Set([
+			buildCopyUsageKey('a.md', 'make deploy\n\n  > log.txt'),
+			buildCopyUsageKey('a.md', 'ls'),
+		]));
+	});
+
+	it('ends a quoted block with its quote', () => {
+		const note = '> ```bash\n> make deploy\n\n```\nls\n```';
+		expect(findCopyUsageKeys('a.md', note)).toEqual(new Set([
+			buildCopyUsageKey('a.md', 'make deploy'),
+			buildCopyUsageKey('a.md', 'ls'),
+		]));
+	});
+});
+
+describe('pruneCopyUsage / moveCopyUsage', () => {
+	const live = buildCopyUsageKey('a.md', 'ls');
+	const edited = buildCopyUsageKey('a.md', 'ls -l');
+	const other = buildCopyUsageKey('b.md', 'pwd');
+
+	it('drops a note\'s records for blocks it no longer has', () => {
+		const counts: Record<string, number> = { [live]: 3, [edited]: 1, [other]: 2 };
+		expect(pruneCopyUsage(counts, 'a.md', new Set([live]))).toBe(true);
+		expect(counts).toEqual({ [live]: 3, [other]: 2 });
+		expect(pruneCopyUsage(counts, 'a.md', new Set([live]))).toBe(false);
+	});
+
+	it('drops all of a deleted note\'s records', () => {
+		const counts: Record<string, number> = { [live]: 3, [other]: 2 };
+		pruneCopyUsage(counts, 'a.md', null);
+		expect(counts).toEqual({ [other]: 2 });
+	});
+
+	it('moves a renamed note\'s records to its new path', () => {
+		const counts: Record<string, number> = { [live]: 3, [other]: 2 };
+		expect(moveCopyUsage(counts, 'a.md', 'Runbooks/a.md')).toBe(true);
+		expect(counts).toEqual({ [buildCopyUsageKey('Runbooks/a.md', 'ls')]: 3, [other]: 2 });
+		expect(copyUsageNotePath(Object.keys(counts)[1])).toBe('Runbooks/a.md');
+	});
+});
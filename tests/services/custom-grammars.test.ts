@@ -0,0 +1,248 @@
+/**
+ * Tests for src/services/custom-grammars.ts
+ *
+ * Covers: grammarLanguageFromPath, parseGrammarFile, convertPrismGrammar,
+ * convertTextMateGrammar, textMateScopeToToken, loadCustomGrammars,
+ * registerCustomGrammars, registerLanguageAliases, isInGrammarFolder
+ */
+
+import { describe, it, expect } from 'vitest';
+import { App, TFile } from 'obsidian';
+import {
+	grammarLanguageFromPath,
+	parseGrammarFile,
+	convertPrismGrammar,
+	convertTextMateGrammar,
+	textMateScopeToToken,
+	loadCustomGrammars,
+	registerCustomGrammars,
+	registerLanguageAliases,
+	isInGrammarFolder,
+	type PrismTokenRule,
+} from '../../src/services/custom-grammars';
+
+describe('grammarLanguageFromPath', () => {
+	it('names the language after the file', () => {
+		expect(grammarLanguageFromPath('Assets/Grammars/MyDSL.json')).toBe('mydsl');
+		expect(grammarLanguageFromPath('Grammars/hcl.tmLanguage.json')).toBe('hcl');
+	});
+
+	it('ignores files that are not grammars', () => {
+		expect(grammarLanguageFromPath('Grammars/readme.md')).toBeNull();
+		expect(grammarLanguageFromPath('Grammars/my dsl.json')).toBeNull();
+	});
+});
+
+describe('convertPrismGrammar', () => {
+	it('turns pattern strings into regexes', () => {
+		const grammar = convertPrismGrammar({
+			comment: '#.*',
+			keyword: { pattern: '\\b(?:deploy|rollback)\\b', flags: 'i', alias: 'important' },
+			string: [{ pattern: '"[^"]*"', greedy: true }, "'[^']*'"],
+		});
+
+		expect(grammar.comment).toEqual(/#.*/);
+		const keyword = grammar.keyword as PrismTokenRule;
+		expect(keyword.pattern.test('DEPLOY')).toBe(true);
+		expect(keyword.alias).toBe('important');
+		expect(grammar.string).toEqual([{ pattern: /"[^"]*"/, greedy: true }, /'[^']*'/]);
+	});
+
+	it('converts nested inside grammars', () => {
+		const grammar = convertPrismGrammar({
+			string: { pattern: '"[^"]*"', inside: { variable: '\\$\\w+' } },
+		});
+		expect((grammar.string as PrismTokenRule).inside).toEqual({ variable: /\$\w+/ });
+	});
+
+	it('names the token with an invalid pattern', () => {
+		expect(() => convertPrismGrammar({ keyword: '(' })).toThrow('Token "keyword" has an invalid pattern');
+		expect(() => convertPrismGrammar({ keyword: { alias: 'x' } })).toThrow('Token "keyword" needs a pattern');
+	});
+});
+
+describe('textMateScopeToToken', () => {
+	it('maps scope names to Prism tokens, most specific first', () => {
+		expect(textMateScopeToToken('comment.line.number-sign.mydsl')).toBe('comment');
+		expect(textMateScopeToToken('keyword.operator.assignment')).toBe('operator');
+		expect(textMateScopeToToken('keyword.control.mydsl')).toBe('keyword');
+		expect(textMateScopeToToken('constant.numeric.integer')).toBe('number');
+		expect(textMateScopeToToken('entity.name.function meta.call')).toBe('function');
+	});
+
+	it('returns null for scopes without an equivalent', () => {
+		expect(textMateScopeToToken('meta.block.mydsl')).toBeNull();
+		expect(textMateScopeToToken('keywords')).toBeNull();
+	});
+});
+
+describe('convertTextMateGrammar', () => {
+	const textMate = {
+		scopeName: 'source.mydsl',
+		patterns: [
+			{ include: '#comments' },
+			{ name: 'keyword.control.mydsl', match: '(?i)\\b(?:deploy|rollback)\\b' },
+			{ name: 'string.quoted.double.mydsl', begin: '"', end: '"' },
+			{ name: 'meta.unmapped', match: 'x' },
+			{ name: 'constant.numeric.mydsl', match: '(?<=a)\\p{Nd}+(?' },
+		],
+		repository: {
+			comments: { patterns: [{ name: 'comment.line.mydsl', match: '//.*' }] },
+		},
+	};
+
+	it('converts match and begin/end rules through includes', () => {
+		const grammar = convertTextMateGrammar(textMate);
+
+		expect(Object.keys(grammar)).toEqual(['comment', 'keyword', 'string']);
+		expect((grammar.comment as PrismTokenRule).pattern).toEqual(/\/\/.*/);
+		expect((grammar.keyword as PrismTokenRule).pattern.test('Deploy')).toBe(true);
+
+		const string = grammar.string as PrismTokenRule;
+		expect(string.greedy).toBe(true);
+		expect(string.pattern.exec('say "hi" now')?.[0]).toBe('"hi"');
+	});
+
+	it('follows each repository entry once', () => {
+		const grammar = convertTextMateGrammar({
+			scopeName: 'source.loop',
+			patterns: [{ include: '#a' }],
+			repository: {
+				a: { patterns: [{ name: 'keyword', match: 'a' }, { include: '#a' }] },
+			},
+		});
+		expect(grammar.keyword).toEqual({ pattern: /a/ });
+	});
+});
+
+describe('parseGrammarFile', () => {
+	it('detects TextMate grammars by their scope name', () => {
+		const grammar = parseGrammarFile(JSON.stringify({
+			scopeName: 'source.x',
+			patterns: [{ name: 'comment.line', match: ';.*' }],
+		}));
+		expect(grammar).toEqual({ comment: { pattern: /;.*/ } });
+	});
+
+	it('reads anything else as a Prism grammar', () => {
+		expect(parseGrammarFile('{"keyword": "\\\\bgo\\\\b"}')).toEqual({ keyword: /\bgo\b/ });
+	});
+
+	it('rejects files it cannot use', () => {
+		expect(() => parseGrammarFile('not json')).toThrow();
+		expect(() => parseGrammarFile('[]')).toThrow('Grammar must be a JSON object');
+		expect(() => parseGrammarFile('{}')).toThrow('Grammar has no usable rules');
+	});
+});
+
+describe('isInGrammarFolder', () => {
+	it('matches files in the folder and below it', () => {
+		expect(isInGrammarFolder('Grammars/a.json', 'Grammars')).toBe(true);
+		expect(isInGrammarFolder('Grammars/dsl/a.json', 'Grammars')).toBe(true);
+		expect(isInGrammarFolder('GrammarsOld/a.json', 'Grammars')).toBe(false);
+	});
+});
+
+describe('loadCustomGrammars', () => {
+	function createVaultApp(files: Record<string, string>): App {
+		const app = new App();
+		Object.assign(app.vault, {
+			getFiles: () => Object.keys(files).map(path => new TFile(path)),
+			cachedRead: async (file: TFile) => files[file.path],
+		});
+		return app;
+	}
+
+	it('loads the grammars in the folder and reports bad files', async () => {
+		const app = createVaultApp({
+			'Grammars/zeta.json': '{"keyword": "zeta"}',
+			'Grammars/sub/alpha.json': '{"keyword": "alpha"}',
+			'Grammars/broken.json': '{',
+			'Grammars/notes.md': '# notes',
+			'Other/beta.json': '{"keyword": "beta"}',
+		});
+
+		const result = await loadCustomGrammars(app, '/Grammars/');
+
+		expect(result.grammars.map(grammar => grammar.language)).toEqual(['alpha', 'zeta']);
+		expect(result.errors.map(error => error.path)).toEqual(['Grammars/broken.json']);
+	});
+
+	it('keeps the first file when two claim a language', async () => {
+		const app = createVaultApp({
+			'Grammars/b/dsl.json': '{"keyword": "second"}',
+			'Grammars/a/dsl.tmLanguage.json': JSON.stringify({ scopeName: 'source.dsl', patterns: [{ name: 'keyword', match: 'first' }] }),
+		});
+
+		const result = await loadCustomGrammars(app, 'Grammars');
+
+		expect(result.grammars).toHaveLength(1);
+		expect(result.grammars[0].path).toBe('Grammars/a/dsl.tmLanguage.json');
+	});
+
+	it('loads nothing without a folder', async () => {
+		expect(await loadCustomGrammars(createVaultApp({ 'a.json': '{"k": "a"}' }), ' ')).toEqual({ grammars: [], errors: [] });
+	});
+});
+
+describe('registerCustomGrammars', () => {
+	it('adds new languages but leaves Prism\'s own alone', () => {
+		const builtIn = { keyword: /if/ };
+		const prism = { languages: { python: builtIn } as Record<string, unknown> };
+
+		const registered = registerCustomGrammars(prism, [
+			{ language: 'mydsl', path: 'G/mydsl.json', grammar: { keyword: /deploy/ } },
+			{ language: 'python', path: 'G/python.json', grammar: { keyword: /x/ } },
+		]);
+
+		expect([...registered]).toEqual(['mydsl']);
+		expect(prism.languages.mydsl).toEqual({ keyword: /deploy/ });
+		expect(prism.languages.python).toBe(builtIn);
+	});
+
+	it('replaces and removes languages from the previous load', () => {
+		const prism = { languages: { old: { keyword: /a/ }, mydsl: { keyword: /a/ } } as Record<string, unknown> };
+
+		const registered = registerCustomGrammars(prism, [
+			{ language: 'mydsl', path: 'G/mydsl.json', grammar: { keyword: /b/ } },
+		], new Set(['old', 'mydsl']));
+
+		expect([...registered]).toEqual(['mydsl']);
+		expect(prism.languages).toEqual({ mydsl: { keyword: /b/ } });
+	});
+});
+
+describe('registerLanguageAliases', () => {
+	const bash = { keyword: /if/ };
+	const json = { string: /"[^"]*"/ };
+
+	it('points aliases at their target\'s grammar', () => {
+		const prism = { languages: { bash, json } as Record<string, unknown> };
+
+		const registered = registerLanguageAliases(prism, { zsh: 'bash', zshrc: 'zsh', jsonc: 'json' });
+
+		expect([...registered].sort()).toEqual(['jsonc', 'zsh', 'zshrc']);
+		expect(prism.languages.zsh).toBe(bash);
+		expect(prism.languages.zshrc).toBe(bash);
+		expect(prism.languages.jsonc).toBe(json);
+	});
+
+	it('skips aliases without a grammar or that Prism already knows', () => {
+		const prism = { languages: { bash, json } as Record<string, unknown> };
+
+		const registered = registerLanguageAliases(prism, { tf: 'hcl', json: 'bash' });
+
+		expect(registered.size).toBe(0);
+		expect(prism.languages).toEqual({ bash, json });
+	});
+
+	it('replaces the aliases from the previous call', () => {
+		const prism = { languages: { bash, json } as Record<string, unknown> };
+		const first = registerLanguageAliases(prism, { zsh: 'bash', jsonc: 'json' });
+
+		const second = registerLanguageAliases(prism, { zsh: 'json' }, first);
+
+		expect([...second]).toEqual(['zsh']);
+		expect(prism.languages).toEqual({ bash, json, zsh: json });
+	});
+});
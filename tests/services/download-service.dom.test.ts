@@ -9,7 +9,7 @@
  */
 
 import { describe, it, expect, vi, beforeEach, afterEach } from 'vitest';
-import { downloadCodeToFile } from '../../src/services/download-service';
+import { downloadCodeToFile, saveExecutableFile } from '../../src/services/download-service';
 
 describe('downloadCodeToFile (jsdom)', () => {
 	let mockCreateObjectURL: ReturnType<typeof vi.fn>;
@@ -229,3 +229,45 @@ describe('downloadCodeToFile (jsdom)', () => {
 		expect(callSequence).toEqual(['appendChild', 'removeChild']);
 	});
 });
+
+describe('saveExecutableFile (jsdom)', () => {
+	afterEach(() => {
+		delete (window as unknown as { require?: unknown }).require;
+		vi.restoreAllMocks();
+	});
+
+	it('writes the file and sets mode 755 via the desktop save dialog', async () => {
+		const showSaveDialog = vi.fn(() => Promise.resolve({ canceled: false, filePath: '/home/me/install.sh' }));
+		const writeFile = vi.fn(() => Promise.resolve());
+		const chmod = vi.fn(() => Promise.resolve());
+		(window as unknown as { require: (id: string) => unknown }).require = (id: string) =>
+			id === 'electron' ? { remote: { dialog: { showSaveDialog } } } : { promises: { writeFile, chmod } };
+
+		const savedPath = await saveExecutableFile('echo hi', 'install.sh', '/home/me/');
+
+		expect(showSaveDialog).toHaveBeenCalledWith({ defaultPath: '/home/me/install.sh' });
+		expect(writeFile).toHaveBeenCalledWith('/home/me/install.sh', 'echo hi');
+		expect(chmod).toHaveBeenCalledWith('/home/me/install.sh', 0o755);
+		expect(savedPath).toBe('/home/me/install.sh');
+	});
+
+	it('does not write anything when the dialog is cancelled', async () => {
+		const writeFile = vi.fn(() => Promise.resolve());
+		(window as unknown as { require: (id: string) => unknown }).require = (id: string) =>
+			id === 'electron'
+				? { remote: { dialog: { showSaveDialog: () => Promise.resolve({ canceled: true }) } } }
+				: { promises: { writeFile, chmod: vi.fn() } };
+
+		expect(await saveExecutableFile('echo hi', 'install.sh')).toBeNull();
+		expect(writeFile).not.toHaveBeenCalled();
+	});
+
+	it('falls back to a plain download when desktop modules are unavailable', async () => {
+		global.URL.createObjectURL = vi.fn(() => 'blob:mock-url');
+		global.URL.revokeObjectURL = vi.fn();
+		const clickSpy = vi.spyOn(HTMLAnchorElement.prototype, 'click').mockImplementation(() => undefined);
+
+		expect(await saveExecutableFile('echo hi', 'install.sh')).toBeNull();
+		expect(clickSpy).toHaveBeenCalledTimes(1);
+	});
+});
@@ -1,11 +1,11 @@
 /**
  * Tests for src/services/download-service.ts
  *
- * Covers: buildSuggestedFilename
+ * Covers: buildSuggestedFilename, getDefaultShebang, injectShebang
  */
 
 import { describe, it, expect } from 'vitest';
-import { buildSuggestedFilename } from '../../src/services/download-service';
+import { buildSuggestedFilename, getDefaultShebang, injectShebang } from '../../src/services/download-service';
 
 describe('buildSuggestedFilename', () => {
 	// -------------------------------------------------------------------------
@@ -68,3 +68,30 @@ describe('buildSuggestedFilename', () => {
 		expect(buildSuggestedFilename('data.csv', '')).toBe('data.csv');
 	});
 });
+
+describe('getDefaultShebang', () => {
+	it('uses env with the language interpreter', () => {
+		expect(getDefaultShebang('bash')).toBe('#!/usr/bin/env bash');
+		expect(getDefaultShebang('python')).toBe('#!/usr/bin/env python3');
+		expect(getDefaultShebang('JS')).toBe('#!/usr/bin/env node');
+	});
+
+	it('returns empty string for languages without an interpreter', () => {
+		expect(getDefaultShebang('css')).toBe('');
+		expect(getDefaultShebang('')).toBe('');
+	});
+});
+
+describe('injectShebang', () => {
+	it('prepends the shebang as the first line', () => {
+		expect(injectShebang('echo hi', '#!/bin/sh')).toBe('#!/bin/sh\necho hi');
+	});
+
+	it('leaves code that already has a shebang unchanged', () => {
+		expect(injectShebang('#!/usr/bin/env zsh\necho hi', '#!/bin/sh')).toBe('#!/usr/bin/env zsh\necho hi');
+	});
+
+	it('leaves code unchanged when shebang is empty', () => {
+		expect(injectShebang('echo hi', '')).toBe('echo hi');
+	});
+});
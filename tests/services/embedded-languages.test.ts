@@ -0,0 +1,78 @@
+/**
+ * Tests for src/services/embedded-languages.ts
+ *
+ * Covers: findLanguageInjections, splitStringDelimiters, isBundledGrammar,
+ * registerBundledGrammars
+ */
+
+import { describe, it, expect } from 'vitest';
+import {
+	findLanguageInjections,
+	splitStringDelimiters,
+	isBundledGrammar,
+	registerBundledGrammars,
+} from '../../src/services/embedded-languages';
+import { DEFAULT_SETTINGS } from '../../src/constants';
+
+describe('findLanguageInjections', () => {
+	it('picks the rules listing the host, in order', () => {
+		const injections = findLanguageInjections([
+			{ hosts: 'go, Python', token: 'String', language: 'SQL', match: '^\\s*select\\b' },
+			{ hosts: 'javascript', token: 'string', language: 'css', match: '' },
+			{ hosts: 'python', token: 'comment', language: 'markdown', match: '' },
+		], ' python ');
+
+		expect(injections.map(injection => `${injection.token} ${injection.language}`)).toEqual(['string sql', 'comment markdown']);
+		expect(injections[0].match?.test('  SELECT 1')).toBe(true);
+		expect(injections[1].match).toBeNull();
+	});
+
+	it('skips rules that cannot work', () => {
+		expect(findLanguageInjections([
+			{ hosts: 'go', token: 'string', language: 'sql', match: '(' },
+			{ hosts: 'go', token: 'bad token', language: 'sql', match: '' },
+			{ hosts: 'go', token: 'string', language: 'my sql', match: '' },
+			{ hosts: 'go', token: 'string', language: 'go', match: '' },
+		], 'go')).toEqual([]);
+		expect(findLanguageInjections(DEFAULT_SETTINGS.languageInjections, '')).toEqual([]);
+	});
+
+	it('ships a rule for SQL in Go strings', () => {
+		const [injection] = findLanguageInjections(DEFAULT_SETTINGS.languageInjections, 'go');
+		expect(injection.language).toBe('sql');
+		expect(injection.match?.test('\n  select id from users')).toBe(true);
+		expect(injection.match?.test('selected items')).toBe(false);
+	});
+});
+
+describe('splitStringDelimiters', () => {
+	it('separates quotes and prefixes from the code', () => {
+		expect(splitStringDelimiters('"SELECT 1"')).toEqual({ open: '"', body: 'SELECT 1', close: '"' });
+		expect(splitStringDelimiters('f"""SELECT {x}"""')).toEqual({ open: 'f"""', body: 'SELECT {x}', close: '"""' });
+		expect(splitStringDelimiters('`SELECT *\nFROM t`')).toEqual({ open: '`', body: 'SELECT *\nFROM t', close: '`' });
+	});
+
+	it('keeps unquoted text whole', () => {
+		expect(splitStringDelimiters('SELECT 1')).toEqual({ open: '', body: 'SELECT 1', close: '' });
+		expect(splitStringDelimiters('"unclosed')).toEqual({ open: '', body: '"unclosed', close: '' });
+	});
+});
+
+describe('registerBundledGrammars', () => {
+	it('adds Helm on top of YAML once', () => {
+		const yaml = { key: /\w+:/ };
+		const prism = { languages: { yaml } as Record<string, unknown> };
+
+		expect(registerBundledGrammars(prism)).toEqual(['helm']);
+		expect((prism.languages.helm as Record<string, unknown>).rest).toBe(yaml);
+		expect(registerBundledGrammars(prism)).toEqual([]);
+		expect(isBundledGrammar('Helm')).toBe(true);
+		expect(isBundledGrammar('yaml')).toBe(false);
+	});
+
+	it('leaves out grammars whose base language is missing', () => {
+		const prism = { languages: {} as Record<string, unknown> };
+		expect(registerBundledGrammars(prism)).toEqual([]);
+		expect(prism.languages).toEqual({});
+	});
+});
@@ -0,0 +1,128 @@
+/**
+ * Tests for src/services/fence-lint.ts
+ *
+ * Covers: lintNoteContent, buildLintReport
+ */
+
+import { describe, it, expect } from 'vitest';
+import { lintNoteContent, buildLintReport } from '../../src/services/fence-lint';
+
+const note = (...lines: string[]): string => lines.join('\n');
+
+describe('lintNoteContent', () => {
+	it('returns nothing for valid blocks', () => {
+		const content = note(
+			'```ufence-bash',
+			'META:',
+			'  TITLE: Deploy',
+			'~~~',
+			'make deploy',
+			'```',
+		);
+		expect(lintNoteContent(content, [])).toEqual([]);
+	});
+
+	it('reports unknown keys with the block line and nearest heading', () => {
+		const content = note(
+			'# Intro',
+			'',
+			'## Setup',
+			'```ufence-bash',
+			'higlight:',
+			'  LINES: 2',
+			'~~~',
+			'echo hi',
+			'```',
+		);
+		expect(lintNoteContent(content, [])).toEqual([{
+			line: 4,
+			blockType: 'bash',
+			heading: 'Setup',
+			messages: ['Unknown key: higlight — did you mean HIGHLIGHT (a section)?'],
+		}]);
+	});
+
+	it('reports settings that fail to parse', () => {
+		const content = note('```ufence-bash', 'RENDER: [unclosed', '~~~', 'ls', '```');
+		const [issue] = lintNoteContent(content, []);
+		expect(issue.messages[0]).toMatch(/^Settings could not be parsed: /);
+	});
+
+	it('reports presets that do not exist', () => {
+		const content = note('```ufence-bash', 'META:', '  PRESET: teaching', '~~~', 'ls', '```');
+		expect(lintNoteContent(content, ['teaching'])).toEqual([]);
+		expect(lintNoteContent(content, [])[0].messages).toEqual(['Unknown preset: teaching']);
+	});
+
+	it('checks the preset the fence line names over the settings section', () => {
+		const content = note('```ufence-bash {preset=draft}', 'META:', '  PRESET: teaching', '~~~', 'ls', '```');
+		expect(lintNoteContent(content, ['teaching'])[0].messages).toEqual(['Unknown preset: draft']);
+	});
+
+	it('accepts the top-level PRESET shorthand in ufence-ufence blocks', () => {
+		const content = note('```ufence-ufence', 'PRESET: demo', '```');
+		expect(lintNoteContent(content, ['demo'])).toEqual([]);
+		expect(lintNoteContent(content, [])[0].messages).toEqual(['Unknown preset: demo']);
+	});
+
+	it('uses the cmdout schema for cmdout blocks', () => {
+		const content = note('```ufence-cmdout', 'RENDER:', '  OUTPUT:', '    BOLD: true', '~~~', '$ ls', '```');
+		expect(lintNoteContent(content, [])).toEqual([]);
+	});
+
+	it('honours the settings format named on the fence', () => {
+		const content = note('```ufence-bash toml', '[RENDER]', 'ZEBRA = "often"', '~~~', 'ls', '```');
+		expect(lintNoteContent(content, [])[0].messages).toEqual(['RENDER.ZEBRA should be true or false']);
+	});
+
+	it('ignores other fenced code, including ufence examples inside it', () => {
+		const content = note(
+			'````markdown',
+			'```ufence-bash',
+			'NOPE: 1',
+			'```',
+			'````',
+			'```python',
+			'# not a heading',
+			'```',
+		);
+		expect(lintNoteContent(content, [])).toEqual([]);
+	});
+
+	it('does not treat the ~~~ separator as the end of the block', () => {
+		const content = note('```ufence-bash', 'RENDER:', '  LINES: true', '~~~', 'ls', '```', '', '```ufence-bash', 'META:', '  TITEL: x', '```');
+		const issues = lintNoteContent(content, []);
+		expect(issues.map(issue => issue.line)).toEqual([8]);
+	});
+});
+
+describe('buildLintReport', () => {
+	it('reports a clean vault', () => {
+		expect(buildLintReport([], 12)).toBe('# Code block check\n\nChecked 12 notes: no problems found.\n');
+	});
+
+	it('lists blocks with links to their note and heading', () => {
+		const report = buildLintReport([{
+			path: 'Runbooks/Deploy.md',
+			issues: [
+				{ line: 4, blockType: 'bash', heading: 'Setup: step [1]', messages: ['Unknown key: NOPE'] },
+				{ line: 20, blockType: 'cmdout', heading: undefined, messages: ['Unknown preset: x', 'RENDER.SCROLL should be a number'] },
+			],
+		}], 3);
+
+		expect(report).toBe([
+			'# Code block check',
+			'',
+			'Checked 3 notes: 2 blocks with problems in 1 note.',
+			'',
+			'## Runbooks/Deploy',
+			'',
+			'- [[Runbooks/Deploy#Setup step 1|Line 4]] · `ufence-bash`',
+			'\t- Unknown key: NOPE',
+			'- [[Runbooks/Deploy|Line 20]] · `ufence-cmdout`',
+			'\t- Unknown preset: x',
+			'\t- RENDER.SCROLL should be a number',
+			'',
+		].join('\n'));
+	});
+});
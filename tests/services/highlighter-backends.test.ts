@@ -0,0 +1,126 @@
+/**
+ * Tests for src/services/highlighter-backends.ts
+ *
+ * Covers: isHighlighterEngine, pickHighlighterEngine, shikiTokensToPrism,
+ * readShikiModule, createShikiBackend
+ */
+
+import { describe, it, expect, vi } from 'vitest';
+import {
+	isHighlighterEngine,
+	pickHighlighterEngine,
+	shikiTokensToPrism,
+	readShikiModule,
+	createShikiBackend,
+} from '../../src/services/highlighter-backends';
+import type { ShikiHighlighter, ShikiToken } from '../../src/services/highlighter-backends';
+
+/** A Shiki token made of parts, each with its innermost scope last. */
+function shikiToken(...parts: Array<[string, ...string[]]>): ShikiToken {
+	return {
+		content: parts.map(([content]) => content).join(''),
+		explanation: parts.map(([content, ...scopes]) => ({ content, scopes: ['source.go', ...scopes].map(scopeName => ({ scopeName })) })),
+	};
+}
+
+describe('isHighlighterEngine', () => {
+	it('knows prism and shiki only', () => {
+		expect(isHighlighterEngine('prism')).toBe(true);
+		expect(isHighlighterEngine('shiki')).toBe(true);
+		expect(isHighlighterEngine('tree-sitter')).toBe(false);
+	});
+});
+
+describe('pickHighlighterEngine', () => {
+	it('uses the chosen engine except for languages kept on Prism', () => {
+		expect(pickHighlighterEngine('shiki', 'helm, MyDSL', 'go')).toBe('shiki');
+		expect(pickHighlighterEngine('Shiki', 'helm, MyDSL', 'mydsl')).toBe('prism');
+		expect(pickHighlighterEngine('shiki', '', 'go')).toBe('shiki');
+	});
+
+	it('falls back to Prism for Prism and unknown engines', () => {
+		expect(pickHighlighterEngine('prism', '', 'go')).toBe('prism');
+		expect(pickHighlighterEngine('tree-sitter', '', 'go')).toBe('prism');
+	});
+});
+
+describe('shikiTokensToPrism', () => {
+	it('maps innermost scopes to Prism tokens and joins lines', () => {
+		expect(shikiTokensToPrism([
+			[shikiToken(['func', 'keyword.control.go']), shikiToken([' ']), shikiToken(['main', 'entity.name.function.go'])],
+			[shikiToken(['x', 'variable.other.go'], [' := ', 'keyword.operator.assignment.go'])],
+		])).toEqual([
+			{ type: 'keyword', content: 'func' },
+			' ',
+			{ type: 'function', content: 'main' },
+			'\n',
+			{ type: 'variable', content: 'x' },
+			{ type: 'operator', content: ' := ' },
+		]);
+	});
+
+	it('gives string quotes and comment markers the surrounding token', () => {
+		const quoted = ['string.quoted.double.go'];
+		expect(shikiTokensToPrism([[
+			shikiToken(['"', ...quoted, 'punctuation.definition.string.begin.go'], ['hi', ...quoted], ['"', ...quoted, 'punctuation.definition.string.end.go']),
+			shikiToken([' ']),
+			shikiToken(['//', 'comment.line.go', 'punctuation.definition.comment.go'], [' note', 'comment.line.go']),
+			shikiToken(['(', 'punctuation.definition.begin.bracket.round.go']),
+		]])).toEqual([
+			{ type: 'string', content: '"hi"' },
+			' ',
+			{ type: 'comment', content: '// note' },
+			{ type: 'punctuation', content: '(' },
+		]);
+	});
+
+	it('keeps tokens without an explanation as plain text', () => {
+		expect(shikiTokensToPrism([[{ content: 'a' }, { content: 'b' }], []])).toEqual(['ab\n']);
+	});
+});
+
+describe('readShikiModule', () => {
+	it('reads createHighlighter, or getHighlighter from older builds', () => {
+		const create = vi.fn();
+		expect(readShikiModule({ createHighlighter: create, bundledLanguages: { go: {} } })?.createHighlighter).toBe(create);
+		expect(readShikiModule({ getHighlighter: create, bundledLanguages: {} })?.createHighlighter).toBe(create);
+	});
+
+	it('returns null for other modules', () => {
+		expect(readShikiModule({ default: {} })).toBeNull();
+		expect(readShikiModule({ createHighlighter: vi.fn() })).toBeNull();
+		expect(readShikiModule(null)).toBeNull();
+	});
+});
+
+describe('createShikiBackend', () => {
+	it('creates the highlighter once and loads each language when first used', async () => {
+		const loaded: string[] = [];
+		const highlighter: ShikiHighlighter = {
+			getLoadedLanguages: () => loaded,
+			loadLanguage: vi.fn((language: string) => {
+				loaded.push(language);
+				return Promise.resolve();
+			}),
+			codeToTokensBase: vi.fn((code: string) => [[shikiToken([code, 'keyword.go'])]]),
+		};
+		const createHighlighter = vi.fn(() => Promise.resolve(highlighter));
+		const backend = createShikiBackend({ bundledLanguages: { go: {}, rust: {} }, createHighlighter });
+
+		expect(await backend.tokenize('func', 'Go')).toEqual([{ type: 'keyword', content: 'func' }]);
+		await backend.tokenize('fn', 'rust');
+		await backend.tokenize('go', 'go');
+
+		expect(createHighlighter).toHaveBeenCalledTimes(1);
+		expect(highlighter.loadLanguage).toHaveBeenCalledTimes(2);
+		expect(highlighter.codeToTokensBase).toHaveBeenLastCalledWith('go', { lang: 'go', theme: 'none', includeExplanation: true });
+	});
+
+	it('returns null for languages the build lacks, without loading Shiki', async () => {
+		const createHighlighter = vi.fn();
+		const backend = createShikiBackend({ bundledLanguages: { go: {} }, createHighlighter });
+
+		expect(await backend.tokenize('x', 'mydsl')).toBeNull();
+		expect(createHighlighter).not.toHaveBeenCalled();
+	});
+});
@@ -0,0 +1,85 @@
+/**
+ * Tests for src/services/note-snapshots.ts
+ *
+ * Covers: parseSnapshotAge, pickSnapshot, matchSnapshotBlock, findChangedLines
+ */
+
+import { describe, it, expect } from 'vitest';
+import { parseSnapshotAge, pickSnapshot, matchSnapshotBlock, findChangedLines } from '../../src/services/note-snapshots';
+import type { UfenceBlock } from '../../src/services/fence-lint';
+
+const HOUR = 60 * 60 * 1000;
+
+function block(fenceLine: string, content: string): UfenceBlock {
+	return { line: 1, blockType: fenceLine.replace(/^`+ufence-/, '').split(' ')[0], fenceLine, content, endLine: content.split('\n').length + 2, heading: undefined };
+}
+
+describe('parseSnapshotAge', () => {
+	it('reads "last" and ages in minutes, hours, days and weeks', () => {
+		expect(parseSnapshotAge('last')).toBe(0);
+		expect(parseSnapshotAge('30m')).toBe(30 * 60 * 1000);
+		expect(parseSnapshotAge('12h')).toBe(12 * HOUR);
+		expect(parseSnapshotAge(' 3D ')).toBe(72 * HOUR);
+		expect(parseSnapshotAge('2w')).toBe(14 * 24 * HOUR);
+	});
+
+	it('reads anything else as off', () => {
+		expect(parseSnapshotAge('off')).toBeNull();
+		expect(parseSnapshotAge('')).toBeNull();
+		expect(parseSnapshotAge('3y')).toBeNull();
+	});
+});
+
+describe('pickSnapshot', () => {
+	const now = 100 * HOUR;
+	const snapshots = [
+		{ time: now - 30 * HOUR, content: 'old' },
+		{ time: now - 1 * HOUR, content: 'same' },
+		{ time: now - 5 * HOUR, content: 'earlier' },
+	];
+
+	it('picks the latest snapshot that differs for "last"', () => {
+		expect(pickSnapshot(snapshots, 'same', 0, now)?.content).toBe('earlier');
+		expect(pickSnapshot([{ time: now, content: 'same' }], 'same', 0, now)).toBeNull();
+	});
+
+	it('picks the latest snapshot at least the given age, or the oldest', () => {
+		expect(pickSnapshot(snapshots, 'same', 4 * HOUR, now)?.content).toBe('earlier');
+		expect(pickSnapshot(snapshots, 'same', 24 * HOUR, now)?.content).toBe('old');
+		expect(pickSnapshot(snapshots, 'same', 48 * HOUR, now)?.content).toBe('old');
+		expect(pickSnapshot([], 'same', HOUR, now)).toBeNull();
+	});
+});
+
+describe('matchSnapshotBlock', () => {
+	const current = block('```ufence-bash', '~~~\nssh prod\nsudo systemctl restart api\n');
+
+	it('matches the block sharing the most lines, preferring the same fence line', () => {
+		const previous = [
+			block('```ufence-bash', '~~~\nls\n'),
+			block('```ufence-python', '~~~\nssh prod\nsudo systemctl restart api\n'),
+			block('```ufence-bash', '~~~\nssh prod\nsudo systemctl restart web\n'),
+		];
+
+		expect(matchSnapshotBlock(current, previous)).toBe(previous[2]);
+	});
+
+	it('falls back to blocks of the same type when the fence line changed', () => {
+		const previous = [block('```ufence-bash {ln}', '~~~\nssh prod\n')];
+
+		expect(matchSnapshotBlock(current, previous)).toBe(previous[0]);
+	});
+
+	it('reads a block with nothing in common as new', () => {
+		expect(matchSnapshotBlock(current, [block('```ufence-bash', '~~~\n\nls\n')])).toBeNull();
+		expect(matchSnapshotBlock(current, [])).toBeNull();
+	});
+});
+
+describe('findChangedLines', () => {
+	it('lists lines added or changed, not removed ones', () => {
+		expect(findChangedLines('a\nb\nc\nd', 'a\nB\nc\nnew')).toEqual([2, 4]);
+		expect(findChangedLines('a\nb\nc', 'a\nc')).toEqual([]);
+		expect(findChangedLines('a', 'a')).toEqual([]);
+	});
+});
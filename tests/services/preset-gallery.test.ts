@@ -0,0 +1,53 @@
+/**
+ * Tests for installing presets from the gallery.
+ *
+ * Covers: findInstalledGalleryPreset, installGalleryPreset, bundled gallery data
+ */
+
+import { describe, it, expect } from 'vitest';
+import { parseYaml } from 'obsidian';
+import { findInstalledGalleryPreset, installGalleryPreset } from '../../src/services/preset-gallery';
+import { validateYamlSchema, PRESET_SCHEMA } from '../../src/ui/yaml-validator';
+import presetGalleryData from '../../src/data/preset-gallery.json';
+
+const teaching = { name: 'teaching', description: 'Numbered lines', yaml: 'RENDER:\n  LINES: true\n' };
+
+describe('findInstalledGalleryPreset', () => {
+	it('finds a saved preset with the same content, under any name', () => {
+		expect(findInstalledGalleryPreset({ teaching: teaching.yaml }, teaching)).toBe('teaching');
+		expect(findInstalledGalleryPreset({ mine: teaching.yaml }, teaching)).toBe('mine');
+		expect(findInstalledGalleryPreset({ teaching: 'RENDER:\n  ZEBRA: true' }, teaching)).toBeUndefined();
+	});
+});
+
+describe('installGalleryPreset', () => {
+	it('adds the preset under its own name', () => {
+		const result = installGalleryPreset({ other: 'x' }, teaching);
+		expect(result).toEqual({ presets: { other: 'x', teaching: teaching.yaml }, name: 'teaching', installed: true });
+	});
+
+	it('never replaces a different saved preset with the same name', () => {
+		const result = installGalleryPreset({ teaching: 'mine', 'teaching-2': 'also mine' }, teaching);
+		expect(result.name).toBe('teaching-3');
+		expect(result.presets.teaching).toBe('mine');
+		expect(result.presets['teaching-3']).toBe(teaching.yaml);
+	});
+
+	it('changes nothing when the preset is already installed', () => {
+		const existing = { teaching: teaching.yaml };
+		const result = installGalleryPreset(existing, teaching);
+		expect(result).toEqual({ presets: existing, name: 'teaching', installed: false });
+	});
+});
+
+describe('bundled preset gallery', () => {
+	it('has uniquely named, described presets with valid settings', () => {
+		const names = presetGalleryData.presets.map(entry => entry.name);
+		expect(new Set(names).size).toBe(names.length);
+
+		for (const entry of presetGalleryData.presets) {
+			expect(entry.description).not.toBe('');
+			expect(validateYamlSchema(parseYaml(entry.yaml) as Record<string, unknown>, PRESET_SCHEMA)).toEqual([]);
+		}
+	});
+});
@@ -0,0 +1,91 @@
+/**
+ * Tests for src/services/preset-transfer.ts
+ *
+ * Covers: buildPresetPack, parsePresetPack, findFreePresetName, importPresets
+ */
+
+import { describe, it, expect } from 'vitest';
+import { buildPresetPack, parsePresetPack, findFreePresetName, importPresets } from '../../src/services/preset-transfer';
+
+const saved: Record<string, string> = {
+	base: 'RENDER:\n  LINES: true\n',
+	bash: 'EXTENDS: base\nRENDER:\n  LANG: bash\n',
+	sql: 'RENDER:\n  LANG: sql\n',
+};
+
+describe('buildPresetPack', () => {
+	it('writes the chosen presets with the format marker', () => {
+		const pack = JSON.parse(buildPresetPack(saved, ['base', 'sql', 'missing'])) as Record<string, unknown>;
+		expect(pack).toEqual({
+			format: 'ultra-code-fence-presets',
+			version: 1,
+			presets: { base: saved.base, sql: saved.sql },
+		});
+	});
+});
+
+describe('parsePresetPack', () => {
+	it('reads back an exported pack', () => {
+		expect(parsePresetPack(buildPresetPack(saved, ['base', 'bash']))).toEqual({ base: saved.base, bash: saved.bash });
+	});
+
+	it('drops entries that are not preset YAML', () => {
+		const text = JSON.stringify({ format: 'ultra-code-fence-presets', version: 1, presets: { ok: 'RENDER: {}', bad: 5, ' ': 'x' } });
+		expect(parsePresetPack(text)).toEqual({ ok: 'RENDER: {}' });
+	});
+
+	it('rejects files that are not preset packs', () => {
+		expect(() => parsePresetPack('{not json')).toThrow('not valid JSON');
+		expect(() => parsePresetPack('{"presets": {}}')).toThrow('not an Ultra Code Fence preset pack');
+		expect(() => parsePresetPack('{"format": "ultra-code-fence-presets", "version": 99, "presets": {}}')).toThrow('newer version');
+		expect(() => parsePresetPack('{"format": "ultra-code-fence-presets", "version": 1}')).toThrow('no presets');
+	});
+});
+
+describe('findFreePresetName', () => {
+	it('appends the first free number', () => {
+		expect(findFreePresetName('base', new Set(['base']))).toBe('base-2');
+		expect(findFreePresetName('base', new Set(['base', 'base-2', 'base-3']))).toBe('base-4');
+	});
+});
+
+describe('importPresets', () => {
+	const incoming: Record<string, string> = {
+		base: 'RENDER:\n  LINES: false\n',
+		sql: saved.sql,
+		diff: 'RENDER:\n  LANG: diff\n',
+	};
+
+	it('adds new presets and skips taken names', () => {
+		const result = importPresets(saved, incoming, 'skip');
+		expect(result.added).toEqual(['diff']);
+		expect(result.skipped).toEqual(['base', 'sql']);
+		expect(result.presets.base).toBe(saved.base);
+		expect(result.presets.diff).toBe(incoming.diff);
+	});
+
+	it('replaces taken names when overwriting, but counts identical presets as skipped', () => {
+		const result = importPresets(saved, incoming, 'overwrite');
+		expect(result.overwritten).toEqual(['base']);
+		expect(result.skipped).toEqual(['sql']);
+		expect(result.presets.base).toBe(incoming.base);
+	});
+
+	it('saves conflicting presets under a new name when renaming', () => {
+		const result = importPresets(saved, incoming, 'rename');
+		expect(result.renamed).toEqual([{ from: 'base', to: 'base-2' }]);
+		expect(result.presets.base).toBe(saved.base);
+		expect(result.presets['base-2']).toBe(incoming.base);
+	});
+
+	it('never renames onto a name from the pack', () => {
+		const result = importPresets({ base: 'a' }, { base: 'b', 'base-2': 'c' }, 'rename');
+		expect(result.renamed).toEqual([{ from: 'base', to: 'base-3' }]);
+		expect(result.presets['base-2']).toBe('c');
+	});
+
+	it('does not modify the saved presets', () => {
+		importPresets(saved, incoming, 'overwrite');
+		expect(saved.base).toBe('RENDER:\n  LINES: true\n');
+	});
+});
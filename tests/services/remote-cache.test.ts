@@ -0,0 +1,121 @@
+/**
+ * Tests for src/services/remote-cache.ts
+ *
+ * Covers: remoteCacheFileName, isRemoteCacheFresh, formatFetchAge, RemoteSourceCache, loadCachedRemoteFile
+ */
+
+import { describe, it, expect, vi } from 'vitest';
+import type { DataAdapter } from 'obsidian';
+import {
+	remoteCacheFileName,
+	isRemoteCacheFresh,
+	formatFetchAge,
+	RemoteSourceCache,
+	loadCachedRemoteFile,
+} from '../../src/services/remote-cache';
+import type { SourceLoadResult } from '../../src/types';
+
+const SCRIPT_URL = 'https://raw.githubusercontent.com/acme/tools/main/install.sh';
+const MINUTE = 60 * 1000;
+
+/** An in-memory stand-in for the vault adapter. */
+function memoryAdapter(files: Record<string, string> = {}): DataAdapter {
+	return {
+		exists: async (path: string) => path in files || Object.keys(files).some(file => file.startsWith(`${path}/`)),
+		read: async (path: string) => files[path],
+		write: async (path: string, data: string) => { files[path] = data; },
+		mkdir: async () => undefined,
+	} as unknown as DataAdapter;
+}
+
+function fetched(text: string): () => Promise<SourceLoadResult> {
+	return async () => ({ succeeded: true, sourceCode: text, fileMetadata: null });
+}
+
+async function failed(): Promise<SourceLoadResult> {
+	return { succeeded: false, sourceCode: '', fileMetadata: null, errorMessage: `couldn't fetch '${SCRIPT_URL}'` };
+}
+
+describe('remoteCacheFileName', () => {
+	it('gives each SCRIPT_URL a stable file name', () => {
+		expect(remoteCacheFileName(SCRIPT_URL)).toMatch(/^[0-9a-f]{8}\.json$/);
+		expect(remoteCacheFileName(SCRIPT_URL)).toBe(remoteCacheFileName(SCRIPT_URL));
+		expect(remoteCacheFileName(SCRIPT_URL)).not.toBe(remoteCacheFileName(`${SCRIPT_URL}?v=2`));
+	});
+});
+
+describe('isRemoteCacheFresh', () => {
+	it('is fresh for less than the maximum age, and never with 0', () => {
+		const entry = { url: SCRIPT_URL, fetchedAt: 0, text: '' };
+		expect(isRemoteCacheFresh(entry, 60 * MINUTE, 59 * MINUTE)).toBe(true);
+		expect(isRemoteCacheFresh(entry, 60 * MINUTE, 60 * MINUTE)).toBe(false);
+		expect(isRemoteCacheFresh(entry, 0, 0)).toBe(false);
+	});
+});
+
+describe('formatFetchAge', () => {
+	it('rounds down to minutes, hours or days', () => {
+		expect(formatFetchAge(30 * 1000)).toBe('just now');
+		expect(formatFetchAge(5 * MINUTE)).toBe('5 min ago');
+		expect(formatFetchAge(3 * 60 * MINUTE + 59 * MINUTE)).toBe('3 h ago');
+		expect(formatFetchAge(2 * 24 * 60 * MINUTE)).toBe('2 d ago');
+	});
+});
+
+describe('RemoteSourceCache', () => {
+	it('reads back what it wrote, by SCRIPT_URL', async () => {
+		const cache = new RemoteSourceCache(memoryAdapter(), 'cache');
+		await cache.write({ url: SCRIPT_URL, fetchedAt: 5, text: 'echo hi' });
+
+		expect(await cache.read(SCRIPT_URL)).toEqual({ url: SCRIPT_URL, fetchedAt: 5, text: 'echo hi' });
+		expect(await cache.read(`${SCRIPT_URL}?v=2`)).toBeNull();
+	});
+
+	it('ignores a copy stored for another SCRIPT_URL or that is not JSON', async () => {
+		const path = `cache/${remoteCacheFileName(SCRIPT_URL)}`;
+		expect(await new RemoteSourceCache(memoryAdapter({ [path]: JSON.stringify({ url: 'https://other', fetchedAt: 1, text: 'x' }) }), 'cache').read(SCRIPT_URL)).toBeNull();
+		expect(await new RemoteSourceCache(memoryAdapter({ [path]: 'not json' }), 'cache').read(SCRIPT_URL)).toBeNull();
+	});
+});
+
+describe('loadCachedRemoteFile', () => {
+	it('fetches and caches a file it has no copy of', async () => {
+		const cache = new RemoteSourceCache(memoryAdapter(), 'cache');
+		const result = await loadCachedRemoteFile(SCRIPT_URL, cache, { maxAge: 60 * MINUTE, now: 1000 }, fetched('v1'));
+
+		expect(result.sourceCode).toBe('v1');
+		expect(result.remoteStatus).toEqual({ url: SCRIPT_URL, fetchedAt: 1000, offline: false });
+		expect(await cache.read(SCRIPT_URL)).toEqual({ url: SCRIPT_URL, fetchedAt: 1000, text: 'v1' });
+	});
+
+	it('uses a fresh copy without fetching, unless forced', async () => {
+		const cache = new RemoteSourceCache(memoryAdapter(), 'cache');
+		await cache.write({ url: SCRIPT_URL, fetchedAt: 0, text: 'v1' });
+		const fetchRemote = vi.fn(fetched('v2'));
+
+		const cached = await loadCachedRemoteFile(SCRIPT_URL, cache, { maxAge: 60 * MINUTE, now: MINUTE }, fetchRemote);
+		expect(cached.sourceCode).toBe('v1');
+		expect(cached.remoteStatus).toEqual({ url: SCRIPT_URL, fetchedAt: 0, offline: false });
+		expect(fetchRemote).not.toHaveBeenCalled();
+
+		const forced = await loadCachedRemoteFile(SCRIPT_URL, cache, { maxAge: 60 * MINUTE, force: true, now: MINUTE }, fetchRemote);
+		expect(forced.sourceCode).toBe('v2');
+		expect(fetchRemote).toHaveBeenCalledTimes(1);
+	});
+
+	it('falls back to the cached copy, however old, when the fetch fails', async () => {
+		const cache = new RemoteSourceCache(memoryAdapter(), 'cache');
+		await cache.write({ url: SCRIPT_URL, fetchedAt: 0, text: 'v1' });
+
+		const result = await loadCachedRemoteFile(SCRIPT_URL, cache, { maxAge: MINUTE, now: 10 * 24 * 60 * MINUTE }, failed);
+		expect(result.succeeded).toBe(true);
+		expect(result.sourceCode).toBe('v1');
+		expect(result.remoteStatus).toEqual({ url: SCRIPT_URL, fetchedAt: 0, offline: true });
+	});
+
+	it('reports the failure when there is no cached copy', async () => {
+		const result = await loadCachedRemoteFile(SCRIPT_URL, new RemoteSourceCache(memoryAdapter(), 'cache'), { maxAge: MINUTE, now: 0 }, failed);
+		expect(result.succeeded).toBe(false);
+		expect(result.remoteStatus).toBeUndefined();
+	});
+});
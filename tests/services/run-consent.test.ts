@@ -0,0 +1,43 @@
+/**
+ * Tests for src/services/run-consent.ts
+ *
+ * Covers: loadRunConsent, saveRunConsent
+ */
+
+import { describe, it, expect } from 'vitest';
+import type { App } from 'obsidian';
+import { loadRunConsent, saveRunConsent } from '../../src/services/run-consent';
+import { RUN_CONSENT_STORE_KEY } from '../../src/constants';
+
+/**
+ * An app whose local storage is a plain map.
+ */
+function fakeApp(): { app: App; storage: Map<string, unknown> } {
+	const storage = new Map<string, unknown>();
+	const app = {
+		loadLocalStorage: (key: string) => storage.get(key) ?? null,
+		saveLocalStorage: (key: string, value: unknown) => {
+			if (value === null) {
+				storage.delete(key);
+			} else {
+				storage.set(key, value);
+			}
+		},
+	} as unknown as App;
+	return { app, storage };
+}
+
+describe('loadRunConsent / saveRunConsent', () => {
+	it('keeps the consent in local storage until it is withdrawn', () => {
+		const { app, storage } = fakeApp();
+		expect(loadRunConsent(app)).toBe(false);
+
+		saveRunConsent(app, true);
+		expect(storage.get(RUN_CONSENT_STORE_KEY)).toBe(true);
+		expect(loadRunConsent(app)).toBe(true);
+
+		saveRunConsent(app, false);
+		expect(storage.has(RUN_CONSENT_STORE_KEY)).toBe(false);
+		expect(loadRunConsent(app)).toBe(false);
+	});
+});
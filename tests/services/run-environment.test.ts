@@ -0,0 +1,81 @@
+/**
+ * Tests for src/services/run-environment.ts
+ *
+ * Covers: loadSecrets, saveSecrets, resolveRunEnvironment, maskSecrets
+ */
+
+import { describe, it, expect } from 'vitest';
+import type { App } from 'obsidian';
+import { loadSecrets, saveSecrets, resolveRunEnvironment, maskSecrets } from '../../src/services/run-environment';
+import { SECRET_STORE_KEY } from '../../src/constants';
+
+/**
+ * An app whose local storage is a plain map.
+ */
+function fakeApp(): { app: App; storage: Map<string, unknown> } {
+	const storage = new Map<string, unknown>();
+	const app = {
+		loadLocalStorage: (key: string) => storage.get(key) ?? null,
+		saveLocalStorage: (key: string, value: unknown) => {
+			if (value === null) {
+				storage.delete(key);
+			} else {
+				storage.set(key, value);
+			}
+		},
+	} as unknown as App;
+	return { app, storage };
+}
+
+describe('loadSecrets / saveSecrets', () => {
+	it('stores secrets in local storage and reads them back', () => {
+		const { app, storage } = fakeApp();
+		saveSecrets(app, { 'prod-token': 'abc123' });
+
+		expect(storage.get(SECRET_STORE_KEY)).toEqual({ 'prod-token': 'abc123' });
+		expect(loadSecrets(app)).toEqual({ 'prod-token': 'abc123' });
+	});
+
+	it('clears the store when the last secret goes', () => {
+		const { app, storage } = fakeApp();
+		saveSecrets(app, { token: 'abc' });
+		saveSecrets(app, {});
+
+		expect(storage.has(SECRET_STORE_KEY)).toBe(false);
+		expect(loadSecrets(app)).toEqual({});
+	});
+
+	it('ignores stored values that aren\'t strings', () => {
+		const { app, storage } = fakeApp();
+		storage.set(SECRET_STORE_KEY, { token: 'abc', count: 3 });
+
+		expect(loadSecrets(app)).toEqual({ token: 'abc' });
+	});
+});
+
+describe('resolveRunEnvironment', () => {
+	it('fills in secret references and notes the values used', () => {
+		const result = resolveRunEnvironment(
+			{ AUTH: 'Bearer {{ secret.prod-token }}', REGION: 'eu' },
+			{ 'prod-token': 'abc123' },
+		);
+
+		expect(result.env).toEqual({ AUTH: 'Bearer abc123', REGION: 'eu' });
+		expect(result.secretValues).toEqual(['abc123']);
+		expect(result.missingSecrets).toEqual([]);
+	});
+
+	it('reports secrets that aren\'t stored and leaves their references', () => {
+		const result = resolveRunEnvironment({ A: '{{secret.missing}}', B: '{{secret.missing}}' }, {});
+
+		expect(result.missingSecrets).toEqual(['missing']);
+		expect(result.env.A).toBe('{{secret.missing}}');
+	});
+});
+
+describe('maskSecrets', () => {
+	it('replaces every occurrence of each value', () => {
+		expect(maskSecrets('token=abc123 again abc123', ['abc123'])).toBe('token=•••••• again ••••••');
+		expect(maskSecrets('nothing secret', ['abc123'])).toBe('nothing secret');
+	});
+});
@@ -0,0 +1,135 @@
+/**
+ * Tests for src/services/script-runner.ts
+ *
+ * Covers: isScriptLanguage, prepareScript, runScript (with the worker
+ * source run in-process in place of a real worker)
+ */
+
+import { describe, it, expect } from 'vitest';
+import { SCRIPT_WORKER_SOURCE, isScriptLanguage, prepareScript, runScript } from '../../src/services/script-runner';
+import type { ScriptWorker, ScriptWorkerFactory } from '../../src/services/script-runner';
+import type { OutputStream } from '../../src/services/shell-runner';
+
+/**
+ * Runs the worker source against a stand-in `self` and `console`, so a run
+ * goes through the real worker code without a Worker. The script's code
+ * sees them as globals until the worker is stopped. Like a real worker's
+ * scope, `self` inherits network functions from its prototype.
+ */
+const inProcessWorker: ScriptWorkerFactory = (source) => {
+	const scopePrototype = { fetch: () => 'fetched', WebSocket: class {} };
+	const scope: { postMessage?: (message: unknown) => void; onmessage?: (event: { data: unknown }) => void } = Object.create(scopePrototype) as object;
+	const globals = globalThis as unknown as Record<string, unknown>;
+	const saved = { self: globals.self, console: globals.console };
+	globals.self = scope;
+	globals.console = {};
+
+	const worker: ScriptWorker & { terminated: boolean } = {
+		onmessage: null,
+		onerror: null,
+		terminated: false,
+		postMessage: (message) => { setTimeout(() => { scope.onmessage?.({ data: message }); }, 0); },
+		terminate: () => {
+			worker.terminated = true;
+			globals.self = saved.self;
+			globals.console = saved.console;
+		},
+	};
+	scope.postMessage = (message) => {
+		if (!worker.terminated) worker.onmessage?.({ data: message } as MessageEvent);
+	};
+	(new Function(source) as () => void)();
+	return worker;
+};
+
+/**
+ * Runs code and collects its output.
+ */
+async function run(code: string, createWorker: ScriptWorkerFactory = inProcessWorker, timeoutMs?: number) {
+	const output: [string, OutputStream][] = [];
+	const result = await runScript(code, { onOutput: (text, stream) => { output.push([text, stream]); }, timeoutMs }, createWorker);
+	return { result, output };
+}
+
+describe('isScriptLanguage', () => {
+	it('accepts JavaScript and TypeScript names', () => {
+		expect(isScriptLanguage('js')).toBe(true);
+		expect(isScriptLanguage('JavaScript')).toBe(true);
+		expect(isScriptLanguage('ts')).toBe(true);
+		expect(isScriptLanguage('typescript')).toBe(true);
+	});
+
+	it('rejects other languages', () => {
+		expect(isScriptLanguage('bash')).toBe(false);
+		expect(isScriptLanguage('python')).toBe(false);
+	});
+});
+
+describe('prepareScript', () => {
+	it('strips types from TypeScript only', () => {
+		expect(prepareScript('const n: number = 1;', 'ts')).toBe('const n = 1;');
+		expect(prepareScript('const n = 1;', 'js')).toBe('const n = 1;');
+	});
+
+	it("refuses TypeScript that can't run without compiling", () => {
+		expect(() => prepareScript('enum Colour { Red }', 'ts')).toThrow("Can't run 'enum Colour' without compiling");
+		expect(() => prepareScript('enum Colour { Red }', 'js')).not.toThrow();
+	});
+});
+
+describe('runScript', () => {
+	it('passes on console output by stream and exits with 0', async () => {
+		const { result, output } = await run('console.log("a", 1); console.warn("careful"); console.log({ b: 2 });');
+		expect(result).toEqual({ exitCode: 0 });
+		expect(output).toEqual([
+			['a 1\n', 'stdout'],
+			['careful\n', 'stderr'],
+			['{\n  "b": 2\n}\n', 'stdout'],
+		]);
+	});
+
+	it('waits for top-level await', async () => {
+		const { result, output } = await run('await new Promise(r => setTimeout(r, 5)); console.log("later");');
+		expect(result.exitCode).toBe(0);
+		expect(output).toEqual([['later\n', 'stdout']]);
+	});
+
+	it('fails with exit code 1 when the code throws', async () => {
+		const { result, output } = await run('throw new Error("broken")');
+		expect(result.exitCode).toBe(1);
+		expect(output[0][1]).toBe('stderr');
+		expect(output[0][0]).toContain('broken');
+	});
+
+	it('removes network functions', async () => {
+		const { output } = await run('console.log(typeof self.fetch)');
+		expect(output).toEqual([['undefined\n', 'stdout']]);
+	});
+
+	it('removes network functions from the prototypes of the scope too', async () => {
+		const { output } = await run('const prototype = Object.getPrototypeOf(self); prototype.fetch = () => "fetched"; console.log(typeof prototype.fetch, typeof prototype.WebSocket)');
+		expect(output).toEqual([['undefined undefined\n', 'stdout']]);
+	});
+
+	it('reports a worker that fails', async () => {
+		const failing: ScriptWorkerFactory = () => {
+			const worker: ScriptWorker = {
+				onmessage: null,
+				onerror: null,
+				postMessage: () => {
+					setTimeout(() => { worker.onerror?.({ message: 'out of memory', preventDefault: () => undefined } as ErrorEvent); }, 0);
+				},
+				terminate: () => undefined,
+			};
+			return worker;
+		};
+		const { result } = await run('', failing);
+		expect(result).toEqual({ exitCode: null, errorMessage: 'out of memory' });
+	});
+
+	it('stops a script that goes past its time limit', async () => {
+		const { result, output } = await run('console.log("started"); await new Promise(() => undefined);', inProcessWorker, 20);
+		expect(result).toEqual({ exitCode: null, stopped: { reason: 'timeout', afterMs: 20 } });
+		expect(output).toEqual([['started\n', 'stdout']]);
+	});
+});
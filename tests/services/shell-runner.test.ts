@@ -0,0 +1,167 @@
+/**
+ * Tests for src/services/shell-runner.ts
+ *
+ * Covers: isShellLanguage, resolveShellInvocation, superviseRun, runShellInvocation
+ */
+
+import { describe, it, expect } from 'vitest';
+import { isShellLanguage, resolveShellInvocation, superviseRun, runShellInvocation } from '../../src/services/shell-runner';
+import type { SpawnFunction, SpawnedProcess, OutputStream } from '../../src/services/shell-runner';
+
+const UNIX = { userShell: '/bin/zsh', commandInterpreter: '', windows: false };
+const WINDOWS = { userShell: '', commandInterpreter: 'C:\\Windows\\system32\\cmd.exe', windows: true };
+
+/**
+ * A spawn that replays output and then exits, or fails to start.
+ */
+function fakeSpawn(output: [string, OutputStream][], exit: number | Error): { spawn: SpawnFunction; calls: unknown[][] } {
+	const calls: unknown[][] = [];
+	const spawn: SpawnFunction = (file, args, options) => {
+		calls.push([file, args, options]);
+		const listeners: Record<string, ((value: unknown) => void)[]> = {};
+		const stream = (name: OutputStream) => ({
+			on: (_event: 'data', listener: (chunk: unknown) => void) => { (listeners[name] ??= []).push(listener); },
+		});
+		const child = {
+			stdout: stream('stdout'),
+			stderr: stream('stderr'),
+			on: (event: string, listener: (value: unknown) => void) => { (listeners[event] ??= []).push(listener); },
+		} as unknown as SpawnedProcess;
+
+		setTimeout(() => {
+			for (const [text, name] of output) {
+				for (const listener of listeners[name] ?? []) listener(Buffer.from(text));
+			}
+			if (exit instanceof Error) {
+				for (const listener of listeners.error ?? []) listener(exit);
+				for (const listener of listeners.close ?? []) listener(-2);
+			} else {
+				for (const listener of listeners.close ?? []) listener(exit);
+			}
+		}, 0);
+		return child;
+	};
+	return { spawn, calls };
+}
+
+/**
+ * A spawn whose process runs until it is killed, then closes.
+ */
+function hangingSpawn(): { spawn: SpawnFunction; kills: number[] } {
+	const kills: number[] = [];
+	const spawn: SpawnFunction = () => {
+		const closeListeners: ((code: number | null) => void)[] = [];
+		return {
+			stdout: null,
+			stderr: null,
+			on: (event: string, listener: (code: number | null) => void) => { if (event === 'close') closeListeners.push(listener); },
+			kill: () => {
+				kills.push(Date.now());
+				setTimeout(() => { for (const listener of closeListeners) listener(null); }, 0);
+			},
+		} as unknown as SpawnedProcess;
+	};
+	return { spawn, kills };
+}
+
+describe('isShellLanguage', () => {
+	it('accepts shell languages only', () => {
+		expect(isShellLanguage('bash')).toBe(true);
+		expect(isShellLanguage('PowerShell')).toBe(true);
+		expect(isShellLanguage('python')).toBe(false);
+	});
+});
+
+describe('resolveShellInvocation', () => {
+	it('uses the interpreter the language names', () => {
+		expect(resolveShellInvocation('bash', 'ls', UNIX)).toEqual({ file: 'bash', args: ['-c', 'ls'] });
+		expect(resolveShellInvocation('ps1', 'dir', UNIX)).toEqual({ file: 'pwsh', args: ['-NoProfile', '-Command', 'dir'] });
+		expect(resolveShellInvocation('sh', 'ls', UNIX)).toEqual({ file: 'sh', args: ['-c', 'ls'] });
+	});
+
+	it("runs plain shell blocks in the user's shell", () => {
+		expect(resolveShellInvocation('shell', 'ls', UNIX)).toEqual({ file: '/bin/zsh', args: ['-c', 'ls'] });
+		expect(resolveShellInvocation('shell', 'ls', { ...UNIX, userShell: '' })).toEqual({ file: '/bin/sh', args: ['-c', 'ls'] });
+		expect(resolveShellInvocation('shell', 'dir', WINDOWS)).toEqual({ file: WINDOWS.commandInterpreter, args: ['/d', '/s', '/c', 'dir'] });
+	});
+
+	it('refuses other languages', () => {
+		expect(resolveShellInvocation('python', 'print(1)', UNIX)).toBeNull();
+	});
+});
+
+describe('runShellInvocation', () => {
+	it('passes output on as it arrives and reports the exit code', async () => {
+		const { spawn, calls } = fakeSpawn([['one\n', 'stdout'], ['oops\n', 'stderr']], 3);
+		const output: string[] = [];
+
+		const result = await runShellInvocation({ file: 'bash', args: ['-c', 'x'] }, {
+			cwd: '/vault',
+			onOutput: (text, stream) => { output.push(`${stream}:${text}`); },
+		}, spawn);
+
+		expect(calls).toEqual([['bash', ['-c', 'x'], { cwd: '/vault' }]]);
+		expect(output).toEqual(['stdout:one\n', 'stderr:oops\n']);
+		expect(result).toEqual({ exitCode: 3 });
+	});
+
+	it('gives the process the block\'s variables', async () => {
+		const { spawn, calls } = fakeSpawn([], 0);
+		await runShellInvocation({ file: 'bash', args: [] }, { env: { API_URL: 'https://example.com' }, onOutput: () => undefined }, spawn);
+		expect(calls[0][2]).toEqual({ env: { API_URL: 'https://example.com' } });
+	});
+
+	it("says when the program couldn't be started", async () => {
+		const { spawn } = fakeSpawn([], new Error('spawn fish ENOENT'));
+		const result = await runShellInvocation({ file: 'fish', args: [] }, { onOutput: () => undefined }, spawn);
+		expect(result).toEqual({ exitCode: null, errorMessage: "couldn't start fish: spawn fish ENOENT" });
+	});
+
+	it('kills a run that goes past its time limit', async () => {
+		const { spawn, kills } = hangingSpawn();
+		const result = await runShellInvocation({ file: 'bash', args: [] }, { onOutput: () => undefined, timeoutMs: 20 }, spawn);
+		expect(kills).toHaveLength(1);
+		expect(result).toEqual({ exitCode: null, stopped: { reason: 'timeout', afterMs: 20 } });
+	});
+
+	it('kills a run when it is cancelled', async () => {
+		const { spawn, kills } = hangingSpawn();
+		const controller = new AbortController();
+		const running = runShellInvocation({ file: 'bash', args: [] }, { onOutput: () => undefined, signal: controller.signal }, spawn);
+		controller.abort();
+
+		const result = await running;
+		expect(kills).toHaveLength(1);
+		expect(result.stopped?.reason).toBe('cancelled');
+	});
+
+	it('does not start a run that was cancelled already', async () => {
+		const { spawn, calls } = fakeSpawn([], 0);
+		const controller = new AbortController();
+		controller.abort();
+
+		const result = await runShellInvocation({ file: 'bash', args: [] }, { onOutput: () => undefined, signal: controller.signal }, spawn);
+		expect(calls).toHaveLength(0);
+		expect(result.stopped?.reason).toBe('cancelled');
+	});
+});
+
+describe('superviseRun', () => {
+	it('stops watching once the run has ended', async () => {
+		const stops: string[] = [];
+		const stopWatching = superviseRun({ onOutput: () => undefined, timeoutMs: 10 }, (stopped) => { stops.push(stopped.reason); });
+		stopWatching();
+
+		await new Promise(resolve => setTimeout(resolve, 20));
+		expect(stops).toEqual([]);
+	});
+
+	it('does not time out at once when the timeout is longer than a timer can wait', async () => {
+		const stops: string[] = [];
+		const stopWatching = superviseRun({ onOutput: () => undefined, timeoutMs: 3600000000 }, (stopped) => { stops.push(stopped.reason); });
+
+		await new Promise(resolve => setTimeout(resolve, 20));
+		stopWatching();
+		expect(stops).toEqual([]);
+	});
+});
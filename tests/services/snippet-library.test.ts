@@ -0,0 +1,91 @@
+/**
+ * Tests for src/services/snippet-library.ts
+ *
+ * Covers: findSnippets, buildSnippetReference, matchSnippet, SnippetLibrary
+ */
+
+import { describe, it, expect } from 'vitest';
+import { findSnippets, buildSnippetReference, matchSnippet, SnippetLibrary } from '../../src/services/snippet-library';
+
+const RUNBOOK = [
+	'# Bootstrap',
+	'```ufence-bash',
+	'META:',
+	'  ID: install-steps',
+	'  TITLE: Install',
+	'~~~',
+	'ID: not-a-setting',
+	'```',
+	'',
+	'```ufence-python {title="Greet"}',
+	'print("hi")',
+	'```',
+	'^greeting',
+	'',
+	'```ufence-bash',
+	'uptime',
+	'```',
+	'',
+	'```ufence-bash {title=Again ref=install-steps}',
+	'```',
+].join('\n');
+
+describe('findSnippets', () => {
+	it('finds titled blocks with their IDs, skipping untitled blocks and references', () => {
+		const snippets = findSnippets('Runbook.md', RUNBOOK);
+
+		expect(snippets.map(snippet => [snippet.title, snippet.blockType, snippet.id, snippet.line])).toEqual([
+			['Install', 'bash', 'install-steps', 2],
+			['Greet', 'python', 'greeting', 10],
+		]);
+		expect(snippets[0].heading).toBe('Bootstrap');
+		expect(snippets[1].text).toBe('```ufence-python {title="Greet"}\nprint("hi")\n```');
+	});
+
+	it('leaves META.ID out of copies but keeps the code', () => {
+		const [install, greet] = findSnippets('Runbook.md', RUNBOOK);
+
+		expect(install.copyText).toBe('```ufence-bash\nMETA:\n  TITLE: Install\n~~~\nID: not-a-setting\n```');
+		// A ^id line is after the block, so copies never have it
+		expect(greet.copyText).toBe(greet.text);
+	});
+
+	it('drops an id= option from the fence line of copies', () => {
+		const [snippet] = findSnippets('Notes.md', '```ufence-sql {id=report ln title=Report}\nSELECT 1;\n```');
+		expect(snippet.id).toBe('report');
+		expect(snippet.copyText).toBe('```ufence-sql {ln title=Report}\nSELECT 1;\n```');
+	});
+});
+
+describe('buildSnippetReference', () => {
+	it('builds a block showing the snippet by ID', () => {
+		const [install] = findSnippets('Runbook.md', RUNBOOK);
+		expect(buildSnippetReference(install)).toBe('```ufence-bash {ref=install-steps}\n```');
+		expect(buildSnippetReference({ ...install, id: 'two words' })).toBe('```ufence-bash {ref="two words"}\n```');
+	});
+});
+
+describe('matchSnippet', () => {
+	const [install] = findSnippets('Ops/Runbook.md', RUNBOOK);
+
+	it('matches every word against the title, language, path and code', () => {
+		expect(matchSnippet(install, '')).toBe(true);
+		expect(matchSnippet(install, 'install BASH')).toBe(true);
+		expect(matchSnippet(install, 'ops not-a-setting')).toBe(true);
+		expect(matchSnippet(install, 'install python')).toBe(false);
+	});
+});
+
+describe('SnippetLibrary', () => {
+	it('lists snippets by title across notes and forgets removed notes', () => {
+		const library = new SnippetLibrary();
+		library.update('b.md', RUNBOOK);
+		library.update('a.md', '```ufence-bash {title=Install}\nmake\n```');
+		library.update('c.md', 'no blocks');
+
+		expect(library.all().map(snippet => `${snippet.title}@${snippet.path}`)).toEqual(['Greet@b.md', 'Install@a.md', 'Install@b.md']);
+
+		library.remove('b.md');
+		expect(library.all().map(snippet => snippet.path)).toEqual(['a.md']);
+	});
+});
@@ -2,7 +2,9 @@
  * Tests for src/services/source-loader.ts
  *
  * Covers the pure functions: detectSourceLocationType, isRemotePath,
- * isVaultPath, extractFileMetadata, createEmbeddedCodeMetadata
+ * isVaultPath, extractFileMetadata, createEmbeddedCodeMetadata,
+ * normalizeGitRepositories, gitRepositoryPaths, parseGitSourcePath; and loadGitFile's checks
+ * before it runs git
  */
 
 import { describe, it, expect } from 'vitest';
@@ -12,6 +14,10 @@ import {
 	isVaultPath,
 	extractFileMetadata,
 	createEmbeddedCodeMetadata,
+	normalizeGitRepositories,
+	gitRepositoryPaths,
+	parseGitSourcePath,
+	loadGitFile,
 } from '../../src/services/source-loader';
 
 // =============================================================================
@@ -39,12 +45,17 @@ describe('detectSourceLocationType', () => {
 		expect(detectSourceLocationType('http://example.com/file.ts')).toBe('remote');
 	});
 
+	it('returns "git" for repository:path@ref paths', () => {
+		expect(detectSourceLocationType('tools:bin/install.sh@v1.4.2')).toBe('git');
+	});
+
 	it('returns "invalid" for bare file paths', () => {
 		expect(detectSourceLocationType('src/main.ts')).toBe('invalid');
 	});
 
 	it('returns "invalid" for other schemes', () => {
 		expect(detectSourceLocationType('ftp://server/file')).toBe('invalid');
+		expect(detectSourceLocationType('git://tools/bin/install.sh@v1.4.2')).toBe('invalid');
 	});
 });
 
@@ -250,3 +261,56 @@ describe('createEmbeddedCodeMetadata', () => {
 		expect(meta.sizeInBytes).toBeUndefined();
 	});
 });
+
+// =============================================================================
+// Git sources
+// =============================================================================
+
+describe('normalizeGitRepositories', () => {
+	it('moves the earlier name=path text to one entry per repository, skipping malformed entries', () => {
+		expect(normalizeGitRepositories('tools = /home/me/src/tools, docs=C:\\src\\docs, broken')).toEqual([
+			{ name: 'tools', path: '/home/me/src/tools' },
+			{ name: 'docs', path: 'C:\\src\\docs' },
+		]);
+		expect(normalizeGitRepositories('')).toEqual([]);
+		expect(normalizeGitRepositories(undefined)).toEqual([]);
+	});
+
+	it('copies a stored list', () => {
+		const stored = [{ name: 'tools', path: '/src/a,b' }];
+		const repositories = normalizeGitRepositories(stored);
+		expect(repositories).toEqual(stored);
+		expect(repositories[0]).not.toBe(stored[0]);
+	});
+});
+
+describe('gitRepositoryPaths', () => {
+	it('maps names to paths, skipping empty rows and keeping the first of a name', () => {
+		expect(gitRepositoryPaths([
+			{ name: ' tools ', path: '/src/tools, old' },
+			{ name: '', path: '/src/nameless' },
+			{ name: 'docs', path: ' ' },
+			{ name: 'tools', path: '/src/other' },
+		])).toEqual({ tools: '/src/tools, old' });
+	});
+});
+
+describe('parseGitSourcePath', () => {
+	it('splits the repository, file and ref', () => {
+		expect(parseGitSourcePath('tools:bin/install.sh@v1.4.2')).toEqual({ repository: 'tools', file: 'bin/install.sh', ref: 'v1.4.2' });
+		expect(parseGitSourcePath('tools:a@b/c.sh@HEAD~2')).toEqual({ repository: 'tools', file: 'a@b/c.sh', ref: 'HEAD~2' });
+	});
+
+	it('returns null without a ref, or for a ref that could be read as an option', () => {
+		expect(parseGitSourcePath('tools:bin/install.sh')).toBeNull();
+		expect(parseGitSourcePath('tools:bin/install.sh@--output=x')).toBeNull();
+		expect(parseGitSourcePath('tools:@v1')).toBeNull();
+	});
+});
+
+describe('loadGitFile', () => {
+	it('fails for a malformed path or an unknown repository', async () => {
+		expect((await loadGitFile('tools:install.sh', {})).errorMessage).toBe("invalid git source 'tools:install.sh', use repository:path@ref");
+		expect((await loadGitFile('tools:install.sh@v1', { docs: '/src/docs' })).errorMessage).toBe("no git repository named 'tools' in the settings");
+	});
+});
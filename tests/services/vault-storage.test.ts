@@ -0,0 +1,85 @@
+/**
+ * Tests for src/services/vault-storage.ts
+ *
+ * Covers: vaultStorageKey, loadVaultLocalStorage, saveVaultLocalStorage
+ */
+
+import { describe, it, expect, vi, afterEach } from 'vitest';
+import type { App } from 'obsidian';
+import { vaultStorageKey, loadVaultLocalStorage, saveVaultLocalStorage } from '../../src/services/vault-storage';
+
+/**
+ * An app for a vault of a name, with or without the app's own local
+ * storage.
+ */
+function fakeApp(vaultName: string, withAppStorage: boolean): { app: App; storage: Map<string, unknown> } {
+	const storage = new Map<string, unknown>();
+	const app = {
+		vault: { getName: () => vaultName },
+		...(withAppStorage ? {
+			loadLocalStorage: (key: string) => storage.get(key) ?? null,
+			saveLocalStorage: (key: string, value: unknown) => {
+				if (value === null) {
+					storage.delete(key);
+				} else {
+					storage.set(key, value);
+				}
+			},
+		} : {}),
+	} as unknown as App;
+	return { app, storage };
+}
+
+/**
+ * Puts a window with a map-backed localStorage in place.
+ */
+function stubWindowStorage(): Map<string, string> {
+	const items = new Map<string, string>();
+	vi.stubGlobal('window', {
+		localStorage: {
+			getItem: (key: string) => items.get(key) ?? null,
+			setItem: (key: string, value: string) => { items.set(key, value); },
+			removeItem: (key: string) => { items.delete(key); },
+		},
+	});
+	return items;
+}
+
+afterEach(() => {
+	vi.unstubAllGlobals();
+});
+
+describe('loadVaultLocalStorage / saveVaultLocalStorage', () => {
+	it("uses the app's local storage when it has one", () => {
+		const items = stubWindowStorage();
+		const { app, storage } = fakeApp('Work', true);
+
+		saveVaultLocalStorage(app, 'ucf-test', { a: 1 });
+		expect(storage.get('ucf-test')).toEqual({ a: 1 });
+		expect(loadVaultLocalStorage(app, 'ucf-test')).toEqual({ a: 1 });
+		expect(items.size).toBe(0);
+	});
+
+	it("falls back to the window's local storage under a key naming the vault", () => {
+		const items = stubWindowStorage();
+		const { app: work } = fakeApp('Work', false);
+		const { app: home } = fakeApp('Home', false);
+
+		saveVaultLocalStorage(work, 'ucf-test', { a: 1 });
+		expect(vaultStorageKey(work, 'ucf-test')).toBe('ucf-test-Work');
+		expect(items.get('ucf-test-Work')).toBe('{"a":1}');
+		expect(loadVaultLocalStorage(work, 'ucf-test')).toEqual({ a: 1 });
+		expect(loadVaultLocalStorage(home, 'ucf-test')).toBeNull();
+
+		saveVaultLocalStorage(work, 'ucf-test', null);
+		expect(items.has('ucf-test-Work')).toBe(false);
+		expect(loadVaultLocalStorage(work, 'ucf-test')).toBeNull();
+	});
+
+	it('reads a value that is not JSON as missing', () => {
+		const items = stubWindowStorage();
+		const { app } = fakeApp('Work', false);
+		items.set('ucf-test-Work', '{broken');
+		expect(loadVaultLocalStorage(app, 'ucf-test')).toBeNull();
+	});
+});
@@ -0,0 +1,102 @@
+/**
+ * Tests for src/services/vscode-theme.ts
+ *
+ * Covers: parseJsonWithComments, vscodeSettingsToStyle, convertVSCodeTheme,
+ * highlightThemeKey
+ */
+
+import { describe, it, expect } from 'vitest';
+import {
+	parseJsonWithComments,
+	vscodeSettingsToStyle,
+	convertVSCodeTheme,
+	highlightThemeKey,
+} from '../../src/services/vscode-theme';
+
+describe('parseJsonWithComments', () => {
+	it('drops comments and trailing commas outside strings', () => {
+		const text = `{
+			// editor colours
+			"colors": { "editor.background": "#1e1e1e", },
+			/* token rules */
+			"name": "a // b, /* c */ ]",
+			"list": [1, 2,],
+		}`;
+
+		expect(parseJsonWithComments(text)).toEqual({
+			colors: { 'editor.background': '#1e1e1e' },
+			name: 'a // b, /* c */ ]',
+			list: [1, 2],
+		});
+	});
+
+	it('keeps escaped quotes inside strings', () => {
+		expect(parseJsonWithComments('{"a": "say \\"hi\\" // not a comment"}')).toEqual({ a: 'say "hi" // not a comment' });
+	});
+});
+
+describe('vscodeSettingsToStyle', () => {
+	it('turns font styles and colours into TOKENS words', () => {
+		expect(vscodeSettingsToStyle({ foreground: '#C678DD', fontStyle: 'bold italic' })).toBe('bold italic #C678DD');
+		expect(vscodeSettingsToStyle({ foreground: '#5c637080', background: '#000' })).toBe('#5c637080 on #000');
+	});
+
+	it('reads an empty font style as normal and ignores bad colours', () => {
+		expect(vscodeSettingsToStyle({ fontStyle: '', foreground: 'red' })).toBe('normal');
+		expect(vscodeSettingsToStyle({})).toBe('');
+	});
+});
+
+describe('convertVSCodeTheme', () => {
+	const theme = JSON.stringify({
+		name: 'One Dark Pro',
+		type: 'dark',
+		colors: { 'editor.background': '#282c34', 'editor.foreground': '#abb2bf' },
+		tokenColors: [
+			{ scope: ['comment', 'punctuation.definition.comment'], settings: { foreground: '#5c6370', fontStyle: 'italic' } },
+			{ scope: 'comment.block.documentation', settings: { foreground: '#7f848e' } },
+			{ scope: 'keyword.control, storage.type', settings: { foreground: '#c678dd' } },
+			{ scope: 'keyword.operator', settings: { foreground: '#56b6c2' } },
+			{ scope: 'meta.tag string', settings: { foreground: '#ffffff' } },
+			{ scope: 'markup.heading', settings: {} },
+		],
+	});
+
+	it('maps each rule to the Prism tokens its scopes stand for', () => {
+		expect(convertVSCodeTheme(theme, 'file')).toEqual({
+			name: 'One Dark Pro',
+			background: '#282c34',
+			foreground: '#abb2bf',
+			tokens: {
+				comment: 'italic #5c6370',
+				punctuation: 'italic #5c6370',
+				keyword: '#c678dd',
+				operator: '#56b6c2',
+			},
+		});
+	});
+
+	it('reads editor colours from an unscoped rule and names the theme after the file', () => {
+		const result = convertVSCodeTheme(JSON.stringify({
+			tokenColors: [
+				{ settings: { background: '#fdf6e3', foreground: '#657b83' } },
+				{ scope: 'string', settings: { foreground: '#2aa198' } },
+			],
+		}), 'solarized-light');
+
+		expect(result.name).toBe('solarized-light');
+		expect(result.background).toBe('#fdf6e3');
+		expect(result.tokens).toEqual({ string: '#2aa198' });
+	});
+
+	it('rejects files that are not colour themes', () => {
+		expect(() => convertVSCodeTheme('[]', 'x')).toThrow('Theme must be a JSON object');
+		expect(() => convertVSCodeTheme('{"name": "x"}', 'x')).toThrow('Not a VS Code colour theme');
+	});
+});
+
+describe('highlightThemeKey', () => {
+	it('lower-cases and trims theme names', () => {
+		expect(highlightThemeKey(' One Dark Pro ')).toBe('one dark pro');
+	});
+});
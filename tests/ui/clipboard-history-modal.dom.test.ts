@@ -0,0 +1,59 @@
+// @vitest-environment jsdom
+
+/**
+ * Tests for src/ui/clipboard-history-modal.ts
+ *
+ * Covers:
+ * - ClipboardHistoryModal filtering, rendering and selection
+ */
+
+import { describe, it, expect, vi, beforeEach } from 'vitest';
+import { setupObsidianDom, App } from '../../__mocks__/obsidian';
+import { ClipboardHistoryModal } from '../../src/ui/clipboard-history-modal';
+import type { ClipboardHistoryEntry } from '../../src/types';
+
+const ENTRIES: ClipboardHistoryEntry[] = [
+	{ text: 'make deploy', source: 'Deploy', copiedAt: Date.now() },
+	{ text: '\nsudo apt update\nsudo apt upgrade', source: 'Server setup', copiedAt: Date.now() },
+];
+
+describe('ClipboardHistoryModal', () => {
+	let app: App;
+
+	beforeEach(() => {
+		setupObsidianDom();
+		app = new App();
+	});
+
+	it('lists every entry for an empty query', () => {
+		const modal = new ClipboardHistoryModal(app, ENTRIES, vi.fn());
+		expect(modal.getSuggestions('')).toEqual(ENTRIES);
+	});
+
+	it('filters by text or source, case-insensitively', () => {
+		const modal = new ClipboardHistoryModal(app, ENTRIES, vi.fn());
+
+		expect(modal.getSuggestions('APT')).toEqual([ENTRIES[1]]);
+		expect(modal.getSuggestions('deploy')).toEqual([ENTRIES[0]]);
+		expect(modal.getSuggestions('nothing')).toEqual([]);
+	});
+
+	it('renders the first non-blank line with source, line count and age', () => {
+		const modal = new ClipboardHistoryModal(app, ENTRIES, vi.fn());
+		const element = document.createElement('div');
+
+		modal.renderSuggestion(ENTRIES[1], element);
+
+		expect(element.querySelector('.ucf-history-text')?.textContent).toBe('sudo apt update');
+		expect(element.querySelector('.ucf-history-details')?.textContent).toBe('Server setup · 3 lines · just now');
+	});
+
+	it('passes the chosen entry to the callback', () => {
+		const onChoose = vi.fn();
+		const modal = new ClipboardHistoryModal(app, ENTRIES, onChoose);
+
+		modal.onChooseSuggestion(ENTRIES[0]);
+
+		expect(onChoose).toHaveBeenCalledWith(ENTRIES[0]);
+	});
+});
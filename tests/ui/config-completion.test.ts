@@ -0,0 +1,115 @@
+/**
+ * Tests for settings autocomplete.
+ */
+
+import { describe, it, expect } from 'vitest';
+import { getConfigCompletions } from '../../src/ui/config-completion';
+
+/**
+ * Gets completions with the cursor at the end of the last line.
+ */
+function completeAtEnd(lines: string[], presetNames: string[] = []) {
+	const cursorLine = lines.length - 1;
+	return getConfigCompletions(lines, cursorLine, lines[cursorLine].length, presetNames);
+}
+
+/** Labels of the completions at the end of the last line. */
+function labelsAtEnd(lines: string[], presetNames: string[] = []): string[] | undefined {
+	return completeAtEnd(lines, presetNames)?.completions.map(c => c.label);
+}
+
+describe('getConfigCompletions — keys', () => {
+	it('offers top-level sections', () => {
+		const result = completeAtEnd(['```ufence-bash', 'HI']);
+		expect(result?.kind).toBe('key');
+		expect(result?.startCh).toBe(0);
+		expect(result?.completions).toEqual([
+			{ label: 'HIGHLIGHT', detail: 'a section', insertText: 'HIGHLIGHT:' },
+		]);
+	});
+
+	it('offers keys of the enclosing section with their types', () => {
+		const result = completeAtEnd(['```ufence-bash', 'RENDER:', '  FOLD: 10', '  LINE']);
+		expect(result?.startCh).toBe(2);
+		expect(result?.completions).toEqual([
+			{ label: 'LINES', detail: 'true or false', insertText: 'LINES: ' },
+			{ label: 'LINE_COPY', detail: 'true or false', insertText: 'LINE_COPY: ' },
+			{ label: 'LINE_START', detail: 'a number', insertText: 'LINE_START: ' },
+			{ label: 'LINE_ANCHOR', detail: 'a number', insertText: 'LINE_ANCHOR: ' },
+		]);
+	});
+
+	it('matches case-insensitively', () => {
+		expect(labelsAtEnd(['```ufence-bash', 'META:', '  ti'])).toEqual(['TITLE']);
+	});
+
+	it('offers keys of nested sections', () => {
+		expect(labelsAtEnd(['```ufence-bash', 'FILTER:', '  BY_MARKS:', '    S'])).toEqual(['START']);
+	});
+
+	it('offers entry keys inside a list item', () => {
+		expect(labelsAtEnd(['```ufence-bash', 'COPY:', '  AS:', '    - FO'])).toEqual(['FORMAT']);
+		expect(labelsAtEnd(['```ufence-bash', 'COPY:', '  AS:', '    - FORMAT: heredoc', '      LA'])).toEqual(['LABEL']);
+	});
+
+	it('accepts list items at the same indent as their key', () => {
+		expect(labelsAtEnd(['```ufence-bash', 'CALLOUT:', '  ENTRIES:', '  - LINE: 3', '    TE'])).toEqual(['TEXT']);
+	});
+
+	it('uses the cmdout RENDER keys in cmdout blocks', () => {
+		expect(labelsAtEnd(['```ufence-cmdout', 'RENDER:', '  O'])).toEqual(['OUTPUT']);
+		expect(labelsAtEnd(['```ufence-cmdout', 'RENDER:', '  OUTPUT:', '    B'])).toEqual(['BOLD']);
+	});
+
+	it('offers nothing under a key that has no sub-keys', () => {
+		expect(completeAtEnd(['```ufence-bash', 'PROMPT:', '  X'])).toBeNull();
+	});
+});
+
+describe('getConfigCompletions — values', () => {
+	it('offers true and false for boolean keys', () => {
+		const result = completeAtEnd(['```ufence-bash', 'RENDER:', '  ZEBRA: ']);
+		expect(result?.kind).toBe('value');
+		expect(result?.startCh).toBe(9);
+		expect(result?.completions.map(c => c.insertText)).toEqual(['true', 'false']);
+	});
+
+	it('offers known values filtered by what was typed', () => {
+		expect(labelsAtEnd(['```ufence-bash', 'RENDER:', '  STYLE: "in'])).toEqual(['integrated', 'infobar']);
+		expect(labelsAtEnd(['```ufence-bash', 'CALLOUT:', '  STYLE: '])).toEqual(['standard', 'border']);
+		expect(labelsAtEnd(['```ufence-bash', 'COPY:', '  AS:', '    - FORMAT: rich'])).toEqual(['richtext']);
+	});
+
+	it('offers preset names for META.PRESET', () => {
+		const result = completeAtEnd(['```ufence-bash', 'META:', '  PRESET: t'], ['teaching', 'demo']);
+		expect(result?.completions).toEqual([{ label: 'teaching', detail: 'preset', insertText: 'teaching' }]);
+		expect(result?.startCh).toBe(10);
+	});
+
+	it('offers nothing for free text keys', () => {
+		expect(labelsAtEnd(['```ufence-bash', 'META:', '  TITLE: '])).toEqual([]);
+	});
+});
+
+describe('getConfigCompletions — outside settings', () => {
+	it('returns null outside ufence blocks', () => {
+		expect(completeAtEnd(['Some text', 'RE'])).toBeNull();
+		expect(completeAtEnd(['```python', 'RE'])).toBeNull();
+	});
+
+	it('returns null in the code after a ~~~ separator', () => {
+		expect(completeAtEnd(['```ufence-bash', 'RENDER:', '  LINES: true', '~~~', 'RE'])).toBeNull();
+	});
+
+	it('returns null after the block has closed', () => {
+		expect(completeAtEnd(['```ufence-bash', 'META:', '  PATH: x', '```', 'RE'])).toBeNull();
+	});
+
+	it('returns null for TOML settings', () => {
+		expect(completeAtEnd(['```ufence-bash', '[RENDER]', 'LI'])).toBeNull();
+	});
+
+	it('returns null for keys under an unknown section', () => {
+		expect(completeAtEnd(['```ufence-bash', 'SETTINGS:', '  LI'])).toBeNull();
+	});
+});
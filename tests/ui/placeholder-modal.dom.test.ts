@@ -0,0 +1,114 @@
+// @vitest-environment jsdom
+
+/**
+ * Tests for src/ui/placeholder-modal.ts
+ *
+ * Covers:
+ * - PlaceholderModal form rendering, submit and cancel
+ * - promptForPlaceholders() substitution and short-circuit
+ */
+
+import { describe, it, expect, vi, beforeEach, afterEach } from 'vitest';
+import { setupObsidianDom, App } from '../../__mocks__/obsidian';
+import { PlaceholderModal, promptForPlaceholders } from '../../src/ui/placeholder-modal';
+
+describe('PlaceholderModal', () => {
+	let app: App;
+
+	beforeEach(() => {
+		setupObsidianDom();
+		app = new App();
+	});
+
+	it('renders one input per placeholder', () => {
+		const modal = new PlaceholderModal(app, ['USER', 'HOST'], vi.fn());
+		modal.onOpen();
+
+		const labels = Array.from(modal.contentEl.querySelectorAll('.ucf-placeholder-field span')).map(el => el.textContent);
+		expect(labels).toEqual(['USER', 'HOST']);
+		expect(modal.contentEl.querySelectorAll('input')).toHaveLength(2);
+	});
+
+	it('passes entered values to the callback on submit', () => {
+		const onDone = vi.fn();
+		const modal = new PlaceholderModal(app, ['USER'], onDone);
+		modal.onOpen();
+
+		(modal.contentEl.querySelector('input') as HTMLInputElement).value = 'admin';
+		(modal.contentEl.querySelector('button.mod-cta') as HTMLButtonElement).click();
+
+		expect(onDone).toHaveBeenCalledWith({ USER: 'admin' });
+	});
+
+	it('submits when Enter is pressed in a field', () => {
+		const onDone = vi.fn();
+		const modal = new PlaceholderModal(app, ['PORT'], onDone);
+		modal.onOpen();
+
+		const input = modal.contentEl.querySelector('input') as HTMLInputElement;
+		input.value = '8080';
+		input.dispatchEvent(new KeyboardEvent('keydown', { key: 'Enter' }));
+
+		expect(onDone).toHaveBeenCalledWith({ PORT: '8080' });
+	});
+
+	it('reports null when closed without submitting', () => {
+		const onDone = vi.fn();
+		const modal = new PlaceholderModal(app, ['USER'], onDone);
+		modal.onOpen();
+		modal.onClose();
+
+		expect(onDone).toHaveBeenCalledWith(null);
+	});
+
+	it('pre-fills values entered earlier in the session', () => {
+		const first = new PlaceholderModal(app, ['REGION'], vi.fn());
+		first.onOpen();
+		(first.contentEl.querySelector('input') as HTMLInputElement).value = 'eu-west-1';
+		(first.contentEl.querySelector('button.mod-cta') as HTMLButtonElement).click();
+
+		const second = new PlaceholderModal(app, ['REGION'], vi.fn());
+		second.onOpen();
+
+		expect((second.contentEl.querySelector('input') as HTMLInputElement).value).toBe('eu-west-1');
+	});
+});
+
+describe('promptForPlaceholders', () => {
+	let app: App;
+
+	beforeEach(() => {
+		setupObsidianDom();
+		app = new App();
+	});
+
+	afterEach(() => {
+		vi.restoreAllMocks();
+	});
+
+	it('returns text without placeholders unchanged, without opening a modal', async () => {
+		const openSpy = vi.spyOn(PlaceholderModal.prototype, 'open');
+
+		expect(await promptForPlaceholders(app, 'echo hi')).toBe('echo hi');
+		expect(openSpy).not.toHaveBeenCalled();
+	});
+
+	it('substitutes the values entered in the modal', async () => {
+		vi.spyOn(PlaceholderModal.prototype, 'open').mockImplementation(function (this: PlaceholderModal) {
+			this.onOpen();
+			(this.contentEl.querySelector('input') as HTMLInputElement).value = 'example.com';
+			(this.contentEl.querySelector('button.mod-cta') as HTMLButtonElement).click();
+		});
+
+		expect(await promptForPlaceholders(app, 'ping {{HOST}}')).toBe('ping example.com');
+	});
+
+	it('resolves null when the modal is cancelled', async () => {
+		vi.spyOn(PlaceholderModal.prototype, 'open').mockImplementation(function (this: PlaceholderModal) {
+			this.onOpen();
+			this.onClose();
+		});
+
+		expect(await promptForPlaceholders(app, 'ping {{HOST}}')).toBeNull();
+	});
+});
@@ -685,3 +685,45 @@ describe('Settings persistence', () => {
 		expect(plugin.settings.defaultTitleBarStyle).toBeDefined();
 	});
 });
+
+// =============================================================================
+// Tests: Preset preview
+// =============================================================================
+
+describe('Presets tab — preview', () => {
+	beforeEach(() => {
+		vi.useFakeTimers();
+	});
+
+	afterEach(() => {
+		vi.useRealTimers();
+	});
+
+	function openPresetsTab(presetPlugin: SettingsPlugin): UltraCodeFenceSettingTab {
+		const presetTab = new UltraCodeFenceSettingTab(app, presetPlugin, testReleaseNotes);
+		presetTab.display();
+		const buttons = presetTab.containerEl.querySelector('.ucf-tabs')?.querySelectorAll('button');
+		buttons?.[6]?.click();
+		return presetTab;
+	}
+
+	it('renders a preview for each saved preset and the new preset editor', () => {
+		const renderPresetPreview = vi.fn(async () => {});
+		const presetTab = openPresetsTab({
+			...createMockPlugin({ presets: { teaching: 'RENDER:\n  LINES: true' } }),
+			renderPresetPreview,
+		});
+
+		expect(presetTab.containerEl.querySelectorAll('.ucf-preset-preview').length).toBe(2);
+		expect(renderPresetPreview).not.toHaveBeenCalled();
+
+		vi.runAllTimers();
+		expect(renderPresetPreview).toHaveBeenCalledWith(expect.any(HTMLElement), 'RENDER:\n  LINES: true', 'teaching');
+		expect(renderPresetPreview).toHaveBeenCalledWith(expect.any(HTMLElement), '', undefined);
+	});
+
+	it('leaves the preview out when the plugin cannot render one', () => {
+		const presetTab = openPresetsTab(createMockPlugin({ presets: { teaching: 'RENDER:\n  LINES: true' } }));
+		expect(presetTab.containerEl.querySelectorAll('.ucf-preset-preview').length).toBe(0);
+	});
+});
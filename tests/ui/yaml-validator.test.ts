@@ -3,7 +3,7 @@
  */
 
 import { describe, it, expect } from 'vitest';
-import { validateYamlSchema, formatWarnings } from '../../src/ui/yaml-validator';
+import { validateYamlSchema, formatWarning, formatWarnings, CMDOUT_BLOCK_SCHEMA, PRESET_SCHEMA } from '../../src/ui/yaml-validator';
 
 describe('validateYamlSchema', () => {
 	// =================================================================
@@ -188,6 +188,42 @@ describe('validateYamlSchema', () => {
 		expect(warnings[1].path).toBe('CALLOUT.ENTRIES[1].ICON');
 	});
 
+	it('accepts valid COPY.AS items', () => {
+		const parsed = {
+			COPY: {
+				AS: [
+					{ LABEL: 'Docker', FORMAT: 'dockerfile' },
+					{ FORMAT: 'makefile', TARGET: 'install' },
+					{ FORMAT: 'crontab', SCHEDULE: '0 3 * * *' },
+				],
+			},
+		};
+		expect(validateYamlSchema(parsed)).toEqual([]);
+	});
+
+	it('accepts HIGHLIGHT.LINES and flags unknown HIGHLIGHT keys', () => {
+		expect(validateYamlSchema({ HIGHLIGHT: { LINES: '3-5' } })).toEqual([]);
+
+		const paths = validateYamlSchema({ HIGHLIGHT: { LINES: '3-5', COLOUR: 'red' } }).map(w => w.path);
+		expect(paths).toEqual(['HIGHLIGHT.COLOUR']);
+	});
+
+	it('accepts valid DOWNLOAD keys', () => {
+		const parsed = { DOWNLOAD: { FILENAME: '{basename}.sh', SHEBANG: true, EXECUTABLE: true } };
+		expect(validateYamlSchema(parsed)).toEqual([]);
+	});
+
+	it('flags unknown COPY keys and COPY.AS item keys', () => {
+		const parsed = {
+			COPY: {
+				AS: [{ FORMAT: 'dockerfile', IMAGE: 'alpine' }],
+				MODE: 'raw',
+			},
+		};
+		const paths = validateYamlSchema(parsed).map(w => w.path);
+		expect(paths).toEqual(['COPY.AS[0].IMAGE', 'COPY.MODE']);
+	});
+
 	// =================================================================
 	// Multiple errors at once
 	// =================================================================
@@ -217,6 +253,158 @@ describe('validateYamlSchema', () => {
 		// — this is a structural issue the YAML validator handles, not the schema checker
 		expect(validateYamlSchema(parsed)).toEqual([]);
 	});
+
+	it('accepts a top-level PROMPT', () => {
+		expect(validateYamlSchema({ PROMPT: '^\\$ ' })).toEqual([]);
+	});
+
+	// =================================================================
+	// Did-you-mean suggestions
+	// =================================================================
+
+	it('suggests the nearest section for a misspelt top-level key', () => {
+		const warnings = validateYamlSchema({ higlight: { LINES: '3' } });
+		expect(warnings).toEqual([
+			{ path: 'higlight', key: 'higlight', suggestion: 'HIGHLIGHT', expectedType: 'a section' },
+		]);
+	});
+
+	it('suggests the nearest key with its expected type', () => {
+		const [warning] = validateYamlSchema({ RENDER: { LINSE: true } });
+		expect(warning.suggestion).toBe('LINES');
+		expect(warning.expectedType).toBe('true or false');
+	});
+
+	it('suggests the uppercase key for a lowercase one', () => {
+		const [warning] = validateYamlSchema({ COPY: { feedback: false } });
+		expect(warning.path).toBe('COPY.feedback');
+		expect(warning.suggestion).toBe('FEEDBACK');
+	});
+
+	it('does not suggest keys that are too different', () => {
+		const [warning] = validateYamlSchema({ RENDER: { NUMBER: true } });
+		expect(warning.suggestion).toBeUndefined();
+	});
+
+	it('suggests keys inside list entries', () => {
+		const [warning] = validateYamlSchema({ COPY: { AS: [{ FORMT: 'makefile' }] } });
+		expect(warning.path).toBe('COPY.AS[0].FORMT');
+		expect(warning.suggestion).toBe('FORMAT');
+	});
+
+	// =================================================================
+	// Value types
+	// =================================================================
+
+	it('flags values of the wrong type', () => {
+		const warnings = validateYamlSchema({ RENDER: { FOLD: 'abc', ZEBRA: 'yes' } });
+		expect(warnings).toEqual([
+			{ path: 'RENDER.FOLD', key: 'FOLD', expectedType: 'a number', wrongType: true },
+			{ path: 'RENDER.ZEBRA', key: 'ZEBRA', expectedType: 'true or false', wrongType: true },
+		]);
+	});
+
+	it('accepts string forms of numbers and booleans', () => {
+		expect(validateYamlSchema({ RENDER: { FOLD: '20', LINES: 'true' } })).toEqual([]);
+	});
+
+	it('flags a mapping where text is expected', () => {
+		const paths = validateYamlSchema({ META: { TITLE: { TEXT: 'x' } } }).map(w => w.path);
+		expect(paths).toEqual(['META.TITLE']);
+	});
+
+	it('flags entries that are not a list', () => {
+		const [warning] = validateYamlSchema({ CALLOUT: { ENTRIES: { LINE: 3 } } });
+		expect(warning.path).toBe('CALLOUT.ENTRIES');
+		expect(warning.wrongType).toBe(true);
+	});
+
+	it('ignores empty values', () => {
+		expect(validateYamlSchema({ RENDER: { FOLD: null }, META: null })).toEqual([]);
+	});
+
+	// =================================================================
+	// Command output schema
+	// =================================================================
+
+	it('validates cmdout RENDER text styles', () => {
+		const parsed = {
+			PROMPT: '^\\$ ',
+			RENDER: { COMMAND: { COLOUR: '#fff', BOLD: true }, OUTPUT: { ITALICS: true } },
+		};
+		const warnings = validateYamlSchema(parsed, CMDOUT_BLOCK_SCHEMA);
+		expect(warnings).toHaveLength(1);
+		expect(warnings[0].path).toBe('RENDER.OUTPUT.ITALICS');
+		expect(warnings[0].suggestion).toBe('ITALIC');
+	});
+
+	it('accepts shared RENDER options and flags code-only ones in a cmdout block', () => {
+		const parsed = { RENDER: { SCROLL: 10, COPY: false, PRINT: 'expand', LINES: true } };
+		const paths = validateYamlSchema(parsed, CMDOUT_BLOCK_SCHEMA).map(w => w.path);
+		expect(paths).toEqual(['RENDER.LINES']);
+	});
+
+	it('flags a renamed section and checks it under its new name', () => {
+		const warnings = validateYamlSchema({ DISPLAY: { LINES: true, FOLD: 'ten' } });
+		expect(warnings).toHaveLength(2);
+		expect(warnings[0]).toEqual({ path: 'DISPLAY', key: 'DISPLAY', renamedTo: 'RENDER' });
+		expect(warnings[1].path).toBe('DISPLAY.FOLD');
+		expect(warnings[1].wrongType).toBe(true);
+	});
+
+	it('ignores top-level scratch keys that hold anchors', () => {
+		expect(validateYamlSchema({ _colours: { COLOUR: '#888' }, RENDER: { LINES: true } })).toEqual([]);
+	});
+
+	it('still checks keys starting with _ below the top level', () => {
+		const paths = validateYamlSchema({ RENDER: { _LINES: true } }).map(w => w.path);
+		expect(paths).toEqual(['RENDER._LINES']);
+	});
+
+	it('accepts EXTENDS in presets but not in blocks', () => {
+		const parsed = { EXTENDS: ['base', 'bash'], RENDER: { LINES: true } };
+		expect(validateYamlSchema(parsed, PRESET_SCHEMA)).toEqual([]);
+		expect(validateYamlSchema(parsed).map(w => w.path)).toEqual(['EXTENDS']);
+	});
+
+	it('accepts APPLIES_TO_TAGS in presets but not in blocks', () => {
+		const parsed = { APPLIES_TO_TAGS: ['#runbook'], RENDER: { LINES: true } };
+		expect(validateYamlSchema(parsed, PRESET_SCHEMA)).toEqual([]);
+		expect(validateYamlSchema(parsed).map(w => w.path)).toEqual(['APPLIES_TO_TAGS']);
+	});
+
+	it('checks WHEN entries in presets', () => {
+		const parsed = { WHEN: { dark: { RENDER: { ZEBRA: false } }, 'dark mobile': { RENDER: { FOLD: 'x' } }, night: {} } };
+		const paths = validateYamlSchema(parsed, PRESET_SCHEMA).map(w => w.path);
+		expect(paths).toEqual(['WHEN.dark mobile.RENDER.FOLD', 'WHEN.night']);
+	});
+
+	it('skips type checks for declared preset parameters', () => {
+		const parsed = { PARAMS: { lines: 10 }, RENDER: { FOLD: '${lines}', SCROLL: '${other}' } };
+		const paths = validateYamlSchema(parsed, PRESET_SCHEMA).map(w => w.path);
+		expect(paths).toEqual(['RENDER.SCROLL']);
+	});
+});
+
+describe('formatWarning', () => {
+	it('formats a plain unknown key', () => {
+		expect(formatWarning({ path: 'RENDER.NUMBER', key: 'NUMBER' })).toBe('Unknown key: RENDER.NUMBER');
+	});
+
+	it('formats a suggestion with its type', () => {
+		expect(formatWarning({ path: 'higlight', key: 'higlight', suggestion: 'HIGHLIGHT', expectedType: 'a section' }))
+			.toBe('Unknown key: higlight — did you mean HIGHLIGHT (a section)?');
+	});
+
+	it('formats a value of the wrong type', () => {
+		expect(formatWarning({ path: 'RENDER.FOLD', key: 'FOLD', expectedType: 'a number', wrongType: true }))
+			.toBe('RENDER.FOLD should be a number');
+	});
+
+	it('formats a renamed key', () => {
+		expect(formatWarning({ path: 'STYLE', key: 'STYLE', renamedTo: 'RENDER' }))
+			.toBe('STYLE was renamed to RENDER');
+	});
 });
 
 describe('formatWarnings', () => {
@@ -236,4 +424,12 @@ describe('formatWarnings', () => {
 		]);
 		expect(result).toBe('Unknown keys: NUMBER, ZEBRA');
 	});
+
+	it('writes out each warning when any has a suggestion', () => {
+		const result = formatWarnings([
+			{ path: 'RENDER.LINSE', key: 'LINSE', suggestion: 'LINES', expectedType: 'true or false' },
+			{ path: 'ZEBRA', key: 'ZEBRA' },
+		]);
+		expect(result).toBe('Unknown key: RENDER.LINSE — did you mean LINES (true or false)?; Unknown key: ZEBRA');
+	});
 });
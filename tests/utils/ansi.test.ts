@@ -0,0 +1,102 @@
+/**
+ * Tests for src/utils/ansi.ts
+ *
+ * Covers: hasAnsiCodes, stripAnsi, ansiPaletteColour, applySgrCodes,
+ *         parseAnsiLine, ansiStyleToCss
+ */
+
+import { describe, it, expect } from 'vitest';
+import {
+	createAnsiStyle,
+	hasAnsiCodes,
+	stripAnsi,
+	ansiPaletteColour,
+	applySgrCodes,
+	parseAnsiLine,
+	ansiStyleToCss,
+} from '../../src/utils/ansi';
+
+describe('stripAnsi', () => {
+	it('removes colour codes', () => {
+		expect(stripAnsi('\x1b[32mPASS\x1b[0m tests/app.test.ts')).toBe('PASS tests/app.test.ts');
+	});
+
+	it('removes codes written out as text', () => {
+		expect(stripAnsi('\\x1b[31mred\\033[0m \\e[1mbold\\u001b[0m ^[[4mline^[[0m')).toBe('red bold line');
+	});
+
+	it('removes cursor movement and window titles', () => {
+		expect(stripAnsi('\x1b[2K\x1b[1Gdone\x1b]0;title\x07')).toBe('done');
+	});
+
+	it('leaves plain text alone', () => {
+		expect(stripAnsi('a [31m b')).toBe('a [31m b');
+		expect(hasAnsiCodes('a [31m b')).toBe(false);
+		expect(hasAnsiCodes('\x1b[0m')).toBe(true);
+	});
+});
+
+describe('ansiPaletteColour', () => {
+	it('uses the theme colours for the first 16', () => {
+		expect(ansiPaletteColour(1)).toBe('var(--ucf-ansi-1)');
+		expect(ansiPaletteColour(15)).toBe('var(--ucf-ansi-15)');
+	});
+
+	it('maps the colour cube and the greys', () => {
+		expect(ansiPaletteColour(16)).toBe('rgb(0, 0, 0)');
+		expect(ansiPaletteColour(196)).toBe('rgb(255, 0, 0)');
+		expect(ansiPaletteColour(232)).toBe('rgb(8, 8, 8)');
+		expect(ansiPaletteColour(255)).toBe('rgb(238, 238, 238)');
+	});
+
+	it('rejects indices outside the palette', () => {
+		expect(ansiPaletteColour(256)).toBe('');
+		expect(ansiPaletteColour(-1)).toBe('');
+	});
+});
+
+describe('applySgrCodes', () => {
+	it('sets and resets attributes', () => {
+		const styled = applySgrCodes(createAnsiStyle(), '1;3;4;31;42');
+		expect(styled).toMatchObject({ bold: true, italic: true, underline: true, foreground: 'var(--ucf-ansi-1)', background: 'var(--ucf-ansi-2)' });
+
+		expect(applySgrCodes(styled, '22;39')).toMatchObject({ bold: false, italic: true, foreground: '' });
+		expect(applySgrCodes(styled, '')).toEqual(createAnsiStyle());
+	});
+
+	it('reads bright, 256 and truecolour codes', () => {
+		expect(applySgrCodes(createAnsiStyle(), '92').foreground).toBe('var(--ucf-ansi-10)');
+		expect(applySgrCodes(createAnsiStyle(), '38;5;208').foreground).toBe('rgb(255, 135, 0)');
+		expect(applySgrCodes(createAnsiStyle(), '48;2;10;20;30;1')).toMatchObject({ background: 'rgb(10, 20, 30)', bold: true });
+	});
+});
+
+describe('parseAnsiLine', () => {
+	it('splits a line into styled runs', () => {
+		const { segments } = parseAnsiLine('ok \x1b[31mfail\x1b[0m end', createAnsiStyle());
+		expect(segments.map(segment => segment.text)).toEqual(['ok ', 'fail', ' end']);
+		expect(segments[1].style.foreground).toBe('var(--ucf-ansi-1)');
+		expect(segments[2].style.foreground).toBe('');
+	});
+
+	it('returns the style at the end of the line for the next one', () => {
+		const { style } = parseAnsiLine('\x1b[33mwarning: ', createAnsiStyle());
+		expect(parseAnsiLine('still yellow', style).segments[0].style.foreground).toBe('var(--ucf-ansi-3)');
+	});
+});
+
+describe('ansiStyleToCss', () => {
+	it('builds declarations for a style', () => {
+		const style = { ...createAnsiStyle(), foreground: 'red', underline: true, strikethrough: true };
+		expect(ansiStyleToCss(style)).toBe('color: red; text-decoration: underline line-through');
+	});
+
+	it('swaps the colours for inverse text', () => {
+		const style = { ...createAnsiStyle(), foreground: 'red', inverse: true };
+		expect(ansiStyleToCss(style)).toContain('background-color: red');
+	});
+
+	it('is empty for the default style', () => {
+		expect(ansiStyleToCss(createAnsiStyle())).toBe('');
+	});
+});
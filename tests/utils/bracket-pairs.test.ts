@@ -0,0 +1,34 @@
+/**
+ * Tests for src/utils/bracket-pairs.ts
+ *
+ * Covers: pairBrackets
+ */
+
+import { describe, it, expect } from 'vitest';
+import { pairBrackets } from '../../src/utils/bracket-pairs';
+
+describe('pairBrackets', () => {
+	it('pairs nested brackets with their depth', () => {
+		expect(pairBrackets('{[()]}()')).toEqual([
+			{ depth: 0, partner: 5 },
+			{ depth: 1, partner: 4 },
+			{ depth: 2, partner: 3 },
+			{ depth: 2, partner: 2 },
+			{ depth: 1, partner: 1 },
+			{ depth: 0, partner: 0 },
+			{ depth: 0, partner: 7 },
+			{ depth: 0, partner: 6 },
+		]);
+	});
+
+	it('leaves a wrong closing bracket unmatched and its opener open', () => {
+		const pairs = pairBrackets('(]))');
+
+		expect(pairs.map(pair => pair.partner)).toEqual([2, null, 0, null]);
+	});
+
+	it('leaves unclosed openers unmatched', () => {
+		expect(pairBrackets('((').map(pair => pair.partner)).toEqual([null, null]);
+		expect(pairBrackets('')).toEqual([]);
+	});
+});
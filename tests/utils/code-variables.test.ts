@@ -0,0 +1,54 @@
+/**
+ * Tests for filling in code variables.
+ *
+ * Covers: parseCodeVariables, formatDateTokens, interpolateCodeVariables
+ */
+
+import { describe, it, expect } from 'vitest';
+import { parseCodeVariables, formatDateTokens, interpolateCodeVariables } from '../../src/utils/code-variables';
+import type { CodeVariableContext } from '../../src/utils/code-variables';
+
+const context: CodeVariableContext = {
+	frontmatter: { cluster: 'prod-eu', replicas: 3, deploy: { region: 'eu-west-1' }, zones: ['a', 'b'] },
+	variables: { registry: 'ghcr.io/acme' },
+	now: new Date(2026, 2, 7, 9, 5, 30),
+};
+
+describe('parseCodeVariables', () => {
+	it('reads name=value pairs', () => {
+		expect(parseCodeVariables('registry=ghcr.io/acme, team = platform')).toEqual({ registry: 'ghcr.io/acme', team: 'platform' });
+	});
+
+	it('skips entries without a name', () => {
+		expect(parseCodeVariables('registry, =value, , empty=')).toEqual({ empty: '' });
+		expect(parseCodeVariables('')).toEqual({});
+	});
+});
+
+describe('formatDateTokens', () => {
+	it('replaces date and time tokens', () => {
+		expect(formatDateTokens(context.now, 'YYYY-MM-DD HH:mm:ss')).toBe('2026-03-07 09:05:30');
+		expect(formatDateTokens(context.now, 'DD/MM/YY')).toBe('07/03/26');
+	});
+});
+
+describe('interpolateCodeVariables', () => {
+	it('fills in frontmatter fields, including nested fields and lists', () => {
+		expect(interpolateCodeVariables('kubectl --context {{fm.cluster}} scale --replicas={{ fm.replicas }}', context))
+			.toBe('kubectl --context prod-eu scale --replicas=3');
+		expect(interpolateCodeVariables('{{fm.deploy.region}} {{fm.zones}}', context)).toBe('eu-west-1 a, b');
+	});
+
+	it('fills in vault variables', () => {
+		expect(interpolateCodeVariables('docker pull {{var.registry}}/api', context)).toBe('docker pull ghcr.io/acme/api');
+	});
+
+	it('fills in the date and time, with an optional format', () => {
+		expect(interpolateCodeVariables('{{date}} {{time}} {{date:YYYY-MM}}', context)).toBe('2026-03-07 09:05 2026-03');
+	});
+
+	it('leaves placeholders and variables without a value alone', () => {
+		const code = 'ssh {{HOSTNAME}} {{fm.missing}} {{var.missing}} {{fm.deploy}}';
+		expect(interpolateCodeVariables(code, context)).toBe(code);
+	});
+});
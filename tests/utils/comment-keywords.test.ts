@@ -0,0 +1,38 @@
+/**
+ * Tests for src/utils/comment-keywords.ts
+ *
+ * Covers: findCommentKeywords
+ */
+
+import { describe, it, expect } from 'vitest';
+import { findCommentKeywords } from '../../src/utils/comment-keywords';
+import { DEFAULT_SETTINGS } from '../../src/constants';
+
+const rules = DEFAULT_SETTINGS.commentKeywords;
+
+describe('findCommentKeywords', () => {
+	it('finds keywords with an optional owner and colon', () => {
+		const matches = findCommentKeywords('# TODO(bob): retry. NOTE: slow', rules);
+
+		expect(matches.map(match => [match.start, match.end, match.rule.keyword])).toEqual([
+			[2, 12, 'TODO'],
+			[20, 25, 'NOTE'],
+		]);
+	});
+
+	it('matches whole words as written', () => {
+		expect(findCommentKeywords('// todo, TODOS, MY_TODO, FIX-ME, BUGFIX', rules)).toEqual([]);
+		expect(findCommentKeywords('/* XXX */', rules).map(match => match.rule.type)).toEqual(['danger']);
+	});
+
+	it('prefers longer keywords and the first rule for a keyword', () => {
+		const matches = findCommentKeywords('FIXME_LATER FIXME', [
+			{ keyword: 'FIXME', type: 'bug', colour: '' },
+			{ keyword: 'FIXME_LATER', type: 'todo', colour: '' },
+			{ keyword: 'FIXME', type: 'note', colour: '' },
+			{ keyword: 'bad word', type: 'note', colour: '' },
+		]);
+
+		expect(matches.map(match => match.rule.type)).toEqual(['todo', 'bug']);
+	});
+});
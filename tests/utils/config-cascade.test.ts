@@ -0,0 +1,79 @@
+/**
+ * Tests for the settings cascade order.
+ *
+ * Covers: normalizeConfigCascade, getActiveCascadeLayers
+ */
+
+import { describe, it, expect } from 'vitest';
+import { CASCADE_LAYERS, normalizeConfigCascade, getActiveCascadeLayers } from '../../src/utils/config-cascade';
+import type { CascadeLayerSetting } from '../../src/types';
+
+describe('normalizeConfigCascade', () => {
+	it('returns the default order when nothing is stored', () => {
+		expect(normalizeConfigCascade(undefined).map(entry => entry.layer)).toEqual([...CASCADE_LAYERS]);
+		expect(normalizeConfigCascade([]).every(entry => entry.enabled)).toBe(true);
+	});
+
+	it('defaults to folder preset, frontmatter, page, preset, block, then shorthand', () => {
+		expect(CASCADE_LAYERS).toEqual(['folder', 'frontmatter', 'page', 'preset', 'block', 'shorthand']);
+	});
+
+	it('keeps a custom order and switched-off layers', () => {
+		const cascade: CascadeLayerSetting[] = [
+			{ layer: 'shorthand', enabled: false },
+			{ layer: 'block', enabled: true },
+			{ layer: 'page', enabled: false },
+			{ layer: 'folder', enabled: true },
+			{ layer: 'frontmatter', enabled: true },
+			{ layer: 'preset', enabled: true },
+		];
+		expect(normalizeConfigCascade(cascade)).toEqual(cascade);
+	});
+
+	it('moves the former default order to the current one', () => {
+		const former: CascadeLayerSetting[] = [
+			{ layer: 'preset', enabled: true },
+			{ layer: 'frontmatter', enabled: true },
+			{ layer: 'page', enabled: true },
+			{ layer: 'block', enabled: true },
+		];
+		expect(normalizeConfigCascade(former).map(entry => entry.layer)).toEqual([...CASCADE_LAYERS]);
+
+		// An old order with a layer switched off was chosen, so it is kept
+		const customised = former.map(entry => ({ ...entry, enabled: entry.layer !== 'page' }));
+		expect(normalizeConfigCascade(customised).map(entry => entry.layer)).toEqual(['folder', 'preset', 'frontmatter', 'page', 'block', 'shorthand']);
+	});
+
+	it('drops unknown and repeated layers and adds missing ones in their default place', () => {
+		const cascade = [
+			{ layer: 'page', enabled: true },
+			{ layer: 'theme', enabled: true },
+			{ layer: 'page', enabled: false },
+			{ layer: 'preset', enabled: false },
+		] as unknown as CascadeLayerSetting[];
+		expect(normalizeConfigCascade(cascade)).toEqual([
+			{ layer: 'folder', enabled: true },
+			{ layer: 'frontmatter', enabled: true },
+			{ layer: 'page', enabled: true },
+			{ layer: 'preset', enabled: false },
+			{ layer: 'block', enabled: true },
+			{ layer: 'shorthand', enabled: true },
+		]);
+	});
+
+	it('keeps the block layer enabled', () => {
+		const result = normalizeConfigCascade([{ layer: 'block', enabled: false }]);
+		expect(result.find(entry => entry.layer === 'block')?.enabled).toBe(true);
+	});
+});
+
+describe('getActiveCascadeLayers', () => {
+	it('lists the enabled layers lowest first', () => {
+		expect(getActiveCascadeLayers([
+			{ layer: 'page', enabled: true },
+			{ layer: 'preset', enabled: false },
+			{ layer: 'folder', enabled: false },
+			{ layer: 'block', enabled: true },
+		])).toEqual(['frontmatter', 'page', 'block', 'shorthand']);
+	});
+});
@@ -0,0 +1,129 @@
+/**
+ * Tests for src/utils/config-inspect.ts
+ *
+ * Covers: flattenConfig, buildSettingsLayer, inspectBlockConfig
+ */
+
+import { describe, it, expect } from 'vitest';
+import { flattenConfig, buildSettingsLayer, inspectBlockConfig } from '../../src/utils/config-inspect';
+import { DEFAULT_SETTINGS } from '../../src/constants';
+import { testSettings } from '../helpers/test-settings';
+
+describe('flattenConfig', () => {
+	it('flattens nested sections into dotted paths', () => {
+		expect(flattenConfig({ META: { TITLE: 'x', DESC: undefined }, FILTER: { BY_LINES: { RANGE: [1, 5] } } }))
+			.toEqual({ 'META.TITLE': 'x', 'FILTER.BY_LINES.RANGE': [1, 5] });
+	});
+
+	it('lists cmdout styles under RENDER', () => {
+		expect(flattenConfig({ RENDER_CMDOUT: { PROMPT: { BOLD: true } } }))
+			.toEqual({ 'RENDER.PROMPT.BOLD': true });
+	});
+});
+
+describe('buildSettingsLayer', () => {
+	it('includes code block fallbacks only for code blocks', () => {
+		const layer = buildSettingsLayer(testSettings({ foldLines: 12 }), false);
+		expect(layer['RENDER.FOLD']).toBe(12);
+		expect(layer['RENDER.PROMPT.COLOUR']).toBeUndefined();
+		expect(layer['PRINT.LINES']).toBe(false);
+	});
+
+	it('includes cmdout styles for cmdout blocks', () => {
+		const layer = buildSettingsLayer(testSettings({ commandPromptBold: true }), true);
+		expect(layer['RENDER.PROMPT.BOLD']).toBe(true);
+		expect(layer['RENDER.FOLD']).toBeUndefined();
+	});
+});
+
+describe('inspectBlockConfig', () => {
+	const sourceOf = (entries: { path: string; layer: string }[], path: string): string | undefined =>
+		entries.find(entry => entry.path === path)?.layer;
+
+	it('separates defaults from changed plugin settings', () => {
+		const { entries } = inspectBlockConfig({}, testSettings({ foldLines: 7 }), DEFAULT_SETTINGS, { isCmdout: false, defaultLanguage: 'bash' });
+		expect(sourceOf(entries, 'RENDER.FOLD')).toBe('settings');
+		expect(sourceOf(entries, 'RENDER.ZEBRA')).toBe('default');
+		expect(entries.find(entry => entry.path === 'RENDER.LANG')?.value).toBe('bash');
+	});
+
+	it('layers page config, preset and block in order', () => {
+		const settings = testSettings({ presets: { wide: 'RENDER:\n  FOLD: 30\n  ZEBRA: true\n  LINES: true' } });
+		const { presetName, entries } = inspectBlockConfig(
+			{ META: { PRESET: 'wide' }, RENDER: { LINES: false } },
+			settings,
+			DEFAULT_SETTINGS,
+			{ isCmdout: false, pageConfig: { RENDER: { ZEBRA: false, SCROLL: 4 } } }
+		);
+		expect(presetName).toBe('wide');
+		expect(sourceOf(entries, 'RENDER.FOLD')).toBe('preset');
+		expect(sourceOf(entries, 'RENDER.ZEBRA')).toBe('preset');
+		expect(sourceOf(entries, 'RENDER.SCROLL')).toBe('page');
+		expect(sourceOf(entries, 'RENDER.LINES')).toBe('block');
+		expect(entries.find(entry => entry.path === 'RENDER.LINES')?.value).toBe(false);
+	});
+
+	it('places the folder preset under the frontmatter and the shorthand over the block', () => {
+		const settings = testSettings({ presets: { docs: 'RENDER:\n  FOLD: 30\n  ZEBRA: true' } });
+		const { entries } = inspectBlockConfig(
+			{ RENDER: { LINES: true, SCROLL: 8 } },
+			settings,
+			DEFAULT_SETTINGS,
+			{ isCmdout: false, folderPreset: 'docs', frontmatterConfig: { RENDER: { FOLD: 12 } }, shorthandConfig: { RENDER: { SCROLL: 3 } } }
+		);
+		expect(sourceOf(entries, 'RENDER.ZEBRA')).toBe('folder');
+		expect(sourceOf(entries, 'RENDER.FOLD')).toBe('frontmatter');
+		expect(sourceOf(entries, 'RENDER.LINES')).toBe('block');
+		expect(sourceOf(entries, 'RENDER.SCROLL')).toBe('shorthand');
+	});
+
+	it('places frontmatter under the page config', () => {
+		const { entries } = inspectBlockConfig(
+			{},
+			testSettings(),
+			DEFAULT_SETTINGS,
+			{ isCmdout: false, frontmatterConfig: { RENDER: { FOLD: 30, ZEBRA: true } }, pageConfig: { RENDER: { ZEBRA: false } } }
+		);
+		expect(sourceOf(entries, 'RENDER.FOLD')).toBe('frontmatter');
+		expect(sourceOf(entries, 'RENDER.ZEBRA')).toBe('page');
+	});
+
+	it('follows a reordered cascade with a layer switched off', () => {
+		const settings = testSettings({
+			configCascade: [
+				{ layer: 'page', enabled: true },
+				{ layer: 'frontmatter', enabled: false },
+				{ layer: 'preset', enabled: true },
+				{ layer: 'block', enabled: true },
+			],
+			presets: { wide: 'RENDER:\n  FOLD: 30' },
+		});
+		const { entries } = inspectBlockConfig(
+			{},
+			settings,
+			DEFAULT_SETTINGS,
+			{ isCmdout: false, pageConfig: { META: { PRESET: 'wide' }, RENDER: { FOLD: 5, ZEBRA: false } }, frontmatterConfig: { RENDER: { LINES: true } } }
+		);
+		expect(sourceOf(entries, 'RENDER.FOLD')).toBe('preset');
+		expect(sourceOf(entries, 'RENDER.ZEBRA')).toBe('page');
+		expect(sourceOf(entries, 'RENDER.LINES')).toBe('default');
+	});
+
+	it('ignores presets and page config for cmdout blocks', () => {
+		const { presetName, entries } = inspectBlockConfig(
+			{ RENDER: { SCROLL: 4 } },
+			testSettings(),
+			DEFAULT_SETTINGS,
+			{ isCmdout: true, pageConfig: { RENDER: { COPY: false } } }
+		);
+		expect(presetName).toBeUndefined();
+		expect(sourceOf(entries, 'RENDER.SCROLL')).toBe('block');
+		expect(sourceOf(entries, 'RENDER.COPY')).toBe('default');
+	});
+
+	it('sorts entries by path', () => {
+		const { entries } = inspectBlockConfig({ META: { TITLE: 'x' } }, testSettings(), DEFAULT_SETTINGS, { isCmdout: false });
+		const paths = entries.map(entry => entry.path);
+		expect(paths).toEqual([...paths].sort((a, b) => a.localeCompare(b)));
+	});
+});
@@ -657,3 +657,118 @@ describe('deepMergeYamlConfigs — full integration', () => {
 		});
 	});
 });
+
+// =============================================================================
+// COPY section
+// =============================================================================
+
+describe('deepMergeYamlConfigs — COPY section', () => {
+	it('replaces COPY.AS entirely when override defines it', () => {
+		const base: ParsedYamlConfig = { COPY: { AS: [{ FORMAT: 'dockerfile' }, { FORMAT: 'crontab' }] } };
+		const override: ParsedYamlConfig = { COPY: { AS: [{ FORMAT: 'makefile' }] } };
+
+		const result = deepMergeYamlConfigs(base, override);
+
+		expect(result.COPY?.AS).toEqual([{ FORMAT: 'makefile' }]);
+	});
+
+	it('lets a block turn off STRIP_COMMENTS set by a preset', () => {
+		const base: ParsedYamlConfig = { COPY: { STRIP_COMMENTS: true } };
+		const override: ParsedYamlConfig = { COPY: { STRIP_COMMENTS: false } };
+
+		expect(deepMergeYamlConfigs(base, override).COPY?.STRIP_COMMENTS).toBe(false);
+	});
+
+	it('keeps preset COPY.AS when block does not define it', () => {
+		const base: ParsedYamlConfig = { COPY: { AS: [{ FORMAT: 'dockerfile' }] } };
+		const override: ParsedYamlConfig = { RENDER: { LINES: true }, COPY: {} };
+
+		const result = deepMergeYamlConfigs(base, override);
+
+		expect(result.COPY?.AS).toEqual([{ FORMAT: 'dockerfile' }]);
+	});
+});
+
+// =============================================================================
+// HIGHLIGHT section
+// =============================================================================
+
+describe('deepMergeYamlConfigs — HIGHLIGHT section', () => {
+	it('lets block HIGHLIGHT.LINES override the preset', () => {
+		const base: ParsedYamlConfig = { HIGHLIGHT: { LINES: '1-2' } };
+		const override: ParsedYamlConfig = { HIGHLIGHT: { LINES: '4' } };
+
+		expect(deepMergeYamlConfigs(base, override).HIGHLIGHT?.LINES).toBe('4');
+	});
+
+	it('keeps preset HIGHLIGHT when block does not define it', () => {
+		const base: ParsedYamlConfig = { HIGHLIGHT: { LINES: '3-5' } };
+
+		expect(deepMergeYamlConfigs(base, {}).HIGHLIGHT?.LINES).toBe('3-5');
+	});
+});
+
+// =============================================================================
+// DOWNLOAD section
+// =============================================================================
+
+describe('deepMergeYamlConfigs — DOWNLOAD section', () => {
+	it('merges DOWNLOAD property-by-property', () => {
+		const base: ParsedYamlConfig = { DOWNLOAD: { SHEBANG: 'true', EXECUTABLE: true } };
+		const override: ParsedYamlConfig = { DOWNLOAD: { FILENAME: 'install.sh', EXECUTABLE: false } };
+
+		expect(deepMergeYamlConfigs(base, override).DOWNLOAD).toEqual({
+			SHEBANG: 'true',
+			EXECUTABLE: false,
+			FILENAME: 'install.sh',
+		});
+	});
+});
+
+// =============================================================================
+// TOKENS section
+// =============================================================================
+
+describe('deepMergeYamlConfigs — TOKENS section', () => {
+	it('merges a language\'s token styles token by token', () => {
+		const base: ParsedYamlConfig = { TOKENS: { comment: 'dim', sql: { keyword: 'bold purple', string: 'green' } } };
+		const override: ParsedYamlConfig = { TOKENS: { sql: { keyword: 'red' } } };
+
+		expect(deepMergeYamlConfigs(base, override).TOKENS).toEqual({
+			comment: 'dim',
+			sql: { keyword: 'red', string: 'green' },
+		});
+	});
+});
+
+// =============================================================================
+// PATTERNS section
+// =============================================================================
+
+describe('deepMergeYamlConfigs — PATTERNS section', () => {
+	it('keeps a preset\'s patterns and lets the block restyle one', () => {
+		const base: ParsedYamlConfig = { PATTERNS: { 'PROJ-\\d+': 'bold purple', '\\bprod\\b': 'red' } };
+		const override: ParsedYamlConfig = { PATTERNS: { '\\bprod\\b': 'orange' } };
+
+		expect(deepMergeYamlConfigs(base, override).PATTERNS).toEqual({
+			'PROJ-\\d+': 'bold purple',
+			'\\bprod\\b': 'orange',
+		});
+	});
+});
+
+// =============================================================================
+// RUN section
+// =============================================================================
+
+describe('deepMergeYamlConfigs — RUN section', () => {
+	it('merges ENV variable by variable, so a block can add to a preset\'s', () => {
+		const base: ParsedYamlConfig = { RUN: { TIMEOUT: '5m', ENV: { API_URL: 'https://prod.example.com', REGION: 'eu' } } };
+		const override: ParsedYamlConfig = { RUN: { ENV: { API_URL: 'https://dev.example.com' } } };
+
+		expect(deepMergeYamlConfigs(base, override).RUN).toEqual({
+			TIMEOUT: '5m',
+			ENV: { API_URL: 'https://dev.example.com', REGION: 'eu' },
+		});
+	});
+});
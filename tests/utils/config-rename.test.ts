@@ -0,0 +1,36 @@
+/**
+ * Tests for src/utils/config-rename.ts
+ *
+ * Covers: renameDeprecatedKeys
+ */
+
+import { describe, it, expect } from 'vitest';
+import { renameDeprecatedKeys } from '../../src/utils/config-rename';
+
+describe('renameDeprecatedKeys', () => {
+	it('renames a top-level section and keeps its contents', () => {
+		const text = 'META:\n  TITLE: "Demo"\nDISPLAY:\n  LINES: true # numbered\n  FOLD: 5';
+		expect(renameDeprecatedKeys(text))
+			.toBe('META:\n  TITLE: "Demo"\nRENDER:\n  LINES: true # numbered\n  FOLD: 5');
+	});
+
+	it('leaves the code after a ~~~ separator untouched', () => {
+		const text = 'STYLE:\n  ZEBRA: true\n~~~\nDISPLAY: not a setting';
+		expect(renameDeprecatedKeys(text)).toBe('RENDER:\n  ZEBRA: true\n~~~\nDISPLAY: not a setting');
+	});
+
+	it('ignores old names that are not at the renamed path', () => {
+		const text = 'CALLOUT:\n  ENTRIES:\n    - LINE: 1\n      DISPLAY: inline\n  DISPLAY: footnote';
+		expect(renameDeprecatedKeys(text)).toBe(text);
+	});
+
+	it('skips a rename when the new key is already present', () => {
+		const text = 'RENDER:\n  FOLD: 3\nDISPLAY:\n  LINES: true';
+		expect(renameDeprecatedKeys(text)).toBe(text);
+	});
+
+	it('renames only the first of two old names for the same key', () => {
+		const text = 'DISPLAY:\n  LINES: true\nSTYLE:\n  ZEBRA: true';
+		expect(renameDeprecatedKeys(text)).toBe('RENDER:\n  LINES: true\nSTYLE:\n  ZEBRA: true');
+	});
+});
@@ -0,0 +1,130 @@
+/**
+ * Tests for reading unified diffs.
+ *
+ * Covers: classifyDiffLines, hasDiffMarker, unifiedDiffSteps, diffLineLists, buildSplitDiffRows, diffWords
+ */
+
+import { describe, it, expect } from 'vitest';
+import { classifyDiffLines, hasDiffMarker, unifiedDiffSteps, diffLineLists, buildSplitDiffRows, diffWords } from '../../src/utils/diff';
+
+describe('classifyDiffLines', () => {
+	it('classifies file headers, hunk headers and changed lines', () => {
+		expect(classifyDiffLines([
+			'diff --git a/app.ts b/app.ts',
+			'--- a/app.ts',
+			'+++ b/app.ts',
+			'@@ -1,3 +1,3 @@',
+			' const a = 1;',
+			'-const b = 2;',
+			'+const b = 3;',
+			'\\ No newline at end of file',
+		])).toEqual(['header', 'header', 'header', 'hunk', 'context', 'removed', 'added', 'header']);
+	});
+
+	it('reads --- and +++ inside a hunk as changed lines', () => {
+		expect(classifyDiffLines(['@@ -1 +1 @@', '--- old comment', '+++ new comment']))
+			.toEqual(['hunk', 'removed', 'added']);
+	});
+
+	it('reads a snippet without hunk headers as one hunk', () => {
+		expect(classifyDiffLines(['-old', '+new', 'same'])).toEqual(['removed', 'added', 'context']);
+	});
+});
+
+describe('hasDiffMarker', () => {
+	it('is true for +, - and space markers on changed and context lines', () => {
+		expect(hasDiffMarker('+x', 'added')).toBe(true);
+		expect(hasDiffMarker(' x', 'context')).toBe(true);
+	});
+
+	it('is false for unmarked context lines and headers', () => {
+		expect(hasDiffMarker('same', 'context')).toBe(false);
+		expect(hasDiffMarker('', 'context')).toBe(false);
+		expect(hasDiffMarker('--- a/app.ts', 'header')).toBe(false);
+	});
+});
+
+describe('unifiedDiffSteps', () => {
+	it('maps each line to its side and leaves out file headers', () => {
+		expect(unifiedDiffSteps(['header', 'hunk', 'context', 'removed', 'added'])).toEqual([
+			{ kind: 'hunk', oldIndex: 1, newIndex: 1 },
+			{ kind: 'context', oldIndex: 2, newIndex: 2 },
+			{ kind: 'removed', oldIndex: 3, newIndex: null },
+			{ kind: 'added', oldIndex: null, newIndex: 4 },
+		]);
+	});
+});
+
+describe('diffLineLists', () => {
+	it('keeps shared lines as context', () => {
+		expect(diffLineLists(['a', 'b', 'c'], ['a', 'x', 'c'])).toEqual([
+			{ kind: 'context', oldIndex: 0, newIndex: 0 },
+			{ kind: 'removed', oldIndex: 1, newIndex: null },
+			{ kind: 'added', oldIndex: null, newIndex: 1 },
+			{ kind: 'context', oldIndex: 2, newIndex: 2 },
+		]);
+	});
+
+	it('handles lines only added or only removed', () => {
+		expect(diffLineLists([], ['a']).map(step => step.kind)).toEqual(['added']);
+		expect(diffLineLists(['a', 'b'], ['b']).map(step => step.kind)).toEqual(['removed', 'context']);
+	});
+
+	it('finds the longest run of unchanged lines', () => {
+		const kinds = diffLineLists(['x', 'a', 'b', 'c'], ['a', 'b', 'c', 'x']).map(step => step.kind);
+		expect(kinds).toEqual(['removed', 'context', 'context', 'context', 'added']);
+	});
+});
+
+describe('buildSplitDiffRows', () => {
+	it('pairs removed lines with the added lines after them', () => {
+		expect(buildSplitDiffRows([
+			{ kind: 'hunk', oldIndex: 0, newIndex: 0 },
+			{ kind: 'removed', oldIndex: 1, newIndex: null },
+			{ kind: 'removed', oldIndex: 2, newIndex: null },
+			{ kind: 'added', oldIndex: null, newIndex: 3 },
+			{ kind: 'context', oldIndex: 4, newIndex: 4 },
+		])).toEqual([
+			{ kind: 'hunk', oldIndex: 0, newIndex: 0 },
+			{ kind: 'changed', oldIndex: 1, newIndex: 3 },
+			{ kind: 'changed', oldIndex: 2, newIndex: null },
+			{ kind: 'context', oldIndex: 4, newIndex: 4 },
+		]);
+	});
+
+	it('starts a new change at a removal after additions', () => {
+		expect(buildSplitDiffRows([
+			{ kind: 'added', oldIndex: null, newIndex: 0 },
+			{ kind: 'removed', oldIndex: 1, newIndex: null },
+		])).toEqual([
+			{ kind: 'changed', oldIndex: null, newIndex: 0 },
+			{ kind: 'changed', oldIndex: 1, newIndex: null },
+		]);
+	});
+});
+
+describe('diffWords', () => {
+	it('returns the changed words on each side', () => {
+		expect(diffWords('port: 8080', 'port: 9090')).toEqual({
+			removed: [{ start: 6, end: 10 }],
+			added: [{ start: 6, end: 10 }],
+		});
+	});
+
+	it('joins neighbouring changed tokens into one range', () => {
+		expect(diffWords('sum(a)', 'sum(a, b)')).toEqual({ removed: [], added: [{ start: 5, end: 8 }] });
+	});
+
+	it('returns null when the lines share no words', () => {
+		expect(diffWords('alpha', 'beta gamma')).toBeNull();
+	});
+
+	it('returns null for identical lines', () => {
+		expect(diffWords('same line', 'same line')).toBeNull();
+	});
+
+	it('returns null for lines too long to compare', () => {
+		const long = Array.from({ length: 150 }, (_, index) => `w${String(index)}`).join(' ');
+		expect(diffWords(long, `${long} x`)).toBeNull();
+	});
+});
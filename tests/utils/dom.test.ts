@@ -3,15 +3,16 @@
 /**
  * Tests for src/utils/dom.ts
  *
- * Covers: addScrollBehaviour, wrapCodeLinesInDom, processCodeElementLines,
+ * Covers: addScrollBehaviour, applyPrintProfile, wrapCodeLinesInDom, processCodeElementLines,
  *         findCodeElement, findPreElement, removeExistingTitleElements,
- *         createCodeBlockContainer, extractCodeText
+ *         createCodeBlockContainer, extractCodeText, extractDiffAfterText
  */
 
 import { describe, it, expect, beforeEach, vi } from 'vitest';
 import { setupObsidianDom } from '../../__mocks__/obsidian';
 import {
 	addScrollBehaviour,
+	applyPrintProfile,
 	wrapCodeLinesInDom,
 	processCodeElementLines,
 	findCodeElement,
@@ -19,6 +20,9 @@ import {
 	removeExistingTitleElements,
 	createCodeBlockContainer,
 	extractCodeText,
+	extractLineText,
+	extractDiffAfterText,
+	wrapTextRange,
 	type LineWrappingOptions,
 } from '../../src/utils/dom';
 
@@ -118,6 +122,25 @@ describe('addScrollBehaviour', () => {
 	});
 });
 
+// =============================================================================
+// applyPrintProfile
+// =============================================================================
+
+describe('applyPrintProfile', () => {
+	it('marks the block for light colours and unbroken pages', () => {
+		const block = document.createElement('div');
+		applyPrintProfile(block, 'light', true);
+		expect(block.classList.contains('ucf-print-light')).toBe(true);
+		expect(block.classList.contains('ucf-print-avoid-breaks')).toBe(true);
+	});
+
+	it('leaves the classes off when printing as displayed', () => {
+		const block = document.createElement('div');
+		applyPrintProfile(block, 'asis', false);
+		expect(block.className).toBe('');
+	});
+});
+
 // =============================================================================
 // wrapCodeLinesInDom
 // =============================================================================
@@ -214,6 +237,34 @@ describe('wrapCodeLinesInDom', () => {
 		expect((lineNums[1] as HTMLElement).textContent).toBe('11');
 	});
 
+	it('numbers lines relative to anchorLine', () => {
+		const code = document.createElement('code');
+		code.textContent = 'a\nb\nc\nd';
+		wrapCodeLinesInDom(code, {
+			showLineNumbers: true,
+			showZebraStripes: false,
+			startingLineNumber: 10,
+			anchorLine: 2,
+		});
+
+		const lineNums = Array.from(code.querySelectorAll('.ucf-line-num')).map(el => el.textContent);
+		expect(lineNums).toEqual(['-1', '11', '+1', '+2']);
+
+		const lines = code.querySelectorAll('.ucf-line');
+		expect((lines[1] as HTMLElement).classList.contains('ucf-line-anchor')).toBe(true);
+		expect(code.querySelectorAll('.ucf-line-anchor').length).toBe(1);
+	});
+
+	it('ignores an anchorLine outside the block', () => {
+		const code = document.createElement('code');
+		code.textContent = 'a\nb';
+		wrapCodeLinesInDom(code, { showLineNumbers: true, showZebraStripes: false, anchorLine: 5 });
+
+		const lineNums = Array.from(code.querySelectorAll('.ucf-line-num')).map(el => el.textContent);
+		expect(lineNums).toEqual(['1', '2']);
+		expect(code.querySelector('.ucf-line-anchor')).toBeNull();
+	});
+
 	it('clears code element before wrapping', () => {
 		const code = document.createElement('code');
 		code.innerHTML = '<span>old content</span>';
@@ -352,6 +403,19 @@ describe('processCodeElementLines', () => {
 		expect(lines.length).toBeGreaterThan(0);
 	});
 
+	it('wraps lines when forceLineWrapping is true', () => {
+		const pre = document.createElement('pre');
+		const code = document.createElement('code');
+		code.textContent = 'line 1\nline 2';
+		pre.appendChild(code);
+
+		processCodeElementLines(pre, code, { showLineNumbers: false, showZebraStripes: false, forceLineWrapping: true });
+
+		expect(code.querySelectorAll('.ucf-line').length).toBe(2);
+		expect(pre.classList.contains('ucf-line-wrapped')).toBe(true);
+		expect(pre.classList.contains('ucf-line-numbers')).toBe(false);
+	});
+
 	it('does not call wrapCodeLinesInDom when both options are false', () => {
 		const pre = document.createElement('pre');
 		const code = document.createElement('code');
@@ -727,6 +791,14 @@ describe('extractCodeText', () => {
 		expect(text).toBe('');
 	});
 
+	it('skips line numbers and rejoins wrapped lines with newlines', () => {
+		const code = document.createElement('code');
+		code.textContent = 'first\n\nthird\n';
+		wrapCodeLinesInDom(code, { showLineNumbers: true, showZebraStripes: false });
+
+		expect(extractCodeText(code)).toBe('first\n\nthird');
+	});
+
 	it('strips HTML and returns plain text', () => {
 		const code = document.createElement('code');
 		code.innerHTML = '<span class="keyword">const</span> <span class="variable">x</span>';
@@ -735,4 +807,113 @@ describe('extractCodeText', () => {
 		expect(text).not.toContain('<');
 		expect(text).not.toContain('>');
 	});
+
+	it('reads colour-rendered lines as written when asked for raw ANSI', () => {
+		const code = document.createElement('code');
+		code.innerHTML = '<span class="ucf-line" data-ucf-ansi-raw="\x1b[32mok\x1b[0m"><span style="color: green">ok</span></span><span class="ucf-line">plain</span>';
+
+		expect(extractCodeText(code)).toBe('ok\nplain');
+		expect(extractCodeText(code, true)).toBe('\x1b[32mok\x1b[0m\nplain');
+	});
+});
+
+// =============================================================================
+// extractLineText
+// =============================================================================
+
+describe('extractLineText', () => {
+	it('reads the content span of a wrapped line', () => {
+		const code = document.createElement('code');
+		code.textContent = 'echo hi';
+		wrapCodeLinesInDom(code, { showLineNumbers: true, showZebraStripes: false });
+
+		const line = code.querySelector('.ucf-line') as HTMLElement;
+		expect(extractLineText(line)).toBe('echo hi');
+	});
+
+	it('falls back to the line element when there is no content span', () => {
+		const line = document.createElement('div');
+		line.className = 'ucf-line';
+		line.textContent = '$ ls';
+
+		expect(extractLineText(line)).toBe('$ ls');
+	});
+});
+
+// =============================================================================
+// extractDiffAfterText
+// =============================================================================
+
+describe('extractDiffAfterText', () => {
+	function diffLine(text: string, lineClass?: string, hasMarker = false): HTMLElement {
+		const line = document.createElement('span');
+		line.className = lineClass ? `ucf-line ${lineClass}` : 'ucf-line';
+		const content = document.createElement('span');
+		content.className = 'ucf-line-content';
+		if (hasMarker) {
+			const marker = document.createElement('span');
+			marker.className = 'ucf-diff-marker';
+			marker.textContent = text.charAt(0);
+			content.appendChild(marker);
+			content.appendChild(document.createTextNode(text.slice(1)));
+		} else {
+			content.textContent = text;
+		}
+		line.appendChild(content);
+		return line;
+	}
+
+	it('drops removed lines and headers and strips the markers', () => {
+		const code = document.createElement('code');
+		code.append(
+			diffLine('@@ -1,2 +1,2 @@', 'ucf-diff-hunk'),
+			diffLine(' keep', undefined, true),
+			diffLine('-old', 'ucf-diff-removed', true),
+			diffLine('+new', 'ucf-diff-added', true),
+		);
+
+		expect(extractDiffAfterText(code)).toBe('keep\nnew');
+	});
+
+	it('keeps unmarked lines whole', () => {
+		const code = document.createElement('code');
+		code.append(diffLine('a = 1', 'ucf-diff-removed'), diffLine('a = 2', 'ucf-diff-added'));
+
+		expect(extractDiffAfterText(code)).toBe('a = 2');
+	});
+});
+
+// =============================================================================
+// wrapTextRange
+// =============================================================================
+
+describe('wrapTextRange', () => {
+	it('wraps a range inside a single text node', () => {
+		const el = document.createElement('span');
+		el.textContent = '$ ls -la';
+
+		const wrappers = wrapTextRange(el, 0, 2, 'marked');
+
+		expect(wrappers).toHaveLength(1);
+		expect(wrappers[0].textContent).toBe('$ ');
+		expect(el.textContent).toBe('$ ls -la');
+	});
+
+	it('wraps a range spanning several highlighted spans', () => {
+		const el = document.createElement('span');
+		el.innerHTML = '<span class="a">ab</span><span class="b">cd</span>ef';
+
+		const wrappers = wrapTextRange(el, 1, 5, 'marked');
+
+		expect(wrappers.map(w => w.textContent)).toEqual(['b', 'cd', 'e']);
+		expect(el.textContent).toBe('abcdef');
+		expect(el.querySelectorAll('.marked')).toHaveLength(3);
+	});
+
+	it('returns no wrappers for an empty range', () => {
+		const el = document.createElement('span');
+		el.textContent = 'text';
+
+		expect(wrapTextRange(el, 2, 2, 'marked')).toEqual([]);
+	});
 });
@@ -0,0 +1,60 @@
+/**
+ * Tests for finding fold regions in a block's code.
+ *
+ * Covers: findFoldRegions
+ */
+
+import { describe, it, expect } from 'vitest';
+import { findFoldRegions } from '../../src/utils/fold-regions';
+
+describe('findFoldRegions', () => {
+	it('finds a #region block with its name and lines', () => {
+		const code = 'using System;\n#region Helpers\nvoid A() {}\n#endregion\nvoid B() {}';
+		expect(findFoldRegions(code)).toEqual([{ name: 'Helpers', startLine: 2, endLine: 4 }]);
+	});
+
+	it('recognises the common language spellings', () => {
+		const markers: [string, string][] = [
+			['// #region setup', '// #endregion'],
+			['#pragma region setup', '#pragma endregion'],
+			['<!-- #region setup -->', '<!-- #endregion -->'],
+			['/* #region setup */', '/* #endregion */'],
+			['#Region "setup"', '#End Region'],
+			['//#region setup', '//#endregion'],
+		];
+
+		for (const [start, end] of markers) {
+			expect(findFoldRegions(`${start}\nbody\n${end}`)).toHaveLength(1);
+		}
+	});
+
+	it('nests regions, closing the innermost first', () => {
+		const code = '// #region outer\n// #region inner\nx\n// #endregion\ny\n// #endregion';
+		expect(findFoldRegions(code)).toEqual([
+			{ name: 'outer', startLine: 1, endLine: 6 },
+			{ name: 'inner', startLine: 2, endLine: 4 },
+		]);
+	});
+
+	it('ignores unmatched markers', () => {
+		expect(findFoldRegions('#endregion\n#region open\nx')).toEqual([]);
+	});
+
+	it('leaves region copy markers without the # unfolded', () => {
+		expect(findFoldRegions('# region: setup\nbody\n# endregion')).toEqual([]);
+		expect(findFoldRegions('// region: setup\nbody\n// endregion')).toEqual([]);
+	});
+
+	it('does not treat ordinary code mentioning region as a marker', () => {
+		expect(findFoldRegions('region = "eu-west-1"\nprint(region)')).toEqual([]);
+	});
+
+	it('uses custom markers, checking the end marker first', () => {
+		const code = '// <editor-fold desc="x">\nbody\n// </editor-fold>';
+		expect(findFoldRegions(code, /<editor-fold/, /<\/editor-fold>/)).toEqual([
+			{ name: '', startLine: 1, endLine: 3 },
+		]);
+
+		expect(findFoldRegions('-- region\nx\n-- endregion', /region/, /endregion/)).toHaveLength(1);
+	});
+});
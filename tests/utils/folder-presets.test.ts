@@ -0,0 +1,106 @@
+/**
+ * Tests for src/utils/folder-presets.ts
+ *
+ * Covers: folderGlobToRegExp, findFolderPreset, findTagPreset, findDefaultPreset
+ */
+
+import { describe, it, expect } from 'vitest';
+import { folderGlobToRegExp, findFolderPreset, findTagPreset, findDefaultPreset } from '../../src/utils/folder-presets';
+import type { FolderPresetRule } from '../../src/types';
+
+describe('folderGlobToRegExp', () => {
+	it('matches a plain folder and everything below it', () => {
+		const pattern = folderGlobToRegExp('Work/Runbooks/');
+		expect(pattern.test('Work/Runbooks')).toBe(true);
+		expect(pattern.test('Work/Runbooks/Deploy')).toBe(true);
+		expect(pattern.test('Work/Runbooks-old')).toBe(false);
+		expect(pattern.test('Work')).toBe(false);
+	});
+
+	it('keeps * within one folder name', () => {
+		const pattern = folderGlobToRegExp('Projects/*/Docs');
+		expect(pattern.test('Projects/Alpha/Docs')).toBe(true);
+		expect(pattern.test('Projects/Alpha/Beta/Docs')).toBe(false);
+	});
+
+	it('lets ** cross folders', () => {
+		const pattern = folderGlobToRegExp('Projects/**/Docs');
+		expect(pattern.test('Projects/Alpha/Beta/Docs')).toBe(true);
+		expect(folderGlobToRegExp('**').test('')).toBe(true);
+	});
+
+	it('treats other characters literally', () => {
+		const pattern = folderGlobToRegExp('Notes (2024).v2');
+		expect(pattern.test('Notes (2024).v2')).toBe(true);
+		expect(pattern.test('Notes (2024)xv2')).toBe(false);
+	});
+});
+
+describe('findFolderPreset', () => {
+	const rules: FolderPresetRule[] = [
+		{ folder: 'Work/Runbooks', preset: 'runbook' },
+		{ folder: 'Blog', preset: 'publishing' },
+		{ folder: 'Work', preset: 'work' },
+		{ folder: '', preset: 'everything' },
+		{ folder: 'Drafts', preset: '' },
+	];
+
+	it('returns the first matching rule', () => {
+		expect(findFolderPreset('Work/Runbooks/Deploy.md', rules)).toBe('runbook');
+		expect(findFolderPreset('Work/Notes.md', rules)).toBe('work');
+		expect(findFolderPreset('Blog/2024/Post.md', rules)).toBe('publishing');
+	});
+
+	it('skips half-filled rules', () => {
+		expect(findFolderPreset('Drafts/Idea.md', rules)).toBeUndefined();
+		expect(findFolderPreset('Root note.md', rules)).toBeUndefined();
+	});
+});
+
+describe('findTagPreset', () => {
+	const presets = {
+		runbook: 'APPLIES_TO_TAGS: ["#Runbook"]\nRENDER:\n  LINES: true',
+		blog: 'APPLIES_TO_TAGS:\n  - blog\n  - "#draft"',
+		plain: 'RENDER:\n  ZEBRA: true',
+	};
+
+	it('finds the preset for one of the note tags, ignoring case and #', () => {
+		expect(findTagPreset(['#project', '#runbook'], presets)).toBe('runbook');
+		expect(findTagPreset(['Draft'], presets)).toBe('blog');
+	});
+
+	it('covers nested tags', () => {
+		expect(findTagPreset(['#runbook/database'], presets)).toBe('runbook');
+		expect(findTagPreset(['#runbooks'], presets)).toBeUndefined();
+	});
+
+	it('picks the first preset by name when several match', () => {
+		expect(findTagPreset(['#runbook', '#blog'], presets)).toBe('blog');
+	});
+
+	it('returns undefined for untagged notes or unmatched tags', () => {
+		expect(findTagPreset([], presets)).toBeUndefined();
+		expect(findTagPreset(['#other'], presets)).toBeUndefined();
+	});
+});
+
+describe('findDefaultPreset', () => {
+	const settings = {
+		presets: { tagged: 'APPLIES_TO_TAGS: runbook' },
+		languagePresets: { sql: 'sql', bash: '' },
+	};
+
+	it('uses a tag preset after the language preset', () => {
+		expect(findDefaultPreset('sql', settings, ['#runbook'])).toBe('sql');
+		expect(findDefaultPreset('bash', settings, ['#runbook'])).toBe('tagged');
+	});
+
+	it('prefers the language preset', () => {
+		expect(findDefaultPreset('SQL', settings)).toBe('sql');
+	});
+
+	it('leaves the folder preset to its own layer', () => {
+		expect(findDefaultPreset('bash', settings, ['#other'])).toBeUndefined();
+		expect(findDefaultPreset(undefined, settings)).toBeUndefined();
+	});
+});
@@ -1,7 +1,7 @@
 /**
  * Tests for src/utils/formatting.ts
  *
- * Covers: escapeHtml, buildStyleString, applyCaseFormat,
+ * Covers: escapeHtml, buildStyleString, buildFontFeatureSettings, applyCaseFormat,
  *         formatFileSize, calculateRelativeTime, formatTimestamp
  */
 
@@ -10,6 +10,7 @@ import {
 	formatCalloutMarkdown,
 	escapeHtml,
 	buildStyleString,
+	buildFontFeatureSettings,
 	applyCaseFormat,
 	formatFileSize,
 	calculateRelativeTime,
@@ -86,6 +87,26 @@ describe('buildStyleString', () => {
 	});
 });
 
+// =============================================================================
+// buildFontFeatureSettings
+// =============================================================================
+
+describe('buildFontFeatureSettings', () => {
+	it('turns features on, with values, and off', () => {
+		expect(buildFontFeatureSettings('ss01, cv02=3, -zero')).toBe('"ss01" 1, "cv02" 3, "zero" 0');
+	});
+
+	it('sets liga and calt for ligatures, before listed features', () => {
+		expect(buildFontFeatureSettings('', false)).toBe('"liga" 0, "calt" 0');
+		expect(buildFontFeatureSettings('calt', false)).toBe('"liga" 0, "calt" 0, "calt" 1');
+	});
+
+	it('skips malformed features', () => {
+		expect(buildFontFeatureSettings('ss1, "ss02", ss03')).toBe('"ss03" 1');
+		expect(buildFontFeatureSettings('')).toBe('');
+	});
+});
+
 // =============================================================================
 // applyCaseFormat
 // =============================================================================
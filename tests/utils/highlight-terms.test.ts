@@ -0,0 +1,35 @@
+/**
+ * Tests for src/utils/highlight-terms.ts
+ *
+ * Covers: findTermMatches
+ */
+
+import { describe, it, expect } from 'vitest';
+import { findTermMatches } from '../../src/utils/highlight-terms';
+
+describe('findTermMatches', () => {
+	it('finds whole identifiers only, case-sensitively', () => {
+		expect(findTermMatches('ctx = newCtx(ctx_old, ctx); $ctx; Ctx', ['ctx'])).toEqual([
+			{ start: 0, end: 3, term: 0 },
+			{ start: 22, end: 25, term: 0 },
+		]);
+	});
+
+	it('prefers the longer of overlapping terms and keeps list indexes', () => {
+		expect(findTermMatches('user.userId', ['user', 'user.userId'])).toEqual([
+			{ start: 0, end: 11, term: 1 },
+		]);
+	});
+
+	it('matches terms with regex characters literally', () => {
+		expect(findTermMatches('a->b + a.b', ['->', 'a.b'])).toEqual([
+			{ start: 1, end: 3, term: 0 },
+			{ start: 7, end: 10, term: 1 },
+		]);
+	});
+
+	it('ignores empty terms', () => {
+		expect(findTermMatches('abc', ['', ' '])).toEqual([]);
+		expect(findTermMatches('abc', [])).toEqual([]);
+	});
+});
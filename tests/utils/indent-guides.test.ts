@@ -0,0 +1,51 @@
+/**
+ * Tests for working out indentation guides.
+ *
+ * Covers: measureIndent, computeIndentGuides, findIndentScope
+ */
+
+import { describe, it, expect } from 'vitest';
+import { measureIndent, computeIndentGuides, findIndentScope } from '../../src/utils/indent-guides';
+
+describe('measureIndent', () => {
+	it('counts spaces and expands tabs to the next tab stop', () => {
+		expect(measureIndent('    x')).toBe(4);
+		expect(measureIndent('\tx')).toBe(4);
+		expect(measureIndent('  \tx')).toBe(4);
+		expect(measureIndent('x')).toBe(0);
+	});
+
+	it('returns null for blank lines', () => {
+		expect(measureIndent('')).toBeNull();
+		expect(measureIndent('   ')).toBeNull();
+	});
+});
+
+describe('computeIndentGuides', () => {
+	it('takes the indentation step from the smallest indent', () => {
+		expect(computeIndentGuides(['a:', '  b:', '    c: 1'])).toEqual({ unit: 2, depths: [0, 1, 2] });
+		expect(computeIndentGuides(['if x:', '    y()'])).toEqual({ unit: 4, depths: [0, 1] });
+	});
+
+	it('carries guides through blank lines inside a block', () => {
+		const layout = computeIndentGuides(['def f():', '    a()', '', '    b()', '', 'c()']);
+		expect(layout.depths).toEqual([0, 1, 1, 1, 0, 0]);
+	});
+
+	it('has no guides for unindented code', () => {
+		expect(computeIndentGuides(['a', 'b']).depths).toEqual([0, 0]);
+	});
+});
+
+describe('findIndentScope', () => {
+	it('spans the lines sharing the innermost guide', () => {
+		const depths = [0, 1, 2, 2, 1, 0];
+		expect(findIndentScope(depths, 2)).toEqual({ level: 2, startIndex: 2, endIndex: 3 });
+		expect(findIndentScope(depths, 1)).toEqual({ level: 1, startIndex: 1, endIndex: 4 });
+	});
+
+	it('returns null for lines without guides or out of range', () => {
+		expect(findIndentScope([0, 1], 0)).toBeNull();
+		expect(findIndentScope([0, 1], 5)).toBeNull();
+	});
+});
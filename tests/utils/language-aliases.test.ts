@@ -0,0 +1,60 @@
+/**
+ * Tests for src/utils/language-aliases.ts
+ *
+ * Covers: resolveLanguageAlias, languageFromPath
+ */
+
+import { describe, it, expect } from 'vitest';
+import { resolveLanguageAlias, languageFromPath } from '../../src/utils/language-aliases';
+
+describe('resolveLanguageAlias', () => {
+	const aliases = { zsh: 'bash', zshrc: 'zsh', tf: 'HCL', jsonc: 'json', a: 'b', b: 'a' };
+
+	it('returns the language an alias stands for', () => {
+		expect(resolveLanguageAlias('zsh', aliases)).toBe('bash');
+		expect(resolveLanguageAlias('TF', aliases)).toBe('hcl');
+	});
+
+	it('follows chains of aliases', () => {
+		expect(resolveLanguageAlias('zshrc', aliases)).toBe('bash');
+	});
+
+	it('returns other languages unchanged', () => {
+		expect(resolveLanguageAlias('python', aliases)).toBe('python');
+		expect(resolveLanguageAlias('python', {})).toBe('python');
+	});
+
+	it('stops when aliases loop', () => {
+		expect(resolveLanguageAlias('a', aliases)).toBe('b');
+	});
+});
+
+describe('languageFromPath', () => {
+	it('finds the language from the extension', () => {
+		expect(languageFromPath('scripts/deploy.sh', {})).toBe('bash');
+		expect(languageFromPath('vault://src/app.PY', {})).toBe('python');
+		expect(languageFromPath('infra/main.tf', {})).toBe('hcl');
+	});
+
+	it('finds files without an extension by name', () => {
+		expect(languageFromPath('build/Dockerfile', {})).toBe('docker');
+		expect(languageFromPath('Makefile', {})).toBe('makefile');
+	});
+
+	it('ignores URL queries and git refs', () => {
+		expect(languageFromPath('https://example.com/raw/config.yml?token=x#L3', {})).toBe('yaml');
+		expect(languageFromPath('tools:bin/install.sh@v1.4.2', {})).toBe('bash');
+	});
+
+	it('resolves the extension through the alias map first', () => {
+		expect(languageFromPath('nginx/site.conf', { conf: 'nginx' })).toBe('nginx');
+		expect(languageFromPath('main.zsh', { bash: 'shell' })).toBe('shell');
+		expect(languageFromPath('notes.xyz', { xyz: 'yaml' })).toBe('yaml');
+	});
+
+	it('returns undefined when the name does not say', () => {
+		expect(languageFromPath('notes.xyz', {})).toBeUndefined();
+		expect(languageFromPath('.bashrc', {})).toBeUndefined();
+		expect(languageFromPath('README', {})).toBeUndefined();
+	});
+});
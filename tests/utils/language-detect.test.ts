@@ -0,0 +1,55 @@
+/**
+ * Tests for src/utils/language-detect.ts
+ *
+ * Covers: detectLanguage, scoreLanguages, DETECTABLE_LANGUAGES
+ */
+
+import { describe, it, expect } from 'vitest';
+import { detectLanguage, scoreLanguages, DETECTABLE_LANGUAGES } from '../../src/utils/language-detect';
+
+describe('detectLanguage', () => {
+	it.each([
+		['bash', 'cd /tmp && ls -la\nif [ -f x ]; then\n  echo $HOME\nfi'],
+		['python', 'import os\n\ndef main(args):\n    print(self.x)\n    return None'],
+		['javascript', 'const fs = require("fs");\nconsole.log(fs);'],
+		['typescript', 'interface Foo { a: string }\nconst x: Foo = { a: "b" };'],
+		['json', '{\n  "name": "x",\n  "version": "1"\n}'],
+		['yaml', 'name: build\nsteps:\n  - run: make\n    with: y'],
+		['sql', 'SELECT id, name FROM users WHERE id = 1;'],
+		['go', 'package main\n\nfunc main() {\n\tfmt.Println("hi")\n}'],
+		['rust', 'fn main() {\n    let mut x = 5;\n    println!("{}", x);\n}'],
+		['powershell', 'Get-ChildItem -Path C:/ | Where-Object { $_.Length -gt 1 }'],
+		['diff', '--- a/x\n+++ b/x\n@@ -1,2 +1,2 @@\n-a\n+b'],
+		['dockerfile', 'FROM node:20\nRUN npm ci\nCOPY . .'],
+	])('recognises %s', (language, code) => {
+		expect(detectLanguage(code)).toBe(language);
+	});
+
+	it('trusts a shebang line', () => {
+		expect(detectLanguage('#!/usr/bin/env python3\nls -la && echo $HOME')).toBe('python');
+		expect(detectLanguage('#!/bin/zsh\nprint -P "%F{red}hi"')).toBe('bash');
+		expect(detectLanguage('#!/usr/bin/env node\nx')).toBe('javascript');
+	});
+
+	it('makes no guess for prose or empty code', () => {
+		expect(detectLanguage('hello world, this is some prose')).toBeNull();
+		expect(detectLanguage('')).toBeNull();
+	});
+});
+
+describe('scoreLanguages', () => {
+	it('scores TypeScript above JavaScript only when types show', () => {
+		const plain = scoreLanguages('const x = 1;\nconsole.log(x);');
+		expect(plain.typescript).toBe(0);
+
+		const typed = scoreLanguages('const x: number = 1;\nconsole.log(x);');
+		expect(typed.typescript).toBeGreaterThan(typed.javascript);
+	});
+});
+
+describe('DETECTABLE_LANGUAGES', () => {
+	it('lists the languages in order', () => {
+		expect(DETECTABLE_LANGUAGES).toContain('python');
+		expect([...DETECTABLE_LANGUAGES]).toEqual([...DETECTABLE_LANGUAGES].sort());
+	});
+});
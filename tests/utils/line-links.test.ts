@@ -0,0 +1,49 @@
+/**
+ * Tests for links to lines of a block.
+ *
+ * Covers: parseLineLink, formatLineLinkSubpath, findFenceBlockId
+ */
+
+import { describe, it, expect } from 'vitest';
+import { parseLineLink, formatLineLinkSubpath, findFenceBlockId } from '../../src/utils/line-links';
+
+describe('parseLineLink', () => {
+	it('reads the note, block ID and line', () => {
+		expect(parseLineLink('Runbook#^migration:L42')).toEqual({ path: 'Runbook', blockId: 'migration', startLine: 42, endLine: 42 });
+	});
+
+	it('reads line ranges, in either order', () => {
+		expect(parseLineLink('#^setup:L40-L45')).toEqual({ path: '', blockId: 'setup', startLine: 40, endLine: 45 });
+		expect(parseLineLink('#^setup:l9-3')).toMatchObject({ startLine: 3, endLine: 9 });
+	});
+
+	it('ignores links that do not address lines', () => {
+		expect(parseLineLink('Runbook')).toBeNull();
+		expect(parseLineLink('Runbook#Heading')).toBeNull();
+		expect(parseLineLink('Runbook#^migration')).toBeNull();
+	});
+});
+
+describe('formatLineLinkSubpath', () => {
+	it('formats single lines and ranges', () => {
+		expect(formatLineLinkSubpath('setup', 42)).toBe('#^setup:L42');
+		expect(formatLineLinkSubpath('setup', 45, 40)).toBe('#^setup:L40-L45');
+	});
+
+	it('round-trips through parseLineLink', () => {
+		expect(parseLineLink(`Note${formatLineLinkSubpath('a-1', 3, 7)}`)).toEqual({ path: 'Note', blockId: 'a-1', startLine: 3, endLine: 7 });
+	});
+});
+
+describe('findFenceBlockId', () => {
+	const note = ['```ufence-bash', 'echo hi', '```', '^setup', 'text'];
+
+	it('reads the ^id line after the closing fence', () => {
+		expect(findFenceBlockId(note, 2)).toBe('setup');
+	});
+
+	it('returns an empty string when there is none', () => {
+		expect(findFenceBlockId(note, 3)).toBe('');
+		expect(findFenceBlockId(note, 4)).toBe('');
+	});
+});
@@ -0,0 +1,55 @@
+/**
+ * Tests for src/utils/output-block.ts
+ *
+ * Covers: formatOutputBlock, parseOutputBlock, findOutputBlock, writeOutputBlock
+ */
+
+import { describe, it, expect } from 'vitest';
+import { formatOutputBlock, parseOutputBlock, findOutputBlock, writeOutputBlock } from '../../src/utils/output-block';
+
+const NOTE = ['# Deploy', '```ufence-bash', 'make deploy', '```', 'Then check.'];
+
+describe('formatOutputBlock', () => {
+	it('puts the status first, then the output', () => {
+		expect(formatOutputBlock('Exited with 0', 'built\ndone\n')).toEqual(['```ufence-output', 'Exited with 0', 'built', 'done', '```']);
+		expect(formatOutputBlock('Exited with 0', '')).toEqual(['```ufence-output', 'Exited with 0', '```']);
+	});
+
+	it('makes the fence longer than backticks in the output', () => {
+		const lines = formatOutputBlock('Exited with 0', 'see ```code```');
+		expect(lines[0]).toBe('````ufence-output');
+		expect(lines[lines.length - 1]).toBe('````');
+	});
+});
+
+describe('parseOutputBlock', () => {
+	it('reads the status and output back', () => {
+		expect(parseOutputBlock('Failed with exit code 2\nno such file')).toEqual({ status: 'Failed with exit code 2', output: 'no such file' });
+		expect(parseOutputBlock('Exited with 0')).toEqual({ status: 'Exited with 0', output: '' });
+	});
+});
+
+describe('findOutputBlock', () => {
+	it('finds the output block just after a block', () => {
+		const lines = [...NOTE.slice(0, 4), '', '```ufence-output', 'Exited with 0', '```', 'Then check.'];
+		expect(findOutputBlock(lines, 3)).toEqual({ start: 5, end: 7 });
+	});
+
+	it('ignores other blocks and text', () => {
+		expect(findOutputBlock(NOTE, 3)).toBeNull();
+		expect(findOutputBlock([...NOTE.slice(0, 4), '```ufence-bash', 'ls', '```'], 3)).toBeNull();
+	});
+});
+
+describe('writeOutputBlock', () => {
+	it('adds an output block after the block', () => {
+		expect(writeOutputBlock(NOTE, 3, 'Exited with 0', 'ok\n')).toEqual([
+			'# Deploy', '```ufence-bash', 'make deploy', '```', '', '```ufence-output', 'Exited with 0', 'ok', '```', 'Then check.',
+		]);
+	});
+
+	it('replaces the output of an earlier run', () => {
+		const first = writeOutputBlock(NOTE, 3, 'Failed with exit code 1', 'broken\nagain\n');
+		expect(writeOutputBlock(first, 3, 'Exited with 0', 'ok\n')).toEqual(writeOutputBlock(NOTE, 3, 'Exited with 0', 'ok\n'));
+	});
+});
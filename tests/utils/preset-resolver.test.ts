@@ -1,5 +1,5 @@
 import { describe, it, expect } from 'vitest';
-import { resolvePreset } from '../../src/utils/preset-resolver';
+import { resolvePreset, resolvePresetConfig, resolvePresetReference } from '../../src/utils/preset-resolver';
 import type { ParsedYamlConfig } from '../../src/types';
 
 // Test data: Presets map
@@ -541,7 +541,7 @@ describe('resolvePreset — Inline page config (no preset reference)', () => {
 // =============================================================================
 
 describe('resolvePreset — Inline page config combined with named preset', () => {
-	it('preset as base, page config in middle, block on top', () => {
+	it('page config as base, the preset it names in the middle, block on top', () => {
 		const blockConfig: ParsedYamlConfig = {
 			RENDER: {
 				FOLD: 10, // Block override
@@ -550,13 +550,13 @@ describe('resolvePreset — Inline page config combined with named preset', () =
 		const pageConfig: ParsedYamlConfig = {
 			META: { PRESET: 'teaching' },
 			RENDER: {
-				STYLE: 'plain', // Override preset's STYLE: "integrated"
+				STYLE: 'plain', // Overridden by the preset's STYLE: "integrated"
 			},
 		};
 		const result = resolvePreset(blockConfig, presets, pageConfig);
 		expect(result.RENDER!.LINES).toBe(true); // From preset (teaching)
 		expect(result.RENDER!.ZEBRA).toBe(true); // From preset (teaching)
-		expect(result.RENDER!.STYLE).toBe('plain'); // Page config overrides preset
+		expect(result.RENDER!.STYLE).toBe('integrated'); // Preset overrides page config
 		expect(result.RENDER!.FOLD).toBe(10); // Block overrides all
 	});
 
@@ -603,3 +603,270 @@ describe('resolvePreset — Inline page config combined with named preset', () =
 		expect(result.RENDER!.ZEBRA).toBe(true);
 	});
 });
+
+// =============================================================================
+// Note Frontmatter Config
+// =============================================================================
+
+describe('resolvePreset — Note frontmatter config', () => {
+	it('applies frontmatter defaults under the page and block config', () => {
+		const blockConfig: ParsedYamlConfig = { RENDER: { FOLD: 10 } };
+		const pageConfig: ParsedYamlConfig = { RENDER: { ZEBRA: false } };
+		const frontmatterConfig: ParsedYamlConfig = { RENDER: { ZEBRA: true, LINES: true, FOLD: 30 } };
+		const result = resolvePreset(blockConfig, presets, pageConfig, frontmatterConfig);
+		expect(result.RENDER!.LINES).toBe(true); // From frontmatter
+		expect(result.RENDER!.ZEBRA).toBe(false); // Page config overrides frontmatter
+		expect(result.RENDER!.FOLD).toBe(10); // Block overrides all
+	});
+
+	it('sits under the preset it names', () => {
+		const frontmatterConfig: ParsedYamlConfig = { META: { PRESET: 'minimal' }, RENDER: { COPY: true, ZEBRA: true } };
+		const result = resolvePreset({}, presets, undefined, frontmatterConfig);
+		expect(result.RENDER!.FOLD).toBe(20); // From preset (minimal)
+		expect(result.RENDER!.COPY).toBe(false); // Preset overrides frontmatter
+		expect(result.RENDER!.ZEBRA).toBe(true); // From frontmatter
+		expect(result.META?.PRESET).toBeUndefined();
+	});
+
+	it('uses the page config preset before the frontmatter one', () => {
+		const pageConfig: ParsedYamlConfig = { META: { PRESET: 'teaching' } };
+		const frontmatterConfig: ParsedYamlConfig = { META: { PRESET: 'minimal' } };
+		const result = resolvePreset({}, presets, pageConfig, frontmatterConfig);
+		expect(result.RENDER!.STYLE).toBe('integrated'); // From 'teaching'
+		expect(result.RENDER!.FOLD).toBeUndefined();
+	});
+});
+
+// =============================================================================
+// Preset Inheritance (EXTENDS)
+// =============================================================================
+
+describe('resolvePresetConfig — EXTENDS', () => {
+	const layered: Record<string, string> = {
+		'base': 'RENDER:\n  LINES: true\n  ZEBRA: true\n  FOLD: 30',
+		'wide': 'RENDER:\n  FOLD: 0\n  SCROLL: 0',
+		'bash': 'EXTENDS: base\nRENDER:\n  LANG: bash\n  ZEBRA: false',
+		'runbook': 'EXTENDS: [bash, wide]\nMETA:\n  DESC: Runbook',
+		'loop-a': 'EXTENDS: loop-b\nRENDER:\n  FOLD: 1',
+		'loop-b': 'EXTENDS: loop-a\nRENDER:\n  FOLD: 2\n  LINES: true',
+	};
+
+	it('layers a preset on top of the one it extends', () => {
+		const result = resolvePresetConfig('bash', layered);
+		expect(result.RENDER).toEqual({ LINES: true, ZEBRA: false, FOLD: 30, LANG: 'bash' });
+	});
+
+	it('applies several presets in order, later ones winning', () => {
+		const result = resolvePresetConfig('runbook', layered);
+		expect(result.RENDER?.LANG).toBe('bash'); // From bash
+		expect(result.RENDER?.LINES).toBe(true); // From base, through bash
+		expect(result.RENDER?.FOLD).toBe(0); // wide overrides base
+		expect(result.META?.DESC).toBe('Runbook');
+	});
+
+	it('skips unknown presets and stops at loops', () => {
+		expect(resolvePresetConfig('missing', layered)).toEqual({});
+		const result = resolvePresetConfig('loop-a', layered);
+		expect(result.RENDER?.FOLD).toBe(1);
+		expect(result.RENDER?.LINES).toBe(true);
+	});
+
+	it('is used for a block that names the preset', () => {
+		const result = resolvePreset({ META: { PRESET: 'bash' }, RENDER: { FOLD: 5 } }, layered);
+		expect(result.RENDER?.ZEBRA).toBe(false);
+		expect(result.RENDER?.LINES).toBe(true);
+		expect(result.RENDER?.FOLD).toBe(5);
+	});
+});
+
+// =============================================================================
+// Folder Preset
+// =============================================================================
+
+describe('resolvePreset — Default preset', () => {
+	it('applies the language or tag preset when nothing else names one', () => {
+		const result = resolvePreset({ RENDER: { FOLD: 5 } }, presets, undefined, undefined, 'minimal');
+		expect(result.RENDER!.COPY).toBe(false); // From 'minimal'
+		expect(result.RENDER!.FOLD).toBe(5); // Block overrides preset
+	});
+
+	it('gives way to a preset named in frontmatter, page config or block', () => {
+		const frontmatterConfig: ParsedYamlConfig = { META: { PRESET: 'teaching' } };
+		const result = resolvePreset({}, presets, undefined, frontmatterConfig, 'minimal');
+		expect(result.RENDER!.STYLE).toBe('integrated'); // From 'teaching'
+		expect(result.RENDER!.COPY).toBeUndefined();
+	});
+});
+
+describe('resolvePreset — Folder preset', () => {
+	it('is the lowest layer, under the frontmatter and a named preset', () => {
+		const frontmatterConfig: ParsedYamlConfig = { RENDER: { FOLD: 8 } };
+		const result = resolvePreset({ META: { PRESET: 'teaching' } }, presets, undefined, frontmatterConfig, undefined, undefined, undefined, 'minimal');
+		expect(result.RENDER!.COPY).toBe(false); // From the folder preset (minimal)
+		expect(result.RENDER!.FOLD).toBe(8); // Frontmatter overrides the folder preset
+		expect(result.RENDER!.STYLE).toBe('integrated'); // From the named preset (teaching)
+	});
+
+	it('is skipped when its layer is off', () => {
+		const result = resolvePreset({ RENDER: { FOLD: 5 } }, presets, undefined, undefined, undefined, undefined, [
+			{ layer: 'folder', enabled: false },
+		], 'minimal');
+		expect(result.RENDER).toEqual({ FOLD: 5 });
+	});
+});
+
+describe('resolvePreset — Fence line shorthand', () => {
+	it('overrides the settings section', () => {
+		const result = resolvePreset({ RENDER: { FOLD: 10, LINES: true } }, presets, undefined, undefined, undefined, undefined, undefined, undefined, { RENDER: { FOLD: 4 } });
+		expect(result.RENDER).toEqual({ FOLD: 4, LINES: true });
+	});
+
+	it('names the preset over the settings section', () => {
+		const result = resolvePreset({ META: { PRESET: 'minimal' } }, presets, undefined, undefined, undefined, undefined, undefined, undefined, { META: { PRESET: 'teaching' } });
+		expect(result.RENDER!.STYLE).toBe('integrated'); // From 'teaching'
+		expect(result.RENDER!.FOLD).toBeUndefined();
+		expect(result.META?.PRESET).toBeUndefined();
+	});
+
+	it('goes under the settings section when the cascade puts it there, and away when it is off', () => {
+		const shorthandConfig: ParsedYamlConfig = { RENDER: { FOLD: 4 } };
+		const under = resolvePreset({ RENDER: { FOLD: 10 } }, presets, undefined, undefined, undefined, undefined, [
+			{ layer: 'shorthand', enabled: true },
+			{ layer: 'block', enabled: true },
+		], undefined, shorthandConfig);
+		expect(under.RENDER!.FOLD).toBe(10);
+
+		const off = resolvePreset({ RENDER: { LINES: true } }, presets, undefined, undefined, undefined, undefined, [
+			{ layer: 'shorthand', enabled: false },
+		], undefined, shorthandConfig);
+		expect(off.RENDER).toEqual({ LINES: true });
+	});
+});
+
+// =============================================================================
+// Preset Parameters
+// =============================================================================
+
+describe('resolvePresetConfig — parameters', () => {
+	const parameterised: Record<string, string> = {
+		'api-example': 'PARAMS:\n  title: Example\n  foldLines: 20\nMETA:\n  TITLE: "API: ${title}"\nRENDER:\n  FOLD: ${foldLines}',
+		'titled': 'PARAMS:\n  title:\nMETA:\n  TITLE: "${title}"',
+		'child': 'EXTENDS: titled\nRENDER:\n  LINES: true',
+		'fixed': 'EXTENDS: titled(title="Fixed")',
+		'legacy (old)': 'RENDER:\n  ZEBRA: true',
+	};
+
+	it('uses the defaults when no arguments are given', () => {
+		const result = resolvePresetConfig('api-example', parameterised);
+		expect(result.META?.TITLE).toBe('API: Example');
+		expect(result.RENDER?.FOLD).toBe(20);
+	});
+
+	it('fills parameters from the reference', () => {
+		const result = resolvePresetConfig('api-example(title="Create user", foldLines=5)', parameterised);
+		expect(result.META?.TITLE).toBe('API: Create user');
+		expect(result.RENDER?.FOLD).toBe(5);
+	});
+
+	it('passes arguments to the presets it extends', () => {
+		expect(resolvePresetConfig('child(title="Inherited")', parameterised).META?.TITLE).toBe('Inherited');
+		expect(resolvePresetConfig('fixed(title="Ignored")', parameterised).META?.TITLE).toBe('Fixed');
+	});
+
+	it('applies arguments given in a block', () => {
+		const result = resolvePreset({ META: { PRESET: 'api-example(foldLines=3)' } }, parameterised);
+		expect(result.META?.TITLE).toBe('API: Example');
+		expect(result.RENDER?.FOLD).toBe(3);
+		expect(result.META?.PRESET).toBeUndefined();
+	});
+
+	it('matches a saved name containing brackets before reading arguments', () => {
+		expect(resolvePresetReference('legacy (old)', parameterised)).toEqual({ name: 'legacy (old)', args: {} });
+		expect(resolvePresetConfig('legacy (old)', parameterised).RENDER?.ZEBRA).toBe(true);
+	});
+});
+
+// =============================================================================
+// Conditional Settings (WHEN)
+// =============================================================================
+
+describe('resolvePresetConfig — WHEN conditions', () => {
+	const themed: Record<string, string> = {
+		'themed': [
+			'RENDER:',
+			'  STYLE: tab',
+			'  ZEBRA: true',
+			'WHEN:',
+			'  dark:',
+			'    RENDER:',
+			'      ZEBRA: false',
+			'  mobile:',
+			'    RENDER:',
+			'      STYLE: minimal',
+			'  dark mobile:',
+			'    RENDER:',
+			'      FOLD: 10',
+		].join('\n'),
+		'child': 'EXTENDS: themed\nRENDER:\n  LINES: true',
+	};
+
+	it('ignores WHEN entries without matching conditions', () => {
+		const result = resolvePresetConfig('themed', themed);
+		expect(result.RENDER).toEqual({ STYLE: 'tab', ZEBRA: true });
+	});
+
+	it('applies the entries whose conditions hold', () => {
+		const result = resolvePresetConfig('themed', themed, new Set(['dark', 'desktop']));
+		expect(result.RENDER).toEqual({ STYLE: 'tab', ZEBRA: false });
+	});
+
+	it('applies combined conditions only when all hold', () => {
+		const result = resolvePresetConfig('themed', themed, new Set(['dark', 'mobile']));
+		expect(result.RENDER).toEqual({ STYLE: 'minimal', ZEBRA: false, FOLD: 10 });
+	});
+
+	it('applies the conditions of extended presets and blocks still override them', () => {
+		const result = resolvePreset({ META: { PRESET: 'child' }, RENDER: { STYLE: 'integrated' } }, themed, undefined, undefined, undefined, new Set(['light', 'mobile']));
+		expect(result.RENDER).toEqual({ STYLE: 'integrated', ZEBRA: true, LINES: true });
+	});
+});
+
+// =============================================================================
+// resolvePreset — settings cascade
+// =============================================================================
+
+describe('resolvePreset — settings cascade', () => {
+	const presets = { wide: 'RENDER:\n  FOLD: 30\n  ZEBRA: true' };
+	const pageConfig: ParsedYamlConfig = { META: { PRESET: 'wide' }, RENDER: { FOLD: 5, LINES: false } };
+	const frontmatterConfig: ParsedYamlConfig = { RENDER: { LINES: true, STYLE: 'tab' } };
+
+	it('applies the layers in the order given', () => {
+		const result = resolvePreset({ RENDER: { FOLD: 1 } }, presets, pageConfig, frontmatterConfig, undefined, undefined, [
+			{ layer: 'block', enabled: true },
+			{ layer: 'page', enabled: true },
+			{ layer: 'frontmatter', enabled: true },
+			{ layer: 'preset', enabled: true },
+		]);
+		expect(result.RENDER).toEqual({ FOLD: 30, ZEBRA: true, LINES: true, STYLE: 'tab' });
+		expect(result.META?.PRESET).toBeUndefined();
+	});
+
+	it('skips switched-off layers, including the presets they name', () => {
+		const result = resolvePreset({}, presets, pageConfig, frontmatterConfig, undefined, undefined, [
+			{ layer: 'preset', enabled: true },
+			{ layer: 'frontmatter', enabled: false },
+			{ layer: 'page', enabled: false },
+			{ layer: 'block', enabled: true },
+		]);
+		expect(result).toEqual({});
+	});
+
+	it('ignores presets when the preset layer is off', () => {
+		const blockConfig: ParsedYamlConfig = { META: { PRESET: 'wide' }, RENDER: { LINES: true } };
+		const result = resolvePreset(blockConfig, presets, undefined, undefined, 'wide', undefined, [
+			{ layer: 'preset', enabled: false },
+			{ layer: 'block', enabled: true },
+		]);
+		expect(result).toBe(blockConfig);
+	});
+});
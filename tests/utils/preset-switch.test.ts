@@ -0,0 +1,67 @@
+/**
+ * Tests for switching a block's preset in its source.
+ *
+ * Covers: setBlockPreset, setBlockLanguage
+ */
+
+import { describe, it, expect } from 'vitest';
+import { setBlockPreset, setBlockLanguage } from '../../src/utils/preset-switch';
+
+describe('setBlockPreset', () => {
+	it('rewrites an existing PRESET line in place', () => {
+		const text = 'META:\n  TITLE: "Install"\n  PRESET: old # comment\nRENDER:\n  LINES: true';
+		expect(setBlockPreset(text, 'teaching', true)).toBe('META:\n  TITLE: "Install"\n  PRESET: teaching\nRENDER:\n  LINES: true');
+	});
+
+	it('adds PRESET under an existing META with its indentation', () => {
+		const text = 'META:\n    TITLE: x\n~~~\necho hi';
+		expect(setBlockPreset(text, 'wide', true)).toBe('META:\n    PRESET: wide\n    TITLE: x\n~~~\necho hi');
+	});
+
+	it('adds a META section when there is none', () => {
+		expect(setBlockPreset('RENDER:\n  ZEBRA: true', 'wide', true)).toBe('META:\n  PRESET: wide\nRENDER:\n  ZEBRA: true');
+		expect(setBlockPreset('~~~\necho hi', 'wide', true)).toBe('META:\n  PRESET: wide\n~~~\necho hi');
+	});
+
+	it('quotes names that are not plain words', () => {
+		expect(setBlockPreset('META:\n  TITLE: x', 'Dark mode: large', true)).toBe('META:\n  PRESET: "Dark mode: large"\n  TITLE: x');
+	});
+
+	it('gives plain code a settings section and separator', () => {
+		expect(setBlockPreset('echo hi', 'wide', false)).toBe('META:\n  PRESET: wide\n~~~\necho hi');
+		expect(setBlockPreset('echo hi', undefined, false)).toBe('echo hi');
+	});
+
+	it('removes the PRESET line and a META section left empty', () => {
+		expect(setBlockPreset('META:\n  TITLE: x\n  PRESET: wide', undefined, true)).toBe('META:\n  TITLE: x');
+		expect(setBlockPreset('META:\n  PRESET: wide\nRENDER:\n  LINES: true', undefined, true)).toBe('RENDER:\n  LINES: true');
+	});
+
+	it('leaves settings and code after the separator alone', () => {
+		const text = 'RENDER:\n  LINES: true\n~~~\nMETA:\n  PRESET: not-settings';
+		expect(setBlockPreset(text, undefined, true)).toBe(text);
+	});
+
+	it('gives up on a META flow mapping', () => {
+		expect(setBlockPreset('META: { TITLE: x }', 'wide', true)).toBeUndefined();
+	});
+});
+
+describe('setBlockLanguage', () => {
+	it('rewrites RENDER.LANG in place', () => {
+		expect(setBlockLanguage('RENDER:\n  LANG: auto\n  LINES: true\n~~~\nls', 'bash', true)).toBe('RENDER:\n  LANG: bash\n  LINES: true\n~~~\nls');
+	});
+
+	it('adds LANG under RENDER, or a RENDER section', () => {
+		expect(setBlockLanguage('RENDER:\n  ZEBRA: true', 'python', true)).toBe('RENDER:\n  LANG: python\n  ZEBRA: true');
+		expect(setBlockLanguage('META:\n  TITLE: x', 'c++', true)).toBe('RENDER:\n  LANG: c++\nMETA:\n  TITLE: x');
+	});
+
+	it('gives plain code a settings section and separator', () => {
+		expect(setBlockLanguage('SELECT 1;', 'sql', false)).toBe('RENDER:\n  LANG: sql\n~~~\nSELECT 1;');
+	});
+
+	it('quotes languages YAML would misread', () => {
+		expect(setBlockLanguage('RENDER:\n  LANG: auto', '#lang', true)).toBe('RENDER:\n  LANG: "#lang"');
+	});
+});
@@ -0,0 +1,80 @@
+// @vitest-environment jsdom
+
+/**
+ * Tests for src/utils/rich-text.ts
+ *
+ * Covers: buildRichTextHtml
+ */
+
+import { describe, it, expect, beforeEach } from 'vitest';
+import { setupObsidianDom } from '../../__mocks__/obsidian';
+import { buildRichTextHtml } from '../../src/utils/rich-text';
+import { wrapCodeLinesInDom } from '../../src/utils/dom';
+import { REDACTION_MARK } from '../../src/constants';
+
+beforeEach(() => {
+	setupObsidianDom();
+	document.body.innerHTML = '';
+});
+
+function renderBlock(innerHtml: string): HTMLElement {
+	const pre = document.createElement('pre');
+	const code = document.createElement('code');
+	code.innerHTML = innerHtml;
+	pre.appendChild(code);
+	document.body.appendChild(pre);
+	return code;
+}
+
+// =============================================================================
+// buildRichTextHtml
+// =============================================================================
+
+describe('buildRichTextHtml', () => {
+	it('wraps the code in a styled pre element', () => {
+		const html = buildRichTextHtml(renderBlock('ls -la'));
+		expect(html).toMatch(/^<pre style="[^"]*font-family[^"]*"><code>ls -la<\/code><\/pre>$/);
+	});
+
+	it('inlines token colours that differ from the block', () => {
+		const html = buildRichTextHtml(renderBlock('<span style="color: rgb(255, 0, 0)">echo</span> hi'));
+		expect(html).toContain('<span style="color: rgb(255, 0, 0)">echo</span> hi');
+	});
+
+	it('leaves unstyled tokens as plain text', () => {
+		const html = buildRichTextHtml(renderBlock('<span class="token">echo</span> hi'));
+		expect(html).toContain('<code>echo hi</code>');
+	});
+
+	it('escapes HTML in the code', () => {
+		expect(buildRichTextHtml(renderBlock('a &lt; b &amp;&amp; c'))).toContain('a &lt; b &amp;&amp; c');
+	});
+
+	it('keeps one line per wrapped line, dropping prompts and masking redactions', () => {
+		const code = renderBlock('$ login\nexport TOKEN=abc');
+		wrapCodeLinesInDom(code, { showLineNumbers: true, showZebraStripes: false });
+		const lines = code.querySelectorAll('.ucf-line-content');
+		lines[0].innerHTML = '<span class="ucf-prompt">$ </span>login';
+		lines[1].innerHTML = 'export TOKEN=<span class="ucf-redacted">abc</span>';
+
+		const html = buildRichTextHtml(code);
+		expect(html).toContain(`<code>login\nexport TOKEN=${REDACTION_MARK}</code>`);
+		expect(html).not.toContain('abc');
+	});
+
+	it('leaves out lines rejected by keepLine', () => {
+		const code = renderBlock('# comment\nls');
+		wrapCodeLinesInDom(code, { showLineNumbers: false, showZebraStripes: false, forceLineWrapping: true });
+
+		expect(buildRichTextHtml(code, line => !line.startsWith('#'))).toContain('<code>ls</code>');
+	});
+
+	it('writes lines with filled-in placeholders from the copied text', () => {
+		const code = renderBlock('<span style="color: rgb(255, 0, 0)">ssh</span> {{HOST}}\n<span style="color: rgb(255, 0, 0)">ls</span>');
+		wrapCodeLinesInDom(code, { showLineNumbers: false, showZebraStripes: false, forceLineWrapping: true });
+
+		const html = buildRichTextHtml(code, () => true, 'ssh db.example.com\nls');
+		expect(html).toContain('<code>ssh db.example.com\n<span style="color: rgb(255, 0, 0)">ls</span></code>');
+		expect(html).not.toContain('{{HOST}}');
+	});
+});
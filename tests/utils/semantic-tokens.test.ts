@@ -0,0 +1,101 @@
+/**
+ * Tests for src/utils/semantic-tokens.ts
+ *
+ * Covers: semanticLanguage, findSemanticTokens
+ */
+
+import { describe, it, expect } from 'vitest';
+import { findSemanticTokens, semanticLanguage } from '../../src/utils/semantic-tokens';
+
+/** Lists the tokens found as "type text". */
+function describeTokens(code: string, language: string): string[] {
+	return findSemanticTokens(code, language).map(token => `${token.type} ${code.slice(token.start, token.end)}`);
+}
+
+describe('semanticLanguage', () => {
+	it('maps language codes to the scan that covers them', () => {
+		expect(semanticLanguage('golang')).toBe('go');
+		expect(semanticLanguage('PY')).toBe('python');
+		expect(semanticLanguage('tsx')).toBe('typescript');
+		expect(semanticLanguage('js')).toBe('typescript');
+		expect(semanticLanguage('rust')).toBeNull();
+	});
+});
+
+describe('findSemanticTokens', () => {
+	it('finds Go methods, their receiver, parameters and field reads', () => {
+		const code = 'func (s *Server) Run(ctx context.Context, a, b int) error {\n\treturn s.run(ctx, a.X)\n}';
+
+		expect(describeTokens(code, 'go')).toEqual([
+			'parameter s',
+			'function-definition Run',
+			'parameter ctx',
+			'property-access Context',
+			'parameter a',
+			'parameter b',
+			'parameter s',
+			'parameter ctx',
+			'parameter a',
+			'property-access X',
+		]);
+	});
+
+	it('reads Go lists of bare types as having no names', () => {
+		expect(describeTokens('func add(int, string) {}', 'go')).toEqual(['function-definition add']);
+		expect(describeTokens('f := func(x int) { x.y }', 'go')).toEqual(['parameter x', 'parameter x', 'property-access y']);
+	});
+
+	it('finds Python parameters in the indented body only', () => {
+		const code = 'def greet(self, name, *args, greeting="hi", **kw):\n    print(self.name, name)\n    return kw\nname = 1';
+
+		expect(describeTokens(code, 'python')).toEqual([
+			'function-definition greet',
+			'parameter self',
+			'parameter name',
+			'parameter args',
+			'parameter greeting',
+			'parameter kw',
+			'parameter self',
+			'property-access name',
+			'parameter name',
+			'parameter kw',
+		]);
+		expect(describeTokens('f = lambda x, y: x + y', 'python')).toEqual(['parameter x', 'parameter y', 'parameter x', 'parameter y']);
+	});
+
+	it('finds TypeScript functions, arrow functions and their parameters', () => {
+		const code = 'function add(a: number, b = 2) {\n  return a + b + obj.field;\n}\nconst mul = (a: number, b: number): number => a * b;\nlist.map(x => x.id);';
+
+		expect(describeTokens(code, 'typescript')).toEqual([
+			'function-definition add',
+			'parameter a',
+			'parameter b',
+			'parameter a',
+			'parameter b',
+			'property-access field',
+			'function-definition mul',
+			'parameter a',
+			'parameter b',
+			'parameter a',
+			'parameter b',
+			'parameter x',
+			'parameter x',
+			'property-access id',
+		]);
+	});
+
+	it('finds methods but not statements, and skips modifiers and calls', () => {
+		const code = 'class A {\n  constructor(private readonly svc: Svc, ...rest: T[]) {\n    this.svc.go();\n  }\n  if (x) {\n  }\n}';
+
+		expect(describeTokens(code, 'ts')).toEqual([
+			'function-definition constructor',
+			'parameter svc',
+			'parameter rest',
+			'property-access svc',
+		]);
+	});
+
+	it('finds nothing for other languages', () => {
+		expect(findSemanticTokens('fn add(a: i32) { a.b }', 'rust')).toEqual([]);
+	});
+});
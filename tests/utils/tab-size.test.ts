@@ -0,0 +1,29 @@
+/**
+ * Tests for picking the tab width of a block.
+ *
+ * Covers: parseLanguageTabSizes, getDefaultTabSize
+ */
+
+import { describe, it, expect } from 'vitest';
+import { parseLanguageTabSizes, getDefaultTabSize } from '../../src/utils/tab-size';
+import { testSettings } from '../helpers/test-settings';
+
+describe('parseLanguageTabSizes', () => {
+	it('reads language=width and language: width entries', () => {
+		expect(parseLanguageTabSizes('go=4, Makefile: 8')).toEqual({ go: 4, makefile: 8 });
+	});
+
+	it('skips malformed and zero widths', () => {
+		expect(parseLanguageTabSizes('go, python=wide, c=0, , rust=2')).toEqual({ rust: 2 });
+		expect(parseLanguageTabSizes('')).toEqual({});
+	});
+});
+
+describe('getDefaultTabSize', () => {
+	it('uses the language\'s width before the default width', () => {
+		const settings = testSettings({ tabSize: 2, languageTabSizes: 'makefile=8' });
+
+		expect(getDefaultTabSize(settings, 'Makefile')).toBe(8);
+		expect(getDefaultTabSize(settings, 'go')).toBe(2);
+	});
+});
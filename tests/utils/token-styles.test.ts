@@ -0,0 +1,80 @@
+/**
+ * Tests for src/utils/token-styles.ts
+ *
+ * Covers: parseTokenStyle, resolveTokenStyles, resolvePatternStyles
+ */
+
+import { describe, it, expect } from 'vitest';
+import { parseTokenStyle, resolvePatternStyles, resolveTokenStyles } from '../../src/utils/token-styles';
+
+describe('parseTokenStyle', () => {
+	it('reads weight, style and colour words in any order', () => {
+		expect(parseTokenStyle('keyword', 'bold #a855f7 italic')).toEqual({
+			token: 'keyword',
+			colour: '#a855f7',
+			background: '',
+			fontWeight: 'bold',
+			fontStyle: 'italic',
+			textDecoration: '',
+			opacity: undefined,
+		});
+	});
+
+	it('reads fading, decoration and background words', () => {
+		const style = parseTokenStyle('comment', 'dim underline on rgb(0, 0, 0)');
+		expect(style.opacity).toBe(0.6);
+		expect(style.textDecoration).toBe('underline');
+		expect(style.background).toBe('rgb(0, 0, 0)');
+		expect(parseTokenStyle('comment', 'faint').opacity).toBe(0.4);
+		expect(parseTokenStyle('comment', '35%').opacity).toBe(0.35);
+		expect(parseTokenStyle('deleted', 'strike').textDecoration).toBe('line-through');
+	});
+
+	it('undoes the theme\'s styling with normal', () => {
+		const style = parseTokenStyle('keyword', 'normal var(--text-normal)');
+		expect(style.fontWeight).toBe('normal');
+		expect(style.fontStyle).toBe('normal');
+		expect(style.textDecoration).toBe('none');
+		expect(style.colour).toBe('var(--text-normal)');
+	});
+
+	it('ignores words it does not know', () => {
+		expect(parseTokenStyle('keyword', 'bold! on url(x) ???').colour).toBe('');
+	});
+});
+
+describe('resolveTokenStyles', () => {
+	const tokens = {
+		keyword: 'bold',
+		comment: 'dim',
+		sql: { keyword: 'purple', 'bad token': 'red' },
+		python: { comment: 'italic' },
+	};
+
+	it('applies all-language styles first, then the language\'s own', () => {
+		const styles = resolveTokenStyles(tokens, 'SQL');
+		expect(styles.map(style => style.token)).toEqual(['keyword', 'comment', 'keyword']);
+		expect(styles[2].colour).toBe('purple');
+	});
+
+	it('skips other languages and returns nothing without TOKENS', () => {
+		expect(resolveTokenStyles(tokens, 'bash').map(style => style.token)).toEqual(['keyword', 'comment']);
+		expect(resolveTokenStyles(undefined, 'sql')).toEqual([]);
+	});
+});
+
+describe('resolvePatternStyles', () => {
+	it('compiles each regex and reads its style, in order', () => {
+		const styles = resolvePatternStyles({ '\\b(?:dev|prod)\\b': 'underline orange', 'PROJ-\\d+': 'bold on #fef3c7' });
+
+		expect(styles.map(entry => entry.pattern.source)).toEqual(['\\b(?:dev|prod)\\b', 'PROJ-\\d+']);
+		expect(styles[0].style.textDecoration).toBe('underline');
+		expect(styles[0].style.colour).toBe('orange');
+		expect(styles[1].style.background).toBe('#fef3c7');
+	});
+
+	it('skips invalid regexes and returns nothing without PATTERNS', () => {
+		expect(resolvePatternStyles({ '[': 'red', '': 'red' })).toEqual([]);
+		expect(resolvePatternStyles(undefined)).toEqual([]);
+	});
+});
@@ -0,0 +1,88 @@
+/**
+ * Tests for src/utils/type-annotations.ts
+ *
+ * Covers: stripTypeAnnotations
+ */
+
+import { describe, it, expect } from 'vitest';
+import { stripTypeAnnotations } from '../../src/utils/type-annotations';
+
+describe('stripTypeAnnotations', () => {
+	it('strips variable, parameter and return types', () => {
+		expect(stripTypeAnnotations('let total: number = 0;')).toBe('let total = 0;');
+		expect(stripTypeAnnotations('const names: Array<string> = [];')).toBe('const names = [];');
+		expect(stripTypeAnnotations('let handler: (a: number) => void = noop;')).toBe('let handler = noop;');
+		expect(stripTypeAnnotations('function add(a: number, b = 2): number {\n\treturn a + b;\n}'))
+			.toBe('function add(a, b = 2) {\n\treturn a + b;\n}');
+		expect(stripTypeAnnotations('const area = (r: number): number => Math.PI * r ** 2;'))
+			.toBe('const area = (r) => Math.PI * r ** 2;');
+	});
+
+	it('strips optional marks, destructured types and generic parameters', () => {
+		expect(stripTypeAnnotations('function greet(name?: string, { loud }: Options = {}) {}'))
+			.toBe('function greet(name, { loud } = {}) {}');
+		expect(stripTypeAnnotations('function first<T>(items: T[]): T | undefined {\n\treturn items[0];\n}'))
+			.toBe('function first(items) {\n\treturn items[0];\n}');
+	});
+
+	it('removes interfaces, type aliases and type imports', () => {
+		const code = [
+			"import type { Config } from './config';",
+			'interface Point {',
+			'\tx: number;',
+			'\ty: number;',
+			'}',
+			'type Pair = [number, number];',
+			'type Mode =',
+			"\t| 'fast'",
+			"\t| 'slow';",
+			'console.log(1);',
+		].join('\n');
+		expect(stripTypeAnnotations(code).trim()).toBe('console.log(1);');
+	});
+
+	it('strips casts, non-null assertions and member modifiers', () => {
+		expect(stripTypeAnnotations('const n = (value as number) + 1;')).toBe('const n = (value) + 1;');
+		expect(stripTypeAnnotations('const keys = [1, 2] as const;')).toBe('const keys = [1, 2];');
+		expect(stripTypeAnnotations('map.get(key)!.push(item);')).toBe('map.get(key).push(item);');
+		expect(stripTypeAnnotations('class Counter {\n\tprivate bump(by: number): void {\n\t}\n}'))
+			.toBe('class Counter {\n\tbump(by) {\n\t}\n}');
+		expect(stripTypeAnnotations('class Box {\n\tconstructor(private readonly size: number) {}\n}'))
+			.toBe('class Box {\n\tconstructor(size) {}\n}');
+	});
+
+	it('strips generic parameters of methods', () => {
+		expect(stripTypeAnnotations('class Bag {\n\tstatic pick<T>(items: T[]): T {\n\t}\n}'))
+			.toBe('class Bag {\n\tstatic pick(items) {\n\t}\n}');
+	});
+
+	it('leaves regex literals alone', () => {
+		expect(stripTypeAnnotations("const quote = /'/g;\nlet n: number = 1;")).toBe("const quote = /'/g;\nlet n = 1;");
+		expect(stripTypeAnnotations('const parts = path.split(/[/\\\\]/);\nfunction f(a: string) {}'))
+			.toBe('const parts = path.split(/[/\\\\]/);\nfunction f(a) {}');
+		expect(stripTypeAnnotations('return /`(x)/.test(s) ? a : b;')).toBe('return /`(x)/.test(s) ? a : b;');
+		expect(stripTypeAnnotations('const half = total / 2 / count;')).toBe('const half = total / 2 / count;');
+	});
+
+	it('refuses enums and generic arguments of calls', () => {
+		expect(() => stripTypeAnnotations('export const enum Level { Low, High }'))
+			.toThrow("Can't run 'enum Level' without compiling: enums aren't supported, use an object instead");
+		expect(() => stripTypeAnnotations('const [n, setN] = useState<number>(0);'))
+			.toThrow("Can't run 'useState<number>(' without compiling: generic arguments of calls aren't supported, leave them out");
+		expect(() => stripTypeAnnotations('const seen = new Map<string, number[]>();')).toThrow('generic arguments of calls');
+		expect(() => stripTypeAnnotations('interface Store {\n\tget<T>(key: string): T;\n}\nconst enumerate = 1;')).not.toThrow();
+		expect(() => stripTypeAnnotations('if (a<b && c>(d)) {}')).not.toThrow();
+	});
+
+	it('leaves JavaScript alone', () => {
+		const code = [
+			'const label = ready ? (count) : { none: true };',
+			"const text = 'a: string';",
+			'if (a !== b) { run({ key: value }); }',
+			'const ok = !done;',
+			'// let x: number',
+			'for (const item of list) total += item;',
+		].join('\n');
+		expect(stripTypeAnnotations(code)).toBe(code);
+	});
+});
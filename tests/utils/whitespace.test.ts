@@ -0,0 +1,41 @@
+/**
+ * Tests for finding whitespace to visualise.
+ *
+ * Covers: findWhitespaceRuns
+ */
+
+import { describe, it, expect } from 'vitest';
+import { findWhitespaceRuns } from '../../src/utils/whitespace';
+
+describe('findWhitespaceRuns', () => {
+	it('finds runs of spaces and each tab separately', () => {
+		expect(findWhitespaceRuns('a  b\t\tc', false)).toEqual([
+			{ start: 1, end: 3, kind: 'space', trailing: false },
+			{ start: 4, end: 5, kind: 'tab', trailing: false },
+			{ start: 5, end: 6, kind: 'tab', trailing: false },
+		]);
+	});
+
+	it('flags trailing whitespace', () => {
+		expect(findWhitespaceRuns('x = 1 \t', false)).toEqual([
+			{ start: 1, end: 2, kind: 'space', trailing: false },
+			{ start: 3, end: 4, kind: 'space', trailing: false },
+			{ start: 5, end: 6, kind: 'space', trailing: true },
+			{ start: 6, end: 7, kind: 'tab', trailing: true },
+		]);
+	});
+
+	it('returns only trailing whitespace when asked', () => {
+		expect(findWhitespaceRuns('  indented ', true)).toEqual([
+			{ start: 10, end: 11, kind: 'space', trailing: true },
+		]);
+	});
+
+	it('treats a whitespace-only line as trailing', () => {
+		expect(findWhitespaceRuns('   ', true)).toEqual([{ start: 0, end: 3, kind: 'space', trailing: true }]);
+	});
+
+	it('returns nothing for a line without whitespace', () => {
+		expect(findWhitespaceRuns('abc', false)).toEqual([]);
+	});
+});